@@ -63,6 +63,13 @@ var promptPasswords = map[string][]string{
 	"purchasetickets":   {"App password:"},
 	"startmmbot":        {"App password:"},
 	"withdrawbchspv":    {"App password"},
+	"amend":             {"App password:"},
+	"addressbook":       {"App password:"},
+	"addaddress":        {"App password:"},
+	"removeaddress":     {"App password:"},
+	"setallowlist":      {"App password:"},
+	"createapikey":      {"App password:"},
+	"revokeapikey":      {"App password:"},
 }
 
 // optionalTextFiles is a map of routes to arg index for routes that should read
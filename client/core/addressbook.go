@@ -0,0 +1,283 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package core
+
+import (
+	"fmt"
+	"time"
+
+	"decred.org/dcrdex/client/asset"
+	"decred.org/dcrdex/client/db"
+	"decred.org/dcrdex/dex"
+	"decred.org/dcrdex/dex/encode"
+	"decred.org/dcrdex/dex/encrypt"
+)
+
+// addressAllowlistDelay is how long after an address is added to the address
+// book before it can be sent to with the allowlist enabled, and how long after
+// a disable is requested before the allowlist is disabled. The delay gives the
+// user time to notice an address or disable request from a compromised
+// automation client.
+var addressAllowlistDelay = 24 * time.Hour
+
+// decryptAddressBookEntry decrypts the address and label of the
+// *db.AddressBookEntry.
+func decryptAddressBookEntry(crypter encrypt.Crypter, e *db.AddressBookEntry) (*AddressBookEntry, error) {
+	addr, err := crypter.Decrypt(e.EncAddress)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting address book entry %x: %w", e.ID, err)
+	}
+	label, err := crypter.Decrypt(e.EncLabel)
+	if err != nil {
+		return nil, fmt.Errorf("error decrypting address book entry %x label: %w", e.ID, err)
+	}
+	return &AddressBookEntry{
+		ID:       e.ID,
+		AssetID:  e.AssetID,
+		Symbol:   unbip(e.AssetID),
+		Address:  string(addr),
+		Label:    string(label),
+		Stamp:    e.Stamp,
+		ActiveAt: e.ActiveAt,
+	}, nil
+}
+
+// addressBook decrypts the address book entries.
+func (c *Core) addressBook(crypter encrypt.Crypter) ([]*AddressBookEntry, []*db.AddressBookEntry, error) {
+	dbEntries, err := c.db.AddressBook()
+	if err != nil {
+		return nil, nil, fmt.Errorf("error retrieving address book: %w", err)
+	}
+	entries := make([]*AddressBookEntry, 0, len(dbEntries))
+	for _, dbEntry := range dbEntries {
+		e, err := decryptAddressBookEntry(crypter, dbEntry)
+		if err != nil {
+			return nil, nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, dbEntries, nil
+}
+
+// AddressBook returns the decrypted address book, sorted by the time the
+// entries were added.
+func (c *Core) AddressBook(pw []byte) ([]*AddressBookEntry, error) {
+	crypter, err := c.encryptionKey(pw)
+	if err != nil {
+		return nil, newError(passwordErr, "password error: %w", err)
+	}
+	defer crypter.Close()
+	entries, _, err := c.addressBook(crypter)
+	return entries, err
+}
+
+// AddAddress adds an address with a label to the address book. If the address
+// is already in the address book for the asset, only the label is updated.
+// A new address can be sent to with the allowlist enabled only after
+// addressAllowlistDelay.
+func (c *Core) AddAddress(pw []byte, assetID uint32, address, label string) (*AddressBookEntry, error) {
+	crypter, err := c.encryptionKey(pw)
+	if err != nil {
+		return nil, newError(passwordErr, "password error: %w", err)
+	}
+	defer crypter.Close()
+
+	valid, err := c.ValidateAddress(address, assetID)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		return nil, newError(addressParseErr, "invalid %s address %q", unbip(assetID), address)
+	}
+
+	encAddr, err := crypter.Encrypt([]byte(address))
+	if err != nil {
+		return nil, newError(encryptionErr, "error encrypting address: %w", err)
+	}
+	encLabel, err := crypter.Encrypt([]byte(label))
+	if err != nil {
+		return nil, newError(encryptionErr, "error encrypting label: %w", err)
+	}
+
+	c.addressBookMtx.Lock()
+	defer c.addressBookMtx.Unlock()
+
+	entries, dbEntries, err := c.addressBook(crypter)
+	if err != nil {
+		return nil, err
+	}
+	now := uint64(time.Now().UnixMilli())
+	dbEntry := &db.AddressBookEntry{
+		ID:       encode.RandomBytes(8),
+		AssetID:  assetID,
+		Stamp:    now,
+		ActiveAt: now + uint64(addressAllowlistDelay.Milliseconds()),
+	}
+	for i, e := range entries {
+		if e.AssetID == assetID && e.Address == address {
+			dbEntry = dbEntries[i]
+			break
+		}
+	}
+	dbEntry.EncAddress, dbEntry.EncLabel = encAddr, encLabel
+	if err := c.db.UpdateAddressBookEntry(dbEntry); err != nil {
+		return nil, newError(dbErr, "error storing address book entry: %w", err)
+	}
+
+	c.log.Infof("%s address %s added to the address book. Allowlisted at %s", unbip(assetID), address,
+		time.UnixMilli(int64(dbEntry.ActiveAt)))
+
+	return &AddressBookEntry{
+		ID:       dbEntry.ID,
+		AssetID:  assetID,
+		Symbol:   unbip(assetID),
+		Address:  address,
+		Label:    label,
+		Stamp:    dbEntry.Stamp,
+		ActiveAt: dbEntry.ActiveAt,
+	}, nil
+}
+
+// RemoveAddress removes an address from the address book.
+func (c *Core) RemoveAddress(pw []byte, id dex.Bytes) error {
+	crypter, err := c.encryptionKey(pw)
+	if err != nil {
+		return newError(passwordErr, "password error: %w", err)
+	}
+	crypter.Close()
+
+	c.addressBookMtx.Lock()
+	defer c.addressBookMtx.Unlock()
+	if err := c.db.DeleteAddressBookEntry(id); err != nil {
+		return newError(dbErr, "error removing address book entry: %w", err)
+	}
+	return nil
+}
+
+// allowlistStatus is the current state of the allowlist. An allowlist with an
+// elapsed disable time is disabled.
+func (c *Core) allowlistStatus() (*AllowlistStatus, error) {
+	setting, err := c.db.AllowlistSetting()
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving allowlist setting: %w", err)
+	}
+	status := &AllowlistStatus{
+		Enabled:   setting.Enabled,
+		DisableAt: setting.DisableAt,
+		Delay:     uint64(addressAllowlistDelay.Milliseconds()),
+	}
+	if status.Enabled && status.DisableAt != 0 && uint64(time.Now().UnixMilli()) >= status.DisableAt {
+		status.Enabled, status.DisableAt = false, 0
+	}
+	return status, nil
+}
+
+// Allowlist returns the state of the withdrawal allowlist.
+func (c *Core) Allowlist() (*AllowlistStatus, error) {
+	return c.allowlistStatus()
+}
+
+// SetAllowlist enables or disables the withdrawal allowlist. While enabled,
+// AllowlistedSend will only send to addresses in the address book that were
+// added at least addressAllowlistDelay ago. Enabling takes effect
+// immediately, but disabling takes effect after addressAllowlistDelay.
+// Enabling the allowlist cancels a pending disable.
+func (c *Core) SetAllowlist(pw []byte, enable bool) (*AllowlistStatus, error) {
+	crypter, err := c.encryptionKey(pw)
+	if err != nil {
+		return nil, newError(passwordErr, "password error: %w", err)
+	}
+	crypter.Close()
+
+	c.addressBookMtx.Lock()
+	defer c.addressBookMtx.Unlock()
+
+	status, err := c.allowlistStatus()
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case enable:
+		status.Enabled, status.DisableAt = true, 0
+	case !status.Enabled:
+		return status, nil
+	case status.DisableAt == 0:
+		status.DisableAt = uint64(time.Now().Add(addressAllowlistDelay).UnixMilli())
+	default:
+		// Disable already pending.
+		return status, nil
+	}
+	err = c.db.SetAllowlistSetting(&db.AllowlistSetting{
+		Enabled:   status.Enabled,
+		DisableAt: status.DisableAt,
+	})
+	if err != nil {
+		return nil, newError(dbErr, "error storing allowlist setting: %w", err)
+	}
+	if status.DisableAt != 0 {
+		c.log.Warnf("Withdrawal allowlist will be disabled at %s", time.UnixMilli(int64(status.DisableAt)))
+	} else {
+		c.log.Infof("Withdrawal allowlist enabled")
+	}
+	return status, nil
+}
+
+// checkAllowlist checks that the address may be sent to. If the withdrawal
+// allowlist is enabled, the address must be in the address book for the asset,
+// and must have been added at least addressAllowlistDelay ago.
+func (c *Core) checkAllowlist(pw []byte, assetID uint32, address string) error {
+	status, err := c.allowlistStatus()
+	if err != nil {
+		return err
+	}
+	if !status.Enabled {
+		return nil
+	}
+	crypter, err := c.encryptionKey(pw)
+	if err != nil {
+		return newError(passwordErr, "password error: %w", err)
+	}
+	entries, _, err := c.addressBook(crypter)
+	crypter.Close()
+	if err != nil {
+		return err
+	}
+	var entry *AddressBookEntry
+	for _, e := range entries {
+		if e.AssetID == assetID && e.Address == address {
+			entry = e
+			break
+		}
+	}
+	if entry == nil {
+		return newError(allowlistErr, "%s address %s is not in the address book", unbip(assetID), address)
+	}
+	if entry.ActiveAt > uint64(time.Now().UnixMilli()) {
+		return newError(allowlistErr, "%s address %s cannot be used until %s", unbip(assetID), address,
+			time.UnixMilli(int64(entry.ActiveAt)).Format(time.RFC3339))
+	}
+	return nil
+}
+
+// AllowlistedSend is like Send, but if the withdrawal allowlist is enabled,
+// the address must be in the address book for the asset, and must have been
+// added at least addressAllowlistDelay ago. AllowlistedSend is intended for
+// automation clients such as the RPC server.
+func (c *Core) AllowlistedSend(pw []byte, assetID uint32, value uint64, address string, subtract bool) (asset.Coin, error) {
+	if err := c.checkAllowlist(pw, assetID, address); err != nil {
+		return nil, err
+	}
+	return c.Send(pw, assetID, value, address, subtract)
+}
+
+// AllowlistedBCHRecoveryTransaction is like GenerateBCHRecoveryTransaction,
+// but the recipient is subject to the same withdrawal allowlist checks as
+// AllowlistedSend.
+func (c *Core) AllowlistedBCHRecoveryTransaction(appPW []byte, recipient string) ([]byte, error) {
+	const bipID = 145
+	if err := c.checkAllowlist(appPW, bipID, recipient); err != nil {
+		return nil, err
+	}
+	return c.GenerateBCHRecoveryTransaction(appPW, recipient)
+}
@@ -0,0 +1,148 @@
+//go:build !harness && !botlive
+
+package core
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"decred.org/dcrdex/dex/encode"
+)
+
+func TestAddressBook(t *testing.T) {
+	rig := newTestRig()
+	defer rig.shutdown()
+	tCore := rig.core
+	wallet, tWallet := newTWallet(tUTXOAssetA.ID)
+	tCore.wallets[tUTXOAssetA.ID] = wallet
+	tWallet.sendCoin = &tCoin{id: encode.RandomBytes(36)}
+	tWallet.validAddr = true
+
+	const addr, addr2 = "addr", "addr2"
+	assetID := tUTXOAssetA.ID
+
+	checkAllowlistErr := func(err error) {
+		t.Helper()
+		var coreErr *Error
+		if !errors.As(err, &coreErr) || coreErr.code != allowlistErr {
+			t.Fatalf("expected an allowlist error, got %v", err)
+		}
+	}
+
+	// Anything goes with the allowlist disabled.
+	if _, err := tCore.AllowlistedSend(tPW, assetID, 1e8, addr, false); err != nil {
+		t.Fatalf("AllowlistedSend error with allowlist disabled: %v", err)
+	}
+
+	entry, err := tCore.AddAddress(tPW, assetID, addr, "cold storage")
+	if err != nil {
+		t.Fatalf("AddAddress error: %v", err)
+	}
+	if entry.ActiveAt < entry.Stamp+uint64(addressAllowlistDelay.Milliseconds()) {
+		t.Fatalf("new address active before the delay")
+	}
+
+	// Adding the same address only updates the label.
+	entry2, err := tCore.AddAddress(tPW, assetID, addr, "vault")
+	if err != nil {
+		t.Fatalf("AddAddress error: %v", err)
+	}
+	if !entry2.ID.Equal(entry.ID) || entry2.ActiveAt != entry.ActiveAt {
+		t.Fatalf("existing address was not updated")
+	}
+	book, err := tCore.AddressBook(tPW)
+	if err != nil {
+		t.Fatalf("AddressBook error: %v", err)
+	}
+	if len(book) != 1 || book[0].Address != addr || book[0].Label != "vault" {
+		t.Fatalf("wrong address book: %+v", book)
+	}
+
+	// Invalid address.
+	tWallet.validAddr = false
+	if _, err := tCore.AddAddress(tPW, assetID, addr2, ""); err == nil {
+		t.Fatalf("no error for invalid address")
+	}
+	tWallet.validAddr = true
+
+	// Bad password.
+	rig.crypter.(*tCrypter).recryptErr = tErr
+	if _, err := tCore.AddAddress(tPW, assetID, addr2, ""); err == nil {
+		t.Fatalf("no error for bad password")
+	}
+	if _, err := tCore.SetAllowlist(tPW, false); err == nil {
+		t.Fatalf("no error for bad password")
+	}
+	rig.crypter.(*tCrypter).recryptErr = nil
+
+	status, err := tCore.SetAllowlist(tPW, true)
+	if err != nil {
+		t.Fatalf("SetAllowlist error: %v", err)
+	}
+	if !status.Enabled || status.DisableAt != 0 {
+		t.Fatalf("allowlist not enabled: %+v", status)
+	}
+
+	// The address is not yet active.
+	_, err = tCore.AllowlistedSend(tPW, assetID, 1e8, addr, false)
+	checkAllowlistErr(err)
+	// Unknown address.
+	_, err = tCore.AllowlistedSend(tPW, assetID, 1e8, addr2, false)
+	checkAllowlistErr(err)
+	// Wrong asset.
+	_, err = tCore.AllowlistedSend(tPW, tUTXOAssetB.ID, 1e8, addr, false)
+	checkAllowlistErr(err)
+	// The BCH SPV recovery transaction is also restricted.
+	_, err = tCore.AllowlistedBCHRecoveryTransaction(tPW, addr2)
+	checkAllowlistErr(err)
+
+	rig.db.addressBook[0].ActiveAt = uint64(time.Now().UnixMilli()) - 1
+	if _, err := tCore.AllowlistedSend(tPW, assetID, 1e8, addr, false); err != nil {
+		t.Fatalf("AllowlistedSend error for allowlisted address: %v", err)
+	}
+
+	// Disabling is delayed.
+	status, err = tCore.SetAllowlist(tPW, false)
+	if err != nil {
+		t.Fatalf("SetAllowlist error: %v", err)
+	}
+	if !status.Enabled || status.DisableAt == 0 {
+		t.Fatalf("allowlist disable not delayed: %+v", status)
+	}
+	_, err = tCore.AllowlistedSend(tPW, assetID, 1e8, addr2, false)
+	checkAllowlistErr(err)
+
+	// Enabling again cancels the pending disable.
+	if status, err = tCore.SetAllowlist(tPW, true); err != nil {
+		t.Fatalf("SetAllowlist error: %v", err)
+	}
+	if !status.Enabled || status.DisableAt != 0 {
+		t.Fatalf("pending disable not canceled: %+v", status)
+	}
+
+	// The allowlist is disabled once the delay has passed.
+	if _, err = tCore.SetAllowlist(tPW, false); err != nil {
+		t.Fatalf("SetAllowlist error: %v", err)
+	}
+	rig.db.allowlist.DisableAt = uint64(time.Now().UnixMilli()) - 1
+	if status, err = tCore.Allowlist(); err != nil {
+		t.Fatalf("Allowlist error: %v", err)
+	}
+	if status.Enabled {
+		t.Fatalf("allowlist still enabled after disable time")
+	}
+	if _, err := tCore.AllowlistedSend(tPW, assetID, 1e8, addr2, false); err != nil {
+		t.Fatalf("AllowlistedSend error after allowlist disabled: %v", err)
+	}
+
+	if err := tCore.RemoveAddress(tPW, entry.ID); err != nil {
+		t.Fatalf("RemoveAddress error: %v", err)
+	}
+	if book, _ = tCore.AddressBook(tPW); len(book) != 0 {
+		t.Fatalf("address not removed")
+	}
+	if err := tCore.RemoveAddress(tPW, entry.ID); err == nil {
+		t.Fatalf("no error removing unknown address")
+	}
+}
@@ -0,0 +1,138 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package core
+
+import (
+	"fmt"
+
+	"decred.org/dcrdex/dex/order"
+)
+
+// findTrade finds the dexConnection and trackedTrade for a trade order.
+func (c *Core) findTrade(oid order.OrderID) (*dexConnection, *trackedTrade) {
+	for _, dc := range c.dexConnections() {
+		if tracker, isCancel := dc.findOrder(oid); tracker != nil && !isCancel {
+			return dc, tracker
+		}
+	}
+	return nil, nil
+}
+
+// abandonTradeRequest releases the resources of a prepared trade request that
+// will not be sent.
+func (c *Core) abandonTradeRequest(tr *tradeRequest) {
+	tr.errCloser.Done(c.log)
+	c.sentCommitsMtx.Lock()
+	delete(c.sentCommits, tr.dbOrder.Order.Prefix().Commit)
+	c.sentCommitsMtx.Unlock()
+	close(tr.commitSig)
+}
+
+// AmendOrder replaces a standing limit order with a new order on the same
+// market and side but with a different rate and/or quantity. The replacement
+// is prepared first, and then a cancel order for the original and the
+// replacement are sent back to back so that both land in the same epoch. The
+// replacement never reuses the original's funding coins, not even for
+// account-based assets. The original may still be matched in the epoch before
+// the cancel executes, and both orders would then draw on the same locked
+// funds, so the original's funding remains locked until the cancel executes.
+// If the cancel order is rejected, the replacement is not sent. The original
+// and the replacement are linked through their AmendedBy and AmendedFrom
+// fields, so that the order history shows the chain of amendments.
+func (c *Core) AmendOrder(pw []byte, form *AmendForm) (*Order, error) {
+	oid, err := order.IDFromBytes(form.OrderID)
+	if err != nil {
+		return nil, err
+	}
+
+	dc, tracker := c.findTrade(oid)
+	if tracker == nil {
+		return nil, newError(unknownOrderErr, "active order %s not found", oid)
+	}
+	lo, ok := tracker.Order.(*order.LimitOrder)
+	if !ok || lo.Force != order.StandingTiF {
+		return nil, newError(orderParamsErr, "cannot amend %s order %s that is not a standing limit order", tracker.Type(), oid)
+	}
+	mktConf := dc.marketConfig(tracker.mktID)
+	if mktConf == nil {
+		return nil, newError(marketErr, "unknown market %q", tracker.mktID)
+	}
+
+	tracker.mtx.RLock()
+	status := tracker.metaData.Status
+	cancelling := tracker.cancel != nil
+	remaining := lo.Remaining()
+	options := tracker.options
+	tracker.mtx.RUnlock()
+
+	if status != order.OrderStatusEpoch && status != order.OrderStatusBooked {
+		return nil, newError(orderParamsErr, "order %s not amendable in status %v", oid, status)
+	}
+	if cancelling {
+		return nil, newError(orderParamsErr, "order %s already has a cancel order pending", oid)
+	}
+
+	rate, qty := form.Rate, form.Qty
+	if rate == 0 {
+		rate = lo.Rate
+	}
+	if qty == 0 {
+		qty = remaining
+	}
+	if rate == lo.Rate && qty == remaining {
+		return nil, newError(orderParamsErr, "amendment does not change order %s", oid)
+	}
+
+	tradeForm := &TradeForm{
+		Host:    dc.acct.host,
+		IsLimit: true,
+		Sell:    lo.Sell,
+		Base:    lo.BaseAsset,
+		Quote:   lo.QuoteAsset,
+		Qty:     qty,
+		Rate:    rate,
+		Options: options,
+	}
+	req, err := c.prepareTradeRequest(pw, tradeForm)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.tryCancelTrade(dc, tracker); err != nil {
+		c.abandonTradeRequest(req)
+		return nil, err
+	}
+
+	tracker.mtx.RLock()
+	co := tracker.cancel
+	tracker.mtx.RUnlock()
+
+	// The replacement is stored with the link to the original.
+	req.dbOrder.MetaData.AmendedFrom = oid
+	corder, err := c.sendTradeRequest(req)
+	if err != nil {
+		return nil, fmt.Errorf("cancel order submitted for %s, but the replacement order failed: %w", oid, err)
+	}
+
+	newID := req.dbOrder.Order.ID()
+	tracker.mtx.Lock()
+	tracker.metaData.AmendedBy = newID
+	err = tracker.db.UpdateOrderMetaData(oid, tracker.metaData)
+	tracker.mtx.Unlock()
+	if err != nil {
+		c.log.Errorf("Error linking amended order %s to replacement order %s: %v", oid, newID, err)
+	}
+
+	if co != nil {
+		if cancelEpoch, newEpoch := uint64(co.ServerTime.UnixMilli())/co.epochLen,
+			uint64(req.dbOrder.Order.Time())/mktConf.EpochLen; cancelEpoch != newEpoch {
+			c.log.Warnf("Cancel order %s (epoch %d) and replacement order %s (epoch %d) for amended order %s are in different epochs",
+				co.ID(), cancelEpoch, newID, newEpoch, oid)
+		}
+	}
+
+	c.log.Infof("Order %s amended at %s. Replacement order: %s", oid, dc.acct.host, newID)
+
+	return corder, nil
+}
@@ -0,0 +1,212 @@
+//go:build !harness && !botlive
+
+package core
+
+import (
+	"testing"
+
+	"decred.org/dcrdex/client/asset"
+	"decred.org/dcrdex/client/db"
+	"decred.org/dcrdex/dex"
+	"decred.org/dcrdex/dex/calc"
+	"decred.org/dcrdex/dex/encode"
+	"decred.org/dcrdex/dex/msgjson"
+	"decred.org/dcrdex/dex/order"
+)
+
+// tRecoveryCoin is an account-based funding coin.
+type tRecoveryCoin struct {
+	*tCoin
+}
+
+func (c *tRecoveryCoin) RecoveryID() dex.Bytes {
+	return c.id
+}
+
+func TestAmendOrder(t *testing.T) {
+	rig := newTestRig()
+	defer rig.shutdown()
+	tCore := rig.core
+	dc := rig.dc
+
+	dcrWallet, tDcrWallet := newTWallet(tUTXOAssetA.ID)
+	tCore.wallets[tUTXOAssetA.ID] = dcrWallet
+	dcrWallet.Unlock(rig.crypter)
+	btcWallet, _ := newTWallet(tUTXOAssetB.ID)
+	tCore.wallets[tUTXOAssetB.ID] = btcWallet
+	btcWallet.Unlock(rig.crypter)
+	ethWallet, tEthWallet := newTAccountLocker(tACCTAsset.ID)
+	tCore.wallets[tACCTAsset.ID] = ethWallet
+	ethWallet.Unlock(rig.crypter)
+
+	handleLimit := func(msg *msgjson.Message, f msgFunc) error {
+		msgOrder := new(msgjson.LimitOrder)
+		if err := msg.Unmarshal(msgOrder); err != nil {
+			t.Fatalf("unmarshal error: %v", err)
+		}
+		f(orderResponse(msg.ID, msgOrder, convertMsgLimitOrder(msgOrder), false, false, false))
+		return nil
+	}
+
+	const lots = 10
+	qty := dcrBtcLotSize * lots
+	rate := dcrBtcRateStep * 1000
+
+	addTracker := func(base, quote uint32, sell bool, coin asset.Coin) *trackedTrade {
+		t.Helper()
+		lo, dbOrder, preImg, _ := makeLimitOrder(dc, sell, qty, rate)
+		lo.BaseAsset, lo.QuoteAsset = base, quote
+		lo.Force = order.StandingTiF
+		lo.Coins = []order.CoinID{order.CoinID(coin.ID())}
+		dbOrder.MetaData.Status = order.OrderStatusBooked
+		wallets, _, _, err := tCore.walletSet(dc, base, quote, sell)
+		if err != nil {
+			t.Fatalf("walletSet error: %v", err)
+		}
+		tracker := newTrackedTrade(dbOrder, preImg, dc, tCore.lockTimeTaker, tCore.lockTimeMaker,
+			rig.db, rig.queue, wallets, asset.Coins{coin}, tCore.notify, tCore.formatDetails)
+		if _, is := coin.(asset.RecoveryCoin); is {
+			tracker.change = coin
+			tracker.coinsLocked = false
+			tracker.changeLocked = true
+		}
+		dc.tradeMtx.Lock()
+		dc.trades[tracker.ID()] = tracker
+		dc.tradeMtx.Unlock()
+		return tracker
+	}
+
+	checkAmended := func(tracker *trackedTrade, newOrd *Order, wantRate, wantQty uint64) *trackedTrade {
+		t.Helper()
+		if tracker.cancel == nil {
+			t.Fatalf("original order not canceled")
+		}
+		newID, _ := order.IDFromBytes(newOrd.ID)
+		newTracker, _ := dc.findOrder(newID)
+		if newTracker == nil {
+			t.Fatalf("replacement order not tracked")
+		}
+		newLo := newTracker.Order.(*order.LimitOrder)
+		if newLo.Rate != wantRate || newLo.Quantity != wantQty || newLo.Sell != tracker.Trade().Sell {
+			t.Fatalf("wrong replacement order. rate %d, qty %d, sell %t", newLo.Rate, newLo.Quantity, newLo.Sell)
+		}
+		if tracker.metaData.AmendedBy != newID || newTracker.metaData.AmendedFrom != tracker.ID() {
+			t.Fatalf("original order not linked to replacement. linked %s -> %s",
+				tracker.metaData.AmendedBy, newTracker.metaData.AmendedFrom)
+		}
+		if !newOrd.AmendedFrom.Equal(tracker.ID().Bytes()) {
+			t.Fatalf("replacement order does not show the amended order")
+		}
+		// The cancel order remains linked to the original.
+		if rig.db.linkedFromID != tracker.ID() || rig.db.linkedToID != tracker.cancel.ID() {
+			t.Fatalf("original not linked to cancel order. linked %s -> %s", rig.db.linkedFromID, rig.db.linkedToID)
+		}
+		return newTracker
+	}
+
+	// UTXO-based orders are funded with new coins.
+	dcrCoin := &tCoin{id: encode.RandomBytes(36), val: qty * 2}
+	tracker := addTracker(tUTXOAssetA.ID, tUTXOAssetB.ID, true, &tCoin{id: encode.RandomBytes(36), val: qty})
+	tDcrWallet.fundingCoins = asset.Coins{dcrCoin}
+	tDcrWallet.fundRedeemScripts = []dex.Bytes{nil}
+	newRate := rate + dcrBtcRateStep
+	rig.queueCancel(nil)
+	rig.ws.queueResponse(msgjson.LimitRoute, handleLimit)
+	newOrd, err := tCore.AmendOrder(tPW, &AmendForm{OrderID: tracker.ID().Bytes(), Rate: newRate})
+	if err != nil {
+		t.Fatalf("AmendOrder error: %v", err)
+	}
+	if tDcrWallet.fundedVal != qty {
+		t.Fatalf("replacement not funded. funded %d", tDcrWallet.fundedVal)
+	}
+	newTracker := checkAmended(tracker, newOrd, newRate, qty)
+	if _, found := newTracker.coins[dcrCoin.String()]; !found {
+		t.Fatalf("replacement not funded with new coins")
+	}
+
+	// The order history shows the chain of amendments.
+	rig.db.orders = []*db.MetaOrder{
+		{MetaData: tracker.metaData, Order: tracker.Order},
+		{MetaData: newTracker.metaData, Order: newTracker.Order},
+	}
+	ords, err := tCore.Orders(&OrderFilter{N: 10})
+	if err != nil {
+		t.Fatalf("Orders error: %v", err)
+	}
+	if len(ords) != 2 {
+		t.Fatalf("expected 2 orders, got %d", len(ords))
+	}
+	if !ords[0].AmendedBy.Equal(newOrd.ID) || ords[0].AmendedFrom != nil ||
+		!ords[1].AmendedFrom.Equal(ords[0].ID) || ords[1].AmendedBy != nil {
+		t.Fatalf("wrong amendment chain %s -> %s, %s -> %s", ords[0].AmendedFrom, ords[0].AmendedBy,
+			ords[1].AmendedFrom, ords[1].AmendedBy)
+	}
+
+	// A pending cancel prevents another amendment.
+	if _, err := tCore.AmendOrder(tPW, &AmendForm{OrderID: tracker.ID().Bytes(), Rate: rate}); err == nil {
+		t.Fatalf("no error amending an order being canceled")
+	}
+
+	// The amendment must change something.
+	tracker = addTracker(tUTXOAssetA.ID, tUTXOAssetB.ID, true, &tCoin{id: encode.RandomBytes(36), val: qty})
+	if _, err := tCore.AmendOrder(tPW, &AmendForm{OrderID: tracker.ID().Bytes(), Rate: rate}); err == nil {
+		t.Fatalf("no error for a no-op amendment")
+	}
+
+	// Unknown order.
+	if _, err := tCore.AmendOrder(tPW, &AmendForm{OrderID: encode.RandomBytes(32), Rate: rate}); err == nil {
+		t.Fatalf("no error for unknown order")
+	}
+
+	// A rejected cancel leaves the original order alone, and the replacement
+	// is not sent.
+	tDcrWallet.returnedCoins = nil
+	rig.queueCancel(msgjson.NewError(msgjson.RPCInternalError, "test error"))
+	if _, err := tCore.AmendOrder(tPW, &AmendForm{OrderID: tracker.ID().Bytes(), Rate: newRate}); err == nil {
+		t.Fatalf("no error for rejected cancel")
+	}
+	if tracker.cancel != nil {
+		t.Fatalf("cancel order set after rejected cancel")
+	}
+	if len(tDcrWallet.returnedCoins) != 1 || !tDcrWallet.returnedCoins[0].ID().Equal(dcrCoin.id) {
+		t.Fatalf("replacement funding coins not returned")
+	}
+	if n := len(dc.trackedTrades()); n != 3 {
+		t.Fatalf("replacement order tracked after rejected cancel. %d trades", n)
+	}
+
+	// Account-based replacements are funded independently. The original keeps
+	// its funding until the cancel executes. A buy on the btc_eth market is
+	// funded with eth.
+	ethCoin := &tRecoveryCoin{&tCoin{id: encode.RandomBytes(20), val: calc.BaseToQuote(rate, qty) * 2}}
+	ethCoin2 := &tRecoveryCoin{&tCoin{id: encode.RandomBytes(20), val: calc.BaseToQuote(rate, qty)}}
+	tracker = addTracker(tUTXOAssetB.ID, tACCTAsset.ID, false, ethCoin)
+	tEthWallet.fundingCoins = asset.Coins{ethCoin2}
+	tEthWallet.fundRedeemScripts = []dex.Bytes{nil}
+	rig.queueCancel(nil)
+	rig.ws.queueResponse(msgjson.LimitRoute, handleLimit)
+	newQty := qty / 2
+	newOrd, err = tCore.AmendOrder(tPW, &AmendForm{OrderID: tracker.ID().Bytes(), Qty: newQty})
+	if err != nil {
+		t.Fatalf("AmendOrder error: %v", err)
+	}
+	if tEthWallet.fundedVal != calc.BaseToQuote(rate, newQty) {
+		t.Fatalf("account-based replacement not funded. funded %d", tEthWallet.fundedVal)
+	}
+	newTracker = checkAmended(tracker, newOrd, rate, newQty)
+	if !tracker.changeLocked || tracker.change != ethCoin || newTracker.change != ethCoin2 {
+		t.Fatalf("original funding should not be transferred to the replacement")
+	}
+
+	// The original is matched before the cancel executes. It still has its
+	// own funding for the match.
+	tracker.mtx.Lock()
+	tracker.Trade().AddFill(qty)
+	tracker.mtx.Unlock()
+	if !tracker.changeLocked || tracker.change != ethCoin {
+		t.Fatalf("matched original lost its funding")
+	}
+	if newTracker.change == tracker.change {
+		t.Fatalf("matched original and replacement share funding")
+	}
+}
@@ -0,0 +1,115 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package core
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"decred.org/dcrdex/client/db"
+	"decred.org/dcrdex/dex/encode"
+)
+
+// apiKeySecretSize is the number of random bytes in an API key secret.
+const apiKeySecretSize = 32
+
+// APIKeys returns the client RPC server API keys, sorted by the time they were
+// created.
+func (c *Core) APIKeys() ([]*APIKey, error) {
+	dbKeys, err := c.db.APIKeys()
+	if err != nil {
+		return nil, newError(dbErr, "error retrieving API keys: %w", err)
+	}
+	keys := make([]*APIKey, 0, len(dbKeys))
+	for _, k := range dbKeys {
+		keys = append(keys, &APIKey{
+			Name:      k.Name,
+			Hash:      k.Hash,
+			Scopes:    k.Scopes,
+			RateLimit: k.RateLimit,
+			Stamp:     k.Stamp,
+		})
+	}
+	return keys, nil
+}
+
+// CreateAPIKey creates a new client RPC server API key with the specified
+// permission scopes and rate limit, in requests per minute. The hex-encoded
+// secret is returned and cannot be retrieved again. Only its hash is stored.
+func (c *Core) CreateAPIKey(pw []byte, name string, scopes []string, rateLimit uint32) (string, *APIKey, error) {
+	crypter, err := c.encryptionKey(pw)
+	if err != nil {
+		return "", nil, newError(passwordErr, "password error: %w", err)
+	}
+	crypter.Close()
+
+	// The name is the user name for HTTP basic authentication, so it cannot
+	// contain a colon.
+	if name == "" || strings.ContainsAny(name, ": \t\r\n") {
+		return "", nil, newError(apiKeyErr, "invalid API key name %q", name)
+	}
+	if len(scopes) == 0 {
+		return "", nil, newError(apiKeyErr, "no scopes specified for API key %q", name)
+	}
+	for _, scope := range scopes {
+		if scope == "" || strings.Contains(scope, ",") {
+			return "", nil, newError(apiKeyErr, "invalid API key scope %q", scope)
+		}
+	}
+
+	c.apiKeyMtx.Lock()
+	defer c.apiKeyMtx.Unlock()
+
+	keys, err := c.db.APIKeys()
+	if err != nil {
+		return "", nil, newError(dbErr, "error retrieving API keys: %w", err)
+	}
+	for _, k := range keys {
+		if k.Name == name {
+			return "", nil, newError(apiKeyErr, "API key %q already exists", name)
+		}
+	}
+
+	secret := hex.EncodeToString(encode.RandomBytes(apiKeySecretSize))
+	hash := sha256.Sum256([]byte(secret))
+	dbKey := &db.APIKey{
+		Name:      name,
+		Hash:      hash[:],
+		Scopes:    scopes,
+		RateLimit: rateLimit,
+		Stamp:     uint64(time.Now().UnixMilli()),
+	}
+	if err := c.db.StoreAPIKey(dbKey); err != nil {
+		return "", nil, newError(dbErr, "error storing API key: %w", err)
+	}
+
+	c.log.Infof("Created API key %q with scopes %v", name, scopes)
+
+	return secret, &APIKey{
+		Name:      name,
+		Hash:      dbKey.Hash,
+		Scopes:    scopes,
+		RateLimit: rateLimit,
+		Stamp:     dbKey.Stamp,
+	}, nil
+}
+
+// RevokeAPIKey deletes the client RPC server API key with the specified name.
+func (c *Core) RevokeAPIKey(pw []byte, name string) error {
+	crypter, err := c.encryptionKey(pw)
+	if err != nil {
+		return newError(passwordErr, "password error: %w", err)
+	}
+	crypter.Close()
+
+	c.apiKeyMtx.Lock()
+	defer c.apiKeyMtx.Unlock()
+	if err := c.db.DeleteAPIKey(name); err != nil {
+		return newError(apiKeyErr, "error revoking API key: %w", err)
+	}
+	c.log.Infof("Revoked API key %q", name)
+	return nil
+}
@@ -0,0 +1,71 @@
+//go:build !harness && !botlive
+
+package core
+
+import (
+	"crypto/sha256"
+	"testing"
+)
+
+func TestAPIKeys(t *testing.T) {
+	rig := newTestRig()
+	defer rig.shutdown()
+	tCore := rig.core
+
+	secret, key, err := tCore.CreateAPIKey(tPW, "monitor", []string{"read"}, 60)
+	if err != nil {
+		t.Fatalf("CreateAPIKey error: %v", err)
+	}
+	hash := sha256.Sum256([]byte(secret))
+	if len(secret) != apiKeySecretSize*2 || !key.Hash.Equal(hash[:]) {
+		t.Fatalf("wrong API key secret hash")
+	}
+	if len(rig.db.apiKeys) != 1 || rig.db.apiKeys[0].RateLimit != 60 {
+		t.Fatalf("API key not stored")
+	}
+
+	for _, tt := range []struct {
+		name   string
+		scopes []string
+	}{
+		{"monitor", []string{"read"}}, // duplicate
+		{"", []string{"read"}},
+		{"mon:itor", []string{"read"}},
+		{"trader", nil},
+		{"trader", []string{"read,trade"}},
+	} {
+		if _, _, err := tCore.CreateAPIKey(tPW, tt.name, tt.scopes, 0); err == nil {
+			t.Fatalf("no error for API key %q with scopes %v", tt.name, tt.scopes)
+		}
+	}
+
+	rig.crypter.(*tCrypter).recryptErr = tErr
+	if _, _, err := tCore.CreateAPIKey(tPW, "trader", []string{"trade"}, 0); err == nil {
+		t.Fatalf("no error for bad password")
+	}
+	rig.crypter.(*tCrypter).recryptErr = nil
+
+	keys, err := tCore.APIKeys()
+	if err != nil {
+		t.Fatalf("APIKeys error: %v", err)
+	}
+	if len(keys) != 1 || keys[0].Name != "monitor" {
+		t.Fatalf("wrong API keys: %+v", keys)
+	}
+
+	rig.crypter.(*tCrypter).recryptErr = tErr
+	if err := tCore.RevokeAPIKey(tPW, "monitor"); err == nil {
+		t.Fatalf("no error revoking with a bad password")
+	}
+	rig.crypter.(*tCrypter).recryptErr = nil
+	if len(rig.db.apiKeys) != 1 {
+		t.Fatalf("API key revoked with a bad password")
+	}
+
+	if err := tCore.RevokeAPIKey(tPW, "monitor"); err != nil {
+		t.Fatalf("RevokeAPIKey error: %v", err)
+	}
+	if err := tCore.RevokeAPIKey(tPW, "monitor"); err == nil {
+		t.Fatalf("no error revoking unknown key")
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"maps"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -679,6 +680,54 @@ func (dc *dexConnection) setMarketFinalEpoch(name string, finalEpoch uint64, per
 	mkt.Persist = &persist
 }
 
+// setMarketParams revises the LotSize and RateStep fields of the named market in
+// the stored ConfigResponse. It returns false if the market is not found.
+func (dc *dexConnection) setMarketParams(name string, lotSize, rateStep uint64) bool {
+	dc.cfgMtx.Lock()
+	defer dc.cfgMtx.Unlock()
+	mkt := dc.findMarketConfig(name)
+	if mkt == nil {
+		return false
+	}
+	mkt.LotSize = lotSize
+	mkt.RateStep = rateStep
+	return true
+}
+
+// scheduleMarketParams applies a lot size and rate step change to the stored
+// ConfigResponse at the change's start time, replacing any change already
+// scheduled for the market. The server only sends the updated config some time
+// after the change takes effect, and orders placed in between must already use
+// the new lot size. Running bots are told through a ServerConfigUpdateNote.
+func (c *Core) scheduleMarketParams(dc *dexConnection, mp *msgjson.MarketParams) {
+	dc.paramTimersMtx.Lock()
+	defer dc.paramTimersMtx.Unlock()
+	if dc.paramTimers == nil {
+		dc.paramTimers = make(map[string]*time.Timer)
+	}
+	if t := dc.paramTimers[mp.MarketID]; t != nil {
+		t.Stop()
+	}
+	var t *time.Timer
+	t = time.AfterFunc(time.Until(time.UnixMilli(int64(mp.StartTime))), func() {
+		dc.paramTimersMtx.Lock()
+		if dc.paramTimers[mp.MarketID] != t {
+			dc.paramTimersMtx.Unlock()
+			return // replaced
+		}
+		delete(dc.paramTimers, mp.MarketID)
+		dc.paramTimersMtx.Unlock()
+
+		if !dc.setMarketParams(mp.MarketID, mp.LotSize, mp.RateStep) {
+			return // market removed
+		}
+		c.log.Infof("Market %s at %s now using lot size %d and rate step %d as of epoch %d.",
+			mp.MarketID, dc.acct.host, mp.LotSize, mp.RateStep, mp.StartEpoch)
+		c.notify(newServerConfigUpdateNote(dc.acct.host))
+	})
+	dc.paramTimers[mp.MarketID] = t
+}
+
 // handleTradeSuspensionMsg is called when a trade suspension notification is
 // received. This message may come in advance of suspension, in which case it
 // has a SuspendTime set, or at the time of suspension if subscribed to the
@@ -804,12 +853,8 @@ func handleTradeResumptionMsg(c *Core, dc *dexConnection, msg *msgjson.Message)
 	dc.epoch[rs.MarketID] = rs.StartEpoch
 	dc.epochMtx.Unlock()
 
-	// TODO: Server config change without restart is not implemented on the
-	// server, but it would involve either getting the config response or adding
-	// the entire market config to the TradeResumption payload.
-	//
-	// Fetch the updated DEX configuration.
-	// dc.refreshServerConfig()
+	// Market config changes made without a restart are sent by the server
+	// in a config notification. See handleConfigMsg.
 
 	subject, detail := c.formatDetails(TopicMarketResumed, rs.MarketID, dc.acct.host, rs.StartEpoch)
 	c.notify(newServerNotifyNote(TopicMarketResumed, subject, detail, db.Success))
@@ -828,7 +873,15 @@ func (dc *dexConnection) refreshServerConfig() (*msgjson.ConfigResult, error) {
 	if err != nil {
 		return nil, fmt.Errorf("unable to fetch server config: %w", err)
 	}
+	if err = dc.applyServerConfig(cfg); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
 
+// applyServerConfig replaces the server configuration data. It also checks
+// that the server's API version is one of supportedAPIVers.
+func (dc *dexConnection) applyServerConfig(cfg *msgjson.ConfigResult) error {
 	apiVer := int32(cfg.APIVersion)
 	dc.log.Infof("Server %v supports API version %v.", dc.acct.host, cfg.APIVersion)
 	atomic.StoreInt32(&dc.apiVer, apiVer)
@@ -845,7 +898,7 @@ func (dc *dexConnection) refreshServerConfig() (*msgjson.ConfigResult, error) {
 		if apiVer > supportedAPIVers[len(supportedAPIVers)-1] {
 			err = fmt.Errorf("%v: %w", err, outdatedClientErr)
 		}
-		return nil, err
+		return err
 	}
 
 	bTimeout := time.Millisecond * time.Duration(cfg.BroadcastTimeout)
@@ -863,7 +916,7 @@ func (dc *dexConnection) refreshServerConfig() (*msgjson.ConfigResult, error) {
 
 	assets, epochs, err := generateDEXMaps(dc.acct.host, cfg)
 	if err != nil {
-		return nil, fmt.Errorf("inconsistent 'config' response: %w", err)
+		return fmt.Errorf("inconsistent 'config' response: %w", err)
 	}
 
 	// Update dc.{epoch,assets}
@@ -879,7 +932,7 @@ func (dc *dexConnection) refreshServerConfig() (*msgjson.ConfigResult, error) {
 	if dc.acct.dexPubKey == nil && len(cfg.DEXPubKey) > 0 {
 		dc.acct.dexPubKey, err = secp256k1.ParsePubKey(cfg.DEXPubKey)
 		if err != nil {
-			return nil, fmt.Errorf("error decoding secp256k1 PublicKey from bytes: %w", err)
+			return fmt.Errorf("error decoding secp256k1 PublicKey from bytes: %w", err)
 		}
 	}
 
@@ -888,7 +941,47 @@ func (dc *dexConnection) refreshServerConfig() (*msgjson.ConfigResult, error) {
 	dc.resolvedEpoch = utils.CopyMap(epochs)
 	dc.epochMtx.Unlock()
 
-	return cfg, nil
+	return nil
+}
+
+// handleConfigMsg is called when a config notification is received. The server
+// sends its updated configuration when markets are added or retired without a
+// restart.
+func handleConfigMsg(c *Core, dc *dexConnection, msg *msgjson.Message) error {
+	cfg := new(msgjson.ConfigResult)
+	if err := msg.Unmarshal(cfg); err != nil {
+		return fmt.Errorf("config note unmarshal error: %w", err)
+	}
+	if err := dc.applyServerConfig(cfg); err != nil {
+		return fmt.Errorf("error applying config from %s: %w", dc.acct.host, err)
+	}
+	c.notify(newServerConfigUpdateNote(dc.acct.host))
+	return nil
+}
+
+// handleMarketParamsMsg is called when a market_params notification is
+// received. The server announces a change to a market's lot size and rate step
+// ahead of time, and the change is applied to the market config at the
+// announced start time.
+func handleMarketParamsMsg(c *Core, dc *dexConnection, msg *msgjson.Message) error {
+	mp := new(msgjson.MarketParams)
+	if err := msg.Unmarshal(mp); err != nil {
+		return fmt.Errorf("market params note unmarshal error: %w", err)
+	}
+	mkt := dc.marketConfig(mp.MarketID)
+	if mkt == nil {
+		return fmt.Errorf("no market found with ID %s", mp.MarketID)
+	}
+	lotSize := strconv.FormatUint(mp.LotSize, 10)
+	if ui, err := asset.UnitInfo(mkt.Base); err == nil {
+		lotSize = ui.FormatConventional(mp.LotSize)
+	}
+	startTime := time.UnixMilli(int64(mp.StartTime))
+	subject, detail := c.formatDetails(TopicMarketParamsScheduled, mp.MarketID, dc.acct.host,
+		lotSize, mp.RateStep, startTime)
+	c.notify(newMarketParamsNote(subject, detail, dc.acct.host, mp))
+	c.scheduleMarketParams(dc, mp)
+	return nil
 }
 
 // subPriceFeed subscribes to the price_feed notification feed and primes the
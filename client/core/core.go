@@ -199,6 +199,11 @@ type dexConnection struct {
 	spotsMtx sync.RWMutex
 	spots    map[string]*msgjson.Spot
 
+	// paramTimers apply the lot size and rate step changes announced by the
+	// server to the market configs when the changes start.
+	paramTimersMtx sync.Mutex
+	paramTimers    map[string]*time.Timer
+
 	// anomaliesCount tracks client's connection anomalies.
 	anomaliesCount uint32 // atomic
 	lastConnectMtx sync.RWMutex
@@ -1532,6 +1537,12 @@ type Core struct {
 
 	ratesMtx        sync.RWMutex
 	fiatRateSources map[string]*commonRateSource
+	// lastFiatSnapshot is the time stamp (unix ms) of the last fiat rate
+	// snapshot stored for the tax export.
+	lastFiatSnapshot atomic.Int64
+	// lastFiatPrune is the time stamp (unix ms) of the last downsampling of
+	// the stored fiat rate snapshots.
+	lastFiatPrune atomic.Int64
 
 	reFiat chan struct{}
 
@@ -1548,6 +1559,19 @@ type Core struct {
 	meshMtx sync.RWMutex
 	mesh    *mesh.Mesh
 	meshCM  *dex.ConnectionMaster
+
+	stopOrdersMtx sync.Mutex
+	stopOrders    map[string]*db.StopOrder // waiting, keyed by ID
+	stopWatchers  map[string]*stopWatcher  // keyed by host and market
+
+	// addressBookMtx serializes changes to the address book and allowlist.
+	addressBookMtx sync.Mutex
+
+	// apiKeyMtx serializes changes to the RPC server API keys.
+	apiKeyMtx sync.Mutex
+
+	scheduledOrdersMtx sync.Mutex
+	scheduledOrders    map[string]*scheduledOrderRunner // active, keyed by ID
 }
 
 // New is the constructor for a new Core.
@@ -1686,6 +1710,10 @@ func New(cfg *Config) (*Core, error) {
 
 		notes:            make(chan asset.WalletNotification, 128),
 		requestedActions: make(map[string]*asset.ActionRequiredNote),
+
+		stopOrders:      make(map[string]*db.StopOrder),
+		stopWatchers:    make(map[string]*stopWatcher),
+		scheduledOrders: make(map[string]*scheduledOrderRunner),
 	}
 
 	c.intl.Store(&locale{
@@ -4702,6 +4730,8 @@ func (c *Core) Login(pw []byte) error {
 		c.connectMesh()
 		c.notify(newLoginNote("Connecting to DEX servers..."))
 		c.initializeDEXConnections(crypter)
+		c.resumeStopOrders()
+		c.resumeScheduledOrders()
 	}
 
 	return nil
@@ -4953,6 +4983,11 @@ func (c *Core) Logout() error {
 		return codedError(activeOrdersErr, ActiveOrdersLogoutErr)
 	}
 
+	// Stop watching stop orders and placing scheduled child orders before the
+	// wallets are locked. Both are resumed on the next login.
+	c.stopStopWatchers()
+	c.stopScheduledOrders()
+
 	// Lock wallets
 	if !c.cfg.NoAutoWalletLock {
 		// Ensure wallet lock in c.Run waits for c.Logout if this is called
@@ -9085,6 +9120,7 @@ var noteHandlers = map[string]routeHandler{
 	msgjson.EpochReportRoute:     handleEpochReportMsg,
 	msgjson.SuspensionRoute:      handleTradeSuspensionMsg,
 	msgjson.ResumptionRoute:      handleTradeResumptionMsg,
+	msgjson.MarketParamsRoute:    handleMarketParamsMsg,
 	msgjson.NotifyRoute:          handleNotifyMsg,
 	msgjson.PenaltyRoute:         handlePenaltyMsg,
 	msgjson.NoMatchRoute:         handleNoMatchRoute,
@@ -9093,6 +9129,7 @@ var noteHandlers = map[string]routeHandler{
 	msgjson.TierChangeRoute:      handleTierChangeMsg,
 	msgjson.ScoreChangeRoute:     handleScoreChangeMsg,
 	msgjson.BondExpiredRoute:     handleBondExpiredMsg,
+	msgjson.ConfigRoute:          handleConfigMsg,
 }
 
 // listen monitors the DEX websocket connection for server requests and
@@ -10724,6 +10761,7 @@ func (c *Core) refreshFiatRates(ctx context.Context) {
 	fiatRatesMap := c.fiatConversions()
 	if len(fiatRatesMap) != 0 {
 		c.notify(newFiatRatesUpdate(fiatRatesMap))
+		c.storeFiatRateSnapshot(fiatRatesMap)
 	}
 }
 
@@ -14,6 +14,7 @@ import (
 	mrand "math/rand/v2"
 	"os"
 	"reflect"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
@@ -387,6 +388,16 @@ type TDB struct {
 	deleteInactiveMatchesErr error
 	archivedMatches          int
 	updateAccountInfoErr     error
+	stopOrdersMtx            sync.Mutex
+	stopOrders               map[string]*db.StopOrder
+	scheduledOrdersMtx       sync.Mutex
+	scheduledOrders          map[string]*db.ScheduledOrder
+	orders                   []*db.MetaOrder
+	matchesByOID             map[order.OrderID][]*db.MetaMatch
+	fiatSnapshots            []*db.FiatRateSnapshot
+	addressBook              []*db.AddressBookEntry
+	allowlist                db.AllowlistSetting
+	apiKeys                  []*db.APIKey
 }
 
 func (tdb *TDB) Run(context.Context) {}
@@ -463,7 +474,7 @@ func (tdb *TDB) Order(oid order.OrderID) (*db.MetaOrder, error) {
 }
 
 func (tdb *TDB) Orders(*db.OrderFilter) ([]*db.MetaOrder, error) {
-	return nil, nil
+	return tdb.orders, nil
 }
 
 func (tdb *TDB) MarketOrders(dex string, base, quote uint32, n int, since uint64) ([]*db.MetaOrder, error) {
@@ -498,6 +509,9 @@ func (tdb *TDB) ActiveMatches() ([]*db.MetaMatch, error) {
 }
 
 func (tdb *TDB) MatchesForOrder(oid order.OrderID, excludeCancels bool) ([]*db.MetaMatch, error) {
+	if mms, found := tdb.matchesByOID[oid]; found {
+		return mms, nil
+	}
 	return tdb.matchesForOID, tdb.matchesForOIDErr
 }
 
@@ -590,6 +604,143 @@ func (tdb *TDB) Language() (string, error) {
 	return "en-US", nil
 }
 
+func (tdb *TDB) UpdateStopOrder(so *db.StopOrder) error {
+	tdb.stopOrdersMtx.Lock()
+	defer tdb.stopOrdersMtx.Unlock()
+	if tdb.stopOrders == nil {
+		tdb.stopOrders = make(map[string]*db.StopOrder)
+	}
+	soCopy := *so
+	tdb.stopOrders[so.ID.String()] = &soCopy
+	return nil
+}
+
+func (tdb *TDB) StopOrders(activeOnly bool) ([]*db.StopOrder, error) {
+	tdb.stopOrdersMtx.Lock()
+	defer tdb.stopOrdersMtx.Unlock()
+	sos := make([]*db.StopOrder, 0, len(tdb.stopOrders))
+	for _, so := range tdb.stopOrders {
+		if activeOnly && so.Status != db.StopOrderWaiting {
+			continue
+		}
+		soCopy := *so
+		sos = append(sos, &soCopy)
+	}
+	return sos, nil
+}
+
+func (tdb *TDB) UpdateScheduledOrder(so *db.ScheduledOrder) error {
+	tdb.scheduledOrdersMtx.Lock()
+	defer tdb.scheduledOrdersMtx.Unlock()
+	if tdb.scheduledOrders == nil {
+		tdb.scheduledOrders = make(map[string]*db.ScheduledOrder)
+	}
+	soCopy := *so
+	soCopy.ChildIDs = slices.Clone(so.ChildIDs)
+	tdb.scheduledOrders[so.ID.String()] = &soCopy
+	return nil
+}
+
+func (tdb *TDB) ScheduledOrder(id []byte) (*db.ScheduledOrder, error) {
+	tdb.scheduledOrdersMtx.Lock()
+	defer tdb.scheduledOrdersMtx.Unlock()
+	so, found := tdb.scheduledOrders[hex.EncodeToString(id)]
+	if !found {
+		return nil, errors.New("not found")
+	}
+	soCopy := *so
+	soCopy.ChildIDs = slices.Clone(so.ChildIDs)
+	return &soCopy, nil
+}
+
+func (tdb *TDB) ScheduledOrders(activeOnly bool) ([]*db.ScheduledOrder, error) {
+	tdb.scheduledOrdersMtx.Lock()
+	defer tdb.scheduledOrdersMtx.Unlock()
+	sos := make([]*db.ScheduledOrder, 0, len(tdb.scheduledOrders))
+	for _, so := range tdb.scheduledOrders {
+		if activeOnly && so.Status != db.ScheduledOrderActive {
+			continue
+		}
+		soCopy := *so
+		soCopy.ChildIDs = slices.Clone(so.ChildIDs)
+		sos = append(sos, &soCopy)
+	}
+	return sos, nil
+}
+
+func (tdb *TDB) StoreFiatRates(snap *db.FiatRateSnapshot) error {
+	tdb.fiatSnapshots = append(tdb.fiatSnapshots, snap)
+	return nil
+}
+
+func (tdb *TDB) FiatRateHistory(start, end uint64) ([]*db.FiatRateSnapshot, error) {
+	snaps := make([]*db.FiatRateSnapshot, 0, len(tdb.fiatSnapshots))
+	for _, snap := range tdb.fiatSnapshots {
+		if snap.Stamp >= start && snap.Stamp <= end {
+			snaps = append(snaps, snap)
+		}
+	}
+	return snaps, nil
+}
+
+func (tdb *TDB) PruneFiatRates(before, interval uint64) (int, error) {
+	return 0, nil
+}
+
+func (tdb *TDB) UpdateAddressBookEntry(e *db.AddressBookEntry) error {
+	for i, entry := range tdb.addressBook {
+		if bytes.Equal(entry.ID, e.ID) {
+			tdb.addressBook[i] = e
+			return nil
+		}
+	}
+	tdb.addressBook = append(tdb.addressBook, e)
+	return nil
+}
+
+func (tdb *TDB) DeleteAddressBookEntry(id []byte) error {
+	for i, entry := range tdb.addressBook {
+		if bytes.Equal(entry.ID, id) {
+			tdb.addressBook = append(tdb.addressBook[:i], tdb.addressBook[i+1:]...)
+			return nil
+		}
+	}
+	return errors.New("not found")
+}
+
+func (tdb *TDB) AddressBook() ([]*db.AddressBookEntry, error) {
+	return tdb.addressBook, nil
+}
+
+func (tdb *TDB) SetAllowlistSetting(s *db.AllowlistSetting) error {
+	tdb.allowlist = *s
+	return nil
+}
+
+func (tdb *TDB) AllowlistSetting() (*db.AllowlistSetting, error) {
+	s := tdb.allowlist
+	return &s, nil
+}
+
+func (tdb *TDB) StoreAPIKey(k *db.APIKey) error {
+	tdb.apiKeys = append(tdb.apiKeys, k)
+	return nil
+}
+
+func (tdb *TDB) DeleteAPIKey(name string) error {
+	for i, k := range tdb.apiKeys {
+		if k.Name == name {
+			tdb.apiKeys = append(tdb.apiKeys[:i], tdb.apiKeys[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("API key %q not found", name)
+}
+
+func (tdb *TDB) APIKeys() ([]*db.APIKey, error) {
+	return tdb.apiKeys, nil
+}
+
 type tCoin struct {
 	id []byte
 
@@ -1361,6 +1512,9 @@ func newTestRig() *testRig {
 			notes:            make(chan asset.WalletNotification, 128),
 			pokesCache:       newPokesCache(pokesCapacity),
 			requestedActions: make(map[string]*asset.ActionRequiredNote),
+			stopOrders:       make(map[string]*db.StopOrder),
+			stopWatchers:     make(map[string]*stopWatcher),
+			scheduledOrders:  make(map[string]*scheduledOrderRunner),
 		},
 		db:      tdb,
 		queue:   queue,
@@ -7052,6 +7206,113 @@ func TestHandleTradeResumptionMsg(t *testing.T) {
 	}
 }
 
+func TestHandleConfigMsg(t *testing.T) {
+	rig := newTestRig()
+	defer rig.shutdown()
+
+	origCfg := rig.dc.cfg
+
+	// A config without the market, as when the market is retired.
+	cfg := *origCfg
+	cfg.Markets = nil
+	note, _ := msgjson.NewNotification(msgjson.ConfigRoute, &cfg)
+	if err := handleConfigMsg(rig.core, rig.dc, note); err != nil {
+		t.Fatalf("handleConfigMsg error: %v", err)
+	}
+	if rig.dc.marketConfig(tDcrBtcMktName) != nil {
+		t.Fatalf("retired market still configured")
+	}
+
+	// The market is added back.
+	note, _ = msgjson.NewNotification(msgjson.ConfigRoute, origCfg)
+	if err := handleConfigMsg(rig.core, rig.dc, note); err != nil {
+		t.Fatalf("handleConfigMsg error: %v", err)
+	}
+	if rig.dc.marketConfig(tDcrBtcMktName) == nil {
+		t.Fatalf("added market not configured")
+	}
+
+	// An unsupported API version is an error.
+	cfg = *origCfg
+	cfg.APIVersion = 1000
+	note, _ = msgjson.NewNotification(msgjson.ConfigRoute, &cfg)
+	if err := handleConfigMsg(rig.core, rig.dc, note); err == nil {
+		t.Fatalf("no error for unsupported API version")
+	}
+}
+
+func TestHandleMarketParamsMsg(t *testing.T) {
+	rig := newTestRig()
+	defer rig.shutdown()
+
+	feed := rig.core.NotificationFeed()
+	defer feed.ReturnFeed()
+
+	mp := &msgjson.MarketParams{
+		MarketID:   tDcrBtcMktName,
+		StartEpoch: 1234,
+		StartTime:  uint64(time.Now().Add(time.Minute).UnixMilli()),
+		LotSize:    dcrBtcLotSize * 2,
+		RateStep:   dcrBtcRateStep * 2,
+	}
+	note, _ := msgjson.NewNotification(msgjson.MarketParamsRoute, mp)
+	if err := handleMarketParamsMsg(rig.core, rig.dc, note); err != nil {
+		t.Fatalf("handleMarketParamsMsg error: %v", err)
+	}
+
+	select {
+	case n := <-feed.C:
+		mpn, ok := n.(*MarketParamsNote)
+		if !ok {
+			t.Fatalf("wrong notification type %T", n)
+		}
+		if mpn.Host != tDexHost || mpn.MarketID != tDcrBtcMktName || mpn.LotSize != mp.LotSize ||
+			mpn.RateStep != mp.RateStep || mpn.StartEpoch != mp.StartEpoch {
+			t.Fatalf("wrong notification %+v", mpn)
+		}
+	default:
+		t.Fatalf("no notification")
+	}
+
+	// The config is not changed until the change starts.
+	if rig.dc.marketConfig(tDcrBtcMktName).LotSize != dcrBtcLotSize {
+		t.Fatalf("lot size changed early")
+	}
+
+	// A new change replaces the scheduled one, and is applied at its start.
+	mp.LotSize, mp.RateStep = dcrBtcLotSize*3, dcrBtcRateStep*3
+	mp.StartTime = uint64(time.Now().Add(50 * time.Millisecond).UnixMilli())
+	note, _ = msgjson.NewNotification(msgjson.MarketParamsRoute, mp)
+	if err := handleMarketParamsMsg(rig.core, rig.dc, note); err != nil {
+		t.Fatalf("handleMarketParamsMsg error: %v", err)
+	}
+	<-feed.C // MarketParamsNote
+	select {
+	case n := <-feed.C:
+		if cn, ok := n.(*ServerConfigUpdateNote); !ok || cn.Host != tDexHost {
+			t.Fatalf("wrong notification %T", n)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("change not applied")
+	}
+	mkt := rig.dc.marketConfig(tDcrBtcMktName)
+	if mkt.LotSize != mp.LotSize || mkt.RateStep != mp.RateStep {
+		t.Fatalf("wrong params applied. lot size %d, rate step %d", mkt.LotSize, mkt.RateStep)
+	}
+	rig.dc.paramTimersMtx.Lock()
+	if len(rig.dc.paramTimers) != 0 {
+		t.Fatalf("replaced change still scheduled")
+	}
+	rig.dc.paramTimersMtx.Unlock()
+
+	// Unknown market.
+	mp.MarketID = "abc_xyz"
+	note, _ = msgjson.NewNotification(msgjson.MarketParamsRoute, mp)
+	if err := handleMarketParamsMsg(rig.core, rig.dc, note); err == nil {
+		t.Fatalf("no error for an unknown market")
+	}
+}
+
 func TestHandleNomatch(t *testing.T) {
 	rig := newTestRig()
 	defer rig.shutdown()
@@ -54,6 +54,8 @@ const (
 	bondPostErr // TODO
 	insufficientRedeemFundsErr
 	bundlerRedemptionLotSizeTooSmallErr
+	allowlistErr
+	apiKeyErr
 )
 
 // Error is an error code and a wrapped error.
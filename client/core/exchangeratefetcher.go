@@ -21,7 +21,10 @@ const (
 	fiatRateRequestInterval = 12 * time.Minute
 	// fiatRateDataExpiry : Any data older than fiatRateDataExpiry will be discarded.
 	fiatRateDataExpiry = 60 * time.Minute
-	fiatRequestTimeout = time.Second * 5
+	// fiatSnapshotInterval is the minimum amount of time between fiat rate
+	// snapshots stored in the database for valuing the trade history.
+	fiatSnapshotInterval = time.Hour
+	fiatRequestTimeout   = time.Second * 5
 
 	// Tokens. Used to identify fiat rate source, source name must not contain a
 	// comma.
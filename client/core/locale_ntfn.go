@@ -81,6 +81,38 @@ var originLocale = map[Topic]*translation{
 		subject:  intl.Translation{T: "Trade limit exceeded"},
 		template: intl.Translation{T: "Order quantity exceeds current trade limit on %s", Notes: "args: [host]"},
 	},
+	TopicStopOrderPlaced: {
+		subject:  intl.Translation{T: "Stop order placed"},
+		template: intl.Translation{T: "Placed %s order on %s market (%s)", Notes: "args: [stop order type, market, stop order ID]"},
+	},
+	TopicStopOrderCanceled: {
+		subject:  intl.Translation{T: "Stop order canceled"},
+		template: intl.Translation{T: "Canceled %s order on %s market (%s)", Notes: "args: [stop order type, market, stop order ID]"},
+	},
+	TopicStopOrderTriggered: {
+		subject:  intl.Translation{T: "Stop order triggered"},
+		template: intl.Translation{T: "The %s order on %s market (%s) was triggered and order %s was placed", Notes: "args: [stop order type, market, stop order ID, order ID]"},
+	},
+	TopicStopOrderFailed: {
+		subject:  intl.Translation{T: "Stop order failed"},
+		template: intl.Translation{T: "The %s order on %s market (%s) was triggered, but the order could not be placed: %v", Notes: "args: [stop order type, market, stop order ID, error]"},
+	},
+	TopicScheduledOrderCreated: {
+		subject:  intl.Translation{T: "Scheduled order created"},
+		template: intl.Translation{T: "Created %s order on %s market (%s)", Notes: "args: [scheduled order type, market, scheduled order ID]"},
+	},
+	TopicScheduledOrderCompleted: {
+		subject:  intl.Translation{T: "Scheduled order completed"},
+		template: intl.Translation{T: "All child orders of the %s order on %s market (%s) are complete", Notes: "args: [scheduled order type, market, scheduled order ID]"},
+	},
+	TopicScheduledOrderCanceled: {
+		subject:  intl.Translation{T: "Scheduled order canceled"},
+		template: intl.Translation{T: "Canceled %s order on %s market (%s)", Notes: "args: [scheduled order type, market, scheduled order ID]"},
+	},
+	TopicScheduledOrderFailed: {
+		subject:  intl.Translation{T: "Scheduled order failed"},
+		template: intl.Translation{T: "A child order of the %s order on %s market (%s) could not be placed: %v", Notes: "args: [scheduled order type, market, scheduled order ID, error]"},
+	},
 	TopicOrderLoadFailure: {
 		subject:  intl.Translation{T: "Order load failure"},
 		template: intl.Translation{T: "Some orders failed to load from the database: %v", Notes: "args: [error]"},
@@ -258,6 +290,10 @@ var originLocale = map[Topic]*translation{
 		subject:  intl.Translation{T: "Market resumed"},
 		template: intl.Translation{T: "Market %s at %s has resumed trading at epoch %d", Notes: "args: [market name, host, epoch]"},
 	},
+	TopicMarketParamsScheduled: {
+		subject:  intl.Translation{T: "Market lot size change scheduled"},
+		template: intl.Translation{T: "Market %s at %s will use lot size %s and rate step %d as of %v. Booked orders that are not compatible will be revoked.", Notes: "args: [market name, host, lot size, rate step, time]"},
+	},
 	TopicUpgradeNeeded: {
 		subject:  intl.Translation{T: "Upgrade needed"},
 		template: intl.Translation{T: "You may need to update your client to trade at %s.", Notes: "args: [host]"},
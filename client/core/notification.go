@@ -43,6 +43,8 @@ const (
 	NoteTypeWalletNote     = "walletnote"
 	NoteTypeReputation     = "reputation"
 	NoteTypeActionRequired = "actionrequired"
+	NoteTypeStopOrder      = "stoporder"
+	NoteTypeScheduledOrder = "scheduledorder"
 )
 
 var noteChanCounter uint64
@@ -419,6 +421,47 @@ func newOrderNoteWithTempID(topic Topic, subject, details string, severity db.Se
 	return note
 }
 
+// StopOrderNote is a notification about a client-side stop order.
+type StopOrderNote struct {
+	db.Notification
+	StopOrder *StopOrder `json:"stopOrder"`
+}
+
+const (
+	TopicStopOrderPlaced    Topic = "StopOrderPlaced"
+	TopicStopOrderCanceled  Topic = "StopOrderCanceled"
+	TopicStopOrderTriggered Topic = "StopOrderTriggered"
+	TopicStopOrderFailed    Topic = "StopOrderFailed"
+)
+
+func newStopOrderNote(topic Topic, subject, details string, severity db.Severity, so *StopOrder) *StopOrderNote {
+	return &StopOrderNote{
+		Notification: db.NewNotification(NoteTypeStopOrder, topic, subject, details, severity),
+		StopOrder:    so,
+	}
+}
+
+// ScheduledOrderNote is a notification about a client-side TWAP or iceberg
+// order.
+type ScheduledOrderNote struct {
+	db.Notification
+	ScheduledOrder *ScheduledOrder `json:"scheduledOrder"`
+}
+
+const (
+	TopicScheduledOrderCreated   Topic = "ScheduledOrderCreated"
+	TopicScheduledOrderCompleted Topic = "ScheduledOrderCompleted"
+	TopicScheduledOrderCanceled  Topic = "ScheduledOrderCanceled"
+	TopicScheduledOrderFailed    Topic = "ScheduledOrderFailed"
+)
+
+func newScheduledOrderNote(topic Topic, subject, details string, severity db.Severity, so *ScheduledOrder) *ScheduledOrderNote {
+	return &ScheduledOrderNote{
+		Notification:   db.NewNotification(NoteTypeScheduledOrder, topic, subject, details, severity),
+		ScheduledOrder: so,
+	}
+}
+
 // MatchNote is a notification about a match.
 type MatchNote struct {
 	db.Notification
@@ -703,6 +746,30 @@ func newServerConfigUpdateNote(host string) *ServerConfigUpdateNote {
 	}
 }
 
+// MarketParamsNote is sent when a server announces a change to a market's lot
+// size and rate step.
+type MarketParamsNote struct {
+	db.Notification
+	Host       string `json:"host"`
+	MarketID   string `json:"marketID"`
+	StartEpoch uint64 `json:"startEpoch"`
+	LotSize    uint64 `json:"lotSize"`
+	RateStep   uint64 `json:"rateStep"`
+}
+
+const TopicMarketParamsScheduled Topic = "MarketParamsScheduled"
+
+func newMarketParamsNote(subject, details, host string, mp *msgjson.MarketParams) *MarketParamsNote {
+	return &MarketParamsNote{
+		Notification: db.NewNotification(NoteTypeServerNotify, TopicMarketParamsScheduled, subject, details, db.WarningLevel),
+		Host:         host,
+		MarketID:     mp.MarketID,
+		StartEpoch:   mp.StartEpoch,
+		LotSize:      mp.LotSize,
+		RateStep:     mp.RateStep,
+	}
+}
+
 // WalletCreationNote is a notification regarding asynchronous wallet creation.
 type WalletCreationNote struct {
 	db.Notification
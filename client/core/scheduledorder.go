@@ -0,0 +1,523 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"decred.org/dcrdex/client/db"
+	"decred.org/dcrdex/dex"
+	"decred.org/dcrdex/dex/encode"
+	"decred.org/dcrdex/dex/order"
+)
+
+// scheduledOrderTick is how often a scheduled order's child orders are checked
+// and the next child order is placed if due.
+var scheduledOrderTick = 5 * time.Second
+
+const (
+	// scheduledOrderMaxRetries is the number of consecutive transient errors
+	// placing a child order after which the scheduled order fails.
+	scheduledOrderMaxRetries = 8
+	// scheduledOrderMaxBackoff is the longest wait between attempts to place a
+	// child order after a transient error.
+	scheduledOrderMaxBackoff = 5 * time.Minute
+)
+
+// scheduledOrderRunner is the goroutine executing an active scheduled order.
+type scheduledOrderRunner struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func coreScheduledOrder(so *db.ScheduledOrder) *ScheduledOrder {
+	childIDs := make([]dex.Bytes, 0, len(so.ChildIDs))
+	for _, oid := range so.ChildIDs {
+		childIDs = append(childIDs, oid.Bytes())
+	}
+	return &ScheduledOrder{
+		ID:         so.ID,
+		Type:       so.Type.String(),
+		Host:       so.Host,
+		BaseID:     so.Base,
+		QuoteID:    so.Quote,
+		MarketID:   marketName(so.Base, so.Quote),
+		Sell:       so.Sell,
+		Qty:        so.Qty,
+		Rate:       so.Rate,
+		Duration:   so.Duration,
+		Slices:     so.Slices,
+		VisibleQty: so.VisibleQty,
+		TifNow:     so.TifNow,
+		Options:    so.Options,
+		Status:     so.Status.String(),
+		Stamp:      so.Stamp,
+		ChildIDs:   childIDs,
+		Placed:     so.Placed,
+		Filled:     so.Filled,
+		FeesPaid: &FeeBreakdown{
+			Swap:       so.SwapFees,
+			Redemption: so.RedemptionFees,
+			Funding:    so.FundingFees,
+		},
+		EndStamp: so.EndStamp,
+		Err:      so.Err,
+	}
+}
+
+// scheduledChildQty is the quantity of the next child order of the scheduled
+// order, or zero if no child order is due at time now (milliseconds). booked
+// indicates whether a previous child order is still in the epoch queue or
+// booked.
+//
+// The remaining lots of a TWAP order are spread over the remaining slices, with
+// earlier slices taking any remainder. The next Iceberg child order is placed
+// only once the previous child order is no longer booked.
+func scheduledChildQty(so *db.ScheduledOrder, lotSize uint64, booked bool, now uint64) uint64 {
+	switch so.Type {
+	case db.TWAP:
+		placedSlices := uint64(len(so.ChildIDs))
+		if placedSlices >= uint64(so.Slices) || so.Placed >= so.Qty || lotSize == 0 {
+			return 0
+		}
+		if now < so.Stamp+placedSlices*so.Duration/uint64(so.Slices) {
+			return 0
+		}
+		remainingLots := (so.Qty - so.Placed) / lotSize
+		remainingSlices := uint64(so.Slices) - placedSlices
+		return (remainingLots + remainingSlices - 1) / remainingSlices * lotSize
+	case db.Iceberg:
+		if booked || so.Filled >= so.Qty {
+			return 0
+		}
+		return min(so.VisibleQty, so.Qty-so.Filled)
+	}
+	return 0
+}
+
+// scheduledOrderDone is true if all of the scheduled order's child orders have
+// been placed. tracked indicates whether any child order is still active, i.e.
+// booked or with matches that have not completed.
+func scheduledOrderDone(so *db.ScheduledOrder, tracked bool) bool {
+	if tracked {
+		return false
+	}
+	switch so.Type {
+	case db.TWAP:
+		return len(so.ChildIDs) >= int(so.Slices)
+	case db.Iceberg:
+		return so.Filled >= so.Qty
+	}
+	return true
+}
+
+// CreateScheduledOrder validates and stores a TWAP or iceberg order, and starts
+// placing its child limit orders. Funds are reserved by each child order as it
+// is placed, and the wallets must be unlocked at that time. Active scheduled
+// orders are paused on logout and resumed on login.
+func (c *Core) CreateScheduledOrder(form *ScheduledOrderForm) (*ScheduledOrder, error) {
+	dc, err := c.registeredDEX(form.Host)
+	if err != nil {
+		return nil, err
+	}
+	mktID := marketName(form.Base, form.Quote)
+	mktConf := dc.marketConfig(mktID)
+	if mktConf == nil {
+		return nil, newError(marketErr, "scheduled order created for unknown market %q", mktID)
+	}
+	if _, _, _, err := c.walletSet(dc, form.Base, form.Quote, form.Sell); err != nil {
+		return nil, err
+	}
+
+	so := &db.ScheduledOrder{
+		ID:         encode.RandomBytes(8),
+		Host:       dc.acct.host,
+		Base:       form.Base,
+		Quote:      form.Quote,
+		Sell:       form.Sell,
+		Qty:        form.Qty,
+		Rate:       form.Rate,
+		Duration:   form.Duration,
+		Slices:     form.Slices,
+		VisibleQty: form.VisibleQty,
+		TifNow:     form.TifNow,
+		Options:    form.Options,
+		Status:     db.ScheduledOrderActive,
+		Stamp:      uint64(time.Now().UnixMilli()),
+	}
+
+	if so.Qty == 0 || so.Qty%mktConf.LotSize != 0 {
+		return nil, newError(orderParamsErr, "scheduled order quantity %d is not a multiple of the lot size %d", so.Qty, mktConf.LotSize)
+	}
+	if so.Rate == 0 || so.Rate%mktConf.RateStep != 0 {
+		return nil, newError(orderParamsErr, "scheduled order rate %d is not a multiple of the rate step %d", so.Rate, mktConf.RateStep)
+	}
+	switch form.Type {
+	case ScheduledOrderTypeTWAP:
+		so.Type = db.TWAP
+		if so.Slices == 0 || so.Qty/mktConf.LotSize < uint64(so.Slices) {
+			return nil, newError(orderParamsErr, "TWAP orders require between 1 and %d slices", so.Qty/mktConf.LotSize)
+		}
+		// Each slice should be placed in a different epoch.
+		if so.Duration/uint64(so.Slices) < mktConf.EpochLen {
+			return nil, newError(orderParamsErr, "TWAP duration %d ms is too short for %d slices with an epoch duration of %d ms",
+				so.Duration, so.Slices, mktConf.EpochLen)
+		}
+		so.VisibleQty = 0
+	case ScheduledOrderTypeIceberg:
+		so.Type = db.Iceberg
+		if so.VisibleQty == 0 || so.VisibleQty%mktConf.LotSize != 0 || so.VisibleQty > so.Qty {
+			return nil, newError(orderParamsErr, "iceberg visible quantity %d must be a multiple of the lot size %d and not more than the order quantity",
+				so.VisibleQty, mktConf.LotSize)
+		}
+		if so.TifNow {
+			return nil, newError(orderParamsErr, "iceberg child orders must be standing orders")
+		}
+		so.Duration, so.Slices = 0, 0
+	default:
+		return nil, newError(orderParamsErr, "unknown scheduled order type %q", form.Type)
+	}
+
+	if err := c.db.UpdateScheduledOrder(so); err != nil {
+		return nil, codedError(dbErr, err)
+	}
+	cso := coreScheduledOrder(so)
+
+	c.scheduledOrdersMtx.Lock()
+	c.startScheduledOrder(so)
+	c.scheduledOrdersMtx.Unlock()
+
+	subject, details := c.formatDetails(TopicScheduledOrderCreated, so.Type, mktID, so.ID)
+	c.notify(newScheduledOrderNote(TopicScheduledOrderCreated, subject, details, db.Success, cso))
+
+	return cso, nil
+}
+
+// CancelScheduledOrder stops placing child orders for an active scheduled
+// order and cancels any of its child orders that are still booked.
+func (c *Core) CancelScheduledOrder(id dex.Bytes) error {
+	c.scheduledOrdersMtx.Lock()
+	runner, found := c.scheduledOrders[id.String()]
+	if found {
+		delete(c.scheduledOrders, id.String())
+	}
+	c.scheduledOrdersMtx.Unlock()
+	if found {
+		runner.cancel()
+		<-runner.done
+	}
+
+	// The runner stores every change, so the DB is up to date.
+	so, err := c.db.ScheduledOrder(id)
+	if err != nil {
+		return newError(unknownOrderErr, "no scheduled order %s: %v", id, err)
+	}
+	if so.Status != db.ScheduledOrderActive {
+		return newError(unknownOrderErr, "scheduled order %s is %s", id, so.Status)
+	}
+
+	booked, _ := c.updateScheduledOrderFills(so)
+	for _, oid := range booked {
+		if err := c.Cancel(oid.Bytes()); err != nil {
+			c.log.Errorf("Error canceling child order %s of scheduled order %s: %v", oid, so.ID, err)
+		}
+	}
+	so.Status = db.ScheduledOrderCanceled
+	so.EndStamp = uint64(time.Now().UnixMilli())
+	if err := c.db.UpdateScheduledOrder(so); err != nil {
+		return codedError(dbErr, err)
+	}
+
+	subject, details := c.formatDetails(TopicScheduledOrderCanceled, so.Type, marketName(so.Base, so.Quote), so.ID)
+	c.notify(newScheduledOrderNote(TopicScheduledOrderCanceled, subject, details, db.Success, coreScheduledOrder(so)))
+	return nil
+}
+
+// ScheduledOrder retrieves the scheduled order with the specified ID.
+func (c *Core) ScheduledOrder(id dex.Bytes) (*ScheduledOrder, error) {
+	so, err := c.db.ScheduledOrder(id)
+	if err != nil {
+		return nil, newError(unknownOrderErr, "no scheduled order %s: %v", id, err)
+	}
+	return coreScheduledOrder(so), nil
+}
+
+// ScheduledOrders retrieves scheduled orders, newest first. If activeOnly is
+// true, only scheduled orders that are still active are returned.
+func (c *Core) ScheduledOrders(activeOnly bool) ([]*ScheduledOrder, error) {
+	sos, err := c.db.ScheduledOrders(activeOnly)
+	if err != nil {
+		return nil, codedError(dbErr, err)
+	}
+	csos := make([]*ScheduledOrder, 0, len(sos))
+	for _, so := range sos {
+		csos = append(csos, coreScheduledOrder(so))
+	}
+	return csos, nil
+}
+
+// resumeScheduledOrders loads the active scheduled orders from the DB and
+// resumes placing their child orders. TWAP slices that came due while logged
+// out are placed one per tick.
+func (c *Core) resumeScheduledOrders() {
+	sos, err := c.db.ScheduledOrders(true)
+	if err != nil {
+		c.log.Errorf("Error loading scheduled orders: %v", err)
+		return
+	}
+	if len(sos) == 0 {
+		return
+	}
+	c.log.Infof("Resuming %d scheduled orders", len(sos))
+	c.scheduledOrdersMtx.Lock()
+	defer c.scheduledOrdersMtx.Unlock()
+	for _, so := range sos {
+		c.startScheduledOrder(so)
+	}
+}
+
+// stopScheduledOrders stops placing child orders. Active scheduled orders
+// remain in the DB, and are resumed on the next login.
+func (c *Core) stopScheduledOrders() {
+	c.scheduledOrdersMtx.Lock()
+	runners := c.scheduledOrders
+	c.scheduledOrders = make(map[string]*scheduledOrderRunner)
+	c.scheduledOrdersMtx.Unlock()
+	for _, runner := range runners {
+		runner.cancel()
+		<-runner.done
+	}
+}
+
+// startScheduledOrder starts executing the scheduled order, if it is not
+// already running. The scheduledOrdersMtx MUST be locked.
+func (c *Core) startScheduledOrder(so *db.ScheduledOrder) {
+	k := so.ID.String()
+	if _, found := c.scheduledOrders[k]; found {
+		return
+	}
+	ctx, cancel := context.WithCancel(c.ctx)
+	runner := &scheduledOrderRunner{
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	c.scheduledOrders[k] = runner
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer close(runner.done)
+		defer cancel()
+		c.runScheduledOrder(ctx, so)
+		c.scheduledOrdersMtx.Lock()
+		if c.scheduledOrders[k] == runner {
+			delete(c.scheduledOrders, k)
+		}
+		c.scheduledOrdersMtx.Unlock()
+	}()
+}
+
+// runScheduledOrder steps the scheduled order every scheduledOrderTick until it
+// is no longer active or the context is canceled.
+func (c *Core) runScheduledOrder(ctx context.Context, so *db.ScheduledOrder) {
+	ticker := time.NewTicker(scheduledOrderTick)
+	defer ticker.Stop()
+	retry := new(scheduledOrderRetry)
+	for {
+		if c.stepScheduledOrder(so, retry, uint64(time.Now().UnixMilli())) {
+			return
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// scheduledOrderRetry tracks the attempts to place a scheduled order's next
+// child order after transient errors.
+type scheduledOrderRetry struct {
+	attempts int
+	next     uint64 // milliseconds
+}
+
+// backoff records a failed attempt at time now (milliseconds), and reports
+// whether another attempt should be made. The wait before the next attempt
+// doubles with each attempt, from scheduledOrderTick up to
+// scheduledOrderMaxBackoff.
+func (r *scheduledOrderRetry) backoff(now uint64) bool {
+	if r.attempts >= scheduledOrderMaxRetries {
+		return false
+	}
+	wait := scheduledOrderTick << r.attempts
+	if wait > scheduledOrderMaxBackoff || wait <= 0 {
+		wait = scheduledOrderMaxBackoff
+	}
+	r.attempts++
+	r.next = now + uint64(wait.Milliseconds())
+	return true
+}
+
+// transientTradeError is true if the error placing a child order may resolve
+// on its own, e.g. a locked, disconnected or unsynced wallet. Errors from the
+// order request itself are not transient, since the server may have accepted
+// the order.
+func transientTradeError(err error) bool {
+	var noPeersErr *WalletNoPeersError
+	var syncErr *WalletSyncError
+	return errorHasCode(err, walletErr) || errorHasCode(err, walletAuthErr) ||
+		errorHasCode(err, connectWalletErr) || errorHasCode(err, connectionErr) ||
+		errors.As(err, &noPeersErr) || errors.As(err, &syncErr)
+}
+
+// updateScheduledOrderFills updates the scheduled order's aggregate fill and
+// fees from its child orders. The IDs of the child orders that are still in the
+// epoch queue or booked are returned. tracked is true if any child order is
+// still active, including executed orders with swaps in progress.
+func (c *Core) updateScheduledOrderFills(so *db.ScheduledOrder) (booked []order.OrderID, tracked bool) {
+	var filled, swapFees, redemptionFees, fundingFees uint64
+	for _, oid := range so.ChildIDs {
+		var corder *Order
+		for _, dc := range c.dexConnections() {
+			if tracker, _ := dc.findOrder(oid); tracker != nil {
+				corder = tracker.coreOrder()
+				tracked = true
+				break
+			}
+		}
+		if corder == nil {
+			mOrd, err := c.db.Order(oid)
+			if err != nil || mOrd == nil {
+				c.log.Errorf("Error retrieving child order %s of scheduled order %s: %v", oid, so.ID, err)
+				continue
+			}
+			corder = coreOrderFromTrade(mOrd.Order, mOrd.MetaData)
+		} else if corder.Status <= order.OrderStatusBooked {
+			booked = append(booked, oid)
+		}
+		filled += corder.Filled
+		if corder.FeesPaid != nil {
+			swapFees += corder.FeesPaid.Swap
+			redemptionFees += corder.FeesPaid.Redemption
+			fundingFees += corder.FeesPaid.Funding
+		}
+	}
+	so.Filled = filled
+	so.SwapFees, so.RedemptionFees, so.FundingFees = swapFees, redemptionFees, fundingFees
+	return booked, tracked
+}
+
+// stepScheduledOrder updates the scheduled order's fills and fees, places the
+// next child order if one is due, and completes the scheduled order once all
+// of its child orders are complete. Changes are stored in the DB. The return
+// value indicates whether the scheduled order is no longer active.
+//
+// Transient errors, such as a disconnected DEX or a locked wallet, are retried
+// with backoff, and the scheduled order fails after scheduledOrderMaxRetries
+// consecutive transient errors or any other error. Booked child orders are
+// canceled when the scheduled order fails.
+func (c *Core) stepScheduledOrder(so *db.ScheduledOrder, retry *scheduledOrderRetry, now uint64) (done bool) {
+	mktID := marketName(so.Base, so.Quote)
+	lastFilled, lastFees := so.Filled, so.SwapFees+so.RedemptionFees+so.FundingFees
+	booked, tracked := c.updateScheduledOrderFills(so)
+	update := func() {
+		if err := c.db.UpdateScheduledOrder(so); err != nil {
+			c.log.Errorf("Error storing scheduled order %s: %v", so.ID, err)
+		}
+	}
+	storeFills := func() {
+		if so.Filled != lastFilled || so.SwapFees+so.RedemptionFees+so.FundingFees != lastFees {
+			update()
+		}
+	}
+	fail := func(err error) bool {
+		for _, oid := range booked {
+			if err := c.Cancel(oid.Bytes()); err != nil {
+				c.log.Errorf("Error canceling child order %s of scheduled order %s: %v", oid, so.ID, err)
+			}
+		}
+		so.Status = db.ScheduledOrderFailed
+		so.EndStamp = now
+		so.Err = err.Error()
+		update()
+		subject, details := c.formatDetails(TopicScheduledOrderFailed, so.Type, mktID, so.ID, err)
+		c.notify(newScheduledOrderNote(TopicScheduledOrderFailed, subject, details, db.ErrorLevel, coreScheduledOrder(so)))
+		return true
+	}
+	retryLater := func(err error) bool {
+		if !retry.backoff(now) {
+			return fail(fmt.Errorf("giving up after %d attempts: %w", retry.attempts+1, err))
+		}
+		c.log.Warnf("Error placing child order of scheduled order %s, attempt %d. Retrying in %v: %v",
+			so.ID, retry.attempts, time.Duration(retry.next-now)*time.Millisecond, err)
+		storeFills()
+		return false
+	}
+
+	if scheduledOrderDone(so, tracked) {
+		so.Status = db.ScheduledOrderCompleted
+		so.EndStamp = now
+		update()
+		subject, details := c.formatDetails(TopicScheduledOrderCompleted, so.Type, mktID, so.ID)
+		c.notify(newScheduledOrderNote(TopicScheduledOrderCompleted, subject, details, db.Success, coreScheduledOrder(so)))
+		return true
+	}
+
+	if now < retry.next {
+		storeFills()
+		return false
+	}
+
+	dc, connected, err := c.dex(so.Host)
+	if err != nil {
+		return retryLater(err)
+	}
+	mktConf := dc.marketConfig(mktID)
+	if mktConf == nil {
+		return fail(fmt.Errorf("market %s not found", mktID))
+	}
+
+	qty := scheduledChildQty(so, mktConf.LotSize, len(booked) > 0, now)
+	if qty == 0 {
+		storeFills()
+		return false
+	}
+
+	switch {
+	case !connected:
+		return retryLater(fmt.Errorf("currently disconnected from %s", so.Host))
+	case dc.acct.locked():
+		return retryLater(fmt.Errorf("account for %s is locked", so.Host))
+	case !dc.running(mktID):
+		return retryLater(fmt.Errorf("%s market trading is suspended", mktID))
+	}
+
+	corder, err := c.Trade(nil, &TradeForm{
+		Host:    so.Host,
+		IsLimit: true,
+		Sell:    so.Sell,
+		Base:    so.Base,
+		Quote:   so.Quote,
+		Qty:     qty,
+		Rate:    so.Rate,
+		TifNow:  so.TifNow,
+		Options: so.Options,
+	})
+	if err != nil {
+		if transientTradeError(err) {
+			return retryLater(err)
+		}
+		return fail(err)
+	}
+	*retry = scheduledOrderRetry{}
+	var oid order.OrderID
+	copy(oid[:], corder.ID)
+	so.ChildIDs = append(so.ChildIDs, oid)
+	so.Placed += qty
+	update()
+	return false
+}
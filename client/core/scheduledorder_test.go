@@ -0,0 +1,359 @@
+//go:build !harness && !botlive
+
+package core
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"decred.org/dcrdex/client/comms"
+	"decred.org/dcrdex/client/db"
+	"decred.org/dcrdex/dex/encode"
+	"decred.org/dcrdex/dex/order"
+)
+
+func TestScheduledChildQty(t *testing.T) {
+	const lotSize = 10
+	tests := []struct {
+		name   string
+		so     *db.ScheduledOrder
+		booked bool
+		now    uint64
+		expQty uint64
+	}{
+		{
+			name:   "twap first slice takes remainder",
+			so:     &db.ScheduledOrder{Type: db.TWAP, Qty: 100, Slices: 3, Duration: 300},
+			expQty: 40,
+		},
+		{
+			name:   "twap second slice not due",
+			so:     &db.ScheduledOrder{Type: db.TWAP, Qty: 100, Slices: 3, Duration: 300, ChildIDs: make([]order.OrderID, 1), Placed: 40},
+			now:    99,
+			expQty: 0,
+		},
+		{
+			name:   "twap second slice due",
+			so:     &db.ScheduledOrder{Type: db.TWAP, Qty: 100, Slices: 3, Duration: 300, ChildIDs: make([]order.OrderID, 1), Placed: 40},
+			now:    100,
+			expQty: 30,
+		},
+		{
+			name:   "twap all slices placed",
+			so:     &db.ScheduledOrder{Type: db.TWAP, Qty: 100, Slices: 3, Duration: 300, ChildIDs: make([]order.OrderID, 3), Placed: 100},
+			now:    1000,
+			expQty: 0,
+		},
+		{
+			name:   "twap ignores booked",
+			so:     &db.ScheduledOrder{Type: db.TWAP, Qty: 100, Slices: 1, Duration: 300},
+			booked: true,
+			expQty: 100,
+		},
+		{
+			name:   "iceberg visible qty",
+			so:     &db.ScheduledOrder{Type: db.Iceberg, Qty: 100, VisibleQty: 30, Filled: 50},
+			expQty: 30,
+		},
+		{
+			name:   "iceberg remainder",
+			so:     &db.ScheduledOrder{Type: db.Iceberg, Qty: 100, VisibleQty: 30, Filled: 80},
+			expQty: 20,
+		},
+		{
+			name:   "iceberg booked",
+			so:     &db.ScheduledOrder{Type: db.Iceberg, Qty: 100, VisibleQty: 30},
+			booked: true,
+			expQty: 0,
+		},
+		{
+			name:   "iceberg filled",
+			so:     &db.ScheduledOrder{Type: db.Iceberg, Qty: 100, VisibleQty: 30, Filled: 100},
+			expQty: 0,
+		},
+	}
+	for _, tt := range tests {
+		if qty := scheduledChildQty(tt.so, lotSize, tt.booked, tt.now); qty != tt.expQty {
+			t.Fatalf("%s: expected qty %d, got %d", tt.name, tt.expQty, qty)
+		}
+	}
+}
+
+func TestCreateScheduledOrder(t *testing.T) {
+	rig := newTestRig()
+	defer rig.shutdown()
+	tCore := rig.core
+
+	dcrWallet, _ := newTWallet(tUTXOAssetA.ID)
+	tCore.wallets[tUTXOAssetA.ID] = dcrWallet
+	btcWallet, _ := newTWallet(tUTXOAssetB.ID)
+	tCore.wallets[tUTXOAssetB.ID] = btcWallet
+
+	epochLen := rig.dc.marketConfig(tDcrBtcMktName).EpochLen
+	newForm := func() *ScheduledOrderForm {
+		return &ScheduledOrderForm{
+			Host:     tDexHost,
+			Type:     ScheduledOrderTypeTWAP,
+			Sell:     true,
+			Base:     tUTXOAssetA.ID,
+			Quote:    tUTXOAssetB.ID,
+			Qty:      dcrBtcLotSize * 10,
+			Rate:     dcrBtcRateStep * 100,
+			Duration: epochLen * 5,
+			Slices:   5,
+		}
+	}
+
+	ensureErr := func(tag string, form *ScheduledOrderForm) {
+		t.Helper()
+		if _, err := tCore.CreateScheduledOrder(form); err == nil {
+			t.Fatalf("%s: no error", tag)
+		}
+	}
+
+	form := newForm()
+	form.Qty = dcrBtcLotSize + 1
+	ensureErr("not a lot multiple", form)
+
+	form = newForm()
+	form.Rate = dcrBtcRateStep + 1
+	ensureErr("not a rate step multiple", form)
+
+	form = newForm()
+	form.Slices = 0
+	ensureErr("no slices", form)
+
+	form = newForm()
+	form.Slices = 11
+	ensureErr("more slices than lots", form)
+
+	form = newForm()
+	form.Duration = epochLen*5 - 1
+	ensureErr("slices shorter than an epoch", form)
+
+	form = newForm()
+	form.Type = ScheduledOrderTypeIceberg
+	ensureErr("no visible qty", form)
+
+	form.VisibleQty = dcrBtcLotSize * 11
+	ensureErr("visible qty too large", form)
+
+	form.VisibleQty = dcrBtcLotSize
+	form.TifNow = true
+	ensureErr("immediate iceberg", form)
+
+	form = newForm()
+	form.Type = "vwap"
+	ensureErr("unknown type", form)
+
+	form = newForm()
+	form.Quote = tACCTAsset.ID
+	ensureErr("unknown market", form)
+
+	so, err := tCore.CreateScheduledOrder(newForm())
+	if err != nil {
+		t.Fatalf("CreateScheduledOrder error: %v", err)
+	}
+	if so.Status != db.ScheduledOrderActive.String() {
+		t.Fatalf("wrong status %s", so.Status)
+	}
+
+	// The test server does not handle the limit route, so placing the first
+	// slice fails.
+	timeout := time.After(time.Second * 5)
+	for {
+		so, err = tCore.ScheduledOrder(so.ID)
+		if err != nil {
+			t.Fatalf("ScheduledOrder error: %v", err)
+		}
+		if so.Status == db.ScheduledOrderFailed.String() {
+			break
+		}
+		select {
+		case <-timeout:
+			t.Fatalf("scheduled order not failed. status = %s", so.Status)
+		case <-time.After(time.Millisecond * 10):
+		}
+	}
+	if so.Err == "" || so.EndStamp == 0 {
+		t.Fatalf("failed scheduled order missing error or end stamp")
+	}
+	if err := tCore.CancelScheduledOrder(so.ID); err == nil {
+		t.Fatalf("no error canceling a failed scheduled order")
+	}
+	if sos, _ := tCore.ScheduledOrders(true); len(sos) != 0 {
+		t.Fatalf("failed scheduled order listed as active")
+	}
+}
+
+func TestStepScheduledOrder(t *testing.T) {
+	rig := newTestRig()
+	defer rig.shutdown()
+	tCore := rig.core
+
+	qty := dcrBtcLotSize * 4
+	rate := dcrBtcRateStep * 100
+
+	// An executed child order with matches that are complete.
+	lo, dbOrder, _, _ := makeLimitOrder(rig.dc, true, qty/2, rate)
+	lo.FillAmt = qty / 2
+	dbOrder.MetaData.Status = order.OrderStatusExecuted
+	dbOrder.MetaData.SwapFeesPaid = 1
+	dbOrder.MetaData.RedemptionFeesPaid = 2
+	dbOrder.MetaData.FundingFeesPaid = 3
+	rig.db.orderOrders[lo.ID()] = dbOrder
+
+	now := uint64(time.Now().UnixMilli())
+	so := &db.ScheduledOrder{
+		ID:       encode.RandomBytes(8),
+		Type:     db.TWAP,
+		Host:     tDexHost,
+		Base:     tUTXOAssetA.ID,
+		Quote:    tUTXOAssetB.ID,
+		Sell:     true,
+		Qty:      qty,
+		Rate:     rate,
+		Duration: 1000,
+		Slices:   2,
+		Status:   db.ScheduledOrderActive,
+		Stamp:    now,
+		ChildIDs: []order.OrderID{lo.ID()},
+		Placed:   qty / 2,
+	}
+	rig.db.UpdateScheduledOrder(so)
+
+	// The second slice is due half way through the duration.
+	if tCore.stepScheduledOrder(so, new(scheduledOrderRetry), now+499) {
+		t.Fatalf("scheduled order done before second slice")
+	}
+	stored, _ := rig.db.ScheduledOrder(so.ID)
+	if stored.Filled != qty/2 || stored.SwapFees != 1 || stored.RedemptionFees != 2 || stored.FundingFees != 3 {
+		t.Fatalf("child fill and fees not stored: %+v", stored)
+	}
+
+	// The second slice is due, but there are no wallets.
+	if !tCore.stepScheduledOrder(so, new(scheduledOrderRetry), now+500) {
+		t.Fatalf("scheduled order not done after failing to place the second slice")
+	}
+	stored, _ = rig.db.ScheduledOrder(so.ID)
+	if stored.Status != db.ScheduledOrderFailed || stored.Err == "" {
+		t.Fatalf("scheduled order not failed")
+	}
+
+	// An iceberg order with its only child order complete.
+	so = &db.ScheduledOrder{
+		ID:         encode.RandomBytes(8),
+		Type:       db.Iceberg,
+		Host:       tDexHost,
+		Base:       tUTXOAssetA.ID,
+		Quote:      tUTXOAssetB.ID,
+		Sell:       true,
+		Qty:        qty / 2,
+		Rate:       rate,
+		VisibleQty: qty / 2,
+		Status:     db.ScheduledOrderActive,
+		Stamp:      now,
+		ChildIDs:   []order.OrderID{lo.ID()},
+		Placed:     qty / 2,
+	}
+	if !tCore.stepScheduledOrder(so, new(scheduledOrderRetry), now) {
+		t.Fatalf("filled iceberg order not done")
+	}
+	stored, _ = rig.db.ScheduledOrder(so.ID)
+	if stored.Status != db.ScheduledOrderCompleted || stored.EndStamp != now {
+		t.Fatalf("filled iceberg order not completed")
+	}
+}
+
+func TestStepScheduledOrderRetry(t *testing.T) {
+	rig := newTestRig()
+	defer rig.shutdown()
+	tCore := rig.core
+
+	qty := dcrBtcLotSize * 4
+	rate := dcrBtcRateStep * 100
+
+	// A booked child order for the first slice.
+	lo, dbOrder, preImg, _ := makeLimitOrder(rig.dc, true, qty/2, rate)
+	lo.Force = order.StandingTiF
+	tracker := newTrackedTrade(dbOrder, preImg, rig.dc, tCore.lockTimeTaker, tCore.lockTimeMaker,
+		rig.db, rig.queue, nil, nil, tCore.notify, tCore.formatDetails)
+	rig.dc.trades[lo.ID()] = tracker
+
+	now := uint64(time.Now().UnixMilli())
+	so := &db.ScheduledOrder{
+		ID:       encode.RandomBytes(8),
+		Type:     db.TWAP,
+		Host:     tDexHost,
+		Base:     tUTXOAssetA.ID,
+		Quote:    tUTXOAssetB.ID,
+		Sell:     true,
+		Qty:      qty,
+		Rate:     rate,
+		Duration: 1000,
+		Slices:   2,
+		Status:   db.ScheduledOrderActive,
+		Stamp:    now,
+		ChildIDs: []order.OrderID{lo.ID()},
+		Placed:   qty / 2,
+	}
+	rig.db.UpdateScheduledOrder(so)
+
+	ensureActive := func(tag string) {
+		t.Helper()
+		stored, _ := rig.db.ScheduledOrder(so.ID)
+		if stored.Status != db.ScheduledOrderActive {
+			t.Fatalf("%s: scheduled order not active: %s", tag, stored.Err)
+		}
+	}
+
+	// The second slice is due while disconnected, so it is retried later.
+	atomic.StoreUint32(&rig.dc.connectionStatus, uint32(comms.Disconnected))
+	retry := new(scheduledOrderRetry)
+	now += 500
+	if tCore.stepScheduledOrder(so, retry, now) {
+		t.Fatalf("scheduled order done after a transient error")
+	}
+	ensureActive("disconnected")
+	if retry.attempts != 1 || retry.next != now+uint64(scheduledOrderTick.Milliseconds()) {
+		t.Fatalf("wrong retry after first attempt: %+v", retry)
+	}
+
+	// Nothing is attempted before the backoff expires.
+	if tCore.stepScheduledOrder(so, retry, retry.next-1) || retry.attempts != 1 {
+		t.Fatalf("attempt made during backoff")
+	}
+
+	// The backoff doubles with each attempt.
+	lastWait := retry.next - now
+	for i := 1; i < scheduledOrderMaxRetries; i++ {
+		now = retry.next
+		if tCore.stepScheduledOrder(so, retry, now) {
+			t.Fatalf("scheduled order done after %d transient errors", retry.attempts)
+		}
+		wait := retry.next - now
+		if wait != min(lastWait*2, uint64(scheduledOrderMaxBackoff.Milliseconds())) {
+			t.Fatalf("wrong backoff %d ms after %d ms", wait, lastWait)
+		}
+		lastWait = wait
+	}
+	ensureActive("retrying")
+	if tracker.cancel != nil {
+		t.Fatalf("booked child order canceled while retrying")
+	}
+
+	// The scheduled order fails when the retries are exhausted, and the booked
+	// child order is canceled.
+	rig.queueCancel(nil)
+	if !tCore.stepScheduledOrder(so, retry, retry.next) {
+		t.Fatalf("scheduled order not done after exhausting retries")
+	}
+	stored, _ := rig.db.ScheduledOrder(so.ID)
+	if stored.Status != db.ScheduledOrderFailed || stored.Err == "" {
+		t.Fatalf("scheduled order not failed after exhausting retries")
+	}
+	if tracker.cancel == nil {
+		t.Fatalf("booked child order not canceled")
+	}
+}
@@ -0,0 +1,485 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package core
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"decred.org/dcrdex/client/db"
+	"decred.org/dcrdex/client/orderbook"
+	"decred.org/dcrdex/dex"
+	"decred.org/dcrdex/dex/encode"
+)
+
+// stopWatchRetry is how long a stop order watcher waits before attempting to
+// resubscribe to a market's order book.
+var stopWatchRetry = 30 * time.Second
+
+// stopWatcher watches a market's order book on behalf of the market's waiting
+// stop orders.
+type stopWatcher struct {
+	host        string
+	base, quote uint32
+	cancel      context.CancelFunc
+}
+
+func stopWatcherKey(host string, base, quote uint32) string {
+	return host + "|" + marketName(base, quote)
+}
+
+func coreStopOrder(so *db.StopOrder) *StopOrder {
+	cso := &StopOrder{
+		ID:           so.ID,
+		Type:         so.Type.String(),
+		Host:         so.Host,
+		BaseID:       so.Base,
+		QuoteID:      so.Quote,
+		MarketID:     marketName(so.Base, so.Quote),
+		Sell:         so.Sell,
+		Qty:          so.Qty,
+		PriceSource:  so.PriceSource.String(),
+		TriggerRate:  so.TriggerRate,
+		Rate:         so.Rate,
+		TrailDelta:   so.TrailDelta,
+		LimitOffset:  so.LimitOffset,
+		RefRate:      so.RefRate,
+		TifNow:       so.TifNow,
+		Options:      so.Options,
+		Status:       so.Status.String(),
+		Stamp:        so.Stamp,
+		TriggerStamp: so.TriggerStamp,
+		Err:          so.Err,
+	}
+	if !so.OrderID.IsZero() {
+		cso.OrderID = so.OrderID[:]
+	}
+	return cso
+}
+
+// stopOrderTriggered checks whether the stop order is triggered at the market
+// rate. The RefRate of a TrailingStop is updated first, and refUpdated is true
+// if it changed.
+func stopOrderTriggered(so *db.StopOrder, rate uint64) (triggered, refUpdated bool) {
+	switch so.Type {
+	case db.StopLimit:
+		if so.Sell {
+			return rate <= so.TriggerRate, false
+		}
+		return rate >= so.TriggerRate, false
+	case db.TrailingStop:
+		if so.Sell {
+			if rate > so.RefRate {
+				so.RefRate, refUpdated = rate, true
+			}
+			return so.RefRate-rate >= so.TrailDelta, refUpdated
+		}
+		if so.RefRate == 0 || rate < so.RefRate {
+			so.RefRate, refUpdated = rate, true
+		}
+		return rate-so.RefRate >= so.TrailDelta, refUpdated
+	}
+	return false, false
+}
+
+// stopOrderLimitRate is the rate of the limit order placed when the stop order
+// is triggered. The trigger rate of a TrailingStop is recorded in TriggerRate,
+// and its limit rate is rounded to the market's rate step in the direction
+// that favors a match.
+func stopOrderLimitRate(so *db.StopOrder, rateStep uint64) uint64 {
+	if so.Type != db.TrailingStop {
+		return so.Rate
+	}
+	var rate uint64
+	if so.Sell {
+		so.TriggerRate = so.RefRate - min(so.TrailDelta, so.RefRate)
+		rate = so.TriggerRate - min(so.LimitOffset, so.TriggerRate)
+		if rateStep > 0 {
+			rate -= rate % rateStep
+		}
+		return max(rate, rateStep)
+	}
+	so.TriggerRate = so.RefRate + so.TrailDelta
+	rate = so.TriggerRate + so.LimitOffset
+	if rateStep > 0 && rate%rateStep != 0 {
+		rate += rateStep - rate%rateStep
+	}
+	return rate
+}
+
+// PlaceStopOrder validates and stores a client-side stop order. The market's
+// order book is watched, and a limit order is placed when the stop order is
+// triggered. Funds are not reserved until the stop order is triggered, and the
+// wallets must be unlocked at that time. Waiting stop orders are resumed on
+// login.
+func (c *Core) PlaceStopOrder(form *StopOrderForm) (*StopOrder, error) {
+	dc, err := c.registeredDEX(form.Host)
+	if err != nil {
+		return nil, err
+	}
+	mktID := marketName(form.Base, form.Quote)
+	mktConf := dc.marketConfig(mktID)
+	if mktConf == nil {
+		return nil, newError(marketErr, "stop order placed for unknown market %q", mktID)
+	}
+	if _, _, _, err := c.walletSet(dc, form.Base, form.Quote, form.Sell); err != nil {
+		return nil, err
+	}
+
+	so := &db.StopOrder{
+		ID:          encode.RandomBytes(8),
+		Host:        dc.acct.host,
+		Base:        form.Base,
+		Quote:       form.Quote,
+		Sell:        form.Sell,
+		Qty:         form.Qty,
+		TriggerRate: form.TriggerRate,
+		Rate:        form.Rate,
+		TrailDelta:  form.TrailDelta,
+		LimitOffset: form.LimitOffset,
+		TifNow:      form.TifNow,
+		Options:     form.Options,
+		Status:      db.StopOrderWaiting,
+		Stamp:       uint64(time.Now().UnixMilli()),
+	}
+	switch form.PriceSource {
+	case "", PriceSourceMidGap:
+		so.PriceSource = db.PriceSourceMidGap
+	case PriceSourceLastTrade:
+		so.PriceSource = db.PriceSourceLastTrade
+	default:
+		return nil, newError(orderParamsErr, "unknown price source %q", form.PriceSource)
+	}
+
+	if so.Qty == 0 || so.Qty%mktConf.LotSize != 0 {
+		return nil, newError(orderParamsErr, "stop order quantity %d is not a multiple of the lot size %d", so.Qty, mktConf.LotSize)
+	}
+	switch form.Type {
+	case StopOrderTypeStopLimit:
+		so.Type = db.StopLimit
+		if so.TriggerRate == 0 || so.Rate == 0 {
+			return nil, newError(orderParamsErr, "stop-limit orders require a trigger rate and a limit rate")
+		}
+		if so.Rate%mktConf.RateStep != 0 {
+			return nil, newError(orderParamsErr, "stop order rate %d is not a multiple of the rate step %d", so.Rate, mktConf.RateStep)
+		}
+		so.TrailDelta, so.LimitOffset = 0, 0
+	case StopOrderTypeTrailingStop:
+		so.Type = db.TrailingStop
+		if so.TrailDelta == 0 {
+			return nil, newError(orderParamsErr, "trailing stop orders require a trail delta")
+		}
+		so.TriggerRate, so.Rate = 0, 0
+	default:
+		return nil, newError(orderParamsErr, "unknown stop order type %q", form.Type)
+	}
+
+	if err := c.db.UpdateStopOrder(so); err != nil {
+		return nil, codedError(dbErr, err)
+	}
+
+	c.stopOrdersMtx.Lock()
+	c.stopOrders[so.ID.String()] = so
+	c.startStopWatcher(so.Host, so.Base, so.Quote)
+	cso := coreStopOrder(so)
+	c.stopOrdersMtx.Unlock()
+
+	subject, details := c.formatDetails(TopicStopOrderPlaced, so.Type, mktID, so.ID)
+	c.notify(newStopOrderNote(TopicStopOrderPlaced, subject, details, db.Success, cso))
+
+	return cso, nil
+}
+
+// CancelStopOrder cancels a stop order that has not yet been triggered.
+func (c *Core) CancelStopOrder(id dex.Bytes) error {
+	// The stopOrdersMtx is held until the cancellation is stored, so the stop
+	// order can not be triggered in the meantime.
+	c.stopOrdersMtx.Lock()
+	so, found := c.stopOrders[id.String()]
+	if !found {
+		// Waiting stop orders are not loaded until login. Triggered stop
+		// orders are stored as triggered before they leave c.stopOrders.
+		sos, err := c.db.StopOrders(false)
+		if err != nil {
+			c.stopOrdersMtx.Unlock()
+			return codedError(dbErr, err)
+		}
+		for _, s := range sos {
+			if s.ID.String() == id.String() {
+				so = s
+				break
+			}
+		}
+		if so == nil {
+			c.stopOrdersMtx.Unlock()
+			return newError(unknownOrderErr, "unknown stop order %s", id)
+		}
+		if so.Status != db.StopOrderWaiting {
+			c.stopOrdersMtx.Unlock()
+			return newError(orderParamsErr, "stop order %s is %s, not waiting", id, so.Status)
+		}
+	}
+
+	so.Status = db.StopOrderCanceled
+	if err := c.db.UpdateStopOrder(so); err != nil {
+		so.Status = db.StopOrderWaiting
+		c.stopOrdersMtx.Unlock()
+		return codedError(dbErr, err)
+	}
+	delete(c.stopOrders, id.String())
+	c.stopOrdersMtx.Unlock()
+
+	subject, details := c.formatDetails(TopicStopOrderCanceled, so.Type, marketName(so.Base, so.Quote), so.ID)
+	c.notify(newStopOrderNote(TopicStopOrderCanceled, subject, details, db.Success, coreStopOrder(so)))
+	return nil
+}
+
+// StopOrders retrieves stop orders, newest first. If activeOnly is true, only
+// stop orders that are waiting to be triggered are returned.
+func (c *Core) StopOrders(activeOnly bool) ([]*StopOrder, error) {
+	sos, err := c.db.StopOrders(activeOnly)
+	if err != nil {
+		return nil, codedError(dbErr, err)
+	}
+	csos := make([]*StopOrder, 0, len(sos))
+	for _, so := range sos {
+		csos = append(csos, coreStopOrder(so))
+	}
+	return csos, nil
+}
+
+// resumeStopOrders loads the waiting stop orders from the DB and starts
+// watching their markets.
+func (c *Core) resumeStopOrders() {
+	sos, err := c.db.StopOrders(true)
+	if err != nil {
+		c.log.Errorf("Error loading stop orders: %v", err)
+		return
+	}
+	if len(sos) == 0 {
+		return
+	}
+	c.log.Infof("Resuming %d stop orders", len(sos))
+	c.stopOrdersMtx.Lock()
+	defer c.stopOrdersMtx.Unlock()
+	for _, so := range sos {
+		c.stopOrders[so.ID.String()] = so
+		c.startStopWatcher(so.Host, so.Base, so.Quote)
+	}
+}
+
+// stopStopWatchers stops watching for stop order triggers. Waiting stop orders
+// remain in the DB, and are resumed on the next login.
+func (c *Core) stopStopWatchers() {
+	c.stopOrdersMtx.Lock()
+	defer c.stopOrdersMtx.Unlock()
+	for k, w := range c.stopWatchers {
+		w.cancel()
+		delete(c.stopWatchers, k)
+	}
+	c.stopOrders = make(map[string]*db.StopOrder)
+}
+
+// startStopWatcher starts watching the market's order book for the market's
+// waiting stop orders, if it is not already being watched. The stopOrdersMtx
+// MUST be locked.
+func (c *Core) startStopWatcher(host string, base, quote uint32) {
+	k := stopWatcherKey(host, base, quote)
+	if _, found := c.stopWatchers[k]; found {
+		return
+	}
+	ctx, cancel := context.WithCancel(c.ctx)
+	w := &stopWatcher{
+		host:   host,
+		base:   base,
+		quote:  quote,
+		cancel: cancel,
+	}
+	c.stopWatchers[k] = w
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		defer cancel()
+		c.watchStopMarket(ctx, w)
+	}()
+}
+
+// retireStopWatcher removes the stopWatcher if there are no more waiting stop
+// orders for its market. The return value indicates whether the watcher was
+// retired.
+func (c *Core) retireStopWatcher(w *stopWatcher) bool {
+	c.stopOrdersMtx.Lock()
+	defer c.stopOrdersMtx.Unlock()
+	for _, so := range c.stopOrders {
+		if so.Host == w.host && so.Base == w.base && so.Quote == w.quote {
+			return false
+		}
+	}
+	k := stopWatcherKey(w.host, w.base, w.quote)
+	if c.stopWatchers[k] == w {
+		delete(c.stopWatchers, k)
+	}
+	return true
+}
+
+// watchStopMarket subscribes to the market's order book and checks the
+// market's stop orders with every book update. If the book feed is closed,
+// e.g. when the DEX connection is stopped, the subscription is retried until
+// the context is canceled or there are no more waiting stop orders for the
+// market.
+func (c *Core) watchStopMarket(ctx context.Context, w *stopWatcher) {
+	mktID := marketName(w.base, w.quote)
+	for {
+		feedClosed := true
+		book, feed, err := c.SyncBook(w.host, w.base, w.quote)
+		if err != nil {
+			c.log.Errorf("Error syncing %s order book at %s for stop orders: %v", mktID, w.host, err)
+		} else {
+			feedClosed = c.runStopFeed(ctx, w, book, feed)
+			feed.Close()
+		}
+		if ctx.Err() != nil || c.retireStopWatcher(w) {
+			return
+		}
+		if !feedClosed {
+			continue
+		}
+		select {
+		case <-time.After(stopWatchRetry):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// runStopFeed checks the market's stop orders with every book update until
+// there are no more waiting stop orders for the market, the context is
+// canceled, or the feed is closed, in which case feedClosed is true.
+func (c *Core) runStopFeed(ctx context.Context, w *stopWatcher, book *orderbook.OrderBook, feed BookFeed) (feedClosed bool) {
+	for {
+		select {
+		case _, ok := <-feed.Next():
+			if !ok {
+				return true
+			}
+			if c.checkStopOrders(w, book) == 0 {
+				return false
+			}
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+// checkStopOrders checks the market's waiting stop orders against the order
+// book, and places limit orders for those that are triggered. The number of
+// stop orders still waiting on the market is returned.
+func (c *Core) checkStopOrders(w *stopWatcher, book *orderbook.OrderBook) (waiting int) {
+	midGap, _ := book.MidGap() // zero for an empty book
+	var lastTrade uint64
+	if matches := book.RecentMatches(); len(matches) > 0 {
+		lastTrade = matches[0].Rate // newest first
+	}
+
+	var triggered []*db.StopOrder
+	c.stopOrdersMtx.Lock()
+	for id, so := range c.stopOrders {
+		if so.Host != w.host || so.Base != w.base || so.Quote != w.quote {
+			continue
+		}
+		rate := midGap
+		if so.PriceSource == db.PriceSourceLastTrade {
+			rate = lastTrade
+		}
+		if rate == 0 {
+			waiting++
+			continue
+		}
+		trigger, refUpdated := stopOrderTriggered(so, rate)
+		if trigger {
+			// Store the trigger before the stop order leaves c.stopOrders,
+			// so that CancelStopOrder can not cancel it from the DB, and a
+			// restart can not trigger it a second time.
+			so.Status = db.StopOrderTriggered
+			so.TriggerStamp = uint64(time.Now().UnixMilli())
+			if err := c.db.UpdateStopOrder(so); err != nil {
+				c.log.Errorf("Error storing triggered stop order %s: %v", so.ID, err)
+				so.Status, so.TriggerStamp = db.StopOrderWaiting, 0
+				waiting++
+				continue
+			}
+			delete(c.stopOrders, id)
+			triggered = append(triggered, so)
+			continue
+		}
+		waiting++
+		if refUpdated {
+			if err := c.db.UpdateStopOrder(so); err != nil {
+				c.log.Errorf("Error updating reference rate for stop order %s: %v", so.ID, err)
+			}
+		}
+	}
+	c.stopOrdersMtx.Unlock()
+
+	for _, so := range triggered {
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			c.executeStopOrder(so)
+		}()
+	}
+	return waiting
+}
+
+// executeStopOrder places the limit order for a triggered stop order.
+func (c *Core) executeStopOrder(so *db.StopOrder) {
+	mktID := marketName(so.Base, so.Quote)
+	fail := func(err error) {
+		so.Status = db.StopOrderFailed
+		so.Err = err.Error()
+		if err := c.db.UpdateStopOrder(so); err != nil {
+			c.log.Errorf("Error storing failed stop order %s: %v", so.ID, err)
+		}
+		subject, details := c.formatDetails(TopicStopOrderFailed, so.Type, mktID, so.ID, err)
+		c.notify(newStopOrderNote(TopicStopOrderFailed, subject, details, db.ErrorLevel, coreStopOrder(so)))
+	}
+
+	dc, _, err := c.dex(so.Host)
+	if err != nil {
+		fail(err)
+		return
+	}
+	mktConf := dc.marketConfig(mktID)
+	if mktConf == nil {
+		fail(fmt.Errorf("market %s not found", mktID))
+		return
+	}
+	so.Rate = stopOrderLimitRate(so, mktConf.RateStep)
+	if err := c.db.UpdateStopOrder(so); err != nil {
+		c.log.Errorf("Error storing limit rate for stop order %s: %v", so.ID, err)
+	}
+
+	corder, err := c.Trade(nil, &TradeForm{
+		Host:    so.Host,
+		IsLimit: true,
+		Sell:    so.Sell,
+		Base:    so.Base,
+		Quote:   so.Quote,
+		Qty:     so.Qty,
+		Rate:    so.Rate,
+		TifNow:  so.TifNow,
+		Options: so.Options,
+	})
+	if err != nil {
+		fail(err)
+		return
+	}
+	copy(so.OrderID[:], corder.ID)
+	if err := c.db.UpdateStopOrder(so); err != nil {
+		c.log.Errorf("Error storing order ID for stop order %s: %v", so.ID, err)
+	}
+	subject, details := c.formatDetails(TopicStopOrderTriggered, so.Type, mktID, so.ID, so.OrderID)
+	c.notify(newStopOrderNote(TopicStopOrderTriggered, subject, details, db.Success, coreStopOrder(so)))
+}
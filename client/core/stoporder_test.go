@@ -0,0 +1,419 @@
+//go:build !harness && !botlive
+
+package core
+
+import (
+	"testing"
+	"time"
+
+	"decred.org/dcrdex/client/db"
+	"decred.org/dcrdex/dex/encode"
+	"decred.org/dcrdex/dex/msgjson"
+)
+
+func TestStopOrderTriggered(t *testing.T) {
+	type check struct {
+		rate       uint64
+		triggered  bool
+		refUpdated bool
+	}
+	tests := []struct {
+		name   string
+		so     *db.StopOrder
+		checks []check
+	}{
+		{
+			name: "stop-limit sell",
+			so:   &db.StopOrder{Type: db.StopLimit, Sell: true, TriggerRate: 100},
+			checks: []check{
+				{rate: 101},
+				{rate: 100, triggered: true},
+			},
+		},
+		{
+			name: "stop-limit buy",
+			so:   &db.StopOrder{Type: db.StopLimit, TriggerRate: 100},
+			checks: []check{
+				{rate: 99},
+				{rate: 100, triggered: true},
+			},
+		},
+		{
+			name: "trailing sell",
+			so:   &db.StopOrder{Type: db.TrailingStop, Sell: true, TrailDelta: 10},
+			checks: []check{
+				{rate: 100, refUpdated: true},
+				{rate: 105, refUpdated: true},
+				{rate: 96},
+				{rate: 95, triggered: true},
+			},
+		},
+		{
+			name: "trailing buy",
+			so:   &db.StopOrder{Type: db.TrailingStop, TrailDelta: 10},
+			checks: []check{
+				{rate: 100, refUpdated: true},
+				{rate: 90, refUpdated: true},
+				{rate: 99},
+				{rate: 100, triggered: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		for i, c := range tt.checks {
+			triggered, refUpdated := stopOrderTriggered(tt.so, c.rate)
+			if triggered != c.triggered {
+				t.Fatalf("%s: check %d: expected triggered = %t, got %t", tt.name, i, c.triggered, triggered)
+			}
+			if refUpdated != c.refUpdated {
+				t.Fatalf("%s: check %d: expected refUpdated = %t, got %t", tt.name, i, c.refUpdated, refUpdated)
+			}
+		}
+	}
+}
+
+func TestStopOrderLimitRate(t *testing.T) {
+	tests := []struct {
+		name           string
+		so             *db.StopOrder
+		rateStep       uint64
+		expTriggerRate uint64
+		expRate        uint64
+	}{
+		{
+			name:     "stop-limit",
+			so:       &db.StopOrder{Type: db.StopLimit, TriggerRate: 100, Rate: 90},
+			rateStep: 5,
+			// The trigger rate is not altered.
+			expTriggerRate: 100,
+			expRate:        90,
+		},
+		{
+			name:           "trailing sell",
+			so:             &db.StopOrder{Type: db.TrailingStop, Sell: true, RefRate: 105, TrailDelta: 10, LimitOffset: 3},
+			rateStep:       2,
+			expTriggerRate: 95,
+			expRate:        92,
+		},
+		{
+			name:           "trailing sell rounded down",
+			so:             &db.StopOrder{Type: db.TrailingStop, Sell: true, RefRate: 105, TrailDelta: 10, LimitOffset: 3},
+			rateStep:       5,
+			expTriggerRate: 95,
+			expRate:        90,
+		},
+		{
+			name:           "trailing sell at least one rate step",
+			so:             &db.StopOrder{Type: db.TrailingStop, Sell: true, RefRate: 8, TrailDelta: 5, LimitOffset: 10},
+			rateStep:       5,
+			expTriggerRate: 3,
+			expRate:        5,
+		},
+		{
+			name:           "trailing buy rounded up",
+			so:             &db.StopOrder{Type: db.TrailingStop, RefRate: 90, TrailDelta: 10, LimitOffset: 3},
+			rateStep:       5,
+			expTriggerRate: 100,
+			expRate:        105,
+		},
+	}
+	for _, tt := range tests {
+		rate := stopOrderLimitRate(tt.so, tt.rateStep)
+		if rate != tt.expRate {
+			t.Fatalf("%s: expected rate %d, got %d", tt.name, tt.expRate, rate)
+		}
+		if tt.so.TriggerRate != tt.expTriggerRate {
+			t.Fatalf("%s: expected trigger rate %d, got %d", tt.name, tt.expTriggerRate, tt.so.TriggerRate)
+		}
+	}
+}
+
+// syncStopOrderBook sets a synced order book for the dcr_btc market with a
+// single buy and sell order.
+func syncStopOrderBook(t *testing.T, rig *testRig, buyRate, sellRate uint64) *bookie {
+	t.Helper()
+	book := newBookie(rig.dc, tUTXOAssetA.ID, tUTXOAssetB.ID, nil, tLogger)
+	bookNote := func(side uint8, rate uint64) *msgjson.BookOrderNote {
+		return &msgjson.BookOrderNote{
+			OrderNote: msgjson.OrderNote{
+				OrderID: encode.RandomBytes(32),
+			},
+			TradeNote: msgjson.TradeNote{
+				Side:     side,
+				Quantity: dcrBtcLotSize,
+				Time:     uint64(time.Now().Unix()),
+				Rate:     rate,
+			},
+		}
+	}
+	err := book.Sync(&msgjson.OrderBook{
+		MarketID: tDcrBtcMktName,
+		Seq:      1,
+		Epoch:    1,
+		Orders: []*msgjson.BookOrderNote{
+			bookNote(msgjson.BuyOrderNum, buyRate),
+			bookNote(msgjson.SellOrderNum, sellRate),
+		},
+	})
+	if err != nil {
+		t.Fatalf("order book sync error: %v", err)
+	}
+	rig.dc.booksMtx.Lock()
+	rig.dc.books[tDcrBtcMktName] = book
+	rig.dc.booksMtx.Unlock()
+	return book
+}
+
+func TestPlaceStopOrder(t *testing.T) {
+	rig := newTestRig()
+	defer rig.shutdown()
+	tCore := rig.core
+
+	dcrWallet, _ := newTWallet(tUTXOAssetA.ID)
+	tCore.wallets[tUTXOAssetA.ID] = dcrWallet
+	btcWallet, _ := newTWallet(tUTXOAssetB.ID)
+	tCore.wallets[tUTXOAssetB.ID] = btcWallet
+
+	midGap := dcrBtcRateStep * 1000
+	syncStopOrderBook(t, rig, midGap-dcrBtcRateStep*100, midGap+dcrBtcRateStep*100)
+
+	newForm := func() *StopOrderForm {
+		return &StopOrderForm{
+			Host:        tDexHost,
+			Type:        StopOrderTypeStopLimit,
+			Sell:        true,
+			Base:        tUTXOAssetA.ID,
+			Quote:       tUTXOAssetB.ID,
+			Qty:         dcrBtcLotSize * 10,
+			TriggerRate: midGap / 2,
+			Rate:        midGap / 2,
+		}
+	}
+
+	ensureErr := func(tag string, form *StopOrderForm) {
+		t.Helper()
+		if _, err := tCore.PlaceStopOrder(form); err == nil {
+			t.Fatalf("%s: no error", tag)
+		}
+	}
+
+	form := newForm()
+	form.Qty = dcrBtcLotSize + 1
+	ensureErr("not a lot multiple", form)
+
+	form = newForm()
+	form.Rate = dcrBtcRateStep + 1
+	ensureErr("not a rate step multiple", form)
+
+	form = newForm()
+	form.TriggerRate = 0
+	ensureErr("no trigger rate", form)
+
+	form = newForm()
+	form.Type = "stoploss"
+	ensureErr("unknown type", form)
+
+	form = newForm()
+	form.PriceSource = "vwap"
+	ensureErr("unknown price source", form)
+
+	form = newForm()
+	form.Type = StopOrderTypeTrailingStop
+	ensureErr("no trail delta", form)
+
+	form = newForm()
+	form.Quote = tACCTAsset.ID
+	ensureErr("unknown market", form)
+
+	form = newForm()
+	form.Host = "unknown.dex"
+	ensureErr("unknown host", form)
+
+	so, err := tCore.PlaceStopOrder(newForm())
+	if err != nil {
+		t.Fatalf("PlaceStopOrder error: %v", err)
+	}
+	if so.Status != db.StopOrderWaiting.String() {
+		t.Fatalf("wrong status %s", so.Status)
+	}
+	if so.PriceSource != PriceSourceMidGap {
+		t.Fatalf("wrong price source %s", so.PriceSource)
+	}
+	sos, _ := rig.db.StopOrders(true)
+	if len(sos) != 1 {
+		t.Fatalf("expected 1 waiting stop order in the DB, found %d", len(sos))
+	}
+	tCore.stopOrdersMtx.Lock()
+	_, watching := tCore.stopWatchers[stopWatcherKey(tDexHost, tUTXOAssetA.ID, tUTXOAssetB.ID)]
+	tCore.stopOrdersMtx.Unlock()
+	if !watching {
+		t.Fatalf("market not watched")
+	}
+
+	if err := tCore.CancelStopOrder(so.ID); err != nil {
+		t.Fatalf("CancelStopOrder error: %v", err)
+	}
+	if err := tCore.CancelStopOrder(so.ID); err == nil {
+		t.Fatalf("no error canceling a canceled stop order")
+	}
+	csos, err := tCore.StopOrders(false)
+	if err != nil {
+		t.Fatalf("StopOrders error: %v", err)
+	}
+	if len(csos) != 1 || csos[0].Status != db.StopOrderCanceled.String() {
+		t.Fatalf("canceled stop order not found")
+	}
+	tCore.stopOrdersMtx.Lock()
+	numWaiting := len(tCore.stopOrders)
+	tCore.stopOrdersMtx.Unlock()
+	if numWaiting != 0 {
+		t.Fatalf("canceled stop order still waiting")
+	}
+}
+
+func TestCheckStopOrders(t *testing.T) {
+	rig := newTestRig()
+	defer rig.shutdown()
+	tCore := rig.core
+
+	step := dcrBtcRateStep
+	midGap := step * 1000
+	book := syncStopOrderBook(t, rig, midGap-step*100, midGap+step*100)
+
+	w := &stopWatcher{host: tDexHost, base: tUTXOAssetA.ID, quote: tUTXOAssetB.ID}
+	addStopOrder := func(so *db.StopOrder) *db.StopOrder {
+		so.ID = encode.RandomBytes(8)
+		so.Host, so.Base, so.Quote = w.host, w.base, w.quote
+		so.Qty = dcrBtcLotSize
+		so.Status = db.StopOrderWaiting
+		tCore.stopOrdersMtx.Lock()
+		tCore.stopOrders[so.ID.String()] = so
+		tCore.stopOrdersMtx.Unlock()
+		rig.db.UpdateStopOrder(so)
+		return so
+	}
+
+	// Neither order is triggered at the current mid-gap.
+	stopLimit := addStopOrder(&db.StopOrder{Type: db.StopLimit, Sell: true, TriggerRate: midGap - step, Rate: midGap - step*2})
+	trailing := addStopOrder(&db.StopOrder{Type: db.TrailingStop, Sell: true, TrailDelta: step * 50})
+	// Last trade orders are not checked without any matches.
+	addStopOrder(&db.StopOrder{Type: db.StopLimit, PriceSource: db.PriceSourceLastTrade, TriggerRate: 1, Rate: step})
+
+	if waiting := tCore.checkStopOrders(w, book.OrderBook); waiting != 3 {
+		t.Fatalf("expected 3 waiting stop orders, got %d", waiting)
+	}
+	sos, _ := rig.db.StopOrders(true)
+	for _, so := range sos {
+		if so.ID.String() == trailing.ID.String() && so.RefRate != midGap {
+			t.Fatalf("trailing stop reference rate not stored. expected %d, got %d", midGap, so.RefRate)
+		}
+	}
+
+	// Raise the stop-limit trigger above the mid-gap.
+	tCore.stopOrdersMtx.Lock()
+	stopLimit.TriggerRate = midGap
+	tCore.stopOrdersMtx.Unlock()
+	if waiting := tCore.checkStopOrders(w, book.OrderBook); waiting != 2 {
+		t.Fatalf("expected 2 waiting stop orders, got %d", waiting)
+	}
+
+	// There are no wallets, so placing the limit order fails.
+	timeout := time.After(time.Second * 5)
+	for {
+		sos, _ := rig.db.StopOrders(false)
+		var status db.StopOrderStatus
+		for _, so := range sos {
+			if so.ID.String() == stopLimit.ID.String() {
+				status = so.Status
+				if status == db.StopOrderFailed && (so.Err == "" || so.TriggerStamp == 0) {
+					t.Fatalf("failed stop order missing error or trigger stamp")
+				}
+			}
+		}
+		if status == db.StopOrderFailed {
+			break
+		}
+		select {
+		case <-timeout:
+			t.Fatalf("stop order not failed. status = %s", status)
+		case <-time.After(time.Millisecond * 10):
+		}
+	}
+}
+
+func TestCancelTriggeredStopOrder(t *testing.T) {
+	rig := newTestRig()
+	defer rig.shutdown()
+	tCore := rig.core
+
+	step := dcrBtcRateStep
+	midGap := step * 1000
+	book := syncStopOrderBook(t, rig, midGap-step*100, midGap+step*100)
+
+	w := &stopWatcher{host: tDexHost, base: tUTXOAssetA.ID, quote: tUTXOAssetB.ID}
+	so := &db.StopOrder{
+		ID:          encode.RandomBytes(8),
+		Host:        w.host,
+		Base:        w.base,
+		Quote:       w.quote,
+		Type:        db.StopLimit,
+		Sell:        true,
+		Qty:         dcrBtcLotSize,
+		TriggerRate: midGap,
+		Rate:        midGap - step,
+		Status:      db.StopOrderWaiting,
+	}
+	tCore.stopOrdersMtx.Lock()
+	tCore.stopOrders[so.ID.String()] = so
+	tCore.stopOrdersMtx.Unlock()
+	rig.db.UpdateStopOrder(so)
+
+	// The stop order is triggered, and its limit order is placed in a
+	// goroutine. Canceling it before the limit order is placed must fail,
+	// since the limit order will still be placed.
+	if waiting := tCore.checkStopOrders(w, book.OrderBook); waiting != 0 {
+		t.Fatalf("expected no waiting stop orders, got %d", waiting)
+	}
+	if err := tCore.CancelStopOrder(so.ID); err == nil {
+		t.Fatalf("no error canceling a triggered stop order")
+	}
+
+	// There are no wallets, so placing the limit order fails, and the stop
+	// order must not have been canceled in between.
+	timeout := time.After(time.Second * 5)
+	for {
+		sos, _ := rig.db.StopOrders(false)
+		if len(sos) != 1 {
+			t.Fatalf("expected 1 stop order, got %d", len(sos))
+		}
+		if sos[0].Status == db.StopOrderFailed {
+			break
+		}
+		if sos[0].Status != db.StopOrderTriggered {
+			t.Fatalf("wrong stop order status %s", sos[0].Status)
+		}
+		select {
+		case <-timeout:
+			t.Fatalf("stop order not failed")
+		case <-time.After(time.Millisecond * 10):
+		}
+	}
+
+	// Stop orders that are no longer waiting can not be canceled.
+	if err := tCore.CancelStopOrder(so.ID); err == nil {
+		t.Fatalf("no error canceling a failed stop order")
+	}
+
+	// A waiting stop order that is not loaded yet is canceled from the DB.
+	so2 := *so
+	so2.ID = encode.RandomBytes(8)
+	so2.Status = db.StopOrderWaiting
+	rig.db.UpdateStopOrder(&so2)
+	if err := tCore.CancelStopOrder(so2.ID); err != nil {
+		t.Fatalf("CancelStopOrder error: %v", err)
+	}
+	sos, _ := rig.db.StopOrders(true)
+	if len(sos) != 0 {
+		t.Fatalf("stop order not canceled")
+	}
+}
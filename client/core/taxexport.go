@@ -0,0 +1,620 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package core
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"decred.org/dcrdex/client/asset"
+	"decred.org/dcrdex/client/db"
+	"decred.org/dcrdex/dex/calc"
+)
+
+const (
+	// fiatRateTolerance is the maximum time between a ledger entry and the
+	// fiat rate snapshot used to value it.
+	fiatRateTolerance = uint64(24 * time.Hour / time.Millisecond)
+	// longTermHolding is the holding period after which a gain is considered
+	// long-term.
+	longTermHolding = uint64(365 * 24 * time.Hour / time.Millisecond)
+	// fiatSnapshotRetention is how long fiat rate snapshots are kept at
+	// fiatSnapshotInterval resolution. Older snapshots are downsampled to one
+	// per fiatSnapshotDownsample, which is well within fiatRateTolerance.
+	fiatSnapshotRetention  = 30 * 24 * time.Hour
+	fiatSnapshotDownsample = 12 * time.Hour
+	// fiatPruneInterval is the minimum amount of time between prunes of the
+	// fiat rate snapshots.
+	fiatPruneInterval = 24 * time.Hour
+)
+
+// fiatRateHistory is a time-ordered list of fiat rate snapshots.
+type fiatRateHistory []*db.FiatRateSnapshot
+
+// rate is the fiat rate for the asset from the snapshot nearest to the time
+// stamp. Zero is returned if there is no rate for the asset within
+// fiatRateTolerance of the time stamp.
+func (h fiatRateHistory) rate(assetID uint32, stamp uint64) float64 {
+	dist := func(snap *db.FiatRateSnapshot) uint64 {
+		if snap.Stamp > stamp {
+			return snap.Stamp - stamp
+		}
+		return stamp - snap.Stamp
+	}
+	// Search outward from the insertion point.
+	j := sort.Search(len(h), func(i int) bool { return h[i].Stamp >= stamp })
+	i := j - 1
+	for i >= 0 || j < len(h) {
+		var snap *db.FiatRateSnapshot
+		if j >= len(h) || (i >= 0 && dist(h[i]) <= dist(h[j])) {
+			snap = h[i]
+			i--
+		} else {
+			snap = h[j]
+			j++
+		}
+		if dist(snap) > fiatRateTolerance {
+			return 0
+		}
+		if r := snap.Rates[assetID]; r > 0 {
+			return r
+		}
+	}
+	return 0
+}
+
+// fiatRateLookup finds historical fiat rates. Only the snapshots near the
+// requested time stamps are loaded from the database, one day at a time.
+type fiatRateLookup struct {
+	db db.DB
+	// current is the current fiat rates. May be nil.
+	current *db.FiatRateSnapshot
+	days    map[uint64]fiatRateHistory
+	// err is the first error loading snapshots.
+	err error
+}
+
+func newFiatRateLookup(d db.DB, current *db.FiatRateSnapshot) *fiatRateLookup {
+	return &fiatRateLookup{
+		db:      d,
+		current: current,
+		days:    make(map[uint64]fiatRateHistory),
+	}
+}
+
+// rate is the fiat rate for the asset from the snapshot nearest to the time
+// stamp, or zero if there is no rate for the asset within fiatRateTolerance of
+// the time stamp.
+func (l *fiatRateLookup) rate(assetID uint32, stamp uint64) float64 {
+	day := stamp / fiatRateTolerance
+	h, found := l.days[day]
+	if !found {
+		// Load every snapshot within fiatRateTolerance of any time in the
+		// day.
+		start := day * fiatRateTolerance
+		start -= min(start, fiatRateTolerance)
+		end := (day + 2) * fiatRateTolerance
+		snaps, err := l.db.FiatRateHistory(start, end)
+		if err != nil && l.err == nil {
+			l.err = fmt.Errorf("error retrieving fiat rate history: %w", err)
+		}
+		h = fiatRateHistory(snaps)
+		if l.current != nil && l.current.Stamp >= start && l.current.Stamp <= end {
+			h = append(h, l.current)
+		}
+		l.days[day] = h
+	}
+	return h.rate(assetID, stamp)
+}
+
+// taxBasisAction is how a ledger entry affects the cost basis of an asset.
+type taxBasisAction uint8
+
+const (
+	// taxBasisNone entries do not affect the cost basis, e.g. bonds, which
+	// remain the property of the user.
+	taxBasisNone taxBasisAction = iota
+	// taxBasisAcquire entries create a new lot.
+	taxBasisAcquire
+	// taxBasisDispose entries consume lots and realize a gain or loss.
+	taxBasisDispose
+	// taxBasisTransfer entries consume lots without realizing a gain or
+	// loss, e.g. a send to an external address, which may be owned by the
+	// user.
+	taxBasisTransfer
+)
+
+// taxEvent is a ledger entry with its cost basis treatment.
+type taxEvent struct {
+	*TaxLedgerEntry
+	action taxBasisAction
+	// value is the fiat cost of an acquisition or the fiat proceeds of a
+	// disposal.
+	value float64
+	// noValue is true if the value is unknown because there is no fiat rate.
+	noValue bool
+	ref     string
+}
+
+// taxLot is a quantity of an asset acquired at the same time and price.
+type taxLot struct {
+	stamp uint64
+	qty   uint64
+	cost  float64
+	// noCost is true if the cost is unknown, either because there was no
+	// fiat rate at the time of acquisition or because the lot is not in the
+	// history.
+	noCost bool
+}
+
+// costBasisTracker tracks the lots of each asset and matches disposals to
+// lots.
+type costBasisTracker struct {
+	lifo bool
+	lots map[uint32][]*taxLot
+}
+
+func newCostBasisTracker(method string) *costBasisTracker {
+	return &costBasisTracker{
+		lifo: method == CostBasisLIFO,
+		lots: make(map[uint32][]*taxLot),
+	}
+}
+
+// acquire adds a lot for the asset.
+func (t *costBasisTracker) acquire(assetID uint32, stamp, qty uint64, cost float64, noCost bool) {
+	t.lots[assetID] = append(t.lots[assetID], &taxLot{stamp: stamp, qty: qty, cost: cost, noCost: noCost})
+}
+
+// consume removes qty of the asset from the lots in FIFO or LIFO order, and
+// returns the portions of the lots consumed. If there are not enough lots, the
+// final portion has a zero stamp and an unknown cost.
+func (t *costBasisTracker) consume(assetID uint32, qty uint64) []*taxLot {
+	var consumed []*taxLot
+	lots := t.lots[assetID]
+	for qty > 0 && len(lots) > 0 {
+		idx := 0
+		if t.lifo {
+			idx = len(lots) - 1
+		}
+		lot := lots[idx]
+		if lot.qty > qty {
+			cost := lot.cost * float64(qty) / float64(lot.qty)
+			consumed = append(consumed, &taxLot{stamp: lot.stamp, qty: qty, cost: cost, noCost: lot.noCost})
+			lot.qty -= qty
+			lot.cost -= cost
+			qty = 0
+			break
+		}
+		consumed = append(consumed, lot)
+		qty -= lot.qty
+		if t.lifo {
+			lots = lots[:idx]
+		} else {
+			lots = lots[1:]
+		}
+	}
+	t.lots[assetID] = lots
+	if qty > 0 {
+		consumed = append(consumed, &taxLot{qty: qty, noCost: true})
+	}
+	return consumed
+}
+
+// TaxExport generates a report of the trade and wallet history for tax
+// reporting and accounting. Executed matches from the order history are
+// paired with the swap, redeem, refund and bond transaction fees, sends and
+// receives from the wallet transaction history. Entries are valued in USD
+// using the fiat rates recorded nearest to the time of the entry. Entries
+// without a recorded rate are flagged rather than valued at zero, and the
+// report includes a warning. The entire history is used to determine the cost
+// basis of the disposals in the requested time period, with lots consumed in
+// FIFO or LIFO order.
+func (c *Core) TaxExport(form *TaxExportForm) (*TaxReport, error) {
+	method := form.Method
+	switch method {
+	case "":
+		method = CostBasisFIFO
+	case CostBasisFIFO, CostBasisLIFO:
+	default:
+		return nil, fmt.Errorf("unknown cost basis method %q", method)
+	}
+	now := uint64(time.Now().UnixMilli())
+	end := form.End
+	if end == 0 || end > now {
+		end = now
+	}
+	if form.Start > end {
+		return nil, fmt.Errorf("start time %d is after end time %d", form.Start, end)
+	}
+
+	var current *db.FiatRateSnapshot
+	if rates := c.fiatConversions(); len(rates) > 0 {
+		current = &db.FiatRateSnapshot{Stamp: now, Rates: rates}
+	}
+	rates := newFiatRateLookup(c.db, current)
+
+	newEntry := func(stamp uint64, entryType string, assetID uint32, amt int64) *TaxLedgerEntry {
+		e := &TaxLedgerEntry{
+			Stamp:   stamp,
+			Type:    entryType,
+			AssetID: assetID,
+			Symbol:  unbip(assetID),
+			Amount:  amt,
+		}
+		ui, err := asset.UnitInfo(assetID)
+		if err == nil {
+			e.FiatRate = rates.rate(assetID, stamp)
+		}
+		if e.FiatRate == 0 {
+			e.NoFiatRate = true
+			return e
+		}
+		e.FiatValue = float64(amt) / float64(ui.Conventional.ConversionFactor) * e.FiatRate
+		return e
+	}
+
+	tradeEvents, err := c.taxTradeEvents(end, newEntry)
+	if err != nil {
+		return nil, err
+	}
+	walletEvents := c.taxWalletEvents(end, newEntry)
+	if rates.err != nil {
+		return nil, rates.err
+	}
+	events := append(tradeEvents, walletEvents...)
+	// Credits go first for entries with the same time stamp so that lots are
+	// available for any debits.
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].Stamp != events[j].Stamp {
+			return events[i].Stamp < events[j].Stamp
+		}
+		return events[i].Amount > events[j].Amount
+	})
+
+	var assets map[uint32]bool
+	if len(form.Assets) > 0 {
+		assets = make(map[uint32]bool, len(form.Assets))
+		for _, assetID := range form.Assets {
+			assets[assetID] = true
+		}
+	}
+
+	report := &TaxReport{
+		Start:     form.Start,
+		End:       end,
+		Method:    method,
+		Ledger:    make([]*TaxLedgerEntry, 0),
+		Disposals: make([]*TaxDisposal, 0),
+	}
+	basis := newCostBasisTracker(method)
+	var noRate, incomplete int
+	for _, ev := range events {
+		var qty uint64
+		if ev.Amount < 0 {
+			qty = uint64(-ev.Amount)
+		} else {
+			qty = uint64(ev.Amount)
+		}
+		var lots []*taxLot
+		switch ev.action {
+		case taxBasisAcquire:
+			basis.acquire(ev.AssetID, ev.Stamp, qty, ev.value, ev.noValue)
+		case taxBasisDispose, taxBasisTransfer:
+			lots = basis.consume(ev.AssetID, qty)
+		}
+		if ev.Stamp < form.Start || (assets != nil && !assets[ev.AssetID]) {
+			continue
+		}
+		report.Ledger = append(report.Ledger, ev.TaxLedgerEntry)
+		if ev.NoFiatRate {
+			noRate++
+		}
+		if ev.action != taxBasisDispose {
+			continue
+		}
+		for _, lot := range lots {
+			d := &TaxDisposal{
+				AssetID:         ev.AssetID,
+				Symbol:          ev.Symbol,
+				Qty:             lot.qty,
+				Acquired:        lot.stamp,
+				Disposed:        ev.Stamp,
+				ProceedsUnknown: ev.noValue,
+				CostUnknown:     lot.noCost,
+				Type:            ev.Type,
+				Ref:             ev.ref,
+			}
+			if !d.ProceedsUnknown {
+				d.Proceeds = ev.value * float64(lot.qty) / float64(qty)
+			}
+			if !d.CostUnknown {
+				d.CostBasis = lot.cost
+			}
+			if !d.ProceedsUnknown && !d.CostUnknown {
+				d.Gain = d.Proceeds - d.CostBasis
+			}
+			if d.ProceedsUnknown || d.CostUnknown {
+				incomplete++
+			}
+			report.Disposals = append(report.Disposals, d)
+		}
+	}
+	if noRate > 0 {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("%d ledger entries have no recorded USD rate "+
+			"and are not valued. Fiat rates are only recorded while the client is running.", noRate))
+	}
+	if incomplete > 0 {
+		report.Warnings = append(report.Warnings, fmt.Sprintf("%d disposals have an unknown proceeds or "+
+			"cost basis, and no gain is computed.", incomplete))
+	}
+	return report, nil
+}
+
+// taxTradeEvents generates a debit and a credit event for every executed match
+// of every trade order up to the end time.
+func (c *Core) taxTradeEvents(end uint64, newEntry func(uint64, string, uint32, int64) *TaxLedgerEntry) ([]*taxEvent, error) {
+	ords, err := c.db.Orders(&db.OrderFilter{})
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving orders: %w", err)
+	}
+	var events []*taxEvent
+	for _, mOrd := range ords {
+		ord := mOrd.Order
+		trade := ord.Trade()
+		if trade == nil {
+			continue
+		}
+		mms, err := c.db.MatchesForOrder(ord.ID(), true)
+		if err != nil {
+			return nil, fmt.Errorf("error retrieving matches for order %s: %w", ord.ID(), err)
+		}
+		base, quote := ord.Base(), ord.Quote()
+		for _, mm := range mms {
+			match := matchFromMetaMatch(ord, mm)
+			if match.IsCancel || match.Redeem == nil || match.Refund != nil || match.Stamp > end {
+				continue
+			}
+			baseQty, quoteQty := match.Qty, calc.BaseToQuote(match.Rate, match.Qty)
+			fromID, fromQty, toID, toQty := quote, quoteQty, base, baseQty
+			if trade.Sell {
+				fromID, fromQty, toID, toQty = base, baseQty, quote, quoteQty
+			}
+			debit := newEntry(match.Stamp, TaxEntryTrade, fromID, -int64(fromQty))
+			credit := newEntry(match.Stamp, TaxEntryTrade, toID, int64(toQty))
+			for _, e := range []*TaxLedgerEntry{debit, credit} {
+				e.Host = mOrd.MetaData.Host
+				e.MarketID = marketName(base, quote)
+				e.OrderID = ord.ID().String()
+				e.MatchID = match.MatchID.String()
+			}
+			// The value of the trade is the value of the asset received, if
+			// known.
+			value, noValue := credit.FiatValue, credit.NoFiatRate
+			if noValue {
+				value, noValue = -debit.FiatValue, debit.NoFiatRate
+			}
+			ref := fmt.Sprintf("%s match %s", mOrd.MetaData.Host, match.MatchID)
+			events = append(events,
+				&taxEvent{TaxLedgerEntry: debit, action: taxBasisDispose, value: value, noValue: noValue, ref: ref},
+				&taxEvent{TaxLedgerEntry: credit, action: taxBasisAcquire, value: value, noValue: noValue, ref: ref},
+			)
+		}
+	}
+	return events, nil
+}
+
+// taxWalletEvents generates events for the mined transactions in the history
+// of every wallet up to the end time. The amounts of swaps, redeems and
+// refunds are accounted for by the trade events, so only their fees are
+// included.
+func (c *Core) taxWalletEvents(end uint64, newEntry func(uint64, string, uint32, int64) *TaxLedgerEntry) []*taxEvent {
+	var events []*taxEvent
+	for _, w := range c.xcWallets() {
+		txs, err := w.TxHistory(0, nil, false)
+		if err != nil {
+			c.log.Debugf("No transaction history for %s wallet: %v", unbip(w.AssetID), err)
+			continue
+		}
+		feeAssetID := w.AssetID
+		if token := asset.TokenInfo(w.AssetID); token != nil {
+			feeAssetID = token.ParentID
+		}
+		for _, tx := range txs {
+			// Token transactions are in the history of the parent asset
+			// wallet too.
+			if tx.TokenID != nil && *tx.TokenID != w.AssetID {
+				continue
+			}
+			stamp := tx.Timestamp * 1000
+			if tx.BlockNumber == 0 || stamp > end {
+				continue
+			}
+			ref := fmt.Sprintf("%s tx %s", unbip(w.AssetID), tx.ID)
+			add := func(entryType string, assetID uint32, amt int64, action taxBasisAction) {
+				e := newEntry(stamp, entryType, assetID, amt)
+				e.TxID = tx.ID
+				value := e.FiatValue
+				if value < 0 {
+					value = -value
+				}
+				events = append(events, &taxEvent{TaxLedgerEntry: e, action: action, value: value, noValue: e.NoFiatRate, ref: ref})
+			}
+			feeType := TaxEntryNetworkFee
+			if !tx.Rejected {
+				switch tx.Type {
+				case asset.Swap:
+					feeType = TaxEntrySwapFee
+				case asset.Redeem:
+					feeType = TaxEntryRedeemFee
+				case asset.Refund:
+					feeType = TaxEntryRefundFee
+				case asset.CreateBond:
+					feeType = TaxEntryBondFee
+					add(TaxEntryBond, w.AssetID, -int64(tx.Amount), taxBasisNone)
+				case asset.RedeemBond:
+					feeType = TaxEntryBondFee
+					add(TaxEntryBondRefund, w.AssetID, int64(tx.Amount), taxBasisNone)
+				case asset.Send:
+					add(TaxEntrySend, w.AssetID, -int64(tx.Amount), taxBasisTransfer)
+				case asset.Receive:
+					add(TaxEntryReceive, w.AssetID, int64(tx.Amount), taxBasisAcquire)
+				}
+			}
+			if tx.Fees > 0 {
+				add(feeType, feeAssetID, -int64(tx.Fees), taxBasisDispose)
+			}
+		}
+	}
+	return events
+}
+
+// WriteLedgerCSV writes the ledger as CSV, with one row for each balance
+// change.
+func (r *TaxReport) WriteLedgerCSV(w io.Writer) error {
+	csvWriter := csv.NewWriter(w)
+	err := csvWriter.Write([]string{
+		"Time",
+		"Type",
+		"Asset",
+		"Amount",
+		"USD Rate",
+		"USD Value",
+		"Host",
+		"Market",
+		"Order ID",
+		"Match ID",
+		"Tx ID",
+	})
+	if err != nil {
+		return err
+	}
+	for _, e := range r.Ledger {
+		// Entries without a rate have empty fiat columns.
+		var rate, value string
+		if !e.NoFiatRate {
+			rate = strconv.FormatFloat(e.FiatRate, 'f', -1, 64)
+			value = strconv.FormatFloat(e.FiatValue, 'f', 2, 64)
+		}
+		err = csvWriter.Write([]string{
+			fmtTaxTime(e.Stamp),
+			e.Type,
+			e.Symbol,
+			fmtTaxAmount(e.AssetID, e.Amount),
+			rate,
+			value,
+			e.Host,
+			e.MarketID,
+			e.OrderID,
+			e.MatchID,
+			e.TxID,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// WriteCostBasisCSV writes the disposals as CSV, in a format similar to IRS
+// Form 8949, with one row for each lot disposed.
+func (r *TaxReport) WriteCostBasisCSV(w io.Writer) error {
+	csvWriter := csv.NewWriter(w)
+	err := csvWriter.Write([]string{
+		"Description",
+		"Date Acquired",
+		"Date Sold",
+		"Proceeds (USD)",
+		"Cost Basis (USD)",
+		"Gain or Loss (USD)",
+		"Term",
+		"Type",
+		"Reference",
+	})
+	if err != nil {
+		return err
+	}
+	fmtUSD := func(v float64, unknown bool) string {
+		if unknown {
+			return ""
+		}
+		return strconv.FormatFloat(v, 'f', 2, 64)
+	}
+	for _, d := range r.Disposals {
+		acquired, term := "UNKNOWN", "short"
+		if d.Acquired != 0 {
+			acquired = fmtTaxTime(d.Acquired)
+			if d.Disposed-d.Acquired > longTermHolding {
+				term = "long"
+			}
+		}
+		err = csvWriter.Write([]string{
+			fmtTaxAmount(d.AssetID, int64(d.Qty)) + " " + d.Symbol,
+			acquired,
+			fmtTaxTime(d.Disposed),
+			fmtUSD(d.Proceeds, d.ProceedsUnknown),
+			fmtUSD(d.CostBasis, d.CostUnknown),
+			fmtUSD(d.Gain, d.ProceedsUnknown || d.CostUnknown),
+			term,
+			d.Type,
+			d.Ref,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// storeFiatRateSnapshot stores the fiat rates in the database if it has been
+// at least fiatSnapshotInterval since the last snapshot. Snapshots older than
+// fiatSnapshotRetention are downsampled at most once per fiatPruneInterval.
+func (c *Core) storeFiatRateSnapshot(rates map[uint32]float64) {
+	now := time.Now().UnixMilli()
+	last := c.lastFiatSnapshot.Load()
+	if time.Duration(now-last)*time.Millisecond < fiatSnapshotInterval || !c.lastFiatSnapshot.CompareAndSwap(last, now) {
+		return
+	}
+	if err := c.db.StoreFiatRates(&db.FiatRateSnapshot{Stamp: uint64(now), Rates: rates}); err != nil {
+		c.log.Errorf("Error storing fiat rate snapshot: %v", err)
+	}
+
+	lastPrune := c.lastFiatPrune.Load()
+	if time.Duration(now-lastPrune)*time.Millisecond < fiatPruneInterval || !c.lastFiatPrune.CompareAndSwap(lastPrune, now) {
+		return
+	}
+	before := uint64(time.UnixMilli(now).Add(-fiatSnapshotRetention).UnixMilli())
+	n, err := c.db.PruneFiatRates(before, uint64(fiatSnapshotDownsample.Milliseconds()))
+	if err != nil {
+		c.log.Errorf("Error pruning fiat rate snapshots: %v", err)
+		return
+	}
+	if n > 0 {
+		c.log.Debugf("Pruned %d old fiat rate snapshots", n)
+	}
+}
+
+// fmtTaxTime formats the unix millisecond time stamp as an RFC 3339 UTC time.
+func fmtTaxTime(stamp uint64) string {
+	return time.UnixMilli(int64(stamp)).UTC().Format(time.RFC3339)
+}
+
+// fmtTaxAmount formats the signed atoms in conventional units.
+func fmtTaxAmount(assetID uint32, amt int64) string {
+	var sign string
+	if amt < 0 {
+		sign, amt = "-", -amt
+	}
+	ui, err := asset.UnitInfo(assetID)
+	if err != nil {
+		return sign + strconv.FormatInt(amt, 10)
+	}
+	return sign + ui.ConventionalString(uint64(amt))
+}
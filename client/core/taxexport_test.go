@@ -0,0 +1,319 @@
+//go:build !harness && !botlive
+
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"math"
+	"testing"
+	"time"
+
+	"decred.org/dcrdex/client/asset"
+	"decred.org/dcrdex/client/db"
+	"decred.org/dcrdex/dex/encode"
+	"decred.org/dcrdex/dex/order"
+)
+
+// tHistorianWallet is a TXCWallet with a transaction history.
+type tHistorianWallet struct {
+	*TXCWallet
+	txs []*asset.WalletTransaction
+}
+
+func (w *tHistorianWallet) TxHistory(n int, refID *string, past bool) ([]*asset.WalletTransaction, error) {
+	return w.txs, nil
+}
+
+func (w *tHistorianWallet) WalletTransaction(context.Context, string) (*asset.WalletTransaction, error) {
+	return nil, asset.CoinNotFoundError
+}
+
+func TestTaxExport(t *testing.T) {
+	rig := newTestRig()
+	defer rig.shutdown()
+	tCore := rig.core
+	dc := rig.dc
+
+	const day = uint64(24 * time.Hour / time.Millisecond)
+	t0 := uint64(time.Now().Add(-30*24*time.Hour).Unix()) * 1000
+	t1 := t0 + 10*day
+	dcrID, btcID := tUTXOAssetA.ID, tUTXOAssetB.ID
+	rig.db.fiatSnapshots = []*db.FiatRateSnapshot{
+		{Stamp: t0, Rates: map[uint32]float64{dcrID: 20, btcID: 40_000}},
+		{Stamp: t1, Rates: map[uint32]float64{dcrID: 30, btcID: 40_000}},
+	}
+
+	// 2 DCR received at $20.
+	dcrWallet, tDcrWallet := newTWallet(dcrID)
+	dcrWallet.Wallet = &tHistorianWallet{
+		TXCWallet: tDcrWallet,
+		txs: []*asset.WalletTransaction{{
+			Type:        asset.Receive,
+			ID:          "receive",
+			Amount:      2e8,
+			BlockNumber: 1,
+			Timestamp:   t0 / 1000,
+		}, {
+			Type:        asset.Swap,
+			ID:          "swap",
+			Amount:      15e7,
+			Fees:        1e6,
+			BlockNumber: 2,
+			Timestamp:   t1/1000 + 1,
+		}, {
+			// Mempool transactions are not exported.
+			Type:      asset.Send,
+			ID:        "mempool",
+			Amount:    1e8,
+			Timestamp: t1 / 1000,
+		}},
+	}
+	tCore.wallets[dcrID] = dcrWallet
+
+	addMatch := func(sell bool, qty, rate, stamp uint64, refunded bool) {
+		_, dbOrder, _, _ := makeLimitOrder(dc, sell, qty, rate)
+		oid := dbOrder.Order.ID()
+		var mid order.MatchID
+		copy(mid[:], encode.RandomBytes(32))
+		proof := db.MatchProof{MakerRedeem: encode.RandomBytes(36)}
+		if refunded {
+			proof = db.MatchProof{RefundCoin: encode.RandomBytes(36)}
+		}
+		rig.db.orders = append(rig.db.orders, dbOrder)
+		if rig.db.matchesByOID == nil {
+			rig.db.matchesByOID = make(map[order.OrderID][]*db.MetaMatch)
+		}
+		rig.db.matchesByOID[oid] = []*db.MetaMatch{{
+			UserMatch: &order.UserMatch{
+				OrderID:  oid,
+				MatchID:  mid,
+				Quantity: qty,
+				Rate:     rate,
+				Address:  "addr",
+				Side:     order.Maker,
+				Status:   order.MatchComplete,
+			},
+			MetaData: &db.MatchMetaData{Stamp: stamp, Proof: proof},
+		}}
+	}
+	// Buy 1 DCR for 0.0005 BTC ($20).
+	addMatch(false, 1e8, 5e4, t0+day/24, false)
+	// Sell 1.5 DCR for 0.001125 BTC ($45).
+	addMatch(true, 15e7, 75e3, t1, false)
+	// Refunded matches are not exported.
+	addMatch(true, 1e8, 75e3, t1, true)
+
+	checkFloat := func(name string, v, exp float64) {
+		t.Helper()
+		if math.Abs(v-exp) > 1e-9 {
+			t.Fatalf("wrong %s. expected %f, got %f", name, exp, v)
+		}
+	}
+
+	type expDisposal struct {
+		qty            uint64
+		acquired       uint64
+		proceeds, cost float64
+	}
+	checkDisposals := func(method string, exp []*expDisposal) *TaxReport {
+		t.Helper()
+		report, err := tCore.TaxExport(&TaxExportForm{Start: t1, Assets: []uint32{dcrID}, Method: method})
+		if err != nil {
+			t.Fatalf("TaxExport error: %v", err)
+		}
+		var disposals []*TaxDisposal
+		for _, d := range report.Disposals {
+			if d.Type == TaxEntryTrade {
+				disposals = append(disposals, d)
+			}
+		}
+		if len(disposals) != len(exp) {
+			t.Fatalf("%s: expected %d trade disposals, got %d", method, len(exp), len(disposals))
+		}
+		for i, d := range disposals {
+			e := exp[i]
+			if d.Qty != e.qty || d.Acquired != e.acquired || d.Disposed != t1 {
+				t.Fatalf("%s: wrong disposal %d: %+v", method, i, d)
+			}
+			checkFloat("proceeds", d.Proceeds, e.proceeds)
+			checkFloat("cost basis", d.CostBasis, e.cost)
+			checkFloat("gain", d.Gain, e.proceeds-e.cost)
+		}
+		return report
+	}
+
+	report := checkDisposals(CostBasisFIFO, []*expDisposal{{qty: 15e7, acquired: t0, proceeds: 45, cost: 30}})
+	checkDisposals(CostBasisLIFO, []*expDisposal{
+		{qty: 1e8, acquired: t0 + day/24, proceeds: 30, cost: 20},
+		{qty: 5e7, acquired: t0, proceeds: 15, cost: 10},
+	})
+
+	// Only the DCR sell and swap fee are in the period.
+	if len(report.Ledger) != 2 {
+		t.Fatalf("expected 2 ledger entries, got %d", len(report.Ledger))
+	}
+	for _, e := range report.Ledger {
+		switch e.Type {
+		case TaxEntryTrade:
+			if e.Amount != -15e7 || e.OrderID == "" || e.MatchID == "" || e.Host != tDexHost {
+				t.Fatalf("wrong trade entry: %+v", e)
+			}
+			checkFloat("trade value", e.FiatValue, -45)
+		case TaxEntrySwapFee:
+			if e.Amount != -1e6 || e.TxID != "swap" {
+				t.Fatalf("wrong swap fee entry: %+v", e)
+			}
+			checkFloat("fee value", e.FiatValue, -0.3)
+		default:
+			t.Fatalf("unexpected ledger entry type %q", e.Type)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteLedgerCSV(&buf); err != nil {
+		t.Fatalf("WriteLedgerCSV error: %v", err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("error reading ledger CSV: %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("expected 3 ledger CSV rows, got %d", len(rows))
+	}
+	buf.Reset()
+	if err := report.WriteCostBasisCSV(&buf); err != nil {
+		t.Fatalf("WriteCostBasisCSV error: %v", err)
+	}
+	rows, err = csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("error reading cost basis CSV: %v", err)
+	}
+	if len(rows) != len(report.Disposals)+1 {
+		t.Fatalf("expected %d cost basis CSV rows, got %d", len(report.Disposals)+1, len(rows))
+	}
+	if rows[1][0] != "1.50000000 dcr" || rows[1][3] != "45.00" || rows[1][5] != "15.00" {
+		t.Fatalf("wrong cost basis CSV row: %v", rows[1])
+	}
+
+	if _, err := tCore.TaxExport(&TaxExportForm{Method: "hifo"}); err == nil {
+		t.Fatalf("no error for unknown cost basis method")
+	}
+	if _, err := tCore.TaxExport(&TaxExportForm{Start: t1, End: t0}); err == nil {
+		t.Fatalf("no error for start after end")
+	}
+}
+
+func TestFiatRateHistoryRate(t *testing.T) {
+	const hour = uint64(time.Hour / time.Millisecond)
+	h := fiatRateHistory{
+		{Stamp: 10 * hour, Rates: map[uint32]float64{0: 1, 42: 2}},
+		{Stamp: 20 * hour, Rates: map[uint32]float64{0: 3}},
+		{Stamp: 100 * hour, Rates: map[uint32]float64{0: 4}},
+	}
+	for _, tt := range []struct {
+		assetID uint32
+		stamp   uint64
+		exp     float64
+	}{
+		{0, 0, 1},
+		{0, 14 * hour, 1},
+		{0, 16 * hour, 3},
+		{0, 50 * hour, 0},
+		{0, 120 * hour, 4},
+		{42, 19 * hour, 2},
+		{42, 40 * hour, 0},
+		{60, 10 * hour, 0},
+	} {
+		if r := h.rate(tt.assetID, tt.stamp); r != tt.exp {
+			t.Fatalf("asset %d at %d hours: expected rate %f, got %f", tt.assetID, tt.stamp/hour, tt.exp, r)
+		}
+	}
+}
+
+func TestTaxExportMissingRate(t *testing.T) {
+	rig := newTestRig()
+	defer rig.shutdown()
+	tCore := rig.core
+
+	const day = uint64(24 * time.Hour / time.Millisecond)
+	t0 := uint64(time.Now().Add(-30*24*time.Hour).Unix()) * 1000
+	t1, t2 := t0+10*day, t0+20*day
+	dcrID := tUTXOAssetA.ID
+	// There is only a rate near t1.
+	rig.db.fiatSnapshots = []*db.FiatRateSnapshot{{Stamp: t1, Rates: map[uint32]float64{dcrID: 30}}}
+
+	dcrWallet, tDcrWallet := newTWallet(dcrID)
+	dcrWallet.Wallet = &tHistorianWallet{
+		TXCWallet: tDcrWallet,
+		txs: []*asset.WalletTransaction{{
+			Type:        asset.Receive,
+			ID:          "receive",
+			Amount:      2e8,
+			BlockNumber: 1,
+			Timestamp:   t0 / 1000,
+		}, {
+			Type:        asset.Swap,
+			ID:          "swap1",
+			Fees:        1e6,
+			BlockNumber: 2,
+			Timestamp:   t1 / 1000,
+		}, {
+			Type:        asset.Swap,
+			ID:          "swap2",
+			Fees:        1e6,
+			BlockNumber: 3,
+			Timestamp:   t2 / 1000,
+		}},
+	}
+	tCore.wallets[dcrID] = dcrWallet
+
+	report, err := tCore.TaxExport(&TaxExportForm{Start: t0, Assets: []uint32{dcrID}})
+	if err != nil {
+		t.Fatalf("TaxExport error: %v", err)
+	}
+	if len(report.Ledger) != 3 || len(report.Disposals) != 2 {
+		t.Fatalf("expected 3 ledger entries and 2 disposals, got %d and %d", len(report.Ledger), len(report.Disposals))
+	}
+	for _, e := range report.Ledger {
+		if expNoRate := e.TxID != "swap1"; e.NoFiatRate != expNoRate {
+			t.Fatalf("%s: expected NoFiatRate = %t", e.TxID, expNoRate)
+		}
+	}
+	// The swap1 fee has known proceeds, but the cost of the DCR received
+	// without a rate is unknown.
+	d1, d2 := report.Disposals[0], report.Disposals[1]
+	if d1.ProceedsUnknown || !d1.CostUnknown || d1.Proceeds == 0 || d1.Gain != 0 {
+		t.Fatalf("wrong swap1 fee disposal: %+v", d1)
+	}
+	if !d2.ProceedsUnknown || !d2.CostUnknown || d2.Proceeds != 0 {
+		t.Fatalf("wrong swap2 fee disposal: %+v", d2)
+	}
+	if len(report.Warnings) != 2 {
+		t.Fatalf("expected 2 warnings, got %v", report.Warnings)
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteLedgerCSV(&buf); err != nil {
+		t.Fatalf("WriteLedgerCSV error: %v", err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("error reading ledger CSV: %v", err)
+	}
+	if receive := rows[1]; receive[4] != "" || receive[5] != "" {
+		t.Fatalf("expected empty fiat columns for entry without a rate: %v", receive)
+	}
+	buf.Reset()
+	if err := report.WriteCostBasisCSV(&buf); err != nil {
+		t.Fatalf("WriteCostBasisCSV error: %v", err)
+	}
+	rows, err = csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("error reading cost basis CSV: %v", err)
+	}
+	if row := rows[1]; row[3] != "0.30" || row[4] != "" || row[5] != "" {
+		t.Fatalf("wrong cost basis CSV row: %v", row)
+	}
+}
@@ -449,6 +449,11 @@ type Order struct {
 	TimeInForce       order.TimeInForce `json:"tif"`           // limit only
 	TargetOrderID     dex.Bytes         `json:"targetOrderID"` // cancel only
 	ReadyToTick       bool              `json:"readyToTick"`
+	// AmendedFrom is the ID of the order that this order replaced when that
+	// order was amended, and AmendedBy is the ID of the order that replaced
+	// this order.
+	AmendedFrom dex.Bytes `json:"amendedFrom,omitempty"`
+	AmendedBy   dex.Bytes `json:"amendedBy,omitempty"`
 }
 
 // InFlightOrder is an Order that is not stamped yet, but has a temporary ID
@@ -556,6 +561,12 @@ func coreOrderFromTrade(ord order.Order, metaData *db.OrderMetaData) *Order {
 		FundingCoins:      fundingCoins,
 		AccelerationCoins: accelerationCoins,
 	}
+	if !metaData.AmendedFrom.IsZero() {
+		corder.AmendedFrom = metaData.AmendedFrom.Bytes()
+	}
+	if !metaData.AmendedBy.IsZero() {
+		corder.AmendedBy = metaData.AmendedBy.Bytes()
+	}
 
 	return corder
 }
@@ -1094,6 +1105,270 @@ type MultiTradeForm struct {
 	MaxLock uint64 `json:"maxLock"`
 }
 
+// AmendForm is used to reprice or resize a standing limit order. The order
+// is canceled and a replacement with the same market, side and options is
+// placed in the same epoch. The replacement is always funded with new coins,
+// even for account-based assets, so the wallet must have the funds for both
+// orders until the cancel executes.
+type AmendForm struct {
+	OrderID dex.Bytes `json:"orderID"`
+	// Rate is the rate of the replacement order. If zero, the rate of the
+	// original order is used.
+	Rate uint64 `json:"rate"`
+	// Qty is the quantity of the replacement order. If zero, the remaining
+	// quantity of the original order is used.
+	Qty uint64 `json:"qty"`
+}
+
+// Stop order types and price sources, as specified in a StopOrderForm.
+const (
+	StopOrderTypeStopLimit    = "stoplimit"
+	StopOrderTypeTrailingStop = "trailingstop"
+	PriceSourceMidGap         = "midgap"
+	PriceSourceLastTrade      = "lasttrade"
+)
+
+// StopOrderForm is used to place a client-side conditional order. When
+// triggered, a limit order is placed with the Host, Sell, Base, Quote, Qty,
+// TifNow and Options fields.
+type StopOrderForm struct {
+	Host  string `json:"host"`
+	Type  string `json:"type"`
+	Sell  bool   `json:"sell"`
+	Base  uint32 `json:"base"`
+	Quote uint32 `json:"quote"`
+	Qty   uint64 `json:"qty"`
+	// PriceSource is the market rate checked against the trigger, either
+	// "midgap" (default) or "lasttrade".
+	PriceSource string `json:"priceSource"`
+	// TriggerRate and Rate are the trigger rate and limit rate of a
+	// "stoplimit" order. A sell is triggered when the market rate falls to
+	// TriggerRate, and a buy when it rises to TriggerRate.
+	TriggerRate uint64 `json:"triggerRate"`
+	Rate        uint64 `json:"rate"`
+	// TrailDelta and LimitOffset apply to a "trailingstop" order. The order
+	// is triggered when the market rate moves TrailDelta against the best
+	// rate seen since placement. The limit rate is the trigger rate less
+	// (sell) or plus (buy) LimitOffset.
+	TrailDelta  uint64            `json:"trailDelta"`
+	LimitOffset uint64            `json:"limitOffset"`
+	TifNow      bool              `json:"tifnow"`
+	Options     map[string]string `json:"options"`
+}
+
+// StopOrder is a client-side conditional order.
+type StopOrder struct {
+	ID           dex.Bytes         `json:"id"`
+	Type         string            `json:"type"`
+	Host         string            `json:"host"`
+	BaseID       uint32            `json:"baseID"`
+	QuoteID      uint32            `json:"quoteID"`
+	MarketID     string            `json:"market"`
+	Sell         bool              `json:"sell"`
+	Qty          uint64            `json:"qty"`
+	PriceSource  string            `json:"priceSource"`
+	TriggerRate  uint64            `json:"triggerRate,omitempty"`
+	Rate         uint64            `json:"rate,omitempty"`
+	TrailDelta   uint64            `json:"trailDelta,omitempty"`
+	LimitOffset  uint64            `json:"limitOffset,omitempty"`
+	RefRate      uint64            `json:"refRate,omitempty"`
+	TifNow       bool              `json:"tifnow"`
+	Options      map[string]string `json:"options,omitempty"`
+	Status       string            `json:"status"`
+	Stamp        uint64            `json:"stamp"`
+	TriggerStamp uint64            `json:"triggerStamp,omitempty"`
+	OrderID      dex.Bytes         `json:"orderID,omitempty"`
+	Err          string            `json:"err,omitempty"`
+}
+
+// Scheduled order types, as specified in a ScheduledOrderForm.
+const (
+	ScheduledOrderTypeTWAP    = "twap"
+	ScheduledOrderTypeIceberg = "iceberg"
+)
+
+// ScheduledOrderForm is used to create a parent order that is executed as a
+// series of child limit orders at Rate. A "twap" order places Slices child
+// orders evenly over Duration. An "iceberg" order keeps a single child order of
+// at most VisibleQty booked until Qty is filled.
+type ScheduledOrderForm struct {
+	Host  string `json:"host"`
+	Type  string `json:"type"`
+	Sell  bool   `json:"sell"`
+	Base  uint32 `json:"base"`
+	Quote uint32 `json:"quote"`
+	Qty   uint64 `json:"qty"`
+	Rate  uint64 `json:"rate"`
+	// Duration is in milliseconds.
+	Duration   uint64            `json:"duration"`
+	Slices     uint32            `json:"slices"`
+	VisibleQty uint64            `json:"visibleQty"`
+	TifNow     bool              `json:"tifnow"`
+	Options    map[string]string `json:"options"`
+}
+
+// ScheduledOrder is a client-side parent order and the aggregate fills and
+// fees of its child orders.
+type ScheduledOrder struct {
+	ID         dex.Bytes         `json:"id"`
+	Type       string            `json:"type"`
+	Host       string            `json:"host"`
+	BaseID     uint32            `json:"baseID"`
+	QuoteID    uint32            `json:"quoteID"`
+	MarketID   string            `json:"market"`
+	Sell       bool              `json:"sell"`
+	Qty        uint64            `json:"qty"`
+	Rate       uint64            `json:"rate"`
+	Duration   uint64            `json:"duration,omitempty"`
+	Slices     uint32            `json:"slices,omitempty"`
+	VisibleQty uint64            `json:"visibleQty,omitempty"`
+	TifNow     bool              `json:"tifnow"`
+	Options    map[string]string `json:"options,omitempty"`
+	Status     string            `json:"status"`
+	Stamp      uint64            `json:"stamp"`
+	ChildIDs   []dex.Bytes       `json:"childIDs"`
+	Placed     uint64            `json:"placed"`
+	Filled     uint64            `json:"filled"`
+	FeesPaid   *FeeBreakdown     `json:"feesPaid"`
+	EndStamp   uint64            `json:"endStamp,omitempty"`
+	Err        string            `json:"err,omitempty"`
+}
+
+// Cost basis methods, as specified in a TaxExportForm.
+const (
+	CostBasisFIFO = "fifo"
+	CostBasisLIFO = "lifo"
+)
+
+// TaxExportForm is used to export the trade and wallet history for tax
+// reporting and accounting.
+type TaxExportForm struct {
+	// Start and End are unix milliseconds. A zero End means now.
+	Start uint64 `json:"start"`
+	End   uint64 `json:"end"`
+	// Assets limits the export to the specified assets. All assets with a
+	// wallet are exported if empty.
+	Assets []uint32 `json:"assets,omitempty"`
+	// Method is the cost basis method, CostBasisFIFO or CostBasisLIFO. The
+	// default is CostBasisFIFO.
+	Method string `json:"method"`
+}
+
+// Tax ledger entry types.
+const (
+	TaxEntryTrade      = "trade"
+	TaxEntrySwapFee    = "swap fee"
+	TaxEntryRedeemFee  = "redeem fee"
+	TaxEntryRefundFee  = "refund fee"
+	TaxEntryBond       = "bond"
+	TaxEntryBondFee    = "bond fee"
+	TaxEntryBondRefund = "bond refund"
+	TaxEntrySend       = "send"
+	TaxEntryReceive    = "receive"
+	TaxEntryNetworkFee = "network fee"
+)
+
+// TaxLedgerEntry is a single change in an asset balance.
+type TaxLedgerEntry struct {
+	// Stamp is the time of the entry in unix milliseconds.
+	Stamp   uint64 `json:"stamp"`
+	Type    string `json:"type"`
+	AssetID uint32 `json:"assetID"`
+	Symbol  string `json:"symbol"`
+	// Amount is the change in the balance of the asset in atoms. Debits are
+	// negative.
+	Amount int64 `json:"amount"`
+	// FiatRate is the USD value of one conventional unit of the asset at
+	// Stamp.
+	FiatRate  float64 `json:"fiatRate"`
+	FiatValue float64 `json:"fiatValue"`
+	// NoFiatRate is true if no rate was recorded near Stamp, in which case
+	// FiatRate and FiatValue are zero and should not be used.
+	NoFiatRate bool   `json:"noFiatRate,omitempty"`
+	Host       string `json:"host,omitempty"`
+	MarketID   string `json:"market,omitempty"`
+	OrderID    string `json:"orderID,omitempty"`
+	MatchID    string `json:"matchID,omitempty"`
+	TxID       string `json:"txID,omitempty"`
+}
+
+// TaxDisposal is the disposal of all or part of a single acquired lot of an
+// asset, with the realized gain in USD.
+type TaxDisposal struct {
+	AssetID uint32 `json:"assetID"`
+	Symbol  string `json:"symbol"`
+	// Qty is in atoms.
+	Qty uint64 `json:"qty"`
+	// Acquired is the unix millisecond time stamp at which the lot was
+	// acquired, or zero if the lot is not in the history.
+	Acquired  uint64  `json:"acquired"`
+	Disposed  uint64  `json:"disposed"`
+	Proceeds  float64 `json:"proceeds"`
+	CostBasis float64 `json:"costBasis"`
+	// Gain is zero if either the proceeds or cost basis is unknown.
+	Gain float64 `json:"gain"`
+	// ProceedsUnknown is true if there was no fiat rate at the time of the
+	// disposal.
+	ProceedsUnknown bool `json:"proceedsUnknown,omitempty"`
+	// CostUnknown is true if there was no fiat rate at the time of
+	// acquisition, or the lot is not in the history.
+	CostUnknown bool `json:"costUnknown,omitempty"`
+	// Type is the type of the ledger entry that disposed of the lot.
+	Type string `json:"type"`
+	Ref  string `json:"ref"`
+}
+
+// TaxReport is the ledger of balance changes and the cost basis of disposals
+// for a time period.
+type TaxReport struct {
+	Start     uint64            `json:"start"`
+	End       uint64            `json:"end"`
+	Method    string            `json:"method"`
+	Ledger    []*TaxLedgerEntry `json:"ledger"`
+	Disposals []*TaxDisposal    `json:"disposals"`
+	// Warnings describe missing data that makes the report incomplete.
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+// AddressBookEntry is a saved address for an asset.
+type AddressBookEntry struct {
+	ID      dex.Bytes `json:"id"`
+	AssetID uint32    `json:"assetID"`
+	Symbol  string    `json:"symbol"`
+	Address string    `json:"address"`
+	Label   string    `json:"label"`
+	// Stamp is the time the entry was added, in unix milliseconds.
+	Stamp uint64 `json:"stamp"`
+	// ActiveAt is the time after which the address may be sent to while the
+	// withdrawal allowlist is enabled, in unix milliseconds.
+	ActiveAt uint64 `json:"activeAt"`
+}
+
+// AllowlistStatus is the state of the withdrawal allowlist.
+type AllowlistStatus struct {
+	Enabled bool `json:"enabled"`
+	// DisableAt is the time at which a requested disable takes effect, in
+	// unix milliseconds. Zero if no disable is pending.
+	DisableAt uint64 `json:"disableAt,omitempty"`
+	// Delay is how long after a new address is added, or a disable is
+	// requested, before it takes effect, in milliseconds.
+	Delay uint64 `json:"delay"`
+}
+
+// APIKey is a client RPC server credential. The secret is only available when
+// the key is created.
+type APIKey struct {
+	Name string `json:"name"`
+	// Hash is the SHA-256 hash of the key's secret.
+	Hash   dex.Bytes `json:"-"`
+	Scopes []string  `json:"scopes"`
+	// RateLimit is the number of requests per minute allowed for the key.
+	// Zero means no limit.
+	RateLimit uint32 `json:"rateLimit"`
+	// Stamp is the time the key was created, in unix milliseconds.
+	Stamp uint64 `json:"stamp"`
+}
+
 // SingleLotFeesForm is used to determine the fees for a single lot trade.
 type SingleLotFeesForm struct {
 	Host          string `json:"host"`
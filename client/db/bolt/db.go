@@ -10,6 +10,7 @@ import (
 	"errors"
 	"fmt"
 	"io/fs"
+	"math"
 	"os"
 	"path/filepath"
 	"slices"
@@ -69,6 +70,11 @@ var (
 	notesBucket           = []byte("notes")
 	pokesBucket           = []byte("pokes")
 	credentialsBucket     = []byte("credentials")
+	stopOrdersBucket      = []byte("stopOrders")
+	scheduledOrdersBucket = []byte("scheduledOrders")
+	fiatRatesBucket       = []byte("fiatRates")
+	addressBookBucket     = []byte("addressBook")
+	apiKeysBucket         = []byte("apiKeys")
 
 	// value keys
 	versionKey = []byte("version")
@@ -124,7 +130,10 @@ var (
 	disabledRateSourceKey = []byte("disabledRateSources")
 	walletDisabledKey     = []byte("walletDisabled")
 	// programKey            = []byte("program") unused
-	langKey = []byte("lang")
+	langKey        = []byte("lang")
+	allowlistKey   = []byte("allowlist")
+	amendedFromKey = []byte("amendedFrom")
+	amendedByKey   = []byte("amendedBy")
 
 	// values
 	byteTrue  = encode.ByteTrue
@@ -181,7 +190,8 @@ func NewDB(dbPath string, logger dex.Logger, opts ...Opts) (dexdb.DB, error) {
 		activeOrdersBucket, archivedOrdersBucket,
 		activeMatchesBucket, archivedMatchesBucket,
 		walletsBucket, notesBucket, credentialsBucket,
-		botProgramsBucket, pokesBucket,
+		botProgramsBucket, pokesBucket, stopOrdersBucket, scheduledOrdersBucket,
+		fiatRatesBucket, addressBookBucket, apiKeysBucket,
 	}); err != nil {
 		return nil, err
 	}
@@ -390,6 +400,30 @@ func (db *BoltDB) Recrypt(creds *dexdb.PrimaryCredentials, oldCrypter, newCrypte
 			return fmt.Errorf("accounts update error: %w", err)
 		}
 
+		addrBook := tx.Bucket(addressBookBucket)
+		if addrBook == nil {
+			return fmt.Errorf("no address book bucket")
+		}
+		err = addrBook.ForEach(func(k, v []byte) error {
+			e, err := dexdb.DecodeAddressBookEntry(bytes.Clone(v))
+			if err != nil {
+				return fmt.Errorf("error decoding address book entry %x: %w", k, err)
+			}
+			for _, encB := range []*[]byte{&e.EncAddress, &e.EncLabel} {
+				b, err := oldCrypter.Decrypt(*encB)
+				if err != nil {
+					return fmt.Errorf("Decrypt error: %w", err)
+				}
+				if *encB, err = newCrypter.Encrypt(b); err != nil {
+					return fmt.Errorf("Encrypt error: %w", err)
+				}
+			}
+			return addrBook.Put(k, e.Encode())
+		})
+		if err != nil {
+			return fmt.Errorf("address book update error: %w", err)
+		}
+
 		// Store the new credentials.
 		return db.setCreds(tx, creds)
 	})
@@ -1181,8 +1215,10 @@ func decodeOrderBucket(oid []byte, oBkt *bbolt.Bucket) (*dexdb.MetaOrder, error)
 		refundReserves = intCoder.Uint64(refundReservesB)
 	}
 
-	var linkedID order.OrderID
+	var linkedID, amendedFrom, amendedBy order.OrderID
 	copy(linkedID[:], oBkt.Get(linkedKey))
+	copy(amendedFrom[:], oBkt.Get(amendedFromKey))
+	copy(amendedBy[:], oBkt.Get(amendedByKey))
 
 	// Old cancel orders may not have a maxFeeRate set since the v2 upgrade
 	// doesn't set it for cancel orders.
@@ -1252,6 +1288,8 @@ func decodeOrderBucket(oid []byte, oBkt *bbolt.Bucket) (*dexdb.MetaOrder, error)
 			Host:               string(getCopy(oBkt, dexKey)),
 			ChangeCoin:         getCopy(oBkt, changeKey),
 			LinkedOrder:        linkedID,
+			AmendedFrom:        amendedFrom,
+			AmendedBy:          amendedBy,
 			SwapFeesPaid:       intCoder.Uint64(oBkt.Get(swapFeesKey)),
 			EpochDur:           epochDur,
 			MaxFeeRate:         maxFeeRate,
@@ -1332,6 +1370,13 @@ func updateOrderMetaData(bkt *bbolt.Bucket, md *dexdb.OrderMetaData) error {
 	if !md.LinkedOrder.IsZero() {
 		linkedB = md.LinkedOrder[:]
 	}
+	var amendedFromB, amendedByB []byte
+	if !md.AmendedFrom.IsZero() {
+		amendedFromB = md.AmendedFrom[:]
+	}
+	if !md.AmendedBy.IsZero() {
+		amendedByB = md.AmendedBy[:]
+	}
 
 	var accelerationsB encode.BuildyBytes
 	if len(md.AccelerationCoins) > 0 {
@@ -1347,6 +1392,8 @@ func updateOrderMetaData(bkt *bbolt.Bucket, md *dexdb.OrderMetaData) error {
 		put(proofKey, md.Proof.Encode()).
 		put(changeKey, md.ChangeCoin).
 		put(linkedKey, linkedB).
+		put(amendedFromKey, amendedFromB).
+		put(amendedByKey, amendedByB).
 		put(swapFeesKey, uint64Bytes(md.SwapFeesPaid)).
 		put(redemptionFeesKey, uint64Bytes(md.RedemptionFeesPaid)).
 		put(optionsKey, config.Data(md.Options)).
@@ -1888,6 +1935,256 @@ func (db *BoltDB) LoadPokes() (pokes []*dexdb.Notification, _ error) {
 	})
 }
 
+// UpdateStopOrder saves the StopOrder, overwriting any existing stop order
+// with the same ID.
+func (db *BoltDB) UpdateStopOrder(so *dexdb.StopOrder) error {
+	if len(so.ID) == 0 {
+		return fmt.Errorf("cannot store stop order without an ID")
+	}
+	return db.withBucket(stopOrdersBucket, db.Update, func(bkt *bbolt.Bucket) error {
+		return bkt.Put(so.ID, so.Encode())
+	})
+}
+
+// StopOrders retrieves stop orders, sorted by descending placement time. If
+// activeOnly is true, only stop orders that are waiting to be triggered are
+// returned.
+func (db *BoltDB) StopOrders(activeOnly bool) (sos []*dexdb.StopOrder, _ error) {
+	err := db.withBucket(stopOrdersBucket, db.View, func(bkt *bbolt.Bucket) error {
+		return bkt.ForEach(func(k, v []byte) error {
+			so, err := dexdb.DecodeStopOrder(bytes.Clone(v))
+			if err != nil {
+				return fmt.Errorf("error decoding stop order %x: %w", k, err)
+			}
+			if activeOnly && so.Status != dexdb.StopOrderWaiting {
+				return nil
+			}
+			sos = append(sos, so)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(sos, func(i, j int) bool {
+		return sos[i].Stamp > sos[j].Stamp
+	})
+	return sos, nil
+}
+
+// UpdateScheduledOrder saves the ScheduledOrder, overwriting any existing
+// scheduled order with the same ID.
+func (db *BoltDB) UpdateScheduledOrder(so *dexdb.ScheduledOrder) error {
+	if len(so.ID) == 0 {
+		return fmt.Errorf("cannot store scheduled order without an ID")
+	}
+	return db.withBucket(scheduledOrdersBucket, db.Update, func(bkt *bbolt.Bucket) error {
+		return bkt.Put(so.ID, so.Encode())
+	})
+}
+
+// ScheduledOrder retrieves the scheduled order with the specified ID.
+func (db *BoltDB) ScheduledOrder(id []byte) (so *dexdb.ScheduledOrder, err error) {
+	err = db.withBucket(scheduledOrdersBucket, db.View, func(bkt *bbolt.Bucket) error {
+		v := bkt.Get(id)
+		if v == nil {
+			return fmt.Errorf("scheduled order %x not found", id)
+		}
+		so, err = dexdb.DecodeScheduledOrder(bytes.Clone(v))
+		return err
+	})
+	return so, err
+}
+
+// ScheduledOrders retrieves scheduled orders, sorted by descending creation
+// time. If activeOnly is true, only active scheduled orders are returned.
+func (db *BoltDB) ScheduledOrders(activeOnly bool) (sos []*dexdb.ScheduledOrder, _ error) {
+	err := db.withBucket(scheduledOrdersBucket, db.View, func(bkt *bbolt.Bucket) error {
+		return bkt.ForEach(func(k, v []byte) error {
+			so, err := dexdb.DecodeScheduledOrder(bytes.Clone(v))
+			if err != nil {
+				return fmt.Errorf("error decoding scheduled order %x: %w", k, err)
+			}
+			if activeOnly && so.Status != dexdb.ScheduledOrderActive {
+				return nil
+			}
+			sos = append(sos, so)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(sos, func(i, j int) bool {
+		return sos[i].Stamp > sos[j].Stamp
+	})
+	return sos, nil
+}
+
+// StoreFiatRates saves a snapshot of the fiat exchange rates, overwriting any
+// existing snapshot with the same time stamp. Snapshots are keyed by their
+// big-endian time stamp, so they are iterated in time order.
+func (db *BoltDB) StoreFiatRates(snap *dexdb.FiatRateSnapshot) error {
+	return db.withBucket(fiatRatesBucket, db.Update, func(bkt *bbolt.Bucket) error {
+		return bkt.Put(uint64Bytes(snap.Stamp), snap.Encode())
+	})
+}
+
+// FiatRateHistory retrieves the fiat rate snapshots with time stamps between
+// start and end, inclusive, sorted by ascending time stamp.
+func (db *BoltDB) FiatRateHistory(start, end uint64) (snaps []*dexdb.FiatRateSnapshot, _ error) {
+	err := db.withBucket(fiatRatesBucket, db.View, func(bkt *bbolt.Bucket) error {
+		c := bkt.Cursor()
+		for k, v := c.Seek(uint64Bytes(start)); k != nil && intCoder.Uint64(k) <= end; k, v = c.Next() {
+			snap, err := dexdb.DecodeFiatRateSnapshot(bytes.Clone(v))
+			if err != nil {
+				return fmt.Errorf("error decoding fiat rate snapshot %x: %w", k, err)
+			}
+			snaps = append(snaps, snap)
+		}
+		return nil
+	})
+	return snaps, err
+}
+
+// PruneFiatRates downsamples the fiat rate snapshots with time stamps before
+// the specified time, keeping only the first snapshot in each interval. The
+// number of snapshots deleted is returned.
+func (db *BoltDB) PruneFiatRates(before, interval uint64) (n int, _ error) {
+	if interval == 0 {
+		return 0, fmt.Errorf("zero fiat rate snapshot interval")
+	}
+	err := db.withBucket(fiatRatesBucket, db.Update, func(bkt *bbolt.Bucket) error {
+		var deletes [][]byte
+		lastSlot := uint64(math.MaxUint64)
+		c := bkt.Cursor()
+		for k, _ := c.First(); k != nil && intCoder.Uint64(k) < before; k, _ = c.Next() {
+			slot := intCoder.Uint64(k) / interval
+			if slot == lastSlot {
+				deletes = append(deletes, bytes.Clone(k))
+				continue
+			}
+			lastSlot = slot
+		}
+		for _, k := range deletes {
+			if err := bkt.Delete(k); err != nil {
+				return err
+			}
+		}
+		n = len(deletes)
+		return nil
+	})
+	return n, err
+}
+
+// UpdateAddressBookEntry saves the AddressBookEntry, overwriting any existing
+// entry with the same ID.
+func (db *BoltDB) UpdateAddressBookEntry(e *dexdb.AddressBookEntry) error {
+	if len(e.ID) == 0 {
+		return fmt.Errorf("cannot store address book entry without an ID")
+	}
+	return db.withBucket(addressBookBucket, db.Update, func(bkt *bbolt.Bucket) error {
+		return bkt.Put(e.ID, e.Encode())
+	})
+}
+
+// DeleteAddressBookEntry deletes the address book entry with the specified ID.
+func (db *BoltDB) DeleteAddressBookEntry(id []byte) error {
+	return db.withBucket(addressBookBucket, db.Update, func(bkt *bbolt.Bucket) error {
+		if bkt.Get(id) == nil {
+			return fmt.Errorf("address book entry %x not found", id)
+		}
+		return bkt.Delete(id)
+	})
+}
+
+// AddressBook retrieves all address book entries, sorted by ascending time
+// stamp.
+func (db *BoltDB) AddressBook() (entries []*dexdb.AddressBookEntry, _ error) {
+	err := db.withBucket(addressBookBucket, db.View, func(bkt *bbolt.Bucket) error {
+		return bkt.ForEach(func(k, v []byte) error {
+			e, err := dexdb.DecodeAddressBookEntry(bytes.Clone(v))
+			if err != nil {
+				return fmt.Errorf("error decoding address book entry %x: %w", k, err)
+			}
+			entries = append(entries, e)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Stamp < entries[j].Stamp
+	})
+	return entries, nil
+}
+
+// SetAllowlistSetting stores the state of the withdrawal allowlist.
+func (db *BoltDB) SetAllowlistSetting(s *dexdb.AllowlistSetting) error {
+	return db.withBucket(appBucket, db.Update, func(bkt *bbolt.Bucket) error {
+		return bkt.Put(allowlistKey, s.Encode())
+	})
+}
+
+// AllowlistSetting retrieves the state of the withdrawal allowlist. A disabled
+// setting is returned if none was stored.
+func (db *BoltDB) AllowlistSetting() (s *dexdb.AllowlistSetting, _ error) {
+	err := db.withBucket(appBucket, db.View, func(bkt *bbolt.Bucket) error {
+		b := bkt.Get(allowlistKey)
+		if b == nil {
+			s = new(dexdb.AllowlistSetting)
+			return nil
+		}
+		var err error
+		s, err = dexdb.DecodeAllowlistSetting(bytes.Clone(b))
+		return err
+	})
+	return s, err
+}
+
+// StoreAPIKey saves the APIKey, overwriting any existing key with the same
+// name.
+func (db *BoltDB) StoreAPIKey(k *dexdb.APIKey) error {
+	if k.Name == "" {
+		return fmt.Errorf("cannot store API key without a name")
+	}
+	return db.withBucket(apiKeysBucket, db.Update, func(bkt *bbolt.Bucket) error {
+		return bkt.Put([]byte(k.Name), k.Encode())
+	})
+}
+
+// DeleteAPIKey deletes the API key with the specified name.
+func (db *BoltDB) DeleteAPIKey(name string) error {
+	return db.withBucket(apiKeysBucket, db.Update, func(bkt *bbolt.Bucket) error {
+		if bkt.Get([]byte(name)) == nil {
+			return fmt.Errorf("API key %q not found", name)
+		}
+		return bkt.Delete([]byte(name))
+	})
+}
+
+// APIKeys retrieves all API keys, sorted by ascending time stamp.
+func (db *BoltDB) APIKeys() (keys []*dexdb.APIKey, _ error) {
+	err := db.withBucket(apiKeysBucket, db.View, func(bkt *bbolt.Bucket) error {
+		return bkt.ForEach(func(k, v []byte) error {
+			key, err := dexdb.DecodeAPIKey(bytes.Clone(v))
+			if err != nil {
+				return fmt.Errorf("error decoding API key %q: %w", k, err)
+			}
+			keys = append(keys, key)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].Stamp < keys[j].Stamp
+	})
+	return keys, nil
+}
+
 // newest buckets gets the nested buckets with the highest timestamp from the
 // specified master buckets. The nested bucket should have an encoded uint64 at
 // the timeKey. An optional filter function can be used to reject buckets.
@@ -5,9 +5,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
 	"math/rand"
 	"os"
 	"path/filepath"
+	"reflect"
 	"sync"
 	"testing"
 	"time"
@@ -1117,8 +1119,25 @@ func TestRecrypt(t *testing.T) {
 			return fmt.Errorf("account not updated")
 		}
 
+		entries, err := boltdb.AddressBook()
+		if err != nil {
+			return fmt.Errorf("error retrieving address book: %v", err)
+		}
+		if len(entries) != 1 || !bytes.Equal(entries[0].EncAddress, encPW) || !bytes.Equal(entries[0].EncLabel, encPW) {
+			return fmt.Errorf("address book not updated")
+		}
+
 		return nil
 	}
+	err := boltdb.UpdateAddressBookEntry(&db.AddressBookEntry{
+		ID:         randBytes(8),
+		AssetID:    42,
+		EncAddress: randBytes(20),
+		EncLabel:   randBytes(10),
+	})
+	if err != nil {
+		t.Fatalf("UpdateAddressBookEntry error: %v", err)
+	}
 	testCredentialsUpdate(t, boltdb, tester)
 }
 
@@ -1508,6 +1527,66 @@ func TestDeleteInactiveOrders(t *testing.T) {
 	}
 }
 
+func TestAmendedOrders(t *testing.T) {
+	boltdb, shutdown := newTestDB(t)
+	defer shutdown()
+
+	acct := dbtest.RandomAccountInfo()
+	if err := boltdb.CreateAccount(acct); err != nil {
+		t.Fatalf("CreateAccount error: %v", err)
+	}
+
+	newMetaOrder := func() *db.MetaOrder {
+		ord, _ := ordertest.RandomLimitOrder()
+		return &db.MetaOrder{
+			MetaData: &db.OrderMetaData{
+				Status: order.OrderStatusBooked,
+				Host:   acct.Host,
+				Proof:  db.OrderProof{DEXSig: randBytes(73)},
+			},
+			Order: ord,
+		}
+	}
+
+	// The replacement is stored with the link to the original, and then the
+	// original is updated with the link to the replacement.
+	orig, replacement := newMetaOrder(), newMetaOrder()
+	replacement.MetaData.AmendedFrom = orig.Order.ID()
+	for _, mord := range []*db.MetaOrder{orig, replacement} {
+		if err := boltdb.UpdateOrder(mord); err != nil {
+			t.Fatalf("UpdateOrder error: %v", err)
+		}
+	}
+	orig.MetaData.Status = order.OrderStatusCanceled
+	orig.MetaData.AmendedBy = replacement.Order.ID()
+	if err := boltdb.UpdateOrderMetaData(orig.Order.ID(), orig.MetaData); err != nil {
+		t.Fatalf("UpdateOrderMetaData error: %v", err)
+	}
+
+	ords, err := boltdb.Orders(&db.OrderFilter{N: 10})
+	if err != nil {
+		t.Fatalf("Orders error: %v", err)
+	}
+	if len(ords) != 2 {
+		t.Fatalf("expected 2 orders, got %d", len(ords))
+	}
+	for _, mord := range ords {
+		var wantFrom, wantBy order.OrderID
+		switch mord.Order.ID() {
+		case orig.Order.ID():
+			wantBy = replacement.Order.ID()
+		case replacement.Order.ID():
+			wantFrom = orig.Order.ID()
+		default:
+			t.Fatalf("unknown order %s", mord.Order.ID())
+		}
+		if mord.MetaData.AmendedFrom != wantFrom || mord.MetaData.AmendedBy != wantBy {
+			t.Fatalf("wrong amendment links for order %s. amended from %s, amended by %s",
+				mord.Order.ID(), mord.MetaData.AmendedFrom, mord.MetaData.AmendedBy)
+		}
+	}
+}
+
 func TestOrderSide(t *testing.T) {
 	boltdb, shutdown := newTestDB(t)
 	defer shutdown()
@@ -1613,3 +1692,318 @@ func TestPokes(t *testing.T) {
 		t.Fatal("Result from second LoadPokes wasn't empty")
 	}
 }
+
+func TestStopOrders(t *testing.T) {
+	boltdb, shutdown := newTestDB(t)
+	defer shutdown()
+
+	const numToDo = 20
+	sos := make([]*db.StopOrder, 0, numToDo)
+	nTimes(numToDo, func(int) {
+		sos = append(sos, dbtest.RandomStopOrder(math.MaxInt64))
+	})
+	for _, so := range sos {
+		if err := boltdb.UpdateStopOrder(so); err != nil {
+			t.Fatalf("UpdateStopOrder error: %v", err)
+		}
+	}
+
+	// Trigger one and cancel another.
+	triggered, canceled := sos[0], sos[1]
+	triggered.Status = db.StopOrderTriggered
+	triggered.TriggerStamp = uint64(time.Now().UnixMilli())
+	triggered.OrderID = ordertest.RandomOrderID()
+	canceled.Status = db.StopOrderCanceled
+	for _, so := range []*db.StopOrder{triggered, canceled} {
+		if err := boltdb.UpdateStopOrder(so); err != nil {
+			t.Fatalf("UpdateStopOrder error: %v", err)
+		}
+	}
+
+	all, err := boltdb.StopOrders(false)
+	if err != nil {
+		t.Fatalf("StopOrders error: %v", err)
+	}
+	if len(all) != numToDo {
+		t.Fatalf("expected %d stop orders, got %d", numToDo, len(all))
+	}
+	for i := 1; i < len(all); i++ {
+		if all[i].Stamp > all[i-1].Stamp {
+			t.Fatalf("stop orders not sorted by descending stamp")
+		}
+	}
+	byID := make(map[string]*db.StopOrder, numToDo)
+	for _, so := range all {
+		byID[so.ID.String()] = so
+	}
+	for _, so := range sos {
+		reSO, found := byID[so.ID.String()]
+		if !found {
+			t.Fatalf("stop order %s not found", so.ID)
+		}
+		dbtest.MustCompareStopOrders(t, so, reSO)
+	}
+
+	active, err := boltdb.StopOrders(true)
+	if err != nil {
+		t.Fatalf("StopOrders(active) error: %v", err)
+	}
+	if len(active) != numToDo-2 {
+		t.Fatalf("expected %d active stop orders, got %d", numToDo-2, len(active))
+	}
+	for _, so := range active {
+		if so.Status != db.StopOrderWaiting {
+			t.Fatalf("inactive stop order %s returned", so.ID)
+		}
+	}
+}
+
+func TestScheduledOrders(t *testing.T) {
+	boltdb, shutdown := newTestDB(t)
+	defer shutdown()
+
+	const numToDo = 20
+	sos := make([]*db.ScheduledOrder, 0, numToDo)
+	nTimes(numToDo, func(int) {
+		sos = append(sos, dbtest.RandomScheduledOrder(math.MaxInt64))
+	})
+	for _, so := range sos {
+		if err := boltdb.UpdateScheduledOrder(so); err != nil {
+			t.Fatalf("UpdateScheduledOrder error: %v", err)
+		}
+	}
+
+	// Complete one and cancel another.
+	completed, canceled := sos[0], sos[1]
+	completed.Status = db.ScheduledOrderCompleted
+	completed.EndStamp = uint64(time.Now().UnixMilli())
+	completed.ChildIDs = append(completed.ChildIDs, ordertest.RandomOrderID())
+	canceled.Status = db.ScheduledOrderCanceled
+	for _, so := range []*db.ScheduledOrder{completed, canceled} {
+		if err := boltdb.UpdateScheduledOrder(so); err != nil {
+			t.Fatalf("UpdateScheduledOrder error: %v", err)
+		}
+	}
+
+	reSO, err := boltdb.ScheduledOrder(completed.ID)
+	if err != nil {
+		t.Fatalf("ScheduledOrder error: %v", err)
+	}
+	dbtest.MustCompareScheduledOrders(t, completed, reSO)
+	if _, err := boltdb.ScheduledOrder([]byte{0x01}); err == nil {
+		t.Fatalf("no error retrieving unknown scheduled order")
+	}
+
+	all, err := boltdb.ScheduledOrders(false)
+	if err != nil {
+		t.Fatalf("ScheduledOrders error: %v", err)
+	}
+	if len(all) != numToDo {
+		t.Fatalf("expected %d scheduled orders, got %d", numToDo, len(all))
+	}
+	for i := 1; i < len(all); i++ {
+		if all[i].Stamp > all[i-1].Stamp {
+			t.Fatalf("scheduled orders not sorted by descending stamp")
+		}
+	}
+	byID := make(map[string]*db.ScheduledOrder, numToDo)
+	for _, so := range all {
+		byID[so.ID.String()] = so
+	}
+	for _, so := range sos {
+		reSO, found := byID[so.ID.String()]
+		if !found {
+			t.Fatalf("scheduled order %s not found", so.ID)
+		}
+		dbtest.MustCompareScheduledOrders(t, so, reSO)
+	}
+
+	active, err := boltdb.ScheduledOrders(true)
+	if err != nil {
+		t.Fatalf("ScheduledOrders(active) error: %v", err)
+	}
+	if len(active) != numToDo-2 {
+		t.Fatalf("expected %d active scheduled orders, got %d", numToDo-2, len(active))
+	}
+	for _, so := range active {
+		if so.Status != db.ScheduledOrderActive {
+			t.Fatalf("inactive scheduled order %s returned", so.ID)
+		}
+	}
+}
+
+func TestFiatRateHistory(t *testing.T) {
+	boltdb, shutdown := newTestDB(t)
+	defer shutdown()
+
+	const numToDo = 10
+	snaps := make([]*db.FiatRateSnapshot, 0, numToDo)
+	for i := uint64(0); i < numToDo; i++ {
+		snaps = append(snaps, &db.FiatRateSnapshot{
+			// Stamps that would sort differently as little-endian.
+			Stamp: 1000 + i*256,
+			Rates: map[uint32]float64{42: rand.Float64() * 100, 0: rand.Float64() * 1e5},
+		})
+	}
+	for i := len(snaps) - 1; i >= 0; i-- {
+		if err := boltdb.StoreFiatRates(snaps[i]); err != nil {
+			t.Fatalf("StoreFiatRates error: %v", err)
+		}
+	}
+
+	hist, err := boltdb.FiatRateHistory(snaps[2].Stamp, snaps[7].Stamp)
+	if err != nil {
+		t.Fatalf("FiatRateHistory error: %v", err)
+	}
+	if len(hist) != 6 {
+		t.Fatalf("expected 6 snapshots, got %d", len(hist))
+	}
+	for i, snap := range hist {
+		want := snaps[i+2]
+		if snap.Stamp != want.Stamp {
+			t.Fatalf("snapshot %d: wrong stamp. wanted %d, got %d", i, want.Stamp, snap.Stamp)
+		}
+		if len(snap.Rates) != len(want.Rates) {
+			t.Fatalf("snapshot %d: wrong number of rates", i)
+		}
+		for assetID, rate := range want.Rates {
+			if snap.Rates[assetID] != rate {
+				t.Fatalf("snapshot %d: wrong rate for asset %d. wanted %f, got %f", i, assetID, rate, snap.Rates[assetID])
+			}
+		}
+	}
+
+	hist, err = boltdb.FiatRateHistory(0, snaps[0].Stamp-1)
+	if err != nil {
+		t.Fatalf("FiatRateHistory error: %v", err)
+	}
+	if len(hist) != 0 {
+		t.Fatalf("expected no snapshots, got %d", len(hist))
+	}
+
+	// Downsample the snapshots before snaps[6] to one per 1024 ms. Slot 1
+	// (1256, 1512, 1768, 2024) is pruned to 1256.
+	n, err := boltdb.PruneFiatRates(snaps[6].Stamp, 1024)
+	if err != nil {
+		t.Fatalf("PruneFiatRates error: %v", err)
+	}
+	if n != 3 {
+		t.Fatalf("expected 3 snapshots pruned, got %d", n)
+	}
+	if hist, err = boltdb.FiatRateHistory(0, math.MaxUint64); err != nil {
+		t.Fatalf("FiatRateHistory error: %v", err)
+	}
+	var stamps []uint64
+	for _, snap := range hist {
+		stamps = append(stamps, snap.Stamp)
+	}
+	wantStamps := []uint64{1000, 1256, 2280, 2536, 2792, 3048, 3304}
+	if !reflect.DeepEqual(stamps, wantStamps) {
+		t.Fatalf("wrong stamps after pruning. wanted %v, got %v", wantStamps, stamps)
+	}
+}
+
+func TestAddressBook(t *testing.T) {
+	boltdb, shutdown := newTestDB(t)
+	defer shutdown()
+
+	const numToDo = 5
+	entries := make([]*db.AddressBookEntry, 0, numToDo)
+	for i := uint64(0); i < numToDo; i++ {
+		entries = append(entries, &db.AddressBookEntry{
+			ID:         randBytes(8),
+			AssetID:    uint32(i),
+			EncAddress: randBytes(30),
+			EncLabel:   randBytes(10),
+			Stamp:      1000 + i,
+			ActiveAt:   2000 + i,
+		})
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		if err := boltdb.UpdateAddressBookEntry(entries[i]); err != nil {
+			t.Fatalf("UpdateAddressBookEntry error: %v", err)
+		}
+	}
+	if err := boltdb.UpdateAddressBookEntry(&db.AddressBookEntry{}); err == nil {
+		t.Fatalf("no error for entry without an ID")
+	}
+
+	if err := boltdb.DeleteAddressBookEntry(entries[1].ID); err != nil {
+		t.Fatalf("DeleteAddressBookEntry error: %v", err)
+	}
+	if err := boltdb.DeleteAddressBookEntry(entries[1].ID); err == nil {
+		t.Fatalf("no error deleting a missing entry")
+	}
+	entries = append(entries[:1], entries[2:]...)
+
+	book, err := boltdb.AddressBook()
+	if err != nil {
+		t.Fatalf("AddressBook error: %v", err)
+	}
+	if len(book) != len(entries) {
+		t.Fatalf("expected %d entries, got %d", len(entries), len(book))
+	}
+	for i, e := range book {
+		if !reflect.DeepEqual(e, entries[i]) {
+			t.Fatalf("entry %d: wanted %+v, got %+v", i, entries[i], e)
+		}
+	}
+
+	setting, err := boltdb.AllowlistSetting()
+	if err != nil {
+		t.Fatalf("AllowlistSetting error: %v", err)
+	}
+	if setting.Enabled || setting.DisableAt != 0 {
+		t.Fatalf("allowlist enabled by default")
+	}
+	want := &db.AllowlistSetting{Enabled: true, DisableAt: 12345}
+	if err := boltdb.SetAllowlistSetting(want); err != nil {
+		t.Fatalf("SetAllowlistSetting error: %v", err)
+	}
+	if setting, err = boltdb.AllowlistSetting(); err != nil {
+		t.Fatalf("AllowlistSetting error: %v", err)
+	}
+	if *setting != *want {
+		t.Fatalf("wrong allowlist setting. wanted %+v, got %+v", want, setting)
+	}
+}
+
+func TestAPIKeys(t *testing.T) {
+	boltdb, shutdown := newTestDB(t)
+	defer shutdown()
+
+	keys := []*db.APIKey{
+		{Name: "monitor", Hash: randBytes(32), Scopes: []string{"read"}, RateLimit: 60, Stamp: 1000},
+		{Name: "trader", Hash: randBytes(32), Scopes: []string{"read", "trade"}, Stamp: 1001},
+		{Name: "none", Hash: randBytes(32), Stamp: 1002},
+	}
+	for i := len(keys) - 1; i >= 0; i-- {
+		if err := boltdb.StoreAPIKey(keys[i]); err != nil {
+			t.Fatalf("StoreAPIKey error: %v", err)
+		}
+	}
+	if err := boltdb.StoreAPIKey(&db.APIKey{}); err == nil {
+		t.Fatalf("no error for key without a name")
+	}
+
+	if err := boltdb.DeleteAPIKey("trader"); err != nil {
+		t.Fatalf("DeleteAPIKey error: %v", err)
+	}
+	if err := boltdb.DeleteAPIKey("trader"); err == nil {
+		t.Fatalf("no error deleting a missing key")
+	}
+	keys = append(keys[:1], keys[2:]...)
+
+	stored, err := boltdb.APIKeys()
+	if err != nil {
+		t.Fatalf("APIKeys error: %v", err)
+	}
+	if len(stored) != len(keys) {
+		t.Fatalf("expected %d keys, got %d", len(keys), len(stored))
+	}
+	for i, k := range stored {
+		if !reflect.DeepEqual(k, keys[i]) {
+			t.Fatalf("key %d: wanted %+v, got %+v", i, keys[i], k)
+		}
+	}
+}
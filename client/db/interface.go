@@ -151,6 +151,53 @@ type DB interface {
 	// SaveDisabledRateSources saves disabled fiat rate sources in the database.
 	// A source name must not contain a comma.
 	SaveDisabledRateSources(disabledSources []string) error
+	// UpdateStopOrder saves the StopOrder, overwriting any existing stop order
+	// with the same ID.
+	UpdateStopOrder(so *StopOrder) error
+	// StopOrders retrieves stop orders, sorted by descending placement time.
+	// If activeOnly is true, only stop orders that are waiting to be
+	// triggered are returned.
+	StopOrders(activeOnly bool) ([]*StopOrder, error)
+	// UpdateScheduledOrder saves the ScheduledOrder, overwriting any existing
+	// scheduled order with the same ID.
+	UpdateScheduledOrder(so *ScheduledOrder) error
+	// ScheduledOrder retrieves the scheduled order with the specified ID.
+	ScheduledOrder(id []byte) (*ScheduledOrder, error)
+	// ScheduledOrders retrieves scheduled orders, sorted by descending
+	// creation time. If activeOnly is true, only active scheduled orders are
+	// returned.
+	ScheduledOrders(activeOnly bool) ([]*ScheduledOrder, error)
+	// StoreFiatRates saves a snapshot of the fiat exchange rates, overwriting
+	// any existing snapshot with the same time stamp.
+	StoreFiatRates(snap *FiatRateSnapshot) error
+	// FiatRateHistory retrieves the fiat rate snapshots with time stamps
+	// between start and end, inclusive, sorted by ascending time stamp.
+	FiatRateHistory(start, end uint64) ([]*FiatRateSnapshot, error)
+	// PruneFiatRates downsamples the fiat rate snapshots with time stamps
+	// before the specified time, keeping only the first snapshot in each
+	// interval. The number of snapshots deleted is returned.
+	PruneFiatRates(before, interval uint64) (int, error)
+	// UpdateAddressBookEntry saves the AddressBookEntry, overwriting any
+	// existing entry with the same ID.
+	UpdateAddressBookEntry(e *AddressBookEntry) error
+	// DeleteAddressBookEntry deletes the address book entry with the
+	// specified ID.
+	DeleteAddressBookEntry(id []byte) error
+	// AddressBook retrieves all address book entries, sorted by ascending
+	// time stamp.
+	AddressBook() ([]*AddressBookEntry, error)
+	// SetAllowlistSetting stores the state of the withdrawal allowlist.
+	SetAllowlistSetting(s *AllowlistSetting) error
+	// AllowlistSetting retrieves the state of the withdrawal allowlist. A
+	// disabled setting is returned if none was stored.
+	AllowlistSetting() (*AllowlistSetting, error)
+	// StoreAPIKey saves the APIKey, overwriting any existing key with the same
+	// name.
+	StoreAPIKey(k *APIKey) error
+	// DeleteAPIKey deletes the API key with the specified name.
+	DeleteAPIKey(name string) error
+	// APIKeys retrieves all API keys, sorted by ascending time stamp.
+	APIKeys() ([]*APIKey, error)
 	// SetLanguage stores the user's chosen language.
 	SetLanguage(lang string) error
 	// Language gets the language stored with SetLanguage.
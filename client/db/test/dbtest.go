@@ -12,6 +12,7 @@ import (
 
 	"decred.org/dcrdex/client/asset"
 	"decred.org/dcrdex/client/db"
+	"decred.org/dcrdex/dex/order"
 	ordertest "decred.org/dcrdex/dex/order/test"
 	"github.com/decred/dcrd/dcrec/secp256k1/v4"
 )
@@ -165,6 +166,60 @@ func RandomNotification(maxTime uint64) *db.Notification {
 	}
 }
 
+// RandomStopOrder creates a random waiting StopOrder.
+func RandomStopOrder(maxTime uint64) *db.StopOrder {
+	return &db.StopOrder{
+		ID:          randBytes(8),
+		Type:        db.StopOrderType(rand.IntN(2)) + db.StopLimit,
+		Host:        ordertest.RandomAddress(),
+		Base:        rand.Uint32(),
+		Quote:       rand.Uint32(),
+		Sell:        rand.IntN(2) == 0,
+		Qty:         rand.Uint64(),
+		PriceSource: db.PriceSource(rand.IntN(2)),
+		TriggerRate: rand.Uint64(),
+		Rate:        rand.Uint64(),
+		TrailDelta:  rand.Uint64(),
+		LimitOffset: rand.Uint64(),
+		TifNow:      rand.IntN(2) == 0,
+		Options:     map[string]string{strings.ToLower(ordertest.RandomAddress()): ordertest.RandomAddress()},
+		Status:      db.StopOrderWaiting,
+		Stamp:       uint64(rand.Int64N(int64(maxTime))),
+		RefRate:     rand.Uint64(),
+	}
+}
+
+// RandomScheduledOrder creates a random active ScheduledOrder.
+func RandomScheduledOrder(maxTime uint64) *db.ScheduledOrder {
+	childIDs := make([]order.OrderID, rand.IntN(5))
+	for i := range childIDs {
+		childIDs[i] = ordertest.RandomOrderID()
+	}
+	return &db.ScheduledOrder{
+		ID:             randBytes(8),
+		Type:           db.ScheduledOrderType(rand.IntN(2)) + db.TWAP,
+		Host:           ordertest.RandomAddress(),
+		Base:           rand.Uint32(),
+		Quote:          rand.Uint32(),
+		Sell:           rand.IntN(2) == 0,
+		Qty:            rand.Uint64(),
+		Rate:           rand.Uint64(),
+		Duration:       rand.Uint64(),
+		Slices:         rand.Uint32(),
+		VisibleQty:     rand.Uint64(),
+		TifNow:         rand.IntN(2) == 0,
+		Options:        map[string]string{strings.ToLower(ordertest.RandomAddress()): ordertest.RandomAddress()},
+		Status:         db.ScheduledOrderActive,
+		Stamp:          uint64(rand.Int64N(int64(maxTime))),
+		ChildIDs:       childIDs,
+		Placed:         rand.Uint64(),
+		Filled:         rand.Uint64(),
+		SwapFees:       rand.Uint64(),
+		RedemptionFees: rand.Uint64(),
+		FundingFees:    rand.Uint64(),
+	}
+}
+
 type testKiller interface {
 	Helper()
 	Fatalf(string, ...any)
@@ -358,3 +413,148 @@ func MustCompareNotifications(t testKiller, n1, n2 *db.Notification) {
 		t.Fatalf("ID mismatch. %s != %s", n1.ID(), n2.ID())
 	}
 }
+
+// MustCompareStopOrders ensures the two StopOrders are identical, calling the
+// Fatalf method of the testKiller if not.
+func MustCompareStopOrders(t testKiller, so1, so2 *db.StopOrder) {
+	t.Helper()
+	if !bytes.Equal(so1.ID, so2.ID) {
+		t.Fatalf("ID mismatch. %s != %s", so1.ID, so2.ID)
+	}
+	if so1.Type != so2.Type {
+		t.Fatalf("Type mismatch. %s != %s", so1.Type, so2.Type)
+	}
+	if so1.Host != so2.Host {
+		t.Fatalf("Host mismatch. %s != %s", so1.Host, so2.Host)
+	}
+	if so1.Base != so2.Base || so1.Quote != so2.Quote {
+		t.Fatalf("market mismatch. %d-%d != %d-%d", so1.Base, so1.Quote, so2.Base, so2.Quote)
+	}
+	if so1.Sell != so2.Sell {
+		t.Fatalf("Sell mismatch. %t != %t", so1.Sell, so2.Sell)
+	}
+	if so1.Qty != so2.Qty {
+		t.Fatalf("Qty mismatch. %d != %d", so1.Qty, so2.Qty)
+	}
+	if so1.PriceSource != so2.PriceSource {
+		t.Fatalf("PriceSource mismatch. %d != %d", so1.PriceSource, so2.PriceSource)
+	}
+	if so1.TriggerRate != so2.TriggerRate {
+		t.Fatalf("TriggerRate mismatch. %d != %d", so1.TriggerRate, so2.TriggerRate)
+	}
+	if so1.Rate != so2.Rate {
+		t.Fatalf("Rate mismatch. %d != %d", so1.Rate, so2.Rate)
+	}
+	if so1.TrailDelta != so2.TrailDelta {
+		t.Fatalf("TrailDelta mismatch. %d != %d", so1.TrailDelta, so2.TrailDelta)
+	}
+	if so1.LimitOffset != so2.LimitOffset {
+		t.Fatalf("LimitOffset mismatch. %d != %d", so1.LimitOffset, so2.LimitOffset)
+	}
+	if so1.TifNow != so2.TifNow {
+		t.Fatalf("TifNow mismatch. %t != %t", so1.TifNow, so2.TifNow)
+	}
+	if len(so1.Options) != len(so2.Options) {
+		t.Fatalf("Options length mismatch. %d != %d", len(so1.Options), len(so2.Options))
+	}
+	for k, v := range so1.Options {
+		if so2.Options[k] != v {
+			t.Fatalf("Options mismatch for %s. %s != %s", k, v, so2.Options[k])
+		}
+	}
+	if so1.Status != so2.Status {
+		t.Fatalf("Status mismatch. %s != %s", so1.Status, so2.Status)
+	}
+	if so1.Stamp != so2.Stamp {
+		t.Fatalf("Stamp mismatch. %d != %d", so1.Stamp, so2.Stamp)
+	}
+	if so1.RefRate != so2.RefRate {
+		t.Fatalf("RefRate mismatch. %d != %d", so1.RefRate, so2.RefRate)
+	}
+	if so1.TriggerStamp != so2.TriggerStamp {
+		t.Fatalf("TriggerStamp mismatch. %d != %d", so1.TriggerStamp, so2.TriggerStamp)
+	}
+	if so1.OrderID != so2.OrderID {
+		t.Fatalf("OrderID mismatch. %s != %s", so1.OrderID, so2.OrderID)
+	}
+	if so1.Err != so2.Err {
+		t.Fatalf("Err mismatch. %s != %s", so1.Err, so2.Err)
+	}
+}
+
+// MustCompareScheduledOrders ensures the two ScheduledOrders are identical,
+// calling the Fatalf method of the testKiller if not.
+func MustCompareScheduledOrders(t testKiller, so1, so2 *db.ScheduledOrder) {
+	t.Helper()
+	if !bytes.Equal(so1.ID, so2.ID) {
+		t.Fatalf("ID mismatch. %s != %s", so1.ID, so2.ID)
+	}
+	if so1.Type != so2.Type {
+		t.Fatalf("Type mismatch. %s != %s", so1.Type, so2.Type)
+	}
+	if so1.Host != so2.Host {
+		t.Fatalf("Host mismatch. %s != %s", so1.Host, so2.Host)
+	}
+	if so1.Base != so2.Base || so1.Quote != so2.Quote {
+		t.Fatalf("market mismatch. %d-%d != %d-%d", so1.Base, so1.Quote, so2.Base, so2.Quote)
+	}
+	if so1.Sell != so2.Sell {
+		t.Fatalf("Sell mismatch. %t != %t", so1.Sell, so2.Sell)
+	}
+	if so1.Qty != so2.Qty {
+		t.Fatalf("Qty mismatch. %d != %d", so1.Qty, so2.Qty)
+	}
+	if so1.Rate != so2.Rate {
+		t.Fatalf("Rate mismatch. %d != %d", so1.Rate, so2.Rate)
+	}
+	if so1.Duration != so2.Duration {
+		t.Fatalf("Duration mismatch. %d != %d", so1.Duration, so2.Duration)
+	}
+	if so1.Slices != so2.Slices {
+		t.Fatalf("Slices mismatch. %d != %d", so1.Slices, so2.Slices)
+	}
+	if so1.VisibleQty != so2.VisibleQty {
+		t.Fatalf("VisibleQty mismatch. %d != %d", so1.VisibleQty, so2.VisibleQty)
+	}
+	if so1.TifNow != so2.TifNow {
+		t.Fatalf("TifNow mismatch. %t != %t", so1.TifNow, so2.TifNow)
+	}
+	if len(so1.Options) != len(so2.Options) {
+		t.Fatalf("Options length mismatch. %d != %d", len(so1.Options), len(so2.Options))
+	}
+	for k, v := range so1.Options {
+		if so2.Options[k] != v {
+			t.Fatalf("Options mismatch for %s. %s != %s", k, v, so2.Options[k])
+		}
+	}
+	if so1.Status != so2.Status {
+		t.Fatalf("Status mismatch. %s != %s", so1.Status, so2.Status)
+	}
+	if so1.Stamp != so2.Stamp {
+		t.Fatalf("Stamp mismatch. %d != %d", so1.Stamp, so2.Stamp)
+	}
+	if len(so1.ChildIDs) != len(so2.ChildIDs) {
+		t.Fatalf("ChildIDs length mismatch. %d != %d", len(so1.ChildIDs), len(so2.ChildIDs))
+	}
+	for i, oid := range so1.ChildIDs {
+		if so2.ChildIDs[i] != oid {
+			t.Fatalf("ChildIDs mismatch at index %d. %s != %s", i, oid, so2.ChildIDs[i])
+		}
+	}
+	if so1.Placed != so2.Placed {
+		t.Fatalf("Placed mismatch. %d != %d", so1.Placed, so2.Placed)
+	}
+	if so1.Filled != so2.Filled {
+		t.Fatalf("Filled mismatch. %d != %d", so1.Filled, so2.Filled)
+	}
+	if so1.SwapFees != so2.SwapFees || so1.RedemptionFees != so2.RedemptionFees || so1.FundingFees != so2.FundingFees {
+		t.Fatalf("fees mismatch. %d/%d/%d != %d/%d/%d", so1.SwapFees, so1.RedemptionFees, so1.FundingFees,
+			so2.SwapFees, so2.RedemptionFees, so2.FundingFees)
+	}
+	if so1.EndStamp != so2.EndStamp {
+		t.Fatalf("EndStamp mismatch. %d != %d", so1.EndStamp, so2.EndStamp)
+	}
+	if so1.Err != so2.Err {
+		t.Fatalf("Err mismatch. %s != %s", so1.Err, so2.Err)
+	}
+}
@@ -421,6 +421,11 @@ type OrderMetaData struct {
 	// LinkedOrder is used to specify the cancellation order for a trade, or
 	// vice-versa.
 	LinkedOrder order.OrderID
+	// AmendedFrom is the order that this order replaced when that order was
+	// amended.
+	AmendedFrom order.OrderID
+	// AmendedBy is the order that replaced this order when it was amended.
+	AmendedBy order.OrderID
 	// SwapFeesPaid is the sum of the actual fees paid for all swaps.
 	SwapFeesPaid uint64
 	// RedemptionFeesPaid is the sum of the actual fees paid for all
@@ -1234,3 +1239,586 @@ func hashKey(b []byte) []byte {
 	h := blake2s.Sum256(b)
 	return h[:]
 }
+
+// StopOrderType is the type of a client-side conditional order.
+type StopOrderType uint8
+
+const (
+	// StopLimit orders place a limit order at a fixed rate once the market
+	// rate crosses the trigger rate.
+	StopLimit StopOrderType = iota + 1
+	// TrailingStop orders track the most favorable market rate seen since
+	// placement and trigger once the market moves against it by the trail
+	// delta.
+	TrailingStop
+)
+
+// String returns a string representation of the StopOrderType.
+func (t StopOrderType) String() string {
+	switch t {
+	case StopLimit:
+		return "stoplimit"
+	case TrailingStop:
+		return "trailingstop"
+	}
+	return "unknown"
+}
+
+// StopOrderStatus is the status of a client-side conditional order.
+type StopOrderStatus uint8
+
+const (
+	StopOrderStatusUnknown StopOrderStatus = iota
+	// StopOrderWaiting is a stop order that is waiting to be triggered.
+	StopOrderWaiting
+	// StopOrderTriggered is a stop order that has been triggered and for
+	// which a limit order was submitted.
+	StopOrderTriggered
+	// StopOrderCanceled is a stop order canceled by the user before it was
+	// triggered.
+	StopOrderCanceled
+	// StopOrderFailed is a stop order that was triggered, but for which the
+	// limit order could not be placed.
+	StopOrderFailed
+)
+
+// String returns a string representation of the StopOrderStatus.
+func (s StopOrderStatus) String() string {
+	switch s {
+	case StopOrderWaiting:
+		return "waiting"
+	case StopOrderTriggered:
+		return "triggered"
+	case StopOrderCanceled:
+		return "canceled"
+	case StopOrderFailed:
+		return "failed"
+	}
+	return "unknown"
+}
+
+// PriceSource is the market rate that a stop order is checked against.
+type PriceSource uint8
+
+const (
+	// PriceSourceMidGap uses the order book's mid-gap rate.
+	PriceSourceMidGap PriceSource = iota
+	// PriceSourceLastTrade uses the rate of the most recent match.
+	PriceSourceLastTrade
+)
+
+// String returns a string representation of the PriceSource.
+func (p PriceSource) String() string {
+	switch p {
+	case PriceSourceMidGap:
+		return "midgap"
+	case PriceSourceLastTrade:
+		return "lasttrade"
+	}
+	return "unknown"
+}
+
+// StopOrder is a client-side conditional order. A StopOrder is not known to
+// the server until it is triggered, at which point a standard limit order is
+// submitted.
+type StopOrder struct {
+	ID          dex.Bytes
+	Type        StopOrderType
+	Host        string
+	Base        uint32
+	Quote       uint32
+	Sell        bool
+	Qty         uint64
+	PriceSource PriceSource
+	// TriggerRate is the rate at which a StopLimit order is triggered.
+	TriggerRate uint64
+	// Rate is the limit rate of the order placed when a StopLimit order is
+	// triggered.
+	Rate uint64
+	// TrailDelta is how far the market rate must move against the RefRate
+	// to trigger a TrailingStop order.
+	TrailDelta uint64
+	// LimitOffset is subtracted from (sell) or added to (buy) the trigger
+	// rate of a TrailingStop order to get the limit rate of the placed order.
+	LimitOffset uint64
+	TifNow      bool
+	Options     map[string]string
+	Status      StopOrderStatus
+	// Stamp is the time of placement, in milliseconds.
+	Stamp uint64
+	// RefRate is the most favorable market rate observed for a TrailingStop
+	// order, i.e. the highest rate for a sell, and the lowest for a buy.
+	RefRate uint64
+	// TriggerStamp is the time the order was triggered, in milliseconds.
+	TriggerStamp uint64
+	// OrderID is the ID of the limit order placed once triggered.
+	OrderID order.OrderID
+	// Err is the error encountered placing the limit order, if any.
+	Err string
+}
+
+// Encode encodes the StopOrder to a versioned blob.
+func (so *StopOrder) Encode() []byte {
+	return versionedBytes(0).
+		AddData(so.ID).
+		AddData([]byte{byte(so.Type)}).
+		AddData([]byte(so.Host)).
+		AddData(uint32Bytes(so.Base)).
+		AddData(uint32Bytes(so.Quote)).
+		AddData(boolByte(so.Sell)).
+		AddData(uint64Bytes(so.Qty)).
+		AddData([]byte{byte(so.PriceSource)}).
+		AddData(uint64Bytes(so.TriggerRate)).
+		AddData(uint64Bytes(so.Rate)).
+		AddData(uint64Bytes(so.TrailDelta)).
+		AddData(uint64Bytes(so.LimitOffset)).
+		AddData(boolByte(so.TifNow)).
+		AddData(config.Data(so.Options)).
+		AddData([]byte{byte(so.Status)}).
+		AddData(uint64Bytes(so.Stamp)).
+		AddData(uint64Bytes(so.RefRate)).
+		AddData(uint64Bytes(so.TriggerStamp)).
+		AddData(so.OrderID[:]).
+		AddData([]byte(so.Err))
+}
+
+// DecodeStopOrder decodes the versioned blob to a *StopOrder.
+func DecodeStopOrder(b []byte) (*StopOrder, error) {
+	ver, pushes, err := encode.DecodeBlob(b, 20)
+	if err != nil {
+		return nil, err
+	}
+	switch ver {
+	case 0:
+		return decodeStopOrder_v0(pushes)
+	}
+	return nil, fmt.Errorf("unknown StopOrder version %d", ver)
+}
+
+func decodeStopOrder_v0(pushes [][]byte) (*StopOrder, error) {
+	if len(pushes) != 20 {
+		return nil, fmt.Errorf("decodeStopOrder_v0: expected 20 pushes, got %d", len(pushes))
+	}
+	for _, i := range []int{1, 5, 7, 12, 14} {
+		if len(pushes[i]) != 1 {
+			return nil, fmt.Errorf("decodeStopOrder_v0: push %d is supposed to be length 1. got %d", i, len(pushes[i]))
+		}
+	}
+	if len(pushes[18]) != order.OrderIDSize {
+		return nil, fmt.Errorf("decodeStopOrder_v0: invalid order ID length %d", len(pushes[18]))
+	}
+	opts, err := config.Parse(pushes[13])
+	if err != nil {
+		return nil, fmt.Errorf("decodeStopOrder_v0: unable to decode options: %w", err)
+	}
+	so := &StopOrder{
+		ID:           pushes[0],
+		Type:         StopOrderType(pushes[1][0]),
+		Host:         string(pushes[2]),
+		Base:         intCoder.Uint32(pushes[3]),
+		Quote:        intCoder.Uint32(pushes[4]),
+		Sell:         pushes[5][0] == 1,
+		Qty:          intCoder.Uint64(pushes[6]),
+		PriceSource:  PriceSource(pushes[7][0]),
+		TriggerRate:  intCoder.Uint64(pushes[8]),
+		Rate:         intCoder.Uint64(pushes[9]),
+		TrailDelta:   intCoder.Uint64(pushes[10]),
+		LimitOffset:  intCoder.Uint64(pushes[11]),
+		TifNow:       pushes[12][0] == 1,
+		Options:      opts,
+		Status:       StopOrderStatus(pushes[14][0]),
+		Stamp:        intCoder.Uint64(pushes[15]),
+		RefRate:      intCoder.Uint64(pushes[16]),
+		TriggerStamp: intCoder.Uint64(pushes[17]),
+		Err:          string(pushes[19]),
+	}
+	copy(so.OrderID[:], pushes[18])
+	return so, nil
+}
+
+// ScheduledOrderType is the execution schedule of a client-side parent order
+// that is placed as a series of child limit orders.
+type ScheduledOrderType uint8
+
+const (
+	// TWAP orders split the parent quantity into evenly spaced child orders
+	// over a duration.
+	TWAP ScheduledOrderType = iota + 1
+	// Iceberg orders keep a single child order of at most the visible
+	// quantity booked until the parent quantity is filled.
+	Iceberg
+)
+
+// String returns a string representation of the ScheduledOrderType.
+func (t ScheduledOrderType) String() string {
+	switch t {
+	case TWAP:
+		return "twap"
+	case Iceberg:
+		return "iceberg"
+	}
+	return "unknown"
+}
+
+// ScheduledOrderStatus is the status of a scheduled parent order.
+type ScheduledOrderStatus uint8
+
+const (
+	ScheduledOrderStatusUnknown ScheduledOrderStatus = iota
+	// ScheduledOrderActive is a scheduled order that is still placing child
+	// orders or has child orders that are not yet complete.
+	ScheduledOrderActive
+	// ScheduledOrderCompleted is a scheduled order for which all child
+	// orders have been placed and are complete.
+	ScheduledOrderCompleted
+	// ScheduledOrderCanceled is a scheduled order canceled by the user.
+	ScheduledOrderCanceled
+	// ScheduledOrderFailed is a scheduled order for which a child order
+	// could not be placed.
+	ScheduledOrderFailed
+)
+
+// String returns a string representation of the ScheduledOrderStatus.
+func (s ScheduledOrderStatus) String() string {
+	switch s {
+	case ScheduledOrderActive:
+		return "active"
+	case ScheduledOrderCompleted:
+		return "completed"
+	case ScheduledOrderCanceled:
+		return "canceled"
+	case ScheduledOrderFailed:
+		return "failed"
+	}
+	return "unknown"
+}
+
+// ScheduledOrder is a client-side parent order that is executed as a series
+// of standard limit orders. The server only knows about the child orders.
+type ScheduledOrder struct {
+	ID    dex.Bytes
+	Type  ScheduledOrderType
+	Host  string
+	Base  uint32
+	Quote uint32
+	Sell  bool
+	Qty   uint64
+	// Rate is the limit rate of every child order.
+	Rate uint64
+	// Duration is the time over which the child orders of a TWAP order are
+	// placed, in milliseconds.
+	Duration uint64
+	// Slices is the number of child orders of a TWAP order.
+	Slices uint32
+	// VisibleQty is the maximum quantity of an Iceberg child order.
+	VisibleQty uint64
+	TifNow     bool
+	Options    map[string]string
+	Status     ScheduledOrderStatus
+	// Stamp is the time of creation, in milliseconds.
+	Stamp uint64
+	// ChildIDs are the IDs of the child orders placed, oldest first.
+	ChildIDs []order.OrderID
+	// Placed is the total quantity of the child orders placed.
+	Placed uint64
+	// Filled is the total quantity filled by the child orders.
+	Filled uint64
+	// SwapFees, RedemptionFees and FundingFees are the totals of the fees paid
+	// by the child orders.
+	SwapFees       uint64
+	RedemptionFees uint64
+	FundingFees    uint64
+	// EndStamp is the time the order was completed, canceled or failed, in
+	// milliseconds.
+	EndStamp uint64
+	// Err is the error encountered placing a child order, if any.
+	Err string
+}
+
+// Encode encodes the ScheduledOrder to a versioned blob.
+func (so *ScheduledOrder) Encode() []byte {
+	childIDs := make([]byte, 0, len(so.ChildIDs)*order.OrderIDSize)
+	for _, oid := range so.ChildIDs {
+		childIDs = append(childIDs, oid[:]...)
+	}
+	return versionedBytes(0).
+		AddData(so.ID).
+		AddData([]byte{byte(so.Type)}).
+		AddData([]byte(so.Host)).
+		AddData(uint32Bytes(so.Base)).
+		AddData(uint32Bytes(so.Quote)).
+		AddData(boolByte(so.Sell)).
+		AddData(uint64Bytes(so.Qty)).
+		AddData(uint64Bytes(so.Rate)).
+		AddData(uint64Bytes(so.Duration)).
+		AddData(uint32Bytes(so.Slices)).
+		AddData(uint64Bytes(so.VisibleQty)).
+		AddData(boolByte(so.TifNow)).
+		AddData(config.Data(so.Options)).
+		AddData([]byte{byte(so.Status)}).
+		AddData(uint64Bytes(so.Stamp)).
+		AddData(childIDs).
+		AddData(uint64Bytes(so.Placed)).
+		AddData(uint64Bytes(so.Filled)).
+		AddData(uint64Bytes(so.SwapFees)).
+		AddData(uint64Bytes(so.RedemptionFees)).
+		AddData(uint64Bytes(so.FundingFees)).
+		AddData(uint64Bytes(so.EndStamp)).
+		AddData([]byte(so.Err))
+}
+
+// DecodeScheduledOrder decodes the versioned blob to a *ScheduledOrder.
+func DecodeScheduledOrder(b []byte) (*ScheduledOrder, error) {
+	ver, pushes, err := encode.DecodeBlob(b, 23)
+	if err != nil {
+		return nil, err
+	}
+	switch ver {
+	case 0:
+		return decodeScheduledOrder_v0(pushes)
+	}
+	return nil, fmt.Errorf("unknown ScheduledOrder version %d", ver)
+}
+
+func decodeScheduledOrder_v0(pushes [][]byte) (*ScheduledOrder, error) {
+	if len(pushes) != 23 {
+		return nil, fmt.Errorf("decodeScheduledOrder_v0: expected 23 pushes, got %d", len(pushes))
+	}
+	for _, i := range []int{1, 5, 11, 13} {
+		if len(pushes[i]) != 1 {
+			return nil, fmt.Errorf("decodeScheduledOrder_v0: push %d is supposed to be length 1. got %d", i, len(pushes[i]))
+		}
+	}
+	childIDsB := pushes[15]
+	if len(childIDsB)%order.OrderIDSize != 0 {
+		return nil, fmt.Errorf("decodeScheduledOrder_v0: invalid child order IDs length %d", len(childIDsB))
+	}
+	childIDs := make([]order.OrderID, len(childIDsB)/order.OrderIDSize)
+	for i := range childIDs {
+		copy(childIDs[i][:], childIDsB[i*order.OrderIDSize:])
+	}
+	opts, err := config.Parse(pushes[12])
+	if err != nil {
+		return nil, fmt.Errorf("decodeScheduledOrder_v0: unable to decode options: %w", err)
+	}
+	return &ScheduledOrder{
+		ID:             pushes[0],
+		Type:           ScheduledOrderType(pushes[1][0]),
+		Host:           string(pushes[2]),
+		Base:           intCoder.Uint32(pushes[3]),
+		Quote:          intCoder.Uint32(pushes[4]),
+		Sell:           pushes[5][0] == 1,
+		Qty:            intCoder.Uint64(pushes[6]),
+		Rate:           intCoder.Uint64(pushes[7]),
+		Duration:       intCoder.Uint64(pushes[8]),
+		Slices:         intCoder.Uint32(pushes[9]),
+		VisibleQty:     intCoder.Uint64(pushes[10]),
+		TifNow:         pushes[11][0] == 1,
+		Options:        opts,
+		Status:         ScheduledOrderStatus(pushes[13][0]),
+		Stamp:          intCoder.Uint64(pushes[14]),
+		ChildIDs:       childIDs,
+		Placed:         intCoder.Uint64(pushes[16]),
+		Filled:         intCoder.Uint64(pushes[17]),
+		SwapFees:       intCoder.Uint64(pushes[18]),
+		RedemptionFees: intCoder.Uint64(pushes[19]),
+		FundingFees:    intCoder.Uint64(pushes[20]),
+		EndStamp:       intCoder.Uint64(pushes[21]),
+		Err:            string(pushes[22]),
+	}, nil
+}
+
+// FiatRateSnapshot is the USD exchange rates of assets at a point in time.
+// Rates are USD per conventional unit of the asset.
+type FiatRateSnapshot struct {
+	// Stamp is the time of the snapshot in unix milliseconds.
+	Stamp uint64
+	Rates map[uint32]float64
+}
+
+// Encode serializes the FiatRateSnapshot. Each rate is encoded as a push of
+// the 4-byte asset ID followed by the 8-byte float bits.
+func (s *FiatRateSnapshot) Encode() []byte {
+	b := versionedBytes(0).AddData(uint64Bytes(s.Stamp))
+	for assetID, rate := range s.Rates {
+		b = b.AddData(append(uint32Bytes(assetID), uint64Bytes(math.Float64bits(rate))...))
+	}
+	return b
+}
+
+// DecodeFiatRateSnapshot decodes the versioned blob to a *FiatRateSnapshot.
+func DecodeFiatRateSnapshot(b []byte) (*FiatRateSnapshot, error) {
+	ver, pushes, err := encode.DecodeBlob(b)
+	if err != nil {
+		return nil, err
+	}
+	switch ver {
+	case 0:
+		return decodeFiatRateSnapshot_v0(pushes)
+	}
+	return nil, fmt.Errorf("unknown FiatRateSnapshot version %d", ver)
+}
+
+func decodeFiatRateSnapshot_v0(pushes [][]byte) (*FiatRateSnapshot, error) {
+	if len(pushes) == 0 || len(pushes[0]) != 8 {
+		return nil, fmt.Errorf("decodeFiatRateSnapshot_v0: invalid time stamp")
+	}
+	snap := &FiatRateSnapshot{
+		Stamp: intCoder.Uint64(pushes[0]),
+		Rates: make(map[uint32]float64, len(pushes)-1),
+	}
+	for _, push := range pushes[1:] {
+		if len(push) != 12 {
+			return nil, fmt.Errorf("decodeFiatRateSnapshot_v0: invalid rate length %d", len(push))
+		}
+		snap.Rates[intCoder.Uint32(push[:4])] = math.Float64frombits(intCoder.Uint64(push[4:]))
+	}
+	return snap, nil
+}
+
+// AddressBookEntry is a saved address for an asset. The address and label are
+// encrypted with the inner crypter, and are re-encrypted by Recrypt.
+type AddressBookEntry struct {
+	ID         []byte
+	AssetID    uint32
+	EncAddress []byte
+	EncLabel   []byte
+	// Stamp is the time the entry was added, in unix milliseconds.
+	Stamp uint64
+	// ActiveAt is the time after which the address may be used while the
+	// withdrawal allowlist is enabled, in unix milliseconds.
+	ActiveAt uint64
+}
+
+// Encode serializes the AddressBookEntry.
+func (e *AddressBookEntry) Encode() []byte {
+	return versionedBytes(0).
+		AddData(e.ID).
+		AddData(uint32Bytes(e.AssetID)).
+		AddData(e.EncAddress).
+		AddData(e.EncLabel).
+		AddData(uint64Bytes(e.Stamp)).
+		AddData(uint64Bytes(e.ActiveAt))
+}
+
+// DecodeAddressBookEntry decodes the versioned blob to an *AddressBookEntry.
+func DecodeAddressBookEntry(b []byte) (*AddressBookEntry, error) {
+	ver, pushes, err := encode.DecodeBlob(b, 6)
+	if err != nil {
+		return nil, err
+	}
+	switch ver {
+	case 0:
+		return decodeAddressBookEntry_v0(pushes)
+	}
+	return nil, fmt.Errorf("unknown AddressBookEntry version %d", ver)
+}
+
+func decodeAddressBookEntry_v0(pushes [][]byte) (*AddressBookEntry, error) {
+	if len(pushes) != 6 {
+		return nil, fmt.Errorf("decodeAddressBookEntry_v0: expected 6 pushes, got %d", len(pushes))
+	}
+	if len(pushes[1]) != 4 || len(pushes[4]) != 8 || len(pushes[5]) != 8 {
+		return nil, fmt.Errorf("decodeAddressBookEntry_v0: invalid integer length")
+	}
+	return &AddressBookEntry{
+		ID:         pushes[0],
+		AssetID:    intCoder.Uint32(pushes[1]),
+		EncAddress: pushes[2],
+		EncLabel:   pushes[3],
+		Stamp:      intCoder.Uint64(pushes[4]),
+		ActiveAt:   intCoder.Uint64(pushes[5]),
+	}, nil
+}
+
+// AllowlistSetting is the state of the withdrawal allowlist.
+type AllowlistSetting struct {
+	Enabled bool
+	// DisableAt is the time at which a requested disable takes effect, in
+	// unix milliseconds. Zero if no disable was requested.
+	DisableAt uint64
+}
+
+// Encode serializes the AllowlistSetting.
+func (s *AllowlistSetting) Encode() []byte {
+	return versionedBytes(0).
+		AddData(boolByte(s.Enabled)).
+		AddData(uint64Bytes(s.DisableAt))
+}
+
+// DecodeAllowlistSetting decodes the versioned blob to an *AllowlistSetting.
+func DecodeAllowlistSetting(b []byte) (*AllowlistSetting, error) {
+	ver, pushes, err := encode.DecodeBlob(b, 2)
+	if err != nil {
+		return nil, err
+	}
+	switch ver {
+	case 0:
+		if len(pushes) != 2 || len(pushes[0]) != 1 || len(pushes[1]) != 8 {
+			return nil, fmt.Errorf("DecodeAllowlistSetting: invalid v0 encoding")
+		}
+		return &AllowlistSetting{
+			Enabled:   pushes[0][0] == 1,
+			DisableAt: intCoder.Uint64(pushes[1]),
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown AllowlistSetting version %d", ver)
+}
+
+// APIKey is a credential for the client RPC server. Only a hash of the secret
+// is stored.
+type APIKey struct {
+	Name string
+	// Hash is the SHA-256 hash of the key's secret.
+	Hash []byte
+	// Scopes are the permission scopes granted to the key.
+	Scopes []string
+	// RateLimit is the number of requests per minute allowed for the key.
+	// Zero means no limit.
+	RateLimit uint32
+	// Stamp is the time the key was created, in unix milliseconds.
+	Stamp uint64
+}
+
+// Encode serializes the APIKey.
+func (k *APIKey) Encode() []byte {
+	return versionedBytes(0).
+		AddData([]byte(k.Name)).
+		AddData(k.Hash).
+		AddData([]byte(strings.Join(k.Scopes, ","))).
+		AddData(uint32Bytes(k.RateLimit)).
+		AddData(uint64Bytes(k.Stamp))
+}
+
+// DecodeAPIKey decodes the versioned blob to an *APIKey.
+func DecodeAPIKey(b []byte) (*APIKey, error) {
+	ver, pushes, err := encode.DecodeBlob(b, 5)
+	if err != nil {
+		return nil, err
+	}
+	switch ver {
+	case 0:
+		return decodeAPIKey_v0(pushes)
+	}
+	return nil, fmt.Errorf("unknown APIKey version %d", ver)
+}
+
+func decodeAPIKey_v0(pushes [][]byte) (*APIKey, error) {
+	if len(pushes) != 5 {
+		return nil, fmt.Errorf("decodeAPIKey_v0: expected 5 pushes, got %d", len(pushes))
+	}
+	if len(pushes[3]) != 4 || len(pushes[4]) != 8 {
+		return nil, fmt.Errorf("decodeAPIKey_v0: invalid integer length")
+	}
+	var scopes []string
+	if len(pushes[2]) > 0 {
+		scopes = strings.Split(string(pushes[2]), ",")
+	}
+	return &APIKey{
+		Name:      string(pushes[0]),
+		Hash:      pushes[1],
+		Scopes:    scopes,
+		RateLimit: intCoder.Uint32(pushes[3]),
+		Stamp:     intCoder.Uint64(pushes[4]),
+	}, nil
+}
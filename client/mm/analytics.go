@@ -0,0 +1,464 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package mm
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"decred.org/dcrdex/client/asset"
+	"decred.org/dcrdex/dex/calc"
+)
+
+// maxAnalyticsWindows is the maximum number of time windows that a run's
+// analytics can be broken down into.
+const maxAnalyticsWindows = 10_000
+
+// FeeBreakdown is the fees paid in a single asset, by category.
+type FeeBreakdown struct {
+	// Swap includes swap, refund, split and acceleration transaction fees.
+	Swap   uint64 `json:"swap"`
+	Redeem uint64 `json:"redeem"`
+	// CEX is the trading fees paid on the CEX. CEX trading fees are not
+	// reported directly, so they are estimated from the difference between
+	// the filled quantities and the order's limit rate. Price improvement
+	// hides fees, so this is a lower bound.
+	CEX uint64 `json:"cex"`
+	// Deposit includes the deposit transaction fees and any difference
+	// between the amount sent and the amount credited by the CEX.
+	Deposit uint64 `json:"deposit"`
+	// Withdraw is the difference between the amount debited by the CEX and
+	// the amount received in the withdrawal transaction.
+	Withdraw uint64 `json:"withdraw"`
+	// Bridge includes the fees on both the source and destination chains.
+	Bridge uint64 `json:"bridge"`
+}
+
+func (f *FeeBreakdown) total() uint64 {
+	return f.Swap + f.Redeem + f.CEX + f.Deposit + f.Withdraw + f.Bridge
+}
+
+// FeesUSD is the USD value of the fees paid across all assets, by category.
+type FeesUSD struct {
+	Swap     float64 `json:"swap"`
+	Redeem   float64 `json:"redeem"`
+	CEX      float64 `json:"cex"`
+	Deposit  float64 `json:"deposit"`
+	Withdraw float64 `json:"withdraw"`
+	Bridge   float64 `json:"bridge"`
+	Total    float64 `json:"total"`
+}
+
+// FillStats summarizes the fills of the orders placed on an exchange.
+type FillStats struct {
+	Orders int `json:"orders"`
+	// Qty and Filled are in units of the base asset.
+	Qty       uint64  `json:"qty"`
+	Filled    uint64  `json:"filled"`
+	FillRatio float64 `json:"fillRatio"`
+}
+
+func (f *FillStats) add(qty, filled uint64) {
+	f.Orders++
+	f.Qty += qty
+	f.Filled += filled
+	if f.Qty > 0 {
+		f.FillRatio = float64(f.Filled) / float64(f.Qty)
+	}
+}
+
+// SpreadCapture is the spread captured between the bot's buy and sell fills,
+// on both the DEX and the CEX. Rates are message-rate encoded and quantities
+// are in units of the base asset.
+type SpreadCapture struct {
+	BuyQty     uint64 `json:"buyQty"`
+	BuyVWAP    uint64 `json:"buyVWAP"`
+	SellQty    uint64 `json:"sellQty"`
+	SellVWAP   uint64 `json:"sellVWAP"`
+	MatchedQty uint64 `json:"matchedQty"`
+	// Spread is the difference between the sell and buy VWAPs as a ratio
+	// of their mid-point.
+	Spread float64 `json:"spread"`
+	// Profit is the quote asset earned on the matched quantity.
+	Profit    int64   `json:"profit"`
+	ProfitUSD float64 `json:"profitUSD"`
+
+	buyQuote  uint64
+	sellQuote uint64
+}
+
+func (s *SpreadCapture) addFill(sell bool, base, quote uint64) {
+	if base == 0 {
+		return
+	}
+	if sell {
+		s.SellQty += base
+		s.sellQuote += quote
+		s.SellVWAP = calc.BaseQuoteToRate(s.SellQty, s.sellQuote)
+	} else {
+		s.BuyQty += base
+		s.buyQuote += quote
+		s.BuyVWAP = calc.BaseQuoteToRate(s.BuyQty, s.buyQuote)
+	}
+}
+
+// AnalyticsPeriod is the analytics for all or part of a market making run.
+type AnalyticsPeriod struct {
+	// Start and End are unix timestamps in seconds.
+	Start         int64                    `json:"start"`
+	End           int64                    `json:"end"`
+	DEXFills      *FillStats               `json:"dexFills"`
+	CEXFills      *FillStats               `json:"cexFills"`
+	SpreadCapture *SpreadCapture           `json:"spreadCapture"`
+	Fees          map[uint32]*FeeBreakdown `json:"fees"`
+	FeesUSD       *FeesUSD                 `json:"feesUSD"`
+	// RealizedPnLUSD is the spread captured on the matched quantity, less
+	// fees.
+	RealizedPnLUSD float64 `json:"realizedPnLUSD"`
+	// UnrealizedPnLUSD is the value of the unmatched quantity at current
+	// fiat rates, relative to the VWAP at which it was acquired.
+	UnrealizedPnLUSD float64 `json:"unrealizedPnLUSD"`
+}
+
+func newAnalyticsPeriod(start, end int64) *AnalyticsPeriod {
+	return &AnalyticsPeriod{
+		Start:         start,
+		End:           end,
+		DEXFills:      &FillStats{},
+		CEXFills:      &FillStats{},
+		SpreadCapture: &SpreadCapture{},
+		Fees:          make(map[uint32]*FeeBreakdown),
+		FeesUSD:       &FeesUSD{},
+	}
+}
+
+func (p *AnalyticsPeriod) fees(assetID uint32) *FeeBreakdown {
+	f, found := p.Fees[assetID]
+	if !found {
+		f = &FeeBreakdown{}
+		p.Fees[assetID] = f
+	}
+	return f
+}
+
+func (p *AnalyticsPeriod) addDEXOrderEvent(e *DEXOrderEvent, baseID, quoteID uint32) {
+	fromAsset, toAsset := quoteID, baseID
+	if e.Sell {
+		fromAsset, toAsset = baseID, quoteID
+	}
+	fromFees, toFees := p.fees(feeAssetID(fromAsset)), p.fees(feeAssetID(toAsset))
+
+	var swapped, refunded uint64
+	for _, tx := range e.Transactions {
+		switch tx.Type {
+		case asset.Swap:
+			swapped += tx.Amount
+			fromFees.Swap += tx.Fees
+		case asset.Refund:
+			refunded += tx.Amount
+			fromFees.Swap += tx.Fees
+		case asset.Split, asset.Acceleration:
+			fromFees.Swap += tx.Fees
+		case asset.Redeem:
+			toFees.Redeem += tx.Fees
+		}
+	}
+
+	var filledBase, filledQuote uint64
+	if swapped > refunded {
+		if e.Sell {
+			filledBase = swapped - refunded
+			filledQuote = calc.BaseToQuote(e.Rate, filledBase)
+		} else {
+			filledQuote = swapped - refunded
+			filledBase = calc.QuoteToBase(e.Rate, filledQuote)
+		}
+	}
+	p.DEXFills.add(e.Qty, filledBase)
+	p.SpreadCapture.addFill(e.Sell, filledBase, filledQuote)
+}
+
+func (p *AnalyticsPeriod) addCEXOrderEvent(e *CEXOrderEvent) {
+	qty := e.Qty
+	if e.Market && !e.Sell {
+		// Market buy quantities are in units of the quote asset.
+		qty = e.BaseFilled
+		if e.QuoteFilled > 0 && e.QuoteFilled < e.Qty {
+			qty = uint64(float64(e.BaseFilled) * float64(e.Qty) / float64(e.QuoteFilled))
+		}
+	}
+	p.CEXFills.add(qty, e.BaseFilled)
+	p.SpreadCapture.addFill(e.Sell, e.BaseFilled, e.QuoteFilled)
+
+	if e.Market || e.BaseFilled == 0 {
+		return
+	}
+	limitQuote := calc.BaseToQuote(e.Rate, e.BaseFilled)
+	if e.Sell && limitQuote > e.QuoteFilled {
+		p.fees(e.QuoteID).CEX += limitQuote - e.QuoteFilled
+	} else if !e.Sell && e.QuoteFilled > limitQuote {
+		p.fees(e.QuoteID).CEX += e.QuoteFilled - limitQuote
+	}
+}
+
+func (p *AnalyticsPeriod) addBridgeFees(bridgeTx *asset.WalletTransaction, fromAsset, toAsset uint32) {
+	if bridgeTx == nil {
+		return
+	}
+	p.fees(feeAssetID(fromAsset)).Bridge += bridgeTx.Fees
+	if bridgeTx.BridgeCounterpartTx != nil {
+		p.fees(feeAssetID(toAsset)).Bridge += bridgeTx.BridgeCounterpartTx.Fees
+	}
+}
+
+func (p *AnalyticsPeriod) addDepositEvent(e *DepositEvent, pending bool) {
+	p.addBridgeFees(e.BridgeTx, e.DexAssetID, e.CEXAssetID)
+	if e.DepositTx == nil {
+		return
+	}
+	p.fees(feeAssetID(e.CEXAssetID)).Deposit += e.DepositTx.Fees
+	if !pending && e.DepositTx.Amount > e.CEXCredit {
+		p.fees(e.CEXAssetID).Deposit += e.DepositTx.Amount - e.CEXCredit
+	}
+}
+
+func (p *AnalyticsPeriod) addWithdrawalEvent(e *WithdrawalEvent) {
+	p.addBridgeFees(e.BridgeTx, e.CEXAssetID, e.DEXAssetID)
+	if e.WithdrawalTx != nil && e.CEXDebit > e.WithdrawalTx.Amount {
+		p.fees(e.CEXAssetID).Withdraw += e.CEXDebit - e.WithdrawalTx.Amount
+	}
+}
+
+func (p *AnalyticsPeriod) addEvent(e *MarketMakingEvent, baseID, quoteID uint32) {
+	switch {
+	case e.DEXOrderEvent != nil:
+		p.addDEXOrderEvent(e.DEXOrderEvent, baseID, quoteID)
+	case e.CEXOrderEvent != nil:
+		p.addCEXOrderEvent(e.CEXOrderEvent)
+	case e.DepositEvent != nil:
+		p.addDepositEvent(e.DepositEvent, e.Pending)
+	case e.WithdrawalEvent != nil:
+		p.addWithdrawalEvent(e.WithdrawalEvent)
+	}
+}
+
+// finalize calculates the fiat values once all events have been added.
+func (p *AnalyticsPeriod) finalize(baseID, quoteID uint32, fiatRates map[uint32]float64) {
+	usd := func(assetID uint32, atoms uint64) float64 {
+		return NewAmount(assetID, int64(atoms), fiatRates[assetID]).USD
+	}
+
+	for assetID, f := range p.Fees {
+		p.FeesUSD.Swap += usd(assetID, f.Swap)
+		p.FeesUSD.Redeem += usd(assetID, f.Redeem)
+		p.FeesUSD.CEX += usd(assetID, f.CEX)
+		p.FeesUSD.Deposit += usd(assetID, f.Deposit)
+		p.FeesUSD.Withdraw += usd(assetID, f.Withdraw)
+		p.FeesUSD.Bridge += usd(assetID, f.Bridge)
+		p.FeesUSD.Total += usd(assetID, f.total())
+	}
+
+	s := p.SpreadCapture
+	s.MatchedQty = min(s.BuyQty, s.SellQty)
+	if s.MatchedQty > 0 {
+		s.Profit = int64(calc.BaseToQuote(s.SellVWAP, s.MatchedQty)) - int64(calc.BaseToQuote(s.BuyVWAP, s.MatchedQty))
+		s.ProfitUSD = NewAmount(quoteID, s.Profit, fiatRates[quoteID]).USD
+		s.Spread = 2 * (float64(s.SellVWAP) - float64(s.BuyVWAP)) / (float64(s.SellVWAP) + float64(s.BuyVWAP))
+	}
+	p.RealizedPnLUSD = s.ProfitUSD - p.FeesUSD.Total
+
+	switch {
+	case s.BuyQty > s.SellQty:
+		net := s.BuyQty - s.SellQty
+		p.UnrealizedPnLUSD = usd(baseID, net) - usd(quoteID, calc.BaseToQuote(s.BuyVWAP, net))
+	case s.SellQty > s.BuyQty:
+		net := s.SellQty - s.BuyQty
+		p.UnrealizedPnLUSD = usd(quoteID, calc.BaseToQuote(s.SellVWAP, net)) - usd(baseID, net)
+	}
+}
+
+// RunAnalytics is the fee, fill, spread capture and PnL analytics for a
+// market making run, optionally broken down into time windows.
+type RunAnalytics struct {
+	StartTime int64           `json:"startTime"`
+	EndTime   *int64          `json:"endTime,omitempty"`
+	Market    *MarketWithHost `json:"market"`
+	// FiatRates are the rates used to value the analytics.
+	FiatRates map[uint32]float64 `json:"fiatRates"`
+	// ProfitLoss is the overall profit and loss of the run, based on the
+	// change in the bot's balances.
+	ProfitLoss *ProfitLoss        `json:"profitLoss"`
+	Summary    *AnalyticsPeriod   `json:"summary"`
+	Window     int64              `json:"window,omitempty"` // seconds
+	Windows    []*AnalyticsPeriod `json:"windows,omitempty"`
+}
+
+// newRunAnalytics computes the analytics for a run from its events. If window
+// is non-zero, the analytics are also broken down into consecutive windows of
+// that duration starting at the run's start time. Events are attributed to
+// the window in which they were created.
+func newRunAnalytics(startTime int64, mkt *MarketWithHost, overview *MarketMakingRunOverview, events []*MarketMakingEvent,
+	window time.Duration, fiatRates map[uint32]float64, now time.Time) (*RunAnalytics, error) {
+
+	endTime := now.Unix()
+	if overview.EndTime != nil {
+		endTime = *overview.EndTime
+	}
+
+	windowSecs := int64(window / time.Second)
+	if window > 0 && windowSecs == 0 {
+		return nil, fmt.Errorf("window %s is less than one second", window)
+	}
+	var windows []*AnalyticsPeriod
+	if windowSecs > 0 {
+		n := (endTime-startTime)/windowSecs + 1
+		if n > maxAnalyticsWindows {
+			return nil, fmt.Errorf("window %s is too small for a run of %s", window, time.Duration(endTime-startTime)*time.Second)
+		}
+		windows = make([]*AnalyticsPeriod, 0, n)
+		for start := startTime; start <= endTime; start += windowSecs {
+			windows = append(windows, newAnalyticsPeriod(start, min(start+windowSecs, endTime)))
+		}
+	}
+
+	summary := newAnalyticsPeriod(startTime, endTime)
+	for _, e := range events {
+		summary.addEvent(e, mkt.BaseID, mkt.QuoteID)
+		if len(windows) == 0 {
+			continue
+		}
+		i := (e.TimeStamp - startTime) / windowSecs
+		i = max(0, min(i, int64(len(windows)-1)))
+		windows[i].addEvent(e, mkt.BaseID, mkt.QuoteID)
+	}
+
+	summary.finalize(mkt.BaseID, mkt.QuoteID, fiatRates)
+	for _, w := range windows {
+		w.finalize(mkt.BaseID, mkt.QuoteID, fiatRates)
+	}
+
+	ra := &RunAnalytics{
+		StartTime: startTime,
+		EndTime:   overview.EndTime,
+		Market:    mkt,
+		FiatRates: fiatRates,
+		Summary:   summary,
+		Window:    windowSecs,
+		Windows:   windows,
+	}
+
+	if overview.FinalState != nil {
+		finalBalances := make(map[uint32]uint64, len(overview.FinalState.Balances))
+		for assetID, bal := range overview.FinalState.Balances {
+			finalBalances[assetID] = bal.Available + bal.Locked + bal.Pending + bal.Reserved
+		}
+		ra.ProfitLoss = newProfitLoss(overview.InitialBalances, finalBalances, overview.FinalState.InventoryMods, fiatRates)
+	}
+
+	return ra, nil
+}
+
+// WriteCSV writes the analytics as CSV, with one row for the run summary
+// followed by one row for each time window.
+func (ra *RunAnalytics) WriteCSV(w io.Writer) error {
+	baseID, quoteID := ra.Market.BaseID, ra.Market.QuoteID
+	baseUI, err := asset.UnitInfo(baseID)
+	if err != nil {
+		return fmt.Errorf("no unit info for base asset %d", baseID)
+	}
+	quoteUI, err := asset.UnitInfo(quoteID)
+	if err != nil {
+		return fmt.Errorf("no unit info for quote asset %d", quoteID)
+	}
+
+	fmtFloat := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	fmtUSD := func(v float64) string {
+		return strconv.FormatFloat(v, 'f', 2, 64)
+	}
+	fmtQty := func(qty uint64) string {
+		return baseUI.ConventionalString(qty)
+	}
+	fmtRate := func(rate uint64) string {
+		return fmtFloat(calc.ConventionalRate(rate, baseUI, quoteUI))
+	}
+	fmtTime := func(stamp int64) string {
+		return time.Unix(stamp, 0).UTC().Format(time.RFC3339)
+	}
+
+	csvWriter := csv.NewWriter(w)
+	err = csvWriter.Write([]string{
+		"Period",
+		"Start",
+		"End",
+		"DEX Orders",
+		"DEX Fill Ratio",
+		"CEX Orders",
+		"CEX Fill Ratio",
+		"Buy Qty",
+		"Buy VWAP",
+		"Sell Qty",
+		"Sell VWAP",
+		"Matched Qty",
+		"Spread",
+		"Spread Capture (USD)",
+		"Swap Fees (USD)",
+		"Redeem Fees (USD)",
+		"CEX Fees (USD)",
+		"Deposit Fees (USD)",
+		"Withdraw Fees (USD)",
+		"Bridge Fees (USD)",
+		"Total Fees (USD)",
+		"Realized PnL (USD)",
+		"Unrealized PnL (USD)",
+	})
+	if err != nil {
+		return err
+	}
+
+	writePeriod := func(label string, p *AnalyticsPeriod) error {
+		s := p.SpreadCapture
+		return csvWriter.Write([]string{
+			label,
+			fmtTime(p.Start),
+			fmtTime(p.End),
+			strconv.Itoa(p.DEXFills.Orders),
+			fmtFloat(p.DEXFills.FillRatio),
+			strconv.Itoa(p.CEXFills.Orders),
+			fmtFloat(p.CEXFills.FillRatio),
+			fmtQty(s.BuyQty),
+			fmtRate(s.BuyVWAP),
+			fmtQty(s.SellQty),
+			fmtRate(s.SellVWAP),
+			fmtQty(s.MatchedQty),
+			fmtFloat(s.Spread),
+			fmtUSD(s.ProfitUSD),
+			fmtUSD(p.FeesUSD.Swap),
+			fmtUSD(p.FeesUSD.Redeem),
+			fmtUSD(p.FeesUSD.CEX),
+			fmtUSD(p.FeesUSD.Deposit),
+			fmtUSD(p.FeesUSD.Withdraw),
+			fmtUSD(p.FeesUSD.Bridge),
+			fmtUSD(p.FeesUSD.Total),
+			fmtUSD(p.RealizedPnLUSD),
+			fmtUSD(p.UnrealizedPnLUSD),
+		})
+	}
+
+	if err := writePeriod("run", ra.Summary); err != nil {
+		return err
+	}
+	for i, p := range ra.Windows {
+		if err := writePeriod(strconv.Itoa(i), p); err != nil {
+			return err
+		}
+	}
+
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
@@ -0,0 +1,190 @@
+//go:build !harness && !botlive
+
+package mm
+
+import (
+	"bytes"
+	"encoding/csv"
+	"math"
+	"testing"
+	"time"
+
+	"decred.org/dcrdex/client/asset"
+)
+
+func TestRunAnalytics(t *testing.T) {
+	const baseID, quoteID = 42, 0
+	const startTime, endTime = 1000, 1200
+	const buyRate, sellRate = 1e6, 1.02e6
+	mkt := &MarketWithHost{Host: "dex.com", BaseID: baseID, QuoteID: quoteID}
+	fiatRates := map[uint32]float64{baseID: 10, quoteID: 1000}
+
+	events := []*MarketMakingEvent{
+		{
+			ID:        1,
+			TimeStamp: startTime + 10,
+			DEXOrderEvent: &DEXOrderEvent{
+				ID:   "sell",
+				Rate: sellRate,
+				Qty:  2e8,
+				Sell: true,
+				Transactions: []*asset.WalletTransaction{
+					{Type: asset.Swap, Amount: 1e8, Fees: 1000},
+					{Type: asset.Redeem, Amount: 1.02e6, Fees: 500},
+				},
+			},
+		},
+		{
+			ID:        2,
+			TimeStamp: startTime + 20,
+			DepositEvent: &DepositEvent{
+				DepositTx:  &asset.WalletTransaction{Type: asset.Send, Amount: 1e8, Fees: 300},
+				DexAssetID: baseID,
+				CEXAssetID: baseID,
+				CEXCredit:  0.999e8,
+			},
+		},
+		{
+			ID:        3,
+			TimeStamp: startTime + 70,
+			CEXOrderEvent: &CEXOrderEvent{
+				ID:          "cexbuy",
+				BaseID:      baseID,
+				QuoteID:     quoteID,
+				Qty:         1e8,
+				Rate:        buyRate,
+				BaseFilled:  1e8,
+				QuoteFilled: 1.001e6,
+			},
+		},
+		{
+			ID:        4,
+			TimeStamp: startTime + 130,
+			DEXOrderEvent: &DEXOrderEvent{
+				ID:   "buy",
+				Rate: buyRate,
+				Qty:  1e8,
+				Transactions: []*asset.WalletTransaction{
+					{Type: asset.Swap, Amount: 5e5, Fees: 200},
+				},
+			},
+		},
+		{
+			ID:        5,
+			TimeStamp: startTime + 190,
+			WithdrawalEvent: &WithdrawalEvent{
+				ID:           "withdrawal",
+				DEXAssetID:   quoteID,
+				CEXAssetID:   quoteID,
+				WithdrawalTx: &asset.WalletTransaction{Type: asset.Receive, Amount: 9.9e5},
+				CEXDebit:     1e6,
+			},
+		},
+	}
+
+	end := int64(endTime)
+	overview := &MarketMakingRunOverview{
+		EndTime:         &end,
+		InitialBalances: map[uint32]uint64{baseID: 1e9, quoteID: 1e7},
+		FinalState: &BalanceState{
+			FiatRates: fiatRates,
+			Balances: map[uint32]*BotBalance{
+				baseID:  {Available: 1.05e9},
+				quoteID: {Available: 9.5e6},
+			},
+		},
+	}
+
+	ra, err := newRunAnalytics(startTime, mkt, overview, events, time.Minute, fiatRates, time.Unix(endTime+100, 0))
+	if err != nil {
+		t.Fatalf("error computing analytics: %v", err)
+	}
+
+	floatEquals := func(a, b float64) bool {
+		return math.Abs(a-b) < 1e-9
+	}
+
+	s := ra.Summary
+	if s.DEXFills.Orders != 2 || s.DEXFills.Qty != 3e8 || s.DEXFills.Filled != 1.5e8 || !floatEquals(s.DEXFills.FillRatio, 0.5) {
+		t.Fatalf("wrong DEX fill stats %+v", s.DEXFills)
+	}
+	if s.CEXFills.Orders != 1 || !floatEquals(s.CEXFills.FillRatio, 1) {
+		t.Fatalf("wrong CEX fill stats %+v", s.CEXFills)
+	}
+
+	sc := s.SpreadCapture
+	const expBuyVWAP = 1000666 // (1.001e6 + 5e5) quote / 1.5e8 base
+	if sc.BuyQty != 1.5e8 || sc.BuyVWAP != expBuyVWAP || sc.SellQty != 1e8 || sc.SellVWAP != sellRate || sc.MatchedQty != 1e8 {
+		t.Fatalf("wrong spread capture %+v", sc)
+	}
+	if sc.Profit != sellRate-expBuyVWAP {
+		t.Fatalf("wrong spread capture profit %d", sc.Profit)
+	}
+
+	expFees := map[uint32]*FeeBreakdown{
+		baseID:  {Swap: 1000, Deposit: 300 + 1e5},
+		quoteID: {Swap: 200, Redeem: 500, CEX: 1000, Withdraw: 1e4},
+	}
+	for assetID, exp := range expFees {
+		if *s.Fees[assetID] != *exp {
+			t.Fatalf("wrong fees for asset %d. wanted %+v, got %+v", assetID, exp, s.Fees[assetID])
+		}
+	}
+	const expFeesUSD = 101300*10/1e8 + 11700*1000/1e8
+	if !floatEquals(s.FeesUSD.Total, expFeesUSD) {
+		t.Fatalf("wrong total fees. wanted %f, got %f", expFeesUSD, s.FeesUSD.Total)
+	}
+
+	expRealized := float64(sellRate-expBuyVWAP)*1000/1e8 - expFeesUSD
+	if !floatEquals(s.RealizedPnLUSD, expRealized) {
+		t.Fatalf("wrong realized PnL. wanted %f, got %f", expRealized, s.RealizedPnLUSD)
+	}
+	// 0.5 DCR held, acquired at the buy VWAP.
+	expUnrealized := 0.5*10 - float64(500333)*1000/1e8
+	if !floatEquals(s.UnrealizedPnLUSD, expUnrealized) {
+		t.Fatalf("wrong unrealized PnL. wanted %f, got %f", expUnrealized, s.UnrealizedPnLUSD)
+	}
+
+	if ra.ProfitLoss == nil {
+		t.Fatalf("no profit loss")
+	}
+
+	if len(ra.Windows) != 4 {
+		t.Fatalf("expected 4 windows, got %d", len(ra.Windows))
+	}
+	for i, exp := range []struct{ dexOrders, cexOrders int }{{1, 0}, {0, 1}, {1, 0}, {0, 0}} {
+		w := ra.Windows[i]
+		if w.DEXFills.Orders != exp.dexOrders || w.CEXFills.Orders != exp.cexOrders {
+			t.Fatalf("wrong orders in window %d: %d DEX, %d CEX", i, w.DEXFills.Orders, w.CEXFills.Orders)
+		}
+	}
+	if ra.Windows[3].End != endTime || ra.Windows[3].Fees[quoteID].Withdraw != 1e4 {
+		t.Fatalf("withdrawal not in last window")
+	}
+
+	var b bytes.Buffer
+	if err := ra.WriteCSV(&b); err != nil {
+		t.Fatalf("error writing CSV: %v", err)
+	}
+	rows, err := csv.NewReader(&b).ReadAll()
+	if err != nil {
+		t.Fatalf("error reading CSV: %v", err)
+	}
+	if len(rows) != 6 {
+		t.Fatalf("expected 6 CSV rows, got %d", len(rows))
+	}
+	if rows[1][0] != "run" || rows[1][7] != "1.50000000" {
+		t.Fatalf("wrong summary row %v", rows[1])
+	}
+
+	if _, err := newRunAnalytics(startTime, mkt, overview, events, time.Millisecond*10, fiatRates, time.Now()); err == nil {
+		t.Fatalf("no error for sub-second window")
+	}
+	if _, err := newRunAnalytics(startTime, mkt, overview, events, time.Second, fiatRates, time.Unix(startTime+1e5, 0)); err != nil {
+		t.Fatalf("error for ended run: %v", err)
+	}
+	overview.EndTime = nil
+	if _, err := newRunAnalytics(startTime, mkt, overview, events, time.Second, fiatRates, time.Unix(startTime+1e5, 0)); err == nil {
+		t.Fatalf("no error for too many windows")
+	}
+}
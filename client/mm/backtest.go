@@ -0,0 +1,983 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package mm
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"time"
+
+	"decred.org/dcrdex/client/core"
+	"decred.org/dcrdex/client/mm/libxc"
+	"decred.org/dcrdex/dex"
+	"decred.org/dcrdex/dex/calc"
+	"decred.org/dcrdex/dex/utils"
+)
+
+// BacktestBookEntry is a price level of a recorded order book.
+type BacktestBookEntry struct {
+	Rate uint64 `json:"rate"`
+	Qty  uint64 `json:"qty"`
+}
+
+// BacktestBook is a recorded order book snapshot.
+type BacktestBook struct {
+	Buys  []*BacktestBookEntry `json:"buys"`
+	Sells []*BacktestBookEntry `json:"sells"`
+}
+
+// BacktestMatch is a match between other traders that was recorded on the DEX
+// during an epoch. Sell is the side of the taker, and Rate is the worst rate
+// the taker was matched at.
+type BacktestMatch struct {
+	Rate uint64 `json:"rate"`
+	Qty  uint64 `json:"qty"`
+	Sell bool   `json:"sell"`
+}
+
+// BacktestEpoch is the recorded market data for a single DEX epoch. The DEX
+// book is the book at the start of the epoch, and the matches are the matches
+// that took place during the epoch.
+type BacktestEpoch struct {
+	Epoch     uint64           `json:"epoch"`
+	TimeStamp int64            `json:"timestamp"`
+	DEXBook   *BacktestBook    `json:"dexBook"`
+	Matches   []*BacktestMatch `json:"matches"`
+	// CEXBook is the book of the CEX market. It is required for the arb
+	// market maker.
+	CEXBook *BacktestBook `json:"cexBook,omitempty"`
+	// OracleRate is the conventional oracle rate for the market. It is used
+	// by the basic market maker.
+	OracleRate float64            `json:"oracleRate,omitempty"`
+	FiatRates  map[uint32]float64 `json:"fiatRates"`
+}
+
+// BacktestConfig is the configuration of a backtest.
+type BacktestConfig struct {
+	// Bot is the configuration of the bot being tested. Only
+	// BasicMMConfig and ArbMarketMakerConfig are supported.
+	Bot      *BotConfig `json:"bot"`
+	LotSize  uint64     `json:"lotSize"`
+	RateStep uint64     `json:"rateStep"`
+	// Alloc is the initial allocation of the bot's funds.
+	Alloc *BotBalanceAllocation `json:"alloc"`
+	// BuyFees and SellFees are the estimated fees for a single lot of a buy
+	// and a sell order, as returned by SingleLotFees.
+	BuyFees  *LotFees `json:"buyFees"`
+	SellFees *LotFees `json:"sellFees"`
+	// CEXTradeFee is the fee charged by the CEX as a fraction of the amount
+	// received.
+	CEXTradeFee float64 `json:"cexTradeFee"`
+	// AutoRebalance, if set, enables simulated deposits and withdrawals for
+	// the arb market maker.
+	AutoRebalance *AutoRebalanceConfig `json:"autoRebalance,omitempty"`
+	// TransferFees are the fees deducted from deposits and withdrawals.
+	TransferFees map[uint32]uint64 `json:"transferFees"`
+	// TransferEpochs is the number of epochs a deposit or withdrawal takes to
+	// complete.
+	TransferEpochs uint64           `json:"transferEpochs"`
+	Epochs         []*BacktestEpoch `json:"epochs"`
+}
+
+// BacktestEpochState is the state of the bot at the end of an epoch.
+type BacktestEpochState struct {
+	Epoch     uint64        `json:"epoch"`
+	TimeStamp int64         `json:"timestamp"`
+	State     *BalanceState `json:"state"`
+	Profit    float64       `json:"profit"`
+}
+
+// BacktestResult is the result of a backtest. The events and balance states
+// are in the same format as the event log of a live run.
+type BacktestResult struct {
+	Events     []*MarketMakingEvent  `json:"events"`
+	Inventory  []*BacktestEpochState `json:"inventory"`
+	ProfitLoss *ProfitLoss           `json:"profitLoss"`
+	LotsPlaced uint64                `json:"lotsPlaced"`
+	LotsFilled uint64                `json:"lotsFilled"`
+	FillRate   float64               `json:"fillRate"`
+}
+
+// backtestBook is a mutable copy of a recorded book, sorted with the best
+// rates first. Liquidity is removed from the book as it is consumed by the
+// bot's trades.
+type backtestBook struct {
+	buys, sells []*BacktestBookEntry
+}
+
+func newBacktestBook(b *BacktestBook) *backtestBook {
+	if b == nil {
+		return &backtestBook{}
+	}
+	cp := func(entries []*BacktestBookEntry) []*BacktestBookEntry {
+		c := make([]*BacktestBookEntry, 0, len(entries))
+		for _, e := range entries {
+			c = append(c, &BacktestBookEntry{Rate: e.Rate, Qty: e.Qty})
+		}
+		return c
+	}
+	book := &backtestBook{buys: cp(b.Buys), sells: cp(b.Sells)}
+	sort.Slice(book.buys, func(i, j int) bool { return book.buys[i].Rate > book.buys[j].Rate })
+	sort.Slice(book.sells, func(i, j int) bool { return book.sells[i].Rate < book.sells[j].Rate })
+	return book
+}
+
+// side returns the side of the book that a trade would be matched against.
+func (b *backtestBook) side(sell bool) []*BacktestBookEntry {
+	if sell {
+		return b.buys
+	}
+	return b.sells
+}
+
+func (b *backtestBook) midGap() uint64 {
+	if len(b.buys) == 0 || len(b.sells) == 0 {
+		return 0
+	}
+	return (b.buys[0].Rate + b.sells[0].Rate) / 2
+}
+
+// vwap is the volume weighted average rate of trading qty of the base asset.
+// sell is the side of the trade.
+func (b *backtestBook) vwap(sell bool, qty uint64) (vwap, extrema uint64, filled bool) {
+	if qty == 0 {
+		return 0, 0, false
+	}
+	weightedTotal, bigRate, bigQty := new(big.Int), new(big.Int), new(big.Int)
+	remaining := qty
+	for _, e := range b.side(sell) {
+		take := min(e.Qty, remaining)
+		bigRate.SetUint64(e.Rate)
+		bigQty.SetUint64(take)
+		weightedTotal.Add(weightedTotal, bigRate.Mul(bigRate, bigQty))
+		remaining -= take
+		if remaining == 0 {
+			filled, extrema = true, e.Rate
+			break
+		}
+	}
+	if !filled {
+		return 0, 0, false
+	}
+	return weightedTotal.Div(weightedTotal, new(big.Int).SetUint64(qty)).Uint64(), extrema, true
+}
+
+// invVWAP is the volume weighted average rate of trading quoteQty of the
+// quote asset. sell is the side of the trade.
+func (b *backtestBook) invVWAP(sell bool, quoteQty uint64) (vwap, extrema uint64, filled bool) {
+	if quoteQty == 0 {
+		return 0, 0, false
+	}
+	var baseQty uint64
+	remaining := quoteQty
+	for _, e := range b.side(sell) {
+		levelQuote := calc.BaseToQuote(e.Rate, e.Qty)
+		if levelQuote >= remaining {
+			baseQty += calc.QuoteToBase(e.Rate, remaining)
+			filled, extrema = true, e.Rate
+			break
+		}
+		baseQty += e.Qty
+		remaining -= levelQuote
+	}
+	if !filled {
+		return 0, 0, false
+	}
+	return calc.BaseQuoteToRate(baseQty, quoteQty), extrema, true
+}
+
+type backtestOrder struct {
+	event    *MarketMakingEvent
+	sell     bool
+	rate     uint64
+	qty      uint64
+	filled   uint64
+	cexRate  uint64
+	complete bool
+	// locked is the remaining amount of the spent asset locked for the
+	// order, and lockedFees is the remaining swap fees locked.
+	locked     uint64
+	lockedFees uint64
+	settled    map[uint32]int64
+}
+
+type backtestTransfer struct {
+	event      *MarketMakingEvent
+	deposit    bool
+	dexAssetID uint32
+	cexAssetID uint32
+	amt        uint64
+	arrival    uint64
+}
+
+// backtester simulates a bot on recorded market data.
+type backtester struct {
+	cfg    *BacktestConfig
+	botCfg *BotConfig
+	mkt    *market
+	log    dex.Logger
+
+	cexBaseID, cexQuoteID uint32
+	driftTolerance        float64
+	ordersToPlace         func() (buys, sells []*TradePlacement, err error)
+	// vol measures volatility from the recorded mid-gap rates for the
+	// volatility gap strategy.
+	vol *volatilityTracker
+
+	epoch     *BacktestEpoch
+	dexBook   *backtestBook
+	cexBook   *backtestBook
+	dexBals   map[uint32]*BotBalance
+	cexBals   map[uint32]*BotBalance
+	orders    map[bool][]*backtestOrder
+	transfers []*backtestTransfer
+	eventID   uint64
+
+	result *BacktestResult
+}
+
+var _ basicMMCalculatorCore = (*backtester)(nil)
+var _ oracle = (*backtester)(nil)
+
+// RunBacktest runs the bot configured in cfg over the recorded epochs, using
+// the same placement logic as the live bots. Matches against the bot's orders
+// are simulated from the recorded books and matches, and the fees are
+// estimated from the configured lot fees.
+//
+// New orders are matched against the recorded DEX book as takers. Orders that
+// remain on the book are filled, at their own rate, by recorded matches in
+// later epochs whose taker crossed the order's rate. For the arb market maker,
+// counter trades are filled immediately against the recorded CEX book, up to
+// the counter trade rate.
+func RunBacktest(cfg *BacktestConfig, log dex.Logger) (*BacktestResult, error) {
+	b, err := newBacktester(cfg, log)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range cfg.Epochs {
+		b.processEpoch(e)
+	}
+	b.finish()
+	return b.result, nil
+}
+
+func newBacktester(cfg *BacktestConfig, log dex.Logger) (*backtester, error) {
+	if cfg.Bot == nil {
+		return nil, errors.New("no bot config")
+	}
+	if cfg.LotSize == 0 || cfg.RateStep == 0 {
+		return nil, errors.New("lot size and rate step must be set")
+	}
+	if cfg.BuyFees == nil || cfg.SellFees == nil {
+		return nil, errors.New("buy and sell fees must be set")
+	}
+	if cfg.Alloc == nil {
+		return nil, errors.New("no allocation")
+	}
+	if len(cfg.Epochs) == 0 {
+		return nil, errors.New("no epochs")
+	}
+
+	botCfg := cfg.Bot.copy()
+	mktName, err := dex.MarketName(botCfg.BaseID, botCfg.QuoteID)
+	if err != nil {
+		return nil, err
+	}
+	mkt, err := parseMarket(botCfg.Host, &core.Market{
+		Name:     mktName,
+		BaseID:   botCfg.BaseID,
+		QuoteID:  botCfg.QuoteID,
+		LotSize:  cfg.LotSize,
+		RateStep: cfg.RateStep,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error parsing market: %w", err)
+	}
+
+	b := &backtester{
+		cfg:     cfg,
+		botCfg:  botCfg,
+		mkt:     mkt,
+		log:     log,
+		dexBals: make(map[uint32]*BotBalance),
+		cexBals: make(map[uint32]*BotBalance),
+		orders:  make(map[bool][]*backtestOrder),
+		result:  &BacktestResult{},
+	}
+	for assetID, v := range cfg.Alloc.DEX {
+		b.dexBals[assetID] = &BotBalance{Available: v}
+	}
+	for assetID, v := range cfg.Alloc.CEX {
+		b.cexBals[assetID] = &BotBalance{Available: v}
+	}
+
+	switch {
+	case botCfg.BasicMMConfig != nil:
+		mmCfg := botCfg.BasicMMConfig
+		if err := mmCfg.validate(); err != nil {
+			return nil, fmt.Errorf("invalid market making config: %w", err)
+		}
+		b.driftTolerance = mmCfg.DriftTolerance
+		b.vol = newVolatilityTracker()
+		calculator := &basicMMCalculatorImpl{
+			market: mkt,
+			oracle: b,
+			core:   b,
+			cfg:    mmCfg,
+			vol:    b.vol,
+			log:    log,
+		}
+		skewer := newInventorySkewer(botCfg.InventorySkew, b.inventoryTotals)
+		b.ordersToPlace = func() (buys, sells []*TradePlacement, err error) {
+			buys, sells, _, _, err = basicMMOrdersToPlace(mmCfg, mkt, calculator, skewer, log)
+			return
+		}
+	case botCfg.ArbMarketMakerConfig != nil:
+		arbCfg := botCfg.ArbMarketMakerConfig
+		if err := arbCfg.validate(botCfg.BaseID, botCfg.QuoteID); err != nil {
+			return nil, fmt.Errorf("invalid arb market maker config: %w", err)
+		}
+		if arbCfg.isMultiHop() {
+			return nil, errors.New("multi-hop arb market making is not supported in backtests")
+		}
+		b.cexBaseID, b.cexQuoteID = botCfg.CEXBaseID, botCfg.CEXQuoteID
+		b.driftTolerance = arbCfg.DriftTolerance
+		placementRate := func(cexRate uint64, sell bool, profit float64) (uint64, error) {
+			feesInQuoteUnits, err := b.OrderFeesInUnits(sell, false, cexRate)
+			if err != nil {
+				return 0, fmt.Errorf("error getting fees in quote units: %w", err)
+			}
+			return dexPlacementRate(cexRate, sell, profit, mkt, feesInQuoteUnits, log)
+		}
+		validateArbTrades := func([]*arbTradeArgs) error { return nil }
+		skewer := newInventorySkewer(botCfg.InventorySkew, b.inventoryTotals)
+		b.ordersToPlace = func() (buys, sells []*TradePlacement, err error) {
+			buys, sells, _, err = arbMMOrdersToPlace(arbCfg, botCfg, mkt, b.cexVWAP, b.cexInvVWAP, validateArbTrades, placementRate, skewer, log)
+			return
+		}
+	default:
+		return nil, errors.New("only basic market maker and arb market maker configs can be backtested")
+	}
+
+	return b, nil
+}
+
+// getMarketPrice returns the recorded oracle rate. Part of the oracle
+// interface.
+func (b *backtester) getMarketPrice(baseID, quoteID uint32) float64 {
+	return b.epoch.OracleRate
+}
+
+func (b *backtester) atomicRate(fromID, toID uint32) (float64, error) {
+	return atomicConversionRate(fromID, toID, b.epoch.FiatRates[fromID], b.epoch.FiatRates[toID])
+}
+
+// ExchangeRateFromFiatSources returns the market rate calculated from the
+// recorded fiat rates.
+func (b *backtester) ExchangeRateFromFiatSources() uint64 {
+	atomicCFactor, err := b.atomicRate(b.mkt.dexBaseID, b.mkt.dexQuoteID)
+	if err != nil {
+		return 0
+	}
+	return uint64(math.Round(atomicCFactor * calc.RateEncodingFactor))
+}
+
+// OrderFeesInUnits returns the configured fees for an order in units of the
+// base or quote asset.
+func (b *backtester) OrderFeesInUnits(sell, base bool, rate uint64) (uint64, error) {
+	return orderFeesInUnits(sell, base, rate, b.cfg.BuyFees, b.cfg.SellFees, b.mkt.dexBaseID, b.mkt.dexQuoteID, b.atomicRate)
+}
+
+func (b *backtester) checkCEXMarket(baseID, quoteID uint32) error {
+	if baseID != b.cexBaseID || quoteID != b.cexQuoteID {
+		return fmt.Errorf("no recorded book for CEX market %d-%d", baseID, quoteID)
+	}
+	if len(b.cexBook.buys) == 0 && len(b.cexBook.sells) == 0 {
+		return errors.New("no recorded CEX book")
+	}
+	return nil
+}
+
+func (b *backtester) cexVWAP(baseID, quoteID uint32, sell bool, qty uint64) (vwap, extrema uint64, filled bool, err error) {
+	if err := b.checkCEXMarket(baseID, quoteID); err != nil {
+		return 0, 0, false, err
+	}
+	// As with the libxc CEXs, sell refers to the side of the DEX order, so a
+	// sell is priced using the CEX asks.
+	vwap, extrema, filled = b.cexBook.vwap(!sell, qty)
+	return
+}
+
+func (b *backtester) cexInvVWAP(baseID, quoteID uint32, sell bool, qty uint64) (vwap, extrema uint64, filled bool, err error) {
+	if err := b.checkCEXMarket(baseID, quoteID); err != nil {
+		return 0, 0, false, err
+	}
+	vwap, extrema, filled = b.cexBook.invVWAP(!sell, qty)
+	return
+}
+
+func backtestBalance(bals map[uint32]*BotBalance, assetID uint32) *BotBalance {
+	bal, found := bals[assetID]
+	if !found {
+		bal = &BotBalance{}
+		bals[assetID] = bal
+	}
+	return bal
+}
+
+// inventoryTotals returns the simulated holdings of the base and quote assets
+// on the DEX and CEX.
+func (b *backtester) inventoryTotals() (base, quote uint64) {
+	total := func(bals map[uint32]*BotBalance, assetID uint32) uint64 {
+		bal, found := bals[assetID]
+		if !found {
+			return 0
+		}
+		return bal.Available + bal.Locked + bal.Pending + bal.Reserved
+	}
+	base = total(b.dexBals, b.mkt.dexBaseID)
+	quote = total(b.dexBals, b.mkt.dexQuoteID)
+	if b.botCfg.ArbMarketMakerConfig != nil {
+		base += total(b.cexBals, b.cexBaseID)
+		quote += total(b.cexBals, b.cexQuoteID)
+	}
+	return
+}
+
+func (b *backtester) newEvent() *MarketMakingEvent {
+	b.eventID++
+	e := &MarketMakingEvent{
+		ID:        b.eventID,
+		TimeStamp: b.epoch.TimeStamp,
+		Pending:   true,
+	}
+	b.result.Events = append(b.result.Events, e)
+	return e
+}
+
+func (b *backtester) processEpoch(e *BacktestEpoch) {
+	b.epoch = e
+	b.dexBook = newBacktestBook(e.DEXBook)
+	b.cexBook = newBacktestBook(e.CEXBook)
+
+	if b.vol != nil {
+		// Recorded candles are not available, so volatility is measured
+		// from the CEX mid-gap if recorded, or the DEX mid-gap otherwise.
+		midGap := b.cexBook.midGap()
+		if midGap == 0 {
+			midGap = b.dexBook.midGap()
+		}
+		b.vol.addMidGap(time.Unix(e.TimeStamp, 0), midGap)
+	}
+
+	b.completeTransfers()
+	b.fillRestingOrders(e.Matches)
+
+	buys, sells, err := b.ordersToPlace()
+	if err != nil {
+		b.log.Debugf("Epoch %d: error determining placements: %v", e.Epoch, err)
+		b.cancelAll()
+	} else {
+		b.updateOrders(buys, false)
+		b.updateOrders(sells, true)
+		if b.cfg.AutoRebalance != nil && b.botCfg.ArbMarketMakerConfig != nil {
+			b.rebalance(buys, sells)
+		}
+	}
+
+	b.recordState()
+}
+
+// fillRestingOrders fills the bot's booked orders using the recorded matches
+// of other traders. A taker that was matched down to a rate would have
+// matched any of the bot's orders at a better rate first.
+func (b *backtester) fillRestingOrders(matches []*BacktestMatch) {
+	lotSize := b.mkt.lotSize.Load()
+	for _, m := range matches {
+		// A taker sell fills the bot's buy orders.
+		var candidates []*backtestOrder
+		for _, o := range b.orders[!m.Sell] {
+			if o == nil || o.complete {
+				continue
+			}
+			if (m.Sell && o.rate >= m.Rate) || (!m.Sell && o.rate <= m.Rate) {
+				candidates = append(candidates, o)
+			}
+		}
+		sort.Slice(candidates, func(i, j int) bool {
+			if m.Sell {
+				return candidates[i].rate > candidates[j].rate
+			}
+			return candidates[i].rate < candidates[j].rate
+		})
+		remaining := m.Qty
+		for _, o := range candidates {
+			fillQty := min(remaining, o.qty-o.filled)
+			fillQty -= fillQty % lotSize
+			if fillQty == 0 {
+				break
+			}
+			b.fill(o, fillQty, o.rate)
+			remaining -= fillQty
+		}
+	}
+}
+
+// updateOrders places, keeps or cancels the bot's orders on one side of the
+// book to match the placements.
+func (b *backtester) updateOrders(placements []*TradePlacement, sell bool) {
+	orders := b.orders[sell]
+	for i := len(placements); i < len(orders); i++ {
+		b.cancel(orders[i])
+	}
+	if len(orders) > len(placements) {
+		orders = orders[:len(placements)]
+	}
+	for len(orders) < len(placements) {
+		orders = append(orders, nil)
+	}
+
+	for i, p := range placements {
+		o := orders[i]
+		if o != nil && !o.complete {
+			if p.Error == nil && p.Rate > 0 && withinTolerance(o.rate, p.Rate, b.driftTolerance) {
+				continue
+			}
+			b.cancel(o)
+		}
+		orders[i] = nil
+		if p.Error != nil || p.Rate == 0 || p.Lots == 0 {
+			continue
+		}
+		if o = b.placeOrder(sell, p.Rate, p.Lots, p.CounterTradeRate); o != nil {
+			orders[i] = o
+		}
+	}
+	b.orders[sell] = orders
+}
+
+func (b *backtester) cancelAll() {
+	for _, orders := range b.orders {
+		for _, o := range orders {
+			b.cancel(o)
+		}
+	}
+	b.orders = make(map[bool][]*backtestOrder)
+}
+
+// swapFeeAsset returns the asset that swap fees are paid in.
+func (b *backtester) swapFeeAsset(sell bool) (assetID uint32, feePerLot uint64) {
+	if sell {
+		return b.mkt.baseFeeID, b.cfg.SellFees.Swap
+	}
+	return b.mkt.quoteFeeID, b.cfg.BuyFees.Swap
+}
+
+// placeOrder places an order for as many lots as the bot can afford, up to
+// the specified number of lots, and matches it against the recorded book.
+func (b *backtester) placeOrder(sell bool, rate, lots, cexRate uint64) *backtestOrder {
+	lotSize := b.mkt.lotSize.Load()
+	spentID, lotCost := b.mkt.dexQuoteID, calc.BaseToQuote(rate, lotSize)
+	if sell {
+		spentID, lotCost = b.mkt.dexBaseID, lotSize
+	}
+	feeID, feePerLot := b.swapFeeAsset(sell)
+
+	spentBal := backtestBalance(b.dexBals, spentID)
+	if feeID == spentID {
+		lots = min(lots, spentBal.Available/(lotCost+feePerLot))
+	} else {
+		lots = min(lots, spentBal.Available/lotCost)
+		if feePerLot > 0 {
+			lots = min(lots, backtestBalance(b.dexBals, feeID).Available/feePerLot)
+		}
+	}
+	if lots == 0 {
+		return nil
+	}
+
+	o := &backtestOrder{
+		event:      b.newEvent(),
+		sell:       sell,
+		rate:       rate,
+		qty:        lots * lotSize,
+		cexRate:    cexRate,
+		locked:     lots * lotCost,
+		lockedFees: lots * feePerLot,
+		settled:    make(map[uint32]int64),
+	}
+	spentBal.Available -= o.locked
+	spentBal.Locked += o.locked
+	feeBal := backtestBalance(b.dexBals, feeID)
+	feeBal.Available -= o.lockedFees
+	feeBal.Locked += o.lockedFees
+	b.result.LotsPlaced += lots
+
+	// Match against the recorded book as a taker at the makers' rates.
+	for _, e := range b.dexBook.side(sell) {
+		if (sell && e.Rate < rate) || (!sell && e.Rate > rate) {
+			break
+		}
+		fillQty := min(e.Qty, o.qty-o.filled)
+		fillQty -= fillQty % lotSize
+		if fillQty == 0 {
+			continue
+		}
+		e.Qty -= fillQty
+		b.fill(o, fillQty, e.Rate)
+		if o.complete {
+			break
+		}
+	}
+
+	b.updateOrderEvent(o)
+	return o
+}
+
+// fill applies a match of qty at the match rate to an order and the balances.
+func (b *backtester) fill(o *backtestOrder, qty, matchRate uint64) {
+	lotSize := b.mkt.lotSize.Load()
+	lots := qty / lotSize
+	baseID, quoteID := b.mkt.dexBaseID, b.mkt.dexQuoteID
+	quoteQty := calc.BaseToQuote(matchRate, qty)
+	swapFeeID, swapFeePerLot := b.swapFeeAsset(o.sell)
+	swapFees := lots * swapFeePerLot
+
+	if o.sell {
+		baseBal := backtestBalance(b.dexBals, baseID)
+		baseBal.Locked -= qty
+		o.locked -= qty
+		backtestBalance(b.dexBals, quoteID).Available += quoteQty
+		o.settled[baseID] -= int64(qty)
+		o.settled[quoteID] += int64(quoteQty)
+	} else {
+		// The quote locked at the order rate may be more than the quote
+		// spent at the match rate.
+		lockedQuote := min(calc.BaseToQuote(o.rate, qty), o.locked)
+		quoteBal := backtestBalance(b.dexBals, quoteID)
+		quoteBal.Locked -= lockedQuote
+		quoteBal.Available += utils.SafeSub(lockedQuote, quoteQty)
+		o.locked -= lockedQuote
+		backtestBalance(b.dexBals, baseID).Available += qty
+		o.settled[quoteID] -= int64(quoteQty)
+		o.settled[baseID] += int64(qty)
+	}
+
+	swapFees = min(swapFees, o.lockedFees)
+	backtestBalance(b.dexBals, swapFeeID).Locked -= swapFees
+	o.lockedFees -= swapFees
+	o.settled[swapFeeID] -= int64(swapFees)
+
+	redeemFeeID, redeemFees := b.mkt.quoteFeeID, lots*b.cfg.SellFees.Redeem
+	if !o.sell {
+		redeemFeeID, redeemFees = b.mkt.baseFeeID, lots*b.cfg.BuyFees.Redeem
+	}
+	redeemBal := backtestBalance(b.dexBals, redeemFeeID)
+	redeemFees = min(redeemFees, redeemBal.Available)
+	redeemBal.Available -= redeemFees
+	o.settled[redeemFeeID] -= int64(redeemFees)
+
+	o.filled += qty
+	b.result.LotsFilled += lots
+	if o.filled >= o.qty {
+		b.complete(o)
+	}
+
+	if b.botCfg.ArbMarketMakerConfig != nil {
+		b.counterTrade(!o.sell, qty, o.cexRate)
+	}
+
+	b.updateOrderEvent(o)
+}
+
+func (b *backtester) cancel(o *backtestOrder) {
+	if o == nil || o.complete {
+		return
+	}
+	b.complete(o)
+	b.updateOrderEvent(o)
+}
+
+// complete marks the order complete and unlocks any remaining funds.
+func (b *backtester) complete(o *backtestOrder) {
+	if o.complete {
+		return
+	}
+	o.complete = true
+	spentID := b.mkt.dexQuoteID
+	if o.sell {
+		spentID = b.mkt.dexBaseID
+	}
+	spentBal := backtestBalance(b.dexBals, spentID)
+	spentBal.Locked -= o.locked
+	spentBal.Available += o.locked
+	o.locked = 0
+	feeID, _ := b.swapFeeAsset(o.sell)
+	feeBal := backtestBalance(b.dexBals, feeID)
+	feeBal.Locked -= o.lockedFees
+	feeBal.Available += o.lockedFees
+	o.lockedFees = 0
+}
+
+func (b *backtester) updateOrderEvent(o *backtestOrder) {
+	effects := newBalanceEffects()
+	for assetID, v := range o.settled {
+		effects.Settled[assetID] = v
+	}
+	if o.locked > 0 {
+		spentID := b.mkt.dexQuoteID
+		if o.sell {
+			spentID = b.mkt.dexBaseID
+		}
+		effects.Locked[spentID] += o.locked
+	}
+	if o.lockedFees > 0 {
+		feeID, _ := b.swapFeeAsset(o.sell)
+		effects.Locked[feeID] += o.lockedFees
+	}
+	o.event.Pending = !o.complete
+	o.event.BalanceEffects = effects
+	o.event.DEXOrderEvent = &DEXOrderEvent{
+		ID:   fmt.Sprintf("%016x", o.event.ID),
+		Rate: o.rate,
+		Qty:  o.qty,
+		Sell: o.sell,
+	}
+}
+
+// counterTrade fills a trade on the CEX against the recorded book, up to the
+// limit rate. Only the quantity the CEX balance can afford is traded.
+func (b *backtester) counterTrade(sell bool, qty, limitRate uint64) {
+	baseBal := backtestBalance(b.cexBals, b.cexBaseID)
+	quoteBal := backtestBalance(b.cexBals, b.cexQuoteID)
+
+	var baseFilled, quoteFilled uint64
+	remaining := qty
+	for _, e := range b.cexBook.side(sell) {
+		if remaining == 0 {
+			break
+		}
+		if limitRate > 0 && ((sell && e.Rate < limitRate) || (!sell && e.Rate > limitRate)) {
+			break
+		}
+		take := min(e.Qty, remaining)
+		if sell {
+			take = min(take, baseBal.Available-baseFilled)
+		} else if cost := calc.BaseToQuote(e.Rate, take); cost > quoteBal.Available-quoteFilled {
+			take = calc.QuoteToBase(e.Rate, quoteBal.Available-quoteFilled)
+		}
+		if take == 0 {
+			break
+		}
+		e.Qty -= take
+		baseFilled += take
+		quoteFilled += calc.BaseToQuote(e.Rate, take)
+		remaining -= take
+	}
+
+	trade := &libxc.Trade{
+		ID:       fmt.Sprintf("backtest-%d", b.eventID+1),
+		Sell:     sell,
+		Qty:      qty,
+		Rate:     limitRate,
+		BaseID:   b.cexBaseID,
+		QuoteID:  b.cexQuoteID,
+		Complete: true,
+	}
+	if sell {
+		fee := uint64(math.Round(float64(quoteFilled) * b.cfg.CEXTradeFee))
+		baseBal.Available -= baseFilled
+		quoteBal.Available += quoteFilled - fee
+		trade.BaseFilled, trade.QuoteFilled = baseFilled, quoteFilled-fee
+	} else {
+		fee := uint64(math.Round(float64(baseFilled) * b.cfg.CEXTradeFee))
+		quoteBal.Available -= quoteFilled
+		baseBal.Available += baseFilled - fee
+		trade.BaseFilled, trade.QuoteFilled = baseFilled-fee, quoteFilled
+	}
+
+	e := b.newEvent()
+	*e = *cexOrderEvent(trade, e.ID, e.TimeStamp, b.log)
+}
+
+// rebalance transfers funds between the DEX and the CEX when one side does
+// not have enough funds for the placements and the other side has a surplus.
+func (b *backtester) rebalance(buys, sells []*TradePlacement) {
+	lotSize := b.mkt.lotSize.Load()
+	var dexBaseReq, cexBaseReq, dexQuoteReq, cexQuoteReq uint64
+	for _, p := range sells {
+		if p.Error != nil {
+			continue
+		}
+		dexBaseReq += p.Lots * lotSize
+		cexQuoteReq += calc.BaseToQuote(p.CounterTradeRate, p.Lots*lotSize)
+	}
+	for _, p := range buys {
+		if p.Error != nil {
+			continue
+		}
+		dexQuoteReq += calc.BaseToQuote(p.Rate, p.Lots*lotSize)
+		cexBaseReq += p.Lots * lotSize
+	}
+
+	b.rebalanceAsset(b.mkt.dexBaseID, b.cexBaseID, dexBaseReq, cexBaseReq, b.cfg.AutoRebalance.MinBaseTransfer)
+	b.rebalanceAsset(b.mkt.dexQuoteID, b.cexQuoteID, dexQuoteReq, cexQuoteReq, b.cfg.AutoRebalance.MinQuoteTransfer)
+}
+
+func (b *backtester) rebalanceAsset(dexAssetID, cexAssetID uint32, dexReq, cexReq, minTransfer uint64) {
+	for _, t := range b.transfers {
+		if t.dexAssetID == dexAssetID {
+			return
+		}
+	}
+	dexBal := backtestBalance(b.dexBals, dexAssetID)
+	cexBal := backtestBalance(b.cexBals, cexAssetID)
+	dexTotal := dexBal.Available + dexBal.Locked
+	cexTotal := cexBal.Available + cexBal.Locked
+
+	var deposit bool
+	var amt uint64
+	switch {
+	case dexTotal < dexReq && cexTotal > cexReq:
+		amt = min(dexReq-dexTotal, cexTotal-cexReq, cexBal.Available)
+	case cexTotal < cexReq && dexTotal > dexReq:
+		deposit = true
+		amt = min(cexReq-cexTotal, dexTotal-dexReq, dexBal.Available)
+	default:
+		return
+	}
+	if amt == 0 || amt < minTransfer || amt <= b.cfg.TransferFees[cexAssetID] {
+		return
+	}
+
+	t := &backtestTransfer{
+		event:      b.newEvent(),
+		deposit:    deposit,
+		dexAssetID: dexAssetID,
+		cexAssetID: cexAssetID,
+		amt:        amt,
+		arrival:    b.epoch.Epoch + b.cfg.TransferEpochs,
+	}
+	if deposit {
+		dexBal.Available -= amt
+		cexBal.Pending += amt
+	} else {
+		cexBal.Available -= amt
+		dexBal.Pending += amt
+	}
+	b.transfers = append(b.transfers, t)
+	b.updateTransferEvent(t, false)
+}
+
+// completeTransfers credits the transfers that have arrived.
+func (b *backtester) completeTransfers() {
+	remaining := b.transfers[:0]
+	for _, t := range b.transfers {
+		if t.arrival > b.epoch.Epoch {
+			remaining = append(remaining, t)
+			continue
+		}
+		dest := backtestBalance(b.dexBals, t.dexAssetID)
+		if t.deposit {
+			dest = backtestBalance(b.cexBals, t.cexAssetID)
+		}
+		dest.Pending -= t.amt
+		dest.Available += t.amt - b.cfg.TransferFees[t.cexAssetID]
+		b.updateTransferEvent(t, true)
+	}
+	b.transfers = remaining
+}
+
+func (b *backtester) updateTransferEvent(t *backtestTransfer, complete bool) {
+	fee := b.cfg.TransferFees[t.cexAssetID]
+	effects := newBalanceEffects()
+	if complete {
+		effects.Settled[t.dexAssetID] = -int64(fee)
+	} else {
+		effects.Pending[t.dexAssetID] = t.amt
+		effects.Settled[t.dexAssetID] = -int64(t.amt)
+	}
+	t.event.Pending = !complete
+	t.event.BalanceEffects = effects
+	if t.deposit {
+		var credit uint64
+		if complete {
+			credit = t.amt - fee
+		}
+		t.event.DepositEvent = &DepositEvent{
+			DexAssetID: t.dexAssetID,
+			CEXAssetID: t.cexAssetID,
+			CEXCredit:  credit,
+		}
+		return
+	}
+	t.event.WithdrawalEvent = &WithdrawalEvent{
+		ID:         fmt.Sprintf("backtest-%d", t.event.ID),
+		DEXAssetID: t.dexAssetID,
+		CEXAssetID: t.cexAssetID,
+		CEXDebit:   t.amt,
+	}
+}
+
+// totals returns the total balances of the bot across the DEX and CEX.
+func (b *backtester) totals() map[uint32]*BotBalance {
+	totals := make(map[uint32]*BotBalance)
+	add := func(bals map[uint32]*BotBalance) {
+		for assetID, bal := range bals {
+			t := backtestBalance(totals, assetID)
+			t.Available += bal.Available
+			t.Locked += bal.Locked
+			t.Pending += bal.Pending
+			t.Reserved += bal.Reserved
+		}
+	}
+	add(b.dexBals)
+	add(b.cexBals)
+	return totals
+}
+
+func (b *backtester) initialBalances() map[uint32]uint64 {
+	initial := make(map[uint32]uint64)
+	for assetID, v := range b.cfg.Alloc.DEX {
+		initial[assetID] += v
+	}
+	for assetID, v := range b.cfg.Alloc.CEX {
+		initial[assetID] += v
+	}
+	return initial
+}
+
+func (b *backtester) profitLoss(totals map[uint32]*BotBalance) *ProfitLoss {
+	final := make(map[uint32]uint64, len(totals))
+	for assetID, bal := range totals {
+		final[assetID] = bal.Available + bal.Locked + bal.Pending + bal.Reserved
+	}
+	return newProfitLoss(b.initialBalances(), final, nil, b.epoch.FiatRates)
+}
+
+func (b *backtester) recordState() {
+	totals := b.totals()
+	b.result.Inventory = append(b.result.Inventory, &BacktestEpochState{
+		Epoch:     b.epoch.Epoch,
+		TimeStamp: b.epoch.TimeStamp,
+		State: &BalanceState{
+			FiatRates:     b.epoch.FiatRates,
+			Balances:      totals,
+			InventoryMods: make(map[uint32]int64),
+		},
+		Profit: b.profitLoss(totals).Profit,
+	})
+}
+
+// finish cancels the remaining orders and calculates the final results.
+func (b *backtester) finish() {
+	b.cancelAll()
+	b.result.ProfitLoss = b.profitLoss(b.totals())
+	if b.result.LotsPlaced > 0 {
+		b.result.FillRate = float64(b.result.LotsFilled) / float64(b.result.LotsPlaced)
+	}
+}
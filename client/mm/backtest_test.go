@@ -0,0 +1,297 @@
+package mm
+
+import (
+	"testing"
+
+	"decred.org/dcrdex/dex/calc"
+)
+
+func tBacktestEpochs(n int, cexBook *BacktestBook) []*BacktestEpoch {
+	epochs := make([]*BacktestEpoch, n)
+	for i := range epochs {
+		epochs[i] = &BacktestEpoch{
+			Epoch:      uint64(i + 1),
+			TimeStamp:  int64(1000 + i*10),
+			DEXBook:    &BacktestBook{},
+			CEXBook:    cexBook,
+			OracleRate: 20.0 / 60000,
+			FiatRates:  map[uint32]float64{42: 20, 0: 60000},
+		}
+	}
+	return epochs
+}
+
+func tBacktestDEXOrders(res *BacktestResult) (buys, sells []*MarketMakingEvent) {
+	for _, e := range res.Events {
+		if e.DEXOrderEvent == nil {
+			continue
+		}
+		if e.DEXOrderEvent.Sell {
+			sells = append(sells, e)
+		} else {
+			buys = append(buys, e)
+		}
+	}
+	return
+}
+
+func TestBacktestBasicMM(t *testing.T) {
+	const lotSize = 1e8
+	epochs := tBacktestEpochs(3, nil)
+	// Takers in the second epoch sell and buy through the bot's orders.
+	epochs[1].Matches = []*BacktestMatch{
+		{Rate: 100, Qty: lotSize, Sell: true},
+		{Rate: 1e9, Qty: lotSize, Sell: false},
+	}
+
+	cfg := &BacktestConfig{
+		Bot: &BotConfig{
+			BaseID:  42,
+			QuoteID: 0,
+			BasicMMConfig: &BasicMarketMakingConfig{
+				GapStrategy:    GapStrategyPercent,
+				BuyPlacements:  []*OrderPlacement{{Lots: 1, GapFactor: 0.01}},
+				SellPlacements: []*OrderPlacement{{Lots: 1, GapFactor: 0.01}},
+			},
+		},
+		LotSize:  lotSize,
+		RateStep: 100,
+		Alloc: &BotBalanceAllocation{
+			DEX: map[uint32]uint64{42: 10e8, 0: 1e7},
+		},
+		BuyFees:  &LotFees{Swap: 1000, Redeem: 1000},
+		SellFees: &LotFees{Swap: 1000, Redeem: 1000},
+		Epochs:   epochs,
+	}
+
+	res, err := RunBacktest(cfg, tLogger)
+	if err != nil {
+		t.Fatalf("RunBacktest error: %v", err)
+	}
+
+	// One lot of each side in the first epoch, and replacements for the
+	// filled orders in the second.
+	if res.LotsPlaced != 4 || res.LotsFilled != 2 || res.FillRate != 0.5 {
+		t.Fatalf("wrong lots placed / filled / fill rate: %d / %d / %f", res.LotsPlaced, res.LotsFilled, res.FillRate)
+	}
+	if len(res.Inventory) != 3 {
+		t.Fatalf("expected 3 inventory states, got %d", len(res.Inventory))
+	}
+
+	buys, sells := tBacktestDEXOrders(res)
+	if len(buys) != 2 || len(sells) != 2 {
+		t.Fatalf("expected 2 buys and 2 sells, got %d and %d", len(buys), len(sells))
+	}
+	buyRate, sellRate := buys[0].DEXOrderEvent.Rate, sells[0].DEXOrderEvent.Rate
+	if buyRate >= sellRate {
+		t.Fatalf("buy rate %d >= sell rate %d", buyRate, sellRate)
+	}
+	for _, e := range res.Events {
+		if e.Pending {
+			t.Fatalf("event %d still pending after backtest", e.ID)
+		}
+	}
+
+	// The filled orders are settled at their own rates, and the fees are
+	// deducted from the fee assets.
+	if buys[0].BalanceEffects.Settled[42] != lotSize-1000 {
+		t.Fatalf("wrong buy base settled %d", buys[0].BalanceEffects.Settled[42])
+	}
+	if sells[0].BalanceEffects.Settled[0] != int64(calc.BaseToQuote(sellRate, lotSize))-1000 {
+		t.Fatalf("wrong sell quote settled %d", sells[0].BalanceEffects.Settled[0])
+	}
+
+	final := res.Inventory[2].State.Balances
+	if final[42].Locked != lotSize+1000 {
+		t.Fatalf("wrong base locked %d", final[42].Locked)
+	}
+
+	expBase := int64(10e8 - 2000)
+	expQuote := int64(1e7) + int64(calc.BaseToQuote(sellRate, lotSize)) - int64(calc.BaseToQuote(buyRate, lotSize)) - 2000
+	if res.ProfitLoss.Final[42].Atoms != expBase {
+		t.Fatalf("wrong final base %d, expected %d", res.ProfitLoss.Final[42].Atoms, expBase)
+	}
+	if res.ProfitLoss.Final[0].Atoms != expQuote {
+		t.Fatalf("wrong final quote %d, expected %d", res.ProfitLoss.Final[0].Atoms, expQuote)
+	}
+}
+
+func TestBacktestBookCross(t *testing.T) {
+	const lotSize = 1e8
+	epochs := tBacktestEpochs(1, nil)
+	// A resting sell order below the bot's buy rate is matched immediately.
+	epochs[0].DEXBook = &BacktestBook{
+		Sells: []*BacktestBookEntry{{Rate: 30000, Qty: 3 * lotSize}},
+	}
+
+	cfg := &BacktestConfig{
+		Bot: &BotConfig{
+			BaseID:  42,
+			QuoteID: 0,
+			BasicMMConfig: &BasicMarketMakingConfig{
+				GapStrategy:   GapStrategyPercent,
+				BuyPlacements: []*OrderPlacement{{Lots: 2, GapFactor: 0.01}},
+			},
+		},
+		LotSize:  lotSize,
+		RateStep: 100,
+		Alloc: &BotBalanceAllocation{
+			DEX: map[uint32]uint64{42: 1e8, 0: 1e7},
+		},
+		BuyFees:  &LotFees{},
+		SellFees: &LotFees{},
+		Epochs:   epochs,
+	}
+
+	res, err := RunBacktest(cfg, tLogger)
+	if err != nil {
+		t.Fatalf("RunBacktest error: %v", err)
+	}
+	if res.LotsFilled != 2 {
+		t.Fatalf("expected 2 lots filled, got %d", res.LotsFilled)
+	}
+	if res.ProfitLoss.Final[0].Atoms != 1e7-2*30000 {
+		t.Fatalf("expected buy at the book rate, final quote = %d", res.ProfitLoss.Final[0].Atoms)
+	}
+	if res.ProfitLoss.Final[42].Atoms != 3e8 {
+		t.Fatalf("wrong final base %d", res.ProfitLoss.Final[42].Atoms)
+	}
+}
+
+func TestBacktestArbMM(t *testing.T) {
+	const lotSize = 1e8
+	cexBook := &BacktestBook{
+		Buys:  []*BacktestBookEntry{{Rate: 33000, Qty: 10 * lotSize}},
+		Sells: []*BacktestBookEntry{{Rate: 34000, Qty: 10 * lotSize}},
+	}
+	epochs := tBacktestEpochs(2, cexBook)
+	epochs[1].Matches = []*BacktestMatch{{Rate: 1e9, Qty: lotSize, Sell: false}}
+
+	cfg := &BacktestConfig{
+		Bot: &BotConfig{
+			BaseID:     42,
+			QuoteID:    0,
+			CEXBaseID:  42,
+			CEXQuoteID: 0,
+			ArbMarketMakerConfig: &ArbMarketMakerConfig{
+				BuyPlacements:      []*ArbMarketMakingPlacement{{Lots: 1, Multiplier: 1}},
+				SellPlacements:     []*ArbMarketMakingPlacement{{Lots: 1, Multiplier: 1}},
+				Profit:             0.01,
+				NumEpochsLeaveOpen: 2,
+			},
+		},
+		LotSize:  lotSize,
+		RateStep: 100,
+		Alloc: &BotBalanceAllocation{
+			DEX: map[uint32]uint64{42: 5e8, 0: 1e7},
+			CEX: map[uint32]uint64{42: 5e8, 0: 1e7},
+		},
+		BuyFees:     &LotFees{Swap: 1000, Redeem: 1000},
+		SellFees:    &LotFees{Swap: 1000, Redeem: 1000},
+		CEXTradeFee: 0.001,
+		Epochs:      epochs,
+	}
+
+	res, err := RunBacktest(cfg, tLogger)
+	if err != nil {
+		t.Fatalf("RunBacktest error: %v", err)
+	}
+
+	buys, sells := tBacktestDEXOrders(res)
+	if len(buys) != 1 || len(sells) != 2 {
+		t.Fatalf("expected 1 buy and 2 sells, got %d and %d", len(buys), len(sells))
+	}
+	if buys[0].DEXOrderEvent.Rate >= 33000 || sells[0].DEXOrderEvent.Rate <= 34000 {
+		t.Fatalf("DEX rates %d / %d not outside of CEX spread", buys[0].DEXOrderEvent.Rate, sells[0].DEXOrderEvent.Rate)
+	}
+
+	var cexEvents []*MarketMakingEvent
+	for _, e := range res.Events {
+		if e.CEXOrderEvent != nil {
+			cexEvents = append(cexEvents, e)
+		}
+	}
+	if len(cexEvents) != 1 {
+		t.Fatalf("expected 1 CEX order, got %d", len(cexEvents))
+	}
+	cexTrade := cexEvents[0].CEXOrderEvent
+	if cexTrade.Sell || cexTrade.BaseFilled != lotSize-lotSize/1000 || cexTrade.QuoteFilled != 34000 {
+		t.Fatalf("wrong counter trade %+v", cexTrade)
+	}
+
+	sellQuote := int64(calc.BaseToQuote(sells[0].DEXOrderEvent.Rate, lotSize))
+	expQuote := int64(2e7) + sellQuote - 34000 - 1000
+	if res.ProfitLoss.Final[0].Atoms != expQuote {
+		t.Fatalf("wrong final quote %d, expected %d", res.ProfitLoss.Final[0].Atoms, expQuote)
+	}
+	expBase := int64(10e8 - lotSize/1000 - 1000)
+	if res.ProfitLoss.Final[42].Atoms != expBase {
+		t.Fatalf("wrong final base %d, expected %d", res.ProfitLoss.Final[42].Atoms, expBase)
+	}
+}
+
+func TestBacktestRebalance(t *testing.T) {
+	const lotSize = 1e8
+	cexBook := &BacktestBook{
+		Buys:  []*BacktestBookEntry{{Rate: 33000, Qty: 10 * lotSize}},
+		Sells: []*BacktestBookEntry{{Rate: 34000, Qty: 10 * lotSize}},
+	}
+	epochs := tBacktestEpochs(3, cexBook)
+
+	cfg := &BacktestConfig{
+		Bot: &BotConfig{
+			BaseID:     42,
+			QuoteID:    0,
+			CEXBaseID:  42,
+			CEXQuoteID: 0,
+			ArbMarketMakerConfig: &ArbMarketMakerConfig{
+				SellPlacements:     []*ArbMarketMakingPlacement{{Lots: 2, Multiplier: 1}},
+				Profit:             0.01,
+				NumEpochsLeaveOpen: 2,
+			},
+		},
+		LotSize:  lotSize,
+		RateStep: 100,
+		Alloc: &BotBalanceAllocation{
+			DEX: map[uint32]uint64{0: 1e7},
+			CEX: map[uint32]uint64{42: 5e8, 0: 1e7},
+		},
+		BuyFees:        &LotFees{},
+		SellFees:       &LotFees{},
+		AutoRebalance:  &AutoRebalanceConfig{MinBaseTransfer: lotSize},
+		TransferFees:   map[uint32]uint64{42: 1000},
+		TransferEpochs: 2,
+		Epochs:         epochs,
+	}
+
+	res, err := RunBacktest(cfg, tLogger)
+	if err != nil {
+		t.Fatalf("RunBacktest error: %v", err)
+	}
+
+	var withdrawals []*MarketMakingEvent
+	for _, e := range res.Events {
+		if e.WithdrawalEvent != nil {
+			withdrawals = append(withdrawals, e)
+		}
+	}
+	if len(withdrawals) != 1 {
+		t.Fatalf("expected 1 withdrawal, got %d", len(withdrawals))
+	}
+	if withdrawals[0].Pending || withdrawals[0].WithdrawalEvent.CEXDebit != 2*lotSize {
+		t.Fatalf("wrong withdrawal %+v", withdrawals[0].WithdrawalEvent)
+	}
+
+	if pending := res.Inventory[0].State.Balances[42].Pending; pending != 2*lotSize {
+		t.Fatalf("expected %d pending after first epoch, got %d", uint64(2*lotSize), pending)
+	}
+	// The withdrawal arrives in the third epoch, minus the fee, which
+	// leaves only enough for a single lot.
+	bal := res.Inventory[2].State.Balances[42]
+	if bal.Pending != 0 || bal.Locked != lotSize {
+		t.Fatalf("wrong balance after withdrawal %+v", bal)
+	}
+	if res.ProfitLoss.Final[42].Atoms != 5e8-1000 {
+		t.Fatalf("wrong final base %d", res.ProfitLoss.Final[42].Atoms)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strconv"
 
+	"decred.org/dcrdex/client/mm/libxc"
 	"decred.org/dcrdex/dex/utils"
 )
 
@@ -12,6 +13,12 @@ import (
 type MarketMakingConfig struct {
 	BotConfigs []*BotConfig `json:"botConfigs"`
 	CexConfigs []*CEXConfig `json:"cexConfigs"`
+	// RiskConfig is the portfolio-wide risk limits that apply across all
+	// running bots.
+	RiskConfig *RiskConfig `json:"riskConfig,omitempty"`
+	// KillSwitch is set if the kill switch has been triggered and not yet
+	// reset. It is saved so that the kill switch survives a restart.
+	KillSwitch *StampedError `json:"killSwitch,omitempty"`
 }
 
 func (cfg *MarketMakingConfig) Copy() *MarketMakingConfig {
@@ -21,6 +28,13 @@ func (cfg *MarketMakingConfig) Copy() *MarketMakingConfig {
 	}
 	copy(c.BotConfigs, cfg.BotConfigs)
 	copy(c.CexConfigs, cfg.CexConfigs)
+	if cfg.RiskConfig != nil {
+		c.RiskConfig = cfg.RiskConfig.copy()
+	}
+	if cfg.KillSwitch != nil {
+		k := *cfg.KillSwitch
+		c.KillSwitch = &k
+	}
 	return c
 }
 
@@ -32,6 +46,13 @@ type CEXConfig struct {
 	APIKey string `json:"apiKey"`
 	// APISecret is the API secret for the CEX.
 	APISecret string `json:"apiSecret"`
+	// APIPassphrase is the passphrase of the API key. It is only required
+	// by some CEXs, e.g. OKX.
+	APIPassphrase string `json:"apiPassphrase,omitempty"`
+	// PaperTrading, if set, replaces the CEX with a simulated exchange so
+	// that bots can be dry-run without trading real funds on the CEX. The
+	// API key and secret are not used.
+	PaperTrading *libxc.PaperTradingConfig `json:"paperTrading,omitempty"`
 }
 
 // AutoRebalanceConfig configures deposits and withdrawals by setting minimum
@@ -54,12 +75,26 @@ type AutoRebalanceConfig struct {
 	// allocating unallocated funds to the bot's balance and never actually
 	// perform deposits and withdrawals with the CEX.
 	InternalOnly bool `json:"internalOnly"`
+	// BotTransfers allows funds that would otherwise be moved by a CEX
+	// deposit or withdrawal to instead be swapped with another running bot
+	// that uses the same wallet and CEX and also has BotTransfers enabled.
+	// The other bot's DEX funds are exchanged for an equal amount of this
+	// bot's CEX funds, or vice versa, without any fees.
+	BotTransfers bool `json:"botTransfers,omitempty"`
+	// BridgeRebalance allows funds that would otherwise be withdrawn from the
+	// CEX to instead be bridged to the bot's DEX wallet from unallocated
+	// funds of the same token on another chain, e.g. USDC on Ethereum to
+	// USDC on Polygon. The bot's CEX balance is released in exchange. The
+	// bridge with the lowest fees is chosen.
+	BridgeRebalance bool `json:"bridgeRebalance,omitempty"`
 }
 
 func (a *AutoRebalanceConfig) copy() *AutoRebalanceConfig {
 	return &AutoRebalanceConfig{
 		MinBaseTransfer:  a.MinBaseTransfer,
 		MinQuoteTransfer: a.MinQuoteTransfer,
+		BotTransfers:     a.BotTransfers,
+		BridgeRebalance:  a.BridgeRebalance,
 	}
 }
 
@@ -169,10 +204,23 @@ type BotConfig struct {
 	// when they are starting the bot.
 	LotSize uint64 `json:"lotSize"`
 
+	// InventorySkew, if set, skews the placements of the basic and arb
+	// market makers based on the bot's base/quote inventory.
+	InventorySkew *InventorySkewConfig `json:"inventorySkew,omitempty"`
+
 	// Only one of the following configs should be set
 	BasicMMConfig        *BasicMarketMakingConfig `json:"basicMarketMakingConfig,omitempty"`
 	SimpleArbConfig      *SimpleArbConfig         `json:"simpleArbConfig,omitempty"`
 	ArbMarketMakerConfig *ArbMarketMakerConfig    `json:"arbMarketMakingConfig,omitempty"`
+	// StrategyConfig runs a custom Strategy registered with
+	// RegisterStrategy.
+	StrategyConfig *StrategyConfig `json:"strategyConfig,omitempty"`
+	// TriangleArbConfig runs a triangular arbitrage bot across three
+	// markets on the DEX host.
+	TriangleArbConfig *TriangleArbConfig `json:"triangleArbConfig,omitempty"`
+	// CrossDEXArbConfig runs an arbitrage bot against the same market on
+	// another DEX host.
+	CrossDEXArbConfig *CrossDEXArbConfig `json:"crossDEXArbConfig,omitempty"`
 }
 
 func (c *BotConfig) copy() *BotConfig {
@@ -188,6 +236,9 @@ func (c *BotConfig) copy() *BotConfig {
 	if c.RPCConfig != nil {
 		b.RPCConfig = c.RPCConfig.copy()
 	}
+	if c.InventorySkew != nil {
+		b.InventorySkew = c.InventorySkew.copy()
+	}
 	if c.BasicMMConfig != nil {
 		b.BasicMMConfig = c.BasicMMConfig.copy()
 	}
@@ -197,6 +248,15 @@ func (c *BotConfig) copy() *BotConfig {
 	if c.ArbMarketMakerConfig != nil {
 		b.ArbMarketMakerConfig = c.ArbMarketMakerConfig.copy()
 	}
+	if c.StrategyConfig != nil {
+		b.StrategyConfig = c.StrategyConfig.copy()
+	}
+	if c.TriangleArbConfig != nil {
+		b.TriangleArbConfig = c.TriangleArbConfig.copy()
+	}
+	if c.CrossDEXArbConfig != nil {
+		b.CrossDEXArbConfig = c.CrossDEXArbConfig.copy()
+	}
 
 	return &b
 }
@@ -231,12 +291,27 @@ func (c *BotConfig) validate(configuredBridgesSupported func([]*configuredBridge
 		return err
 	}
 
+	if c.InventorySkew != nil {
+		if c.BasicMMConfig == nil && c.ArbMarketMakerConfig == nil {
+			return fmt.Errorf("inventory skew is only supported by the basic and arb market makers")
+		}
+		if err := c.InventorySkew.validate(); err != nil {
+			return fmt.Errorf("invalid inventory skew config: %w", err)
+		}
+	}
+
 	if c.BasicMMConfig != nil {
 		return c.BasicMMConfig.validate()
 	} else if c.SimpleArbConfig != nil {
 		return c.SimpleArbConfig.validate()
 	} else if c.ArbMarketMakerConfig != nil {
 		return c.ArbMarketMakerConfig.validate(c.BaseID, c.QuoteID)
+	} else if c.StrategyConfig != nil {
+		return c.StrategyConfig.validate()
+	} else if c.TriangleArbConfig != nil {
+		return c.TriangleArbConfig.validate(c.BaseID, c.QuoteID)
+	} else if c.CrossDEXArbConfig != nil {
+		return c.CrossDEXArbConfig.validate(c.Host)
 	}
 
 	return fmt.Errorf("no bot config set")
@@ -245,7 +320,10 @@ func (c *BotConfig) validate(configuredBridgesSupported func([]*configuredBridge
 func validateConfigUpdate(old, new *BotConfig, bridgesSupported func([]*configuredBridge) error) error {
 	if (old.BasicMMConfig == nil) != (new.BasicMMConfig == nil) ||
 		(old.SimpleArbConfig == nil) != (new.SimpleArbConfig == nil) ||
-		(old.ArbMarketMakerConfig == nil) != (new.ArbMarketMakerConfig == nil) {
+		(old.ArbMarketMakerConfig == nil) != (new.ArbMarketMakerConfig == nil) ||
+		(old.StrategyConfig == nil) != (new.StrategyConfig == nil) ||
+		(old.TriangleArbConfig == nil) != (new.TriangleArbConfig == nil) ||
+		(old.CrossDEXArbConfig == nil) != (new.CrossDEXArbConfig == nil) {
 		return fmt.Errorf("cannot change bot type")
 	}
 
@@ -254,6 +332,14 @@ func validateConfigUpdate(old, new *BotConfig, bridgesSupported func([]*configur
 		return fmt.Errorf("cannot change assets of a running bot")
 	}
 
+	if old.TriangleArbConfig != nil && old.TriangleArbConfig.IntermediateAssetID != new.TriangleArbConfig.IntermediateAssetID {
+		return fmt.Errorf("cannot change assets of a running bot")
+	}
+
+	if old.CrossDEXArbConfig != nil && old.CrossDEXArbConfig.CounterHost != new.CrossDEXArbConfig.CounterHost {
+		return fmt.Errorf("cannot change the counter host of a running bot")
+	}
+
 	return new.validate(bridgesSupported)
 }
 
@@ -261,6 +347,19 @@ func (c *BotConfig) requiresPriceOracle() bool {
 	return c.BasicMMConfig != nil
 }
 
+// extraDEXAssets returns the DEX assets, other than the assets of the bot's
+// market and their fee assets, that the bot trades.
+func (c *BotConfig) extraDEXAssets() []uint32 {
+	if c.TriangleArbConfig == nil {
+		return nil
+	}
+	assetID := c.TriangleArbConfig.IntermediateAssetID
+	if feeID := feeAssetID(assetID); feeID != assetID {
+		return []uint32{assetID, feeID}
+	}
+	return []uint32{assetID}
+}
+
 // multiSplitBuffer returns the additional buffer to add to the order size
 // when doing a multi-split. This only applies to the quote asset.
 func (c *BotConfig) multiSplitBuffer() float64 {
@@ -288,6 +387,10 @@ func (c *BotConfig) maxPlacements() (buy, sell uint32) {
 		return uint32(len(c.ArbMarketMakerConfig.BuyPlacements)), uint32(len(c.ArbMarketMakerConfig.SellPlacements))
 	case c.BasicMMConfig != nil:
 		return uint32(len(c.BasicMMConfig.BuyPlacements)), uint32(len(c.BasicMMConfig.SellPlacements))
+	case c.StrategyConfig != nil:
+		return c.StrategyConfig.MaxBuyPlacements, c.StrategyConfig.MaxSellPlacements
+	case c.TriangleArbConfig != nil, c.CrossDEXArbConfig != nil:
+		return 1, 1
 	default:
 		return 1, 1
 	}
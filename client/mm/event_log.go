@@ -59,6 +59,30 @@ type WithdrawalEvent struct {
 	CEXDebit     uint64                   `json:"cexDebit"`
 }
 
+// BridgeEvent represents a bridge of unallocated funds into one of the bot's
+// DEX wallets that was done in place of a CEX withdrawal. The bot's CEX
+// balance is debited by CEXDebit, and the bot's DEX balance is credited
+// with the amount received once the bridge is complete.
+type BridgeEvent struct {
+	FromAssetID uint32                   `json:"fromAssetID"`
+	ToAssetID   uint32                   `json:"toAssetID"`
+	CEXAssetID  uint32                   `json:"cexAssetID"`
+	BridgeName  string                   `json:"bridgeName"`
+	BridgeTx    *asset.WalletTransaction `json:"bridgeTx"`
+	CEXDebit    uint64                   `json:"cexDebit"`
+}
+
+// BotTransferEvent represents an exchange of funds with another bot that
+// uses the same wallet and CEX. If ToDEX is true, the bot received Amount of
+// DEX funds and gave up Amount of CEX funds, otherwise the opposite.
+type BotTransferEvent struct {
+	Peer       *MarketWithHost `json:"peer"`
+	DEXAssetID uint32          `json:"assetID"`
+	CEXAssetID uint32          `json:"cexAssetID"`
+	Amount     uint64          `json:"amount"`
+	ToDEX      bool            `json:"toDEX"`
+}
+
 // MarketMakingEvent represents an action that a market making bot takes.
 type MarketMakingEvent struct {
 	ID             uint64          `json:"id"`
@@ -67,12 +91,14 @@ type MarketMakingEvent struct {
 	BalanceEffects *BalanceEffects `json:"balanceEffects,omitempty"`
 
 	// Only one of the following will be populated.
-	DEXOrderEvent   *DEXOrderEvent    `json:"dexOrderEvent,omitempty"`
-	CEXOrderEvent   *CEXOrderEvent    `json:"cexOrderEvent,omitempty"`
-	DepositEvent    *DepositEvent     `json:"depositEvent,omitempty"`
-	WithdrawalEvent *WithdrawalEvent  `json:"withdrawalEvent,omitempty"`
-	UpdateConfig    *BotConfig        `json:"updateConfig,omitempty"`
-	UpdateInventory *map[uint32]int64 `json:"updateInventory,omitempty"`
+	DEXOrderEvent    *DEXOrderEvent    `json:"dexOrderEvent,omitempty"`
+	CEXOrderEvent    *CEXOrderEvent    `json:"cexOrderEvent,omitempty"`
+	DepositEvent     *DepositEvent     `json:"depositEvent,omitempty"`
+	WithdrawalEvent  *WithdrawalEvent  `json:"withdrawalEvent,omitempty"`
+	BridgeEvent      *BridgeEvent      `json:"bridgeEvent,omitempty"`
+	BotTransferEvent *BotTransferEvent `json:"botTransferEvent,omitempty"`
+	UpdateConfig     *BotConfig        `json:"updateConfig,omitempty"`
+	UpdateInventory  *map[uint32]int64 `json:"updateInventory,omitempty"`
 }
 
 // MarketMakingRun identifies a market making run.
@@ -455,6 +455,11 @@ type unifiedExchangeAdaptor struct {
 	// internalTransfer.
 	internalTransfer func(*MarketWithHost, doInternalTransferFunc) error
 	bridgesSupported func([]*configuredBridge) error
+	// bridgeTransfer and botTransfer are used for the alternatives to CEX
+	// deposits and withdrawals. No mutexes should be locked when calling
+	// them either.
+	bridgeTransfer func(mkt *MarketWithHost, fromAssetIDs []uint32, amt uint64, doTransfer doBridgeTransferFunc) error
+	botTransfer    func(mkt *MarketWithHost, dexAssetID, cexAssetID uint32, amt uint64, toDEX bool) (*MarketWithHost, error)
 
 	botLooper dex.Connector
 	botLoop   *dex.ConnectionMaster
@@ -484,6 +489,7 @@ type unifiedExchangeAdaptor struct {
 	pendingCEXOrders   map[string]*pendingCEXOrder
 	pendingWithdrawals map[string]*pendingWithdrawal
 	pendingDeposits    map[string]*pendingDeposit
+	pendingBridges     map[string]*pendingBridge
 	inventoryMods      map[uint32]int64
 
 	// If pendingBaseRebalance/pendingQuoteRebalance are true, it means
@@ -998,6 +1004,14 @@ func withinTolerance(rate, target uint64, driftTolerance float64) bool {
 }
 
 func (u *unifiedExchangeAdaptor) placeMultiTrade(placements []*dexOrderInfo, sell bool) []*core.MultiTradeResult {
+	return u.placeMultiTradeOnMarket(u.host, u.dexBaseID, u.dexQuoteID, placements, sell)
+}
+
+// placeMultiTradeOnMarket places orders on any market on any DEX host. Bots
+// that trade on markets other than their own must have the assets of those
+// markets allocated to them. Orders on all hosts are funded by the same
+// wallets, so they are tracked in the bot's DEX balances.
+func (u *unifiedExchangeAdaptor) placeMultiTradeOnMarket(host string, baseID, quoteID uint32, placements []*dexOrderInfo, sell bool) []*core.MultiTradeResult {
 	corePlacements := make([]*core.QtyRate, 0, len(placements))
 	for _, p := range placements {
 		corePlacements = append(corePlacements, p.placement)
@@ -1005,17 +1019,23 @@ func (u *unifiedExchangeAdaptor) placeMultiTrade(placements []*dexOrderInfo, sel
 
 	botCfg := u.botCfg()
 	var walletOptions map[string]string
-	if sell {
+	if sell && baseID == botCfg.BaseID {
 		walletOptions = botCfg.BaseWalletOptions
-	} else {
+	} else if !sell && quoteID == botCfg.QuoteID {
 		walletOptions = botCfg.QuoteWalletOptions
 	}
 
-	fromAsset, fromFeeAsset, _, toFeeAsset, _, cexToAsset := orderAssets(u.dexBaseID, u.dexQuoteID, u.botCfg().CEXBaseID, u.botCfg().CEXQuoteID, sell)
+	// Orders on other markets are never counter-traded on the CEX.
+	cexBaseID, cexQuoteID := baseID, quoteID
+	if host == u.host && baseID == u.dexBaseID && quoteID == u.dexQuoteID {
+		cexBaseID, cexQuoteID = botCfg.CEXBaseID, botCfg.CEXQuoteID
+	}
+
+	fromAsset, fromFeeAsset, _, toFeeAsset, _, cexToAsset := orderAssets(baseID, quoteID, cexBaseID, cexQuoteID, sell)
 	multiTradeForm := &core.MultiTradeForm{
-		Host:       u.host,
-		Base:       u.dexBaseID,
-		Quote:      u.dexQuoteID,
+		Host:       host,
+		Base:       baseID,
+		Quote:      quoteID,
 		Sell:       sell,
 		Placements: corePlacements,
 		Options:    walletOptions,
@@ -1475,6 +1495,29 @@ func (u *unifiedExchangeAdaptor) DEXTrade(rate, qty uint64, sell bool) (*core.Or
 	return results[0].Order, nil
 }
 
+// dexTradeOnMarket places a single order on another market or DEX host. The
+// caller is responsible for checking that the bot has sufficient balance,
+// although the order will never lock more than the bot's available balance of
+// the from asset.
+func (u *unifiedExchangeAdaptor) dexTradeOnMarket(host string, baseID, quoteID uint32, rate, qty uint64, sell bool) (*core.Order, error) {
+	placements := []*dexOrderInfo{{
+		placement: &core.QtyRate{
+			Qty:  qty,
+			Rate: rate,
+		},
+	}}
+
+	results := u.placeMultiTradeOnMarket(host, baseID, quoteID, placements, sell)
+	if len(results) == 0 {
+		return nil, fmt.Errorf("no orders placed")
+	}
+	if results[0].Error != nil {
+		return nil, results[0].Error
+	}
+
+	return results[0].Order, nil
+}
+
 type BotBalances struct {
 	DEX *BotBalance `json:"dex"`
 	CEX *BotBalance `json:"cex"`
@@ -1517,6 +1560,11 @@ func (u *unifiedExchangeAdaptor) dexBalance(assetID uint32) *BotBalance {
 		addEffects(dexEffects)
 	}
 
+	for _, pendingBridge := range u.pendingBridges {
+		dexEffects, _ := pendingBridge.balanceEffects()
+		addEffects(dexEffects)
+	}
+
 	availableBalance := bal + totalEffects.Settled[assetID]
 	if availableBalance < 0 {
 		u.log.Errorf("negative dex balance for %s: %d", dex.BipIDSymbol(assetID), availableBalance)
@@ -1555,13 +1603,16 @@ func (u *unifiedExchangeAdaptor) refreshAllPendingEvents(ctx context.Context) {
 	maps.Copy(pendingWithdrawals, u.pendingWithdrawals)
 	pendingDeposits := make(map[string]*pendingDeposit, len(u.pendingDeposits))
 	maps.Copy(pendingDeposits, u.pendingDeposits)
+	pendingBridges := make(map[string]*pendingBridge, len(u.pendingBridges))
+	maps.Copy(pendingBridges, u.pendingBridges)
 	u.balancesMtx.Unlock()
 
 	cfg := u.botCfg()
 	for _, pendingOrder := range pendingDEXOrders {
 		pendingOrder.txsMtx.Lock()
 		state := pendingOrder.currentState()
-		pendingOrder.updateState(state.order, cfg.CEXBaseID, cfg.CEXQuoteID, u.clientCore.WalletTransaction, u.baseTraits, u.quoteTraits)
+		baseTraits, quoteTraits := u.orderTraits(state.order)
+		pendingOrder.updateState(state.order, cfg.CEXBaseID, cfg.CEXQuoteID, u.clientCore.WalletTransaction, baseTraits, quoteTraits)
 		pendingOrder.txsMtx.Unlock()
 	}
 
@@ -1573,6 +1624,10 @@ func (u *unifiedExchangeAdaptor) refreshAllPendingEvents(ctx context.Context) {
 		u.confirmWithdrawal(ctx, pendingWithdrawal.withdrawalID)
 	}
 
+	for txID := range pendingBridges {
+		u.confirmBridge(ctx, txID)
+	}
+
 	for _, pendingOrder := range pendingCEXOrders {
 		pendingOrder.tradeMtx.RLock()
 		id, baseID, quoteID := pendingOrder.trade.ID, pendingOrder.trade.BaseID, pendingOrder.trade.QuoteID
@@ -1657,6 +1712,11 @@ func (u *unifiedExchangeAdaptor) cexBalance(assetID uint32) *BotBalance {
 		addEffects(cexEffects)
 	}
 
+	for _, bridge := range u.pendingBridges {
+		_, cexEffects := bridge.balanceEffects()
+		addEffects(cexEffects)
+	}
+
 	// Credited deposits generally should already be part of the base balance,
 	// but just in case the amount was credited before the wallet confirmed the
 	// fee.
@@ -1703,6 +1763,10 @@ func (u *unifiedExchangeAdaptor) allAssets() []uint32 {
 	assets[feeAssetID(u.botCfg().CEXBaseID)] = struct{}{}
 	assets[feeAssetID(u.botCfg().CEXQuoteID)] = struct{}{}
 
+	for _, assetID := range u.botCfg().extraDEXAssets() {
+		assets[assetID] = struct{}{}
+	}
+
 	// TODO: add intermediate asset ID if multi-hop is enabled?
 
 	return utils.MapKeys(assets)
@@ -2125,6 +2189,16 @@ func (u *unifiedExchangeAdaptor) confirmWithdrawal(ctx context.Context, withdraw
 		withdrawal.txMtx.Lock()
 		withdrawal.amtWithdrawn = amt
 		withdrawal.withdrawalTxID = withdrawalTxID
+		// A simulated withdrawal has no transaction for the wallet to find.
+		// It is credited as soon as the CEX reports it complete.
+		if sim, is := u.CEX.(libxc.Simulator); is && sim.Simulated() {
+			withdrawal.withdrawalTx = &asset.WalletTransaction{
+				Type:      asset.Receive,
+				ID:        withdrawalTxID,
+				Amount:    amt,
+				Confirmed: true,
+			}
+		}
 		withdrawal.txMtx.Unlock()
 		updated = true
 	}
@@ -2555,8 +2629,12 @@ func (u *unifiedExchangeAdaptor) ExchangeRateFromFiatSources() uint64 {
 // This is the same as a message-rate, but without the RateEncodingFactor,
 // hence a float.
 func (u *unifiedExchangeAdaptor) atomicConversionRateFromFiat(fromID, toID uint32) (float64, error) {
-	fromRate := u.fiatRate(fromID)
-	toRate := u.fiatRate(toID)
+	return atomicConversionRate(fromID, toID, u.fiatRate(fromID), u.fiatRate(toID))
+}
+
+// atomicConversionRate generates a conversion rate from atomic units of one
+// asset to atomic units of another using the assets' fiat rates.
+func atomicConversionRate(fromID, toID uint32, fromRate, toRate float64) (float64, error) {
 	if fromRate == 0 || toRate == 0 {
 		return 0, fmt.Errorf("missing fiat rate. rate for %d = %f, rate for %d = %f", fromID, fromRate, toID, toRate)
 	}
@@ -2598,30 +2676,38 @@ func (u *unifiedExchangeAdaptor) OrderFeesInUnits(sell, base bool, rate uint64)
 	if err != nil {
 		return 0, fmt.Errorf("error getting order fees: %v", err)
 	}
+	return orderFeesInUnits(sell, base, rate, buyFeeRange.Estimated, sellFeeRange.Estimated, u.dexBaseID, u.dexQuoteID, u.atomicConversionRateFromFiat)
+}
+
+// orderFeesInUnits converts the fees for a lot of a buy or sell order into
+// units of either the base or quote asset. Token fees are converted from the
+// parent asset using atomicRate.
+func orderFeesInUnits(sell, base bool, rate uint64, buyFees, sellFees *LotFees, baseID, quoteID uint32,
+	atomicRate func(fromID, toID uint32) (float64, error)) (uint64, error) {
 
-	buyFees, sellFees := buyFeeRange.Estimated, sellFeeRange.Estimated
 	baseFees, quoteFees := buyFees.Redeem, buyFees.Swap
 	if sell {
 		baseFees, quoteFees = sellFees.Swap, sellFees.Redeem
 	}
 
 	convertViaFiat := func(fees uint64, fromID, toID uint32) (uint64, error) {
-		atomicCFactor, err := u.atomicConversionRateFromFiat(fromID, toID)
+		atomicCFactor, err := atomicRate(fromID, toID)
 		if err != nil {
 			return 0, err
 		}
 		return uint64(math.Round(float64(fees) * atomicCFactor)), nil
 	}
 
+	var err error
 	var baseFeesInUnits, quoteFeesInUnits uint64
-	if tkn := asset.TokenInfo(u.dexBaseID); tkn != nil {
-		baseFees, err = convertViaFiat(baseFees, tkn.ParentID, u.dexBaseID)
+	if tkn := asset.TokenInfo(baseID); tkn != nil {
+		baseFees, err = convertViaFiat(baseFees, tkn.ParentID, baseID)
 		if err != nil {
 			return 0, err
 		}
 	}
-	if tkn := asset.TokenInfo(u.dexQuoteID); tkn != nil {
-		quoteFees, err = convertViaFiat(quoteFees, tkn.ParentID, u.dexQuoteID)
+	if tkn := asset.TokenInfo(quoteID); tkn != nil {
+		quoteFees, err = convertViaFiat(quoteFees, tkn.ParentID, quoteID)
 		if err != nil {
 			return 0, err
 		}
@@ -2798,6 +2884,22 @@ func (u *unifiedExchangeAdaptor) isWithdrawer(assetID uint32) bool {
 	return u.quoteTraits.IsWithdrawer()
 }
 
+// orderTraits returns the wallet traits of the base and quote assets of an
+// order, which may be on a market other than the bot's own.
+func (u *unifiedExchangeAdaptor) orderTraits(o *core.Order) (baseTraits, quoteTraits asset.WalletTrait) {
+	if o.BaseID == u.dexBaseID && o.QuoteID == u.dexQuoteID {
+		return u.baseTraits, u.quoteTraits
+	}
+	var err error
+	if baseTraits, err = u.clientCore.WalletTraits(o.BaseID); err != nil {
+		u.log.Errorf("Error getting wallet traits for %s: %v", dex.BipIDSymbol(o.BaseID), err)
+	}
+	if quoteTraits, err = u.clientCore.WalletTraits(o.QuoteID); err != nil {
+		u.log.Errorf("Error getting wallet traits for %s: %v", dex.BipIDSymbol(o.QuoteID), err)
+	}
+	return
+}
+
 // orderAssets returns all the assets involved in a dex order.
 // cexFromAsset and cexToAsset are the assets that will be traded on the CEX.
 // If they are different from fromAsset and toAsset, cexFromAsset is the asset
@@ -3027,7 +3129,8 @@ func (u *unifiedExchangeAdaptor) handleDEXOrderUpdate(o *core.Order) {
 
 	cfg := u.botCfg()
 	pendingOrder.txsMtx.Lock()
-	pendingOrder.updateState(o, cfg.CEXBaseID, cfg.CEXQuoteID, u.clientCore.WalletTransaction, u.baseTraits, u.quoteTraits)
+	baseTraits, quoteTraits := u.orderTraits(o)
+	pendingOrder.updateState(o, cfg.CEXBaseID, cfg.CEXQuoteID, u.clientCore.WalletTransaction, baseTraits, quoteTraits)
 	dexEffects := pendingOrder.currentState().dexBalanceEffects
 	var havePending bool
 	for _, v := range dexEffects.Pending {
@@ -3130,6 +3233,18 @@ func (u *unifiedExchangeAdaptor) handleDEXNotification(n core.Notification) {
 			return
 		}
 		u.handleServerConfigUpdate()
+	case *core.MarketParamsNote:
+		if note.Host != u.host {
+			return
+		}
+		if mktName, _ := dex.MarketName(u.dexBaseID, u.dexQuoteID); note.MarketID != mktName {
+			return
+		}
+		// Core applies the change to the market config at the start epoch
+		// and sends a ServerConfigUpdateNote, and the bot's placements are
+		// then adjusted by handleServerConfigUpdate.
+		u.log.Infof("Lot size changing from %d to %d and rate step from %d to %d at epoch %d",
+			u.lotSize.Load(), note.LotSize, u.rateStep.Load(), note.RateStep, note.StartEpoch)
 	}
 }
 
@@ -3639,6 +3754,10 @@ func (u *unifiedExchangeAdaptor) tryTransfers(currEpoch uint64, df distributionF
 		return false, err
 	}
 
+	if err := u.tryAlternativeTransfers(dist, autoRebalanceCfg); err != nil {
+		return false, err
+	}
+
 	if !autoRebalanceCfg.InternalOnly {
 		return u.doExternalTransfers(dist, currEpoch)
 	}
@@ -3687,6 +3806,25 @@ func (u *unifiedExchangeAdaptor) inventory(dexAssetID, cexAssetID uint32, dexLot
 	return
 }
 
+// inventoryTotals returns the bot's total holdings of the market's base and
+// quote assets on the DEX and, if the bot uses one, the CEX.
+func (u *unifiedExchangeAdaptor) inventoryTotals() (base, quote uint64) {
+	u.balancesMtx.RLock()
+	defer u.balancesMtx.RUnlock()
+
+	total := func(bal *BotBalance) uint64 {
+		return bal.Available + bal.Locked + bal.Pending + bal.Reserved
+	}
+	base = total(u.dexBalance(u.dexBaseID))
+	quote = total(u.dexBalance(u.dexQuoteID))
+	if u.CEX != nil {
+		botCfg := u.botCfg()
+		base += total(u.cexBalance(botCfg.CEXBaseID))
+		quote += total(u.cexBalance(botCfg.CEXQuoteID))
+	}
+	return
+}
+
 // cexCounterRates attempts to get vwap estimates for the cex book for a
 // specified number of lots. If the book is too empty for the specified number
 // of lots, a 1-lot estimate will be attempted too.
@@ -4046,7 +4184,8 @@ func (u *unifiedExchangeAdaptor) stats() *RunStats {
 	u.runStats.tradedUSD.Unlock()
 
 	// Effects of pendingWithdrawals are applied when the withdrawal is
-	// complete.
+	// complete. Pending bridges are reported as pending withdrawals, since
+	// they are done in place of withdrawals.
 	return &RunStats{
 		InitialBalances:    u.initialBalances,
 		DEXBalances:        dexBalances,
@@ -4054,7 +4193,7 @@ func (u *unifiedExchangeAdaptor) stats() *RunStats {
 		ProfitLoss:         newProfitLoss(u.initialBalances, totalBalances, u.inventoryMods, fiatRates),
 		StartTime:          u.startTime.Load(),
 		PendingDeposits:    len(u.pendingDeposits),
-		PendingWithdrawals: len(u.pendingWithdrawals),
+		PendingWithdrawals: len(u.pendingWithdrawals) + len(u.pendingBridges),
 		CompletedMatches:   u.runStats.completedMatches.Load(),
 		TradedUSD:          tradedUSD,
 		FeeGap:             feeGap,
@@ -4311,6 +4450,8 @@ type exchangeAdaptorCfg struct {
 	botCfg              *BotConfig
 	bridgesSupported    func([]*configuredBridge) error
 	internalTransfer    func(*MarketWithHost, doInternalTransferFunc) error
+	bridgeTransfer      func(mkt *MarketWithHost, fromAssetIDs []uint32, amt uint64, doTransfer doBridgeTransferFunc) error
+	botTransfer         func(mkt *MarketWithHost, dexAssetID, cexAssetID uint32, amt uint64, toDEX bool) (*MarketWithHost, error)
 }
 
 // newUnifiedExchangeAdaptor is the constructor for a unifiedExchangeAdaptor.
@@ -4361,6 +4502,8 @@ func newUnifiedExchangeAdaptor(cfg *exchangeAdaptorCfg) (*unifiedExchangeAdaptor
 		quoteTraits:      quoteTraits,
 		internalTransfer: cfg.internalTransfer,
 		bridgesSupported: cfg.bridgesSupported,
+		bridgeTransfer:   cfg.bridgeTransfer,
+		botTransfer:      cfg.botTransfer,
 
 		baseDexBalances:    baseDEXBalances,
 		baseCexBalances:    baseCEXBalances,
@@ -4368,6 +4511,7 @@ func newUnifiedExchangeAdaptor(cfg *exchangeAdaptorCfg) (*unifiedExchangeAdaptor
 		pendingCEXOrders:   make(map[string]*pendingCEXOrder),
 		pendingDeposits:    make(map[string]*pendingDeposit),
 		pendingWithdrawals: make(map[string]*pendingWithdrawal),
+		pendingBridges:     make(map[string]*pendingBridge),
 		mwh:                cfg.mwh,
 		inventoryMods:      make(map[uint32]int64),
 		cexProblems:        newCEXProblems(),
@@ -626,8 +626,8 @@ func testDistribution(t *testing.T, baseID, quoteID uint32) {
 		BookingFeesPerLot: sellBookingFees,
 	}
 
-	buyRate, _ := a.dexPlacementRate(buyVWAP, false)
-	sellRate, _ := a.dexPlacementRate(sellVWAP, true)
+	buyRate, _ := a.dexPlacementRate(buyVWAP, false, a.cfg().Profit)
+	sellRate, _ := a.dexPlacementRate(sellVWAP, true, a.cfg().Profit)
 
 	var buyLots, sellLots, minDexBase, minCexBase, totalBase, minDexQuote, minCexQuote, totalQuote uint64
 	var addBaseFees, addQuoteFees uint64
@@ -6619,3 +6619,59 @@ func TestRefreshPendingEvents(t *testing.T) {
 	expectedCEXAvailableBalance[42] -= 2e7
 	checkAvailableBalances()
 }
+
+// tSimulatedCEX is a tCEX that only simulates withdrawals, like the paper
+// trading CEX.
+type tSimulatedCEX struct {
+	*tCEX
+}
+
+func (c *tSimulatedCEX) Simulated() bool {
+	return true
+}
+
+func TestSimulatedWithdrawal(t *testing.T) {
+	tCore := newTCore()
+	tCEX := newTCEX()
+	adaptor := mustParseAdaptor(&exchangeAdaptorCfg{
+		core: tCore,
+		cex:  &tSimulatedCEX{tCEX},
+		mwh: &MarketWithHost{
+			Host:    "host1",
+			BaseID:  42,
+			QuoteID: 0,
+		},
+		baseDexBalances: map[uint32]uint64{42: 1e9, 0: 1e9},
+		baseCexBalances: map[uint32]uint64{42: 1e9, 0: 1e9},
+		eventLogDB:      &tEventLogDB{},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const withdrawalID = "456"
+	adaptor.pendingWithdrawals[withdrawalID] = &pendingWithdrawal{
+		withdrawalID: withdrawalID,
+		dexAssetID:   42,
+		cexAssetID:   42,
+		amtWithdrawn: 2e7,
+	}
+	if adaptor.confirmWithdrawal(ctx, withdrawalID) {
+		t.Fatalf("pending withdrawal confirmed")
+	}
+
+	// The wallet does not know about the simulated withdrawal transaction,
+	// but the net amount is credited as soon as the CEX reports it complete.
+	const netAmt = 2e7 - 1e5
+	tCEX.confirmWithdrawalMtx.Lock()
+	tCEX.confirmWithdrawal = &withdrawArgs{assetID: 42, amt: netAmt, txID: "paper"}
+	tCEX.confirmWithdrawalMtx.Unlock()
+	if !adaptor.confirmWithdrawal(ctx, withdrawalID) {
+		t.Fatalf("simulated withdrawal not confirmed")
+	}
+	if bal := adaptor.DEXBalance(42); bal.Available != 1e9+netAmt {
+		t.Fatalf("wrong dex balance. want %d, got %d", uint64(1e9+netAmt), bal.Available)
+	}
+	if bal := adaptor.CEXBalance(42); bal.Available != 1e9-netAmt {
+		t.Fatalf("wrong cex balance. want %d, got %d", uint64(1e9-netAmt), bal.Available)
+	}
+}
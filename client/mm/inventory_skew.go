@@ -0,0 +1,149 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package mm
+
+import (
+	"fmt"
+	"math"
+
+	"decred.org/dcrdex/dex/calc"
+)
+
+// InventorySkewConfig enables inventory-aware quoting in the style of
+// Avellaneda-Stoikov. When the share of the bot's inventory held in the base
+// asset drifts from the target, the bot shifts its basis price away from the
+// side it has too much of, and narrows the placements that would bring the
+// inventory back towards the target while widening the ones that would push
+// it further away.
+type InventorySkewConfig struct {
+	// TargetBaseRatio is the desired share of the bot's total inventory
+	// value, measured in the quote asset, that is held in the base asset.
+	// 0 < x < 1. 0.5 is a balanced inventory.
+	TargetBaseRatio float64 `json:"targetBaseRatio"`
+	// RiskAversion is the ratio of the basis price that the basis price is
+	// shifted by when the inventory is entirely in one asset. The shift is
+	// proportional to the deviation from the target. 0 <= x <= 0.1.
+	RiskAversion float64 `json:"riskAversion"`
+	// SpreadSkew controls the asymmetric adjustment of the gap factors. When
+	// the inventory is entirely in one asset, the gaps on one side are
+	// multiplied by 1 + SpreadSkew and on the other by 1 - SpreadSkew.
+	// 0 <= x <= 1.
+	SpreadSkew float64 `json:"spreadSkew"`
+}
+
+func (c *InventorySkewConfig) validate() error {
+	if c.TargetBaseRatio <= 0 || c.TargetBaseRatio >= 1 {
+		return fmt.Errorf("target base ratio %f out of bounds", c.TargetBaseRatio)
+	}
+	if c.RiskAversion < 0 || c.RiskAversion > 0.1 {
+		return fmt.Errorf("risk aversion %f out of bounds", c.RiskAversion)
+	}
+	if c.SpreadSkew < 0 || c.SpreadSkew > 1 {
+		return fmt.Errorf("spread skew %f out of bounds", c.SpreadSkew)
+	}
+	return nil
+}
+
+func (c *InventorySkewConfig) copy() *InventorySkewConfig {
+	cfg := *c
+	return &cfg
+}
+
+// InventorySkew is the inventory-dependent adjustment that was applied to a
+// bot's placements during an epoch.
+type InventorySkew struct {
+	// BaseRatio is the share of the inventory value held in the base asset.
+	BaseRatio float64 `json:"baseRatio"`
+	// TargetBaseRatio is the configured target for BaseRatio.
+	TargetBaseRatio float64 `json:"targetBaseRatio"`
+	// Deviation is the normalized distance of BaseRatio from the target.
+	// -1 means the inventory is entirely quote asset, 1 means it is entirely
+	// base asset.
+	Deviation float64 `json:"deviation"`
+	// BasisShift is the ratio of the basis price that the basis price was
+	// moved by. It is negative when there is excess base asset.
+	BasisShift float64 `json:"basisShift"`
+	// BuyGapMultiplier is applied to the gap factors of buy placements.
+	BuyGapMultiplier float64 `json:"buyGapMultiplier"`
+	// SellGapMultiplier is applied to the gap factors of sell placements.
+	SellGapMultiplier float64 `json:"sellGapMultiplier"`
+}
+
+// shiftRate applies the basis shift to a rate.
+func (s *InventorySkew) shiftRate(rate, rateStep uint64) uint64 {
+	if s == nil || s.BasisShift == 0 {
+		return rate
+	}
+	return steppedRate(uint64(math.Round(float64(rate)*(1+s.BasisShift))), rateStep)
+}
+
+// gapMultiplier returns the multiplier for the gap factors of one side.
+func (s *InventorySkew) gapMultiplier(sell bool) float64 {
+	if s == nil {
+		return 1
+	}
+	if sell {
+		return s.SellGapMultiplier
+	}
+	return s.BuyGapMultiplier
+}
+
+// calcInventorySkew calculates the skew for inventory of base and quote asset
+// valued at the specified rate. nil is returned if the inventory cannot be
+// valued.
+func calcInventorySkew(cfg *InventorySkewConfig, base, quote, rate uint64) *InventorySkew {
+	if rate == 0 {
+		return nil
+	}
+	baseValue := float64(calc.BaseToQuote(rate, base))
+	total := baseValue + float64(quote)
+	if total == 0 {
+		return nil
+	}
+
+	baseRatio := baseValue / total
+	target := cfg.TargetBaseRatio
+	var dev float64
+	if baseRatio > target {
+		dev = (baseRatio - target) / (1 - target)
+	} else {
+		dev = (baseRatio - target) / target
+	}
+
+	return &InventorySkew{
+		BaseRatio:         baseRatio,
+		TargetBaseRatio:   target,
+		Deviation:         dev,
+		BasisShift:        -cfg.RiskAversion * dev,
+		BuyGapMultiplier:  1 + cfg.SpreadSkew*dev,
+		SellGapMultiplier: 1 - cfg.SpreadSkew*dev,
+	}
+}
+
+// inventorySkewer calculates the InventorySkew for a bot's current inventory.
+// A nil *inventorySkewer never skews.
+type inventorySkewer struct {
+	cfg *InventorySkewConfig
+	// inventory returns the bot's total holdings of the base and quote
+	// assets.
+	inventory func() (base, quote uint64)
+}
+
+func newInventorySkewer(cfg *InventorySkewConfig, inventory func() (base, quote uint64)) *inventorySkewer {
+	if cfg == nil {
+		return nil
+	}
+	return &inventorySkewer{
+		cfg:       cfg,
+		inventory: inventory,
+	}
+}
+
+func (s *inventorySkewer) skew(rate uint64) *InventorySkew {
+	if s == nil {
+		return nil
+	}
+	base, quote := s.inventory()
+	return calcInventorySkew(s.cfg, base, quote, rate)
+}
@@ -0,0 +1,193 @@
+//go:build !harness && !botlive
+
+package mm
+
+import (
+	"math"
+	"testing"
+
+	"decred.org/dcrdex/client/core"
+	"decred.org/dcrdex/dex/calc"
+)
+
+func TestCalcInventorySkew(t *testing.T) {
+	const rate uint64 = 2e8 // 2 quote per base
+	cfg := &InventorySkewConfig{
+		TargetBaseRatio: 0.5,
+		RiskAversion:    0.02,
+		SpreadSkew:      0.5,
+	}
+
+	tests := []struct {
+		name         string
+		base, quote  uint64
+		expNil       bool
+		expBaseRatio float64
+		expDev       float64
+	}{
+		{
+			name:         "balanced",
+			base:         1e8,
+			quote:        2e8,
+			expBaseRatio: 0.5,
+			expDev:       0,
+		},
+		{
+			name:         "all base",
+			base:         1e8,
+			expBaseRatio: 1,
+			expDev:       1,
+		},
+		{
+			name:         "all quote",
+			quote:        1e8,
+			expBaseRatio: 0,
+			expDev:       -1,
+		},
+		{
+			name:         "excess base",
+			base:         3e8,
+			quote:        2e8,
+			expBaseRatio: 0.75,
+			expDev:       0.5,
+		},
+		{
+			name:   "empty",
+			expNil: true,
+		},
+	}
+
+	for _, tt := range tests {
+		skew := calcInventorySkew(cfg, tt.base, tt.quote, rate)
+		if tt.expNil {
+			if skew != nil {
+				t.Fatalf("%s: expected nil skew", tt.name)
+			}
+			continue
+		}
+		if math.Abs(skew.BaseRatio-tt.expBaseRatio) > 1e-9 {
+			t.Fatalf("%s: wrong base ratio. wanted %f, got %f", tt.name, tt.expBaseRatio, skew.BaseRatio)
+		}
+		if math.Abs(skew.Deviation-tt.expDev) > 1e-9 {
+			t.Fatalf("%s: wrong deviation. wanted %f, got %f", tt.name, tt.expDev, skew.Deviation)
+		}
+		if math.Abs(skew.BasisShift+cfg.RiskAversion*tt.expDev) > 1e-9 {
+			t.Fatalf("%s: wrong basis shift %f", tt.name, skew.BasisShift)
+		}
+		if math.Abs(skew.BuyGapMultiplier-(1+cfg.SpreadSkew*tt.expDev)) > 1e-9 ||
+			math.Abs(skew.SellGapMultiplier-(1-cfg.SpreadSkew*tt.expDev)) > 1e-9 {
+			t.Fatalf("%s: wrong gap multipliers %f, %f", tt.name, skew.BuyGapMultiplier, skew.SellGapMultiplier)
+		}
+	}
+
+	if calcInventorySkew(cfg, 1e8, 1e8, 0) != nil {
+		t.Fatalf("expected nil skew for zero rate")
+	}
+}
+
+func TestBasicMMInventorySkew(t *testing.T) {
+	const basisPrice uint64 = 5e6
+	const rateStep uint64 = 1e3
+	const lotSize = 5e9
+	const gapFactor = 0.1
+
+	mkt := mustParseAdaptorFromMarket(&core.Market{
+		RateStep:   rateStep,
+		AtomToConv: 1,
+		LotSize:    lotSize,
+		BaseID:     42,
+		QuoteID:    0,
+	}).market
+	cfg := &BasicMarketMakingConfig{
+		GapStrategy:    GapStrategyPercent,
+		BuyPlacements:  []*OrderPlacement{{Lots: 1, GapFactor: gapFactor}},
+		SellPlacements: []*OrderPlacement{{Lots: 1, GapFactor: gapFactor}},
+	}
+	calculator := &tBasicMMCalculator{bp: basisPrice}
+
+	ordersToPlace := func(skewer *inventorySkewer) (buy, sell uint64, skew *InventorySkew) {
+		t.Helper()
+		buys, sells, _, skew, err := basicMMOrdersToPlace(cfg, mkt, calculator, skewer, tLogger)
+		if err != nil {
+			t.Fatalf("basicMMOrdersToPlace error: %v", err)
+		}
+		return buys[0].Rate, sells[0].Rate, skew
+	}
+
+	buy, sell, skew := ordersToPlace(nil)
+	if skew != nil {
+		t.Fatalf("unexpected skew without config")
+	}
+	expGap := uint64(math.Round(gapFactor * float64(basisPrice)))
+	if buy != basisPrice-expGap || sell != basisPrice+expGap {
+		t.Fatalf("unskewed rates wrong. buy = %d, sell = %d", buy, sell)
+	}
+
+	skewCfg := &InventorySkewConfig{
+		TargetBaseRatio: 0.5,
+		RiskAversion:    0.01,
+		SpreadSkew:      0.5,
+	}
+
+	// All base. The basis price moves down, the sell moves closer to it and
+	// the buy moves farther away.
+	skewer := newInventorySkewer(skewCfg, func() (uint64, uint64) { return lotSize * 10, 0 })
+	buy, sell, skew = ordersToPlace(skewer)
+	if skew == nil || skew.Deviation != 1 {
+		t.Fatalf("expected full base deviation, got %+v", skew)
+	}
+	skewedBasis := steppedRate(uint64(math.Round(float64(basisPrice)*(1-skewCfg.RiskAversion))), rateStep)
+	expBuy := skewedBasis - steppedRate(uint64(math.Round(gapFactor*1.5*float64(skewedBasis))), rateStep)
+	expSell := skewedBasis + steppedRate(uint64(math.Round(gapFactor*0.5*float64(skewedBasis))), rateStep)
+	if buy != expBuy {
+		t.Fatalf("wrong skewed buy rate. wanted %d, got %d", expBuy, buy)
+	}
+	if sell != expSell {
+		t.Fatalf("wrong skewed sell rate. wanted %d, got %d", expSell, sell)
+	}
+
+	// Balanced inventory is not skewed.
+	skewer = newInventorySkewer(skewCfg, func() (uint64, uint64) {
+		return lotSize, calc.BaseToQuote(basisPrice, lotSize)
+	})
+	buy, sell, skew = ordersToPlace(skewer)
+	if skew == nil || skew.Deviation != 0 {
+		t.Fatalf("expected zero deviation, got %+v", skew)
+	}
+	if buy != basisPrice-expGap || sell != basisPrice+expGap {
+		t.Fatalf("balanced rates wrong. buy = %d, sell = %d", buy, sell)
+	}
+}
+
+func TestArbMMPlacementProfit(t *testing.T) {
+	const profit = 0.01
+	if p := arbMMPlacementProfit(profit, nil, true); p != profit {
+		t.Fatalf("expected unskewed profit, got %f", p)
+	}
+
+	// Excess quote. Buys are placed closer to the CEX rate to encourage them,
+	// and sells farther away.
+	skew := calcInventorySkew(&InventorySkewConfig{
+		TargetBaseRatio: 0.5,
+		RiskAversion:    0.002,
+		SpreadSkew:      0.5,
+	}, 0, 1e8, 1e8)
+	buyProfit := arbMMPlacementProfit(profit, skew, false)
+	sellProfit := arbMMPlacementProfit(profit, skew, true)
+	if math.Abs(buyProfit-0.003) > 1e-9 {
+		t.Fatalf("wrong buy profit %f", buyProfit)
+	}
+	if math.Abs(sellProfit-0.017) > 1e-9 {
+		t.Fatalf("wrong sell profit %f", sellProfit)
+	}
+
+	// The profit is never skewed below zero.
+	skew = calcInventorySkew(&InventorySkewConfig{
+		TargetBaseRatio: 0.5,
+		RiskAversion:    0.1,
+		SpreadSkew:      1,
+	}, 0, 1e8, 1e8)
+	if p := arbMMPlacementProfit(profit, skew, false); p != 0 {
+		t.Fatalf("expected zero buy profit, got %f", p)
+	}
+}
@@ -0,0 +1,300 @@
+package bbtypes
+
+import (
+	"encoding/json"
+)
+
+// Numeric values are sent by Bybit as strings, and are often empty strings
+// when not applicable, so they are kept as strings here and parsed where
+// needed.
+
+// ============================================================================
+// REST
+// ============================================================================
+
+// Response is the envelope for every Bybit REST response. RetCode is 0 for
+// success.
+type Response struct {
+	RetCode int             `json:"retCode"`
+	RetMsg  string          `json:"retMsg"`
+	Result  json.RawMessage `json:"result"`
+}
+
+// LotSizeFilter is the quantity precision and limits of an instrument.
+type LotSizeFilter struct {
+	BasePrecision  string `json:"basePrecision"`
+	QuotePrecision string `json:"quotePrecision"`
+	MinOrderQty    string `json:"minOrderQty"`
+	MinOrderAmt    string `json:"minOrderAmt"`
+}
+
+// PriceFilter is the price precision of an instrument.
+type PriceFilter struct {
+	TickSize string `json:"tickSize"`
+}
+
+// Instrument is an entry in the /v5/market/instruments-info response.
+type Instrument struct {
+	Symbol        string         `json:"symbol"` // e.g. BTCUSDT
+	BaseCoin      string         `json:"baseCoin"`
+	QuoteCoin     string         `json:"quoteCoin"`
+	Status        string         `json:"status"` // Trading, PreLaunch, Delivering, Closed
+	LotSizeFilter *LotSizeFilter `json:"lotSizeFilter"`
+	PriceFilter   *PriceFilter   `json:"priceFilter"`
+
+	// Below fields are parsed from the above and the asset unit info.
+	LotSize       uint64
+	MinQty        uint64
+	MinCost       uint64
+	RateStep      uint64
+	PriceDecimals int
+	SizeDecimals  int
+	QuoteDecimals int
+}
+
+// InstrumentsResult is the result of /v5/market/instruments-info.
+type InstrumentsResult struct {
+	Category string        `json:"category"`
+	List     []*Instrument `json:"list"`
+}
+
+// Ticker is an entry in the /v5/market/tickers response. For spot markets,
+// Volume24h is in units of the base coin and Turnover24h is in units of the
+// quote coin.
+type Ticker struct {
+	Symbol       string `json:"symbol"`
+	LastPrice    string `json:"lastPrice"`
+	PrevPrice24h string `json:"prevPrice24h"`
+	HighPrice24h string `json:"highPrice24h"`
+	LowPrice24h  string `json:"lowPrice24h"`
+	Volume24h    string `json:"volume24h"`
+	Turnover24h  string `json:"turnover24h"`
+}
+
+// TickersResult is the result of /v5/market/tickers.
+type TickersResult struct {
+	Category string    `json:"category"`
+	List     []*Ticker `json:"list"`
+}
+
+// CoinBalance is the balance of a single coin in the unified trading
+// account. Locked is the amount locked in open orders.
+type CoinBalance struct {
+	Coin          string `json:"coin"`
+	WalletBalance string `json:"walletBalance"`
+	Locked        string `json:"locked"`
+}
+
+// WalletBalance is an entry in the /v5/account/wallet-balance response and
+// the data of the wallet websocket topic.
+type WalletBalance struct {
+	AccountType string         `json:"accountType"`
+	Coin        []*CoinBalance `json:"coin"`
+}
+
+// WalletBalanceResult is the result of /v5/account/wallet-balance.
+type WalletBalanceResult struct {
+	List []*WalletBalance `json:"list"`
+}
+
+// OrderRequest is the body of a /v5/order/create request.
+type OrderRequest struct {
+	Category    string `json:"category"` // "spot"
+	Symbol      string `json:"symbol"`
+	Side        string `json:"side"`      // Buy or Sell
+	OrderType   string `json:"orderType"` // Limit or Market
+	Qty         string `json:"qty"`
+	Price       string `json:"price,omitempty"`
+	TimeInForce string `json:"timeInForce,omitempty"` // GTC or IOC
+	OrderLinkID string `json:"orderLinkId,omitempty"`
+	// MarketUnit is the unit of Qty for market orders, baseCoin or
+	// quoteCoin.
+	MarketUnit string `json:"marketUnit,omitempty"`
+}
+
+// CancelOrderRequest is the body of a /v5/order/cancel request.
+type CancelOrderRequest struct {
+	Category string `json:"category"`
+	Symbol   string `json:"symbol"`
+	OrderID  string `json:"orderId"`
+}
+
+// OrderResult is the result of /v5/order/create and /v5/order/cancel.
+type OrderResult struct {
+	OrderID     string `json:"orderId"`
+	OrderLinkID string `json:"orderLinkId"`
+}
+
+// Order is an entry in the /v5/order/realtime and /v5/order/history
+// responses and the data of the order websocket topic. For spot orders,
+// CumExecFee is charged in the coin that is received.
+type Order struct {
+	Category     string `json:"category"`
+	Symbol       string `json:"symbol"`
+	OrderID      string `json:"orderId"`
+	OrderLinkID  string `json:"orderLinkId"`
+	Side         string `json:"side"`
+	OrderType    string `json:"orderType"`
+	Price        string `json:"price"`
+	Qty          string `json:"qty"`
+	MarketUnit   string `json:"marketUnit"`
+	OrderStatus  string `json:"orderStatus"`
+	CumExecQty   string `json:"cumExecQty"`
+	CumExecValue string `json:"cumExecValue"`
+	CumExecFee   string `json:"cumExecFee"`
+}
+
+// OrdersResult is the result of /v5/order/realtime and /v5/order/history.
+type OrdersResult struct {
+	List []*Order `json:"list"`
+}
+
+// Chain is the deposit and withdrawal information of a coin on a chain.
+type Chain struct {
+	Chain         string `json:"chain"` // e.g. ETH
+	ChainType     string `json:"chainType"`
+	WithdrawFee   string `json:"withdrawFee"`
+	WithdrawMin   string `json:"withdrawMin"`
+	MinAccuracy   string `json:"minAccuracy"`
+	ChainDeposit  string `json:"chainDeposit"`  // "1" if deposits are enabled
+	ChainWithdraw string `json:"chainWithdraw"` // "1" if withdrawals are enabled
+}
+
+// CoinInfo is an entry in the /v5/asset/coin/query-info response.
+type CoinInfo struct {
+	Coin   string   `json:"coin"`
+	Chains []*Chain `json:"chains"`
+}
+
+// CoinInfoResult is the result of /v5/asset/coin/query-info.
+type CoinInfoResult struct {
+	Rows []*CoinInfo `json:"rows"`
+}
+
+// DepositChain is a deposit address on a chain.
+type DepositChain struct {
+	Chain          string `json:"chain"`
+	ChainType      string `json:"chainType"`
+	AddressDeposit string `json:"addressDeposit"`
+	TagDeposit     string `json:"tagDeposit"`
+}
+
+// DepositAddressResult is the result of /v5/asset/deposit/query-address.
+type DepositAddressResult struct {
+	Coin   string          `json:"coin"`
+	Chains []*DepositChain `json:"chains"`
+}
+
+// Deposit is an entry in the /v5/asset/deposit/query-record response.
+type Deposit struct {
+	Coin   string `json:"coin"`
+	Chain  string `json:"chain"`
+	Amount string `json:"amount"`
+	TxID   string `json:"txID"`
+	Status int    `json:"status"`
+}
+
+// Deposit statuses.
+const (
+	DepositStatusSuccess = 3
+	DepositStatusFailed  = 4
+)
+
+// DepositsResult is the result of /v5/asset/deposit/query-record.
+type DepositsResult struct {
+	Rows []*Deposit `json:"rows"`
+}
+
+// TransferRequest is the body of a /v5/asset/transfer/inter-transfer
+// request. TransferID must be a UUID.
+type TransferRequest struct {
+	TransferID      string `json:"transferId"`
+	Coin            string `json:"coin"`
+	Amount          string `json:"amount"`
+	FromAccountType string `json:"fromAccountType"`
+	ToAccountType   string `json:"toAccountType"`
+}
+
+// Account types for transfers.
+const (
+	AccountFund    = "FUND"
+	AccountUnified = "UNIFIED"
+)
+
+// WithdrawRequest is the body of a /v5/asset/withdraw request. If FeeType
+// is 1, the fee is deducted from Amount.
+type WithdrawRequest struct {
+	Coin        string `json:"coin"`
+	Chain       string `json:"chain"`
+	Address     string `json:"address"`
+	Amount      string `json:"amount"`
+	Timestamp   int64  `json:"timestamp"`
+	ForceChain  int    `json:"forceChain"`
+	AccountType string `json:"accountType"`
+	FeeType     int    `json:"feeType"`
+}
+
+// WithdrawResult is the result of /v5/asset/withdraw.
+type WithdrawResult struct {
+	ID string `json:"id"`
+}
+
+// Withdrawal is an entry in the /v5/asset/withdraw/query-record response.
+// TxID is empty until the withdrawal has been broadcast.
+type Withdrawal struct {
+	WithdrawID  string `json:"withdrawId"`
+	Coin        string `json:"coin"`
+	Chain       string `json:"chain"`
+	Amount      string `json:"amount"`
+	WithdrawFee string `json:"withdrawFee"`
+	TxID        string `json:"txID"`
+	Status      string `json:"status"`
+}
+
+// WithdrawalsResult is the result of /v5/asset/withdraw/query-record.
+type WithdrawalsResult struct {
+	Rows []*Withdrawal `json:"rows"`
+}
+
+// Withdrawal statuses that indicate the withdrawal will not be completed.
+const (
+	WithdrawalStatusCancelByUser = "CancelByUser"
+	WithdrawalStatusReject       = "Reject"
+	WithdrawalStatusFail         = "Fail"
+)
+
+// ============================================================================
+// Websocket
+// ============================================================================
+
+// WsRequest is a request sent over the websocket connection. Args are topic
+// names for subscriptions, and the API key, expiry and signature for auth.
+type WsRequest struct {
+	Op   string        `json:"op"`
+	Args []interface{} `json:"args,omitempty"`
+}
+
+// WsMessage is used to determine the type of an incoming websocket message.
+// Responses to requests have Op set, while topic messages have Topic and
+// Data set.
+type WsMessage struct {
+	Op      string `json:"op"`
+	Success *bool  `json:"success"`
+	RetMsg  string `json:"ret_msg"`
+
+	Topic string          `json:"topic"`
+	Type  string          `json:"type"` // snapshot or delta, for orderbooks
+	Data  json.RawMessage `json:"data"`
+}
+
+// BookData is the data of an orderbook topic message. Levels are of the
+// form [price, size], and a size of zero removes the level. UpdateID is
+// incremented with every message, and is 1 for snapshots that are sent after
+// a service restart.
+type BookData struct {
+	Symbol   string          `json:"s"`
+	Bids     [][]json.Number `json:"b"`
+	Asks     [][]json.Number `json:"a"`
+	UpdateID int64           `json:"u"`
+	Seq      int64           `json:"seq"`
+}
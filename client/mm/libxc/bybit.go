@@ -0,0 +1,1749 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package libxc
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"decred.org/dcrdex/client/asset"
+	"decred.org/dcrdex/client/comms"
+	"decred.org/dcrdex/client/core"
+	"decred.org/dcrdex/client/mm/libxc/bbtypes"
+	"decred.org/dcrdex/dex"
+	"decred.org/dcrdex/dex/calc"
+	"decred.org/dcrdex/dex/dexnet"
+	"decred.org/dcrdex/dex/encode"
+	"decred.org/dcrdex/dex/utils"
+)
+
+// REST docs: https://bybit-exchange.github.io/docs/v5/intro
+// Websocket docs: https://bybit-exchange.github.io/docs/v5/ws/connect
+// Funding docs: https://bybit-exchange.github.io/docs/v5/asset/balance/all-balance
+
+const (
+	bybitHTTPURL       = "https://api.bybit.com"
+	bybitPublicWsURL   = "wss://stream.bybit.com/v5/public/spot"
+	bybitPrivateWsURL  = "wss://stream.bybit.com/v5/private"
+	bybitRecvWindow    = "5000"
+	bybitPingInterval  = time.Second * 20
+	bybitBookDepth     = 200
+	bybitOrderTopic    = "order"
+	bybitWalletTopic   = "wallet"
+	bybitSpotCategory  = "spot"
+	bybitUnifiedWallet = "UNIFIED"
+)
+
+// dexToBybitCoin maps the DEX symbols to the Bybit coins where they differ.
+var dexToBybitCoin = map[string]string{
+	"polygon": "POL",
+	"base":    "ETH",
+	"weth":    "ETH",
+}
+
+// bybitChains are the names that Bybit uses for the chains, keyed by the
+// asset ID of the chain's base asset.
+var bybitChains = map[uint32]string{
+	0:    "BTC",
+	2:    "LTC",
+	3:    "DOGE",
+	5:    "DASH",
+	42:   "DCR",
+	60:   "ETH",
+	133:  "ZEC",
+	145:  "BCH",
+	966:  "MATIC",
+	8453: "BASE",
+}
+
+// supportedBybitTokens is the set of supported Bybit tokens. Bybit lists
+// each token as a single coin with a separate chain for each network.
+var supportedBybitTokens = map[uint32]struct{}{
+	60001:  {}, // USDC on ETH
+	60002:  {}, // USDT on ETH
+	61000:  {}, // USDC on BASE
+	966001: {}, // USDC on POLYGON
+	966004: {}, // USDT on POLYGON
+}
+
+// bybitCoin returns the Bybit coin for a DEX asset symbol.
+func bybitCoin(dexSymbol string) string {
+	sym := strings.Split(dexSymbol, ".")[0]
+	if coin, found := dexToBybitCoin[sym]; found {
+		return coin
+	}
+	return strings.ToUpper(sym)
+}
+
+// bybitChain returns the Bybit chain that is used to deposit and withdraw the
+// DEX asset.
+func bybitChain(assetID uint32) (string, error) {
+	netID := assetID
+	if token := asset.TokenInfo(assetID); token != nil {
+		netID = token.ParentID
+	}
+	chain, found := bybitChains[netID]
+	if !found {
+		return "", fmt.Errorf("no Bybit chain known for %s", dex.BipIDSymbol(assetID))
+	}
+	return chain, nil
+}
+
+// bybitFloat parses a numeric string from the Bybit API. Bybit uses empty
+// strings for values that are not applicable, which are parsed as zero.
+func bybitFloat(s string) float64 {
+	f, _ := strconv.ParseFloat(s, 64)
+	return f
+}
+
+// bybitDecimals returns the number of decimal places in a numeric string, e.g.
+// 4 for "0.0001". Bybit's precision strings can have trailing zeros, which are
+// not counted.
+func bybitDecimals(s string) int {
+	i := strings.Index(s, ".")
+	if i < 0 {
+		return 0
+	}
+	return len(strings.TrimRight(s[i+1:], "0"))
+}
+
+// BybitError is an error returned by the Bybit REST API.
+type BybitError struct {
+	Code int
+	Msg  string
+}
+
+func (e *BybitError) Error() string {
+	return fmt.Sprintf("code %d: %s", e.Code, e.Msg)
+}
+
+// bybitSignature generates a hex-encoded HMAC-SHA256 signature of the
+// payload. For REST requests, the payload is the timestamp, API key, receive
+// window and the query string or JSON body concatenated.
+func bybitSignature(payload, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// bybitTransferID generates a random version 4 UUID, which Bybit requires
+// as the ID of an internal transfer.
+func bybitTransferID() string {
+	b := encode.RandomBytes(16)
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	h := hex.EncodeToString(b)
+	return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
+// bybitWSConn manages a websocket connection to the Bybit API. If auth is
+// set, the subscriptions are sent after successful authentication.
+// Otherwise, they are sent every time the connection is established.
+type bybitWSConn struct {
+	wsConn     comms.WsConn
+	url        string
+	log        dex.Logger
+	auth       func() *bbtypes.WsRequest
+	subs       func() []*bbtypes.WsRequest
+	msgHandler func(*bbtypes.WsMessage)
+	setSynced  func(bool)
+}
+
+func newBybitWSConn(url string, auth func() *bbtypes.WsRequest, subs func() []*bbtypes.WsRequest, msgHandler func(*bbtypes.WsMessage), setSynced func(bool), log dex.Logger) *bybitWSConn {
+	return &bybitWSConn{
+		url:        url,
+		auth:       auth,
+		subs:       subs,
+		msgHandler: msgHandler,
+		setSynced:  setSynced,
+		log:        log,
+	}
+}
+
+func (c *bybitWSConn) send(req *bbtypes.WsRequest) error {
+	b, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	return c.wsConn.SendRaw(b)
+}
+
+func (c *bybitWSConn) subscribe() error {
+	for _, req := range c.subs() {
+		if err := c.send(req); err != nil {
+			return fmt.Errorf("error sending %s request: %w", req.Op, err)
+		}
+	}
+	return nil
+}
+
+// start authenticates if required, or subscribes to the topics.
+func (c *bybitWSConn) start() error {
+	if c.auth != nil {
+		if err := c.send(c.auth()); err != nil {
+			return fmt.Errorf("error authenticating: %w", err)
+		}
+		return nil
+	}
+	return c.subscribe()
+}
+
+func (c *bybitWSConn) handleWebsocketMessage(b []byte) {
+	msg := new(bbtypes.WsMessage)
+	if err := json.Unmarshal(b, msg); err != nil {
+		c.log.Errorf("Error unmarshaling websocket message: %v", err)
+		c.log.Errorf("Raw Message: %s", string(b))
+		return
+	}
+
+	if msg.Topic != "" {
+		c.msgHandler(msg)
+		return
+	}
+
+	success := msg.Success == nil || *msg.Success
+	switch msg.Op {
+	case "auth":
+		if !success {
+			c.log.Errorf("Websocket authentication failed: %s", msg.RetMsg)
+			return
+		}
+		if err := c.subscribe(); err != nil {
+			c.log.Errorf("Error subscribing after authentication: %v", err)
+		}
+	case "subscribe", "unsubscribe":
+		if !success {
+			c.log.Errorf("Websocket %s error: %s", msg.Op, msg.RetMsg)
+		}
+	}
+}
+
+func (c *bybitWSConn) Connect(ctx context.Context) (*sync.WaitGroup, error) {
+	initialConnect := true
+	conn, err := comms.NewWsConn(&comms.WsCfg{
+		URL: c.url,
+		// We send a ping every bybitPingInterval, so if no messages come for
+		// one minute, we are disconnected.
+		PingWait:      time.Minute,
+		ReconnectSync: func() {},
+		ConnectEventFunc: func(cs comms.ConnectionStatus) {
+			if cs != comms.Connected && cs != comms.Disconnected {
+				return
+			}
+
+			if cs == comms.Connected && initialConnect {
+				initialConnect = false
+			} else if cs == comms.Connected {
+				if err := c.start(); err != nil {
+					c.log.Errorf("Error resubscribing after reconnect: %v", err)
+				}
+			} else { // Disconnected
+				c.setSynced(false)
+			}
+		},
+		Logger:     c.log,
+		RawHandler: c.handleWebsocketMessage,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating WsConn: %w", err)
+	}
+
+	cm := dex.NewConnectionMaster(conn)
+	if err := cm.ConnectOnce(ctx); err != nil {
+		return nil, fmt.Errorf("error connecting to websocket feed: %w", err)
+	}
+
+	c.wsConn = conn
+
+	if err := c.start(); err != nil {
+		cm.Disconnect()
+		return nil, err
+	}
+
+	var wg sync.WaitGroup
+
+	// Bybit recommends sending a ping every 20 seconds to keep the
+	// connection alive.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(bybitPingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := c.send(&bbtypes.WsRequest{Op: "ping"}); err != nil {
+					c.log.Debugf("Error sending ping: %v", err)
+				}
+			}
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-ctx.Done()
+		cm.Disconnect()
+	}()
+
+	return &wg, nil
+}
+
+// bybitBook maintains the order book for a single Bybit market using its own
+// websocket connection.
+type bybitBook struct {
+	mtx            sync.RWMutex
+	numSubscribers uint32
+
+	cm     *dex.ConnectionMaster
+	conn   *bybitWSConn
+	synced atomic.Bool
+	symbol string
+	wsURL  string
+	book   *orderbook
+	bui    *dex.UnitInfo
+	qui    *dex.UnitInfo
+	log    dex.Logger
+
+	// updateID is the update ID of the last message applied to the book. It
+	// is only accessed by the websocket message handler.
+	updateID int64
+}
+
+func newBybitBook(wsURL, symbol string, bui, qui *dex.UnitInfo, log dex.Logger) *bybitBook {
+	return &bybitBook{
+		wsURL:  wsURL,
+		symbol: symbol,
+		book:   newOrderBook(),
+		bui:    bui,
+		qui:    qui,
+		log:    log.SubLogger(symbol),
+	}
+}
+
+func (b *bybitBook) topic() string {
+	return fmt.Sprintf("orderbook.%d.%s", bybitBookDepth, b.symbol)
+}
+
+func (b *bybitBook) convertLevels(levels [][]json.Number) ([]*obEntry, error) {
+	entries := make([]*obEntry, 0, len(levels))
+	for _, level := range levels {
+		if len(level) < 2 {
+			return nil, fmt.Errorf("invalid level %v", level)
+		}
+		price, err := level[0].Float64()
+		if err != nil {
+			return nil, fmt.Errorf("error parsing price %q: %w", level[0], err)
+		}
+		qty, err := level[1].Float64()
+		if err != nil {
+			return nil, fmt.Errorf("error parsing qty %q: %w", level[1], err)
+		}
+		entries = append(entries, &obEntry{
+			qty:  toAtomic(qty, b.bui),
+			rate: messageRate(price, b.bui, b.qui),
+		})
+	}
+	return entries, nil
+}
+
+func (b *bybitBook) subscriptions(subscribe bool) []*bbtypes.WsRequest {
+	op := "subscribe"
+	if !subscribe {
+		op = "unsubscribe"
+	}
+	return []*bbtypes.WsRequest{{
+		Op:   op,
+		Args: []interface{}{b.topic()},
+	}}
+}
+
+// resync unsubscribes from the orderbook topic and subscribes again, which
+// causes Bybit to send a new snapshot.
+func (b *bybitBook) resync() {
+	b.synced.Store(false)
+	for _, req := range b.subscriptions(false) {
+		if err := b.conn.send(req); err != nil {
+			b.log.Errorf("Error unsubscribing from book: %v", err)
+		}
+	}
+	if err := b.conn.subscribe(); err != nil {
+		b.log.Errorf("Error resubscribing to book: %v", err)
+	}
+}
+
+// handleBookMessage applies an orderbook topic message to the book. Every
+// delta increments the update ID by one, so a gap means an update was
+// missed.
+func (b *bybitBook) handleBookMessage(msg *bbtypes.WsMessage) {
+	if msg.Topic != b.topic() {
+		return
+	}
+
+	var d bbtypes.BookData
+	if err := json.Unmarshal(msg.Data, &d); err != nil {
+		b.log.Errorf("Error unmarshaling book data: %v", err)
+		return
+	}
+
+	// A snapshot with update ID 1 may also arrive as a delta after a
+	// service restart, and must replace the book.
+	snapshot := msg.Type == "snapshot" || d.UpdateID == 1
+	if !snapshot {
+		if !b.synced.Load() {
+			// Waiting for a snapshot after a resync.
+			return
+		}
+		if d.UpdateID != b.updateID+1 {
+			b.log.Errorf("Book update ID gap. Expected %d, got %d. Resyncing.", b.updateID+1, d.UpdateID)
+			b.resync()
+			return
+		}
+	}
+	bids, err := b.convertLevels(d.Bids)
+	if err != nil {
+		b.log.Errorf("Error converting bids: %v", err)
+		b.resync()
+		return
+	}
+	asks, err := b.convertLevels(d.Asks)
+	if err != nil {
+		b.log.Errorf("Error converting asks: %v", err)
+		b.resync()
+		return
+	}
+
+	if snapshot {
+		b.book.clear()
+	}
+	b.book.update(bids, asks)
+	b.updateID = d.UpdateID
+
+	if snapshot && !b.synced.Load() {
+		b.log.Infof("Book synced")
+		b.synced.Store(true)
+	}
+}
+
+func (b *bybitBook) midGap() (uint64, error) {
+	if !b.synced.Load() {
+		return 0, ErrUnsyncedOrderbook
+	}
+
+	return b.book.midGap(), nil
+}
+
+func (b *bybitBook) vwap(bids bool, qty uint64) (vwap, extrema uint64, filled bool, err error) {
+	if !b.synced.Load() {
+		return 0, 0, false, ErrUnsyncedOrderbook
+	}
+
+	vwap, extrema, filled = b.book.vwap(bids, qty)
+	return
+}
+
+func (b *bybitBook) invVWAP(bids bool, qty uint64) (vwap, extrema uint64, filled bool, err error) {
+	if !b.synced.Load() {
+		return 0, 0, false, ErrUnsyncedOrderbook
+	}
+
+	vwap, extrema, filled = b.book.invVWAP(bids, qty)
+	return
+}
+
+func (b *bybitBook) Connect(ctx context.Context) (*sync.WaitGroup, error) {
+	subs := func() []*bbtypes.WsRequest {
+		return b.subscriptions(true)
+	}
+	b.conn = newBybitWSConn(b.wsURL, nil, subs, b.handleBookMessage, b.synced.Store, b.log)
+	wsCM := dex.NewConnectionMaster(b.conn)
+	if err := wsCM.ConnectOnce(ctx); err != nil {
+		return nil, fmt.Errorf("error connecting to websocket feed: %w", err)
+	}
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-ctx.Done()
+		wsCM.Disconnect()
+	}()
+
+	return &wg, nil
+}
+
+func (b *bybitBook) sync(ctx context.Context) error {
+	cm := dex.NewConnectionMaster(b)
+	b.mtx.Lock()
+	b.cm = cm
+	b.numSubscribers++
+	b.mtx.Unlock()
+	return cm.ConnectOnce(ctx)
+}
+
+type bybit struct {
+	log          dex.Logger
+	url          string
+	publicWsURL  string
+	privateWsURL string
+	apiKey       string
+	secretKey    string
+	net          dex.Network
+	broadcast    func(interface{})
+	ctx          context.Context
+
+	// coinIDs maps the Bybit coins to the DEX asset IDs.
+	coinIDs map[string][]uint32
+	// idCoin maps the DEX asset IDs to the Bybit coins.
+	idCoin map[uint32]string
+
+	tradeIDNonce       atomic.Uint32
+	tradeIDNoncePrefix dex.Bytes
+
+	instruments atomic.Value // map[string]*bbtypes.Instrument, BASE/QUOTE -> instrument
+
+	marketSnapshotMtx sync.RWMutex
+	marketSnapshot    struct {
+		stamp time.Time
+		m     map[string]*Market
+	}
+
+	balanceMtx sync.RWMutex
+	balances   map[uint32]*ExchangeBalance
+
+	// subMarketMtx must be held while subscribing or unsubscribing to a
+	// market.
+	subMarketMtx sync.Mutex
+
+	booksMtx sync.RWMutex
+	books    map[string]*bybitBook
+
+	tradeUpdaterMtx    sync.RWMutex
+	tradeInfo          map[string]*tradeInfo // keyed by order link ID
+	tradeUpdaters      map[int]chan *Trade
+	tradeUpdateCounter int
+}
+
+var _ CEX = (*bybit)(nil)
+
+func newBybit(cfg *CEXConfig) (*bybit, error) {
+	if cfg.Net != dex.Mainnet {
+		return nil, fmt.Errorf("bybit is only supported on mainnet")
+	}
+
+	coinIDs := make(map[string][]uint32)
+	idCoin := make(map[uint32]string)
+	addAsset := func(assetID uint32, symbol string) {
+		coin := bybitCoin(symbol)
+		coinIDs[coin] = append(coinIDs[coin], assetID)
+		idCoin[assetID] = coin
+	}
+	for _, a := range asset.Assets() {
+		addAsset(a.ID, a.Symbol)
+		for tokenID := range a.Tokens {
+			if _, supported := supportedBybitTokens[tokenID]; supported {
+				addAsset(tokenID, dex.BipIDSymbol(tokenID))
+			}
+		}
+	}
+
+	bb := &bybit{
+		log:                cfg.Logger,
+		url:                bybitHTTPURL,
+		publicWsURL:        bybitPublicWsURL,
+		privateWsURL:       bybitPrivateWsURL,
+		apiKey:             cfg.APIKey,
+		secretKey:          cfg.SecretKey,
+		net:                cfg.Net,
+		broadcast:          cfg.Notify,
+		coinIDs:            coinIDs,
+		idCoin:             idCoin,
+		tradeIDNoncePrefix: encode.RandomBytes(12),
+		balances:           make(map[uint32]*ExchangeBalance),
+		books:              make(map[string]*bybitBook),
+		tradeInfo:          make(map[string]*tradeInfo),
+		tradeUpdaters:      make(map[int]chan *Trade),
+	}
+	bb.instruments.Store(make(map[string]*bbtypes.Instrument))
+	return bb, nil
+}
+
+func (bb *bybit) getAPI(ctx context.Context, endpoint string, query url.Values, private bool, thing interface{}) error {
+	return bb.request(ctx, http.MethodGet, endpoint, query, nil, private, thing)
+}
+
+func (bb *bybit) postAPI(ctx context.Context, endpoint string, body interface{}, thing interface{}) error {
+	return bb.request(ctx, http.MethodPost, endpoint, nil, body, true, thing)
+}
+
+// request sends a request to the Bybit REST API. The result of the response
+// is unmarshaled into thing.
+func (bb *bybit) request(ctx context.Context, method, endpoint string, query url.Values, body interface{}, private bool, thing interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*30)
+	defer cancel()
+
+	var queryString string
+	fullURL := bb.url + endpoint
+	if len(query) > 0 {
+		queryString = query.Encode()
+		fullURL += "?" + queryString
+	}
+
+	var bodyB []byte
+	var bodyReader io.Reader
+	if body != nil {
+		var err error
+		if bodyB, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("error marshaling request body: %w", err)
+		}
+		bodyReader = bytes.NewReader(bodyB)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, fullURL, bodyReader)
+	if err != nil {
+		return fmt.Errorf("error generating http request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if private {
+		ts := strconv.FormatInt(time.Now().UnixMilli(), 10)
+		payload := queryString
+		if method == http.MethodPost {
+			payload = string(bodyB)
+		}
+		req.Header.Set("X-BAPI-API-KEY", bb.apiKey)
+		req.Header.Set("X-BAPI-TIMESTAMP", ts)
+		req.Header.Set("X-BAPI-RECV-WINDOW", bybitRecvWindow)
+		req.Header.Set("X-BAPI-SIGN", bybitSignature(ts+bb.apiKey+bybitRecvWindow+payload, bb.secretKey))
+	}
+
+	var resp bbtypes.Response
+	var errCode int
+	if err := dexnet.Do(req, &resp, dexnet.WithStatusFunc(func(code int) { errCode = code })); err != nil {
+		return fmt.Errorf("%s %s error (%d): %w", method, endpoint, errCode, err)
+	}
+	if resp.RetCode != 0 {
+		return fmt.Errorf("%s %s error: %w", method, endpoint, &BybitError{Code: resp.RetCode, Msg: resp.RetMsg})
+	}
+	if thing == nil {
+		return nil
+	}
+	if err := json.Unmarshal(resp.Result, thing); err != nil {
+		return fmt.Errorf("error unmarshaling %s result: %w", endpoint, err)
+	}
+	return nil
+}
+
+// parseBybitInstrument fills in the fields of the instrument that are
+// expressed in the atomic units of the DEX assets. The base precision is
+// the lot size, and the quote precision is the step of the quote quantity
+// of market buys.
+func parseBybitInstrument(inst *bbtypes.Instrument, bui, qui *dex.UnitInfo) {
+	bFactor := float64(bui.Conventional.ConversionFactor)
+	qFactor := float64(qui.Conventional.ConversionFactor)
+	var basePrecision, quotePrecision, minQty, minAmt string
+	if f := inst.LotSizeFilter; f != nil {
+		basePrecision, quotePrecision, minQty, minAmt = f.BasePrecision, f.QuotePrecision, f.MinOrderQty, f.MinOrderAmt
+	}
+	var tickSize string
+	if f := inst.PriceFilter; f != nil {
+		tickSize = f.TickSize
+	}
+	inst.LotSize = uint64(math.Max(1, math.Round(bybitFloat(basePrecision)*bFactor)))
+	inst.MinQty = uint64(math.Round(bybitFloat(minQty) * bFactor))
+	inst.MinCost = uint64(math.Round(bybitFloat(minAmt) * qFactor))
+	inst.RateStep = uint64(math.Max(1, float64(messageRate(bybitFloat(tickSize), bui, qui))))
+	inst.PriceDecimals = bybitDecimals(tickSize)
+	inst.SizeDecimals = bybitDecimals(basePrecision)
+	inst.QuoteDecimals = bybitDecimals(quotePrecision)
+}
+
+func (bb *bybit) updateMarkets(ctx context.Context) (map[string]*Market, error) {
+	q := url.Values{"category": []string{bybitSpotCategory}}
+	var res bbtypes.InstrumentsResult
+	if err := bb.getAPI(ctx, "/v5/market/instruments-info", q, false, &res); err != nil {
+		return nil, fmt.Errorf("error fetching instruments: %w", err)
+	}
+
+	instruments := make(map[string]*bbtypes.Instrument, len(res.List))
+	dexMarkets := make(map[string][][2]uint32, len(res.List))
+	for _, inst := range res.List {
+		if inst.Status != "Trading" {
+			continue
+		}
+		baseIDs, quoteIDs := bb.coinIDs[inst.BaseCoin], bb.coinIDs[inst.QuoteCoin]
+		if len(baseIDs) == 0 || len(quoteIDs) == 0 {
+			continue
+		}
+		bui, err := asset.UnitInfo(baseIDs[0])
+		if err != nil {
+			continue
+		}
+		qui, err := asset.UnitInfo(quoteIDs[0])
+		if err != nil {
+			continue
+		}
+		parseBybitInstrument(inst, &bui, &qui)
+		instruments[inst.BaseCoin+"/"+inst.QuoteCoin] = inst
+		for _, baseID := range baseIDs {
+			for _, quoteID := range quoteIDs {
+				dexMarkets[inst.Symbol] = append(dexMarkets[inst.Symbol], [2]uint32{baseID, quoteID})
+			}
+		}
+	}
+
+	tickers := make(map[string]*bbtypes.Ticker)
+	var tickerRes bbtypes.TickersResult
+	if err := bb.getAPI(ctx, "/v5/market/tickers", q, false, &tickerRes); err != nil {
+		bb.log.Errorf("Error fetching tickers: %v", err)
+	}
+	for _, t := range tickerRes.List {
+		tickers[t.Symbol] = t
+	}
+
+	mins, err := bb.minWithdraws(ctx)
+	if err != nil {
+		bb.log.Errorf("Error fetching coin info: %v", err)
+	}
+
+	markets := make(map[string]*Market, len(dexMarkets))
+	for symbol, mkts := range dexMarkets {
+		var day *MarketDay
+		if t := tickers[symbol]; t != nil {
+			day = bybitMarketDay(t)
+		}
+		for _, m := range mkts {
+			markets[dex.BipIDSymbol(m[0])+"_"+dex.BipIDSymbol(m[1])] = &Market{
+				BaseID:           m[0],
+				QuoteID:          m[1],
+				Day:              day,
+				BaseMinWithdraw:  mins[m[0]],
+				QuoteMinWithdraw: mins[m[1]],
+			}
+		}
+	}
+
+	bb.instruments.Store(instruments)
+
+	bb.marketSnapshotMtx.Lock()
+	defer bb.marketSnapshotMtx.Unlock()
+	bb.marketSnapshot.m = markets
+	bb.marketSnapshot.stamp = time.Now()
+	return markets, nil
+}
+
+// minWithdraws returns the minimum withdrawal amounts of the DEX assets, in
+// atomic units. Bybit deducts the fee from the withdrawal amount, so the
+// amount must also exceed the fee.
+func (bb *bybit) minWithdraws(ctx context.Context) (map[uint32]uint64, error) {
+	var info bbtypes.CoinInfoResult
+	if err := bb.getAPI(ctx, "/v5/asset/coin/query-info", nil, true, &info); err != nil {
+		return nil, err
+	}
+	type coinChain struct{ coin, chain string }
+	chains := make(map[coinChain]*bbtypes.Chain)
+	for _, row := range info.Rows {
+		for _, ch := range row.Chains {
+			chains[coinChain{row.Coin, ch.Chain}] = ch
+		}
+	}
+	mins := make(map[uint32]uint64, len(bb.idCoin))
+	for assetID := range bb.idCoin {
+		coin, chain, err := bb.coinAndChain(assetID)
+		if err != nil {
+			continue
+		}
+		ch := chains[coinChain{coin, chain}]
+		if ch == nil || ch.ChainWithdraw != "1" {
+			continue
+		}
+		ui, err := asset.UnitInfo(assetID)
+		if err != nil {
+			continue
+		}
+		minWd := toAtomic(bybitFloat(ch.WithdrawMin), &ui)
+		if fee := toAtomic(bybitFloat(ch.WithdrawFee), &ui); minWd <= fee {
+			minWd = fee + 1
+		}
+		mins[assetID] = minWd
+	}
+	return mins, nil
+}
+
+func bybitMarketDay(t *bbtypes.Ticker) *MarketDay {
+	last, open := bybitFloat(t.LastPrice), bybitFloat(t.PrevPrice24h)
+	vol, quoteVol := bybitFloat(t.Volume24h), bybitFloat(t.Turnover24h)
+	var pctChange, avg float64
+	if open > 0 {
+		pctChange = (last - open) / open * 100
+	}
+	if vol > 0 {
+		avg = quoteVol / vol
+	}
+	return &MarketDay{
+		Vol:            vol,
+		QuoteVol:       quoteVol,
+		PriceChange:    last - open,
+		PriceChangePct: pctChange,
+		AvgPrice:       avg,
+		LastPrice:      last,
+		OpenPrice:      open,
+		HighPrice:      bybitFloat(t.HighPrice24h),
+		LowPrice:       bybitFloat(t.LowPrice24h),
+	}
+}
+
+func (bb *bybit) instrument(baseID, quoteID uint32) (*bbtypes.Instrument, error) {
+	baseCoin, found := bb.idCoin[baseID]
+	if !found {
+		return nil, fmt.Errorf("no Bybit coin for %s", dex.BipIDSymbol(baseID))
+	}
+	quoteCoin, found := bb.idCoin[quoteID]
+	if !found {
+		return nil, fmt.Errorf("no Bybit coin for %s", dex.BipIDSymbol(quoteID))
+	}
+	instruments := bb.instruments.Load().(map[string]*bbtypes.Instrument)
+	inst, found := instruments[baseCoin+"/"+quoteCoin]
+	if !found {
+		return nil, fmt.Errorf("no Bybit market for %s/%s", baseCoin, quoteCoin)
+	}
+	return inst, nil
+}
+
+// updateBalances updates the balances of the coins. If replace is true,
+// coins are the balances of all coins, and the balances of any coins that
+// are not included are zeroed.
+func (bb *bybit) updateBalances(coins []*bbtypes.CoinBalance, replace bool) {
+	balances := make(map[uint32]*ExchangeBalance)
+	for _, c := range coins {
+		for _, assetID := range bb.coinIDs[c.Coin] {
+			ui, err := asset.UnitInfo(assetID)
+			if err != nil {
+				bb.log.Errorf("no unit info for known asset ID %d?", assetID)
+				continue
+			}
+			total := toAtomic(bybitFloat(c.WalletBalance), &ui)
+			locked := toAtomic(bybitFloat(c.Locked), &ui)
+			balances[assetID] = &ExchangeBalance{
+				Available: utils.SafeSub(total, locked),
+				Locked:    locked,
+			}
+		}
+	}
+
+	updates := make([]*BalanceUpdate, 0)
+	bb.balanceMtx.Lock()
+	if replace {
+		for assetID := range bb.balances {
+			if _, found := balances[assetID]; !found {
+				balances[assetID] = &ExchangeBalance{}
+			}
+		}
+	}
+	for assetID, newBal := range balances {
+		if oldBal := bb.balances[assetID]; oldBal == nil || *oldBal != *newBal {
+			updates = append(updates, &BalanceUpdate{
+				AssetID: assetID,
+				Balance: newBal,
+			})
+		}
+		bb.balances[assetID] = newBal
+	}
+	bb.balanceMtx.Unlock()
+
+	for _, u := range updates {
+		bb.broadcast(u)
+	}
+}
+
+func (bb *bybit) refreshBalances(ctx context.Context) error {
+	var res bbtypes.WalletBalanceResult
+	if err := bb.getAPI(ctx, "/v5/account/wallet-balance", url.Values{"accountType": []string{bybitUnifiedWallet}}, true, &res); err != nil {
+		return err
+	}
+	var coins []*bbtypes.CoinBalance
+	for _, w := range res.List {
+		coins = append(coins, w.Coin...)
+	}
+	bb.updateBalances(coins, true)
+	return nil
+}
+
+func (bb *bybit) handleUserMessage(msg *bbtypes.WsMessage) {
+	switch msg.Topic {
+	case bybitOrderTopic:
+		var ords []*bbtypes.Order
+		if err := json.Unmarshal(msg.Data, &ords); err != nil {
+			bb.log.Errorf("Error unmarshaling orders: %v", err)
+			return
+		}
+		for _, ord := range ords {
+			if ord.Category == bybitSpotCategory {
+				bb.handleOrderUpdate(ord)
+			}
+		}
+	case bybitWalletTopic:
+		var wallets []*bbtypes.WalletBalance
+		if err := json.Unmarshal(msg.Data, &wallets); err != nil {
+			bb.log.Errorf("Error unmarshaling wallet: %v", err)
+			return
+		}
+		for _, w := range wallets {
+			if w.AccountType == bybitUnifiedWallet {
+				bb.updateBalances(w.Coin, false)
+			}
+		}
+	default:
+		bb.log.Debugf("Message for unknown topic %q", msg.Topic)
+	}
+}
+
+func bybitOrderComplete(status string) bool {
+	switch status {
+	case "Filled", "Cancelled", "PartiallyFilledCanceled", "Rejected", "Deactivated":
+		return true
+	}
+	return false
+}
+
+// bybitOrderFills returns the amounts of the base and quote assets that have
+// been received and spent by an order. For spot orders, Bybit charges the fee
+// in the asset that is received.
+func bybitOrderFills(ord *bbtypes.Order, bui, qui *dex.UnitInfo) (baseFilled, quoteFilled uint64) {
+	baseFilled = toAtomic(bybitFloat(ord.CumExecQty), bui)
+	quoteFilled = toAtomic(bybitFloat(ord.CumExecValue), qui)
+	fee := bybitFloat(ord.CumExecFee)
+	if ord.Side == "Sell" {
+		quoteFilled = utils.SafeSub(quoteFilled, toAtomic(fee, qui))
+	} else {
+		baseFilled = utils.SafeSub(baseFilled, toAtomic(fee, bui))
+	}
+	return
+}
+
+func (bb *bybit) handleOrderUpdate(ord *bbtypes.Order) {
+	if ord.OrderLinkID == "" {
+		// Not placed by us.
+		return
+	}
+
+	bb.tradeUpdaterMtx.Lock()
+	defer bb.tradeUpdaterMtx.Unlock()
+
+	info, found := bb.tradeInfo[ord.OrderLinkID]
+	if !found {
+		bb.log.Debugf("No trade info for order link ID %s", ord.OrderLinkID)
+		return
+	}
+
+	updater, found := bb.tradeUpdaters[info.updaterID]
+	if !found {
+		bb.log.Errorf("No trade updater with ID %v", info.updaterID)
+		return
+	}
+
+	bui, err := asset.UnitInfo(info.baseID)
+	if err != nil {
+		bb.log.Errorf("Error getting unit info for asset ID %d: %v", info.baseID, err)
+		return
+	}
+	qui, err := asset.UnitInfo(info.quoteID)
+	if err != nil {
+		bb.log.Errorf("Error getting unit info for asset ID %d: %v", info.quoteID, err)
+		return
+	}
+
+	baseFilled, quoteFilled := bybitOrderFills(ord, &bui, &qui)
+	complete := bybitOrderComplete(ord.OrderStatus)
+	updater <- &Trade{
+		ID:          ord.OrderID,
+		Sell:        info.sell,
+		Rate:        info.rate,
+		Qty:         info.qty,
+		Market:      info.market,
+		BaseID:      info.baseID,
+		QuoteID:     info.quoteID,
+		BaseFilled:  baseFilled,
+		QuoteFilled: quoteFilled,
+		Complete:    complete,
+	}
+
+	if complete {
+		delete(bb.tradeInfo, ord.OrderLinkID)
+	}
+}
+
+// authRequest generates a websocket authentication request. The signature
+// is of "GET/realtime" and the expiry time in milliseconds.
+func (bb *bybit) authRequest() *bbtypes.WsRequest {
+	expires := strconv.FormatInt(time.Now().Add(time.Second*10).UnixMilli(), 10)
+	return &bbtypes.WsRequest{
+		Op:   "auth",
+		Args: []interface{}{bb.apiKey, expires, bybitSignature("GET/realtime"+expires, bb.secretKey)},
+	}
+}
+
+func (bb *bybit) subscribeUserTopics(ctx context.Context) (*sync.WaitGroup, error) {
+	subs := func() []*bbtypes.WsRequest {
+		return []*bbtypes.WsRequest{{
+			Op:   "subscribe",
+			Args: []interface{}{bybitOrderTopic, bybitWalletTopic},
+		}}
+	}
+	conn := newBybitWSConn(bb.privateWsURL, bb.authRequest, subs, bb.handleUserMessage, func(bool) {}, bb.log.SubLogger("WS-private"))
+	cm := dex.NewConnectionMaster(conn)
+	if err := cm.ConnectOnce(ctx); err != nil {
+		return nil, fmt.Errorf("error connecting to websocket feed: %w", err)
+	}
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-ctx.Done()
+		cm.Disconnect()
+	}()
+
+	return &wg, nil
+}
+
+func (bb *bybit) Connect(ctx context.Context) (*sync.WaitGroup, error) {
+	if _, err := bb.updateMarkets(ctx); err != nil {
+		return nil, fmt.Errorf("error fetching markets: %w", err)
+	}
+
+	if err := bb.refreshBalances(ctx); err != nil {
+		return nil, fmt.Errorf("error fetching balances: %w", err)
+	}
+
+	bb.ctx = ctx
+
+	wg, err := bb.subscribeUserTopics(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error subscribing to user topics: %w", err)
+	}
+
+	// The wallet topic pushes balance changes, but refresh the balances
+	// periodically in case an update is missed.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := bb.refreshBalances(ctx); err != nil {
+					bb.log.Errorf("Error refreshing balances: %v", err)
+				}
+			}
+		}
+	}()
+
+	// Update markets every 10 minutes. These shouldn't change often.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(time.Minute * 10)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := bb.updateMarkets(ctx); err != nil {
+					bb.log.Errorf("Error fetching markets: %v", err)
+				}
+			}
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-ctx.Done()
+
+		bb.booksMtx.RLock()
+		defer bb.booksMtx.RUnlock()
+		for _, book := range bb.books {
+			book.cm.Disconnect()
+		}
+	}()
+
+	return wg, nil
+}
+
+// Balance returns the balance of an asset at the CEX.
+func (bb *bybit) Balance(assetID uint32) (*ExchangeBalance, error) {
+	bb.balanceMtx.RLock()
+	defer bb.balanceMtx.RUnlock()
+
+	bal, found := bb.balances[assetID]
+	if !found {
+		return nil, fmt.Errorf("no %s balance found", dex.BipIDSymbol(assetID))
+	}
+	return bal, nil
+}
+
+// Balances returns the balances of known assets on the CEX.
+func (bb *bybit) Balances(ctx context.Context) (map[uint32]*ExchangeBalance, error) {
+	if err := bb.refreshBalances(ctx); err != nil {
+		return nil, err
+	}
+
+	bb.balanceMtx.RLock()
+	defer bb.balanceMtx.RUnlock()
+
+	balances := make(map[uint32]*ExchangeBalance, len(bb.balances))
+	for assetID, bal := range bb.balances {
+		b := *bal
+		balances[assetID] = &b
+	}
+	return balances, nil
+}
+
+// CancelTrade cancels a trade on the CEX.
+func (bb *bybit) CancelTrade(ctx context.Context, baseID, quoteID uint32, tradeID string) error {
+	inst, err := bb.instrument(baseID, quoteID)
+	if err != nil {
+		return err
+	}
+	req := &bbtypes.CancelOrderRequest{Category: bybitSpotCategory, Symbol: inst.Symbol, OrderID: tradeID}
+	var res bbtypes.OrderResult
+	if err := bb.postAPI(ctx, "/v5/order/cancel", req, &res); err != nil {
+		return fmt.Errorf("error cancelling order: %w", err)
+	}
+	if res.OrderID != tradeID {
+		return fmt.Errorf("order %s not cancelled", tradeID)
+	}
+	return nil
+}
+
+// Markets returns the list of markets at the CEX.
+func (bb *bybit) Markets(ctx context.Context) (map[string]*Market, error) {
+	bb.marketSnapshotMtx.RLock()
+	const snapshotTimeout = time.Minute * 30
+	if bb.marketSnapshot.m != nil && time.Since(bb.marketSnapshot.stamp) < snapshotTimeout {
+		defer bb.marketSnapshotMtx.RUnlock()
+		return bb.marketSnapshot.m, nil
+	}
+	bb.marketSnapshotMtx.RUnlock()
+
+	return bb.updateMarkets(ctx)
+}
+
+// SubscribeMarket subscribes to order book updates on a market. This must
+// be called before calling VWAP or MidGap.
+func (bb *bybit) SubscribeMarket(ctx context.Context, baseID, quoteID uint32) error {
+	bb.subMarketMtx.Lock()
+	defer bb.subMarketMtx.Unlock()
+
+	inst, err := bb.instrument(baseID, quoteID)
+	if err != nil {
+		return err
+	}
+	bui, err := asset.UnitInfo(baseID)
+	if err != nil {
+		return fmt.Errorf("error getting unit info for base asset ID %d: %v", baseID, err)
+	}
+	qui, err := asset.UnitInfo(quoteID)
+	if err != nil {
+		return fmt.Errorf("error getting unit info for quote asset ID %d: %v", quoteID, err)
+	}
+
+	bb.booksMtx.RLock()
+	book, exists := bb.books[inst.Symbol]
+	bb.booksMtx.RUnlock()
+	if exists {
+		book.mtx.Lock()
+		book.numSubscribers++
+		book.mtx.Unlock()
+		return nil
+	}
+
+	book = newBybitBook(bb.publicWsURL, inst.Symbol, &bui, &qui, bb.log)
+	if err := book.sync(bb.ctx); err != nil {
+		return fmt.Errorf("error syncing book: %v", err)
+	}
+
+	bb.booksMtx.Lock()
+	bb.books[inst.Symbol] = book
+	bb.booksMtx.Unlock()
+
+	return nil
+}
+
+// SubscribeTradeUpdates returns a channel that the caller can use to
+// listen for updates to a trade's status. When the subscription ID
+// returned from this function is passed as the updaterID argument to
+// Trade, then updates to the trade will be sent on the updated channel
+// returned from this function.
+func (bb *bybit) SubscribeTradeUpdates() (<-chan *Trade, func(), int) {
+	bb.tradeUpdaterMtx.Lock()
+	defer bb.tradeUpdaterMtx.Unlock()
+
+	updaterID := bb.tradeUpdateCounter
+	bb.tradeUpdateCounter++
+	updater := make(chan *Trade, 256)
+	bb.tradeUpdaters[updaterID] = updater
+
+	unsubscribe := func() {
+		bb.tradeUpdaterMtx.Lock()
+		delete(bb.tradeUpdaters, updaterID)
+		bb.tradeUpdaterMtx.Unlock()
+	}
+
+	return updater, unsubscribe, updaterID
+}
+
+// generateTradeID generates an order link ID. Bybit accepts up to 36
+// characters as an order link ID.
+func (bb *bybit) generateTradeID() string {
+	nonce := bb.tradeIDNonce.Add(1)
+	nonceB := encode.Uint32Bytes(nonce)
+	return hex.EncodeToString(append(bb.tradeIDNoncePrefix, nonceB...))
+}
+
+// buildBybitOrderRequest builds an order request, and returns the quantity
+// of the order after it has been adjusted to the lot size.
+func buildBybitOrderRequest(inst *bbtypes.Instrument, bui, qui *dex.UnitInfo, sell bool, orderType OrderType, rate, qty, quoteQty uint64, tradeID string) (*bbtypes.OrderRequest, uint64, error) {
+	if qty > 0 && quoteQty > 0 {
+		return nil, 0, fmt.Errorf("cannot specify both quantity and quote quantity")
+	}
+	if sell && quoteQty > 0 {
+		return nil, 0, fmt.Errorf("quote quantity cannot be used for sell orders")
+	}
+	if !sell && orderType == OrderTypeMarket && qty > 0 {
+		return nil, 0, fmt.Errorf("quoteQty MUST be used for market buys")
+	}
+
+	bFactor := bui.Conventional.ConversionFactor
+	qFactor := qui.Conventional.ConversionFactor
+
+	req := &bbtypes.OrderRequest{
+		Category:    bybitSpotCategory,
+		Symbol:      inst.Symbol,
+		Side:        "Buy",
+		OrderLinkID: tradeID,
+	}
+	if sell {
+		req.Side = "Sell"
+	}
+
+	switch orderType {
+	case OrderTypeMarket:
+		req.OrderType = "Market"
+		if quoteQty > 0 {
+			// Market buys are specified in units of the quote asset.
+			req.MarketUnit = "quoteCoin"
+			quoteLot := uint64(math.Max(1, math.Round(math.Pow10(-inst.QuoteDecimals)*float64(qFactor))))
+			quoteQty = steppedQty(quoteQty, quoteLot)
+			if quoteQty == 0 || quoteQty < inst.MinCost {
+				return nil, 0, fmt.Errorf("quote quantity %s is lower than the minimum %s",
+					qui.FormatConventional(quoteQty), qui.FormatConventional(inst.MinCost))
+			}
+			req.Qty = strconv.FormatFloat(float64(quoteQty)/float64(qFactor), 'f', inst.QuoteDecimals, 64)
+			return req, quoteQty, nil
+		}
+		req.MarketUnit = "baseCoin"
+	default:
+		req.OrderType = "Limit"
+		req.TimeInForce = "GTC"
+		if orderType == OrderTypeLimitIOC {
+			req.TimeInForce = "IOC"
+		}
+		if rate == 0 {
+			return nil, 0, fmt.Errorf("rate must be specified for limit orders")
+		}
+		rate = steppedRate(rate, inst.RateStep)
+		convRate := calc.ConventionalRateAlt(rate, bFactor, qFactor)
+		req.Price = strconv.FormatFloat(convRate, 'f', inst.PriceDecimals, 64)
+		if quoteQty > 0 {
+			qty = calc.QuoteToBase(rate, quoteQty)
+		}
+	}
+
+	if qty == 0 {
+		return nil, 0, fmt.Errorf("must specify quantity or quote quantity")
+	}
+	qty = steppedQty(qty, inst.LotSize)
+	if qty < inst.MinQty {
+		return nil, 0, fmt.Errorf("quantity %s is lower than the minimum %s",
+			bui.FormatConventional(qty), bui.FormatConventional(inst.MinQty))
+	}
+	req.Qty = strconv.FormatFloat(float64(qty)/float64(bFactor), 'f', inst.SizeDecimals, 64)
+
+	return req, qty, nil
+}
+
+// Trade executes a trade on the CEX.
+//   - subscriptionID takes an ID returned from SubscribeTradeUpdates.
+//   - Rate is ignored for market orders.
+//   - Qty is in units of base asset, quoteQty is in units of quote asset.
+//     Only one of qty or quoteQty should be non-zero.
+//   - QuoteQty is only allowed for BUY orders, and it is required for market
+//     buy orders.
+func (bb *bybit) Trade(ctx context.Context, baseID, quoteID uint32, sell bool, rate, qty, quoteQty uint64, orderType OrderType, subscriptionID int) (*Trade, error) {
+	inst, err := bb.instrument(baseID, quoteID)
+	if err != nil {
+		return nil, err
+	}
+	bui, err := asset.UnitInfo(baseID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting unit info for base asset ID %d: %v", baseID, err)
+	}
+	qui, err := asset.UnitInfo(quoteID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting unit info for quote asset ID %d: %v", quoteID, err)
+	}
+
+	tradeID := bb.generateTradeID()
+	req, qtyInRequest, err := buildBybitOrderRequest(inst, &bui, &qui, sell, orderType, rate, qty, quoteQty, tradeID)
+	if err != nil {
+		return nil, fmt.Errorf("error building order request: %w", err)
+	}
+
+	// The trade info is stored before the order is placed, because the
+	// order updates may arrive before the response.
+	market := orderType == OrderTypeMarket
+	bb.tradeUpdaterMtx.Lock()
+	if _, found := bb.tradeUpdaters[subscriptionID]; !found {
+		bb.tradeUpdaterMtx.Unlock()
+		return nil, fmt.Errorf("no trade updater with ID %v", subscriptionID)
+	}
+	bb.tradeInfo[tradeID] = &tradeInfo{
+		updaterID: subscriptionID,
+		baseID:    baseID,
+		quoteID:   quoteID,
+		sell:      sell,
+		rate:      rate,
+		qty:       qtyInRequest,
+		market:    market,
+	}
+	bb.tradeUpdaterMtx.Unlock()
+
+	var res bbtypes.OrderResult
+	if err := bb.postAPI(ctx, "/v5/order/create", req, &res); err != nil {
+		bb.tradeUpdaterMtx.Lock()
+		delete(bb.tradeInfo, tradeID)
+		bb.tradeUpdaterMtx.Unlock()
+		return nil, err
+	}
+
+	return &Trade{
+		ID:      res.OrderID,
+		Sell:    sell,
+		Rate:    rate,
+		Qty:     qtyInRequest,
+		BaseID:  baseID,
+		QuoteID: quoteID,
+		Market:  market,
+	}, nil
+}
+
+// ValidateTrade validates a trade before it is executed.
+func (bb *bybit) ValidateTrade(baseID, quoteID uint32, sell bool, rate, qty, quoteQty uint64, orderType OrderType) error {
+	inst, err := bb.instrument(baseID, quoteID)
+	if err != nil {
+		return err
+	}
+	bui, err := asset.UnitInfo(baseID)
+	if err != nil {
+		return fmt.Errorf("error getting unit info for base asset ID %d: %v", baseID, err)
+	}
+	qui, err := asset.UnitInfo(quoteID)
+	if err != nil {
+		return fmt.Errorf("error getting unit info for quote asset ID %d: %v", quoteID, err)
+	}
+	_, _, err = buildBybitOrderRequest(inst, &bui, &qui, sell, orderType, rate, qty, quoteQty, "")
+	return err
+}
+
+// UnsubscribeMarket unsubscribes from order book updates on a market.
+func (bb *bybit) UnsubscribeMarket(baseID, quoteID uint32) error {
+	bb.subMarketMtx.Lock()
+	defer bb.subMarketMtx.Unlock()
+
+	inst, err := bb.instrument(baseID, quoteID)
+	if err != nil {
+		return err
+	}
+
+	bb.booksMtx.RLock()
+	book, found := bb.books[inst.Symbol]
+	bb.booksMtx.RUnlock()
+	if !found {
+		return fmt.Errorf("no book found for %s", inst.Symbol)
+	}
+
+	book.mtx.Lock()
+	book.numSubscribers--
+	numSubscribers := book.numSubscribers
+	book.mtx.Unlock()
+
+	if numSubscribers == 0 {
+		bb.booksMtx.Lock()
+		delete(bb.books, inst.Symbol)
+		bb.booksMtx.Unlock()
+		go book.cm.Disconnect()
+	}
+
+	return nil
+}
+
+func (bb *bybit) book(baseID, quoteID uint32) (*bybitBook, error) {
+	inst, err := bb.instrument(baseID, quoteID)
+	if err != nil {
+		return nil, err
+	}
+
+	bb.booksMtx.RLock()
+	book, found := bb.books[inst.Symbol]
+	bb.booksMtx.RUnlock()
+	if !found {
+		return nil, fmt.Errorf("no book for market %s", inst.Symbol)
+	}
+
+	return book, nil
+}
+
+// Book generates the CEX's current view of a market's orderbook.
+func (bb *bybit) Book(baseID, quoteID uint32) (buys, sells []*core.MiniOrder, _ error) {
+	book, err := bb.book(baseID, quoteID)
+	if err != nil {
+		return nil, nil, err
+	}
+	bids, asks := book.book.snap()
+	baseFactor := book.bui.Conventional.ConversionFactor
+	quoteFactor := book.qui.Conventional.ConversionFactor
+	buys = convertSide(bids, false, baseFactor, quoteFactor)
+	sells = convertSide(asks, true, baseFactor, quoteFactor)
+	return
+}
+
+// VWAP returns the volume weighted average price for a certain quantity
+// of the base asset on a market. SubscribeMarket must be called, and the
+// market must be synced before results can be expected.
+func (bb *bybit) VWAP(baseID, quoteID uint32, sell bool, qty uint64) (vwap, extrema uint64, filled bool, err error) {
+	book, err := bb.book(baseID, quoteID)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	return book.vwap(!sell, qty)
+}
+
+// InvVWAP returns the inverse volume weighted average price for a certain
+// quantity of the quote asset on a market. SubscribeMarket must be called,
+// and the market must be synced before results can be expected.
+func (bb *bybit) InvVWAP(baseID, quoteID uint32, sell bool, qty uint64) (vwap, extrema uint64, filled bool, err error) {
+	book, err := bb.book(baseID, quoteID)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	return book.invVWAP(!sell, qty)
+}
+
+// MidGap returns the mid-gap price for a market.
+func (bb *bybit) MidGap(baseID, quoteID uint32) uint64 {
+	book, err := bb.book(baseID, quoteID)
+	if err != nil {
+		bb.log.Errorf("Error getting book: %v", err)
+		return 0
+	}
+
+	midGap, err := book.midGap()
+	if err != nil {
+		bb.log.Errorf("Error getting mid gap: %v", err)
+		return 0
+	}
+
+	return midGap
+}
+
+// coinAndChain returns the Bybit coin and chain for the asset.
+func (bb *bybit) coinAndChain(assetID uint32) (coin, chain string, err error) {
+	coin, found := bb.idCoin[assetID]
+	if !found {
+		return "", "", fmt.Errorf("no Bybit coin for %s", dex.BipIDSymbol(assetID))
+	}
+	chain, err = bybitChain(assetID)
+	return coin, chain, err
+}
+
+// formatBybitAmount formats an amount for the funding endpoints.
+func formatBybitAmount(amt uint64, ui *dex.UnitInfo) string {
+	prec := int(math.Round(math.Log10(float64(ui.Conventional.ConversionFactor))))
+	return strconv.FormatFloat(toConv(amt, ui), 'f', prec, 64)
+}
+
+// transfer moves funds between the funding account, where deposits are
+// credited and withdrawals are sent from, and the unified trading account.
+func (bb *bybit) transfer(ctx context.Context, assetID uint32, amt uint64, from, to string) error {
+	coin, found := bb.idCoin[assetID]
+	if !found {
+		return fmt.Errorf("no Bybit coin for %s", dex.BipIDSymbol(assetID))
+	}
+	ui, err := asset.UnitInfo(assetID)
+	if err != nil {
+		return fmt.Errorf("error getting unit info for asset ID %d: %v", assetID, err)
+	}
+	req := &bbtypes.TransferRequest{
+		TransferID:      bybitTransferID(),
+		Coin:            coin,
+		Amount:          formatBybitAmount(amt, &ui),
+		FromAccountType: from,
+		ToAccountType:   to,
+	}
+	return bb.postAPI(ctx, "/v5/asset/transfer/inter-transfer", req, nil)
+}
+
+// GetDepositAddress returns a deposit address for an asset.
+func (bb *bybit) GetDepositAddress(ctx context.Context, assetID uint32) (string, error) {
+	coin, chain, err := bb.coinAndChain(assetID)
+	if err != nil {
+		return "", err
+	}
+
+	var res bbtypes.DepositAddressResult
+	q := url.Values{"coin": []string{coin}, "chainType": []string{chain}}
+	if err := bb.getAPI(ctx, "/v5/asset/deposit/query-address", q, true, &res); err != nil {
+		return "", fmt.Errorf("error fetching deposit address: %w", err)
+	}
+	for _, c := range res.Chains {
+		if c.Chain == chain && c.AddressDeposit != "" {
+			return c.AddressDeposit, nil
+		}
+	}
+
+	return "", fmt.Errorf("no deposit address returned for %s on %s", dex.BipIDSymbol(assetID), chain)
+}
+
+// ConfirmDeposit checks whether a deposit has been credited and returns the
+// amount credited to the account. Deposits are credited to the funding
+// account, so once the deposit is complete, the amount is transferred to the
+// unified trading account.
+func (bb *bybit) ConfirmDeposit(ctx context.Context, deposit *DepositData) (bool, uint64) {
+	coin, chain, err := bb.coinAndChain(deposit.AssetID)
+	if err != nil {
+		bb.log.Errorf("Error confirming deposit %s: %v", deposit.TxID, err)
+		return false, 0
+	}
+
+	var res bbtypes.DepositsResult
+	q := url.Values{"coin": []string{coin}, "txID": []string{deposit.TxID}}
+	if err := bb.getAPI(ctx, "/v5/asset/deposit/query-record", q, true, &res); err != nil {
+		bb.log.Errorf("Error getting deposit status for %s: %v", deposit.TxID, err)
+		return false, 0
+	}
+	var dep *bbtypes.Deposit
+	for _, d := range res.Rows {
+		if d.TxID == deposit.TxID && d.Chain == chain {
+			dep = d
+			break
+		}
+	}
+	if dep == nil {
+		return false, 0
+	}
+	if dep.Status == bbtypes.DepositStatusFailed {
+		bb.log.Errorf("Deposit %s failed", deposit.TxID)
+		return true, 0
+	}
+	if dep.Status != bbtypes.DepositStatusSuccess {
+		return false, 0
+	}
+
+	ui, err := asset.UnitInfo(deposit.AssetID)
+	if err != nil {
+		bb.log.Errorf("Failed to find unit info for asset ID %d", deposit.AssetID)
+		return true, 0
+	}
+	amt := toAtomic(bybitFloat(dep.Amount), &ui)
+	if err := bb.transfer(ctx, deposit.AssetID, amt, bbtypes.AccountFund, bbtypes.AccountUnified); err != nil {
+		// The transfer will be retried the next time the deposit is checked.
+		bb.log.Errorf("Error transferring deposit %s to the trading account: %v", deposit.TxID, err)
+		return false, 0
+	}
+
+	return true, amt
+}
+
+// Withdraw withdraws funds from the CEX to a certain address. The
+// withdrawal fee is deducted from the withdrawn amount, so the balance is
+// reduced by exactly amt. The funds are transferred from the unified trading
+// account to the funding account first.
+func (bb *bybit) Withdraw(ctx context.Context, assetID uint32, amt uint64, address string) (string, uint64, error) {
+	coin, chain, err := bb.coinAndChain(assetID)
+	if err != nil {
+		return "", 0, err
+	}
+
+	ui, err := asset.UnitInfo(assetID)
+	if err != nil {
+		return "", 0, fmt.Errorf("error getting unit info for asset ID %d: %v", assetID, err)
+	}
+
+	var info bbtypes.CoinInfoResult
+	if err := bb.getAPI(ctx, "/v5/asset/coin/query-info", url.Values{"coin": []string{coin}}, true, &info); err != nil {
+		return "", 0, fmt.Errorf("error fetching coin info: %w", err)
+	}
+	var c *bbtypes.Chain
+	for _, row := range info.Rows {
+		if row.Coin != coin {
+			continue
+		}
+		for _, ch := range row.Chains {
+			if ch.Chain == chain {
+				c = ch
+				break
+			}
+		}
+	}
+	if c == nil || c.ChainWithdraw != "1" {
+		return "", 0, fmt.Errorf("withdrawals of %s on %s are not available", coin, chain)
+	}
+
+	fee := toAtomic(bybitFloat(c.WithdrawFee), &ui)
+	if minWd := toAtomic(bybitFloat(c.WithdrawMin), &ui); amt <= fee || amt < minWd {
+		return "", 0, fmt.Errorf("withdrawal amount %s is lower than the minimum %s or the fee %s",
+			ui.FormatConventional(amt), ui.FormatConventional(minWd), ui.FormatConventional(fee))
+	}
+
+	if err := bb.transfer(ctx, assetID, amt, bbtypes.AccountUnified, bbtypes.AccountFund); err != nil {
+		return "", 0, fmt.Errorf("error transferring to the funding account: %w", err)
+	}
+
+	req := &bbtypes.WithdrawRequest{
+		Coin:        coin,
+		Chain:       chain,
+		Address:     address,
+		Amount:      formatBybitAmount(amt, &ui),
+		Timestamp:   time.Now().UnixMilli(),
+		ForceChain:  1,
+		AccountType: bbtypes.AccountFund,
+		FeeType:     1,
+	}
+	var res bbtypes.WithdrawResult
+	if err := bb.postAPI(ctx, "/v5/asset/withdraw", req, &res); err != nil {
+		if err := bb.transfer(ctx, assetID, amt, bbtypes.AccountFund, bbtypes.AccountUnified); err != nil {
+			bb.log.Errorf("Error returning funds to the trading account after failed withdrawal: %v", err)
+		}
+		return "", 0, fmt.Errorf("error withdrawing: %w", err)
+	}
+
+	return res.ID, amt, nil
+}
+
+// ConfirmWithdrawal checks whether a withdrawal has been completed. If the
+// withdrawal has not yet been sent, ErrWithdrawalPending is returned.
+func (bb *bybit) ConfirmWithdrawal(ctx context.Context, withdrawalID string, assetID uint32) (uint64, string, error) {
+	var res bbtypes.WithdrawalsResult
+	if err := bb.getAPI(ctx, "/v5/asset/withdraw/query-record", url.Values{"withdrawID": []string{withdrawalID}}, true, &res); err != nil {
+		return 0, "", fmt.Errorf("error getting withdrawal status: %w", err)
+	}
+	if len(res.Rows) == 0 {
+		return 0, "", fmt.Errorf("withdrawal %s not found", withdrawalID)
+	}
+	wd := res.Rows[0]
+
+	switch wd.Status {
+	case bbtypes.WithdrawalStatusCancelByUser, bbtypes.WithdrawalStatusReject, bbtypes.WithdrawalStatusFail:
+		return 0, "", fmt.Errorf("withdrawal %s failed with status %s", withdrawalID, wd.Status)
+	}
+	if wd.TxID == "" {
+		return 0, "", ErrWithdrawalPending
+	}
+
+	ui, err := asset.UnitInfo(assetID)
+	if err != nil {
+		return 0, "", fmt.Errorf("error getting unit info for asset ID %d: %v", assetID, err)
+	}
+
+	return toAtomic(bybitFloat(wd.Amount), &ui), wd.TxID, nil
+}
+
+// TradeStatus returns the current status of a trade.
+func (bb *bybit) TradeStatus(ctx context.Context, id string, baseID, quoteID uint32) (*Trade, error) {
+	inst, err := bb.instrument(baseID, quoteID)
+	if err != nil {
+		return nil, err
+	}
+	q := url.Values{
+		"category": []string{bybitSpotCategory},
+		"symbol":   []string{inst.Symbol},
+		"orderId":  []string{id},
+	}
+	var res bbtypes.OrdersResult
+	if err := bb.getAPI(ctx, "/v5/order/realtime", q, true, &res); err != nil {
+		return nil, fmt.Errorf("error fetching order status: %w", err)
+	}
+	if len(res.List) == 0 {
+		// Orders that are no longer open are only in the order history.
+		if err := bb.getAPI(ctx, "/v5/order/history", q, true, &res); err != nil {
+			return nil, fmt.Errorf("error fetching order history: %w", err)
+		}
+	}
+	if len(res.List) == 0 {
+		return nil, fmt.Errorf("order %s not found", id)
+	}
+
+	bui, err := asset.UnitInfo(baseID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting unit info for base asset ID %d: %v", baseID, err)
+	}
+	qui, err := asset.UnitInfo(quoteID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting unit info for quote asset ID %d: %v", quoteID, err)
+	}
+
+	ord := res.List[0]
+	market := ord.OrderType == "Market"
+	var qty uint64
+	if ord.MarketUnit == "quoteCoin" {
+		qty = toAtomic(bybitFloat(ord.Qty), &qui)
+	} else {
+		qty = toAtomic(bybitFloat(ord.Qty), &bui)
+	}
+	var rate uint64
+	if !market {
+		rate = messageRate(bybitFloat(ord.Price), &bui, &qui)
+	}
+	baseFilled, quoteFilled := bybitOrderFills(ord, &bui, &qui)
+
+	return &Trade{
+		ID:          id,
+		Sell:        ord.Side == "Sell",
+		Qty:         qty,
+		Rate:        rate,
+		Market:      market,
+		BaseID:      baseID,
+		QuoteID:     quoteID,
+		BaseFilled:  baseFilled,
+		QuoteFilled: quoteFilled,
+		Complete:    bybitOrderComplete(ord.OrderStatus),
+	}, nil
+}
@@ -0,0 +1,888 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package libxc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"decred.org/dcrdex/client/asset"
+	"decred.org/dcrdex/client/comms"
+	"decred.org/dcrdex/client/mm/libxc/bbtypes"
+	"decred.org/dcrdex/dex"
+	"decred.org/dcrdex/dex/calc"
+)
+
+const (
+	bybitTestSecret = "22582BD0CFF14C41EDBF1AB98506286D"
+
+	bybitInstrumentsFixture = `{"retCode":0,"retMsg":"OK","result":{"category":"spot","list":[
+		{"symbol":"BTCUSDT","baseCoin":"BTC","quoteCoin":"USDT","status":"Trading",
+			"lotSizeFilter":{"basePrecision":"0.000001","quotePrecision":"0.00000001","minOrderQty":"0.000048","minOrderAmt":"1"},
+			"priceFilter":{"tickSize":"0.01"}},
+		{"symbol":"ETHBTC","baseCoin":"ETH","quoteCoin":"BTC","status":"Trading",
+			"lotSizeFilter":{"basePrecision":"0.00001","quotePrecision":"0.0000001","minOrderQty":"0.0001","minOrderAmt":"0.00001"},
+			"priceFilter":{"tickSize":"0.000001"}},
+		{"symbol":"LTCUSDT","baseCoin":"LTC","quoteCoin":"USDT","status":"PreLaunch",
+			"lotSizeFilter":{"basePrecision":"0.00001","quotePrecision":"0.000001","minOrderQty":"0.01","minOrderAmt":"1"},
+			"priceFilter":{"tickSize":"0.01"}},
+		{"symbol":"MNTUSDT","baseCoin":"MNT","quoteCoin":"USDT","status":"Trading",
+			"lotSizeFilter":{"basePrecision":"0.01","quotePrecision":"0.000001","minOrderQty":"1","minOrderAmt":"1"},
+			"priceFilter":{"tickSize":"0.0001"}}]}}`
+
+	bybitTickersFixture = `{"retCode":0,"retMsg":"OK","result":{"category":"spot","list":[
+		{"symbol":"BTCUSDT","lastPrice":"45284","prevPrice24h":"44284","highPrice24h":"46100","lowPrice24h":"43900","volume24h":"250","turnover24h":"11225000"},
+		{"symbol":"ETHBTC","lastPrice":"0.05","prevPrice24h":"0.049","highPrice24h":"0.051","lowPrice24h":"0.048","volume24h":"1000","turnover24h":"50"}]}}`
+
+	bybitWalletFixture = `{"retCode":0,"retMsg":"OK","result":{"list":[{"accountType":"UNIFIED","coin":[
+		{"coin":"BTC","walletBalance":"1.5","locked":"0.25"},
+		{"coin":"USDT","walletBalance":"1000","locked":"0"},
+		{"coin":"MNT","walletBalance":"5","locked":"0"}]}]}}`
+
+	bybitPlaceOrderFixture = `{"retCode":0,"retMsg":"OK","result":{"orderId":"1321003749386327552","orderLinkId":"abc"}}`
+
+	bybitOrderFixture = `{"retCode":0,"retMsg":"OK","result":{"list":[{"category":"spot","symbol":"BTCUSDT",
+		"orderId":"1321003749386327552","orderLinkId":"abc","side":"Buy","orderType":"Limit","price":"45000","qty":"0.1",
+		"marketUnit":"","orderStatus":"Filled","cumExecQty":"0.1","cumExecValue":"4500","cumExecFee":"0.0001"}]}}`
+
+	bybitDepositAddressFixture = `{"retCode":0,"retMsg":"OK","result":{"coin":"USDT","chains":[
+		{"chain":"ETH","chainType":"ETH","addressDeposit":"0x2dB0B7A4E2D8Ef0Ef1F21C8a1b0B7d2A3b4C5d6E","tagDeposit":""}]}}`
+
+	bybitDepositsFixture = `{"retCode":0,"retMsg":"OK","result":{"rows":[
+		{"coin":"USDT","chain":"ETH","amount":"100","txID":"0xdeposit","status":3}]}}`
+
+	bybitCoinInfoFixture = `{"retCode":0,"retMsg":"OK","result":{"rows":[{"coin":"BTC","chains":[
+		{"chain":"LIGHTNING","withdrawFee":"0","withdrawMin":"0.000001","minAccuracy":"8","chainDeposit":"1","chainWithdraw":"1"},
+		{"chain":"BTC","withdrawFee":"0.0002","withdrawMin":"0.0005","minAccuracy":"8","chainDeposit":"1","chainWithdraw":"1"}]},
+		{"coin":"USDT","chains":[
+		{"chain":"ETH","withdrawFee":"3","withdrawMin":"2","minAccuracy":"6","chainDeposit":"1","chainWithdraw":"1"},
+		{"chain":"MATIC","withdrawFee":"0.3","withdrawMin":"1","minAccuracy":"6","chainDeposit":"1","chainWithdraw":"0"}]}]}}`
+
+	bybitWithdrawFixture = `{"retCode":0,"retMsg":"success","result":{"id":"10195"}}`
+
+	bybitWithdrawalsFixture = `{"retCode":0,"retMsg":"OK","result":{"rows":[
+		{"withdrawId":"10195","coin":"BTC","chain":"BTC","amount":"0.0998","withdrawFee":"0.0002","txID":"","status":"Pending"}]}}`
+
+	bybitTransferFixture = `{"retCode":0,"retMsg":"success","result":{"transferId":"42c0cfb0-6bca-c242-bc76-4e6df6cbab16"}}`
+)
+
+type tBybitRequest struct {
+	query url.Values
+	body  []byte
+}
+
+// tBybitServer serves the recorded REST responses.
+type tBybitServer struct {
+	mtx       sync.Mutex
+	responses map[string]string
+	requests  map[string][]*tBybitRequest
+}
+
+func newTBybitServer(t *testing.T) (*tBybitServer, *httptest.Server) {
+	s := &tBybitServer{
+		responses: map[string]string{
+			"/v5/market/instruments-info":       bybitInstrumentsFixture,
+			"/v5/market/tickers":                bybitTickersFixture,
+			"/v5/account/wallet-balance":        bybitWalletFixture,
+			"/v5/order/create":                  bybitPlaceOrderFixture,
+			"/v5/order/cancel":                  bybitPlaceOrderFixture,
+			"/v5/order/realtime":                `{"retCode":0,"retMsg":"OK","result":{"list":[]}}`,
+			"/v5/order/history":                 bybitOrderFixture,
+			"/v5/asset/deposit/query-address":   bybitDepositAddressFixture,
+			"/v5/asset/deposit/query-record":    bybitDepositsFixture,
+			"/v5/asset/coin/query-info":         bybitCoinInfoFixture,
+			"/v5/asset/transfer/inter-transfer": bybitTransferFixture,
+			"/v5/asset/withdraw":                bybitWithdrawFixture,
+			"/v5/asset/withdraw/query-record":   bybitWithdrawalsFixture,
+		},
+		requests: make(map[string][]*tBybitRequest),
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mtx.Lock()
+		defer s.mtx.Unlock()
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("error reading body: %v", err)
+		}
+		if !strings.HasPrefix(r.URL.Path, "/v5/market/") {
+			payload := r.URL.RawQuery
+			if r.Method == http.MethodPost {
+				payload = string(body)
+			}
+			ts, key, recvWindow := r.Header.Get("X-BAPI-TIMESTAMP"), r.Header.Get("X-BAPI-API-KEY"), r.Header.Get("X-BAPI-RECV-WINDOW")
+			if r.Header.Get("X-BAPI-SIGN") != bybitSignature(ts+key+recvWindow+payload, bybitTestSecret) {
+				w.Write([]byte(`{"retCode":10004,"retMsg":"error sign!","result":{}}`))
+				return
+			}
+		}
+		s.requests[r.URL.Path] = append(s.requests[r.URL.Path], &tBybitRequest{query: r.URL.Query(), body: body})
+		resp, found := s.responses[r.URL.Path]
+		if !found {
+			w.Write([]byte(`{"retCode":10001,"retMsg":"Unknown endpoint","result":{}}`))
+			return
+		}
+		w.Write([]byte(resp))
+	}))
+	return s, srv
+}
+
+func (s *tBybitServer) setResponse(path, resp string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.responses[path] = resp
+}
+
+// lastRequest returns the last request to the path, and the number of
+// requests that have been made to it.
+func (s *tBybitServer) lastRequest(path string) (*tBybitRequest, int) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	reqs := s.requests[path]
+	if len(reqs) == 0 {
+		return nil, 0
+	}
+	return reqs[len(reqs)-1], len(reqs)
+}
+
+func tNewBybit(t *testing.T, srvURL string, notify func(interface{})) *bybit {
+	if notify == nil {
+		notify = func(interface{}) {}
+	}
+	bb, err := newBybit(&CEXConfig{
+		Net:       dex.Mainnet,
+		APIKey:    "key",
+		SecretKey: bybitTestSecret,
+		Logger:    dex.StdOutLogger("T", dex.LevelTrace),
+		Notify:    notify,
+	})
+	if err != nil {
+		t.Fatalf("error creating bybit: %v", err)
+	}
+	bb.url = srvURL
+	return bb
+}
+
+func TestBybitAssetMapping(t *testing.T) {
+	coins := map[string]string{
+		"btc":          "BTC",
+		"dcr":          "DCR",
+		"polygon":      "POL",
+		"base":         "ETH",
+		"weth.polygon": "ETH",
+		"usdt.eth":     "USDT",
+		"usdc.polygon": "USDC",
+	}
+	for sym, exp := range coins {
+		if coin := bybitCoin(sym); coin != exp {
+			t.Fatalf("wrong coin for %s. expected %s, got %s", sym, exp, coin)
+		}
+	}
+
+	chains := map[uint32]string{
+		0:      "BTC",
+		60:     "ETH",
+		966:    "MATIC",
+		8453:   "BASE",
+		60002:  "ETH",
+		61000:  "BASE",
+		966004: "MATIC",
+	}
+	for assetID, exp := range chains {
+		chain, err := bybitChain(assetID)
+		if err != nil {
+			t.Fatalf("error getting chain for %s: %v", dex.BipIDSymbol(assetID), err)
+		}
+		if chain != exp {
+			t.Fatalf("wrong chain for %s. expected %s, got %s", dex.BipIDSymbol(assetID), exp, chain)
+		}
+	}
+
+	uuidRE := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	if id := bybitTransferID(); !uuidRE.MatchString(id) {
+		t.Fatalf("invalid transfer ID %s", id)
+	}
+
+	if _, err := newBybit(&CEXConfig{Net: dex.Testnet, APIKey: "key", SecretKey: "secret"}); err == nil {
+		t.Fatalf("no error for testnet")
+	}
+}
+
+func TestBybitMarketsAndBalances(t *testing.T) {
+	s, srv := newTBybitServer(t)
+	defer srv.Close()
+	var notesMtx sync.Mutex
+	var notes []*BalanceUpdate
+	bb := tNewBybit(t, srv.URL, func(n interface{}) {
+		notesMtx.Lock()
+		notes = append(notes, n.(*BalanceUpdate))
+		notesMtx.Unlock()
+	})
+
+	markets, err := bb.Markets(context.Background())
+	if err != nil {
+		t.Fatalf("Markets error: %v", err)
+	}
+	for _, mktID := range []string{"btc_usdt.eth", "btc_usdt.polygon", "eth_btc", "base_btc"} {
+		if _, found := markets[mktID]; !found {
+			t.Fatalf("market %s not found", mktID)
+		}
+	}
+	if len(markets) != 4 {
+		t.Fatalf("expected 4 markets, got %d", len(markets))
+	}
+	day := markets["btc_usdt.eth"].Day
+	if day == nil || day.LastPrice != 45284 || day.OpenPrice != 44284 || day.Vol != 250 || day.AvgPrice != 44900 || day.HighPrice != 46100 {
+		t.Fatalf("wrong market day: %+v", day)
+	}
+	for mktID, exp := range map[string][2]uint64{
+		"btc_usdt.eth":     {50000, 3000001},
+		"btc_usdt.polygon": {50000, 0},
+	} {
+		mkt := markets[mktID]
+		if mkt.BaseMinWithdraw != exp[0] || mkt.QuoteMinWithdraw != exp[1] {
+			t.Fatalf("wrong %s min withdraws. expected %v, got %d, %d", mktID, exp, mkt.BaseMinWithdraw, mkt.QuoteMinWithdraw)
+		}
+	}
+
+	inst, err := bb.instrument(0, 60002)
+	if err != nil {
+		t.Fatalf("instrument error: %v", err)
+	}
+	btcUI, _ := asset.UnitInfo(0)
+	usdtUI, _ := asset.UnitInfo(60002)
+	if inst.LotSize != 100 || inst.MinQty != 4800 || inst.MinCost != 1e6 || inst.PriceDecimals != 2 || inst.SizeDecimals != 6 || inst.QuoteDecimals != 8 {
+		t.Fatalf("wrong instrument: %+v", inst)
+	}
+	if expStep := calc.MessageRate(0.01, btcUI, usdtUI); inst.RateStep != expStep {
+		t.Fatalf("wrong rate step. expected %d, got %d", expStep, inst.RateStep)
+	}
+	if _, err := bb.instrument(2, 60002); err == nil {
+		t.Fatalf("no error for instrument that is not trading")
+	}
+
+	balances, err := bb.Balances(context.Background())
+	if err != nil {
+		t.Fatalf("Balances error: %v", err)
+	}
+	if req, _ := s.lastRequest("/v5/account/wallet-balance"); req.query.Get("accountType") != "UNIFIED" {
+		t.Fatalf("wrong wallet balance query %v", req.query)
+	}
+	expBTC := &ExchangeBalance{Available: 1.25e8, Locked: 0.25e8}
+	if !reflect.DeepEqual(balances[0], expBTC) {
+		t.Fatalf("wrong btc balance. expected %+v, got %+v", expBTC, balances[0])
+	}
+	expUSDT := &ExchangeBalance{Available: 1000e6}
+	for _, assetID := range []uint32{60002, 966004} {
+		if !reflect.DeepEqual(balances[assetID], expUSDT) {
+			t.Fatalf("wrong %s balance. expected %+v, got %+v", dex.BipIDSymbol(assetID), expUSDT, balances[assetID])
+		}
+	}
+	if len(balances) != 3 {
+		t.Fatalf("expected 3 balances, got %d", len(balances))
+	}
+
+	// The wallet topic only includes the coins that changed.
+	notes = nil
+	bb.handleUserMessage(&bbtypes.WsMessage{
+		Topic: "wallet",
+		Data:  json.RawMessage(`[{"accountType":"UNIFIED","coin":[{"coin":"USDT","walletBalance":"1000","locked":"100"}]}]`),
+	})
+	expUSDT = &ExchangeBalance{Available: 900e6, Locked: 100e6}
+	if bal, _ := bb.Balance(60002); !reflect.DeepEqual(bal, expUSDT) {
+		t.Fatalf("wrong usdt balance after wallet update: %+v", bal)
+	}
+	if bal, _ := bb.Balance(0); !reflect.DeepEqual(bal, expBTC) {
+		t.Fatalf("btc balance changed by wallet update: %+v", bal)
+	}
+	if len(notes) != 2 {
+		t.Fatalf("expected 2 balance notifications, got %d", len(notes))
+	}
+
+	// Coins that are no longer returned by the REST API have a zero balance.
+	s.setResponse("/v5/account/wallet-balance", `{"retCode":0,"retMsg":"OK","result":{"list":[{"accountType":"UNIFIED","coin":[
+		{"coin":"USDT","walletBalance":"1000","locked":"100"}]}]}}`)
+	notes = nil
+	if _, err := bb.Balances(context.Background()); err != nil {
+		t.Fatalf("Balances error: %v", err)
+	}
+	if bal, _ := bb.Balance(0); bal.Available != 0 || bal.Locked != 0 {
+		t.Fatalf("btc balance not zeroed: %+v", bal)
+	}
+	if len(notes) != 1 || notes[0].AssetID != 0 {
+		t.Fatalf("expected 1 btc balance notification, got %+v", notes)
+	}
+}
+
+func TestBuildBybitOrderRequest(t *testing.T) {
+	btcUI, _ := asset.UnitInfo(0)
+	usdtUI, _ := asset.UnitInfo(60002)
+	msgRate := func(rate float64) uint64 {
+		return calc.MessageRate(rate, btcUI, usdtUI)
+	}
+	inst := &bbtypes.Instrument{
+		Symbol: "BTCUSDT",
+		LotSizeFilter: &bbtypes.LotSizeFilter{
+			BasePrecision:  "0.000001",
+			QuotePrecision: "0.0001",
+			MinOrderQty:    "0.000048",
+			MinOrderAmt:    "1",
+		},
+		PriceFilter: &bbtypes.PriceFilter{TickSize: "0.01"},
+	}
+	parseBybitInstrument(inst, &btcUI, &usdtUI)
+
+	tests := []struct {
+		name      string
+		sell      bool
+		orderType OrderType
+		rate      uint64
+		qty       uint64
+		quoteQty  uint64
+		expReq    *bbtypes.OrderRequest
+		expQty    uint64
+		wantErr   bool
+	}{
+		{
+			name:      "limit buy",
+			orderType: OrderTypeLimit,
+			rate:      msgRate(45000.004),
+			qty:       0.1e8,
+			expReq: &bbtypes.OrderRequest{
+				Category:    "spot",
+				Symbol:      "BTCUSDT",
+				Side:        "Buy",
+				OrderType:   "Limit",
+				Qty:         "0.100000",
+				Price:       "45000.00",
+				TimeInForce: "GTC",
+				OrderLinkID: "id",
+			},
+			expQty: 0.1e8,
+		},
+		{
+			name:      "limit ioc sell",
+			sell:      true,
+			orderType: OrderTypeLimitIOC,
+			rate:      msgRate(45000.55),
+			qty:       0.12345678e8,
+			expReq: &bbtypes.OrderRequest{
+				Category:    "spot",
+				Symbol:      "BTCUSDT",
+				Side:        "Sell",
+				OrderType:   "Limit",
+				Qty:         "0.123456",
+				Price:       "45000.55",
+				TimeInForce: "IOC",
+				OrderLinkID: "id",
+			},
+			expQty: 0.123456e8,
+		},
+		{
+			name:      "limit buy with quote qty",
+			orderType: OrderTypeLimit,
+			rate:      msgRate(50000),
+			quoteQty:  5000e6,
+			expReq: &bbtypes.OrderRequest{
+				Category:    "spot",
+				Symbol:      "BTCUSDT",
+				Side:        "Buy",
+				OrderType:   "Limit",
+				Qty:         "0.100000",
+				Price:       "50000.00",
+				TimeInForce: "GTC",
+				OrderLinkID: "id",
+			},
+			expQty: 0.1e8,
+		},
+		{
+			name:      "market buy",
+			orderType: OrderTypeMarket,
+			quoteQty:  100.12345e6,
+			expReq: &bbtypes.OrderRequest{
+				Category:    "spot",
+				Symbol:      "BTCUSDT",
+				Side:        "Buy",
+				OrderType:   "Market",
+				Qty:         "100.1234",
+				OrderLinkID: "id",
+				MarketUnit:  "quoteCoin",
+			},
+			expQty: 100.1234e6,
+		},
+		{
+			name:      "market sell",
+			sell:      true,
+			orderType: OrderTypeMarket,
+			qty:       0.5e8,
+			expReq: &bbtypes.OrderRequest{
+				Category:    "spot",
+				Symbol:      "BTCUSDT",
+				Side:        "Sell",
+				OrderType:   "Market",
+				Qty:         "0.500000",
+				OrderLinkID: "id",
+				MarketUnit:  "baseCoin",
+			},
+			expQty: 0.5e8,
+		},
+		{
+			name:      "market buy with base qty",
+			orderType: OrderTypeMarket,
+			qty:       0.5e8,
+			wantErr:   true,
+		},
+		{
+			name:      "market buy below minimum amount",
+			orderType: OrderTypeMarket,
+			quoteQty:  0.5e6,
+			wantErr:   true,
+		},
+		{
+			name:      "qty below minimum",
+			sell:      true,
+			orderType: OrderTypeLimit,
+			rate:      msgRate(45000),
+			qty:       4799,
+			wantErr:   true,
+		},
+		{
+			name:      "limit without rate",
+			orderType: OrderTypeLimit,
+			qty:       0.1e8,
+			wantErr:   true,
+		},
+		{
+			name:      "sell with quote qty",
+			sell:      true,
+			orderType: OrderTypeLimit,
+			rate:      msgRate(45000),
+			quoteQty:  100e6,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, qty, err := buildBybitOrderRequest(inst, &btcUI, &usdtUI, tt.sell, tt.orderType, tt.rate, tt.qty, tt.quoteQty, "id")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(req, tt.expReq) {
+				t.Fatalf("wrong request. expected %+v, got %+v", tt.expReq, req)
+			}
+			if qty != tt.expQty {
+				t.Fatalf("wrong qty. expected %d, got %d", tt.expQty, qty)
+			}
+		})
+	}
+}
+
+func TestBybitTrade(t *testing.T) {
+	s, srv := newTBybitServer(t)
+	defer srv.Close()
+	bb := tNewBybit(t, srv.URL, nil)
+	if _, err := bb.Markets(context.Background()); err != nil {
+		t.Fatalf("Markets error: %v", err)
+	}
+
+	btcUI, _ := asset.UnitInfo(0)
+	usdtUI, _ := asset.UnitInfo(60002)
+	rate := calc.MessageRate(45000, btcUI, usdtUI)
+
+	updates, _, subID := bb.SubscribeTradeUpdates()
+	if _, err := bb.Trade(context.Background(), 0, 60002, false, rate, 0.1e8, 0, OrderTypeLimit, subID+1); err == nil {
+		t.Fatalf("no error for unknown subscription ID")
+	}
+
+	trade, err := bb.Trade(context.Background(), 0, 60002, false, rate, 0.1e8, 0, OrderTypeLimit, subID)
+	if err != nil {
+		t.Fatalf("Trade error: %v", err)
+	}
+	if trade.ID != "1321003749386327552" || trade.Qty != 0.1e8 || trade.Rate != rate || trade.Sell {
+		t.Fatalf("wrong trade: %+v", trade)
+	}
+	req, _ := s.lastRequest("/v5/order/create")
+	var ordReq bbtypes.OrderRequest
+	if err := json.Unmarshal(req.body, &ordReq); err != nil {
+		t.Fatalf("error unmarshaling order request: %v", err)
+	}
+	if len(ordReq.OrderLinkID) != 32 || ordReq.Symbol != "BTCUSDT" || ordReq.Price != "45000.00" || ordReq.Qty != "0.100000" {
+		t.Fatalf("wrong order request %+v", ordReq)
+	}
+	orderLinkID := ordReq.OrderLinkID
+
+	sendOrders := func(ords ...string) {
+		t.Helper()
+		bb.handleUserMessage(&bbtypes.WsMessage{
+			Topic: "order",
+			Data:  json.RawMessage("[" + strings.Join(ords, ",") + "]"),
+		})
+	}
+	checkUpdate := func(expBase, expQuote uint64, expComplete bool) {
+		t.Helper()
+		select {
+		case u := <-updates:
+			if u.ID != trade.ID {
+				t.Fatalf("wrong trade ID %s", u.ID)
+			}
+			if u.BaseFilled != expBase || u.QuoteFilled != expQuote || u.Complete != expComplete {
+				t.Fatalf("wrong update. expected base %d, quote %d, complete %t, got %+v", expBase, expQuote, expComplete, u)
+			}
+		default:
+			t.Fatalf("no update")
+		}
+	}
+	ord := func(status, cumExecQty, cumExecValue, cumExecFee string) string {
+		return fmt.Sprintf(`{"category":"spot","symbol":"BTCUSDT","orderId":"1321003749386327552","orderLinkId":%q,`+
+			`"side":"Buy","orderType":"Limit","price":"45000","qty":"0.1","orderStatus":%q,"cumExecQty":%q,`+
+			`"cumExecValue":%q,"cumExecFee":%q}`, orderLinkID, status, cumExecQty, cumExecValue, cumExecFee)
+	}
+
+	sendOrders(ord("New", "0", "0", "0"))
+	checkUpdate(0, 0, false)
+
+	sendOrders(ord("PartiallyFilled", "0.04", "1800", "0.00004"))
+	checkUpdate(0.03996e8, 1800e6, false)
+
+	// The fee is the accumulated fee of the order, charged in the base asset
+	// for buys.
+	sendOrders(ord("Filled", "0.1", "4500", "0.0001"))
+	checkUpdate(0.0999e8, 4500e6, true)
+
+	// Trade info should be deleted after completion.
+	sendOrders(ord("Filled", "0.1", "4500", "0.0001"))
+	select {
+	case u := <-updates:
+		t.Fatalf("unexpected update after completion: %+v", u)
+	default:
+	}
+
+	// The order is no longer open, so the status comes from the history.
+	status, err := bb.TradeStatus(context.Background(), trade.ID, 0, 60002)
+	if err != nil {
+		t.Fatalf("TradeStatus error: %v", err)
+	}
+	expStatus := &Trade{
+		ID:          trade.ID,
+		Qty:         0.1e8,
+		Rate:        rate,
+		BaseID:      0,
+		QuoteID:     60002,
+		BaseFilled:  0.0999e8,
+		QuoteFilled: 4500e6,
+		Complete:    true,
+	}
+	if !reflect.DeepEqual(status, expStatus) {
+		t.Fatalf("wrong trade status. expected %+v, got %+v", expStatus, status)
+	}
+	if req, n := s.lastRequest("/v5/order/history"); n != 1 || req.query.Get("symbol") != "BTCUSDT" || req.query.Get("orderId") != trade.ID {
+		t.Fatalf("wrong order history query %v", req.query)
+	}
+
+	// Sell fees are charged in the quote asset.
+	s.setResponse("/v5/order/realtime", strings.Replace(strings.Replace(bybitOrderFixture, `"Buy"`, `"Sell"`, 1), `"0.0001"`, `"4.5"`, 1))
+	status, err = bb.TradeStatus(context.Background(), trade.ID, 0, 60002)
+	if err != nil {
+		t.Fatalf("TradeStatus error: %v", err)
+	}
+	if !status.Sell || status.BaseFilled != 0.1e8 || status.QuoteFilled != 4495.5e6 {
+		t.Fatalf("wrong sell trade status %+v", status)
+	}
+	if _, n := s.lastRequest("/v5/order/history"); n != 1 {
+		t.Fatalf("order history requested for open order")
+	}
+
+	if err := bb.CancelTrade(context.Background(), 0, 60002, trade.ID); err != nil {
+		t.Fatalf("CancelTrade error: %v", err)
+	}
+
+	// A rejected order is an error, and the trade info is removed.
+	s.setResponse("/v5/order/create", `{"retCode":170131,"retMsg":"Insufficient balance.","result":{}}`)
+	_, err = bb.Trade(context.Background(), 0, 60002, false, rate, 0.1e8, 0, OrderTypeLimit, subID)
+	if err == nil || !strings.Contains(err.Error(), "Insufficient balance") {
+		t.Fatalf("wrong error for rejected order: %v", err)
+	}
+	if len(bb.tradeInfo) != 0 {
+		t.Fatalf("trade info not removed after rejected order")
+	}
+}
+
+func TestBybitFunding(t *testing.T) {
+	s, srv := newTBybitServer(t)
+	defer srv.Close()
+	bb := tNewBybit(t, srv.URL, nil)
+	ctx := context.Background()
+
+	addr, err := bb.GetDepositAddress(ctx, 60002)
+	if err != nil {
+		t.Fatalf("GetDepositAddress error: %v", err)
+	}
+	if addr != "0x2dB0B7A4E2D8Ef0Ef1F21C8a1b0B7d2A3b4C5d6E" {
+		t.Fatalf("wrong deposit address %s", addr)
+	}
+	if req, _ := s.lastRequest("/v5/asset/deposit/query-address"); req.query.Get("coin") != "USDT" || req.query.Get("chainType") != "ETH" {
+		t.Fatalf("wrong deposit address query %v", req.query)
+	}
+	if _, err := bb.GetDepositAddress(ctx, 966004); err == nil {
+		t.Fatalf("no error for missing deposit address")
+	}
+
+	checkTransfer := func(exp bbtypes.TransferRequest) {
+		t.Helper()
+		req, _ := s.lastRequest("/v5/asset/transfer/inter-transfer")
+		var transfer bbtypes.TransferRequest
+		if err := json.Unmarshal(req.body, &transfer); err != nil {
+			t.Fatalf("error unmarshaling transfer request: %v", err)
+		}
+		if transfer.TransferID == "" {
+			t.Fatalf("no transfer ID")
+		}
+		exp.TransferID = transfer.TransferID
+		if transfer != exp {
+			t.Fatalf("wrong transfer request. expected %+v, got %+v", exp, transfer)
+		}
+	}
+
+	// Completed deposits are transferred to the unified trading account.
+	complete, amt := bb.ConfirmDeposit(ctx, &DepositData{AssetID: 60002, TxID: "0xdeposit"})
+	if !complete || amt != 100e6 {
+		t.Fatalf("wrong deposit confirmation. complete = %t, amt = %d", complete, amt)
+	}
+	checkTransfer(bbtypes.TransferRequest{Coin: "USDT", Amount: "100.000000", FromAccountType: "FUND", ToAccountType: "UNIFIED"})
+	// The deposit is for a different chain.
+	if complete, _ := bb.ConfirmDeposit(ctx, &DepositData{AssetID: 966004, TxID: "0xdeposit"}); complete {
+		t.Fatalf("deposit on wrong chain confirmed")
+	}
+	if complete, _ := bb.ConfirmDeposit(ctx, &DepositData{AssetID: 60002, TxID: "0xunknown"}); complete {
+		t.Fatalf("unknown deposit confirmed")
+	}
+	s.setResponse("/v5/asset/deposit/query-record", strings.Replace(bybitDepositsFixture, `"status":3`, `"status":1`, 1))
+	if complete, _ := bb.ConfirmDeposit(ctx, &DepositData{AssetID: 60002, TxID: "0xdeposit"}); complete {
+		t.Fatalf("unconfirmed deposit confirmed")
+	}
+	_, nTransfers := s.lastRequest("/v5/asset/transfer/inter-transfer")
+	s.setResponse("/v5/asset/deposit/query-record", strings.Replace(bybitDepositsFixture, `"status":3`, `"status":4`, 1))
+	if complete, amt := bb.ConfirmDeposit(ctx, &DepositData{AssetID: 60002, TxID: "0xdeposit"}); !complete || amt != 0 {
+		t.Fatalf("failed deposit not completed with zero amount")
+	}
+	if _, n := s.lastRequest("/v5/asset/transfer/inter-transfer"); n != nTransfers {
+		t.Fatalf("failed deposit transferred")
+	}
+
+	// The amount is below the minimum withdrawal.
+	if _, _, err := bb.Withdraw(ctx, 0, 0.0004e8, "bc1qwallet"); err == nil {
+		t.Fatalf("no error for withdrawal below minimum")
+	}
+	id, amt, err := bb.Withdraw(ctx, 0, 0.1e8, "bc1qwallet")
+	if err != nil {
+		t.Fatalf("Withdraw error: %v", err)
+	}
+	if id != "10195" || amt != 0.1e8 {
+		t.Fatalf("wrong withdrawal result: %s, %d", id, amt)
+	}
+	checkTransfer(bbtypes.TransferRequest{Coin: "BTC", Amount: "0.10000000", FromAccountType: "UNIFIED", ToAccountType: "FUND"})
+	req, _ := s.lastRequest("/v5/asset/withdraw")
+	var wdReq bbtypes.WithdrawRequest
+	if err := json.Unmarshal(req.body, &wdReq); err != nil {
+		t.Fatalf("error unmarshaling withdrawal request: %v", err)
+	}
+	expWdReq := bbtypes.WithdrawRequest{Coin: "BTC", Chain: "BTC", Address: "bc1qwallet", Amount: "0.10000000",
+		Timestamp: wdReq.Timestamp, ForceChain: 1, AccountType: "FUND", FeeType: 1}
+	if wdReq != expWdReq || wdReq.Timestamp == 0 {
+		t.Fatalf("wrong withdrawal request %+v", wdReq)
+	}
+
+	if _, _, err := bb.ConfirmWithdrawal(ctx, id, 0); err != ErrWithdrawalPending {
+		t.Fatalf("expected ErrWithdrawalPending, got %v", err)
+	}
+	if req, _ := s.lastRequest("/v5/asset/withdraw/query-record"); req.query.Get("withdrawID") != id {
+		t.Fatalf("wrong withdrawal query %v", req.query)
+	}
+	s.setResponse("/v5/asset/withdraw/query-record", strings.Replace(
+		strings.Replace(bybitWithdrawalsFixture, `"txID":""`, `"txID":"abcd"`, 1), `"Pending"`, `"success"`, 1))
+	amt, txID, err := bb.ConfirmWithdrawal(ctx, id, 0)
+	if err != nil {
+		t.Fatalf("ConfirmWithdrawal error: %v", err)
+	}
+	if amt != 0.0998e8 || txID != "abcd" {
+		t.Fatalf("wrong withdrawal confirmation: %d, %s", amt, txID)
+	}
+	s.setResponse("/v5/asset/withdraw/query-record", strings.Replace(bybitWithdrawalsFixture, `"Pending"`, `"Reject"`, 1))
+	if _, _, err := bb.ConfirmWithdrawal(ctx, id, 0); err == nil || err == ErrWithdrawalPending {
+		t.Fatalf("expected error for rejected withdrawal, got %v", err)
+	}
+
+	// If the withdrawal fails, the funds are returned to the trading account.
+	s.setResponse("/v5/asset/withdraw", `{"retCode":131002,"retMsg":"Withdrawal address not in whitelist","result":{}}`)
+	if _, _, err := bb.Withdraw(ctx, 0, 0.1e8, "bc1qunknown"); err == nil {
+		t.Fatalf("no error for failed withdrawal")
+	}
+	checkTransfer(bbtypes.TransferRequest{Coin: "BTC", Amount: "0.10000000", FromAccountType: "FUND", ToAccountType: "UNIFIED"})
+}
+
+type tBybitWsConn struct {
+	comms.WsConn
+	sent []*bbtypes.WsRequest
+}
+
+func (c *tBybitWsConn) SendRaw(b []byte) error {
+	req := new(bbtypes.WsRequest)
+	if err := json.Unmarshal(b, req); err != nil {
+		return err
+	}
+	c.sent = append(c.sent, req)
+	return nil
+}
+
+func TestBybitWSAuth(t *testing.T) {
+	bb := tNewBybit(t, "", nil)
+	auth := bb.authRequest()
+	if auth.Op != "auth" || len(auth.Args) != 3 || auth.Args[0] != "key" {
+		t.Fatalf("wrong auth request %+v", auth)
+	}
+	expires := auth.Args[1].(string)
+	if expSig := bybitSignature("GET/realtime"+expires, bybitTestSecret); auth.Args[2] != expSig {
+		t.Fatalf("wrong auth signature")
+	}
+
+	subs := func() []*bbtypes.WsRequest {
+		return []*bbtypes.WsRequest{{Op: "subscribe", Args: []interface{}{"order", "wallet"}}}
+	}
+	var msgs []*bbtypes.WsMessage
+	wsConn := &tBybitWsConn{}
+	conn := newBybitWSConn("", bb.authRequest, subs, func(msg *bbtypes.WsMessage) { msgs = append(msgs, msg) }, func(bool) {}, bb.log)
+	conn.wsConn = wsConn
+
+	// The subscriptions are sent after successful authentication.
+	if err := conn.start(); err != nil {
+		t.Fatalf("start error: %v", err)
+	}
+	if len(wsConn.sent) != 1 || wsConn.sent[0].Op != "auth" {
+		t.Fatalf("expected auth request, got %+v", wsConn.sent)
+	}
+	conn.handleWebsocketMessage([]byte(`{"success":false,"ret_msg":"Params Error","op":"auth","conn_id":"cejreaspqfh3sjdnldmg-p"}`))
+	if len(wsConn.sent) != 1 {
+		t.Fatalf("subscribed after failed authentication")
+	}
+	conn.handleWebsocketMessage([]byte(`{"success":true,"ret_msg":"","op":"auth","conn_id":"cejreaspqfh3sjdnldmg-p"}`))
+	if len(wsConn.sent) != 2 || wsConn.sent[1].Op != "subscribe" {
+		t.Fatalf("expected subscribe request, got %+v", wsConn.sent)
+	}
+
+	// Responses and pongs are not passed to the handler.
+	conn.handleWebsocketMessage([]byte(`{"success":true,"ret_msg":"","op":"subscribe","conn_id":"cejreaspqfh3sjdnldmg-p"}`))
+	conn.handleWebsocketMessage([]byte(`{"req_id":"","op":"pong","args":["1700000000000"],"conn_id":"cejreaspqfh3sjdnldmg-p"}`))
+	if len(msgs) != 0 {
+		t.Fatalf("unexpected messages passed to handler: %+v", msgs)
+	}
+	conn.handleWebsocketMessage([]byte(`{"topic":"order","id":"5923240c6880ab-c59f-420b-9adb-3639adc9dd90","creationTime":1700000000000,"data":[]}`))
+	if len(msgs) != 1 {
+		t.Fatalf("topic message not passed to handler")
+	}
+}
+
+func TestBybitBook(t *testing.T) {
+	btcUI, _ := asset.UnitInfo(0)
+	usdtUI, _ := asset.UnitInfo(60002)
+	b := newBybitBook("", "BTCUSDT", &btcUI, &usdtUI, dex.StdOutLogger("T", dex.LevelTrace))
+	wsConn := &tBybitWsConn{}
+	b.conn = newBybitWSConn("", nil, func() []*bbtypes.WsRequest { return b.subscriptions(true) }, b.handleBookMessage, b.synced.Store, b.log)
+	b.conn.wsConn = wsConn
+
+	snapshot := `{"topic":"orderbook.200.BTCUSDT","type":"snapshot","ts":1700000000000,"data":{"s":"BTCUSDT",` +
+		`"b":[["45283.5","0.1"],["45283.4","1.54582015"]],` +
+		`"a":[["45285.2","0.001"],["45286.4","1.54582015"],["45286.6","1"]],"u":100,"seq":7961638724}}`
+	b.conn.handleWebsocketMessage([]byte(snapshot))
+	if !b.synced.Load() {
+		t.Fatalf("book not synced after snapshot")
+	}
+
+	midGap, err := b.midGap()
+	if err != nil {
+		t.Fatalf("midGap error: %v", err)
+	}
+	expMidGap := (calc.MessageRate(45283.5, btcUI, usdtUI) + calc.MessageRate(45285.2, btcUI, usdtUI)) / 2
+	if midGap != expMidGap {
+		t.Fatalf("wrong mid gap. expected %d, got %d", expMidGap, midGap)
+	}
+
+	// Messages for other markets are ignored.
+	b.conn.handleWebsocketMessage([]byte(strings.ReplaceAll(snapshot, "BTCUSDT", "ETHUSDT")))
+
+	// Remove the best ask and change the qty of the best bid.
+	update := `{"topic":"orderbook.200.BTCUSDT","type":"delta","ts":1700000000100,"data":{"s":"BTCUSDT",` +
+		`"b":[["45283.5","0.2"]],"a":[["45285.2","0"]],"u":101,"seq":7961638725}}`
+	b.conn.handleWebsocketMessage([]byte(update))
+	if !b.synced.Load() {
+		t.Fatalf("book not synced after update")
+	}
+
+	vwap, extrema, filled, err := b.vwap(false, 2e8)
+	if err != nil {
+		t.Fatalf("vwap error: %v", err)
+	}
+	r1, r2 := calc.MessageRate(45286.4, btcUI, usdtUI), calc.MessageRate(45286.6, btcUI, usdtUI)
+	expVWAP := (r1*154582015 + r2*45417985) / 2e8
+	if !filled || extrema != r2 || vwap != expVWAP {
+		t.Fatalf("wrong vwap. expected %d, %d, got %d, %d, filled = %t", expVWAP, r2, vwap, extrema, filled)
+	}
+	bids, _ := b.book.snap()
+	if len(bids) != 2 || bids[0].qty != 0.2e8 {
+		t.Fatalf("wrong bids after update: %+v", bids)
+	}
+
+	// An update ID gap should trigger a resubscription.
+	gapUpdate := `{"topic":"orderbook.200.BTCUSDT","type":"delta","ts":1700000000300,"data":{"s":"BTCUSDT",` +
+		`"b":[["45283.5","0.3"]],"a":[],"u":103,"seq":7961638727}}`
+	b.conn.handleWebsocketMessage([]byte(gapUpdate))
+	if b.synced.Load() {
+		t.Fatalf("book still synced after update ID gap")
+	}
+	if len(wsConn.sent) != 2 || wsConn.sent[0].Op != "unsubscribe" || wsConn.sent[1].Op != "subscribe" ||
+		wsConn.sent[1].Args[0] != "orderbook.200.BTCUSDT" {
+		t.Fatalf("expected unsubscribe and subscribe requests, got %+v", wsConn.sent)
+	}
+	if _, _, _, err := b.vwap(false, 1e8); err != ErrUnsyncedOrderbook {
+		t.Fatalf("expected ErrUnsyncedOrderbook, got %v", err)
+	}
+
+	// Updates are ignored until the next snapshot.
+	b.conn.handleWebsocketMessage([]byte(update))
+	if b.synced.Load() {
+		t.Fatalf("book synced by update")
+	}
+	b.conn.handleWebsocketMessage([]byte(snapshot))
+	if !b.synced.Load() {
+		t.Fatalf("book not synced after second snapshot")
+	}
+	bids, asks := b.book.snap()
+	if len(bids) != 2 || len(asks) != 3 || bids[0].qty != 0.1e8 {
+		t.Fatalf("wrong book after second snapshot: %d bids, %d asks", len(bids), len(asks))
+	}
+
+	// A delta with update ID 1 after a service restart replaces the book.
+	restart := `{"topic":"orderbook.200.BTCUSDT","type":"delta","ts":1700000000500,"data":{"s":"BTCUSDT",` +
+		`"b":[["45280","1"]],"a":[["45290","1"]],"u":1,"seq":7961638800}}`
+	b.conn.handleWebsocketMessage([]byte(restart))
+	bids, asks = b.book.snap()
+	if !b.synced.Load() || len(bids) != 1 || len(asks) != 1 {
+		t.Fatalf("book not replaced after restart: %d bids, %d asks", len(bids), len(asks))
+	}
+}
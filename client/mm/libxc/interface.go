@@ -152,6 +152,8 @@ type CEX interface {
 	Withdraw(ctx context.Context, assetID uint32, amt uint64, address string) (string, uint64, error)
 	// ConfirmWithdrawal checks whether a withdrawal has been completed. If the
 	// withdrawal has not yet been sent, ErrWithdrawalPending is returned.
+	// Otherwise, the amount sent, net of withdrawal fees, and the ID of the
+	// withdrawal transaction are returned.
 	ConfirmWithdrawal(ctx context.Context, withdrawalID string, assetID uint32) (uint64, string, error)
 	// TradeStatus returns the current status of a trade.
 	TradeStatus(ctx context.Context, id string, baseID, quoteID uint32) (*Trade, error)
@@ -159,28 +161,50 @@ type CEX interface {
 	Book(baseID, quoteID uint32) (buys, sells []*core.MiniOrder, _ error)
 }
 
+// Simulator is implemented by a CEX that only simulates deposits and
+// withdrawals. The transaction IDs returned by ConfirmWithdrawal of a
+// simulated CEX are placeholders that will not be found by any wallet.
+type Simulator interface {
+	Simulated() bool
+}
+
 const (
 	Binance   = "Binance"
 	BinanceUS = "BinanceUS"
 	Coinbase  = "Coinbase"
 	MEXC      = "MEXC"
+	Kraken    = "Kraken"
+	OKX       = "OKX"
+	Bybit     = "Bybit"
 )
 
 // IsValidCEXName returns whether or not a cex name is supported.
 func IsValidCexName(cexName string) bool {
-	return cexName == Binance || cexName == BinanceUS || cexName == MEXC
+	return cexName == Binance || cexName == BinanceUS || cexName == MEXC || cexName == Kraken || cexName == OKX || cexName == Bybit
 }
 
 type CEXConfig struct {
 	Net       dex.Network
 	APIKey    string
 	SecretKey string
-	Logger    dex.Logger
-	Notify    func(interface{})
+	// Passphrase is the passphrase of the API key, which is required by
+	// OKX.
+	Passphrase string
+	Logger     dex.Logger
+	Notify     func(interface{})
+	// PaperTrading, if set, causes NewCEX to return a simulated exchange
+	// rather than connecting to the named CEX.
+	PaperTrading *PaperTradingConfig
+	// PaperWallet is optional, and is used by the simulated exchange to
+	// generate deposit addresses.
+	PaperWallet PaperWallet
 }
 
 // NewCEX creates a new CEX.
 func NewCEX(cexName string, cfg *CEXConfig) (CEX, error) {
+	if cfg.PaperTrading != nil {
+		return newPaperCEX(cfg)
+	}
 	switch cexName {
 	case Binance:
 		return newBinance(cfg, false), nil
@@ -190,6 +214,12 @@ func NewCEX(cexName string, cfg *CEXConfig) (CEX, error) {
 		return newCoinbase(cfg)
 	case MEXC:
 		return newMEXC(cfg)
+	case Kraken:
+		return newKraken(cfg)
+	case OKX:
+		return newOKX(cfg)
+	case Bybit:
+		return newBybit(cfg)
 	default:
 		return nil, fmt.Errorf("unrecognized CEX: %v", cexName)
 	}
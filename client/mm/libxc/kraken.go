@@ -0,0 +1,1803 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package libxc
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"decred.org/dcrdex/client/asset"
+	"decred.org/dcrdex/client/comms"
+	"decred.org/dcrdex/client/core"
+	"decred.org/dcrdex/client/mm/libxc/ktypes"
+	"decred.org/dcrdex/dex"
+	"decred.org/dcrdex/dex/calc"
+	"decred.org/dcrdex/dex/dexnet"
+	"decred.org/dcrdex/dex/encode"
+	"decred.org/dcrdex/dex/utils"
+)
+
+// Spot REST docs: https://docs.kraken.com/api/docs/rest-api/add-order
+// Websocket v2 docs: https://docs.kraken.com/api/docs/websocket-v2/book
+// Funding docs: https://docs.kraken.com/api/docs/rest-api/get-deposit-methods
+
+const (
+	krakenHTTPURL   = "https://api.kraken.com"
+	krakenWsURL     = "wss://ws.kraken.com/v2"
+	krakenAuthWsURL = "wss://ws-auth.kraken.com/v2"
+
+	// krakenBookDepth is the depth of the order book subscription. Kraken
+	// does not send removals for levels that fall out of the subscribed
+	// depth, so the book is truncated to this depth after every update.
+	krakenBookDepth = 100
+	// krakenChecksumDepth is the number of levels on each side of the book
+	// that are included in the checksum.
+	krakenChecksumDepth = 10
+)
+
+// dexToKrakenSymbol maps the DEX symbols to the Kraken alt names where they
+// differ.
+var dexToKrakenSymbol = map[string]string{
+	"btc":  "XBT",
+	"doge": "XDG",
+}
+
+// krakenAltToWsName maps the Kraken alt names that are used in the REST API
+// to the names that are used in the v2 websocket API where they differ.
+var krakenAltToWsName = map[string]string{
+	"XBT": "BTC",
+	"XDG": "DOGE",
+}
+
+// supportedKrakenTokens is the set of supported Kraken tokens. Kraken lists
+// each token as a single asset with a separate deposit / withdrawal method for
+// each network.
+var supportedKrakenTokens = map[uint32]struct{}{
+	60001:  {}, // USDC on ETH
+	60002:  {}, // USDT on ETH
+	966001: {}, // USDC on POLYGON
+	966004: {}, // USDT on POLYGON
+}
+
+// krakenNetworkKeywords are the strings that identify the network of a
+// Kraken funding method, keyed by the asset ID of the network's base chain.
+var krakenNetworkKeywords = map[uint32][]string{
+	60:  {"ERC20", "Ethereum"},
+	966: {"Polygon"},
+}
+
+// krakenNonNativeMethodKeywords identify funding methods that are not the
+// base chain of a native asset, e.g. "Bitcoin Lightning" or
+// "Ethereum (Arbitrum One)".
+var krakenNonNativeMethodKeywords = []string{"Lightning", "Arbitrum", "Optimism", "Base", "Polygon", "Linea", "Unichain", "zkSync"}
+
+// krakenAltName returns the Kraken alt name for a DEX asset symbol.
+func krakenAltName(dexSymbol string) string {
+	sym := strings.Split(dexSymbol, ".")[0]
+	if alt, found := dexToKrakenSymbol[sym]; found {
+		return alt
+	}
+	return strings.ToUpper(sym)
+}
+
+// krakenWsName returns the name used by the v2 websocket API for a Kraken alt
+// name.
+func krakenWsName(alt string) string {
+	if name, found := krakenAltToWsName[alt]; found {
+		return name
+	}
+	return alt
+}
+
+// krakenMethodMatchesAsset returns whether a Kraken deposit or withdrawal
+// method can be used for the DEX asset.
+func krakenMethodMatchesAsset(method string, assetID uint32) bool {
+	containsAny := func(keywords []string) bool {
+		for _, kw := range keywords {
+			if strings.Contains(method, kw) {
+				return true
+			}
+		}
+		return false
+	}
+	if token := asset.TokenInfo(assetID); token != nil {
+		return containsAny(krakenNetworkKeywords[token.ParentID])
+	}
+	return !containsAny(krakenNonNativeMethodKeywords)
+}
+
+// KrakenError is an error returned by the Kraken REST API.
+type KrakenError struct {
+	Errors []string
+}
+
+func (e *KrakenError) Error() string {
+	return strings.Join(e.Errors, ", ")
+}
+
+// krakenWSConn manages a websocket connection to the Kraken v2 API. The
+// subscriptions are (re)sent every time the connection is established.
+type krakenWSConn struct {
+	wsConn     comms.WsConn
+	url        string
+	log        dex.Logger
+	subs       func() ([]*ktypes.WsRequest, error)
+	msgHandler func(*ktypes.WsMessage)
+	setSynced  func(bool)
+}
+
+func newKrakenWSConn(url string, subs func() ([]*ktypes.WsRequest, error), msgHandler func(*ktypes.WsMessage), setSynced func(bool), log dex.Logger) *krakenWSConn {
+	return &krakenWSConn{
+		url:        url,
+		subs:       subs,
+		msgHandler: msgHandler,
+		setSynced:  setSynced,
+		log:        log,
+	}
+}
+
+func (c *krakenWSConn) send(req *ktypes.WsRequest) error {
+	b, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	return c.wsConn.SendRaw(b)
+}
+
+func (c *krakenWSConn) subscribe() error {
+	reqs, err := c.subs()
+	if err != nil {
+		return err
+	}
+	for _, req := range reqs {
+		if err := c.send(req); err != nil {
+			return fmt.Errorf("error subscribing to %s: %w", req.Params.Channel, err)
+		}
+	}
+	return nil
+}
+
+func (c *krakenWSConn) handleWebsocketMessage(b []byte) {
+	msg := new(ktypes.WsMessage)
+	if err := json.Unmarshal(b, msg); err != nil {
+		c.log.Errorf("Error unmarshaling websocket message: %v", err)
+		c.log.Errorf("Raw Message: %s", string(b))
+		return
+	}
+
+	if msg.Method != "" {
+		if msg.Success != nil && !*msg.Success {
+			c.log.Errorf("Websocket %s request failed: %s", msg.Method, msg.Error)
+		}
+		return
+	}
+
+	switch msg.Channel {
+	case "heartbeat", "status":
+	default:
+		c.msgHandler(msg)
+	}
+}
+
+func (c *krakenWSConn) Connect(ctx context.Context) (*sync.WaitGroup, error) {
+	initialConnect := true
+	conn, err := comms.NewWsConn(&comms.WsCfg{
+		URL: c.url,
+		// Kraken sends a heartbeat every second when subscribed to any
+		// channel, so if no messages come for one minute, we are
+		// disconnected.
+		PingWait:      time.Minute,
+		ReconnectSync: func() {},
+		ConnectEventFunc: func(cs comms.ConnectionStatus) {
+			if cs != comms.Connected && cs != comms.Disconnected {
+				return
+			}
+
+			if cs == comms.Connected && initialConnect {
+				initialConnect = false
+			} else if cs == comms.Connected {
+				if err := c.subscribe(); err != nil {
+					c.log.Errorf("Error resubscribing after reconnect: %v", err)
+				}
+			} else { // Disconnected
+				c.setSynced(false)
+			}
+		},
+		Logger:     c.log,
+		RawHandler: c.handleWebsocketMessage,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating WsConn: %w", err)
+	}
+
+	cm := dex.NewConnectionMaster(conn)
+	if err := cm.ConnectOnce(ctx); err != nil {
+		return nil, fmt.Errorf("error connecting to websocket feed: %w", err)
+	}
+
+	c.wsConn = conn
+
+	if err := c.subscribe(); err != nil {
+		cm.Disconnect()
+		return nil, err
+	}
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-ctx.Done()
+		cm.Disconnect()
+	}()
+
+	return &wg, nil
+}
+
+// krakenBook maintains the order book for a single Kraken market using its
+// own websocket connection.
+type krakenBook struct {
+	mtx            sync.RWMutex
+	numSubscribers uint32
+
+	cm     *dex.ConnectionMaster
+	conn   *krakenWSConn
+	synced atomic.Bool
+	symbol string
+	wsURL  string
+	book   *orderbook
+	bui    *dex.UnitInfo
+	qui    *dex.UnitInfo
+	log    dex.Logger
+
+	pairDecimals int
+	lotDecimals  int
+	// verifyChecksum is false if the precision of the DEX assets is
+	// insufficient to reproduce the strings that Kraken uses to calculate the
+	// checksum.
+	verifyChecksum bool
+}
+
+func newKrakenBook(wsURL string, pair *ktypes.AssetPair, bui, qui *dex.UnitInfo, log dex.Logger) *krakenBook {
+	bFactor := float64(bui.Conventional.ConversionFactor)
+	qFactor := float64(qui.Conventional.ConversionFactor)
+	baseDecimals := int(math.Round(math.Log10(bFactor)))
+	rateDecimals := int(math.Floor(math.Log10(calc.RateEncodingFactor * qFactor / bFactor)))
+	symbol := krakenWsName(pair.BaseAlt) + "/" + krakenWsName(pair.QuoteAlt)
+	return &krakenBook{
+		wsURL:          wsURL,
+		symbol:         symbol,
+		book:           newOrderBook(),
+		bui:            bui,
+		qui:            qui,
+		log:            log.SubLogger(symbol),
+		pairDecimals:   pair.PairDecimals,
+		lotDecimals:    pair.LotDecimals,
+		verifyChecksum: baseDecimals >= pair.LotDecimals && rateDecimals >= pair.PairDecimals,
+	}
+}
+
+func (b *krakenBook) convertLevels(levels []*ktypes.BookLevel) ([]*obEntry, error) {
+	entries := make([]*obEntry, 0, len(levels))
+	for _, level := range levels {
+		price, err := level.Price.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("error parsing price %q: %w", level.Price, err)
+		}
+		qty, err := level.Qty.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("error parsing qty %q: %w", level.Qty, err)
+		}
+		entries = append(entries, &obEntry{
+			qty:  toAtomic(qty, b.bui),
+			rate: messageRate(price, b.bui, b.qui),
+		})
+	}
+	return entries, nil
+}
+
+// krakenBookChecksum calculates the CRC32 checksum of the top levels of the
+// book as described in https://docs.kraken.com/api/docs/guides/spot-ws-book-v2.
+// The prices and quantities are formatted with the precision of the pair,
+// the decimal point and leading zeros are removed, and the resulting strings
+// for the asks and then the bids are concatenated.
+func krakenBookChecksum(bids, asks []*obEntry, bui, qui *dex.UnitInfo, pairDecimals, lotDecimals int) uint32 {
+	bFactor, qFactor := bui.Conventional.ConversionFactor, qui.Conventional.ConversionFactor
+	format := func(v float64, decimals int) string {
+		s := strconv.FormatFloat(v, 'f', decimals, 64)
+		return strings.TrimLeft(strings.Replace(s, ".", "", 1), "0")
+	}
+	var sb strings.Builder
+	write := func(entries []*obEntry) {
+		for i, e := range entries {
+			if i == krakenChecksumDepth {
+				break
+			}
+			sb.WriteString(format(calc.ConventionalRateAlt(e.rate, bFactor, qFactor), pairDecimals))
+			sb.WriteString(format(float64(e.qty)/float64(bFactor), lotDecimals))
+		}
+	}
+	write(asks)
+	write(bids)
+	return crc32.ChecksumIEEE([]byte(sb.String()))
+}
+
+func (b *krakenBook) subscriptions(subscribe bool) []*ktypes.WsRequest {
+	method := "subscribe"
+	if !subscribe {
+		method = "unsubscribe"
+	}
+	return []*ktypes.WsRequest{{
+		Method: method,
+		Params: &ktypes.SubscribeParams{
+			Channel: "book",
+			Symbol:  []string{b.symbol},
+			Depth:   krakenBookDepth,
+		},
+	}}
+}
+
+// resync unsubscribes from the book channel and subscribes again, which
+// causes Kraken to send a new snapshot.
+func (b *krakenBook) resync() {
+	b.synced.Store(false)
+	for _, req := range b.subscriptions(false) {
+		if err := b.conn.send(req); err != nil {
+			b.log.Errorf("Error unsubscribing from book: %v", err)
+		}
+	}
+	if err := b.conn.subscribe(); err != nil {
+		b.log.Errorf("Error resubscribing to book: %v", err)
+	}
+}
+
+func (b *krakenBook) handleBookMessage(msg *ktypes.WsMessage) {
+	if msg.Channel != "book" {
+		return
+	}
+
+	var data []*ktypes.BookData
+	if err := json.Unmarshal(msg.Data, &data); err != nil {
+		b.log.Errorf("Error unmarshaling book data: %v", err)
+		return
+	}
+
+	snapshot := msg.Type == "snapshot"
+	for _, d := range data {
+		if d.Symbol != b.symbol {
+			continue
+		}
+		if !snapshot && !b.synced.Load() {
+			// Waiting for a snapshot after a resync.
+			continue
+		}
+		bids, err := b.convertLevels(d.Bids)
+		if err != nil {
+			b.log.Errorf("Error converting bids: %v", err)
+			b.resync()
+			return
+		}
+		asks, err := b.convertLevels(d.Asks)
+		if err != nil {
+			b.log.Errorf("Error converting asks: %v", err)
+			b.resync()
+			return
+		}
+
+		if snapshot {
+			b.book.clear()
+		}
+		b.book.update(bids, asks)
+		b.book.truncate(krakenBookDepth)
+
+		if b.verifyChecksum {
+			bookBids, bookAsks := b.book.snap()
+			checksum := krakenBookChecksum(bookBids, bookAsks, b.bui, b.qui, b.pairDecimals, b.lotDecimals)
+			if checksum != d.Checksum {
+				b.log.Errorf("Book checksum mismatch. Expected %d, calculated %d. Resyncing.", d.Checksum, checksum)
+				b.resync()
+				return
+			}
+		}
+
+		if snapshot {
+			b.log.Infof("Book synced")
+			b.synced.Store(true)
+		}
+	}
+}
+
+func (b *krakenBook) midGap() (uint64, error) {
+	if !b.synced.Load() {
+		return 0, ErrUnsyncedOrderbook
+	}
+
+	return b.book.midGap(), nil
+}
+
+func (b *krakenBook) vwap(bids bool, qty uint64) (vwap, extrema uint64, filled bool, err error) {
+	if !b.synced.Load() {
+		return 0, 0, false, ErrUnsyncedOrderbook
+	}
+
+	vwap, extrema, filled = b.book.vwap(bids, qty)
+	return
+}
+
+func (b *krakenBook) invVWAP(bids bool, qty uint64) (vwap, extrema uint64, filled bool, err error) {
+	if !b.synced.Load() {
+		return 0, 0, false, ErrUnsyncedOrderbook
+	}
+
+	vwap, extrema, filled = b.book.invVWAP(bids, qty)
+	return
+}
+
+func (b *krakenBook) Connect(ctx context.Context) (*sync.WaitGroup, error) {
+	subs := func() ([]*ktypes.WsRequest, error) {
+		return b.subscriptions(true), nil
+	}
+	b.conn = newKrakenWSConn(b.wsURL, subs, b.handleBookMessage, b.synced.Store, b.log)
+	wsCM := dex.NewConnectionMaster(b.conn)
+	if err := wsCM.ConnectOnce(ctx); err != nil {
+		return nil, fmt.Errorf("error connecting to websocket feed: %w", err)
+	}
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-ctx.Done()
+		wsCM.Disconnect()
+	}()
+
+	return &wg, nil
+}
+
+func (b *krakenBook) sync(ctx context.Context) error {
+	cm := dex.NewConnectionMaster(b)
+	b.mtx.Lock()
+	b.cm = cm
+	b.numSubscribers++
+	b.mtx.Unlock()
+	return cm.ConnectOnce(ctx)
+}
+
+// krakenTradeInfo is the information about a trade that is needed to send
+// trade updates. Kraken reports the fees of each fill separately, so the
+// fees are accumulated here.
+type krakenTradeInfo struct {
+	tradeInfo
+	baseFees  uint64
+	quoteFees uint64
+	// execIDs are the IDs of the fills whose fees have been counted.
+	execIDs map[string]bool
+}
+
+type kraken struct {
+	log       dex.Logger
+	url       string
+	wsURL     string
+	authWsURL string
+	apiKey    string
+	secretKey string
+	net       dex.Network
+	broadcast func(interface{})
+	ctx       context.Context
+
+	// altIDs maps the Kraken alt names to the DEX asset IDs.
+	altIDs map[string][]uint32
+	// idAlt maps the DEX asset IDs to the Kraken alt names.
+	idAlt map[uint32]string
+
+	nonce              atomic.Uint64
+	tradeIDNonce       atomic.Uint32
+	tradeIDNoncePrefix dex.Bytes
+
+	assets atomic.Value // map[string]*ktypes.Asset, Kraken asset name -> asset
+	pairs  atomic.Value // map[string]*ktypes.AssetPair, BASEALT/QUOTEALT -> pair
+
+	marketSnapshotMtx sync.RWMutex
+	marketSnapshot    struct {
+		stamp time.Time
+		m     map[string]*Market
+	}
+
+	balanceMtx     sync.RWMutex
+	balances       map[uint32]*ExchangeBalance
+	refreshBalance chan struct{}
+
+	depositMethodsMtx sync.Mutex
+	depositMethods    map[uint32]string
+
+	// subMarketMtx must be held while subscribing or unsubscribing to a
+	// market.
+	subMarketMtx sync.Mutex
+
+	booksMtx sync.RWMutex
+	books    map[string]*krakenBook
+
+	tradeUpdaterMtx    sync.RWMutex
+	tradeInfo          map[string]*krakenTradeInfo // keyed by client order ID
+	tradeUpdaters      map[int]chan *Trade
+	tradeUpdateCounter int
+}
+
+var _ CEX = (*kraken)(nil)
+
+func newKraken(cfg *CEXConfig) (*kraken, error) {
+	if cfg.Net != dex.Mainnet {
+		return nil, fmt.Errorf("kraken is only supported on mainnet")
+	}
+
+	altIDs := make(map[string][]uint32)
+	idAlt := make(map[uint32]string)
+	addAsset := func(assetID uint32, symbol string) {
+		alt := krakenAltName(symbol)
+		altIDs[alt] = append(altIDs[alt], assetID)
+		idAlt[assetID] = alt
+	}
+	for _, a := range asset.Assets() {
+		addAsset(a.ID, a.Symbol)
+		for tokenID := range a.Tokens {
+			if _, supported := supportedKrakenTokens[tokenID]; supported {
+				addAsset(tokenID, dex.BipIDSymbol(tokenID))
+			}
+		}
+	}
+
+	k := &kraken{
+		log:                cfg.Logger,
+		url:                krakenHTTPURL,
+		wsURL:              krakenWsURL,
+		authWsURL:          krakenAuthWsURL,
+		apiKey:             cfg.APIKey,
+		secretKey:          cfg.SecretKey,
+		net:                cfg.Net,
+		broadcast:          cfg.Notify,
+		altIDs:             altIDs,
+		idAlt:              idAlt,
+		tradeIDNoncePrefix: encode.RandomBytes(12),
+		balances:           make(map[uint32]*ExchangeBalance),
+		refreshBalance:     make(chan struct{}, 1),
+		depositMethods:     make(map[uint32]string),
+		books:              make(map[string]*krakenBook),
+		tradeInfo:          make(map[string]*krakenTradeInfo),
+		tradeUpdaters:      make(map[int]chan *Trade),
+	}
+	// Kraken requires the nonce to always increase for an API key, including
+	// across restarts.
+	k.nonce.Store(uint64(time.Now().UnixNano()))
+	k.pairs.Store(make(map[string]*ktypes.AssetPair))
+	return k, nil
+}
+
+// krakenSignature generates the API-Sign header for a private request.
+func krakenSignature(path, nonce, postData, secret string) (string, error) {
+	key, err := base64.StdEncoding.DecodeString(secret)
+	if err != nil {
+		return "", fmt.Errorf("error decoding secret key: %w", err)
+	}
+	sha := sha256.Sum256([]byte(nonce + postData))
+	mac := hmac.New(sha512.New, key)
+	mac.Write(append([]byte(path), sha[:]...))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+func (k *kraken) getAPI(ctx context.Context, endpoint string, query url.Values, thing interface{}) error {
+	return k.request(ctx, http.MethodGet, endpoint, query, thing)
+}
+
+func (k *kraken) postAPI(ctx context.Context, endpoint string, form url.Values, thing interface{}) error {
+	return k.request(ctx, http.MethodPost, endpoint, form, thing)
+}
+
+// request sends a request to the Kraken REST API. GET requests are public
+// and POST requests are private and signed.
+func (k *kraken) request(ctx context.Context, method, endpoint string, params url.Values, thing interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*30)
+	defer cancel()
+
+	if params == nil {
+		params = make(url.Values)
+	}
+
+	var req *http.Request
+	var err error
+	if method == http.MethodGet {
+		fullURL := k.url + endpoint
+		if len(params) > 0 {
+			fullURL += "?" + params.Encode()
+		}
+		req, err = http.NewRequestWithContext(ctx, method, fullURL, nil)
+		if err != nil {
+			return fmt.Errorf("error generating http request: %w", err)
+		}
+	} else {
+		nonce := strconv.FormatUint(k.nonce.Add(1), 10)
+		params.Set("nonce", nonce)
+		body := params.Encode()
+		req, err = http.NewRequestWithContext(ctx, method, k.url+endpoint, strings.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("error generating http request: %w", err)
+		}
+		sig, err := krakenSignature(endpoint, nonce, body, k.secretKey)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("API-Key", k.apiKey)
+		req.Header.Set("API-Sign", sig)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	var resp ktypes.Response
+	var errCode int
+	if err := dexnet.Do(req, &resp, dexnet.WithStatusFunc(func(code int) { errCode = code })); err != nil {
+		return fmt.Errorf("%s %s error (%d): %w", method, endpoint, errCode, err)
+	}
+	if len(resp.Error) > 0 {
+		return fmt.Errorf("%s %s error: %w", method, endpoint, &KrakenError{Errors: resp.Error})
+	}
+	if thing == nil {
+		return nil
+	}
+	if err := json.Unmarshal(resp.Result, thing); err != nil {
+		return fmt.Errorf("error unmarshaling %s result: %w", endpoint, err)
+	}
+	return nil
+}
+
+func (k *kraken) updateAssets(ctx context.Context) error {
+	var assets map[string]*ktypes.Asset
+	if err := k.getAPI(ctx, "/0/public/Assets", nil, &assets); err != nil {
+		return err
+	}
+	k.assets.Store(assets)
+	return nil
+}
+
+// assetName returns the Kraken asset name, e.g. XXBT, for the alt name, e.g.
+// XBT.
+func (k *kraken) assetName(alt string) (string, error) {
+	assets, _ := k.assets.Load().(map[string]*ktypes.Asset)
+	for name, a := range assets {
+		if a.AltName == alt {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("no Kraken asset found for %s", alt)
+}
+
+// altName returns the alt name, e.g. XBT, for the Kraken asset name, e.g.
+// XXBT.
+func (k *kraken) altName(name string) (string, bool) {
+	assets, _ := k.assets.Load().(map[string]*ktypes.Asset)
+	a, found := assets[name]
+	if !found {
+		return "", false
+	}
+	return a.AltName, true
+}
+
+// parseAssetPair fills in the fields of the pair that are expressed in the
+// atomic units of the DEX assets.
+func parseAssetPair(pair *ktypes.AssetPair, bui, qui *dex.UnitInfo) {
+	bFactor := float64(bui.Conventional.ConversionFactor)
+	qFactor := float64(qui.Conventional.ConversionFactor)
+	pair.LotSize = uint64(math.Max(1, math.Round(math.Pow10(-pair.LotDecimals)*bFactor)))
+	pair.MinQty = uint64(math.Round(pair.OrderMin * bFactor))
+	pair.MinCost = uint64(math.Round(pair.CostMin * qFactor))
+	tickSize := pair.TickSize
+	if tickSize == 0 {
+		tickSize = math.Pow10(-pair.PairDecimals)
+	}
+	pair.RateStep = uint64(math.Max(1, float64(messageRate(tickSize, bui, qui))))
+}
+
+func (k *kraken) updateMarkets(ctx context.Context) (map[string]*Market, error) {
+	if err := k.updateAssets(ctx); err != nil {
+		return nil, fmt.Errorf("error fetching assets: %w", err)
+	}
+
+	var res map[string]*ktypes.AssetPair
+	if err := k.getAPI(ctx, "/0/public/AssetPairs", nil, &res); err != nil {
+		return nil, fmt.Errorf("error fetching asset pairs: %w", err)
+	}
+
+	pairs := make(map[string]*ktypes.AssetPair, len(res))
+	dexMarkets := make(map[string][][2]uint32, len(res))
+	pairNames := make([]string, 0, len(res))
+	for name, pair := range res {
+		if pair.Status != "online" {
+			continue
+		}
+		baseAlt, found := k.altName(pair.Base)
+		if !found {
+			continue
+		}
+		quoteAlt, found := k.altName(pair.Quote)
+		if !found {
+			continue
+		}
+		baseIDs, quoteIDs := k.altIDs[baseAlt], k.altIDs[quoteAlt]
+		if len(baseIDs) == 0 || len(quoteIDs) == 0 {
+			continue
+		}
+		bui, err := asset.UnitInfo(baseIDs[0])
+		if err != nil {
+			continue
+		}
+		qui, err := asset.UnitInfo(quoteIDs[0])
+		if err != nil {
+			continue
+		}
+		pair.PairName = name
+		pair.BaseAlt = baseAlt
+		pair.QuoteAlt = quoteAlt
+		parseAssetPair(pair, &bui, &qui)
+		pairs[baseAlt+"/"+quoteAlt] = pair
+		pairNames = append(pairNames, name)
+		for _, baseID := range baseIDs {
+			for _, quoteID := range quoteIDs {
+				dexMarkets[name] = append(dexMarkets[name], [2]uint32{baseID, quoteID})
+			}
+		}
+	}
+
+	tickers := make(map[string]*ktypes.Ticker)
+	if len(pairNames) > 0 {
+		q := url.Values{"pair": []string{strings.Join(pairNames, ",")}}
+		if err := k.getAPI(ctx, "/0/public/Ticker", q, &tickers); err != nil {
+			k.log.Errorf("Error fetching tickers: %v", err)
+		}
+	}
+
+	mins, err := k.minWithdraws(ctx)
+	if err != nil {
+		k.log.Errorf("Error fetching withdraw methods: %v", err)
+	}
+
+	markets := make(map[string]*Market, len(dexMarkets))
+	for name, mkts := range dexMarkets {
+		var day *MarketDay
+		if t := tickers[name]; t != nil {
+			day = krakenMarketDay(t)
+		}
+		for _, m := range mkts {
+			markets[dex.BipIDSymbol(m[0])+"_"+dex.BipIDSymbol(m[1])] = &Market{
+				BaseID:           m[0],
+				QuoteID:          m[1],
+				Day:              day,
+				BaseMinWithdraw:  mins[m[0]],
+				QuoteMinWithdraw: mins[m[1]],
+			}
+		}
+	}
+
+	k.pairs.Store(pairs)
+
+	k.marketSnapshotMtx.Lock()
+	defer k.marketSnapshotMtx.Unlock()
+	k.marketSnapshot.m = markets
+	k.marketSnapshot.stamp = time.Now()
+	return markets, nil
+}
+
+// minWithdraws returns the minimum withdrawal amounts of the DEX assets, in
+// atomic units, from the Kraken withdrawal method matching each asset.
+func (k *kraken) minWithdraws(ctx context.Context) (map[uint32]uint64, error) {
+	var methods []*ktypes.WithdrawMethod
+	if err := k.postAPI(ctx, "/0/private/WithdrawMethods", url.Values{}, &methods); err != nil {
+		return nil, err
+	}
+	mins := make(map[uint32]uint64)
+	for _, m := range methods {
+		alt, found := k.altName(m.Asset)
+		if !found {
+			alt = m.Asset
+		}
+		for _, assetID := range k.altIDs[alt] {
+			if !krakenMethodMatchesAsset(m.Method, assetID) {
+				continue
+			}
+			ui, err := asset.UnitInfo(assetID)
+			if err != nil {
+				continue
+			}
+			mins[assetID] = toAtomic(m.Minimum, &ui)
+		}
+	}
+	return mins, nil
+}
+
+func krakenMarketDay(t *ktypes.Ticker) *MarketDay {
+	idx := func(vs []json.Number, i int) float64 {
+		if len(vs) <= i {
+			return 0
+		}
+		f, _ := vs[i].Float64()
+		return f
+	}
+	last := idx(t.LastTrade, 0)
+	open, _ := t.OpeningPx.Float64()
+	vol := idx(t.Volume, 1)
+	avg := idx(t.VWAP, 1)
+	var pctChange float64
+	if open > 0 {
+		pctChange = (last - open) / open * 100
+	}
+	return &MarketDay{
+		Vol:            vol,
+		QuoteVol:       vol * avg,
+		PriceChange:    last - open,
+		PriceChangePct: pctChange,
+		AvgPrice:       avg,
+		LastPrice:      last,
+		OpenPrice:      open,
+		HighPrice:      idx(t.High, 1),
+		LowPrice:       idx(t.Low, 1),
+	}
+}
+
+func (k *kraken) pair(baseID, quoteID uint32) (*ktypes.AssetPair, error) {
+	baseAlt, found := k.idAlt[baseID]
+	if !found {
+		return nil, fmt.Errorf("no Kraken asset for %s", dex.BipIDSymbol(baseID))
+	}
+	quoteAlt, found := k.idAlt[quoteID]
+	if !found {
+		return nil, fmt.Errorf("no Kraken asset for %s", dex.BipIDSymbol(quoteID))
+	}
+	pairs := k.pairs.Load().(map[string]*ktypes.AssetPair)
+	pair, found := pairs[baseAlt+"/"+quoteAlt]
+	if !found {
+		return nil, fmt.Errorf("no Kraken market for %s/%s", baseAlt, quoteAlt)
+	}
+	return pair, nil
+}
+
+func (k *kraken) refreshBalances(ctx context.Context) error {
+	var res map[string]*ktypes.Balance
+	if err := k.postAPI(ctx, "/0/private/BalanceEx", nil, &res); err != nil {
+		return err
+	}
+
+	balances := make(map[uint32]*ExchangeBalance)
+	for name, bal := range res {
+		alt, found := k.altName(name)
+		if !found {
+			// Includes earn and staking balances, e.g. XBT.F.
+			continue
+		}
+		for _, assetID := range k.altIDs[alt] {
+			ui, err := asset.UnitInfo(assetID)
+			if err != nil {
+				k.log.Errorf("no unit info for known asset ID %d?", assetID)
+				continue
+			}
+			balances[assetID] = &ExchangeBalance{
+				Available: toAtomic(bal.Balance-bal.HoldTrade, &ui),
+				Locked:    toAtomic(bal.HoldTrade, &ui),
+			}
+		}
+	}
+
+	updates := make([]*BalanceUpdate, 0)
+	k.balanceMtx.Lock()
+	for assetID, newBal := range balances {
+		if oldBal := k.balances[assetID]; oldBal != nil && *oldBal != *newBal {
+			updates = append(updates, &BalanceUpdate{
+				AssetID: assetID,
+				Balance: newBal,
+			})
+		}
+	}
+	k.balances = balances
+	k.balanceMtx.Unlock()
+
+	for _, u := range updates {
+		k.broadcast(u)
+	}
+
+	return nil
+}
+
+func (k *kraken) wsToken(ctx context.Context) (string, error) {
+	var res ktypes.WebSocketsToken
+	if err := k.postAPI(ctx, "/0/private/GetWebSocketsToken", nil, &res); err != nil {
+		return "", err
+	}
+	return res.Token, nil
+}
+
+func (k *kraken) handleUserMessage(msg *ktypes.WsMessage) {
+	switch msg.Channel {
+	case "executions":
+		var execs []*ktypes.Execution
+		if err := json.Unmarshal(msg.Data, &execs); err != nil {
+			k.log.Errorf("Error unmarshaling executions: %v", err)
+			return
+		}
+		for _, exec := range execs {
+			k.handleExecution(exec)
+		}
+	case "balances":
+		if msg.Type != "update" {
+			return
+		}
+		// The balances channel does not report the amount on hold for
+		// open orders, so the balances are fetched from the REST API.
+		select {
+		case k.refreshBalance <- struct{}{}:
+		default:
+		}
+	default:
+		k.log.Debugf("Message for unknown channel %q", msg.Channel)
+	}
+}
+
+func krakenOrderComplete(status string) bool {
+	return status == "filled" || status == "canceled" || status == "expired"
+}
+
+func (k *kraken) handleExecution(exec *ktypes.Execution) {
+	if exec.ClientOrderID == "" {
+		// Not placed by us.
+		return
+	}
+
+	k.tradeUpdaterMtx.Lock()
+	defer k.tradeUpdaterMtx.Unlock()
+
+	info, found := k.tradeInfo[exec.ClientOrderID]
+	if !found {
+		k.log.Debugf("No trade info for client order ID %s", exec.ClientOrderID)
+		return
+	}
+
+	updater, found := k.tradeUpdaters[info.updaterID]
+	if !found {
+		k.log.Errorf("No trade updater with ID %v", info.updaterID)
+		return
+	}
+
+	bui, err := asset.UnitInfo(info.baseID)
+	if err != nil {
+		k.log.Errorf("Error getting unit info for asset ID %d: %v", info.baseID, err)
+		return
+	}
+	qui, err := asset.UnitInfo(info.quoteID)
+	if err != nil {
+		k.log.Errorf("Error getting unit info for asset ID %d: %v", info.quoteID, err)
+		return
+	}
+
+	if exec.ExecType == "trade" && !info.execIDs[exec.ExecID] {
+		info.execIDs[exec.ExecID] = true
+		baseName, quoteName := krakenWsName(k.idAlt[info.baseID]), krakenWsName(k.idAlt[info.quoteID])
+		for _, fee := range exec.Fees {
+			switch fee.Asset {
+			case baseName:
+				info.baseFees += toAtomic(fee.Qty, &bui)
+			case quoteName:
+				info.quoteFees += toAtomic(fee.Qty, &qui)
+			default:
+				k.log.Errorf("Unknown fee asset %q for trade %s", fee.Asset, exec.OrderID)
+			}
+		}
+	}
+
+	complete := krakenOrderComplete(exec.OrderStatus)
+	updater <- &Trade{
+		ID:          exec.OrderID,
+		Sell:        info.sell,
+		Rate:        info.rate,
+		Qty:         info.qty,
+		Market:      info.market,
+		BaseID:      info.baseID,
+		QuoteID:     info.quoteID,
+		BaseFilled:  utils.SafeSub(toAtomic(exec.CumQty, &bui), info.baseFees),
+		QuoteFilled: utils.SafeSub(toAtomic(exec.CumCost, &qui), info.quoteFees),
+		Complete:    complete,
+	}
+
+	if complete {
+		delete(k.tradeInfo, exec.ClientOrderID)
+	}
+}
+
+func (k *kraken) subscribeUserChannels(ctx context.Context) (*sync.WaitGroup, error) {
+	subs := func() ([]*ktypes.WsRequest, error) {
+		// A new token is required every time we connect.
+		token, err := k.wsToken(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error getting websockets token: %w", err)
+		}
+		f := false
+		return []*ktypes.WsRequest{{
+			Method: "subscribe",
+			Params: &ktypes.SubscribeParams{
+				Channel:    "executions",
+				Token:      token,
+				SnapOrders: &f,
+				SnapTrades: &f,
+			},
+		}, {
+			Method: "subscribe",
+			Params: &ktypes.SubscribeParams{
+				Channel:  "balances",
+				Token:    token,
+				Snapshot: &f,
+			},
+		}}, nil
+	}
+	conn := newKrakenWSConn(k.authWsURL, subs, k.handleUserMessage, func(bool) {}, k.log.SubLogger("WS-private"))
+	cm := dex.NewConnectionMaster(conn)
+	if err := cm.ConnectOnce(ctx); err != nil {
+		return nil, fmt.Errorf("error connecting to websocket feed: %w", err)
+	}
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-ctx.Done()
+		cm.Disconnect()
+	}()
+
+	return &wg, nil
+}
+
+func (k *kraken) Connect(ctx context.Context) (*sync.WaitGroup, error) {
+	if _, err := k.updateMarkets(ctx); err != nil {
+		return nil, fmt.Errorf("error fetching markets: %w", err)
+	}
+
+	if err := k.refreshBalances(ctx); err != nil {
+		return nil, fmt.Errorf("error fetching balances: %w", err)
+	}
+
+	k.ctx = ctx
+
+	wg, err := k.subscribeUserChannels(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error subscribing to user channels: %w", err)
+	}
+
+	// Refresh the balances when notified by the balances channel, and
+	// periodically in case a notification is missed.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			case <-k.refreshBalance:
+			}
+			if err := k.refreshBalances(ctx); err != nil {
+				k.log.Errorf("Error refreshing balances: %v", err)
+			}
+		}
+	}()
+
+	// Update markets every 10 minutes. These shouldn't change often.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(time.Minute * 10)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := k.updateMarkets(ctx); err != nil {
+					k.log.Errorf("Error fetching markets: %v", err)
+				}
+			}
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-ctx.Done()
+
+		k.booksMtx.RLock()
+		defer k.booksMtx.RUnlock()
+		for _, book := range k.books {
+			book.cm.Disconnect()
+		}
+	}()
+
+	return wg, nil
+}
+
+// Balance returns the balance of an asset at the CEX.
+func (k *kraken) Balance(assetID uint32) (*ExchangeBalance, error) {
+	k.balanceMtx.RLock()
+	defer k.balanceMtx.RUnlock()
+
+	bal, found := k.balances[assetID]
+	if !found {
+		return nil, fmt.Errorf("no %s balance found", dex.BipIDSymbol(assetID))
+	}
+	return bal, nil
+}
+
+// Balances returns the balances of known assets on the CEX.
+func (k *kraken) Balances(ctx context.Context) (map[uint32]*ExchangeBalance, error) {
+	if k.assets.Load() == nil {
+		if err := k.updateAssets(ctx); err != nil {
+			return nil, fmt.Errorf("error fetching assets: %w", err)
+		}
+	}
+
+	if err := k.refreshBalances(ctx); err != nil {
+		return nil, err
+	}
+
+	k.balanceMtx.RLock()
+	defer k.balanceMtx.RUnlock()
+
+	balances := make(map[uint32]*ExchangeBalance, len(k.balances))
+	for assetID, bal := range k.balances {
+		b := *bal
+		balances[assetID] = &b
+	}
+	return balances, nil
+}
+
+// CancelTrade cancels a trade on the CEX.
+func (k *kraken) CancelTrade(ctx context.Context, baseID, quoteID uint32, tradeID string) error {
+	var res ktypes.CancelOrderResult
+	if err := k.postAPI(ctx, "/0/private/CancelOrder", url.Values{"txid": []string{tradeID}}, &res); err != nil {
+		return fmt.Errorf("error cancelling order: %w", err)
+	}
+	if res.Count == 0 {
+		return fmt.Errorf("order %s not cancelled", tradeID)
+	}
+	return nil
+}
+
+// Markets returns the list of markets at the CEX.
+func (k *kraken) Markets(ctx context.Context) (map[string]*Market, error) {
+	k.marketSnapshotMtx.RLock()
+	const snapshotTimeout = time.Minute * 30
+	if k.marketSnapshot.m != nil && time.Since(k.marketSnapshot.stamp) < snapshotTimeout {
+		defer k.marketSnapshotMtx.RUnlock()
+		return k.marketSnapshot.m, nil
+	}
+	k.marketSnapshotMtx.RUnlock()
+
+	return k.updateMarkets(ctx)
+}
+
+// SubscribeMarket subscribes to order book updates on a market. This must
+// be called before calling VWAP or MidGap.
+func (k *kraken) SubscribeMarket(ctx context.Context, baseID, quoteID uint32) error {
+	k.subMarketMtx.Lock()
+	defer k.subMarketMtx.Unlock()
+
+	pair, err := k.pair(baseID, quoteID)
+	if err != nil {
+		return err
+	}
+	bui, err := asset.UnitInfo(baseID)
+	if err != nil {
+		return fmt.Errorf("error getting unit info for base asset ID %d: %v", baseID, err)
+	}
+	qui, err := asset.UnitInfo(quoteID)
+	if err != nil {
+		return fmt.Errorf("error getting unit info for quote asset ID %d: %v", quoteID, err)
+	}
+
+	k.booksMtx.RLock()
+	book, exists := k.books[pair.PairName]
+	k.booksMtx.RUnlock()
+	if exists {
+		book.mtx.Lock()
+		book.numSubscribers++
+		book.mtx.Unlock()
+		return nil
+	}
+
+	book = newKrakenBook(k.wsURL, pair, &bui, &qui, k.log)
+	if err := book.sync(k.ctx); err != nil {
+		return fmt.Errorf("error syncing book: %v", err)
+	}
+
+	k.booksMtx.Lock()
+	k.books[pair.PairName] = book
+	k.booksMtx.Unlock()
+
+	return nil
+}
+
+// SubscribeTradeUpdates returns a channel that the caller can use to
+// listen for updates to a trade's status. When the subscription ID
+// returned from this function is passed as the updaterID argument to
+// Trade, then updates to the trade will be sent on the updated channel
+// returned from this function.
+func (k *kraken) SubscribeTradeUpdates() (<-chan *Trade, func(), int) {
+	k.tradeUpdaterMtx.Lock()
+	defer k.tradeUpdaterMtx.Unlock()
+
+	updaterID := k.tradeUpdateCounter
+	k.tradeUpdateCounter++
+	updater := make(chan *Trade, 256)
+	k.tradeUpdaters[updaterID] = updater
+
+	unsubscribe := func() {
+		k.tradeUpdaterMtx.Lock()
+		delete(k.tradeUpdaters, updaterID)
+		k.tradeUpdaterMtx.Unlock()
+	}
+
+	return updater, unsubscribe, updaterID
+}
+
+// generateTradeID generates a client order ID. Kraken accepts 32 hex
+// characters as a client order ID.
+func (k *kraken) generateTradeID() string {
+	nonce := k.tradeIDNonce.Add(1)
+	nonceB := encode.Uint32Bytes(nonce)
+	return hex.EncodeToString(append(k.tradeIDNoncePrefix, nonceB...))
+}
+
+// buildKrakenOrderRequest builds the form values for an AddOrder request.
+// The fee is always charged in the asset that is received, so that the filled
+// amounts can be reported the same way as the other exchanges.
+func buildKrakenOrderRequest(pair *ktypes.AssetPair, bui, qui *dex.UnitInfo, sell bool, orderType OrderType, rate, qty, quoteQty uint64, tradeID string) (url.Values, uint64, error) {
+	if qty > 0 && quoteQty > 0 {
+		return nil, 0, fmt.Errorf("cannot specify both quantity and quote quantity")
+	}
+	if sell && quoteQty > 0 {
+		return nil, 0, fmt.Errorf("quote quantity cannot be used for sell orders")
+	}
+	if !sell && orderType == OrderTypeMarket && qty > 0 {
+		return nil, 0, fmt.Errorf("quoteQty MUST be used for market buys")
+	}
+
+	bFactor := bui.Conventional.ConversionFactor
+	qFactor := qui.Conventional.ConversionFactor
+
+	v := make(url.Values)
+	v.Set("pair", pair.AltName)
+	if sell {
+		v.Set("type", "sell")
+		v.Set("oflags", "fciq")
+	} else {
+		v.Set("type", "buy")
+		v.Set("oflags", "fcib")
+	}
+	if tradeID != "" {
+		v.Set("cl_ord_id", tradeID)
+	}
+
+	if orderType == OrderTypeMarket {
+		v.Set("ordertype", "market")
+		if quoteQty > 0 {
+			if quoteQty < pair.MinCost {
+				return nil, 0, fmt.Errorf("quote quantity %s is lower than the minimum %s",
+					qui.FormatConventional(quoteQty), qui.FormatConventional(pair.MinCost))
+			}
+			// Market buys are specified in units of the quote asset.
+			v.Set("oflags", "fcib,viqc")
+			convQty := float64(quoteQty) / float64(qFactor)
+			v.Set("volume", strconv.FormatFloat(convQty, 'f', pair.CostDecimals, 64))
+			return v, quoteQty, nil
+		}
+	} else {
+		v.Set("ordertype", "limit")
+		if orderType == OrderTypeLimitIOC {
+			v.Set("timeinforce", "IOC")
+		}
+		if rate == 0 {
+			return nil, 0, fmt.Errorf("rate must be specified for limit orders")
+		}
+		rate = steppedRate(rate, pair.RateStep)
+		convRate := calc.ConventionalRateAlt(rate, bFactor, qFactor)
+		v.Set("price", strconv.FormatFloat(convRate, 'f', pair.PairDecimals, 64))
+		if quoteQty > 0 {
+			qty = calc.QuoteToBase(rate, quoteQty)
+		}
+	}
+
+	if qty == 0 {
+		return nil, 0, fmt.Errorf("must specify quantity or quote quantity")
+	}
+	qty = steppedQty(qty, pair.LotSize)
+	if qty < pair.MinQty {
+		return nil, 0, fmt.Errorf("quantity %s is lower than the minimum %s",
+			bui.FormatConventional(qty), bui.FormatConventional(pair.MinQty))
+	}
+	if orderType != OrderTypeMarket && calc.BaseToQuote(rate, qty) < pair.MinCost {
+		return nil, 0, fmt.Errorf("order cost %s is lower than the minimum %s",
+			qui.FormatConventional(calc.BaseToQuote(rate, qty)), qui.FormatConventional(pair.MinCost))
+	}
+	convQty := float64(qty) / float64(bFactor)
+	v.Set("volume", strconv.FormatFloat(convQty, 'f', pair.LotDecimals, 64))
+
+	return v, qty, nil
+}
+
+// Trade executes a trade on the CEX.
+//   - subscriptionID takes an ID returned from SubscribeTradeUpdates.
+//   - Rate is ignored for market orders.
+//   - Qty is in units of base asset, quoteQty is in units of quote asset.
+//     Only one of qty or quoteQty should be non-zero.
+//   - QuoteQty is only allowed for BUY orders, and it is required for market
+//     buy orders.
+func (k *kraken) Trade(ctx context.Context, baseID, quoteID uint32, sell bool, rate, qty, quoteQty uint64, orderType OrderType, subscriptionID int) (*Trade, error) {
+	pair, err := k.pair(baseID, quoteID)
+	if err != nil {
+		return nil, err
+	}
+	bui, err := asset.UnitInfo(baseID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting unit info for base asset ID %d: %v", baseID, err)
+	}
+	qui, err := asset.UnitInfo(quoteID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting unit info for quote asset ID %d: %v", quoteID, err)
+	}
+
+	tradeID := k.generateTradeID()
+	v, qtyInRequest, err := buildKrakenOrderRequest(pair, &bui, &qui, sell, orderType, rate, qty, quoteQty, tradeID)
+	if err != nil {
+		return nil, fmt.Errorf("error building order request: %w", err)
+	}
+
+	// The trade info is stored before the order is placed, because the
+	// execution reports may arrive before the AddOrder response.
+	market := orderType == OrderTypeMarket
+	k.tradeUpdaterMtx.Lock()
+	if _, found := k.tradeUpdaters[subscriptionID]; !found {
+		k.tradeUpdaterMtx.Unlock()
+		return nil, fmt.Errorf("no trade updater with ID %v", subscriptionID)
+	}
+	k.tradeInfo[tradeID] = &krakenTradeInfo{
+		tradeInfo: tradeInfo{
+			updaterID: subscriptionID,
+			baseID:    baseID,
+			quoteID:   quoteID,
+			sell:      sell,
+			rate:      rate,
+			qty:       qtyInRequest,
+			market:    market,
+		},
+		execIDs: make(map[string]bool),
+	}
+	k.tradeUpdaterMtx.Unlock()
+
+	var res ktypes.AddOrderResult
+	if err := k.postAPI(ctx, "/0/private/AddOrder", v, &res); err != nil {
+		k.tradeUpdaterMtx.Lock()
+		delete(k.tradeInfo, tradeID)
+		k.tradeUpdaterMtx.Unlock()
+		return nil, err
+	}
+	if len(res.TxIDs) != 1 {
+		return nil, fmt.Errorf("expected 1 transaction ID, got %d", len(res.TxIDs))
+	}
+
+	return &Trade{
+		ID:      res.TxIDs[0],
+		Sell:    sell,
+		Rate:    rate,
+		Qty:     qtyInRequest,
+		BaseID:  baseID,
+		QuoteID: quoteID,
+		Market:  market,
+	}, nil
+}
+
+// ValidateTrade validates a trade before it is executed.
+func (k *kraken) ValidateTrade(baseID, quoteID uint32, sell bool, rate, qty, quoteQty uint64, orderType OrderType) error {
+	pair, err := k.pair(baseID, quoteID)
+	if err != nil {
+		return err
+	}
+	bui, err := asset.UnitInfo(baseID)
+	if err != nil {
+		return fmt.Errorf("error getting unit info for base asset ID %d: %v", baseID, err)
+	}
+	qui, err := asset.UnitInfo(quoteID)
+	if err != nil {
+		return fmt.Errorf("error getting unit info for quote asset ID %d: %v", quoteID, err)
+	}
+	_, _, err = buildKrakenOrderRequest(pair, &bui, &qui, sell, orderType, rate, qty, quoteQty, "")
+	return err
+}
+
+// UnsubscribeMarket unsubscribes from order book updates on a market.
+func (k *kraken) UnsubscribeMarket(baseID, quoteID uint32) error {
+	k.subMarketMtx.Lock()
+	defer k.subMarketMtx.Unlock()
+
+	pair, err := k.pair(baseID, quoteID)
+	if err != nil {
+		return err
+	}
+
+	k.booksMtx.RLock()
+	book, found := k.books[pair.PairName]
+	k.booksMtx.RUnlock()
+	if !found {
+		return fmt.Errorf("no book found for %s", pair.AltName)
+	}
+
+	book.mtx.Lock()
+	book.numSubscribers--
+	numSubscribers := book.numSubscribers
+	book.mtx.Unlock()
+
+	if numSubscribers == 0 {
+		k.booksMtx.Lock()
+		delete(k.books, pair.PairName)
+		k.booksMtx.Unlock()
+		go book.cm.Disconnect()
+	}
+
+	return nil
+}
+
+func (k *kraken) book(baseID, quoteID uint32) (*krakenBook, error) {
+	pair, err := k.pair(baseID, quoteID)
+	if err != nil {
+		return nil, err
+	}
+
+	k.booksMtx.RLock()
+	book, found := k.books[pair.PairName]
+	k.booksMtx.RUnlock()
+	if !found {
+		return nil, fmt.Errorf("no book for market %s", pair.AltName)
+	}
+
+	return book, nil
+}
+
+// Book generates the CEX's current view of a market's orderbook.
+func (k *kraken) Book(baseID, quoteID uint32) (buys, sells []*core.MiniOrder, _ error) {
+	book, err := k.book(baseID, quoteID)
+	if err != nil {
+		return nil, nil, err
+	}
+	bids, asks := book.book.snap()
+	baseFactor := book.bui.Conventional.ConversionFactor
+	quoteFactor := book.qui.Conventional.ConversionFactor
+	buys = convertSide(bids, false, baseFactor, quoteFactor)
+	sells = convertSide(asks, true, baseFactor, quoteFactor)
+	return
+}
+
+// VWAP returns the volume weighted average price for a certain quantity
+// of the base asset on a market. SubscribeMarket must be called, and the
+// market must be synced before results can be expected.
+func (k *kraken) VWAP(baseID, quoteID uint32, sell bool, qty uint64) (vwap, extrema uint64, filled bool, err error) {
+	book, err := k.book(baseID, quoteID)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	return book.vwap(!sell, qty)
+}
+
+// InvVWAP returns the inverse volume weighted average price for a certain
+// quantity of the quote asset on a market. SubscribeMarket must be called,
+// and the market must be synced before results can be expected.
+func (k *kraken) InvVWAP(baseID, quoteID uint32, sell bool, qty uint64) (vwap, extrema uint64, filled bool, err error) {
+	book, err := k.book(baseID, quoteID)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	return book.invVWAP(!sell, qty)
+}
+
+// MidGap returns the mid-gap price for a market.
+func (k *kraken) MidGap(baseID, quoteID uint32) uint64 {
+	book, err := k.book(baseID, quoteID)
+	if err != nil {
+		k.log.Errorf("Error getting book: %v", err)
+		return 0
+	}
+
+	midGap, err := book.midGap()
+	if err != nil {
+		k.log.Errorf("Error getting mid gap: %v", err)
+		return 0
+	}
+
+	return midGap
+}
+
+// depositMethod returns the Kraken funding method for the asset.
+func (k *kraken) depositMethod(ctx context.Context, assetID uint32) (string, error) {
+	k.depositMethodsMtx.Lock()
+	defer k.depositMethodsMtx.Unlock()
+
+	if method, found := k.depositMethods[assetID]; found {
+		return method, nil
+	}
+
+	alt, found := k.idAlt[assetID]
+	if !found {
+		return "", fmt.Errorf("no Kraken asset for %s", dex.BipIDSymbol(assetID))
+	}
+
+	var methods []*ktypes.DepositMethod
+	if err := k.postAPI(ctx, "/0/private/DepositMethods", url.Values{"asset": []string{alt}}, &methods); err != nil {
+		return "", fmt.Errorf("error fetching deposit methods: %w", err)
+	}
+	for _, m := range methods {
+		if krakenMethodMatchesAsset(m.Method, assetID) {
+			k.depositMethods[assetID] = m.Method
+			return m.Method, nil
+		}
+	}
+
+	return "", fmt.Errorf("no Kraken deposit method found for %s", dex.BipIDSymbol(assetID))
+}
+
+// GetDepositAddress returns a deposit address for an asset.
+func (k *kraken) GetDepositAddress(ctx context.Context, assetID uint32) (string, error) {
+	alt, found := k.idAlt[assetID]
+	if !found {
+		return "", fmt.Errorf("no Kraken asset for %s", dex.BipIDSymbol(assetID))
+	}
+
+	method, err := k.depositMethod(ctx, assetID)
+	if err != nil {
+		return "", err
+	}
+
+	getAddrs := func(generate bool) ([]*ktypes.DepositAddress, error) {
+		v := url.Values{
+			"asset":  []string{alt},
+			"method": []string{method},
+		}
+		if generate {
+			v.Set("new", "true")
+		}
+		var addrs []*ktypes.DepositAddress
+		if err := k.postAPI(ctx, "/0/private/DepositAddresses", v, &addrs); err != nil {
+			return nil, fmt.Errorf("error fetching deposit addresses: %w", err)
+		}
+		return addrs, nil
+	}
+
+	addrs, err := getAddrs(false)
+	if err != nil {
+		return "", err
+	}
+	if len(addrs) == 0 {
+		if addrs, err = getAddrs(true); err != nil {
+			return "", err
+		}
+	}
+	if len(addrs) == 0 {
+		return "", fmt.Errorf("no deposit address returned for %s", dex.BipIDSymbol(assetID))
+	}
+
+	return addrs[0].Address, nil
+}
+
+// fundingStatus returns the status of the deposit or withdrawal with the
+// specified on-chain transaction ID or Kraken reference ID.
+func (k *kraken) fundingStatus(ctx context.Context, endpoint string, assetID uint32, txID, refID string) (*ktypes.FundingStatus, error) {
+	alt, found := k.idAlt[assetID]
+	if !found {
+		return nil, fmt.Errorf("no Kraken asset for %s", dex.BipIDSymbol(assetID))
+	}
+
+	var statuses []*ktypes.FundingStatus
+	if err := k.postAPI(ctx, endpoint, url.Values{"asset": []string{alt}}, &statuses); err != nil {
+		return nil, err
+	}
+	for _, s := range statuses {
+		if (txID != "" && s.TxID == txID) || (refID != "" && s.RefID == refID) {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("transaction not found")
+}
+
+// ConfirmDeposit checks whether a deposit has been credited and returns the
+// amount credited to the account.
+func (k *kraken) ConfirmDeposit(ctx context.Context, deposit *DepositData) (bool, uint64) {
+	status, err := k.fundingStatus(ctx, "/0/private/DepositStatus", deposit.AssetID, deposit.TxID, "")
+	if err != nil {
+		k.log.Errorf("Error getting deposit status for %s: %v", deposit.TxID, err)
+		return false, 0
+	}
+
+	switch status.Status {
+	case ktypes.FundingStatusSuccess:
+		ui, err := asset.UnitInfo(deposit.AssetID)
+		if err != nil {
+			k.log.Errorf("Failed to find unit info for asset ID %d", deposit.AssetID)
+			return true, 0
+		}
+		return true, toAtomic(status.Amount-status.Fee, &ui)
+	case ktypes.FundingStatusFailure:
+		k.log.Errorf("Deposit %s to Kraken failed", deposit.TxID)
+		return true, 0
+	default:
+		return false, 0
+	}
+}
+
+// withdrawKey returns the name of the withdrawal address book entry for the
+// address. Kraken does not allow withdrawals to addresses that are not in the
+// account's address book.
+func (k *kraken) withdrawKey(ctx context.Context, assetID uint32, address string) (string, error) {
+	alt, found := k.idAlt[assetID]
+	if !found {
+		return "", fmt.Errorf("no Kraken asset for %s", dex.BipIDSymbol(assetID))
+	}
+
+	var addrs []*ktypes.WithdrawAddress
+	if err := k.postAPI(ctx, "/0/private/WithdrawAddresses", url.Values{"asset": []string{alt}}, &addrs); err != nil {
+		return "", fmt.Errorf("error fetching withdrawal addresses: %w", err)
+	}
+	for _, a := range addrs {
+		if a.Address == address && a.Verified && krakenMethodMatchesAsset(a.Method, assetID) {
+			return a.Key, nil
+		}
+	}
+
+	return "", fmt.Errorf("%s address %s is not a verified withdrawal address in the Kraken account", dex.BipIDSymbol(assetID), address)
+}
+
+// Withdraw withdraws funds from the CEX to a certain address. The address
+// must have been added to the account's withdrawal addresses on Kraken. Kraken
+// deducts the fee from the amount, so the balance is reduced by exactly amt.
+func (k *kraken) Withdraw(ctx context.Context, assetID uint32, amt uint64, address string) (string, uint64, error) {
+	alt, found := k.idAlt[assetID]
+	if !found {
+		return "", 0, fmt.Errorf("no Kraken asset for %s", dex.BipIDSymbol(assetID))
+	}
+
+	ui, err := asset.UnitInfo(assetID)
+	if err != nil {
+		return "", 0, fmt.Errorf("error getting unit info for asset ID %d: %v", assetID, err)
+	}
+
+	key, err := k.withdrawKey(ctx, assetID, address)
+	if err != nil {
+		return "", 0, err
+	}
+
+	prec := int(math.Round(math.Log10(float64(ui.Conventional.ConversionFactor))))
+	v := url.Values{
+		"asset":   []string{alt},
+		"key":     []string{key},
+		"address": []string{address},
+		"amount":  []string{strconv.FormatFloat(toConv(amt, &ui), 'f', prec, 64)},
+	}
+	var res ktypes.WithdrawResult
+	if err := k.postAPI(ctx, "/0/private/Withdraw", v, &res); err != nil {
+		return "", 0, fmt.Errorf("error withdrawing: %w", err)
+	}
+
+	return res.RefID, amt, nil
+}
+
+// ConfirmWithdrawal checks whether a withdrawal has been completed. If the
+// withdrawal has not yet been sent, ErrWithdrawalPending is returned.
+func (k *kraken) ConfirmWithdrawal(ctx context.Context, withdrawalID string, assetID uint32) (uint64, string, error) {
+	status, err := k.fundingStatus(ctx, "/0/private/WithdrawStatus", assetID, "", withdrawalID)
+	if err != nil {
+		return 0, "", fmt.Errorf("error getting withdrawal status: %w", err)
+	}
+
+	if status.Status == ktypes.FundingStatusFailure {
+		return 0, "", fmt.Errorf("withdrawal %s failed", withdrawalID)
+	}
+	if status.TxID == "" {
+		return 0, "", ErrWithdrawalPending
+	}
+
+	ui, err := asset.UnitInfo(assetID)
+	if err != nil {
+		return 0, "", fmt.Errorf("error getting unit info for asset ID %d: %v", assetID, err)
+	}
+
+	return toAtomic(status.Amount, &ui), status.TxID, nil
+}
+
+// TradeStatus returns the current status of a trade.
+func (k *kraken) TradeStatus(ctx context.Context, id string, baseID, quoteID uint32) (*Trade, error) {
+	var res map[string]*ktypes.Order
+	if err := k.postAPI(ctx, "/0/private/QueryOrders", url.Values{"txid": []string{id}}, &res); err != nil {
+		return nil, fmt.Errorf("error fetching order status: %w", err)
+	}
+	ord, found := res[id]
+	if !found {
+		return nil, fmt.Errorf("order %s not found", id)
+	}
+
+	bui, err := asset.UnitInfo(baseID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting unit info for base asset ID %d: %v", baseID, err)
+	}
+	qui, err := asset.UnitInfo(quoteID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting unit info for quote asset ID %d: %v", quoteID, err)
+	}
+
+	return krakenOrderToTrade(id, ord, baseID, quoteID, &bui, &qui), nil
+}
+
+func krakenOrderToTrade(id string, ord *ktypes.Order, baseID, quoteID uint32, bui, qui *dex.UnitInfo) *Trade {
+	sell := ord.Description.Type == "sell"
+	market := ord.Description.OrderType == "market"
+	baseFilled := toAtomic(ord.VolumeExec, bui)
+	quoteFilled := toAtomic(ord.Cost, qui)
+	flags := strings.Split(ord.OrderFlags, ",")
+	hasFlag := func(flag string) bool {
+		for _, f := range flags {
+			if f == flag {
+				return true
+			}
+		}
+		return false
+	}
+	if hasFlag("fcib") {
+		baseFilled = utils.SafeSub(baseFilled, toAtomic(ord.Fee, bui))
+	} else if hasFlag("fciq") {
+		quoteFilled = utils.SafeSub(quoteFilled, toAtomic(ord.Fee, qui))
+	}
+
+	var qty uint64
+	if hasFlag("viqc") {
+		qty = toAtomic(ord.Volume, qui)
+	} else {
+		qty = toAtomic(ord.Volume, bui)
+	}
+
+	var rate uint64
+	if !market {
+		rate = messageRate(ord.Description.Price, bui, qui)
+	}
+
+	return &Trade{
+		ID:          id,
+		Sell:        sell,
+		Qty:         qty,
+		Rate:        rate,
+		Market:      market,
+		BaseID:      baseID,
+		QuoteID:     quoteID,
+		BaseFilled:  baseFilled,
+		QuoteFilled: quoteFilled,
+		Complete:    ord.Status == "closed" || ord.Status == "canceled" || ord.Status == "expired",
+	}
+}
@@ -0,0 +1,736 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package libxc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"decred.org/dcrdex/client/asset"
+	"decred.org/dcrdex/client/comms"
+	"decred.org/dcrdex/client/mm/libxc/ktypes"
+	"decred.org/dcrdex/dex"
+	"decred.org/dcrdex/dex/calc"
+)
+
+const (
+	krakenTestSecret = "kQH5HW/8p1uGOVjbgWA7FunAmGO8lsSUXNsu3eow76sz84Q18fWxnyRzBHCd3pd5nE9qa99HAZtuZuj6F1huXg=="
+
+	krakenAssetsFixture = `{"error":[],"result":{
+		"XXBT":{"aclass":"currency","altname":"XBT","decimals":10,"display_decimals":5,"status":"enabled"},
+		"USDT":{"aclass":"currency","altname":"USDT","decimals":8,"display_decimals":4,"status":"enabled"},
+		"DCR":{"aclass":"currency","altname":"DCR","decimals":10,"display_decimals":5,"status":"enabled"},
+		"ZUSD":{"aclass":"currency","altname":"USD","decimals":4,"display_decimals":2,"status":"enabled"}}}`
+
+	krakenAssetPairsFixture = `{"error":[],"result":{
+		"XBTUSDT":{"altname":"XBTUSDT","wsname":"XBT/USDT","base":"XXBT","quote":"USDT","cost_decimals":8,"pair_decimals":1,"lot_decimals":8,"ordermin":"0.00005","costmin":"0.5","tick_size":"0.1","status":"online"},
+		"DCRXBT":{"altname":"DCRXBT","wsname":"DCR/XBT","base":"DCR","quote":"XXBT","cost_decimals":10,"pair_decimals":7,"lot_decimals":8,"ordermin":"0.5","costmin":"0.00002","tick_size":"0.0000001","status":"online"},
+		"XXBTZUSD":{"altname":"XBTUSD","wsname":"XBT/USD","base":"XXBT","quote":"ZUSD","cost_decimals":5,"pair_decimals":1,"lot_decimals":8,"ordermin":"0.00005","costmin":"0.5","tick_size":"0.1","status":"online"}}}`
+
+	krakenTickerFixture = `{"error":[],"result":{
+		"XBTUSDT":{"a":["45285.20000","1","1.000"],"b":["45283.50000","2","2.000"],"c":["45284.00000","0.00100000"],
+			"v":["120.5","250.0"],"p":["45000.0","44900.0"],"t":[1000,2000],"l":["44000.0","43900.0"],"h":["46000.0","46100.0"],"o":"44284.00000"},
+		"DCRXBT":{"a":["0.0002500","1","1.000"],"b":["0.0002490","2","2.000"],"c":["0.0002495","1.0"],
+			"v":["100","200"],"p":["0.0002480","0.0002470"],"t":[10,20],"l":["0.0002400","0.0002400"],"h":["0.0002600","0.0002600"],"o":"0.0002450"}}}`
+
+	krakenBalanceFixture = `{"error":[],"result":{
+		"XXBT":{"balance":"1.5000000000","hold_trade":"0.2500000000"},
+		"USDT":{"balance":"1000.00000000","hold_trade":"0.00000000"},
+		"XBT.F":{"balance":"5.0000000000","hold_trade":"0.0000000000"}}}`
+
+	krakenAddOrderFixture = `{"error":[],"result":{"descr":{"order":"buy 0.10000000 XBTUSDT @ limit 45000.0"},"txid":["OUF4EM-FRGI2-MQMWZD"]}}`
+
+	krakenQueryOrdersFixture = `{"error":[],"result":{"OUF4EM-FRGI2-MQMWZD":{"cl_ord_id":"abc","status":"closed",
+		"descr":{"pair":"XBTUSDT","type":"buy","ordertype":"limit","price":"45000.0"},
+		"vol":"0.10000000","vol_exec":"0.10000000","cost":"4500.00000000","fee":"0.00026000","price":"45000.0","oflags":"fcib"}}}`
+
+	krakenDepositMethodsFixture = `{"error":[],"result":[
+		{"method":"Tether USD (TRC20)","limit":false,"fee":"0.0","gen-address":true},
+		{"method":"Tether USD (ERC20)","limit":false,"fee":"0.0","gen-address":true},
+		{"method":"Tether USD (Polygon)","limit":false,"fee":"0.0","gen-address":true}]}`
+
+	krakenDepositAddressesFixture = `{"error":[],"result":[{"address":"0x2dB0B7A4E2D8Ef0Ef1F21C8a1b0B7d2A3b4C5d6E","expiretm":"0","new":true}]}`
+
+	krakenDepositStatusFixture = `{"error":[],"result":[
+		{"method":"Tether USD (ERC20)","aclass":"currency","asset":"USDT","refid":"FTQcuak-V6Za8qrWnhzTx67yYHz8Tg","txid":"0xdeposit","info":"0x2dB0","amount":"100.00000000","fee":"1.00000000","time":1688992722,"status":"Success"}]}`
+
+	krakenWithdrawAddressesFixture = `{"error":[],"result":[
+		{"address":"bc1qother","asset":"XBT","method":"Bitcoin","key":"other-wallet","verified":true},
+		{"address":"bc1qwallet","asset":"XBT","method":"Bitcoin","key":"dex-wallet","verified":true},
+		{"address":"bc1qwallet","asset":"XBT","method":"Bitcoin Lightning","key":"dex-wallet-ln","verified":true}]}`
+
+	krakenWithdrawMethodsFixture = `{"error":[],"result":[
+		{"asset":"XXBT","method":"Bitcoin","network":"Bitcoin","minimum":"0.0004"},
+		{"asset":"XXBT","method":"Bitcoin Lightning","network":"Lightning","minimum":"0.00001"},
+		{"asset":"USDT","method":"Tether USD (ERC20)","network":"Ethereum","minimum":"10.0"},
+		{"asset":"USDT","method":"Tether USD (Polygon)","network":"Polygon","minimum":"2.5"}]}`
+
+	krakenWithdrawFixture = `{"error":[],"result":{"refid":"FTQcuak-V6Za8qrWnhzTx67yYHz8Tg"}}`
+
+	krakenWithdrawStatusFixture = `{"error":[],"result":[
+		{"method":"Bitcoin","aclass":"currency","asset":"XXBT","refid":"FTQcuak-V6Za8qrWnhzTx67yYHz8Tg","txid":"","info":"bc1qwallet","amount":"0.10000000","fee":"0.00020000","time":1688014586,"status":"Pending"}]}`
+)
+
+// tKrakenServer serves the recorded REST responses.
+type tKrakenServer struct {
+	mtx       sync.Mutex
+	responses map[string]string
+	requests  map[string]url.Values
+	lastNonce string
+}
+
+func newTKrakenServer(t *testing.T) (*tKrakenServer, *httptest.Server) {
+	s := &tKrakenServer{
+		responses: map[string]string{
+			"/0/public/Assets":             krakenAssetsFixture,
+			"/0/public/AssetPairs":         krakenAssetPairsFixture,
+			"/0/public/Ticker":             krakenTickerFixture,
+			"/0/private/BalanceEx":         krakenBalanceFixture,
+			"/0/private/AddOrder":          krakenAddOrderFixture,
+			"/0/private/QueryOrders":       krakenQueryOrdersFixture,
+			"/0/private/DepositMethods":    krakenDepositMethodsFixture,
+			"/0/private/DepositAddresses":  krakenDepositAddressesFixture,
+			"/0/private/DepositStatus":     krakenDepositStatusFixture,
+			"/0/private/WithdrawAddresses": krakenWithdrawAddressesFixture,
+			"/0/private/WithdrawMethods":   krakenWithdrawMethodsFixture,
+			"/0/private/Withdraw":          krakenWithdrawFixture,
+			"/0/private/WithdrawStatus":    krakenWithdrawStatusFixture,
+		},
+		requests: make(map[string]url.Values),
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mtx.Lock()
+		defer s.mtx.Unlock()
+		if err := r.ParseForm(); err != nil {
+			t.Errorf("error parsing form: %v", err)
+		}
+		if strings.HasPrefix(r.URL.Path, "/0/private/") {
+			nonce := r.PostForm.Get("nonce")
+			body := r.PostForm.Encode()
+			expSig, _ := krakenSignature(r.URL.Path, nonce, body, krakenTestSecret)
+			if r.Header.Get("API-Sign") != expSig {
+				w.Write([]byte(`{"error":["EAPI:Invalid signature"]}`))
+				return
+			}
+			if nonce <= s.lastNonce {
+				w.Write([]byte(`{"error":["EAPI:Invalid nonce"]}`))
+				return
+			}
+			s.lastNonce = nonce
+		}
+		s.requests[r.URL.Path] = r.Form
+		resp, found := s.responses[r.URL.Path]
+		if !found {
+			w.Write([]byte(`{"error":["EGeneral:Unknown method"]}`))
+			return
+		}
+		w.Write([]byte(resp))
+	}))
+	return s, srv
+}
+
+func (s *tKrakenServer) request(path string) url.Values {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.requests[path]
+}
+
+func tNewKraken(t *testing.T, srvURL string) *kraken {
+	k, err := newKraken(&CEXConfig{
+		Net:       dex.Mainnet,
+		APIKey:    "key",
+		SecretKey: krakenTestSecret,
+		Logger:    dex.StdOutLogger("T", dex.LevelTrace),
+		Notify:    func(interface{}) {},
+	})
+	if err != nil {
+		t.Fatalf("error creating kraken: %v", err)
+	}
+	k.url = srvURL
+	return k
+}
+
+func TestKrakenSignature(t *testing.T) {
+	// Example from https://docs.kraken.com/api/docs/guides/spot-rest-auth
+	const postData = "nonce=1616492376594&ordertype=limit&pair=XBTUSD&price=37500&type=buy&volume=1.25"
+	sig, err := krakenSignature("/0/private/AddOrder", "1616492376594", postData, krakenTestSecret)
+	if err != nil {
+		t.Fatalf("signature error: %v", err)
+	}
+	const expSig = "4/dpxb3iT4tp/ZCVEwSnEsLxx0bqyhLpdfOpc6fn7OR8+UClSV5n9E6aSS8MPtnRfp32bAb0nmbRn6H8ndwLUQ=="
+	if sig != expSig {
+		t.Fatalf("wrong signature. expected %s, got %s", expSig, sig)
+	}
+}
+
+func TestKrakenAltNames(t *testing.T) {
+	tests := map[string]string{
+		"btc":          "XBT",
+		"doge":         "XDG",
+		"dcr":          "DCR",
+		"usdt.eth":     "USDT",
+		"usdc.polygon": "USDC",
+	}
+	for sym, exp := range tests {
+		if alt := krakenAltName(sym); alt != exp {
+			t.Fatalf("wrong alt name for %s. expected %s, got %s", sym, exp, alt)
+		}
+	}
+	if name := krakenWsName("XBT"); name != "BTC" {
+		t.Fatalf("wrong ws name for XBT: %s", name)
+	}
+	if name := krakenWsName("DCR"); name != "DCR" {
+		t.Fatalf("wrong ws name for DCR: %s", name)
+	}
+}
+
+func TestKrakenMethodMatchesAsset(t *testing.T) {
+	tests := []struct {
+		method  string
+		assetID uint32
+		exp     bool
+	}{
+		{"Bitcoin", 0, true},
+		{"Bitcoin Lightning", 0, false},
+		{"Decred", 42, true},
+		{"Ether (Hex)", 60, true},
+		{"Ethereum (Arbitrum One)", 60, false},
+		{"Tether USD (ERC20)", 60002, true},
+		{"Tether USD (TRC20)", 60002, false},
+		{"Tether USD (Polygon)", 60002, false},
+		{"Tether USD (Polygon)", 966004, true},
+	}
+	for _, tt := range tests {
+		if match := krakenMethodMatchesAsset(tt.method, tt.assetID); match != tt.exp {
+			t.Fatalf("%q for %s: expected %t, got %t", tt.method, dex.BipIDSymbol(tt.assetID), tt.exp, match)
+		}
+	}
+}
+
+func TestKrakenMarketsAndBalances(t *testing.T) {
+	_, srv := newTKrakenServer(t)
+	defer srv.Close()
+	k := tNewKraken(t, srv.URL)
+
+	markets, err := k.Markets(context.Background())
+	if err != nil {
+		t.Fatalf("Markets error: %v", err)
+	}
+	for _, mktID := range []string{"btc_usdt.eth", "btc_usdt.polygon", "dcr_btc"} {
+		if _, found := markets[mktID]; !found {
+			t.Fatalf("market %s not found", mktID)
+		}
+	}
+	if len(markets) != 3 {
+		t.Fatalf("expected 3 markets, got %d", len(markets))
+	}
+	day := markets["btc_usdt.eth"].Day
+	if day == nil || day.LastPrice != 45284 || day.OpenPrice != 44284 || day.Vol != 250 || day.HighPrice != 46100 {
+		t.Fatalf("wrong market day: %+v", day)
+	}
+	for mktID, exp := range map[string][2]uint64{
+		"btc_usdt.eth":     {40000, 10e6},
+		"btc_usdt.polygon": {40000, 2.5e6},
+		"dcr_btc":          {0, 40000},
+	} {
+		mkt := markets[mktID]
+		if mkt.BaseMinWithdraw != exp[0] || mkt.QuoteMinWithdraw != exp[1] {
+			t.Fatalf("wrong %s min withdraws. expected %v, got %d, %d", mktID, exp, mkt.BaseMinWithdraw, mkt.QuoteMinWithdraw)
+		}
+	}
+
+	pair, err := k.pair(0, 60002)
+	if err != nil {
+		t.Fatalf("pair error: %v", err)
+	}
+	btcUI, _ := asset.UnitInfo(0)
+	usdtUI, _ := asset.UnitInfo(60002)
+	if pair.LotSize != 1 {
+		t.Fatalf("wrong lot size %d", pair.LotSize)
+	}
+	if pair.MinQty != 5000 {
+		t.Fatalf("wrong min qty %d", pair.MinQty)
+	}
+	if pair.MinCost != 500000 {
+		t.Fatalf("wrong min cost %d", pair.MinCost)
+	}
+	if expStep := calc.MessageRate(0.1, btcUI, usdtUI); pair.RateStep != expStep {
+		t.Fatalf("wrong rate step. expected %d, got %d", expStep, pair.RateStep)
+	}
+
+	balances, err := k.Balances(context.Background())
+	if err != nil {
+		t.Fatalf("Balances error: %v", err)
+	}
+	expBTC := &ExchangeBalance{Available: 1.25e8, Locked: 0.25e8}
+	if !reflect.DeepEqual(balances[0], expBTC) {
+		t.Fatalf("wrong btc balance. expected %+v, got %+v", expBTC, balances[0])
+	}
+	expUSDT := &ExchangeBalance{Available: 1000e6}
+	for _, assetID := range []uint32{60002, 966004} {
+		if !reflect.DeepEqual(balances[assetID], expUSDT) {
+			t.Fatalf("wrong %s balance. expected %+v, got %+v", dex.BipIDSymbol(assetID), expUSDT, balances[assetID])
+		}
+	}
+	if len(balances) != 3 {
+		t.Fatalf("expected 3 balances, got %d", len(balances))
+	}
+}
+
+func TestBuildKrakenOrderRequest(t *testing.T) {
+	btcUI, _ := asset.UnitInfo(0)
+	usdtUI, _ := asset.UnitInfo(60002)
+	msgRate := func(rate float64) uint64 {
+		return calc.MessageRate(rate, btcUI, usdtUI)
+	}
+	pair := &ktypes.AssetPair{
+		AltName:      "XBTUSDT",
+		CostDecimals: 8,
+		PairDecimals: 1,
+		LotDecimals:  8,
+		OrderMin:     0.00005,
+		CostMin:      0.5,
+		TickSize:     0.1,
+	}
+	parseAssetPair(pair, &btcUI, &usdtUI)
+
+	tests := []struct {
+		name      string
+		sell      bool
+		orderType OrderType
+		rate      uint64
+		qty       uint64
+		quoteQty  uint64
+		expValues url.Values
+		expQty    uint64
+		wantErr   bool
+	}{
+		{
+			name:      "limit buy",
+			orderType: OrderTypeLimit,
+			rate:      msgRate(45000.04),
+			qty:       0.1e8,
+			expValues: url.Values{
+				"pair":      {"XBTUSDT"},
+				"type":      {"buy"},
+				"oflags":    {"fcib"},
+				"ordertype": {"limit"},
+				"price":     {"45000.0"},
+				"volume":    {"0.10000000"},
+				"cl_ord_id": {"id"},
+			},
+			expQty: 0.1e8,
+		},
+		{
+			name:      "limit ioc sell",
+			sell:      true,
+			orderType: OrderTypeLimitIOC,
+			rate:      msgRate(45000.5),
+			qty:       0.12345678e8,
+			expValues: url.Values{
+				"pair":        {"XBTUSDT"},
+				"type":        {"sell"},
+				"oflags":      {"fciq"},
+				"ordertype":   {"limit"},
+				"timeinforce": {"IOC"},
+				"price":       {"45000.5"},
+				"volume":      {"0.12345678"},
+				"cl_ord_id":   {"id"},
+			},
+			expQty: 0.12345678e8,
+		},
+		{
+			name:      "limit buy with quote qty",
+			orderType: OrderTypeLimit,
+			rate:      msgRate(50000),
+			quoteQty:  5000e6,
+			expValues: url.Values{
+				"pair":      {"XBTUSDT"},
+				"type":      {"buy"},
+				"oflags":    {"fcib"},
+				"ordertype": {"limit"},
+				"price":     {"50000.0"},
+				"volume":    {"0.10000000"},
+				"cl_ord_id": {"id"},
+			},
+			expQty: 0.1e8,
+		},
+		{
+			name:      "market buy",
+			orderType: OrderTypeMarket,
+			quoteQty:  100e6,
+			expValues: url.Values{
+				"pair":      {"XBTUSDT"},
+				"type":      {"buy"},
+				"oflags":    {"fcib,viqc"},
+				"ordertype": {"market"},
+				"volume":    {"100.00000000"},
+				"cl_ord_id": {"id"},
+			},
+			expQty: 100e6,
+		},
+		{
+			name:      "market sell",
+			sell:      true,
+			orderType: OrderTypeMarket,
+			qty:       0.5e8,
+			expValues: url.Values{
+				"pair":      {"XBTUSDT"},
+				"type":      {"sell"},
+				"oflags":    {"fciq"},
+				"ordertype": {"market"},
+				"volume":    {"0.50000000"},
+				"cl_ord_id": {"id"},
+			},
+			expQty: 0.5e8,
+		},
+		{
+			name:      "market buy with base qty",
+			orderType: OrderTypeMarket,
+			qty:       0.5e8,
+			wantErr:   true,
+		},
+		{
+			name:      "qty below minimum",
+			sell:      true,
+			orderType: OrderTypeLimit,
+			rate:      msgRate(45000),
+			qty:       4000,
+			wantErr:   true,
+		},
+		{
+			name:      "market buy below minimum cost",
+			orderType: OrderTypeMarket,
+			quoteQty:  0.1e6,
+			wantErr:   true,
+		},
+		{
+			name:      "sell with quote qty",
+			sell:      true,
+			orderType: OrderTypeLimit,
+			rate:      msgRate(45000),
+			quoteQty:  100e6,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v, qty, err := buildKrakenOrderRequest(pair, &btcUI, &usdtUI, tt.sell, tt.orderType, tt.rate, tt.qty, tt.quoteQty, "id")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(v, tt.expValues) {
+				t.Fatalf("wrong values. expected %v, got %v", tt.expValues, v)
+			}
+			if qty != tt.expQty {
+				t.Fatalf("wrong qty. expected %d, got %d", tt.expQty, qty)
+			}
+		})
+	}
+}
+
+func TestKrakenTrade(t *testing.T) {
+	s, srv := newTKrakenServer(t)
+	defer srv.Close()
+	k := tNewKraken(t, srv.URL)
+	if _, err := k.Markets(context.Background()); err != nil {
+		t.Fatalf("Markets error: %v", err)
+	}
+
+	btcUI, _ := asset.UnitInfo(0)
+	usdtUI, _ := asset.UnitInfo(60002)
+	rate := calc.MessageRate(45000, btcUI, usdtUI)
+
+	updates, _, subID := k.SubscribeTradeUpdates()
+	if _, err := k.Trade(context.Background(), 0, 60002, false, rate, 0.1e8, 0, OrderTypeLimit, subID+1); err == nil {
+		t.Fatalf("no error for unknown subscription ID")
+	}
+
+	trade, err := k.Trade(context.Background(), 0, 60002, false, rate, 0.1e8, 0, OrderTypeLimit, subID)
+	if err != nil {
+		t.Fatalf("Trade error: %v", err)
+	}
+	if trade.ID != "OUF4EM-FRGI2-MQMWZD" || trade.Qty != 0.1e8 || trade.Rate != rate || trade.Sell {
+		t.Fatalf("wrong trade: %+v", trade)
+	}
+	req := s.request("/0/private/AddOrder")
+	clOrdID := req.Get("cl_ord_id")
+	if len(clOrdID) != 32 {
+		t.Fatalf("wrong client order ID %q", clOrdID)
+	}
+
+	sendExecutions := func(execs ...string) {
+		t.Helper()
+		msg := &ktypes.WsMessage{
+			Channel: "executions",
+			Type:    "update",
+			Data:    json.RawMessage("[" + strings.Join(execs, ",") + "]"),
+		}
+		k.handleUserMessage(msg)
+	}
+	checkUpdate := func(expBase, expQuote uint64, expComplete bool) {
+		t.Helper()
+		select {
+		case u := <-updates:
+			if u.ID != trade.ID {
+				t.Fatalf("wrong trade ID %s", u.ID)
+			}
+			if u.BaseFilled != expBase || u.QuoteFilled != expQuote || u.Complete != expComplete {
+				t.Fatalf("wrong update. expected base %d, quote %d, complete %t, got %+v", expBase, expQuote, expComplete, u)
+			}
+		default:
+			t.Fatalf("no update")
+		}
+	}
+
+	exec := func(execType, execID, status string, cumQty, cumCost, fee float64) string {
+		return fmt.Sprintf(`{"order_id":"OUF4EM-FRGI2-MQMWZD","cl_ord_id":%q,"symbol":"BTC/USDT","side":"buy",`+
+			`"exec_type":%q,"exec_id":%q,"order_status":%q,"cum_qty":%v,"cum_cost":%v,"fees":[{"asset":"BTC","qty":%v}]}`,
+			clOrdID, execType, execID, status, cumQty, cumCost, fee)
+	}
+
+	sendExecutions(`{"order_id":"OUF4EM-FRGI2-MQMWZD","cl_ord_id":"` + clOrdID + `","exec_type":"new","order_status":"new","cum_qty":0,"cum_cost":0}`)
+	checkUpdate(0, 0, false)
+
+	sendExecutions(exec("trade", "T1", "partially_filled", 0.04, 1800, 0.0001))
+	checkUpdate(0.0399e8, 1800e6, false)
+
+	// A repeated execution should not double count the fee.
+	sendExecutions(exec("trade", "T1", "partially_filled", 0.04, 1800, 0.0001))
+	checkUpdate(0.0399e8, 1800e6, false)
+
+	sendExecutions(exec("trade", "T2", "filled", 0.1, 4500, 0.00016))
+	checkUpdate(0.09974e8, 4500e6, true)
+
+	// Trade info should be deleted after completion.
+	sendExecutions(exec("trade", "T3", "filled", 0.1, 4500, 0.00016))
+	select {
+	case u := <-updates:
+		t.Fatalf("unexpected update after completion: %+v", u)
+	default:
+	}
+
+	status, err := k.TradeStatus(context.Background(), trade.ID, 0, 60002)
+	if err != nil {
+		t.Fatalf("TradeStatus error: %v", err)
+	}
+	expStatus := &Trade{
+		ID:          trade.ID,
+		Qty:         0.1e8,
+		Rate:        rate,
+		BaseID:      0,
+		QuoteID:     60002,
+		BaseFilled:  0.09974e8,
+		QuoteFilled: 4500e6,
+		Complete:    true,
+	}
+	if !reflect.DeepEqual(status, expStatus) {
+		t.Fatalf("wrong trade status. expected %+v, got %+v", expStatus, status)
+	}
+}
+
+func TestKrakenFunding(t *testing.T) {
+	s, srv := newTKrakenServer(t)
+	defer srv.Close()
+	k := tNewKraken(t, srv.URL)
+	ctx := context.Background()
+
+	addr, err := k.GetDepositAddress(ctx, 60002)
+	if err != nil {
+		t.Fatalf("GetDepositAddress error: %v", err)
+	}
+	if addr != "0x2dB0B7A4E2D8Ef0Ef1F21C8a1b0B7d2A3b4C5d6E" {
+		t.Fatalf("wrong deposit address %s", addr)
+	}
+	if method := s.request("/0/private/DepositAddresses").Get("method"); method != "Tether USD (ERC20)" {
+		t.Fatalf("wrong deposit method %q", method)
+	}
+
+	complete, amt := k.ConfirmDeposit(ctx, &DepositData{AssetID: 60002, TxID: "0xdeposit"})
+	if !complete || amt != 99e6 {
+		t.Fatalf("wrong deposit confirmation. complete = %t, amt = %d", complete, amt)
+	}
+	complete, _ = k.ConfirmDeposit(ctx, &DepositData{AssetID: 60002, TxID: "0xunknown"})
+	if complete {
+		t.Fatalf("unknown deposit confirmed")
+	}
+
+	if _, _, err := k.Withdraw(ctx, 0, 0.1e8, "bc1qunknown"); err == nil {
+		t.Fatalf("no error for withdrawal to unknown address")
+	}
+	id, amt, err := k.Withdraw(ctx, 0, 0.1e8, "bc1qwallet")
+	if err != nil {
+		t.Fatalf("Withdraw error: %v", err)
+	}
+	if id != "FTQcuak-V6Za8qrWnhzTx67yYHz8Tg" || amt != 0.1e8 {
+		t.Fatalf("wrong withdrawal result: %s, %d", id, amt)
+	}
+	req := s.request("/0/private/Withdraw")
+	if req.Get("key") != "dex-wallet" || req.Get("amount") != "0.10000000" || req.Get("asset") != "XBT" {
+		t.Fatalf("wrong withdraw request: %v", req)
+	}
+
+	if _, _, err := k.ConfirmWithdrawal(ctx, id, 0); err != ErrWithdrawalPending {
+		t.Fatalf("expected ErrWithdrawalPending, got %v", err)
+	}
+
+	s.mtx.Lock()
+	s.responses["/0/private/WithdrawStatus"] = strings.Replace(
+		strings.Replace(krakenWithdrawStatusFixture, `"txid":""`, `"txid":"abcd"`, 1), "Pending", "Success", 1)
+	s.mtx.Unlock()
+	amt, txID, err := k.ConfirmWithdrawal(ctx, id, 0)
+	if err != nil {
+		t.Fatalf("ConfirmWithdrawal error: %v", err)
+	}
+	if amt != 0.1e8 || txID != "abcd" {
+		t.Fatalf("wrong withdrawal confirmation: %d, %s", amt, txID)
+	}
+}
+
+type tKrakenWsConn struct {
+	comms.WsConn
+	sent []*ktypes.WsRequest
+}
+
+func (c *tKrakenWsConn) SendRaw(b []byte) error {
+	req := new(ktypes.WsRequest)
+	if err := json.Unmarshal(b, req); err != nil {
+		return err
+	}
+	c.sent = append(c.sent, req)
+	return nil
+}
+
+func TestKrakenBook(t *testing.T) {
+	btcUI, _ := asset.UnitInfo(0)
+	usdtUI, _ := asset.UnitInfo(60002)
+	pair := &ktypes.AssetPair{
+		AltName:      "XBTUSDT",
+		BaseAlt:      "XBT",
+		QuoteAlt:     "USDT",
+		PairDecimals: 1,
+		LotDecimals:  8,
+	}
+	b := newKrakenBook("", pair, &btcUI, &usdtUI, dex.StdOutLogger("T", dex.LevelTrace))
+	if b.symbol != "BTC/USDT" {
+		t.Fatalf("wrong symbol %s", b.symbol)
+	}
+	if !b.verifyChecksum {
+		t.Fatalf("checksum verification should be enabled")
+	}
+	wsConn := &tKrakenWsConn{}
+	b.conn = newKrakenWSConn("", func() ([]*ktypes.WsRequest, error) { return b.subscriptions(true), nil }, b.handleBookMessage, b.synced.Store, b.log)
+	b.conn.wsConn = wsConn
+
+	// The checksum strings are the asks then the bids, with the decimal point
+	// and leading zeros removed.
+	snapChecksum := crc32.ChecksumIEEE([]byte(
+		"452852" + "100000" + "452864" + "154582015" + "452866" + "100000000" +
+			"452835" + "10000000" + "452834" + "154582015"))
+	snapshot := fmt.Sprintf(`{"channel":"book","type":"snapshot","data":[{"symbol":"BTC/USDT",`+
+		`"bids":[{"price":45283.5,"qty":0.10000000},{"price":45283.4,"qty":1.54582015}],`+
+		`"asks":[{"price":45285.2,"qty":0.00100000},{"price":45286.4,"qty":1.54582015},{"price":45286.6,"qty":1.00000000}],`+
+		`"checksum":%d}]}`, snapChecksum)
+	b.conn.handleWebsocketMessage([]byte(snapshot))
+	if !b.synced.Load() {
+		t.Fatalf("book not synced after snapshot")
+	}
+
+	midGap, err := b.midGap()
+	if err != nil {
+		t.Fatalf("midGap error: %v", err)
+	}
+	expMidGap := (calc.MessageRate(45283.5, btcUI, usdtUI) + calc.MessageRate(45285.2, btcUI, usdtUI)) / 2
+	if midGap != expMidGap {
+		t.Fatalf("wrong mid gap. expected %d, got %d", expMidGap, midGap)
+	}
+
+	// Remove the best ask and change the qty of the best bid.
+	updateChecksum := crc32.ChecksumIEEE([]byte(
+		"452864" + "154582015" + "452866" + "100000000" +
+			"452835" + "20000000" + "452834" + "154582015"))
+	update := fmt.Sprintf(`{"channel":"book","type":"update","data":[{"symbol":"BTC/USDT",`+
+		`"bids":[{"price":45283.5,"qty":0.2}],"asks":[{"price":45285.2,"qty":0}],"checksum":%d}]}`, updateChecksum)
+	b.conn.handleWebsocketMessage([]byte(update))
+	if !b.synced.Load() {
+		t.Fatalf("book not synced after update")
+	}
+
+	vwap, extrema, filled, err := b.vwap(false, 2e8)
+	if err != nil {
+		t.Fatalf("vwap error: %v", err)
+	}
+	r1, r2 := calc.MessageRate(45286.4, btcUI, usdtUI), calc.MessageRate(45286.6, btcUI, usdtUI)
+	expVWAP := (r1*154582015 + r2*45417985) / 2e8
+	if !filled || extrema != r2 || vwap != expVWAP {
+		t.Fatalf("wrong vwap. expected %d, %d, got %d, %d, filled = %t", expVWAP, r2, vwap, extrema, filled)
+	}
+
+	// A bad checksum should trigger a resubscription.
+	badUpdate := `{"channel":"book","type":"update","data":[{"symbol":"BTC/USDT",` +
+		`"bids":[{"price":45283.5,"qty":0.3}],"asks":[],"checksum":1}]}`
+	b.conn.handleWebsocketMessage([]byte(badUpdate))
+	if b.synced.Load() {
+		t.Fatalf("book still synced after checksum mismatch")
+	}
+	if len(wsConn.sent) != 2 || wsConn.sent[0].Method != "unsubscribe" || wsConn.sent[1].Method != "subscribe" {
+		t.Fatalf("expected unsubscribe and subscribe requests, got %+v", wsConn.sent)
+	}
+	if _, _, _, err := b.vwap(false, 1e8); err != ErrUnsyncedOrderbook {
+		t.Fatalf("expected ErrUnsyncedOrderbook, got %v", err)
+	}
+
+	// Updates are ignored until the next snapshot.
+	b.conn.handleWebsocketMessage([]byte(update))
+	if b.synced.Load() {
+		t.Fatalf("book synced by update")
+	}
+	b.conn.handleWebsocketMessage([]byte(snapshot))
+	if !b.synced.Load() {
+		t.Fatalf("book not synced after second snapshot")
+	}
+}
+
+func TestKrakenBookTruncation(t *testing.T) {
+	btcUI, _ := asset.UnitInfo(0)
+	usdtUI, _ := asset.UnitInfo(60002)
+	pair := &ktypes.AssetPair{BaseAlt: "XBT", QuoteAlt: "USDT", PairDecimals: 1, LotDecimals: 8}
+	b := newKrakenBook("", pair, &btcUI, &usdtUI, dex.StdOutLogger("T", dex.LevelTrace))
+	b.verifyChecksum = false
+
+	levels := make([]string, 0, krakenBookDepth+5)
+	for i := 0; i < krakenBookDepth+5; i++ {
+		levels = append(levels, fmt.Sprintf(`{"price":%d.0,"qty":1.0}`, 40000-i))
+	}
+	snapshot := `{"channel":"book","type":"snapshot","data":[{"symbol":"BTC/USDT","bids":[` +
+		strings.Join(levels, ",") + `],"asks":[{"price":50000.0,"qty":1.0}],"checksum":0}]}`
+	msg := new(ktypes.WsMessage)
+	if err := json.Unmarshal([]byte(snapshot), msg); err != nil {
+		t.Fatalf("error unmarshaling snapshot: %v", err)
+	}
+	b.handleBookMessage(msg)
+	bids, _ := b.book.snap()
+	if len(bids) != krakenBookDepth {
+		t.Fatalf("expected %d bids, got %d", krakenBookDepth, len(bids))
+	}
+	if worst := bids[len(bids)-1].rate; worst != calc.MessageRate(40000-krakenBookDepth+1, btcUI, usdtUI) {
+		t.Fatalf("wrong worst bid %d", worst)
+	}
+}
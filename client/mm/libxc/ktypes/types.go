@@ -0,0 +1,253 @@
+package ktypes
+
+import (
+	"encoding/json"
+)
+
+// ============================================================================
+// REST
+// ============================================================================
+
+// Response is the envelope for every Kraken REST response. Error is a list of
+// strings of the form "<severity><category>:<message>", e.g.
+// "EGeneral:Invalid arguments".
+type Response struct {
+	Error  []string        `json:"error"`
+	Result json.RawMessage `json:"result"`
+}
+
+// Asset is an entry in the /0/public/Assets response, keyed by the Kraken
+// asset name, e.g. XXBT.
+type Asset struct {
+	AssetClass      string `json:"aclass"`
+	AltName         string `json:"altname"`
+	Decimals        int    `json:"decimals"`
+	DisplayDecimals int    `json:"display_decimals"`
+	Status          string `json:"status"`
+}
+
+// AssetPair is an entry in the /0/public/AssetPairs response, keyed by the
+// Kraken pair name, e.g. XXBTZUSD.
+type AssetPair struct {
+	AltName      string  `json:"altname"`
+	WSName       string  `json:"wsname"`
+	Base         string  `json:"base"`
+	Quote        string  `json:"quote"`
+	CostDecimals int     `json:"cost_decimals"`
+	PairDecimals int     `json:"pair_decimals"`
+	LotDecimals  int     `json:"lot_decimals"`
+	OrderMin     float64 `json:"ordermin,string"`
+	CostMin      float64 `json:"costmin,string"`
+	TickSize     float64 `json:"tick_size,string"`
+	Status       string  `json:"status"`
+
+	// Below fields are parsed from the above and the asset unit info.
+	PairName string
+	BaseAlt  string
+	QuoteAlt string
+	LotSize  uint64
+	MinQty   uint64
+	MinCost  uint64
+	RateStep uint64
+}
+
+// Ticker is an entry in the /0/public/Ticker response. Array fields with two
+// elements are [today, last 24 hours].
+type Ticker struct {
+	Ask        []json.Number `json:"a"`
+	Bid        []json.Number `json:"b"`
+	LastTrade  []json.Number `json:"c"`
+	Volume     []json.Number `json:"v"`
+	VWAP       []json.Number `json:"p"`
+	Low        []json.Number `json:"l"`
+	High       []json.Number `json:"h"`
+	OpeningPx  json.Number   `json:"o"`
+	TradeCount []int64       `json:"t"`
+}
+
+// Balance is an entry in the /0/private/BalanceEx response.
+type Balance struct {
+	Balance   float64 `json:"balance,string"`
+	HoldTrade float64 `json:"hold_trade,string"`
+}
+
+// AddOrderResult is the result of /0/private/AddOrder.
+type AddOrderResult struct {
+	Description struct {
+		Order string `json:"order"`
+	} `json:"descr"`
+	TxIDs []string `json:"txid"`
+}
+
+// CancelOrderResult is the result of /0/private/CancelOrder.
+type CancelOrderResult struct {
+	Count int `json:"count"`
+}
+
+// OrderDescription describes the parameters of an order.
+type OrderDescription struct {
+	Pair      string  `json:"pair"`
+	Type      string  `json:"type"` // "buy" or "sell"
+	OrderType string  `json:"ordertype"`
+	Price     float64 `json:"price,string"`
+}
+
+// Order is an entry in the /0/private/QueryOrders response, keyed by the
+// Kraken transaction ID.
+type Order struct {
+	ClientOrderID string           `json:"cl_ord_id"`
+	Status        string           `json:"status"` // pending, open, closed, canceled, expired
+	Description   OrderDescription `json:"descr"`
+	Volume        float64          `json:"vol,string"`
+	VolumeExec    float64          `json:"vol_exec,string"`
+	Cost          float64          `json:"cost,string"`
+	Fee           float64          `json:"fee,string"`
+	Price         float64          `json:"price,string"`
+	OrderFlags    string           `json:"oflags"`
+}
+
+// DepositMethod is an entry in the /0/private/DepositMethods response.
+type DepositMethod struct {
+	Method     string `json:"method"`
+	GenAddress bool   `json:"gen-address"`
+}
+
+// DepositAddress is an entry in the /0/private/DepositAddresses response.
+type DepositAddress struct {
+	Address string `json:"address"`
+	Tag     string `json:"tag"`
+	New     bool   `json:"new"`
+}
+
+// FundingStatus is an entry in the /0/private/DepositStatus and
+// /0/private/WithdrawStatus responses.
+type FundingStatus struct {
+	Method string  `json:"method"`
+	Asset  string  `json:"asset"`
+	RefID  string  `json:"refid"`
+	TxID   string  `json:"txid"`
+	Info   string  `json:"info"`
+	Amount float64 `json:"amount,string"`
+	Fee    float64 `json:"fee,string"`
+	Time   int64   `json:"time"`
+	Status string  `json:"status"`
+}
+
+// Funding statuses. See https://github.com/globalcitizen/ifex-protocol.
+const (
+	FundingStatusInitial = "Initial"
+	FundingStatusPending = "Pending"
+	FundingStatusSettled = "Settled"
+	FundingStatusSuccess = "Success"
+	FundingStatusFailure = "Failure"
+)
+
+// WithdrawAddress is an entry in the /0/private/WithdrawAddresses response.
+// Kraken only allows withdrawals to addresses that have been saved to the
+// account's address book, identified by Key.
+type WithdrawAddress struct {
+	Address  string `json:"address"`
+	Asset    string `json:"asset"`
+	Method   string `json:"method"`
+	Key      string `json:"key"`
+	Verified bool   `json:"verified"`
+}
+
+// WithdrawMethod is an entry in the /0/private/WithdrawMethods response.
+// Minimum is the smallest amount that can be withdrawn with the method.
+type WithdrawMethod struct {
+	Asset   string  `json:"asset"`
+	Method  string  `json:"method"`
+	Network string  `json:"network"`
+	Minimum float64 `json:"minimum,string"`
+}
+
+// WithdrawResult is the result of /0/private/Withdraw.
+type WithdrawResult struct {
+	RefID string `json:"refid"`
+}
+
+// WebSocketsToken is the result of /0/private/GetWebSocketsToken.
+type WebSocketsToken struct {
+	Token   string `json:"token"`
+	Expires int64  `json:"expires"`
+}
+
+// ============================================================================
+// Websocket (v2)
+// ============================================================================
+
+// SubscribeParams are the params of a websocket subscribe or unsubscribe
+// request.
+type SubscribeParams struct {
+	Channel    string   `json:"channel"`
+	Symbol     []string `json:"symbol,omitempty"`
+	Depth      int      `json:"depth,omitempty"`
+	Snapshot   *bool    `json:"snapshot,omitempty"`
+	SnapOrders *bool    `json:"snap_orders,omitempty"`
+	SnapTrades *bool    `json:"snap_trades,omitempty"`
+	Token      string   `json:"token,omitempty"`
+}
+
+// WsRequest is a request sent over the websocket connection.
+type WsRequest struct {
+	Method string           `json:"method"`
+	Params *SubscribeParams `json:"params,omitempty"`
+	ReqID  uint64           `json:"req_id,omitempty"`
+}
+
+// WsMessage is used to determine the type of an incoming websocket message.
+// Channel messages have Channel and Type set, while responses to requests
+// have Method, Success and optionally Error set.
+type WsMessage struct {
+	Channel string          `json:"channel"`
+	Type    string          `json:"type"` // snapshot or update
+	Data    json.RawMessage `json:"data"`
+
+	Method  string `json:"method"`
+	Success *bool  `json:"success"`
+	Error   string `json:"error"`
+	ReqID   uint64 `json:"req_id"`
+}
+
+// BookLevel is a price level in the order book. The price and quantity are
+// kept as json.Number so that they can be used to verify the book checksum.
+type BookLevel struct {
+	Price json.Number `json:"price"`
+	Qty   json.Number `json:"qty"`
+}
+
+// BookData is the data of a book channel message.
+type BookData struct {
+	Symbol   string       `json:"symbol"`
+	Bids     []*BookLevel `json:"bids"`
+	Asks     []*BookLevel `json:"asks"`
+	Checksum uint32       `json:"checksum"`
+}
+
+// ExecutionFee is a fee charged for a fill.
+type ExecutionFee struct {
+	Asset string  `json:"asset"`
+	Qty   float64 `json:"qty"`
+}
+
+// Execution is the data of an executions channel message.
+type Execution struct {
+	OrderID       string          `json:"order_id"`
+	ClientOrderID string          `json:"cl_ord_id"`
+	Symbol        string          `json:"symbol"`
+	Side          string          `json:"side"`
+	ExecType      string          `json:"exec_type"`
+	ExecID        string          `json:"exec_id"`
+	OrderStatus   string          `json:"order_status"`
+	CumQty        float64         `json:"cum_qty"`
+	CumCost       float64         `json:"cum_cost"`
+	LastQty       float64         `json:"last_qty"`
+	Fees          []*ExecutionFee `json:"fees"`
+}
+
+// BalanceData is the data of a balances channel message.
+type BalanceData struct {
+	Asset   string  `json:"asset"`
+	Balance float64 `json:"balance"`
+}
@@ -0,0 +1,1707 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package libxc
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"decred.org/dcrdex/client/asset"
+	"decred.org/dcrdex/client/comms"
+	"decred.org/dcrdex/client/core"
+	"decred.org/dcrdex/client/mm/libxc/okxtypes"
+	"decred.org/dcrdex/dex"
+	"decred.org/dcrdex/dex/calc"
+	"decred.org/dcrdex/dex/dexnet"
+	"decred.org/dcrdex/dex/encode"
+	"decred.org/dcrdex/dex/utils"
+)
+
+// REST docs: https://www.okx.com/docs-v5/en/#rest-api
+// Websocket docs: https://www.okx.com/docs-v5/en/#overview-websocket
+// Funding docs: https://www.okx.com/docs-v5/en/#funding-account-rest-api
+
+const (
+	okxHTTPURL       = "https://www.okx.com"
+	okxPublicWsURL   = "wss://ws.okx.com:8443/ws/v5/public"
+	okxPrivateWsURL  = "wss://ws.okx.com:8443/ws/v5/private"
+	okxTimestampFmt  = "2006-01-02T15:04:05.000Z"
+	okxPingInterval  = time.Second * 20
+	okxBooksChannel  = "books"
+	okxOrdersChannel = "orders"
+)
+
+// dexToOKXCcy maps the DEX symbols to the OKX currencies where they differ.
+var dexToOKXCcy = map[string]string{
+	"polygon": "POL",
+	"base":    "ETH",
+	"weth":    "ETH",
+}
+
+// okxNetworks are the names that OKX uses for the chains, keyed by the asset
+// ID of the chain's base asset. The OKX chain of a currency is of the form
+// CCY-NETWORK, e.g. USDT-ERC20.
+var okxNetworks = map[uint32]string{
+	0:    "Bitcoin",
+	2:    "Litecoin",
+	3:    "Dogecoin",
+	5:    "Dash",
+	42:   "Decred",
+	60:   "ERC20",
+	133:  "Zcash",
+	145:  "BitcoinCash",
+	966:  "Polygon",
+	8453: "Base",
+}
+
+// supportedOKXTokens is the set of supported OKX tokens. OKX lists each token
+// as a single currency with a separate chain for each network.
+var supportedOKXTokens = map[uint32]struct{}{
+	60001:  {}, // USDC on ETH
+	60002:  {}, // USDT on ETH
+	61000:  {}, // USDC on BASE
+	966001: {}, // USDC on POLYGON
+	966004: {}, // USDT on POLYGON
+}
+
+// okxCcy returns the OKX currency for a DEX asset symbol.
+func okxCcy(dexSymbol string) string {
+	sym := strings.Split(dexSymbol, ".")[0]
+	if ccy, found := dexToOKXCcy[sym]; found {
+		return ccy
+	}
+	return strings.ToUpper(sym)
+}
+
+// okxChain returns the OKX chain, e.g. USDT-ERC20, that is used to deposit
+// and withdraw the DEX asset.
+func okxChain(assetID uint32) (string, error) {
+	netID := assetID
+	if token := asset.TokenInfo(assetID); token != nil {
+		netID = token.ParentID
+	}
+	network, found := okxNetworks[netID]
+	if !found {
+		return "", fmt.Errorf("no OKX network known for %s", dex.BipIDSymbol(assetID))
+	}
+	return okxCcy(dex.BipIDSymbol(assetID)) + "-" + network, nil
+}
+
+// okxFloat parses a numeric string from the OKX API. OKX uses empty strings
+// for values that are not applicable, which are parsed as zero.
+func okxFloat(s string) float64 {
+	f, _ := strconv.ParseFloat(s, 64)
+	return f
+}
+
+// okxDecimals returns the number of decimal places in a numeric string, e.g.
+// 3 for "0.001".
+func okxDecimals(s string) int {
+	i := strings.Index(s, ".")
+	if i < 0 {
+		return 0
+	}
+	return len(strings.TrimRight(s[i+1:], "0"))
+}
+
+// OKXError is an error returned by the OKX REST API.
+type OKXError struct {
+	Code string
+	Msg  string
+}
+
+func (e *OKXError) Error() string {
+	return fmt.Sprintf("code %s: %s", e.Code, e.Msg)
+}
+
+// okxResponseError returns the error for an unsuccessful response. The
+// order endpoints report the reason for a failure in the data.
+func okxResponseError(resp *okxtypes.Response) error {
+	var results []*okxtypes.OrderResult
+	if err := json.Unmarshal(resp.Data, &results); err == nil && len(results) > 0 && results[0].SMsg != "" {
+		return &OKXError{Code: results[0].SCode, Msg: results[0].SMsg}
+	}
+	return &OKXError{Code: resp.Code, Msg: resp.Msg}
+}
+
+// okxSignature generates the OK-ACCESS-SIGN header for a private request.
+func okxSignature(timestamp, method, requestPath, body, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + method + requestPath + body))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// okxWSConn manages a websocket connection to the OKX API. If login is set,
+// the subscriptions are sent after a successful login. Otherwise, they are
+// sent every time the connection is established.
+type okxWSConn struct {
+	wsConn     comms.WsConn
+	url        string
+	log        dex.Logger
+	login      func() *okxtypes.WsRequest
+	subs       func() []*okxtypes.WsRequest
+	msgHandler func(*okxtypes.WsMessage)
+	setSynced  func(bool)
+}
+
+func newOKXWSConn(url string, login func() *okxtypes.WsRequest, subs func() []*okxtypes.WsRequest, msgHandler func(*okxtypes.WsMessage), setSynced func(bool), log dex.Logger) *okxWSConn {
+	return &okxWSConn{
+		url:        url,
+		login:      login,
+		subs:       subs,
+		msgHandler: msgHandler,
+		setSynced:  setSynced,
+		log:        log,
+	}
+}
+
+func (c *okxWSConn) send(req *okxtypes.WsRequest) error {
+	b, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	return c.wsConn.SendRaw(b)
+}
+
+func (c *okxWSConn) subscribe() error {
+	for _, req := range c.subs() {
+		if err := c.send(req); err != nil {
+			return fmt.Errorf("error sending %s request: %w", req.Op, err)
+		}
+	}
+	return nil
+}
+
+// start logs in if required, or subscribes to the channels.
+func (c *okxWSConn) start() error {
+	if c.login != nil {
+		if err := c.send(c.login()); err != nil {
+			return fmt.Errorf("error logging in: %w", err)
+		}
+		return nil
+	}
+	return c.subscribe()
+}
+
+func (c *okxWSConn) handleWebsocketMessage(b []byte) {
+	if string(b) == "pong" {
+		return
+	}
+
+	msg := new(okxtypes.WsMessage)
+	if err := json.Unmarshal(b, msg); err != nil {
+		c.log.Errorf("Error unmarshaling websocket message: %v", err)
+		c.log.Errorf("Raw Message: %s", string(b))
+		return
+	}
+
+	switch msg.Event {
+	case "":
+		if msg.Arg != nil {
+			c.msgHandler(msg)
+		}
+	case "login":
+		if msg.Code != "0" {
+			c.log.Errorf("Websocket login failed: %s", msg.Msg)
+			return
+		}
+		if err := c.subscribe(); err != nil {
+			c.log.Errorf("Error subscribing after login: %v", err)
+		}
+	case "error":
+		c.log.Errorf("Websocket error %s: %s", msg.Code, msg.Msg)
+	}
+}
+
+func (c *okxWSConn) Connect(ctx context.Context) (*sync.WaitGroup, error) {
+	initialConnect := true
+	conn, err := comms.NewWsConn(&comms.WsCfg{
+		URL: c.url,
+		// We send a ping every okxPingInterval, so if no messages come for
+		// one minute, we are disconnected.
+		PingWait:      time.Minute,
+		ReconnectSync: func() {},
+		ConnectEventFunc: func(cs comms.ConnectionStatus) {
+			if cs != comms.Connected && cs != comms.Disconnected {
+				return
+			}
+
+			if cs == comms.Connected && initialConnect {
+				initialConnect = false
+			} else if cs == comms.Connected {
+				if err := c.start(); err != nil {
+					c.log.Errorf("Error resubscribing after reconnect: %v", err)
+				}
+			} else { // Disconnected
+				c.setSynced(false)
+			}
+		},
+		Logger:     c.log,
+		RawHandler: c.handleWebsocketMessage,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error creating WsConn: %w", err)
+	}
+
+	cm := dex.NewConnectionMaster(conn)
+	if err := cm.ConnectOnce(ctx); err != nil {
+		return nil, fmt.Errorf("error connecting to websocket feed: %w", err)
+	}
+
+	c.wsConn = conn
+
+	if err := c.start(); err != nil {
+		cm.Disconnect()
+		return nil, err
+	}
+
+	var wg sync.WaitGroup
+
+	// OKX closes the connection if no messages are sent for 30 seconds.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(okxPingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := conn.SendRaw([]byte("ping")); err != nil {
+					c.log.Debugf("Error sending ping: %v", err)
+				}
+			}
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-ctx.Done()
+		cm.Disconnect()
+	}()
+
+	return &wg, nil
+}
+
+// okxBook maintains the order book for a single OKX market using its own
+// websocket connection.
+type okxBook struct {
+	mtx            sync.RWMutex
+	numSubscribers uint32
+
+	cm     *dex.ConnectionMaster
+	conn   *okxWSConn
+	synced atomic.Bool
+	instID string
+	wsURL  string
+	book   *orderbook
+	bui    *dex.UnitInfo
+	qui    *dex.UnitInfo
+	log    dex.Logger
+
+	// seqID is the sequence ID of the last message applied to the book. It
+	// is only accessed by the websocket message handler.
+	seqID int64
+}
+
+func newOKXBook(wsURL, instID string, bui, qui *dex.UnitInfo, log dex.Logger) *okxBook {
+	return &okxBook{
+		wsURL:  wsURL,
+		instID: instID,
+		book:   newOrderBook(),
+		bui:    bui,
+		qui:    qui,
+		log:    log.SubLogger(instID),
+	}
+}
+
+func (b *okxBook) convertLevels(levels [][]json.Number) ([]*obEntry, error) {
+	entries := make([]*obEntry, 0, len(levels))
+	for _, level := range levels {
+		if len(level) < 2 {
+			return nil, fmt.Errorf("invalid level %v", level)
+		}
+		price, err := level[0].Float64()
+		if err != nil {
+			return nil, fmt.Errorf("error parsing price %q: %w", level[0], err)
+		}
+		qty, err := level[1].Float64()
+		if err != nil {
+			return nil, fmt.Errorf("error parsing qty %q: %w", level[1], err)
+		}
+		entries = append(entries, &obEntry{
+			qty:  toAtomic(qty, b.bui),
+			rate: messageRate(price, b.bui, b.qui),
+		})
+	}
+	return entries, nil
+}
+
+func (b *okxBook) subscriptions(subscribe bool) []*okxtypes.WsRequest {
+	op := "subscribe"
+	if !subscribe {
+		op = "unsubscribe"
+	}
+	return []*okxtypes.WsRequest{{
+		Op:   op,
+		Args: []interface{}{&okxtypes.WsArg{Channel: okxBooksChannel, InstID: b.instID}},
+	}}
+}
+
+// resync unsubscribes from the books channel and subscribes again, which
+// causes OKX to send a new snapshot.
+func (b *okxBook) resync() {
+	b.synced.Store(false)
+	for _, req := range b.subscriptions(false) {
+		if err := b.conn.send(req); err != nil {
+			b.log.Errorf("Error unsubscribing from book: %v", err)
+		}
+	}
+	if err := b.conn.subscribe(); err != nil {
+		b.log.Errorf("Error resubscribing to book: %v", err)
+	}
+}
+
+// handleBookMessage applies a books channel message to the book. Rather
+// than the checksum, which is calculated from the price and size strings,
+// the sequence IDs are used to detect missed updates.
+func (b *okxBook) handleBookMessage(msg *okxtypes.WsMessage) {
+	if msg.Arg.Channel != okxBooksChannel || msg.Arg.InstID != b.instID {
+		return
+	}
+
+	var data []*okxtypes.BookData
+	if err := json.Unmarshal(msg.Data, &data); err != nil {
+		b.log.Errorf("Error unmarshaling book data: %v", err)
+		return
+	}
+
+	snapshot := msg.Action == "snapshot"
+	for _, d := range data {
+		if !snapshot {
+			if !b.synced.Load() {
+				// Waiting for a snapshot after a resync.
+				continue
+			}
+			if d.PrevSeqID != b.seqID {
+				b.log.Errorf("Book sequence gap. Expected previous sequence ID %d, got %d. Resyncing.", b.seqID, d.PrevSeqID)
+				b.resync()
+				return
+			}
+		}
+		bids, err := b.convertLevels(d.Bids)
+		if err != nil {
+			b.log.Errorf("Error converting bids: %v", err)
+			b.resync()
+			return
+		}
+		asks, err := b.convertLevels(d.Asks)
+		if err != nil {
+			b.log.Errorf("Error converting asks: %v", err)
+			b.resync()
+			return
+		}
+
+		if snapshot {
+			b.book.clear()
+		}
+		b.book.update(bids, asks)
+		b.seqID = d.SeqID
+
+		if snapshot {
+			b.log.Infof("Book synced")
+			b.synced.Store(true)
+		}
+	}
+}
+
+func (b *okxBook) midGap() (uint64, error) {
+	if !b.synced.Load() {
+		return 0, ErrUnsyncedOrderbook
+	}
+
+	return b.book.midGap(), nil
+}
+
+func (b *okxBook) vwap(bids bool, qty uint64) (vwap, extrema uint64, filled bool, err error) {
+	if !b.synced.Load() {
+		return 0, 0, false, ErrUnsyncedOrderbook
+	}
+
+	vwap, extrema, filled = b.book.vwap(bids, qty)
+	return
+}
+
+func (b *okxBook) invVWAP(bids bool, qty uint64) (vwap, extrema uint64, filled bool, err error) {
+	if !b.synced.Load() {
+		return 0, 0, false, ErrUnsyncedOrderbook
+	}
+
+	vwap, extrema, filled = b.book.invVWAP(bids, qty)
+	return
+}
+
+func (b *okxBook) Connect(ctx context.Context) (*sync.WaitGroup, error) {
+	subs := func() []*okxtypes.WsRequest {
+		return b.subscriptions(true)
+	}
+	b.conn = newOKXWSConn(b.wsURL, nil, subs, b.handleBookMessage, b.synced.Store, b.log)
+	wsCM := dex.NewConnectionMaster(b.conn)
+	if err := wsCM.ConnectOnce(ctx); err != nil {
+		return nil, fmt.Errorf("error connecting to websocket feed: %w", err)
+	}
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-ctx.Done()
+		wsCM.Disconnect()
+	}()
+
+	return &wg, nil
+}
+
+func (b *okxBook) sync(ctx context.Context) error {
+	cm := dex.NewConnectionMaster(b)
+	b.mtx.Lock()
+	b.cm = cm
+	b.numSubscribers++
+	b.mtx.Unlock()
+	return cm.ConnectOnce(ctx)
+}
+
+type okx struct {
+	log          dex.Logger
+	url          string
+	publicWsURL  string
+	privateWsURL string
+	apiKey       string
+	secretKey    string
+	passphrase   string
+	net          dex.Network
+	broadcast    func(interface{})
+	ctx          context.Context
+
+	// ccyIDs maps the OKX currencies to the DEX asset IDs.
+	ccyIDs map[string][]uint32
+	// idCcy maps the DEX asset IDs to the OKX currencies.
+	idCcy map[uint32]string
+
+	tradeIDNonce       atomic.Uint32
+	tradeIDNoncePrefix dex.Bytes
+
+	instruments atomic.Value // map[string]*okxtypes.Instrument, instID -> instrument
+
+	marketSnapshotMtx sync.RWMutex
+	marketSnapshot    struct {
+		stamp time.Time
+		m     map[string]*Market
+	}
+
+	balanceMtx sync.RWMutex
+	balances   map[uint32]*ExchangeBalance
+
+	// subMarketMtx must be held while subscribing or unsubscribing to a
+	// market.
+	subMarketMtx sync.Mutex
+
+	booksMtx sync.RWMutex
+	books    map[string]*okxBook
+
+	tradeUpdaterMtx    sync.RWMutex
+	tradeInfo          map[string]*tradeInfo // keyed by client order ID
+	tradeUpdaters      map[int]chan *Trade
+	tradeUpdateCounter int
+}
+
+var _ CEX = (*okx)(nil)
+
+func newOKX(cfg *CEXConfig) (*okx, error) {
+	if cfg.Net != dex.Mainnet {
+		return nil, fmt.Errorf("okx is only supported on mainnet")
+	}
+	if cfg.Passphrase == "" {
+		return nil, fmt.Errorf("okx requires the API key passphrase")
+	}
+
+	ccyIDs := make(map[string][]uint32)
+	idCcy := make(map[uint32]string)
+	addAsset := func(assetID uint32, symbol string) {
+		ccy := okxCcy(symbol)
+		ccyIDs[ccy] = append(ccyIDs[ccy], assetID)
+		idCcy[assetID] = ccy
+	}
+	for _, a := range asset.Assets() {
+		addAsset(a.ID, a.Symbol)
+		for tokenID := range a.Tokens {
+			if _, supported := supportedOKXTokens[tokenID]; supported {
+				addAsset(tokenID, dex.BipIDSymbol(tokenID))
+			}
+		}
+	}
+
+	o := &okx{
+		log:                cfg.Logger,
+		url:                okxHTTPURL,
+		publicWsURL:        okxPublicWsURL,
+		privateWsURL:       okxPrivateWsURL,
+		apiKey:             cfg.APIKey,
+		secretKey:          cfg.SecretKey,
+		passphrase:         cfg.Passphrase,
+		net:                cfg.Net,
+		broadcast:          cfg.Notify,
+		ccyIDs:             ccyIDs,
+		idCcy:              idCcy,
+		tradeIDNoncePrefix: encode.RandomBytes(12),
+		balances:           make(map[uint32]*ExchangeBalance),
+		books:              make(map[string]*okxBook),
+		tradeInfo:          make(map[string]*tradeInfo),
+		tradeUpdaters:      make(map[int]chan *Trade),
+	}
+	o.instruments.Store(make(map[string]*okxtypes.Instrument))
+	return o, nil
+}
+
+func (o *okx) getAPI(ctx context.Context, endpoint string, query url.Values, private bool, thing interface{}) error {
+	return o.request(ctx, http.MethodGet, endpoint, query, nil, private, thing)
+}
+
+func (o *okx) postAPI(ctx context.Context, endpoint string, body interface{}, thing interface{}) error {
+	return o.request(ctx, http.MethodPost, endpoint, nil, body, true, thing)
+}
+
+// request sends a request to the OKX REST API. The data of the response is
+// unmarshaled into thing.
+func (o *okx) request(ctx context.Context, method, endpoint string, query url.Values, body interface{}, private bool, thing interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*30)
+	defer cancel()
+
+	requestPath := endpoint
+	if len(query) > 0 {
+		requestPath += "?" + query.Encode()
+	}
+
+	var bodyB []byte
+	var bodyReader io.Reader
+	if body != nil {
+		var err error
+		if bodyB, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("error marshaling request body: %w", err)
+		}
+		bodyReader = bytes.NewReader(bodyB)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, o.url+requestPath, bodyReader)
+	if err != nil {
+		return fmt.Errorf("error generating http request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if private {
+		ts := time.Now().UTC().Format(okxTimestampFmt)
+		req.Header.Set("OK-ACCESS-KEY", o.apiKey)
+		req.Header.Set("OK-ACCESS-SIGN", okxSignature(ts, method, requestPath, string(bodyB), o.secretKey))
+		req.Header.Set("OK-ACCESS-TIMESTAMP", ts)
+		req.Header.Set("OK-ACCESS-PASSPHRASE", o.passphrase)
+	}
+
+	var resp okxtypes.Response
+	var errCode int
+	if err := dexnet.Do(req, &resp, dexnet.WithStatusFunc(func(code int) { errCode = code })); err != nil {
+		return fmt.Errorf("%s %s error (%d): %w", method, endpoint, errCode, err)
+	}
+	if resp.Code != "0" {
+		return fmt.Errorf("%s %s error: %w", method, endpoint, okxResponseError(&resp))
+	}
+	if thing == nil {
+		return nil
+	}
+	if err := json.Unmarshal(resp.Data, thing); err != nil {
+		return fmt.Errorf("error unmarshaling %s data: %w", endpoint, err)
+	}
+	return nil
+}
+
+// parseOKXInstrument fills in the fields of the instrument that are
+// expressed in the atomic units of the DEX assets.
+func parseOKXInstrument(inst *okxtypes.Instrument, bui, qui *dex.UnitInfo) {
+	bFactor := float64(bui.Conventional.ConversionFactor)
+	inst.LotSize = uint64(math.Max(1, math.Round(okxFloat(inst.LotSz)*bFactor)))
+	inst.MinQty = uint64(math.Round(okxFloat(inst.MinSz) * bFactor))
+	inst.RateStep = uint64(math.Max(1, float64(messageRate(okxFloat(inst.TickSz), bui, qui))))
+	inst.PriceDecimals = okxDecimals(inst.TickSz)
+	inst.SizeDecimals = okxDecimals(inst.LotSz)
+}
+
+func (o *okx) updateMarkets(ctx context.Context) (map[string]*Market, error) {
+	var res []*okxtypes.Instrument
+	if err := o.getAPI(ctx, "/api/v5/public/instruments", url.Values{"instType": []string{"SPOT"}}, false, &res); err != nil {
+		return nil, fmt.Errorf("error fetching instruments: %w", err)
+	}
+
+	instruments := make(map[string]*okxtypes.Instrument, len(res))
+	dexMarkets := make(map[string][][2]uint32, len(res))
+	for _, inst := range res {
+		if inst.State != "live" {
+			continue
+		}
+		baseIDs, quoteIDs := o.ccyIDs[inst.BaseCcy], o.ccyIDs[inst.QuoteCcy]
+		if len(baseIDs) == 0 || len(quoteIDs) == 0 {
+			continue
+		}
+		bui, err := asset.UnitInfo(baseIDs[0])
+		if err != nil {
+			continue
+		}
+		qui, err := asset.UnitInfo(quoteIDs[0])
+		if err != nil {
+			continue
+		}
+		parseOKXInstrument(inst, &bui, &qui)
+		instruments[inst.InstID] = inst
+		for _, baseID := range baseIDs {
+			for _, quoteID := range quoteIDs {
+				dexMarkets[inst.InstID] = append(dexMarkets[inst.InstID], [2]uint32{baseID, quoteID})
+			}
+		}
+	}
+
+	tickers := make(map[string]*okxtypes.Ticker)
+	var tickerList []*okxtypes.Ticker
+	if err := o.getAPI(ctx, "/api/v5/market/tickers", url.Values{"instType": []string{"SPOT"}}, false, &tickerList); err != nil {
+		o.log.Errorf("Error fetching tickers: %v", err)
+	}
+	for _, t := range tickerList {
+		tickers[t.InstID] = t
+	}
+
+	mins, err := o.minWithdraws(ctx)
+	if err != nil {
+		o.log.Errorf("Error fetching currency info: %v", err)
+	}
+
+	markets := make(map[string]*Market, len(dexMarkets))
+	for instID, mkts := range dexMarkets {
+		var day *MarketDay
+		if t := tickers[instID]; t != nil {
+			day = okxMarketDay(t)
+		}
+		for _, m := range mkts {
+			markets[dex.BipIDSymbol(m[0])+"_"+dex.BipIDSymbol(m[1])] = &Market{
+				BaseID:           m[0],
+				QuoteID:          m[1],
+				Day:              day,
+				BaseMinWithdraw:  mins[m[0]],
+				QuoteMinWithdraw: mins[m[1]],
+			}
+		}
+	}
+
+	o.instruments.Store(instruments)
+
+	o.marketSnapshotMtx.Lock()
+	defer o.marketSnapshotMtx.Unlock()
+	o.marketSnapshot.m = markets
+	o.marketSnapshot.stamp = time.Now()
+	return markets, nil
+}
+
+// minWithdraws returns the minimum withdrawal amounts of the DEX assets, in
+// atomic units. OKX deducts the fee from the withdrawal amount, and the
+// amount less the fee must be at least the currency's minimum.
+func (o *okx) minWithdraws(ctx context.Context) (map[uint32]uint64, error) {
+	var currencies []*okxtypes.Currency
+	if err := o.getAPI(ctx, "/api/v5/asset/currencies", nil, true, &currencies); err != nil {
+		return nil, err
+	}
+	type ccyChain struct{ ccy, chain string }
+	curs := make(map[ccyChain]*okxtypes.Currency, len(currencies))
+	for _, c := range currencies {
+		curs[ccyChain{c.Ccy, c.Chain}] = c
+	}
+	mins := make(map[uint32]uint64, len(o.idCcy))
+	for assetID := range o.idCcy {
+		ccy, chain, err := o.ccyAndChain(assetID)
+		if err != nil {
+			continue
+		}
+		c := curs[ccyChain{ccy, chain}]
+		if c == nil || !c.CanWd {
+			continue
+		}
+		ui, err := asset.UnitInfo(assetID)
+		if err != nil {
+			continue
+		}
+		minWd := toAtomic(okxFloat(c.MinWd), &ui)
+		if minWd == 0 {
+			minWd = 1
+		}
+		mins[assetID] = toAtomic(okxFloat(c.MinFee), &ui) + minWd
+	}
+	return mins, nil
+}
+
+func okxMarketDay(t *okxtypes.Ticker) *MarketDay {
+	last, open := okxFloat(t.Last), okxFloat(t.Open24h)
+	vol, quoteVol := okxFloat(t.Vol24h), okxFloat(t.VolCcy24h)
+	var pctChange, avg float64
+	if open > 0 {
+		pctChange = (last - open) / open * 100
+	}
+	if vol > 0 {
+		avg = quoteVol / vol
+	}
+	return &MarketDay{
+		Vol:            vol,
+		QuoteVol:       quoteVol,
+		PriceChange:    last - open,
+		PriceChangePct: pctChange,
+		AvgPrice:       avg,
+		LastPrice:      last,
+		OpenPrice:      open,
+		HighPrice:      okxFloat(t.High24h),
+		LowPrice:       okxFloat(t.Low24h),
+	}
+}
+
+func (o *okx) instrument(baseID, quoteID uint32) (*okxtypes.Instrument, error) {
+	baseCcy, found := o.idCcy[baseID]
+	if !found {
+		return nil, fmt.Errorf("no OKX currency for %s", dex.BipIDSymbol(baseID))
+	}
+	quoteCcy, found := o.idCcy[quoteID]
+	if !found {
+		return nil, fmt.Errorf("no OKX currency for %s", dex.BipIDSymbol(quoteID))
+	}
+	instruments := o.instruments.Load().(map[string]*okxtypes.Instrument)
+	inst, found := instruments[baseCcy+"-"+quoteCcy]
+	if !found {
+		return nil, fmt.Errorf("no OKX market for %s-%s", baseCcy, quoteCcy)
+	}
+	return inst, nil
+}
+
+// updateBalances updates the balances of the currencies in details. If
+// replace is true, details are the balances of all currencies, and the
+// balances of any currencies that are not included are zeroed.
+func (o *okx) updateBalances(details []*okxtypes.BalanceDetail, replace bool) {
+	balances := make(map[uint32]*ExchangeBalance)
+	for _, d := range details {
+		for _, assetID := range o.ccyIDs[d.Ccy] {
+			ui, err := asset.UnitInfo(assetID)
+			if err != nil {
+				o.log.Errorf("no unit info for known asset ID %d?", assetID)
+				continue
+			}
+			balances[assetID] = &ExchangeBalance{
+				Available: toAtomic(okxFloat(d.AvailBal), &ui),
+				Locked:    toAtomic(okxFloat(d.FrozenBal), &ui),
+			}
+		}
+	}
+
+	updates := make([]*BalanceUpdate, 0)
+	o.balanceMtx.Lock()
+	if replace {
+		for assetID := range o.balances {
+			if _, found := balances[assetID]; !found {
+				balances[assetID] = &ExchangeBalance{}
+			}
+		}
+	}
+	for assetID, newBal := range balances {
+		if oldBal := o.balances[assetID]; oldBal == nil || *oldBal != *newBal {
+			updates = append(updates, &BalanceUpdate{
+				AssetID: assetID,
+				Balance: newBal,
+			})
+		}
+		o.balances[assetID] = newBal
+	}
+	o.balanceMtx.Unlock()
+
+	for _, u := range updates {
+		o.broadcast(u)
+	}
+}
+
+func (o *okx) refreshBalances(ctx context.Context) error {
+	var res []*okxtypes.AccountBalance
+	if err := o.getAPI(ctx, "/api/v5/account/balance", nil, true, &res); err != nil {
+		return err
+	}
+	var details []*okxtypes.BalanceDetail
+	for _, b := range res {
+		details = append(details, b.Details...)
+	}
+	o.updateBalances(details, true)
+	return nil
+}
+
+func (o *okx) handleUserMessage(msg *okxtypes.WsMessage) {
+	switch msg.Arg.Channel {
+	case okxOrdersChannel:
+		var ords []*okxtypes.Order
+		if err := json.Unmarshal(msg.Data, &ords); err != nil {
+			o.log.Errorf("Error unmarshaling orders: %v", err)
+			return
+		}
+		for _, ord := range ords {
+			o.handleOrderUpdate(ord)
+		}
+	case "account":
+		var res []*okxtypes.AccountBalance
+		if err := json.Unmarshal(msg.Data, &res); err != nil {
+			o.log.Errorf("Error unmarshaling account: %v", err)
+			return
+		}
+		for _, b := range res {
+			o.updateBalances(b.Details, false)
+		}
+	default:
+		o.log.Debugf("Message for unknown channel %q", msg.Arg.Channel)
+	}
+}
+
+func okxOrderComplete(state string) bool {
+	return state == "filled" || state == "canceled" || state == "mmp_canceled"
+}
+
+// okxOrderFills returns the amounts of the base and quote assets that have
+// been received and spent by an order. OKX charges the fee in the asset
+// that is received.
+func okxOrderFills(ord *okxtypes.Order, baseCcy, quoteCcy string, bui, qui *dex.UnitInfo) (baseFilled, quoteFilled uint64) {
+	filled := okxFloat(ord.AccFillSz)
+	baseFilled = toAtomic(filled, bui)
+	quoteFilled = toAtomic(filled*okxFloat(ord.AvgPx), qui)
+	// The fee is negative when charged and positive for rebates.
+	fee := okxFloat(ord.Fee)
+	applyFee := func(v uint64, ui *dex.UnitInfo) uint64 {
+		if fee < 0 {
+			return utils.SafeSub(v, toAtomic(-fee, ui))
+		}
+		return v + toAtomic(fee, ui)
+	}
+	switch ord.FeeCcy {
+	case baseCcy:
+		baseFilled = applyFee(baseFilled, bui)
+	case quoteCcy:
+		quoteFilled = applyFee(quoteFilled, qui)
+	}
+	return
+}
+
+func (o *okx) handleOrderUpdate(ord *okxtypes.Order) {
+	if ord.ClOrdID == "" {
+		// Not placed by us.
+		return
+	}
+
+	o.tradeUpdaterMtx.Lock()
+	defer o.tradeUpdaterMtx.Unlock()
+
+	info, found := o.tradeInfo[ord.ClOrdID]
+	if !found {
+		o.log.Debugf("No trade info for client order ID %s", ord.ClOrdID)
+		return
+	}
+
+	updater, found := o.tradeUpdaters[info.updaterID]
+	if !found {
+		o.log.Errorf("No trade updater with ID %v", info.updaterID)
+		return
+	}
+
+	bui, err := asset.UnitInfo(info.baseID)
+	if err != nil {
+		o.log.Errorf("Error getting unit info for asset ID %d: %v", info.baseID, err)
+		return
+	}
+	qui, err := asset.UnitInfo(info.quoteID)
+	if err != nil {
+		o.log.Errorf("Error getting unit info for asset ID %d: %v", info.quoteID, err)
+		return
+	}
+
+	baseFilled, quoteFilled := okxOrderFills(ord, o.idCcy[info.baseID], o.idCcy[info.quoteID], &bui, &qui)
+	complete := okxOrderComplete(ord.State)
+	updater <- &Trade{
+		ID:          ord.OrdID,
+		Sell:        info.sell,
+		Rate:        info.rate,
+		Qty:         info.qty,
+		Market:      info.market,
+		BaseID:      info.baseID,
+		QuoteID:     info.quoteID,
+		BaseFilled:  baseFilled,
+		QuoteFilled: quoteFilled,
+		Complete:    complete,
+	}
+
+	if complete {
+		delete(o.tradeInfo, ord.ClOrdID)
+	}
+}
+
+// loginRequest generates a websocket login request.
+func (o *okx) loginRequest() *okxtypes.WsRequest {
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	return &okxtypes.WsRequest{
+		Op: "login",
+		Args: []interface{}{&okxtypes.LoginArg{
+			APIKey:     o.apiKey,
+			Passphrase: o.passphrase,
+			Timestamp:  ts,
+			Sign:       okxSignature(ts, http.MethodGet, "/users/self/verify", "", o.secretKey),
+		}},
+	}
+}
+
+func (o *okx) subscribeUserChannels(ctx context.Context) (*sync.WaitGroup, error) {
+	subs := func() []*okxtypes.WsRequest {
+		return []*okxtypes.WsRequest{{
+			Op: "subscribe",
+			Args: []interface{}{
+				&okxtypes.WsArg{Channel: okxOrdersChannel, InstType: "SPOT"},
+				&okxtypes.WsArg{Channel: "account"},
+			},
+		}}
+	}
+	conn := newOKXWSConn(o.privateWsURL, o.loginRequest, subs, o.handleUserMessage, func(bool) {}, o.log.SubLogger("WS-private"))
+	cm := dex.NewConnectionMaster(conn)
+	if err := cm.ConnectOnce(ctx); err != nil {
+		return nil, fmt.Errorf("error connecting to websocket feed: %w", err)
+	}
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-ctx.Done()
+		cm.Disconnect()
+	}()
+
+	return &wg, nil
+}
+
+func (o *okx) Connect(ctx context.Context) (*sync.WaitGroup, error) {
+	if _, err := o.updateMarkets(ctx); err != nil {
+		return nil, fmt.Errorf("error fetching markets: %w", err)
+	}
+
+	if err := o.refreshBalances(ctx); err != nil {
+		return nil, fmt.Errorf("error fetching balances: %w", err)
+	}
+
+	o.ctx = ctx
+
+	wg, err := o.subscribeUserChannels(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error subscribing to user channels: %w", err)
+	}
+
+	// The account channel pushes balance changes, but refresh the balances
+	// periodically in case an update is missed.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := o.refreshBalances(ctx); err != nil {
+					o.log.Errorf("Error refreshing balances: %v", err)
+				}
+			}
+		}
+	}()
+
+	// Update markets every 10 minutes. These shouldn't change often.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		ticker := time.NewTicker(time.Minute * 10)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := o.updateMarkets(ctx); err != nil {
+					o.log.Errorf("Error fetching markets: %v", err)
+				}
+			}
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-ctx.Done()
+
+		o.booksMtx.RLock()
+		defer o.booksMtx.RUnlock()
+		for _, book := range o.books {
+			book.cm.Disconnect()
+		}
+	}()
+
+	return wg, nil
+}
+
+// Balance returns the balance of an asset at the CEX.
+func (o *okx) Balance(assetID uint32) (*ExchangeBalance, error) {
+	o.balanceMtx.RLock()
+	defer o.balanceMtx.RUnlock()
+
+	bal, found := o.balances[assetID]
+	if !found {
+		return nil, fmt.Errorf("no %s balance found", dex.BipIDSymbol(assetID))
+	}
+	return bal, nil
+}
+
+// Balances returns the balances of known assets on the CEX.
+func (o *okx) Balances(ctx context.Context) (map[uint32]*ExchangeBalance, error) {
+	if err := o.refreshBalances(ctx); err != nil {
+		return nil, err
+	}
+
+	o.balanceMtx.RLock()
+	defer o.balanceMtx.RUnlock()
+
+	balances := make(map[uint32]*ExchangeBalance, len(o.balances))
+	for assetID, bal := range o.balances {
+		b := *bal
+		balances[assetID] = &b
+	}
+	return balances, nil
+}
+
+// CancelTrade cancels a trade on the CEX.
+func (o *okx) CancelTrade(ctx context.Context, baseID, quoteID uint32, tradeID string) error {
+	inst, err := o.instrument(baseID, quoteID)
+	if err != nil {
+		return err
+	}
+	var res []*okxtypes.OrderResult
+	req := &okxtypes.CancelOrderRequest{InstID: inst.InstID, OrdID: tradeID}
+	if err := o.postAPI(ctx, "/api/v5/trade/cancel-order", req, &res); err != nil {
+		return fmt.Errorf("error cancelling order: %w", err)
+	}
+	if len(res) != 1 || res[0].SCode != "0" {
+		return fmt.Errorf("order %s not cancelled", tradeID)
+	}
+	return nil
+}
+
+// Markets returns the list of markets at the CEX.
+func (o *okx) Markets(ctx context.Context) (map[string]*Market, error) {
+	o.marketSnapshotMtx.RLock()
+	const snapshotTimeout = time.Minute * 30
+	if o.marketSnapshot.m != nil && time.Since(o.marketSnapshot.stamp) < snapshotTimeout {
+		defer o.marketSnapshotMtx.RUnlock()
+		return o.marketSnapshot.m, nil
+	}
+	o.marketSnapshotMtx.RUnlock()
+
+	return o.updateMarkets(ctx)
+}
+
+// SubscribeMarket subscribes to order book updates on a market. This must
+// be called before calling VWAP or MidGap.
+func (o *okx) SubscribeMarket(ctx context.Context, baseID, quoteID uint32) error {
+	o.subMarketMtx.Lock()
+	defer o.subMarketMtx.Unlock()
+
+	inst, err := o.instrument(baseID, quoteID)
+	if err != nil {
+		return err
+	}
+	bui, err := asset.UnitInfo(baseID)
+	if err != nil {
+		return fmt.Errorf("error getting unit info for base asset ID %d: %v", baseID, err)
+	}
+	qui, err := asset.UnitInfo(quoteID)
+	if err != nil {
+		return fmt.Errorf("error getting unit info for quote asset ID %d: %v", quoteID, err)
+	}
+
+	o.booksMtx.RLock()
+	book, exists := o.books[inst.InstID]
+	o.booksMtx.RUnlock()
+	if exists {
+		book.mtx.Lock()
+		book.numSubscribers++
+		book.mtx.Unlock()
+		return nil
+	}
+
+	book = newOKXBook(o.publicWsURL, inst.InstID, &bui, &qui, o.log)
+	if err := book.sync(o.ctx); err != nil {
+		return fmt.Errorf("error syncing book: %v", err)
+	}
+
+	o.booksMtx.Lock()
+	o.books[inst.InstID] = book
+	o.booksMtx.Unlock()
+
+	return nil
+}
+
+// SubscribeTradeUpdates returns a channel that the caller can use to
+// listen for updates to a trade's status. When the subscription ID
+// returned from this function is passed as the updaterID argument to
+// Trade, then updates to the trade will be sent on the updated channel
+// returned from this function.
+func (o *okx) SubscribeTradeUpdates() (<-chan *Trade, func(), int) {
+	o.tradeUpdaterMtx.Lock()
+	defer o.tradeUpdaterMtx.Unlock()
+
+	updaterID := o.tradeUpdateCounter
+	o.tradeUpdateCounter++
+	updater := make(chan *Trade, 256)
+	o.tradeUpdaters[updaterID] = updater
+
+	unsubscribe := func() {
+		o.tradeUpdaterMtx.Lock()
+		delete(o.tradeUpdaters, updaterID)
+		o.tradeUpdaterMtx.Unlock()
+	}
+
+	return updater, unsubscribe, updaterID
+}
+
+// generateTradeID generates a client order ID. OKX accepts up to 32
+// alphanumeric characters as a client order ID.
+func (o *okx) generateTradeID() string {
+	nonce := o.tradeIDNonce.Add(1)
+	nonceB := encode.Uint32Bytes(nonce)
+	return hex.EncodeToString(append(o.tradeIDNoncePrefix, nonceB...))
+}
+
+// buildOKXOrderRequest builds an order request, and returns the quantity of
+// the order after it has been adjusted to the lot size.
+func buildOKXOrderRequest(inst *okxtypes.Instrument, bui, qui *dex.UnitInfo, sell bool, orderType OrderType, rate, qty, quoteQty uint64, tradeID string) (*okxtypes.OrderRequest, uint64, error) {
+	if qty > 0 && quoteQty > 0 {
+		return nil, 0, fmt.Errorf("cannot specify both quantity and quote quantity")
+	}
+	if sell && quoteQty > 0 {
+		return nil, 0, fmt.Errorf("quote quantity cannot be used for sell orders")
+	}
+	if !sell && orderType == OrderTypeMarket && qty > 0 {
+		return nil, 0, fmt.Errorf("quoteQty MUST be used for market buys")
+	}
+
+	bFactor := bui.Conventional.ConversionFactor
+	qFactor := qui.Conventional.ConversionFactor
+
+	req := &okxtypes.OrderRequest{
+		InstID:  inst.InstID,
+		TdMode:  "cash",
+		ClOrdID: tradeID,
+		Side:    "buy",
+	}
+	if sell {
+		req.Side = "sell"
+	}
+
+	switch orderType {
+	case OrderTypeMarket:
+		req.OrdType = "market"
+		if quoteQty > 0 {
+			// Market buys are specified in units of the quote asset.
+			req.TgtCcy = "quote_ccy"
+			quoteDecimals := int(math.Round(math.Log10(float64(qFactor))))
+			req.Sz = strconv.FormatFloat(float64(quoteQty)/float64(qFactor), 'f', quoteDecimals, 64)
+			return req, quoteQty, nil
+		}
+		req.TgtCcy = "base_ccy"
+	default:
+		req.OrdType = "limit"
+		if orderType == OrderTypeLimitIOC {
+			req.OrdType = "ioc"
+		}
+		if rate == 0 {
+			return nil, 0, fmt.Errorf("rate must be specified for limit orders")
+		}
+		rate = steppedRate(rate, inst.RateStep)
+		convRate := calc.ConventionalRateAlt(rate, bFactor, qFactor)
+		req.Px = strconv.FormatFloat(convRate, 'f', inst.PriceDecimals, 64)
+		if quoteQty > 0 {
+			qty = calc.QuoteToBase(rate, quoteQty)
+		}
+	}
+
+	if qty == 0 {
+		return nil, 0, fmt.Errorf("must specify quantity or quote quantity")
+	}
+	qty = steppedQty(qty, inst.LotSize)
+	if qty < inst.MinQty {
+		return nil, 0, fmt.Errorf("quantity %s is lower than the minimum %s",
+			bui.FormatConventional(qty), bui.FormatConventional(inst.MinQty))
+	}
+	req.Sz = strconv.FormatFloat(float64(qty)/float64(bFactor), 'f', inst.SizeDecimals, 64)
+
+	return req, qty, nil
+}
+
+// Trade executes a trade on the CEX.
+//   - subscriptionID takes an ID returned from SubscribeTradeUpdates.
+//   - Rate is ignored for market orders.
+//   - Qty is in units of base asset, quoteQty is in units of quote asset.
+//     Only one of qty or quoteQty should be non-zero.
+//   - QuoteQty is only allowed for BUY orders, and it is required for market
+//     buy orders.
+func (o *okx) Trade(ctx context.Context, baseID, quoteID uint32, sell bool, rate, qty, quoteQty uint64, orderType OrderType, subscriptionID int) (*Trade, error) {
+	inst, err := o.instrument(baseID, quoteID)
+	if err != nil {
+		return nil, err
+	}
+	bui, err := asset.UnitInfo(baseID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting unit info for base asset ID %d: %v", baseID, err)
+	}
+	qui, err := asset.UnitInfo(quoteID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting unit info for quote asset ID %d: %v", quoteID, err)
+	}
+
+	tradeID := o.generateTradeID()
+	req, qtyInRequest, err := buildOKXOrderRequest(inst, &bui, &qui, sell, orderType, rate, qty, quoteQty, tradeID)
+	if err != nil {
+		return nil, fmt.Errorf("error building order request: %w", err)
+	}
+
+	// The trade info is stored before the order is placed, because the
+	// order updates may arrive before the response.
+	market := orderType == OrderTypeMarket
+	o.tradeUpdaterMtx.Lock()
+	if _, found := o.tradeUpdaters[subscriptionID]; !found {
+		o.tradeUpdaterMtx.Unlock()
+		return nil, fmt.Errorf("no trade updater with ID %v", subscriptionID)
+	}
+	o.tradeInfo[tradeID] = &tradeInfo{
+		updaterID: subscriptionID,
+		baseID:    baseID,
+		quoteID:   quoteID,
+		sell:      sell,
+		rate:      rate,
+		qty:       qtyInRequest,
+		market:    market,
+	}
+	o.tradeUpdaterMtx.Unlock()
+
+	deleteInfo := func() {
+		o.tradeUpdaterMtx.Lock()
+		delete(o.tradeInfo, tradeID)
+		o.tradeUpdaterMtx.Unlock()
+	}
+
+	var res []*okxtypes.OrderResult
+	if err := o.postAPI(ctx, "/api/v5/trade/order", req, &res); err != nil {
+		deleteInfo()
+		return nil, err
+	}
+	if len(res) != 1 {
+		deleteInfo()
+		return nil, fmt.Errorf("expected 1 order result, got %d", len(res))
+	}
+	if res[0].SCode != "0" {
+		deleteInfo()
+		return nil, fmt.Errorf("order rejected: %w", &OKXError{Code: res[0].SCode, Msg: res[0].SMsg})
+	}
+
+	return &Trade{
+		ID:      res[0].OrdID,
+		Sell:    sell,
+		Rate:    rate,
+		Qty:     qtyInRequest,
+		BaseID:  baseID,
+		QuoteID: quoteID,
+		Market:  market,
+	}, nil
+}
+
+// ValidateTrade validates a trade before it is executed.
+func (o *okx) ValidateTrade(baseID, quoteID uint32, sell bool, rate, qty, quoteQty uint64, orderType OrderType) error {
+	inst, err := o.instrument(baseID, quoteID)
+	if err != nil {
+		return err
+	}
+	bui, err := asset.UnitInfo(baseID)
+	if err != nil {
+		return fmt.Errorf("error getting unit info for base asset ID %d: %v", baseID, err)
+	}
+	qui, err := asset.UnitInfo(quoteID)
+	if err != nil {
+		return fmt.Errorf("error getting unit info for quote asset ID %d: %v", quoteID, err)
+	}
+	_, _, err = buildOKXOrderRequest(inst, &bui, &qui, sell, orderType, rate, qty, quoteQty, "")
+	return err
+}
+
+// UnsubscribeMarket unsubscribes from order book updates on a market.
+func (o *okx) UnsubscribeMarket(baseID, quoteID uint32) error {
+	o.subMarketMtx.Lock()
+	defer o.subMarketMtx.Unlock()
+
+	inst, err := o.instrument(baseID, quoteID)
+	if err != nil {
+		return err
+	}
+
+	o.booksMtx.RLock()
+	book, found := o.books[inst.InstID]
+	o.booksMtx.RUnlock()
+	if !found {
+		return fmt.Errorf("no book found for %s", inst.InstID)
+	}
+
+	book.mtx.Lock()
+	book.numSubscribers--
+	numSubscribers := book.numSubscribers
+	book.mtx.Unlock()
+
+	if numSubscribers == 0 {
+		o.booksMtx.Lock()
+		delete(o.books, inst.InstID)
+		o.booksMtx.Unlock()
+		go book.cm.Disconnect()
+	}
+
+	return nil
+}
+
+func (o *okx) book(baseID, quoteID uint32) (*okxBook, error) {
+	inst, err := o.instrument(baseID, quoteID)
+	if err != nil {
+		return nil, err
+	}
+
+	o.booksMtx.RLock()
+	book, found := o.books[inst.InstID]
+	o.booksMtx.RUnlock()
+	if !found {
+		return nil, fmt.Errorf("no book for market %s", inst.InstID)
+	}
+
+	return book, nil
+}
+
+// Book generates the CEX's current view of a market's orderbook.
+func (o *okx) Book(baseID, quoteID uint32) (buys, sells []*core.MiniOrder, _ error) {
+	book, err := o.book(baseID, quoteID)
+	if err != nil {
+		return nil, nil, err
+	}
+	bids, asks := book.book.snap()
+	baseFactor := book.bui.Conventional.ConversionFactor
+	quoteFactor := book.qui.Conventional.ConversionFactor
+	buys = convertSide(bids, false, baseFactor, quoteFactor)
+	sells = convertSide(asks, true, baseFactor, quoteFactor)
+	return
+}
+
+// VWAP returns the volume weighted average price for a certain quantity
+// of the base asset on a market. SubscribeMarket must be called, and the
+// market must be synced before results can be expected.
+func (o *okx) VWAP(baseID, quoteID uint32, sell bool, qty uint64) (vwap, extrema uint64, filled bool, err error) {
+	book, err := o.book(baseID, quoteID)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	return book.vwap(!sell, qty)
+}
+
+// InvVWAP returns the inverse volume weighted average price for a certain
+// quantity of the quote asset on a market. SubscribeMarket must be called,
+// and the market must be synced before results can be expected.
+func (o *okx) InvVWAP(baseID, quoteID uint32, sell bool, qty uint64) (vwap, extrema uint64, filled bool, err error) {
+	book, err := o.book(baseID, quoteID)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	return book.invVWAP(!sell, qty)
+}
+
+// MidGap returns the mid-gap price for a market.
+func (o *okx) MidGap(baseID, quoteID uint32) uint64 {
+	book, err := o.book(baseID, quoteID)
+	if err != nil {
+		o.log.Errorf("Error getting book: %v", err)
+		return 0
+	}
+
+	midGap, err := book.midGap()
+	if err != nil {
+		o.log.Errorf("Error getting mid gap: %v", err)
+		return 0
+	}
+
+	return midGap
+}
+
+// ccyAndChain returns the OKX currency and chain for the asset.
+func (o *okx) ccyAndChain(assetID uint32) (ccy, chain string, err error) {
+	ccy, found := o.idCcy[assetID]
+	if !found {
+		return "", "", fmt.Errorf("no OKX currency for %s", dex.BipIDSymbol(assetID))
+	}
+	chain, err = okxChain(assetID)
+	return ccy, chain, err
+}
+
+// transfer moves funds between the funding account, where deposits are
+// credited and withdrawals are sent from, and the trading account.
+func (o *okx) transfer(ctx context.Context, assetID uint32, amt uint64, from, to string) error {
+	ccy, found := o.idCcy[assetID]
+	if !found {
+		return fmt.Errorf("no OKX currency for %s", dex.BipIDSymbol(assetID))
+	}
+	ui, err := asset.UnitInfo(assetID)
+	if err != nil {
+		return fmt.Errorf("error getting unit info for asset ID %d: %v", assetID, err)
+	}
+	prec := int(math.Round(math.Log10(float64(ui.Conventional.ConversionFactor))))
+	req := &okxtypes.TransferRequest{
+		Ccy:  ccy,
+		Amt:  strconv.FormatFloat(toConv(amt, &ui), 'f', prec, 64),
+		From: from,
+		To:   to,
+	}
+	return o.postAPI(ctx, "/api/v5/asset/transfer", req, nil)
+}
+
+// GetDepositAddress returns a deposit address for an asset.
+func (o *okx) GetDepositAddress(ctx context.Context, assetID uint32) (string, error) {
+	ccy, chain, err := o.ccyAndChain(assetID)
+	if err != nil {
+		return "", err
+	}
+
+	var addrs []*okxtypes.DepositAddress
+	if err := o.getAPI(ctx, "/api/v5/asset/deposit-address", url.Values{"ccy": []string{ccy}}, true, &addrs); err != nil {
+		return "", fmt.Errorf("error fetching deposit addresses: %w", err)
+	}
+	for _, a := range addrs {
+		if a.Chain == chain {
+			return a.Addr, nil
+		}
+	}
+
+	return "", fmt.Errorf("no deposit address returned for %s on %s", dex.BipIDSymbol(assetID), chain)
+}
+
+// ConfirmDeposit checks whether a deposit has been credited and returns the
+// amount credited to the account. Deposits are credited to the funding
+// account, so once the deposit is complete, the amount is transferred to the
+// trading account.
+func (o *okx) ConfirmDeposit(ctx context.Context, deposit *DepositData) (bool, uint64) {
+	ccy, chain, err := o.ccyAndChain(deposit.AssetID)
+	if err != nil {
+		o.log.Errorf("Error confirming deposit %s: %v", deposit.TxID, err)
+		return false, 0
+	}
+
+	var deposits []*okxtypes.Deposit
+	q := url.Values{"ccy": []string{ccy}, "txId": []string{deposit.TxID}}
+	if err := o.getAPI(ctx, "/api/v5/asset/deposit-history", q, true, &deposits); err != nil {
+		o.log.Errorf("Error getting deposit status for %s: %v", deposit.TxID, err)
+		return false, 0
+	}
+	var dep *okxtypes.Deposit
+	for _, d := range deposits {
+		if d.TxID == deposit.TxID && d.Chain == chain {
+			dep = d
+			break
+		}
+	}
+	if dep == nil || dep.State != okxtypes.DepositStateSuccessful {
+		return false, 0
+	}
+
+	ui, err := asset.UnitInfo(deposit.AssetID)
+	if err != nil {
+		o.log.Errorf("Failed to find unit info for asset ID %d", deposit.AssetID)
+		return true, 0
+	}
+	amt := toAtomic(okxFloat(dep.Amt), &ui)
+	if err := o.transfer(ctx, deposit.AssetID, amt, okxtypes.AccountFunding, okxtypes.AccountTrading); err != nil {
+		// The transfer will be retried the next time the deposit is checked.
+		o.log.Errorf("Error transferring deposit %s to the trading account: %v", deposit.TxID, err)
+		return false, 0
+	}
+
+	return true, amt
+}
+
+// Withdraw withdraws funds from the CEX to a certain address. OKX charges
+// the withdrawal fee in addition to the withdrawn amount, so the fee is
+// subtracted from amt, and the balance is reduced by exactly amt. The funds
+// are transferred from the trading account to the funding account first.
+func (o *okx) Withdraw(ctx context.Context, assetID uint32, amt uint64, address string) (string, uint64, error) {
+	ccy, chain, err := o.ccyAndChain(assetID)
+	if err != nil {
+		return "", 0, err
+	}
+
+	ui, err := asset.UnitInfo(assetID)
+	if err != nil {
+		return "", 0, fmt.Errorf("error getting unit info for asset ID %d: %v", assetID, err)
+	}
+
+	var currencies []*okxtypes.Currency
+	if err := o.getAPI(ctx, "/api/v5/asset/currencies", url.Values{"ccy": []string{ccy}}, true, &currencies); err != nil {
+		return "", 0, fmt.Errorf("error fetching currency info: %w", err)
+	}
+	var cur *okxtypes.Currency
+	for _, c := range currencies {
+		if c.Chain == chain {
+			cur = c
+			break
+		}
+	}
+	if cur == nil || !cur.CanWd {
+		return "", 0, fmt.Errorf("withdrawals of %s on %s are not available", ccy, chain)
+	}
+
+	fee := toAtomic(okxFloat(cur.MinFee), &ui)
+	wdAmt := utils.SafeSub(amt, fee)
+	if minWd := toAtomic(okxFloat(cur.MinWd), &ui); wdAmt == 0 || wdAmt < minWd {
+		return "", 0, fmt.Errorf("withdrawal amount %s less fee %s is lower than the minimum %s",
+			ui.FormatConventional(amt), ui.FormatConventional(fee), ui.FormatConventional(minWd))
+	}
+
+	if err := o.transfer(ctx, assetID, amt, okxtypes.AccountTrading, okxtypes.AccountFunding); err != nil {
+		return "", 0, fmt.Errorf("error transferring to the funding account: %w", err)
+	}
+
+	prec := int(math.Round(math.Log10(float64(ui.Conventional.ConversionFactor))))
+	req := &okxtypes.WithdrawalRequest{
+		Ccy:    ccy,
+		Amt:    strconv.FormatFloat(toConv(wdAmt, &ui), 'f', prec, 64),
+		Dest:   "4",
+		ToAddr: address,
+		Chain:  chain,
+	}
+	var res []*okxtypes.WithdrawalResult
+	if err := o.postAPI(ctx, "/api/v5/asset/withdrawal", req, &res); err != nil {
+		if err := o.transfer(ctx, assetID, amt, okxtypes.AccountFunding, okxtypes.AccountTrading); err != nil {
+			o.log.Errorf("Error returning funds to the trading account after failed withdrawal: %v", err)
+		}
+		return "", 0, fmt.Errorf("error withdrawing: %w", err)
+	}
+	if len(res) != 1 {
+		return "", 0, fmt.Errorf("expected 1 withdrawal result, got %d", len(res))
+	}
+
+	return res[0].WdID, amt, nil
+}
+
+// ConfirmWithdrawal checks whether a withdrawal has been completed. If the
+// withdrawal has not yet been sent, ErrWithdrawalPending is returned.
+func (o *okx) ConfirmWithdrawal(ctx context.Context, withdrawalID string, assetID uint32) (uint64, string, error) {
+	var withdrawals []*okxtypes.Withdrawal
+	if err := o.getAPI(ctx, "/api/v5/asset/withdrawal-history", url.Values{"wdId": []string{withdrawalID}}, true, &withdrawals); err != nil {
+		return 0, "", fmt.Errorf("error getting withdrawal status: %w", err)
+	}
+	if len(withdrawals) == 0 {
+		return 0, "", fmt.Errorf("withdrawal %s not found", withdrawalID)
+	}
+	wd := withdrawals[0]
+
+	if wd.State == okxtypes.WithdrawalStateFailed || wd.State == okxtypes.WithdrawalStateCanceled {
+		return 0, "", fmt.Errorf("withdrawal %s failed", withdrawalID)
+	}
+	if wd.TxID == "" {
+		return 0, "", ErrWithdrawalPending
+	}
+
+	ui, err := asset.UnitInfo(assetID)
+	if err != nil {
+		return 0, "", fmt.Errorf("error getting unit info for asset ID %d: %v", assetID, err)
+	}
+
+	return toAtomic(okxFloat(wd.Amt), &ui), wd.TxID, nil
+}
+
+// TradeStatus returns the current status of a trade.
+func (o *okx) TradeStatus(ctx context.Context, id string, baseID, quoteID uint32) (*Trade, error) {
+	inst, err := o.instrument(baseID, quoteID)
+	if err != nil {
+		return nil, err
+	}
+	var res []*okxtypes.Order
+	q := url.Values{"instId": []string{inst.InstID}, "ordId": []string{id}}
+	if err := o.getAPI(ctx, "/api/v5/trade/order", q, true, &res); err != nil {
+		return nil, fmt.Errorf("error fetching order status: %w", err)
+	}
+	if len(res) == 0 {
+		return nil, fmt.Errorf("order %s not found", id)
+	}
+
+	bui, err := asset.UnitInfo(baseID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting unit info for base asset ID %d: %v", baseID, err)
+	}
+	qui, err := asset.UnitInfo(quoteID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting unit info for quote asset ID %d: %v", quoteID, err)
+	}
+
+	ord := res[0]
+	market := ord.OrdType == "market"
+	var qty uint64
+	if ord.TgtCcy == "quote_ccy" {
+		qty = toAtomic(okxFloat(ord.Sz), &qui)
+	} else {
+		qty = toAtomic(okxFloat(ord.Sz), &bui)
+	}
+	var rate uint64
+	if !market {
+		rate = messageRate(okxFloat(ord.Px), &bui, &qui)
+	}
+	baseFilled, quoteFilled := okxOrderFills(ord, o.idCcy[baseID], o.idCcy[quoteID], &bui, &qui)
+
+	return &Trade{
+		ID:          id,
+		Sell:        ord.Side == "sell",
+		Qty:         qty,
+		Rate:        rate,
+		Market:      market,
+		BaseID:      baseID,
+		QuoteID:     quoteID,
+		BaseFilled:  baseFilled,
+		QuoteFilled: quoteFilled,
+		Complete:    okxOrderComplete(ord.State),
+	}, nil
+}
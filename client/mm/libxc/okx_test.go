@@ -0,0 +1,831 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package libxc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"decred.org/dcrdex/client/asset"
+	"decred.org/dcrdex/client/comms"
+	"decred.org/dcrdex/client/mm/libxc/okxtypes"
+	"decred.org/dcrdex/dex"
+	"decred.org/dcrdex/dex/calc"
+)
+
+const (
+	okxTestSecret     = "22582BD0CFF14C41EDBF1AB98506286D"
+	okxTestPassphrase = "passphrase"
+
+	okxInstrumentsFixture = `{"code":"0","msg":"","data":[
+		{"instId":"BTC-USDT","baseCcy":"BTC","quoteCcy":"USDT","tickSz":"0.1","lotSz":"0.00000001","minSz":"0.00001","state":"live"},
+		{"instId":"ETH-BTC","baseCcy":"ETH","quoteCcy":"BTC","tickSz":"0.00001","lotSz":"0.000001","minSz":"0.001","state":"live"},
+		{"instId":"LTC-USDT","baseCcy":"LTC","quoteCcy":"USDT","tickSz":"0.01","lotSz":"0.000001","minSz":"0.001","state":"suspend"},
+		{"instId":"OKB-USDT","baseCcy":"OKB","quoteCcy":"USDT","tickSz":"0.001","lotSz":"0.000001","minSz":"0.1","state":"live"}]}`
+
+	okxTickersFixture = `{"code":"0","msg":"","data":[
+		{"instId":"BTC-USDT","last":"45284","open24h":"44284","high24h":"46100","low24h":"43900","vol24h":"250","volCcy24h":"11225000"},
+		{"instId":"ETH-BTC","last":"0.05","open24h":"0.049","high24h":"0.051","low24h":"0.048","vol24h":"1000","volCcy24h":"50"}]}`
+
+	okxBalanceFixture = `{"code":"0","msg":"","data":[{"details":[
+		{"ccy":"BTC","availBal":"1.25","frozenBal":"0.25","cashBal":"1.5"},
+		{"ccy":"USDT","availBal":"1000","frozenBal":"0","cashBal":"1000"},
+		{"ccy":"OKB","availBal":"5","frozenBal":"0","cashBal":"5"}]}]}`
+
+	okxPlaceOrderFixture = `{"code":"0","msg":"","data":[{"ordId":"312269865356374016","clOrdId":"abc","sCode":"0","sMsg":""}]}`
+
+	okxOrderFixture = `{"code":"0","msg":"","data":[{"instId":"BTC-USDT","ordId":"312269865356374016","clOrdId":"abc",
+		"px":"45000.0","sz":"0.1","ordType":"limit","side":"buy","tgtCcy":"","accFillSz":"0.1","avgPx":"45000",
+		"fee":"-0.0001","feeCcy":"BTC","state":"filled"}]}`
+
+	okxDepositAddressFixture = `{"code":"0","msg":"","data":[
+		{"ccy":"USDT","chain":"USDT-TRC20","addr":"TXmVthgn6rsMwkmYEBYKwjnDxYRn4ZB6Yh"},
+		{"ccy":"USDT","chain":"USDT-ERC20","addr":"0x2dB0B7A4E2D8Ef0Ef1F21C8a1b0B7d2A3b4C5d6E"},
+		{"ccy":"USDT","chain":"USDT-Polygon","addr":"0x9a1a2e7c5d4a8f2b3c6e7d8f9a0b1c2d3e4f5a6b"}]}`
+
+	okxDepositHistoryFixture = `{"code":"0","msg":"","data":[
+		{"ccy":"USDT","chain":"USDT-ERC20","amt":"100","txId":"0xdeposit","depId":"88","state":"2"}]}`
+
+	okxCurrenciesFixture = `{"code":"0","msg":"","data":[
+		{"ccy":"BTC","chain":"BTC-Lightning","canDep":true,"canWd":true,"minWd":"0.000001","minFee":"0"},
+		{"ccy":"BTC","chain":"BTC-Bitcoin","canDep":true,"canWd":true,"minWd":"0.0005","minFee":"0.0002"},
+		{"ccy":"USDT","chain":"USDT-ERC20","canDep":true,"canWd":true,"minWd":"10","minFee":"1.5"},
+		{"ccy":"USDT","chain":"USDT-Polygon","canDep":true,"canWd":false,"minWd":"1","minFee":"0.1"}]}`
+
+	okxWithdrawalFixture = `{"code":"0","msg":"","data":[{"wdId":"58","ccy":"BTC","chain":"BTC-Bitcoin","amt":"0.0998"}]}`
+
+	okxWithdrawalHistoryFixture = `{"code":"0","msg":"","data":[
+		{"wdId":"58","ccy":"BTC","chain":"BTC-Bitcoin","amt":"0.0998","fee":"0.0002","txId":"","state":"1"}]}`
+
+	okxEmptyFixture = `{"code":"0","msg":"","data":[]}`
+)
+
+type tOKXRequest struct {
+	query url.Values
+	body  []byte
+}
+
+// tOKXServer serves the recorded REST responses.
+type tOKXServer struct {
+	mtx       sync.Mutex
+	responses map[string]string
+	requests  map[string][]*tOKXRequest
+}
+
+func newTOKXServer(t *testing.T) (*tOKXServer, *httptest.Server) {
+	s := &tOKXServer{
+		responses: map[string]string{
+			"/api/v5/public/instruments":       okxInstrumentsFixture,
+			"/api/v5/market/tickers":           okxTickersFixture,
+			"/api/v5/account/balance":          okxBalanceFixture,
+			"/api/v5/trade/order":              okxPlaceOrderFixture,
+			"/api/v5/asset/deposit-address":    okxDepositAddressFixture,
+			"/api/v5/asset/deposit-history":    okxDepositHistoryFixture,
+			"/api/v5/asset/currencies":         okxCurrenciesFixture,
+			"/api/v5/asset/transfer":           okxEmptyFixture,
+			"/api/v5/asset/withdrawal":         okxWithdrawalFixture,
+			"/api/v5/asset/withdrawal-history": okxWithdrawalHistoryFixture,
+		},
+		requests: make(map[string][]*tOKXRequest),
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mtx.Lock()
+		defer s.mtx.Unlock()
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("error reading body: %v", err)
+		}
+		public := strings.HasPrefix(r.URL.Path, "/api/v5/public/") || strings.HasPrefix(r.URL.Path, "/api/v5/market/")
+		if !public {
+			expSig := okxSignature(r.Header.Get("OK-ACCESS-TIMESTAMP"), r.Method, r.URL.RequestURI(), string(body), okxTestSecret)
+			if r.Header.Get("OK-ACCESS-SIGN") != expSig || r.Header.Get("OK-ACCESS-PASSPHRASE") != okxTestPassphrase {
+				w.Write([]byte(`{"code":"50113","msg":"Invalid Sign","data":[]}`))
+				return
+			}
+		}
+		s.requests[r.URL.Path] = append(s.requests[r.URL.Path], &tOKXRequest{query: r.URL.Query(), body: body})
+		path := r.URL.Path
+		if path == "/api/v5/trade/order" && r.Method == http.MethodGet {
+			path += "?get"
+		}
+		resp, found := s.responses[path]
+		if !found {
+			w.Write([]byte(`{"code":"50000","msg":"Unknown endpoint","data":[]}`))
+			return
+		}
+		w.Write([]byte(resp))
+	}))
+	return s, srv
+}
+
+func (s *tOKXServer) setResponse(path, resp string) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.responses[path] = resp
+}
+
+// lastRequest returns the last request to the path, and the number of
+// requests that have been made to it.
+func (s *tOKXServer) lastRequest(path string) (*tOKXRequest, int) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	reqs := s.requests[path]
+	if len(reqs) == 0 {
+		return nil, 0
+	}
+	return reqs[len(reqs)-1], len(reqs)
+}
+
+func tNewOKX(t *testing.T, srvURL string, notify func(interface{})) *okx {
+	if notify == nil {
+		notify = func(interface{}) {}
+	}
+	o, err := newOKX(&CEXConfig{
+		Net:        dex.Mainnet,
+		APIKey:     "key",
+		SecretKey:  okxTestSecret,
+		Passphrase: okxTestPassphrase,
+		Logger:     dex.StdOutLogger("T", dex.LevelTrace),
+		Notify:     notify,
+	})
+	if err != nil {
+		t.Fatalf("error creating okx: %v", err)
+	}
+	o.url = srvURL
+	return o
+}
+
+func TestOKXAssetMapping(t *testing.T) {
+	ccys := map[string]string{
+		"btc":          "BTC",
+		"dcr":          "DCR",
+		"polygon":      "POL",
+		"base":         "ETH",
+		"weth.polygon": "ETH",
+		"usdt.eth":     "USDT",
+		"usdc.polygon": "USDC",
+	}
+	for sym, exp := range ccys {
+		if ccy := okxCcy(sym); ccy != exp {
+			t.Fatalf("wrong currency for %s. expected %s, got %s", sym, exp, ccy)
+		}
+	}
+
+	chains := map[uint32]string{
+		0:      "BTC-Bitcoin",
+		60:     "ETH-ERC20",
+		966:    "POL-Polygon",
+		8453:   "ETH-Base",
+		60002:  "USDT-ERC20",
+		61000:  "USDC-Base",
+		966004: "USDT-Polygon",
+	}
+	for assetID, exp := range chains {
+		chain, err := okxChain(assetID)
+		if err != nil {
+			t.Fatalf("error getting chain for %s: %v", dex.BipIDSymbol(assetID), err)
+		}
+		if chain != exp {
+			t.Fatalf("wrong chain for %s. expected %s, got %s", dex.BipIDSymbol(assetID), exp, chain)
+		}
+	}
+
+	if _, err := newOKX(&CEXConfig{Net: dex.Mainnet, APIKey: "key", SecretKey: "secret"}); err == nil {
+		t.Fatalf("no error for missing passphrase")
+	}
+}
+
+func TestOKXMarketsAndBalances(t *testing.T) {
+	s, srv := newTOKXServer(t)
+	defer srv.Close()
+	var notesMtx sync.Mutex
+	var notes []*BalanceUpdate
+	o := tNewOKX(t, srv.URL, func(n interface{}) {
+		notesMtx.Lock()
+		notes = append(notes, n.(*BalanceUpdate))
+		notesMtx.Unlock()
+	})
+
+	markets, err := o.Markets(context.Background())
+	if err != nil {
+		t.Fatalf("Markets error: %v", err)
+	}
+	for _, mktID := range []string{"btc_usdt.eth", "btc_usdt.polygon", "eth_btc", "base_btc"} {
+		if _, found := markets[mktID]; !found {
+			t.Fatalf("market %s not found", mktID)
+		}
+	}
+	if len(markets) != 4 {
+		t.Fatalf("expected 4 markets, got %d", len(markets))
+	}
+	day := markets["btc_usdt.eth"].Day
+	if day == nil || day.LastPrice != 45284 || day.OpenPrice != 44284 || day.Vol != 250 || day.AvgPrice != 44900 || day.HighPrice != 46100 {
+		t.Fatalf("wrong market day: %+v", day)
+	}
+	for mktID, exp := range map[string][2]uint64{
+		"btc_usdt.eth":     {70000, 11.5e6},
+		"btc_usdt.polygon": {70000, 0},
+	} {
+		mkt := markets[mktID]
+		if mkt.BaseMinWithdraw != exp[0] || mkt.QuoteMinWithdraw != exp[1] {
+			t.Fatalf("wrong %s min withdraws. expected %v, got %d, %d", mktID, exp, mkt.BaseMinWithdraw, mkt.QuoteMinWithdraw)
+		}
+	}
+
+	inst, err := o.instrument(0, 60002)
+	if err != nil {
+		t.Fatalf("instrument error: %v", err)
+	}
+	btcUI, _ := asset.UnitInfo(0)
+	usdtUI, _ := asset.UnitInfo(60002)
+	if inst.LotSize != 1 || inst.MinQty != 1000 || inst.PriceDecimals != 1 || inst.SizeDecimals != 8 {
+		t.Fatalf("wrong instrument: %+v", inst)
+	}
+	if expStep := calc.MessageRate(0.1, btcUI, usdtUI); inst.RateStep != expStep {
+		t.Fatalf("wrong rate step. expected %d, got %d", expStep, inst.RateStep)
+	}
+	if _, err := o.instrument(2, 60002); err == nil {
+		t.Fatalf("no error for suspended instrument")
+	}
+
+	balances, err := o.Balances(context.Background())
+	if err != nil {
+		t.Fatalf("Balances error: %v", err)
+	}
+	expBTC := &ExchangeBalance{Available: 1.25e8, Locked: 0.25e8}
+	if !reflect.DeepEqual(balances[0], expBTC) {
+		t.Fatalf("wrong btc balance. expected %+v, got %+v", expBTC, balances[0])
+	}
+	expUSDT := &ExchangeBalance{Available: 1000e6}
+	for _, assetID := range []uint32{60002, 966004} {
+		if !reflect.DeepEqual(balances[assetID], expUSDT) {
+			t.Fatalf("wrong %s balance. expected %+v, got %+v", dex.BipIDSymbol(assetID), expUSDT, balances[assetID])
+		}
+	}
+	if len(balances) != 3 {
+		t.Fatalf("expected 3 balances, got %d", len(balances))
+	}
+
+	// The account channel only includes the currencies that changed.
+	notes = nil
+	o.handleUserMessage(&okxtypes.WsMessage{
+		Arg:  &okxtypes.WsArg{Channel: "account"},
+		Data: json.RawMessage(`[{"details":[{"ccy":"USDT","availBal":"900","frozenBal":"100","cashBal":"1000"}]}]`),
+	})
+	expUSDT = &ExchangeBalance{Available: 900e6, Locked: 100e6}
+	if bal, _ := o.Balance(60002); !reflect.DeepEqual(bal, expUSDT) {
+		t.Fatalf("wrong usdt balance after account update: %+v", bal)
+	}
+	if bal, _ := o.Balance(0); !reflect.DeepEqual(bal, expBTC) {
+		t.Fatalf("btc balance changed by account update: %+v", bal)
+	}
+	if len(notes) != 2 {
+		t.Fatalf("expected 2 balance notifications, got %d", len(notes))
+	}
+
+	// Currencies that are no longer returned by the REST API have a zero
+	// balance.
+	s.setResponse("/api/v5/account/balance", `{"code":"0","msg":"","data":[{"details":[
+		{"ccy":"USDT","availBal":"900","frozenBal":"100","cashBal":"1000"}]}]}`)
+	notes = nil
+	if _, err := o.Balances(context.Background()); err != nil {
+		t.Fatalf("Balances error: %v", err)
+	}
+	if bal, _ := o.Balance(0); bal.Available != 0 || bal.Locked != 0 {
+		t.Fatalf("btc balance not zeroed: %+v", bal)
+	}
+	if len(notes) != 1 || notes[0].AssetID != 0 {
+		t.Fatalf("expected 1 btc balance notification, got %+v", notes)
+	}
+}
+
+func TestBuildOKXOrderRequest(t *testing.T) {
+	btcUI, _ := asset.UnitInfo(0)
+	usdtUI, _ := asset.UnitInfo(60002)
+	msgRate := func(rate float64) uint64 {
+		return calc.MessageRate(rate, btcUI, usdtUI)
+	}
+	inst := &okxtypes.Instrument{
+		InstID: "BTC-USDT",
+		TickSz: "0.1",
+		LotSz:  "0.00000001",
+		MinSz:  "0.00001",
+	}
+	parseOKXInstrument(inst, &btcUI, &usdtUI)
+
+	tests := []struct {
+		name      string
+		sell      bool
+		orderType OrderType
+		rate      uint64
+		qty       uint64
+		quoteQty  uint64
+		expReq    *okxtypes.OrderRequest
+		expQty    uint64
+		wantErr   bool
+	}{
+		{
+			name:      "limit buy",
+			orderType: OrderTypeLimit,
+			rate:      msgRate(45000.04),
+			qty:       0.1e8,
+			expReq: &okxtypes.OrderRequest{
+				InstID:  "BTC-USDT",
+				TdMode:  "cash",
+				ClOrdID: "id",
+				Side:    "buy",
+				OrdType: "limit",
+				Px:      "45000.0",
+				Sz:      "0.10000000",
+			},
+			expQty: 0.1e8,
+		},
+		{
+			name:      "limit ioc sell",
+			sell:      true,
+			orderType: OrderTypeLimitIOC,
+			rate:      msgRate(45000.5),
+			qty:       0.12345678e8,
+			expReq: &okxtypes.OrderRequest{
+				InstID:  "BTC-USDT",
+				TdMode:  "cash",
+				ClOrdID: "id",
+				Side:    "sell",
+				OrdType: "ioc",
+				Px:      "45000.5",
+				Sz:      "0.12345678",
+			},
+			expQty: 0.12345678e8,
+		},
+		{
+			name:      "limit buy with quote qty",
+			orderType: OrderTypeLimit,
+			rate:      msgRate(50000),
+			quoteQty:  5000e6,
+			expReq: &okxtypes.OrderRequest{
+				InstID:  "BTC-USDT",
+				TdMode:  "cash",
+				ClOrdID: "id",
+				Side:    "buy",
+				OrdType: "limit",
+				Px:      "50000.0",
+				Sz:      "0.10000000",
+			},
+			expQty: 0.1e8,
+		},
+		{
+			name:      "market buy",
+			orderType: OrderTypeMarket,
+			quoteQty:  100e6,
+			expReq: &okxtypes.OrderRequest{
+				InstID:  "BTC-USDT",
+				TdMode:  "cash",
+				ClOrdID: "id",
+				Side:    "buy",
+				OrdType: "market",
+				Sz:      "100.000000",
+				TgtCcy:  "quote_ccy",
+			},
+			expQty: 100e6,
+		},
+		{
+			name:      "market sell",
+			sell:      true,
+			orderType: OrderTypeMarket,
+			qty:       0.5e8,
+			expReq: &okxtypes.OrderRequest{
+				InstID:  "BTC-USDT",
+				TdMode:  "cash",
+				ClOrdID: "id",
+				Side:    "sell",
+				OrdType: "market",
+				Sz:      "0.50000000",
+				TgtCcy:  "base_ccy",
+			},
+			expQty: 0.5e8,
+		},
+		{
+			name:      "market buy with base qty",
+			orderType: OrderTypeMarket,
+			qty:       0.5e8,
+			wantErr:   true,
+		},
+		{
+			name:      "qty below minimum",
+			sell:      true,
+			orderType: OrderTypeLimit,
+			rate:      msgRate(45000),
+			qty:       999,
+			wantErr:   true,
+		},
+		{
+			name:      "limit without rate",
+			orderType: OrderTypeLimit,
+			qty:       0.1e8,
+			wantErr:   true,
+		},
+		{
+			name:      "sell with quote qty",
+			sell:      true,
+			orderType: OrderTypeLimit,
+			rate:      msgRate(45000),
+			quoteQty:  100e6,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, qty, err := buildOKXOrderRequest(inst, &btcUI, &usdtUI, tt.sell, tt.orderType, tt.rate, tt.qty, tt.quoteQty, "id")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(req, tt.expReq) {
+				t.Fatalf("wrong request. expected %+v, got %+v", tt.expReq, req)
+			}
+			if qty != tt.expQty {
+				t.Fatalf("wrong qty. expected %d, got %d", tt.expQty, qty)
+			}
+		})
+	}
+}
+
+func TestOKXTrade(t *testing.T) {
+	s, srv := newTOKXServer(t)
+	defer srv.Close()
+	o := tNewOKX(t, srv.URL, nil)
+	if _, err := o.Markets(context.Background()); err != nil {
+		t.Fatalf("Markets error: %v", err)
+	}
+
+	btcUI, _ := asset.UnitInfo(0)
+	usdtUI, _ := asset.UnitInfo(60002)
+	rate := calc.MessageRate(45000, btcUI, usdtUI)
+
+	updates, _, subID := o.SubscribeTradeUpdates()
+	if _, err := o.Trade(context.Background(), 0, 60002, false, rate, 0.1e8, 0, OrderTypeLimit, subID+1); err == nil {
+		t.Fatalf("no error for unknown subscription ID")
+	}
+
+	trade, err := o.Trade(context.Background(), 0, 60002, false, rate, 0.1e8, 0, OrderTypeLimit, subID)
+	if err != nil {
+		t.Fatalf("Trade error: %v", err)
+	}
+	if trade.ID != "312269865356374016" || trade.Qty != 0.1e8 || trade.Rate != rate || trade.Sell {
+		t.Fatalf("wrong trade: %+v", trade)
+	}
+	req, _ := s.lastRequest("/api/v5/trade/order")
+	var ordReq okxtypes.OrderRequest
+	if err := json.Unmarshal(req.body, &ordReq); err != nil {
+		t.Fatalf("error unmarshaling order request: %v", err)
+	}
+	if len(ordReq.ClOrdID) != 32 || ordReq.InstID != "BTC-USDT" || ordReq.Px != "45000.0" || ordReq.Sz != "0.10000000" {
+		t.Fatalf("wrong order request %+v", ordReq)
+	}
+	clOrdID := ordReq.ClOrdID
+
+	sendOrders := func(ords ...string) {
+		t.Helper()
+		o.handleUserMessage(&okxtypes.WsMessage{
+			Arg:  &okxtypes.WsArg{Channel: "orders", InstType: "SPOT"},
+			Data: json.RawMessage("[" + strings.Join(ords, ",") + "]"),
+		})
+	}
+	checkUpdate := func(expBase, expQuote uint64, expComplete bool) {
+		t.Helper()
+		select {
+		case u := <-updates:
+			if u.ID != trade.ID {
+				t.Fatalf("wrong trade ID %s", u.ID)
+			}
+			if u.BaseFilled != expBase || u.QuoteFilled != expQuote || u.Complete != expComplete {
+				t.Fatalf("wrong update. expected base %d, quote %d, complete %t, got %+v", expBase, expQuote, expComplete, u)
+			}
+		default:
+			t.Fatalf("no update")
+		}
+	}
+	ord := func(state string, accFillSz, avgPx, fee string) string {
+		return fmt.Sprintf(`{"instId":"BTC-USDT","ordId":"312269865356374016","clOrdId":%q,"px":"45000.0","sz":"0.1",`+
+			`"ordType":"limit","side":"buy","accFillSz":%q,"avgPx":%q,"fee":%q,"feeCcy":"BTC","state":%q}`,
+			clOrdID, accFillSz, avgPx, fee, state)
+	}
+
+	sendOrders(ord("live", "0", "", "0"))
+	checkUpdate(0, 0, false)
+
+	sendOrders(ord("partially_filled", "0.04", "45000", "-0.00004"))
+	checkUpdate(0.03996e8, 1800e6, false)
+
+	// The fee is the accumulated fee of the order.
+	sendOrders(ord("filled", "0.1", "45000", "-0.0001"))
+	checkUpdate(0.0999e8, 4500e6, true)
+
+	// Trade info should be deleted after completion.
+	sendOrders(ord("filled", "0.1", "45000", "-0.0001"))
+	select {
+	case u := <-updates:
+		t.Fatalf("unexpected update after completion: %+v", u)
+	default:
+	}
+
+	s.setResponse("/api/v5/trade/order?get", okxOrderFixture)
+	status, err := o.TradeStatus(context.Background(), trade.ID, 0, 60002)
+	if err != nil {
+		t.Fatalf("TradeStatus error: %v", err)
+	}
+	expStatus := &Trade{
+		ID:          trade.ID,
+		Qty:         0.1e8,
+		Rate:        rate,
+		BaseID:      0,
+		QuoteID:     60002,
+		BaseFilled:  0.0999e8,
+		QuoteFilled: 4500e6,
+		Complete:    true,
+	}
+	if !reflect.DeepEqual(status, expStatus) {
+		t.Fatalf("wrong trade status. expected %+v, got %+v", expStatus, status)
+	}
+	if req, _ := s.lastRequest("/api/v5/trade/order"); req.query.Get("instId") != "BTC-USDT" || req.query.Get("ordId") != trade.ID {
+		t.Fatalf("wrong order status query %v", req.query)
+	}
+
+	// A rejected order is an error, and the trade info is removed.
+	s.setResponse("/api/v5/trade/order", `{"code":"1","msg":"All operations failed","data":[
+		{"ordId":"","clOrdId":"abc","sCode":"51008","sMsg":"Order failed. Insufficient USDT balance in account."}]}`)
+	_, err = o.Trade(context.Background(), 0, 60002, false, rate, 0.1e8, 0, OrderTypeLimit, subID)
+	if err == nil || !strings.Contains(err.Error(), "Insufficient USDT balance") {
+		t.Fatalf("wrong error for rejected order: %v", err)
+	}
+	if len(o.tradeInfo) != 0 {
+		t.Fatalf("trade info not removed after rejected order")
+	}
+}
+
+func TestOKXFunding(t *testing.T) {
+	s, srv := newTOKXServer(t)
+	defer srv.Close()
+	o := tNewOKX(t, srv.URL, nil)
+	ctx := context.Background()
+
+	for assetID, expAddr := range map[uint32]string{
+		60002:  "0x2dB0B7A4E2D8Ef0Ef1F21C8a1b0B7d2A3b4C5d6E",
+		966004: "0x9a1a2e7c5d4a8f2b3c6e7d8f9a0b1c2d3e4f5a6b",
+	} {
+		addr, err := o.GetDepositAddress(ctx, assetID)
+		if err != nil {
+			t.Fatalf("GetDepositAddress error: %v", err)
+		}
+		if addr != expAddr {
+			t.Fatalf("wrong %s deposit address %s", dex.BipIDSymbol(assetID), addr)
+		}
+	}
+	if _, err := o.GetDepositAddress(ctx, 60001); err == nil {
+		t.Fatalf("no error for missing deposit address")
+	}
+
+	// Completed deposits are transferred to the trading account.
+	complete, amt := o.ConfirmDeposit(ctx, &DepositData{AssetID: 60002, TxID: "0xdeposit"})
+	if !complete || amt != 100e6 {
+		t.Fatalf("wrong deposit confirmation. complete = %t, amt = %d", complete, amt)
+	}
+	req, n := s.lastRequest("/api/v5/asset/transfer")
+	var transfer okxtypes.TransferRequest
+	if err := json.Unmarshal(req.body, &transfer); err != nil {
+		t.Fatalf("error unmarshaling transfer request: %v", err)
+	}
+	expTransfer := okxtypes.TransferRequest{Ccy: "USDT", Amt: "100.000000", From: okxtypes.AccountFunding, To: okxtypes.AccountTrading}
+	if n != 1 || transfer != expTransfer {
+		t.Fatalf("wrong transfer request %+v", transfer)
+	}
+	// The deposit is for a different chain.
+	if complete, _ := o.ConfirmDeposit(ctx, &DepositData{AssetID: 966004, TxID: "0xdeposit"}); complete {
+		t.Fatalf("deposit on wrong chain confirmed")
+	}
+	if complete, _ := o.ConfirmDeposit(ctx, &DepositData{AssetID: 60002, TxID: "0xunknown"}); complete {
+		t.Fatalf("unknown deposit confirmed")
+	}
+	s.setResponse("/api/v5/asset/deposit-history", strings.Replace(okxDepositHistoryFixture, `"state":"2"`, `"state":"0"`, 1))
+	if complete, _ := o.ConfirmDeposit(ctx, &DepositData{AssetID: 60002, TxID: "0xdeposit"}); complete {
+		t.Fatalf("unconfirmed deposit confirmed")
+	}
+
+	// The amount less the fee is below the minimum withdrawal.
+	if _, _, err := o.Withdraw(ctx, 0, 0.0006e8, "bc1qwallet"); err == nil {
+		t.Fatalf("no error for withdrawal below minimum")
+	}
+	id, amt, err := o.Withdraw(ctx, 0, 0.1e8, "bc1qwallet")
+	if err != nil {
+		t.Fatalf("Withdraw error: %v", err)
+	}
+	if id != "58" || amt != 0.1e8 {
+		t.Fatalf("wrong withdrawal result: %s, %d", id, amt)
+	}
+	req, _ = s.lastRequest("/api/v5/asset/transfer")
+	if err := json.Unmarshal(req.body, &transfer); err != nil {
+		t.Fatalf("error unmarshaling transfer request: %v", err)
+	}
+	expTransfer = okxtypes.TransferRequest{Ccy: "BTC", Amt: "0.10000000", From: okxtypes.AccountTrading, To: okxtypes.AccountFunding}
+	if transfer != expTransfer {
+		t.Fatalf("wrong transfer request %+v", transfer)
+	}
+	req, _ = s.lastRequest("/api/v5/asset/withdrawal")
+	var wdReq okxtypes.WithdrawalRequest
+	if err := json.Unmarshal(req.body, &wdReq); err != nil {
+		t.Fatalf("error unmarshaling withdrawal request: %v", err)
+	}
+	expWdReq := okxtypes.WithdrawalRequest{Ccy: "BTC", Amt: "0.09980000", Dest: "4", ToAddr: "bc1qwallet", Chain: "BTC-Bitcoin"}
+	if wdReq != expWdReq {
+		t.Fatalf("wrong withdrawal request %+v", wdReq)
+	}
+
+	if _, _, err := o.ConfirmWithdrawal(ctx, id, 0); err != ErrWithdrawalPending {
+		t.Fatalf("expected ErrWithdrawalPending, got %v", err)
+	}
+	s.setResponse("/api/v5/asset/withdrawal-history", strings.Replace(
+		strings.Replace(okxWithdrawalHistoryFixture, `"txId":""`, `"txId":"abcd"`, 1), `"state":"1"`, `"state":"2"`, 1))
+	amt, txID, err := o.ConfirmWithdrawal(ctx, id, 0)
+	if err != nil {
+		t.Fatalf("ConfirmWithdrawal error: %v", err)
+	}
+	if amt != 0.0998e8 || txID != "abcd" {
+		t.Fatalf("wrong withdrawal confirmation: %d, %s", amt, txID)
+	}
+	s.setResponse("/api/v5/asset/withdrawal-history", strings.Replace(okxWithdrawalHistoryFixture, `"state":"1"`, `"state":"-1"`, 1))
+	if _, _, err := o.ConfirmWithdrawal(ctx, id, 0); err == nil || err == ErrWithdrawalPending {
+		t.Fatalf("expected error for failed withdrawal, got %v", err)
+	}
+
+	// If the withdrawal fails, the funds are returned to the trading account.
+	s.setResponse("/api/v5/asset/withdrawal", `{"code":"58207","msg":"Withdrawal address is not whitelisted","data":[]}`)
+	if _, _, err := o.Withdraw(ctx, 0, 0.1e8, "bc1qunknown"); err == nil {
+		t.Fatalf("no error for failed withdrawal")
+	}
+	req, _ = s.lastRequest("/api/v5/asset/transfer")
+	if err := json.Unmarshal(req.body, &transfer); err != nil {
+		t.Fatalf("error unmarshaling transfer request: %v", err)
+	}
+	expTransfer = okxtypes.TransferRequest{Ccy: "BTC", Amt: "0.10000000", From: okxtypes.AccountFunding, To: okxtypes.AccountTrading}
+	if transfer != expTransfer {
+		t.Fatalf("funds not returned after failed withdrawal: %+v", transfer)
+	}
+}
+
+type tOKXWsConn struct {
+	comms.WsConn
+	sent []*okxtypes.WsRequest
+}
+
+func (c *tOKXWsConn) SendRaw(b []byte) error {
+	req := new(okxtypes.WsRequest)
+	if err := json.Unmarshal(b, req); err != nil {
+		return err
+	}
+	c.sent = append(c.sent, req)
+	return nil
+}
+
+func TestOKXWSLogin(t *testing.T) {
+	o := tNewOKX(t, "", nil)
+	login := o.loginRequest()
+	arg := login.Args[0].(*okxtypes.LoginArg)
+	if login.Op != "login" || arg.APIKey != "key" || arg.Passphrase != okxTestPassphrase {
+		t.Fatalf("wrong login request %+v", arg)
+	}
+	if expSig := okxSignature(arg.Timestamp, "GET", "/users/self/verify", "", okxTestSecret); arg.Sign != expSig {
+		t.Fatalf("wrong login signature")
+	}
+
+	subs := func() []*okxtypes.WsRequest {
+		return []*okxtypes.WsRequest{{Op: "subscribe", Args: []interface{}{&okxtypes.WsArg{Channel: "orders", InstType: "SPOT"}}}}
+	}
+	var msgs []*okxtypes.WsMessage
+	wsConn := &tOKXWsConn{}
+	conn := newOKXWSConn("", o.loginRequest, subs, func(msg *okxtypes.WsMessage) { msgs = append(msgs, msg) }, func(bool) {}, o.log)
+	conn.wsConn = wsConn
+
+	// The subscriptions are sent after a successful login.
+	if err := conn.start(); err != nil {
+		t.Fatalf("start error: %v", err)
+	}
+	if len(wsConn.sent) != 1 || wsConn.sent[0].Op != "login" {
+		t.Fatalf("expected login request, got %+v", wsConn.sent)
+	}
+	conn.handleWebsocketMessage([]byte(`{"event":"login","code":"60009","msg":"Login failed."}`))
+	if len(wsConn.sent) != 1 {
+		t.Fatalf("subscribed after failed login")
+	}
+	conn.handleWebsocketMessage([]byte(`{"event":"login","code":"0","msg":""}`))
+	if len(wsConn.sent) != 2 || wsConn.sent[1].Op != "subscribe" {
+		t.Fatalf("expected subscribe request, got %+v", wsConn.sent)
+	}
+
+	// Responses and pongs are not passed to the handler.
+	conn.handleWebsocketMessage([]byte(`{"event":"subscribe","arg":{"channel":"orders","instType":"SPOT"}}`))
+	conn.handleWebsocketMessage([]byte("pong"))
+	if len(msgs) != 0 {
+		t.Fatalf("unexpected messages passed to handler: %+v", msgs)
+	}
+	conn.handleWebsocketMessage([]byte(`{"arg":{"channel":"orders","instType":"SPOT"},"data":[]}`))
+	if len(msgs) != 1 {
+		t.Fatalf("channel message not passed to handler")
+	}
+}
+
+func TestOKXBook(t *testing.T) {
+	btcUI, _ := asset.UnitInfo(0)
+	usdtUI, _ := asset.UnitInfo(60002)
+	b := newOKXBook("", "BTC-USDT", &btcUI, &usdtUI, dex.StdOutLogger("T", dex.LevelTrace))
+	wsConn := &tOKXWsConn{}
+	b.conn = newOKXWSConn("", nil, func() []*okxtypes.WsRequest { return b.subscriptions(true) }, b.handleBookMessage, b.synced.Store, b.log)
+	b.conn.wsConn = wsConn
+
+	snapshot := `{"arg":{"channel":"books","instId":"BTC-USDT"},"action":"snapshot","data":[{` +
+		`"asks":[["45285.2","0.001","0","1"],["45286.4","1.54582015","0","3"],["45286.6","1","0","2"]],` +
+		`"bids":[["45283.5","0.1","0","1"],["45283.4","1.54582015","0","2"]],` +
+		`"ts":"1700000000000","checksum":0,"prevSeqId":-1,"seqId":100}]}`
+	b.conn.handleWebsocketMessage([]byte(snapshot))
+	if !b.synced.Load() {
+		t.Fatalf("book not synced after snapshot")
+	}
+
+	midGap, err := b.midGap()
+	if err != nil {
+		t.Fatalf("midGap error: %v", err)
+	}
+	expMidGap := (calc.MessageRate(45283.5, btcUI, usdtUI) + calc.MessageRate(45285.2, btcUI, usdtUI)) / 2
+	if midGap != expMidGap {
+		t.Fatalf("wrong mid gap. expected %d, got %d", expMidGap, midGap)
+	}
+
+	// Messages for other markets are ignored.
+	b.conn.handleWebsocketMessage([]byte(strings.Replace(snapshot, "BTC-USDT", "ETH-USDT", 1)))
+
+	// Remove the best ask and change the qty of the best bid.
+	update := `{"arg":{"channel":"books","instId":"BTC-USDT"},"action":"update","data":[{` +
+		`"asks":[["45285.2","0","0","0"]],"bids":[["45283.5","0.2","0","2"]],` +
+		`"ts":"1700000000100","checksum":0,"prevSeqId":100,"seqId":101}]}`
+	b.conn.handleWebsocketMessage([]byte(update))
+	if !b.synced.Load() {
+		t.Fatalf("book not synced after update")
+	}
+
+	vwap, extrema, filled, err := b.vwap(false, 2e8)
+	if err != nil {
+		t.Fatalf("vwap error: %v", err)
+	}
+	r1, r2 := calc.MessageRate(45286.4, btcUI, usdtUI), calc.MessageRate(45286.6, btcUI, usdtUI)
+	expVWAP := (r1*154582015 + r2*45417985) / 2e8
+	if !filled || extrema != r2 || vwap != expVWAP {
+		t.Fatalf("wrong vwap. expected %d, %d, got %d, %d, filled = %t", expVWAP, r2, vwap, extrema, filled)
+	}
+	bids, _ := b.book.snap()
+	if len(bids) != 2 || bids[0].qty != 0.2e8 {
+		t.Fatalf("wrong bids after update: %+v", bids)
+	}
+
+	// A sequence gap should trigger a resubscription.
+	gapUpdate := `{"arg":{"channel":"books","instId":"BTC-USDT"},"action":"update","data":[{` +
+		`"asks":[],"bids":[["45283.5","0.3","0","3"]],"ts":"1700000000300","checksum":0,"prevSeqId":102,"seqId":103}]}`
+	b.conn.handleWebsocketMessage([]byte(gapUpdate))
+	if b.synced.Load() {
+		t.Fatalf("book still synced after sequence gap")
+	}
+	if len(wsConn.sent) != 2 || wsConn.sent[0].Op != "unsubscribe" || wsConn.sent[1].Op != "subscribe" {
+		t.Fatalf("expected unsubscribe and subscribe requests, got %+v", wsConn.sent)
+	}
+	if _, _, _, err := b.vwap(false, 1e8); err != ErrUnsyncedOrderbook {
+		t.Fatalf("expected ErrUnsyncedOrderbook, got %v", err)
+	}
+
+	// Updates are ignored until the next snapshot.
+	b.conn.handleWebsocketMessage([]byte(update))
+	if b.synced.Load() {
+		t.Fatalf("book synced by update")
+	}
+	b.conn.handleWebsocketMessage([]byte(snapshot))
+	if !b.synced.Load() {
+		t.Fatalf("book not synced after second snapshot")
+	}
+	bids, asks := b.book.snap()
+	if len(bids) != 2 || len(asks) != 3 || bids[0].qty != 0.1e8 {
+		t.Fatalf("wrong book after second snapshot: %d bids, %d asks", len(bids), len(asks))
+	}
+}
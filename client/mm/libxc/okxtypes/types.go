@@ -0,0 +1,252 @@
+package okxtypes
+
+import (
+	"encoding/json"
+)
+
+// Numeric values are sent by OKX as strings, and are often empty strings
+// when not applicable, e.g. the price of a market order, so they are kept as
+// strings here and parsed where needed.
+
+// ============================================================================
+// REST
+// ============================================================================
+
+// Response is the envelope for every OKX REST response. Code is "0" for
+// success.
+type Response struct {
+	Code string          `json:"code"`
+	Msg  string          `json:"msg"`
+	Data json.RawMessage `json:"data"`
+}
+
+// Instrument is an entry in the /api/v5/public/instruments response.
+type Instrument struct {
+	InstID   string `json:"instId"` // e.g. BTC-USDT
+	BaseCcy  string `json:"baseCcy"`
+	QuoteCcy string `json:"quoteCcy"`
+	TickSz   string `json:"tickSz"`
+	LotSz    string `json:"lotSz"`
+	MinSz    string `json:"minSz"`
+	State    string `json:"state"` // live, suspend, preopen, test
+
+	// Below fields are parsed from the above and the asset unit info.
+	LotSize       uint64
+	MinQty        uint64
+	RateStep      uint64
+	PriceDecimals int
+	SizeDecimals  int
+}
+
+// Ticker is an entry in the /api/v5/market/tickers response. For spot
+// markets, Vol24h is in units of the base currency and VolCcy24h is in units
+// of the quote currency.
+type Ticker struct {
+	InstID    string `json:"instId"`
+	Last      string `json:"last"`
+	Open24h   string `json:"open24h"`
+	High24h   string `json:"high24h"`
+	Low24h    string `json:"low24h"`
+	Vol24h    string `json:"vol24h"`
+	VolCcy24h string `json:"volCcy24h"`
+}
+
+// BalanceDetail is the balance of a single currency in the trading account.
+type BalanceDetail struct {
+	Ccy       string `json:"ccy"`
+	AvailBal  string `json:"availBal"`
+	FrozenBal string `json:"frozenBal"`
+	CashBal   string `json:"cashBal"`
+}
+
+// AccountBalance is an entry in the /api/v5/account/balance response and
+// the data of the account websocket channel.
+type AccountBalance struct {
+	Details []*BalanceDetail `json:"details"`
+}
+
+// OrderRequest is the body of a /api/v5/trade/order request.
+type OrderRequest struct {
+	InstID  string `json:"instId"`
+	TdMode  string `json:"tdMode"` // "cash" for spot
+	ClOrdID string `json:"clOrdId,omitempty"`
+	Side    string `json:"side"`    // buy or sell
+	OrdType string `json:"ordType"` // limit, market, ioc
+	Px      string `json:"px,omitempty"`
+	Sz      string `json:"sz"`
+	// TgtCcy is the unit of Sz for market orders, base_ccy or quote_ccy.
+	TgtCcy string `json:"tgtCcy,omitempty"`
+}
+
+// CancelOrderRequest is the body of a /api/v5/trade/cancel-order request.
+type CancelOrderRequest struct {
+	InstID string `json:"instId"`
+	OrdID  string `json:"ordId"`
+}
+
+// OrderResult is an entry in the response of the order placement and
+// cancellation endpoints. SCode is "0" for success.
+type OrderResult struct {
+	OrdID   string `json:"ordId"`
+	ClOrdID string `json:"clOrdId"`
+	SCode   string `json:"sCode"`
+	SMsg    string `json:"sMsg"`
+}
+
+// Order is an entry in the /api/v5/trade/order response and the data of the
+// orders websocket channel. Fee is the accumulated fee of the order, and is
+// negative when charged.
+type Order struct {
+	InstID    string `json:"instId"`
+	OrdID     string `json:"ordId"`
+	ClOrdID   string `json:"clOrdId"`
+	Px        string `json:"px"`
+	Sz        string `json:"sz"`
+	OrdType   string `json:"ordType"`
+	Side      string `json:"side"`
+	TgtCcy    string `json:"tgtCcy"`
+	AccFillSz string `json:"accFillSz"`
+	AvgPx     string `json:"avgPx"`
+	Fee       string `json:"fee"`
+	FeeCcy    string `json:"feeCcy"`
+	State     string `json:"state"` // live, partially_filled, filled, canceled, mmp_canceled
+}
+
+// Currency is an entry in the /api/v5/asset/currencies response. There is
+// one entry for every chain that the currency can be deposited or withdrawn
+// on.
+type Currency struct {
+	Ccy    string `json:"ccy"`
+	Chain  string `json:"chain"` // e.g. USDT-ERC20
+	CanDep bool   `json:"canDep"`
+	CanWd  bool   `json:"canWd"`
+	MinWd  string `json:"minWd"`
+	MinFee string `json:"minFee"`
+}
+
+// DepositAddress is an entry in the /api/v5/asset/deposit-address response.
+type DepositAddress struct {
+	Ccy   string `json:"ccy"`
+	Chain string `json:"chain"`
+	Addr  string `json:"addr"`
+}
+
+// Deposit is an entry in the /api/v5/asset/deposit-history response.
+type Deposit struct {
+	Ccy   string `json:"ccy"`
+	Chain string `json:"chain"`
+	Amt   string `json:"amt"`
+	TxID  string `json:"txId"`
+	DepID string `json:"depId"`
+	State string `json:"state"`
+}
+
+// Deposit states.
+const (
+	DepositStateWaiting    = "0"
+	DepositStateCredited   = "1"
+	DepositStateSuccessful = "2"
+)
+
+// TransferRequest is the body of a /api/v5/asset/transfer request.
+type TransferRequest struct {
+	Ccy  string `json:"ccy"`
+	Amt  string `json:"amt"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Account types for transfers.
+const (
+	AccountFunding = "6"
+	AccountTrading = "18"
+)
+
+// WithdrawalRequest is the body of a /api/v5/asset/withdrawal request. The
+// fee is charged in addition to Amt.
+type WithdrawalRequest struct {
+	Ccy    string `json:"ccy"`
+	Amt    string `json:"amt"`
+	Dest   string `json:"dest"` // "4" for on-chain withdrawals
+	ToAddr string `json:"toAddr"`
+	Chain  string `json:"chain"`
+}
+
+// WithdrawalResult is an entry in the /api/v5/asset/withdrawal response.
+type WithdrawalResult struct {
+	WdID  string `json:"wdId"`
+	Ccy   string `json:"ccy"`
+	Chain string `json:"chain"`
+	Amt   string `json:"amt"`
+}
+
+// Withdrawal is an entry in the /api/v5/asset/withdrawal-history response.
+// TxID is empty until the withdrawal has been broadcast.
+type Withdrawal struct {
+	WdID  string `json:"wdId"`
+	Ccy   string `json:"ccy"`
+	Chain string `json:"chain"`
+	Amt   string `json:"amt"`
+	Fee   string `json:"fee"`
+	TxID  string `json:"txId"`
+	State string `json:"state"`
+}
+
+// Withdrawal states that indicate the withdrawal will not be completed.
+const (
+	WithdrawalStateCanceled = "-2"
+	WithdrawalStateFailed   = "-1"
+)
+
+// ============================================================================
+// Websocket
+// ============================================================================
+
+// WsArg identifies a websocket channel subscription.
+type WsArg struct {
+	Channel  string `json:"channel"`
+	InstID   string `json:"instId,omitempty"`
+	InstType string `json:"instType,omitempty"`
+}
+
+// LoginArg is the argument of a websocket login request. Sign is generated
+// the same way as for a REST request to GET /users/self/verify, with the
+// timestamp in Unix seconds.
+type LoginArg struct {
+	APIKey     string `json:"apiKey"`
+	Passphrase string `json:"passphrase"`
+	Timestamp  string `json:"timestamp"`
+	Sign       string `json:"sign"`
+}
+
+// WsRequest is a request sent over the websocket connection. Args is a list
+// of *WsArg or *LoginArg.
+type WsRequest struct {
+	Op   string        `json:"op"`
+	Args []interface{} `json:"args"`
+}
+
+// WsMessage is used to determine the type of an incoming websocket message.
+// Responses to requests have Event set, while channel messages have Arg and
+// Data set.
+type WsMessage struct {
+	Event string `json:"event"`
+	Code  string `json:"code"`
+	Msg   string `json:"msg"`
+
+	Arg    *WsArg          `json:"arg"`
+	Action string          `json:"action"` // snapshot or update, for books
+	Data   json.RawMessage `json:"data"`
+}
+
+// BookData is the data of a books channel message. Levels are of the form
+// [price, size, deprecated, number of orders]. A size of zero removes the
+// level. Every message has a sequence ID and the sequence ID of the previous
+// message, which is -1 for snapshots.
+type BookData struct {
+	Asks      [][]json.Number `json:"asks"`
+	Bids      [][]json.Number `json:"bids"`
+	Checksum  int32           `json:"checksum"`
+	SeqID     int64           `json:"seqId"`
+	PrevSeqID int64           `json:"prevSeqId"`
+}
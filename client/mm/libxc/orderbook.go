@@ -108,6 +108,21 @@ func (ob *orderbook) update(bids []*obEntry, asks []*obEntry) {
 	}
 }
 
+// truncate removes the worst entries from both sides of the book so that
+// neither side has more than depth entries. This is required for exchanges
+// that do not send removals for levels that fall out of the subscribed depth.
+func (ob *orderbook) truncate(depth int) {
+	ob.mtx.Lock()
+	defer ob.mtx.Unlock()
+
+	for ob.bids.Len() > depth {
+		ob.bids.RemoveBack()
+	}
+	for ob.asks.Len() > depth {
+		ob.asks.RemoveBack()
+	}
+}
+
 // clear clears the orderbook.
 func (ob *orderbook) clear() {
 	ob.mtx.Lock()
@@ -150,6 +150,16 @@ func TestOrderbook(t *testing.T) {
 	checkVWAP(true, 65e8, expVWAP, 400, true)
 	expVWAP = (10e8*uint64(3000) + 35e8*uint64(4000) + 20e8*uint64(5000)) / 65e8
 	checkVWAP(false, 65e8, expVWAP, 5000, true)
+
+	// Truncating should remove the worst levels from each side.
+	ob.truncate(2)
+	snapBids, snapAsks = ob.snap()
+	if !reflect.DeepEqual(snapBids, expSnapBids[:2]) {
+		t.Fatalf("wrong truncated bids. expected %v got %v", expSnapBids[:2], snapBids)
+	}
+	if !reflect.DeepEqual(snapAsks, expSnapAsks[:2]) {
+		t.Fatalf("wrong truncated asks. expected %v got %v", expSnapAsks[:2], snapAsks)
+	}
 }
 
 // Test vwap and inv vwap with values that would overflow uint64.
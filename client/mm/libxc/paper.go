@@ -0,0 +1,954 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package libxc
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	mrand "math/rand"
+	"sync"
+	"time"
+
+	"decred.org/dcrdex/client/asset"
+	"decred.org/dcrdex/client/core"
+	"decred.org/dcrdex/dex"
+	"decred.org/dcrdex/dex/calc"
+	"decred.org/dcrdex/dex/utils"
+)
+
+const (
+	defaultPaperLevels             = 20
+	defaultPaperSpread             = 0.002
+	defaultPaperLevelSpacing       = 0.001
+	defaultPaperBookUpdateInterval = time.Second
+)
+
+// PaperMarketConfig configures a simulated market on the paper trading CEX.
+// Rates are conventional, and quantities are in atomic units.
+type PaperMarketConfig struct {
+	BaseID  uint32 `json:"baseID"`
+	QuoteID uint32 `json:"quoteID"`
+	// MidGap is the initial mid-gap rate. If Replay is set, MidGap is ignored.
+	MidGap float64 `json:"midGap"`
+	// Replay is an optional series of mid-gap rates. On each book update, the
+	// next rate in the series is used, looping back to the start after the
+	// last one. If Replay is empty, the mid-gap follows a random walk.
+	Replay []float64 `json:"replay,omitempty"`
+	// Volatility is the standard deviation of the relative change in the
+	// mid-gap on each book update of the random walk.
+	Volatility float64 `json:"volatility"`
+	// Spread is the distance between the best bid and the best ask as a
+	// fraction of the mid-gap.
+	Spread float64 `json:"spread"`
+	// Levels is the number of price levels on each side of the book.
+	Levels int `json:"levels"`
+	// LevelSpacing is the distance between adjacent levels as a fraction of
+	// the mid-gap.
+	LevelSpacing float64 `json:"levelSpacing"`
+	// LevelQty is the quantity of the base asset at each level.
+	LevelQty uint64 `json:"levelQty"`
+	// LotSize is the base asset quantity step. Defaults to 1.
+	LotSize uint64 `json:"lotSize"`
+	// RateStep is the message-rate step. Defaults to 1.
+	RateStep uint64 `json:"rateStep"`
+}
+
+// PaperTradingConfig is the configuration of the paper trading CEX. When set
+// in a CEXConfig, NewCEX returns a simulated exchange instead of connecting to
+// the named CEX. The simulated exchange fills trades against synthesized
+// order books and keeps an internal ledger of balances, so that bots can be
+// dry-run without any real funds on a CEX.
+type PaperTradingConfig struct {
+	Markets []*PaperMarketConfig `json:"markets"`
+	// Balances are the starting balances of the simulated account.
+	Balances map[uint32]uint64 `json:"balances"`
+	// TradeFee is the fee charged on each fill as a fraction of the amount
+	// received. The fee is taken from the asset received.
+	TradeFee float64 `json:"tradeFee"`
+	// WithdrawFees are the fees deducted from each withdrawal.
+	WithdrawFees map[uint32]uint64 `json:"withdrawFees"`
+	// FillLatencyMS is the delay between placing an order and it being
+	// matched against the book.
+	FillLatencyMS uint64 `json:"fillLatencyMS"`
+	// DepositLatencyMS is the delay between a deposit first being checked
+	// with ConfirmDeposit and it being credited.
+	DepositLatencyMS uint64 `json:"depositLatencyMS"`
+	// WithdrawLatencyMS is the delay between a withdrawal being requested and
+	// it being sent.
+	WithdrawLatencyMS uint64 `json:"withdrawLatencyMS"`
+	// BookUpdateIntervalMS is how often the mid-gap is moved and the books
+	// are regenerated. Defaults to 1 second.
+	BookUpdateIntervalMS uint64 `json:"bookUpdateIntervalMS"`
+}
+
+// PaperWallet is used by the paper trading CEX to generate deposit addresses
+// that belong to the wallets of the DEX client, so that funds deposited to the
+// simulated exchange never leave the client's wallets. If no PaperWallet is
+// provided, placeholder addresses are used. Withdrawals are only simulated and
+// never move funds.
+type PaperWallet interface {
+	NewDepositAddress(assetID uint32) (string, error)
+}
+
+type paperBook struct {
+	cfg            *PaperMarketConfig
+	bui, qui       dex.UnitInfo
+	book           *orderbook
+	midGap         uint64
+	replayIdx      int
+	numSubscribers int
+}
+
+// regenerate builds the book around the current mid-gap.
+func (b *paperBook) regenerate() {
+	bids := make([]*obEntry, 0, b.cfg.Levels)
+	asks := make([]*obEntry, 0, b.cfg.Levels)
+	mid := float64(b.midGap)
+	for i := 0; i < b.cfg.Levels; i++ {
+		offset := b.cfg.Spread/2 + float64(i)*b.cfg.LevelSpacing
+		if bidRate := mid * (1 - offset); bidRate >= 1 {
+			bids = append(bids, &obEntry{rate: steppedRate(uint64(bidRate), b.cfg.RateStep), qty: b.cfg.LevelQty})
+		}
+		asks = append(asks, &obEntry{rate: steppedRate(uint64(math.Round(mid*(1+offset))), b.cfg.RateStep), qty: b.cfg.LevelQty})
+	}
+	b.book.clear()
+	b.book.update(bids, asks)
+}
+
+// step advances the mid-gap to the next replay rate or by a step of the random
+// walk.
+func (b *paperBook) step() {
+	if len(b.cfg.Replay) > 0 {
+		b.replayIdx = (b.replayIdx + 1) % len(b.cfg.Replay)
+		b.midGap = messageRate(b.cfg.Replay[b.replayIdx], &b.bui, &b.qui)
+		return
+	}
+	if b.cfg.Volatility == 0 {
+		return
+	}
+	newMid := float64(b.midGap) * (1 + b.cfg.Volatility*mrand.NormFloat64())
+	if newMid >= 1 {
+		b.midGap = uint64(math.Round(newMid))
+	}
+}
+
+type paperTrade struct {
+	trade     *Trade
+	updaterID int
+	limit     bool
+	// locked is the remaining amount of the spent asset that is reserved for
+	// the trade.
+	locked uint64
+	// baseFilledGross and quoteFilledGross are the filled quantities before
+	// fees.
+	baseFilledGross  uint64
+	quoteFilledGross uint64
+}
+
+// spentAssetID is the asset that is debited when the trade fills.
+func (t *paperTrade) spentAssetID() uint32 {
+	if t.trade.Sell {
+		return t.trade.BaseID
+	}
+	return t.trade.QuoteID
+}
+
+type paperDeposit struct {
+	firstSeen time.Time
+	amt       uint64
+	credited  bool
+}
+
+type paperWithdrawal struct {
+	assetID uint32
+	amt     uint64
+	address string
+	stamp   time.Time
+	txID    string
+}
+
+// paperCEX is a CEX implementation that simulates an exchange. It does not
+// connect to any server.
+type paperCEX struct {
+	log    dex.Logger
+	cfg    *PaperTradingConfig
+	wallet PaperWallet
+	notify func(interface{})
+
+	fillLatency     time.Duration
+	depositLatency  time.Duration
+	withdrawLatency time.Duration
+	updateInterval  time.Duration
+
+	mtx         sync.RWMutex
+	ctx         context.Context
+	wg          *sync.WaitGroup
+	books       map[string]*paperBook
+	balances    map[uint32]*ExchangeBalance
+	trades      map[string]*paperTrade
+	deposits    map[string]*paperDeposit
+	withdrawals map[string]*paperWithdrawal
+
+	tradeUpdaterMtx    sync.RWMutex
+	tradeUpdaters      map[int]chan *Trade
+	tradeUpdateCounter int
+}
+
+var _ CEX = (*paperCEX)(nil)
+
+func newPaperCEX(cfg *CEXConfig) (*paperCEX, error) {
+	pCfg := cfg.PaperTrading
+	books := make(map[string]*paperBook, len(pCfg.Markets))
+	balances := make(map[uint32]*ExchangeBalance)
+	for _, mktCfg := range pCfg.Markets {
+		mkt := *mktCfg
+		mktID, err := dex.MarketName(mkt.BaseID, mkt.QuoteID)
+		if err != nil {
+			return nil, fmt.Errorf("error getting market name: %w", err)
+		}
+		bui, err := asset.UnitInfo(mkt.BaseID)
+		if err != nil {
+			return nil, fmt.Errorf("error getting unit info for %s base asset: %w", mktID, err)
+		}
+		qui, err := asset.UnitInfo(mkt.QuoteID)
+		if err != nil {
+			return nil, fmt.Errorf("error getting unit info for %s quote asset: %w", mktID, err)
+		}
+		midGap := mkt.MidGap
+		if len(mkt.Replay) > 0 {
+			midGap = mkt.Replay[0]
+		}
+		if midGap <= 0 {
+			return nil, fmt.Errorf("no mid-gap rate specified for %s", mktID)
+		}
+		if mkt.LevelQty == 0 {
+			return nil, fmt.Errorf("no level quantity specified for %s", mktID)
+		}
+		if mkt.Levels <= 0 {
+			mkt.Levels = defaultPaperLevels
+		}
+		if mkt.Spread <= 0 {
+			mkt.Spread = defaultPaperSpread
+		}
+		if mkt.LevelSpacing <= 0 {
+			mkt.LevelSpacing = defaultPaperLevelSpacing
+		}
+		if mkt.LotSize == 0 {
+			mkt.LotSize = 1
+		}
+		if mkt.RateStep == 0 {
+			mkt.RateStep = 1
+		}
+		b := &paperBook{
+			cfg:    &mkt,
+			bui:    bui,
+			qui:    qui,
+			book:   newOrderBook(),
+			midGap: messageRate(midGap, &bui, &qui),
+		}
+		b.regenerate()
+		books[mktID] = b
+		balances[mkt.BaseID] = &ExchangeBalance{}
+		balances[mkt.QuoteID] = &ExchangeBalance{}
+	}
+	for assetID, bal := range pCfg.Balances {
+		balances[assetID] = &ExchangeBalance{Available: bal}
+	}
+
+	updateInterval := defaultPaperBookUpdateInterval
+	if pCfg.BookUpdateIntervalMS > 0 {
+		updateInterval = time.Duration(pCfg.BookUpdateIntervalMS) * time.Millisecond
+	}
+
+	return &paperCEX{
+		log:             cfg.Logger,
+		cfg:             pCfg,
+		wallet:          cfg.PaperWallet,
+		notify:          cfg.Notify,
+		fillLatency:     time.Duration(pCfg.FillLatencyMS) * time.Millisecond,
+		depositLatency:  time.Duration(pCfg.DepositLatencyMS) * time.Millisecond,
+		withdrawLatency: time.Duration(pCfg.WithdrawLatencyMS) * time.Millisecond,
+		updateInterval:  updateInterval,
+		books:           books,
+		balances:        balances,
+		trades:          make(map[string]*paperTrade),
+		deposits:        make(map[string]*paperDeposit),
+		withdrawals:     make(map[string]*paperWithdrawal),
+		tradeUpdaters:   make(map[int]chan *Trade),
+	}, nil
+}
+
+func randomPaperID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// Connect starts the goroutine that updates the simulated books.
+func (p *paperCEX) Connect(ctx context.Context) (*sync.WaitGroup, error) {
+	var wg sync.WaitGroup
+	p.mtx.Lock()
+	p.ctx = ctx
+	p.wg = &wg
+	p.mtx.Unlock()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(p.updateInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.updateBooks()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return &wg, nil
+}
+
+// updateBooks moves the mid-gap of every market, regenerates the books, and
+// fills any resting orders that are crossed by the new books.
+func (p *paperCEX) updateBooks() {
+	p.mtx.Lock()
+	for _, b := range p.books {
+		b.step()
+		b.regenerate()
+	}
+	var updates []*paperTrade
+	for _, t := range p.trades {
+		if t.trade.Complete || !t.limit {
+			continue
+		}
+		if p.matchRestingLocked(t) {
+			updates = append(updates, t)
+		}
+	}
+	p.mtx.Unlock()
+	p.sendTradeUpdates(updates)
+}
+
+func (p *paperCEX) book(baseID, quoteID uint32) (*paperBook, error) {
+	mktID, err := dex.MarketName(baseID, quoteID)
+	if err != nil {
+		return nil, err
+	}
+	b, found := p.books[mktID]
+	if !found {
+		return nil, fmt.Errorf("market %s not supported by paper trading config", mktID)
+	}
+	return b, nil
+}
+
+func (p *paperCEX) subscribedBook(baseID, quoteID uint32) (*paperBook, error) {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+	b, err := p.book(baseID, quoteID)
+	if err != nil {
+		return nil, err
+	}
+	if b.numSubscribers == 0 {
+		return nil, fmt.Errorf("not subscribed to market %d-%d", baseID, quoteID)
+	}
+	return b, nil
+}
+
+// Balance returns the balance of an asset at the CEX.
+func (p *paperCEX) Balance(assetID uint32) (*ExchangeBalance, error) {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+	bal, found := p.balances[assetID]
+	if !found {
+		return nil, fmt.Errorf("no %s balance found", dex.BipIDSymbol(assetID))
+	}
+	return &ExchangeBalance{Available: bal.Available, Locked: bal.Locked}, nil
+}
+
+// Balances returns the balances of known assets on the CEX.
+func (p *paperCEX) Balances(ctx context.Context) (map[uint32]*ExchangeBalance, error) {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+	balances := make(map[uint32]*ExchangeBalance, len(p.balances))
+	for assetID, bal := range p.balances {
+		balances[assetID] = &ExchangeBalance{Available: bal.Available, Locked: bal.Locked}
+	}
+	return balances, nil
+}
+
+func (p *paperCEX) balanceLocked(assetID uint32) *ExchangeBalance {
+	bal, found := p.balances[assetID]
+	if !found {
+		bal = &ExchangeBalance{}
+		p.balances[assetID] = bal
+	}
+	return bal
+}
+
+// sendBalanceUpdates notifies the caller of the current balances of the
+// assets.
+func (p *paperCEX) sendBalanceUpdates(assetIDs ...uint32) {
+	for _, assetID := range assetIDs {
+		bal, err := p.Balance(assetID)
+		if err != nil {
+			continue
+		}
+		p.notify(&BalanceUpdate{
+			AssetID: assetID,
+			Balance: bal,
+		})
+	}
+}
+
+// Markets returns the list of simulated markets.
+func (p *paperCEX) Markets(ctx context.Context) (map[string]*Market, error) {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+	mkts := make(map[string]*Market, len(p.books))
+	for mktID, b := range p.books {
+		rate := calc.ConventionalRateAlt(b.midGap, b.bui.Conventional.ConversionFactor, b.qui.Conventional.ConversionFactor)
+		mkts[mktID] = &Market{
+			BaseID:  b.cfg.BaseID,
+			QuoteID: b.cfg.QuoteID,
+			Day: &MarketDay{
+				AvgPrice:  rate,
+				LastPrice: rate,
+				OpenPrice: rate,
+				HighPrice: rate,
+				LowPrice:  rate,
+			},
+		}
+	}
+	return mkts, nil
+}
+
+// SubscribeMarket subscribes to order book updates on a market.
+func (p *paperCEX) SubscribeMarket(ctx context.Context, baseID, quoteID uint32) error {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	b, err := p.book(baseID, quoteID)
+	if err != nil {
+		return err
+	}
+	b.numSubscribers++
+	return nil
+}
+
+// UnsubscribeMarket unsubscribes from order book updates on a market.
+func (p *paperCEX) UnsubscribeMarket(baseID, quoteID uint32) error {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	b, err := p.book(baseID, quoteID)
+	if err != nil {
+		return err
+	}
+	if b.numSubscribers > 0 {
+		b.numSubscribers--
+	}
+	return nil
+}
+
+// SubscribeTradeUpdates returns a channel that the caller can use to
+// listen for updates to a trade's status.
+func (p *paperCEX) SubscribeTradeUpdates() (<-chan *Trade, func(), int) {
+	p.tradeUpdaterMtx.Lock()
+	defer p.tradeUpdaterMtx.Unlock()
+
+	updaterID := p.tradeUpdateCounter
+	p.tradeUpdateCounter++
+	updater := make(chan *Trade, 256)
+	p.tradeUpdaters[updaterID] = updater
+
+	unsubscribe := func() {
+		p.tradeUpdaterMtx.Lock()
+		delete(p.tradeUpdaters, updaterID)
+		p.tradeUpdaterMtx.Unlock()
+	}
+
+	return updater, unsubscribe, updaterID
+}
+
+// validatePaperTrade checks the trade parameters and returns the base quantity
+// for limit orders, or the quantity in the units of the order for market
+// orders.
+func validatePaperTrade(b *paperBook, sell bool, rate, qty, quoteQty uint64, orderType OrderType) (uint64, error) {
+	if qty > 0 && quoteQty > 0 {
+		return 0, fmt.Errorf("cannot specify both quantity and quote quantity")
+	}
+	if sell && quoteQty > 0 {
+		return 0, fmt.Errorf("quote quantity cannot be used for sell orders")
+	}
+	if !sell && orderType == OrderTypeMarket && qty > 0 {
+		return 0, fmt.Errorf("quoteQty MUST be used for market buys")
+	}
+	if orderType == OrderTypeMarket {
+		if quoteQty > 0 {
+			return quoteQty, nil
+		}
+	} else {
+		if rate == 0 {
+			return 0, fmt.Errorf("rate must be specified for limit orders")
+		}
+		if quoteQty > 0 {
+			qty = calc.QuoteToBase(rate, quoteQty)
+		}
+	}
+	if qty < b.cfg.LotSize {
+		return 0, fmt.Errorf("quantity %s is less than the lot size %s",
+			b.bui.FormatConventional(qty), b.bui.FormatConventional(b.cfg.LotSize))
+	}
+	return steppedQty(qty, b.cfg.LotSize), nil
+}
+
+// ValidateTrade validates a trade before it is executed.
+func (p *paperCEX) ValidateTrade(baseID, quoteID uint32, sell bool, rate, qty, quoteQty uint64, orderType OrderType) error {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+	b, err := p.book(baseID, quoteID)
+	if err != nil {
+		return err
+	}
+	_, err = validatePaperTrade(b, sell, rate, qty, quoteQty, orderType)
+	return err
+}
+
+// Trade places a simulated trade. The trade is matched against the book after
+// the configured fill latency. Limit orders that are not completely filled
+// remain on the book until they are crossed by the simulated book or
+// canceled.
+func (p *paperCEX) Trade(ctx context.Context, baseID, quoteID uint32, sell bool, rate, qty, quoteQty uint64, orderType OrderType, subscriptionID int) (*Trade, error) {
+	p.tradeUpdaterMtx.RLock()
+	_, found := p.tradeUpdaters[subscriptionID]
+	p.tradeUpdaterMtx.RUnlock()
+	if !found {
+		return nil, fmt.Errorf("no trade updater found for subscription ID %d", subscriptionID)
+	}
+
+	p.mtx.Lock()
+	if p.ctx == nil {
+		p.mtx.Unlock()
+		return nil, errors.New("not connected")
+	}
+	b, err := p.book(baseID, quoteID)
+	if err != nil {
+		p.mtx.Unlock()
+		return nil, err
+	}
+	tradeQty, err := validatePaperTrade(b, sell, rate, qty, quoteQty, orderType)
+	if err != nil {
+		p.mtx.Unlock()
+		return nil, err
+	}
+
+	market := orderType == OrderTypeMarket
+	var lockAmt uint64
+	switch {
+	case sell:
+		lockAmt = tradeQty
+	case market:
+		lockAmt = tradeQty
+	default:
+		lockAmt = calc.BaseToQuote(rate, tradeQty)
+	}
+	t := &paperTrade{
+		trade: &Trade{
+			ID:      randomPaperID(),
+			Sell:    sell,
+			Qty:     tradeQty,
+			Market:  market,
+			Rate:    rate,
+			BaseID:  baseID,
+			QuoteID: quoteID,
+		},
+		updaterID: subscriptionID,
+		limit:     orderType == OrderTypeLimit,
+		locked:    lockAmt,
+	}
+	bal := p.balanceLocked(t.spentAssetID())
+	if bal.Available < lockAmt {
+		p.mtx.Unlock()
+		return nil, fmt.Errorf("insufficient %s balance. required: %d, available: %d",
+			dex.BipIDSymbol(t.spentAssetID()), lockAmt, bal.Available)
+	}
+	bal.Available -= lockAmt
+	bal.Locked += lockAmt
+	p.trades[t.trade.ID] = t
+	tradeCopy := *t.trade
+	ctx, wg := p.ctx, p.wg
+	p.mtx.Unlock()
+
+	p.sendBalanceUpdates(t.spentAssetID())
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		timer := time.NewTimer(p.fillLatency)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			p.matchNewTrade(t)
+		case <-ctx.Done():
+		}
+	}()
+
+	return &tradeCopy, nil
+}
+
+// matchNewTrade matches a newly placed trade against the book. Market and
+// IOC orders are completed, and limit orders that are not filled remain on
+// the book.
+func (p *paperCEX) matchNewTrade(t *paperTrade) {
+	p.mtx.Lock()
+	if t.trade.Complete {
+		p.mtx.Unlock()
+		return
+	}
+	b, err := p.book(t.trade.BaseID, t.trade.QuoteID)
+	if err != nil {
+		p.mtx.Unlock()
+		p.log.Errorf("Error finding book for trade %s: %v", t.trade.ID, err)
+		return
+	}
+	bids, asks := b.book.snap()
+	levels := asks
+	if t.trade.Sell {
+		levels = bids
+	}
+	var limitRate uint64
+	if !t.trade.Market {
+		limitRate = t.trade.Rate
+	}
+	remaining := t.trade.Qty - t.baseFilledGross
+	quoteDenominated := t.trade.Market && !t.trade.Sell
+	if quoteDenominated {
+		remaining = t.trade.Qty - t.quoteFilledGross
+	}
+	baseFilled, quoteFilled := walkPaperBook(levels, t.trade.Sell, limitRate, remaining, quoteDenominated)
+	p.fillLocked(t, baseFilled, quoteFilled)
+	if !t.limit {
+		p.completeLocked(t)
+	}
+	p.mtx.Unlock()
+	p.sendTradeUpdates([]*paperTrade{t})
+}
+
+// walkPaperBook fills a quantity against the levels of one side of the book,
+// stopping at the limit rate if non-zero. If quoteDenominated is true, qty is
+// in units of the quote asset.
+func walkPaperBook(levels []*obEntry, sell bool, limitRate, qty uint64, quoteDenominated bool) (baseFilled, quoteFilled uint64) {
+	remaining := qty
+	for _, lvl := range levels {
+		if remaining == 0 {
+			break
+		}
+		if limitRate > 0 && ((sell && lvl.rate < limitRate) || (!sell && lvl.rate > limitRate)) {
+			break
+		}
+		if quoteDenominated {
+			lvlQuote := calc.BaseToQuote(lvl.rate, lvl.qty)
+			if lvlQuote >= remaining {
+				baseFilled += calc.QuoteToBase(lvl.rate, remaining)
+				quoteFilled += remaining
+				remaining = 0
+				break
+			}
+			baseFilled += lvl.qty
+			quoteFilled += lvlQuote
+			remaining -= lvlQuote
+			continue
+		}
+		fillQty := min(lvl.qty, remaining)
+		baseFilled += fillQty
+		quoteFilled += calc.BaseToQuote(lvl.rate, fillQty)
+		remaining -= fillQty
+	}
+	return
+}
+
+// matchRestingLocked fills the remainder of a resting limit order at its
+// limit rate if the book has moved through it. The mtx MUST be held.
+func (p *paperCEX) matchRestingLocked(t *paperTrade) bool {
+	b, err := p.book(t.trade.BaseID, t.trade.QuoteID)
+	if err != nil {
+		return false
+	}
+	bids, asks := b.book.snap()
+	if t.trade.Sell {
+		if len(bids) == 0 || bids[0].rate < t.trade.Rate {
+			return false
+		}
+	} else if len(asks) == 0 || asks[0].rate > t.trade.Rate {
+		return false
+	}
+	remaining := t.trade.Qty - t.baseFilledGross
+	p.fillLocked(t, remaining, calc.BaseToQuote(t.trade.Rate, remaining))
+	return true
+}
+
+// fillLocked applies a fill to the trade and the balances. The fee is taken
+// from the asset received. The trade is completed if it is fully filled. The
+// mtx MUST be held.
+func (p *paperCEX) fillLocked(t *paperTrade, baseFilled, quoteFilled uint64) {
+	if baseFilled == 0 && quoteFilled == 0 {
+		return
+	}
+	t.baseFilledGross += baseFilled
+	t.quoteFilledGross += quoteFilled
+
+	spent, received := baseFilled, quoteFilled
+	receivedAssetID := t.trade.QuoteID
+	if !t.trade.Sell {
+		spent, received = quoteFilled, baseFilled
+		receivedAssetID = t.trade.BaseID
+	}
+	fee := uint64(math.Round(float64(received) * p.cfg.TradeFee))
+
+	// A buy filled at a better rate than the limit spends less than was
+	// locked for that quantity.
+	debit := min(spent, t.locked)
+	t.locked -= debit
+	spentBal := p.balanceLocked(t.spentAssetID())
+	spentBal.Locked = utils.SafeSub(spentBal.Locked, debit)
+	receivedBal := p.balanceLocked(receivedAssetID)
+	receivedBal.Available += received - fee
+
+	baseFees, quoteFees := fee, uint64(0)
+	if t.trade.Sell {
+		baseFees, quoteFees = 0, fee
+	}
+	t.trade.BaseFilled = utils.SafeSub(t.trade.BaseFilled+baseFilled, baseFees)
+	t.trade.QuoteFilled = utils.SafeSub(t.trade.QuoteFilled+quoteFilled, quoteFees)
+
+	if t.trade.Market && !t.trade.Sell {
+		if t.quoteFilledGross >= t.trade.Qty {
+			p.completeLocked(t)
+		}
+	} else if t.baseFilledGross >= t.trade.Qty {
+		p.completeLocked(t)
+	}
+}
+
+// completeLocked marks the trade complete and unlocks any remaining funds.
+// The mtx MUST be held.
+func (p *paperCEX) completeLocked(t *paperTrade) {
+	if t.trade.Complete {
+		return
+	}
+	t.trade.Complete = true
+	bal := p.balanceLocked(t.spentAssetID())
+	bal.Locked = utils.SafeSub(bal.Locked, t.locked)
+	bal.Available += t.locked
+	t.locked = 0
+}
+
+// sendTradeUpdates sends the current state of the trades to their updaters
+// and notifies the caller of the balance changes.
+func (p *paperCEX) sendTradeUpdates(trades []*paperTrade) {
+	if len(trades) == 0 {
+		return
+	}
+	assetIDs := make(map[uint32]struct{})
+	p.tradeUpdaterMtx.RLock()
+	for _, t := range trades {
+		p.mtx.RLock()
+		update := *t.trade
+		p.mtx.RUnlock()
+		assetIDs[update.BaseID] = struct{}{}
+		assetIDs[update.QuoteID] = struct{}{}
+		updater, found := p.tradeUpdaters[t.updaterID]
+		if !found {
+			p.log.Errorf("No updater found for trade %s", update.ID)
+			continue
+		}
+		select {
+		case updater <- &update:
+		default:
+			p.log.Errorf("Trade update channel full for trade %s", update.ID)
+		}
+	}
+	p.tradeUpdaterMtx.RUnlock()
+	p.sendBalanceUpdates(utils.MapKeys(assetIDs)...)
+}
+
+// CancelTrade cancels a resting trade.
+func (p *paperCEX) CancelTrade(ctx context.Context, baseID, quoteID uint32, tradeID string) error {
+	p.mtx.Lock()
+	t, found := p.trades[tradeID]
+	if !found {
+		p.mtx.Unlock()
+		return fmt.Errorf("trade %s not found", tradeID)
+	}
+	if t.trade.Complete {
+		p.mtx.Unlock()
+		return fmt.Errorf("trade %s is already complete", tradeID)
+	}
+	p.completeLocked(t)
+	p.mtx.Unlock()
+	p.sendTradeUpdates([]*paperTrade{t})
+	return nil
+}
+
+// TradeStatus returns the current status of a trade.
+func (p *paperCEX) TradeStatus(ctx context.Context, id string, baseID, quoteID uint32) (*Trade, error) {
+	p.mtx.RLock()
+	defer p.mtx.RUnlock()
+	t, found := p.trades[id]
+	if !found {
+		return nil, fmt.Errorf("trade %s not found", id)
+	}
+	tradeCopy := *t.trade
+	return &tradeCopy, nil
+}
+
+// VWAP returns the volume weighted average price for a certain quantity
+// of the base asset on a market.
+func (p *paperCEX) VWAP(baseID, quoteID uint32, sell bool, qty uint64) (vwap, extrema uint64, filled bool, err error) {
+	b, err := p.subscribedBook(baseID, quoteID)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	vwap, extrema, filled = b.book.vwap(!sell, qty)
+	return
+}
+
+// InvVWAP returns the inverse volume weighted average price for a certain
+// quantity of the quote asset on a market.
+func (p *paperCEX) InvVWAP(baseID, quoteID uint32, sell bool, qty uint64) (vwap, extrema uint64, filled bool, err error) {
+	b, err := p.subscribedBook(baseID, quoteID)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	vwap, extrema, filled = b.book.invVWAP(!sell, qty)
+	return
+}
+
+// MidGap returns the mid-gap price for an order book.
+func (p *paperCEX) MidGap(baseID, quoteID uint32) uint64 {
+	b, err := p.subscribedBook(baseID, quoteID)
+	if err != nil {
+		p.log.Errorf("Error getting order book for (%d, %d): %v", baseID, quoteID, err)
+		return 0
+	}
+	return b.book.midGap()
+}
+
+// Book generates the CEX's current view of a market's orderbook.
+func (p *paperCEX) Book(baseID, quoteID uint32) (buys, sells []*core.MiniOrder, _ error) {
+	b, err := p.subscribedBook(baseID, quoteID)
+	if err != nil {
+		return nil, nil, err
+	}
+	bids, asks := b.book.snap()
+	baseFactor := b.bui.Conventional.ConversionFactor
+	quoteFactor := b.qui.Conventional.ConversionFactor
+	buys = convertSide(bids, false, baseFactor, quoteFactor)
+	sells = convertSide(asks, true, baseFactor, quoteFactor)
+	return
+}
+
+// GetDepositAddress returns a deposit address for an asset. If a PaperWallet
+// was provided, the address is generated by the wallet.
+func (p *paperCEX) GetDepositAddress(ctx context.Context, assetID uint32) (string, error) {
+	if p.wallet != nil {
+		return p.wallet.NewDepositAddress(assetID)
+	}
+	return fmt.Sprintf("paper-%s-%s", dex.BipIDSymbol(assetID), randomPaperID()[:8]), nil
+}
+
+// ConfirmDeposit credits the deposit once the deposit latency has passed
+// since the deposit was first seen.
+func (p *paperCEX) ConfirmDeposit(ctx context.Context, deposit *DepositData) (bool, uint64) {
+	ui, err := asset.UnitInfo(deposit.AssetID)
+	if err != nil {
+		p.log.Errorf("Error getting unit info for %d: %v", deposit.AssetID, err)
+		return false, 0
+	}
+
+	p.mtx.Lock()
+	d, found := p.deposits[deposit.TxID]
+	if !found {
+		d = &paperDeposit{
+			firstSeen: time.Now(),
+			amt:       toAtomic(deposit.AmountConventional, &ui),
+		}
+		p.deposits[deposit.TxID] = d
+	}
+	if time.Since(d.firstSeen) < p.depositLatency {
+		p.mtx.Unlock()
+		return false, 0
+	}
+	var credited bool
+	if !d.credited {
+		p.balanceLocked(deposit.AssetID).Available += d.amt
+		d.credited = true
+		credited = true
+	}
+	amt := d.amt
+	p.mtx.Unlock()
+
+	if credited {
+		p.sendBalanceUpdates(deposit.AssetID)
+	}
+	return true, amt
+}
+
+// Withdraw debits the balance and records a withdrawal that will be sent
+// after the withdrawal latency.
+func (p *paperCEX) Withdraw(ctx context.Context, assetID uint32, amt uint64, address string) (string, uint64, error) {
+	fee := p.cfg.WithdrawFees[assetID]
+	if amt <= fee {
+		return "", 0, fmt.Errorf("withdrawal amount %d does not cover the fee %d", amt, fee)
+	}
+	p.mtx.Lock()
+	bal := p.balanceLocked(assetID)
+	if bal.Available < amt {
+		p.mtx.Unlock()
+		return "", 0, fmt.Errorf("insufficient %s balance. required: %d, available: %d", dex.BipIDSymbol(assetID), amt, bal.Available)
+	}
+	bal.Available -= amt
+	id := randomPaperID()
+	p.withdrawals[id] = &paperWithdrawal{
+		assetID: assetID,
+		amt:     amt,
+		address: address,
+		stamp:   time.Now(),
+	}
+	p.mtx.Unlock()
+
+	p.sendBalanceUpdates(assetID)
+	return id, amt, nil
+}
+
+// ConfirmWithdrawal completes the withdrawal once the withdrawal latency has
+// passed, returning the amount less the withdrawal fee. No transaction is
+// sent, and the returned transaction ID is a placeholder. See Simulated.
+func (p *paperCEX) ConfirmWithdrawal(ctx context.Context, withdrawalID string, assetID uint32) (uint64, string, error) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	w, found := p.withdrawals[withdrawalID]
+	if !found {
+		return 0, "", fmt.Errorf("withdrawal %s not found", withdrawalID)
+	}
+	sendAmt := w.amt - p.cfg.WithdrawFees[w.assetID]
+	if w.txID != "" {
+		return sendAmt, w.txID, nil
+	}
+	if time.Since(w.stamp) < p.withdrawLatency {
+		return 0, "", ErrWithdrawalPending
+	}
+	w.txID = randomPaperID()
+	return sendAmt, w.txID, nil
+}
+
+// Simulated is part of the Simulator interface. Deposits and withdrawals are
+// only simulated by the paper trading CEX.
+func (p *paperCEX) Simulated() bool {
+	return true
+}
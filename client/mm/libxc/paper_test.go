@@ -0,0 +1,293 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package libxc
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"decred.org/dcrdex/client/asset"
+	"decred.org/dcrdex/dex"
+	"decred.org/dcrdex/dex/calc"
+)
+
+type tPaperWallet struct {
+	addr string
+}
+
+func (w *tPaperWallet) NewDepositAddress(assetID uint32) (string, error) {
+	return w.addr, nil
+}
+
+func tNewPaperCEX(t *testing.T, cfg *PaperTradingConfig, wallet PaperWallet) (*paperCEX, context.CancelFunc) {
+	t.Helper()
+	c, err := NewCEX(Binance, &CEXConfig{
+		Net:          dex.Simnet,
+		Logger:       dex.StdOutLogger("T", dex.LevelTrace),
+		Notify:       func(interface{}) {},
+		PaperTrading: cfg,
+		PaperWallet:  wallet,
+	})
+	if err != nil {
+		t.Fatalf("error creating paper CEX: %v", err)
+	}
+	p := c.(*paperCEX)
+	ctx, cancel := context.WithCancel(context.Background())
+	if _, err := p.Connect(ctx); err != nil {
+		t.Fatalf("Connect error: %v", err)
+	}
+	if err := p.SubscribeMarket(ctx, 42, 0); err != nil {
+		t.Fatalf("SubscribeMarket error: %v", err)
+	}
+	return p, cancel
+}
+
+func tPaperTradingConfig() *PaperTradingConfig {
+	return &PaperTradingConfig{
+		Markets: []*PaperMarketConfig{{
+			BaseID:       42,
+			QuoteID:      0,
+			MidGap:       0.001,
+			Spread:       0.02,
+			LevelSpacing: 0.01,
+			Levels:       3,
+			LevelQty:     10e8,
+			LotSize:      1e6,
+			RateStep:     100,
+		}},
+		Balances: map[uint32]uint64{
+			42: 100e8,
+			0:  1e8,
+		},
+		TradeFee:     0.001,
+		WithdrawFees: map[uint32]uint64{42: 1e5},
+		// Books are only updated manually in tests.
+		BookUpdateIntervalMS: uint64(time.Hour / time.Millisecond),
+	}
+}
+
+func waitForPaperUpdate(t *testing.T, updates <-chan *Trade) *Trade {
+	t.Helper()
+	select {
+	case u := <-updates:
+		return u
+	case <-time.After(time.Second):
+		t.Fatalf("no trade update")
+	}
+	return nil
+}
+
+func TestPaperBook(t *testing.T) {
+	p, cancel := tNewPaperCEX(t, tPaperTradingConfig(), nil)
+	defer cancel()
+
+	dcrUI, _ := asset.UnitInfo(42)
+	btcUI, _ := asset.UnitInfo(0)
+	msgRate := func(r float64) uint64 { return calc.MessageRate(r, dcrUI, btcUI) }
+
+	buys, sells, err := p.Book(42, 0)
+	if err != nil {
+		t.Fatalf("Book error: %v", err)
+	}
+	if len(buys) != 3 || len(sells) != 3 {
+		t.Fatalf("wrong number of levels. %d buys, %d sells", len(buys), len(sells))
+	}
+	expBuys := []uint64{msgRate(0.00099), msgRate(0.00098), msgRate(0.00097)}
+	expSells := []uint64{msgRate(0.00101), msgRate(0.00102), msgRate(0.00103)}
+	for i := range buys {
+		if buys[i].MsgRate != expBuys[i] || sells[i].MsgRate != expSells[i] {
+			t.Fatalf("wrong level %d rates. buy %d, sell %d", i, buys[i].MsgRate, sells[i].MsgRate)
+		}
+	}
+	if midGap := p.MidGap(42, 0); midGap != msgRate(0.001) {
+		t.Fatalf("wrong mid gap %d", midGap)
+	}
+
+	if _, _, _, err := p.VWAP(0, 42, true, 1e8); err == nil {
+		t.Fatalf("no error for unknown market")
+	}
+	p.UnsubscribeMarket(42, 0)
+	if _, _, _, err := p.VWAP(42, 0, true, 1e8); err == nil {
+		t.Fatalf("no error for unsubscribed market")
+	}
+
+	// Replayed rates are used in order and loop back to the start.
+	cfg := tPaperTradingConfig()
+	cfg.Markets[0].Replay = []float64{0.002, 0.003}
+	p, cancel = tNewPaperCEX(t, cfg, nil)
+	defer cancel()
+	for _, expRate := range []float64{0.002, 0.003, 0.002} {
+		if midGap := p.MidGap(42, 0); midGap != msgRate(expRate) {
+			t.Fatalf("wrong replayed mid gap. expected %d, got %d", msgRate(expRate), midGap)
+		}
+		p.updateBooks()
+	}
+}
+
+func TestPaperTrade(t *testing.T) {
+	p, cancel := tNewPaperCEX(t, tPaperTradingConfig(), nil)
+	defer cancel()
+
+	dcrUI, _ := asset.UnitInfo(42)
+	btcUI, _ := asset.UnitInfo(0)
+	msgRate := func(r float64) uint64 { return calc.MessageRate(r, dcrUI, btcUI) }
+	checkBalance := func(assetID uint32, exp *ExchangeBalance) {
+		t.Helper()
+		bal, _ := p.Balance(assetID)
+		if !reflect.DeepEqual(bal, exp) {
+			t.Fatalf("wrong %s balance. expected %+v, got %+v", dex.BipIDSymbol(assetID), exp, bal)
+		}
+	}
+
+	updates, _, subID := p.SubscribeTradeUpdates()
+	ctx := context.Background()
+
+	// Market sell 15 DCR. 10 fill at the best bid and 5 at the next level.
+	trade, err := p.Trade(ctx, 42, 0, true, 0, 15e8, 0, OrderTypeMarket, subID)
+	if err != nil {
+		t.Fatalf("Trade error: %v", err)
+	}
+	u := waitForPaperUpdate(t, updates)
+	expQuote := calc.BaseToQuote(msgRate(0.00099), 10e8) + calc.BaseToQuote(msgRate(0.00098), 5e8)
+	expFee := uint64(float64(expQuote) * 0.001)
+	if u.ID != trade.ID || !u.Complete || u.BaseFilled != 15e8 || u.QuoteFilled != expQuote-expFee {
+		t.Fatalf("wrong market sell update: %+v", u)
+	}
+	checkBalance(42, &ExchangeBalance{Available: 85e8})
+	checkBalance(0, &ExchangeBalance{Available: 1e8 + expQuote - expFee})
+
+	// Market buy with more quote than the book can fill. The unfilled
+	// portion is unlocked.
+	if _, err := p.Trade(ctx, 42, 0, false, 0, 1e8, 0, OrderTypeMarket, subID); err == nil {
+		t.Fatalf("no error for market buy with base qty")
+	}
+	btcBal, _ := p.Balance(0)
+	trade, err = p.Trade(ctx, 42, 0, false, 0, 0, btcBal.Available, OrderTypeMarket, subID)
+	if err != nil {
+		t.Fatalf("Trade error: %v", err)
+	}
+	u = waitForPaperUpdate(t, updates)
+	var spent uint64
+	for _, r := range []float64{0.00101, 0.00102, 0.00103} {
+		spent += calc.BaseToQuote(msgRate(r), 10e8)
+	}
+	if !u.Complete || u.BaseFilled != 30e8-30e5 || u.QuoteFilled != spent {
+		t.Fatalf("wrong market buy update: %+v", u)
+	}
+	checkBalance(42, &ExchangeBalance{Available: 85e8 + 30e8 - 30e5})
+	checkBalance(0, &ExchangeBalance{Available: btcBal.Available - spent})
+
+	// A limit sell above the best bid rests on the book until the book
+	// moves through it.
+	rate := msgRate(0.0015)
+	trade, err = p.Trade(ctx, 42, 0, true, rate, 2e8, 0, OrderTypeLimit, subID)
+	if err != nil {
+		t.Fatalf("Trade error: %v", err)
+	}
+	u = waitForPaperUpdate(t, updates)
+	if u.Complete || u.BaseFilled != 0 {
+		t.Fatalf("limit order should not be filled: %+v", u)
+	}
+	dcrBal, _ := p.Balance(42)
+	if dcrBal.Locked != 2e8 {
+		t.Fatalf("wrong locked balance %d", dcrBal.Locked)
+	}
+	p.mtx.Lock()
+	p.books["dcr_btc"].midGap = msgRate(0.002)
+	p.mtx.Unlock()
+	p.updateBooks()
+	u = waitForPaperUpdate(t, updates)
+	expQuote = calc.BaseToQuote(rate, 2e8)
+	expFee = uint64(float64(expQuote) * 0.001)
+	if !u.Complete || u.BaseFilled != 2e8 || u.QuoteFilled != expQuote-expFee {
+		t.Fatalf("wrong limit sell update: %+v", u)
+	}
+	status, err := p.TradeStatus(ctx, trade.ID, 42, 0)
+	if err != nil || !reflect.DeepEqual(status, u) {
+		t.Fatalf("wrong trade status: %+v, err = %v", status, err)
+	}
+
+	// Canceled limit orders are unlocked.
+	btcBal, _ = p.Balance(0)
+	trade, err = p.Trade(ctx, 42, 0, false, msgRate(0.001), 1e8, 0, OrderTypeLimit, subID)
+	if err != nil {
+		t.Fatalf("Trade error: %v", err)
+	}
+	waitForPaperUpdate(t, updates)
+	if err := p.CancelTrade(ctx, 42, 0, trade.ID); err != nil {
+		t.Fatalf("CancelTrade error: %v", err)
+	}
+	u = waitForPaperUpdate(t, updates)
+	if !u.Complete || u.BaseFilled != 0 {
+		t.Fatalf("wrong canceled trade update: %+v", u)
+	}
+	checkBalance(0, btcBal)
+
+	// Insufficient balance.
+	if _, err := p.Trade(ctx, 42, 0, true, rate, 1000e8, 0, OrderTypeLimit, subID); err == nil {
+		t.Fatalf("no error for insufficient balance")
+	}
+	// Quantity below lot size.
+	if err := p.ValidateTrade(42, 0, true, rate, 1e5, 0, OrderTypeLimit); err == nil {
+		t.Fatalf("no error for quantity below lot size")
+	}
+}
+
+func TestPaperFunding(t *testing.T) {
+	cfg := tPaperTradingConfig()
+	cfg.DepositLatencyMS = 50
+	cfg.WithdrawLatencyMS = 50
+	wallet := &tPaperWallet{addr: "Dsaddr"}
+	p, cancel := tNewPaperCEX(t, cfg, wallet)
+	defer cancel()
+	ctx := context.Background()
+
+	addr, err := p.GetDepositAddress(ctx, 42)
+	if err != nil || addr != "Dsaddr" {
+		t.Fatalf("wrong deposit address %q, err = %v", addr, err)
+	}
+
+	deposit := &DepositData{AssetID: 42, AmountConventional: 1.5, TxID: "deposit"}
+	if complete, _ := p.ConfirmDeposit(ctx, deposit); complete {
+		t.Fatalf("deposit confirmed before latency")
+	}
+	time.Sleep(60 * time.Millisecond)
+	for i := 0; i < 2; i++ {
+		complete, amt := p.ConfirmDeposit(ctx, deposit)
+		if !complete || amt != 1.5e8 {
+			t.Fatalf("wrong deposit confirmation. complete = %t, amt = %d", complete, amt)
+		}
+	}
+	if bal, _ := p.Balance(42); bal.Available != 101.5e8 {
+		t.Fatalf("wrong balance after deposit %d", bal.Available)
+	}
+
+	if _, _, err := p.Withdraw(ctx, 42, 1000e8, "Dsother"); err == nil {
+		t.Fatalf("no error for withdrawal exceeding balance")
+	}
+	id, amt, err := p.Withdraw(ctx, 42, 1e8, "Dsother")
+	if err != nil || amt != 1e8 {
+		t.Fatalf("Withdraw error: %v, amt = %d", err, amt)
+	}
+	if bal, _ := p.Balance(42); bal.Available != 100.5e8 {
+		t.Fatalf("wrong balance after withdrawal %d", bal.Available)
+	}
+	if _, _, err := p.ConfirmWithdrawal(ctx, id, 42); !errors.Is(err, ErrWithdrawalPending) {
+		t.Fatalf("expected ErrWithdrawalPending, got %v", err)
+	}
+	time.Sleep(60 * time.Millisecond)
+	// The amount received is the amount withdrawn less the withdrawal fee.
+	for i := 0; i < 2; i++ {
+		amt, txID, err := p.ConfirmWithdrawal(ctx, id, 42)
+		if err != nil || amt != 1e8-1e5 || txID == "" {
+			t.Fatalf("wrong withdrawal confirmation: %d, %s, err = %v", amt, txID, err)
+		}
+	}
+	if bal, _ := p.Balance(42); bal.Available != 100.5e8 {
+		t.Fatalf("wrong balance after withdrawal confirmation %d", bal.Available)
+	}
+}
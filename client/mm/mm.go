@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"maps"
 	"os"
+	"reflect"
 	"slices"
 	"sync"
 	"time"
@@ -50,6 +51,8 @@ type clientCore interface {
 	Bridge(fromAssetID, toAssetID uint32, amt uint64, bridgeName string) (txID string, err error)
 	SupportedBridgeDestinations(assetID uint32) (map[uint32][]string, error)
 	BridgeContractApprovalStatus(assetID uint32, bridgeName string) (asset.ApprovalStatus, error)
+	BridgeFeesAndLimits(fromAssetID, toAssetID uint32, bridgeName string) (*core.BridgeFeesAndLimits, error)
+	AllBridgePaths() (map[uint32]map[uint32][]string, error)
 }
 
 var _ clientCore = (*core.Core)(nil)
@@ -115,12 +118,14 @@ type bot interface {
 	timeStart() int64
 	botCfg() *BotConfig
 	Book() (buys, sells []*core.MiniOrder, _ error)
+	acceptBotTransfer(peer *MarketWithHost, dexAssetID, cexAssetID uint32, amt uint64, toDEX bool) bool
 }
 
 type runningBot struct {
 	bot
 	cm     *dex.ConnectionMaster
 	cexCfg *CEXConfig
+	alloc  *BotBalanceAllocation
 }
 
 func (rb *runningBot) assets() map[uint32]interface{} {
@@ -130,6 +135,9 @@ func (rb *runningBot) assets() map[uint32]interface{} {
 	assets[cfg.QuoteID] = struct{}{}
 	assets[feeAssetID(cfg.BaseID)] = struct{}{}
 	assets[feeAssetID(cfg.QuoteID)] = struct{}{}
+	for _, assetID := range cfg.extraDEXAssets() {
+		assets[assetID] = struct{}{}
+	}
 
 	return assets
 }
@@ -162,8 +170,17 @@ type MarketMaker struct {
 	// startUpdateMtx is used to prevent starting or updating bots concurrently.
 	startUpdateMtx sync.Mutex
 
+	// bridgeReserves are the unallocated DEX funds that may be used by
+	// bridges that are being initiated. They are excluded from the available
+	// balances so that they are not allocated elsewhere before the bridge
+	// transaction is sent.
+	bridgeReservesMtx sync.Mutex
+	bridgeReserves    map[uint32]uint64
+
 	cexMtx sync.RWMutex
 	cexes  map[string]*centralizedExchange
+
+	risk *riskManager
 }
 
 // NewMarketMaker creates a new MarketMaker.
@@ -176,6 +193,11 @@ func NewMarketMaker(c clientCore, eventLogDBPath, cfgPath string, log dex.Logger
 			return nil, fmt.Errorf("error unmarshaling config file: %v", err)
 		}
 	}
+	if cfg.RiskConfig != nil {
+		if err := cfg.RiskConfig.validate(); err != nil {
+			return nil, fmt.Errorf("invalid risk config: %w", err)
+		}
+	}
 
 	return &MarketMaker{
 		core:           c,
@@ -185,6 +207,7 @@ func NewMarketMaker(c clientCore, eventLogDBPath, cfgPath string, log dex.Logger
 		eventLogDBPath: eventLogDBPath,
 		runningBots:    make(map[MarketWithHost]*runningBot),
 		cexes:          make(map[string]*centralizedExchange),
+		risk:           newRiskManager(cfg.RiskConfig, cfg.KillSwitch, log.SubLogger("RISK")),
 	}, nil
 }
 
@@ -203,6 +226,7 @@ func (m *MarketMaker) runningBotsLookup() map[MarketWithHost]*runningBot {
 type Status struct {
 	Bots  []*BotStatus          `json:"bots"`
 	CEXes map[string]*CEXStatus `json:"cexes"`
+	Risk  *RiskStatus           `json:"risk"`
 }
 
 // CEXStatus is state information about a cex.
@@ -277,6 +301,9 @@ type EpochReport struct {
 	BuysReport *OrderReport `json:"buysReport"`
 	// SellsReport is the report for the sells.
 	SellsReport *OrderReport `json:"sellsReport"`
+	// InventorySkew is the inventory-based adjustment applied to the
+	// placements, if inventory skew is configured.
+	InventorySkew *InventorySkew `json:"inventorySkew,omitempty"`
 	// EpochNum is the number of the epoch.
 	EpochNum uint64 `json:"epochNum"`
 }
@@ -358,6 +385,7 @@ func (m *MarketMaker) Status() *Status {
 	status := &Status{
 		CEXes: make(map[string]*CEXStatus, len(cfg.CexConfigs)),
 		Bots:  make([]*BotStatus, 0, len(cfg.BotConfigs)),
+		Risk:  m.risk.status(),
 	}
 	runningBots := m.runningBotsLookup()
 	for _, botCfg := range cfg.BotConfigs {
@@ -403,6 +431,7 @@ func (m *MarketMaker) RunningBotsStatus() *Status {
 	status := &Status{
 		CEXes: make(map[string]*CEXStatus, 0),
 		Bots:  make([]*BotStatus, 0),
+		Risk:  m.risk.status(),
 	}
 	runningBots := m.runningBotsLookup()
 	for _, rb := range runningBots {
@@ -525,6 +554,13 @@ func (m *MarketMaker) loginAndUnlockWallets(pw []byte, cfg *BotConfig) error {
 		}
 	}
 
+	for _, assetID := range cfg.extraDEXAssets() {
+		err = m.core.OpenWallet(assetID, pw)
+		if err != nil {
+			return fmt.Errorf("failed to unlock wallet for asset %d: %w", assetID, err)
+		}
+	}
+
 	return nil
 }
 
@@ -585,11 +621,11 @@ func (m *MarketMaker) loadCEX(ctx context.Context, cfg *CEXConfig) (*centralized
 	defer m.cexMtx.Unlock()
 	var success bool
 	if cex := m.cexes[cfg.Name]; cex != nil {
-		if cex.APIKey == cfg.APIKey && cex.APISecret == cfg.APISecret {
+		if cex.APIKey == cfg.APIKey && cex.APISecret == cfg.APISecret && cex.APIPassphrase == cfg.APIPassphrase && reflect.DeepEqual(cex.PaperTrading, cfg.PaperTrading) {
 			return cex, nil
 		}
 		if m.cexInUse(cfg.Name) {
-			return nil, fmt.Errorf("CEX %s already in use with different API key", cfg.Name)
+			return nil, fmt.Errorf("CEX %s already in use with different configuration", cfg.Name)
 		}
 		// New credentials. Delete the old cex.
 		defer func() {
@@ -602,15 +638,22 @@ func (m *MarketMaker) loadCEX(ctx context.Context, cfg *CEXConfig) (*centralized
 		}()
 	}
 	logger := m.log.SubLogger(fmt.Sprintf("CEX-%s", cfg.Name))
-	cex, err := libxc.NewCEX(cfg.Name, &libxc.CEXConfig{
-		APIKey:    cfg.APIKey,
-		SecretKey: cfg.APISecret,
-		Logger:    logger,
-		Net:       m.core.Network(),
+	libxcCfg := &libxc.CEXConfig{
+		APIKey:     cfg.APIKey,
+		SecretKey:  cfg.APISecret,
+		Passphrase: cfg.APIPassphrase,
+		Logger:     logger,
+		Net:        m.core.Network(),
 		Notify: func(n interface{}) {
 			m.handleCEXUpdate(cfg.Name, n)
 		},
-	})
+	}
+	if cfg.PaperTrading != nil {
+		logger.Infof("Using simulated exchange for paper trading in place of %s", cfg.Name)
+		libxcCfg.PaperTrading = cfg.PaperTrading
+		libxcCfg.PaperWallet = &paperWallet{core: m.core}
+	}
+	cex, err := libxc.NewCEX(cfg.Name, libxcCfg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create CEX: %v", err)
 	}
@@ -634,6 +677,19 @@ func (m *MarketMaker) loadCEX(ctx context.Context, cfg *CEXConfig) (*centralized
 	return c, nil
 }
 
+// paperWallet implements libxc.PaperWallet using the client's wallets, so that
+// deposits to the simulated exchange are sent back to the client's own wallets
+// and can be tracked by the bots. Simulated withdrawals do not use the wallets.
+type paperWallet struct {
+	core clientCore
+}
+
+var _ libxc.PaperWallet = (*paperWallet)(nil)
+
+func (w *paperWallet) NewDepositAddress(assetID uint32) (string, error) {
+	return w.core.NewDepositAddress(assetID)
+}
+
 func (m *MarketMaker) handleCEXUpdate(cexName string, ni interface{}) {
 	switch n := ni.(type) {
 	case *libxc.BalanceUpdate:
@@ -695,6 +751,12 @@ func (m *MarketMaker) Connect(ctx context.Context) (*sync.WaitGroup, error) {
 
 	var wg sync.WaitGroup
 
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		m.runRiskChecks(ctx)
+	}()
+
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
@@ -719,7 +781,7 @@ func (m *MarketMaker) Connect(ctx context.Context) (*sync.WaitGroup, error) {
 }
 
 func (m *MarketMaker) balancesSufficient(balances *BotBalanceAllocation, mkt *MarketWithHost, botCfg *BotConfig, cexCfg *CEXConfig) error {
-	availableDEXBalances, availableCEXBalances, err := m.availableBalances(mkt, botCfg.CEXBaseID, botCfg.CEXQuoteID, cexCfg)
+	availableDEXBalances, availableCEXBalances, err := m.availableBalances(mkt, botCfg.CEXBaseID, botCfg.CEXQuoteID, botCfg.extraDEXAssets(), cexCfg)
 	if err != nil {
 		return fmt.Errorf("error getting available balances: %v", err)
 	}
@@ -785,6 +847,12 @@ func (m *MarketMaker) botSubLogger(cfg *BotConfig) dex.Logger {
 		return m.log.SubLogger(fmt.Sprintf("ARB-%s", mktID))
 	case cfg.ArbMarketMakerConfig != nil:
 		return m.log.SubLogger(fmt.Sprintf("AMM-%s", mktID))
+	case cfg.StrategyConfig != nil:
+		return m.log.SubLogger(fmt.Sprintf("STRAT-%s", mktID))
+	case cfg.TriangleArbConfig != nil:
+		return m.log.SubLogger(fmt.Sprintf("TRI-%s", mktID))
+	case cfg.CrossDEXArbConfig != nil:
+		return m.log.SubLogger(fmt.Sprintf("XARB-%s", mktID))
 	}
 	// This will error in the caller.
 	return m.log.SubLogger(fmt.Sprintf("Bot-%s", mktID))
@@ -859,6 +927,12 @@ func (m *MarketMaker) newBot(cfg *BotConfig, adaptorCfg *exchangeAdaptorCfg) (bo
 		return newBasicMarketMaker(cfg, adaptorCfg, m.oracle, m.log.SubLogger(fmt.Sprintf("MM-%s", mktID)))
 	case cfg.SimpleArbConfig != nil:
 		return newSimpleArbMarketMaker(cfg, adaptorCfg, m.log.SubLogger(fmt.Sprintf("ARB-%s", mktID)))
+	case cfg.StrategyConfig != nil:
+		return newStrategyBot(cfg, adaptorCfg, m.log.SubLogger(fmt.Sprintf("STRAT-%s", mktID)))
+	case cfg.TriangleArbConfig != nil:
+		return newTriangleArbBot(cfg, adaptorCfg, m.log.SubLogger(fmt.Sprintf("TRI-%s", mktID)))
+	case cfg.CrossDEXArbConfig != nil:
+		return newCrossDEXArbBot(cfg, adaptorCfg, m.log.SubLogger(fmt.Sprintf("XARB-%s", mktID)))
 	default:
 		return nil, fmt.Errorf("not bot config found")
 	}
@@ -937,8 +1011,20 @@ func (m *MarketMaker) StartBot(startCfg *StartConfig, alternateConfigPath *strin
 	return m.startBot(startCfg, botCfg, cexCfg, appPW)
 }
 
+// startBot starts a bot and registers it in runningBots. startBot must be
+// called with the startUpdateMtx locked, so that the exposure of the running
+// bots cannot change between the risk limit check and the registration of the
+// new bot.
 func (m *MarketMaker) startBot(startCfg *StartConfig, botCfg *BotConfig, cexCfg *CEXConfig, appPW []byte) (err error) {
 	mwh := &startCfg.MarketWithHost
+	botState := &botRiskState{
+		mkt:       *mwh,
+		alloc:     startCfg.Alloc,
+		cexAssets: cexAssetMap(botCfg),
+	}
+	if err := m.risk.checkAllocation(botState, m.botRiskStates()); err != nil {
+		return fmt.Errorf("risk limits: %w", err)
+	}
 	if err := m.balancesSufficient(startCfg.Alloc, mwh, botCfg, cexCfg); err != nil {
 		return err
 	}
@@ -983,6 +1069,8 @@ func (m *MarketMaker) startBot(startCfg *StartConfig, botCfg *BotConfig, cexCfg
 		eventLogDB:          m.eventLogDB,
 		internalTransfer:    m.internalTransfer,
 		bridgesSupported:    m.configuredBridgesSupported,
+		bridgeTransfer:      m.bridgeTransfer,
+		botTransfer:         m.botTransfer,
 	}
 
 	bot, err := m.newBot(botCfg, adaptorCfg)
@@ -995,6 +1083,28 @@ func (m *MarketMaker) startBot(startCfg *StartConfig, botCfg *BotConfig, cexCfg
 		return fmt.Errorf("error connecting bot: %w", err)
 	}
 
+	rb := &runningBot{
+		bot:    bot,
+		cm:     cm,
+		cexCfg: cexCfg,
+		alloc:  startCfg.Alloc,
+	}
+
+	m.runningBotsMtx.Lock()
+	// The kill switch may have been triggered while the bot was starting.
+	// triggerKillSwitch sets the kill state before looking up the running bots
+	// to stop, so checking it here with the runningBotsMtx locked ensures that
+	// the bot is either never registered or stopped by the kill switch.
+	if err := m.risk.killErr(); err != nil {
+		m.runningBotsMtx.Unlock()
+		cm.Disconnect()
+		return err
+	}
+	m.runningBots[*mwh] = rb
+	m.runningBotsMtx.Unlock()
+
+	startedBot = true
+
 	go func() {
 		cm.Wait()
 		m.runningBotsMtx.Lock()
@@ -1008,18 +1118,6 @@ func (m *MarketMaker) startBot(startCfg *StartConfig, botCfg *BotConfig, cexCfg
 		m.core.Broadcast(newRunStatsNote(mwh.Host, mwh.BaseID, mwh.QuoteID, nil))
 	}()
 
-	startedBot = true
-
-	rb := &runningBot{
-		bot:    bot,
-		cm:     cm,
-		cexCfg: cexCfg,
-	}
-
-	m.runningBotsMtx.Lock()
-	m.runningBots[*mwh] = rb
-	m.runningBotsMtx.Unlock()
-
 	return nil
 }
 
@@ -1035,6 +1133,123 @@ func (m *MarketMaker) StopBot(mkt *MarketWithHost) error {
 	return nil
 }
 
+// botRiskStates returns the risk-relevant state of the running bots.
+func (m *MarketMaker) botRiskStates() []*botRiskState {
+	runningBots := m.runningBotsLookup()
+	states := make([]*botRiskState, 0, len(runningBots))
+	for mkt, rb := range runningBots {
+		states = append(states, &botRiskState{
+			mkt:       mkt,
+			stats:     rb.stats(),
+			alloc:     rb.alloc,
+			cexAssets: cexAssetMap(rb.botCfg()),
+		})
+	}
+	return states
+}
+
+// checkRiskLimits checks the running bots against the risk limits, stopping
+// any bots that have exceeded their max drawdown, and triggering the kill
+// switch if a portfolio-wide limit has been exceeded.
+func (m *MarketMaker) checkRiskLimits() {
+	res := m.risk.check(m.botRiskStates())
+	if res.kill != nil {
+		if err := m.triggerKillSwitch(res.kill); err != nil {
+			m.log.Errorf("Error triggering kill switch: %v", err)
+		}
+		return
+	}
+	for mkt, reason := range res.stopBots {
+		m.log.Warnf("Stopping bot on %s: %v", mkt, reason)
+		if err := m.StopBot(&mkt); err != nil {
+			m.log.Errorf("Error stopping bot on %s: %v", mkt, err)
+		}
+		m.core.Broadcast(newBotRiskLimitNote(&mkt, reason))
+	}
+}
+
+func (m *MarketMaker) runRiskChecks(ctx context.Context) {
+	ticker := time.NewTicker(riskCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			m.checkRiskLimits()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// triggerKillSwitch stops all running bots, which cancels all of their DEX
+// and CEX orders. No bots can be started until ResetKillSwitch is called, even
+// after a restart. The kill state is set before the running bots are looked
+// up, so a bot that is being started is either stopped here or refused by
+// startBot.
+func (m *MarketMaker) triggerKillSwitch(reason error) error {
+	killed := m.risk.kill(reason)
+	if killed == nil {
+		return nil
+	}
+	m.log.Errorf("Kill switch triggered: %v", reason)
+	for mkt := range m.runningBotsLookup() {
+		if err := m.StopBot(&mkt); err != nil {
+			m.log.Errorf("Error stopping bot on %s: %v", mkt, err)
+		}
+	}
+	m.core.Broadcast(newKillSwitchNote(reason))
+	if err := m.saveKillSwitch(killed); err != nil {
+		return fmt.Errorf("error saving kill switch state: %w", err)
+	}
+	return nil
+}
+
+// saveKillSwitch saves the state of the kill switch to the config file.
+func (m *MarketMaker) saveKillSwitch(killed *StampedError) error {
+	cfg := m.defaultConfig()
+	cfg.KillSwitch = killed
+	return m.writeConfigFile(cfg)
+}
+
+// KillSwitch stops all running bots and prevents any bots from being started
+// until ResetKillSwitch is called.
+func (m *MarketMaker) KillSwitch() error {
+	return m.triggerKillSwitch(errors.New("triggered by user"))
+}
+
+// ResetKillSwitch allows bots to be started again after the kill switch was
+// triggered. The aggregate drawdown tracking is also reset.
+func (m *MarketMaker) ResetKillSwitch() error {
+	m.risk.reset()
+	if err := m.saveKillSwitch(nil); err != nil {
+		return fmt.Errorf("error saving kill switch state: %w", err)
+	}
+	return nil
+}
+
+// RiskStatus returns the state of the portfolio risk limits.
+func (m *MarketMaker) RiskStatus() *RiskStatus {
+	return m.risk.status()
+}
+
+// UpdateRiskConfig updates the portfolio-wide risk limits. The new limits
+// are applied to the running bots at the next check.
+func (m *MarketMaker) UpdateRiskConfig(cfg *RiskConfig) error {
+	if err := cfg.validate(); err != nil {
+		return err
+	}
+	m.risk.setConfig(cfg)
+
+	m.defaultCfgMtx.Lock()
+	m.defaultCfg.RiskConfig = cfg.copy()
+	m.defaultCfgMtx.Unlock()
+
+	if err := m.writeConfigFile(m.defaultConfig()); err != nil {
+		return fmt.Errorf("error saving risk configuration: %w", err)
+	}
+	return nil
+}
+
 func getMarketMakingConfig(path string) (*MarketMakingConfig, error) {
 	if path == "" {
 		return nil, fmt.Errorf("no config file provided")
@@ -1180,6 +1395,18 @@ func validRunningBotCfgUpdate(oldCfg, newCfg *BotConfig) error {
 		return fmt.Errorf("cannot change bot type for running bot")
 	}
 
+	if oldCfg.StrategyConfig == nil != (newCfg.StrategyConfig == nil) {
+		return fmt.Errorf("cannot change bot type for running bot")
+	}
+
+	if oldCfg.TriangleArbConfig == nil != (newCfg.TriangleArbConfig == nil) {
+		return fmt.Errorf("cannot change bot type for running bot")
+	}
+
+	if oldCfg.CrossDEXArbConfig == nil != (newCfg.CrossDEXArbConfig == nil) {
+		return fmt.Errorf("cannot change bot type for running bot")
+	}
+
 	return nil
 }
 
@@ -1198,7 +1425,7 @@ func (m *MarketMaker) internalTransfer(mkt *MarketWithHost, doTransfer doInterna
 		return fmt.Errorf("internalTransfer called for non-running bot %s", mkt)
 	}
 
-	dex, cex, err := m.availableBalances(mkt, rb.botCfg().CEXBaseID, rb.botCfg().CEXQuoteID, rb.cexCfg)
+	dex, cex, err := m.availableBalances(mkt, rb.botCfg().CEXBaseID, rb.botCfg().CEXQuoteID, rb.botCfg().extraDEXAssets(), rb.cexCfg)
 	if err != nil {
 		return fmt.Errorf("error getting available balances: %v", err)
 	}
@@ -1206,6 +1433,103 @@ func (m *MarketMaker) internalTransfer(mkt *MarketWithHost, doTransfer doInterna
 	return doTransfer(dex, cex)
 }
 
+// bridgeTransfer is called from the exchange adaptor when attempting to
+// bridge amt of unallocated funds into the bot's DEX wallets. The unallocated
+// DEX balances of the bot's assets and the potential bridge sources are passed
+// to doTransfer.
+//
+// doTransfer is not called with the startUpdateMtx locked, since it initiates
+// the bridge. Instead, amt of each source with sufficient unallocated funds is
+// reserved until doTransfer returns.
+func (m *MarketMaker) bridgeTransfer(mkt *MarketWithHost, fromAssetIDs []uint32, amt uint64, doTransfer doBridgeTransferFunc) error {
+	dex, err := func() (map[uint32]uint64, error) {
+		m.startUpdateMtx.Lock()
+		defer m.startUpdateMtx.Unlock()
+
+		runningBots := m.runningBotsLookup()
+		rb, found := runningBots[*mkt]
+		if !found {
+			return nil, fmt.Errorf("bridgeTransfer called for non-running bot %s", mkt)
+		}
+
+		botCfg := rb.botCfg()
+		extraDEXAssets := append(botCfg.extraDEXAssets(), fromAssetIDs...)
+		dex, _, err := m.availableBalances(mkt, botCfg.CEXBaseID, botCfg.CEXQuoteID, extraDEXAssets, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error getting available balances: %v", err)
+		}
+		m.reserveBridgeFunds(fromAssetIDs, dex, amt)
+		return dex, nil
+	}()
+	if err != nil {
+		return err
+	}
+	defer m.releaseBridgeReserves(fromAssetIDs, dex, amt)
+
+	return doTransfer(dex)
+}
+
+// reserveBridgeFunds reserves amt of each of the assets with at least amt
+// available. The startUpdateMtx MUST be locked, and the available balances
+// must not include the existing reserves.
+func (m *MarketMaker) reserveBridgeFunds(assetIDs []uint32, available map[uint32]uint64, amt uint64) {
+	m.bridgeReservesMtx.Lock()
+	defer m.bridgeReservesMtx.Unlock()
+	if m.bridgeReserves == nil {
+		m.bridgeReserves = make(map[uint32]uint64)
+	}
+	for _, assetID := range assetIDs {
+		if available[assetID] >= amt {
+			m.bridgeReserves[assetID] += amt
+		}
+	}
+}
+
+// releaseBridgeReserves releases the funds reserved by reserveBridgeFunds.
+func (m *MarketMaker) releaseBridgeReserves(assetIDs []uint32, available map[uint32]uint64, amt uint64) {
+	m.bridgeReservesMtx.Lock()
+	defer m.bridgeReservesMtx.Unlock()
+	for _, assetID := range assetIDs {
+		if available[assetID] < amt {
+			continue
+		}
+		if m.bridgeReserves[assetID] <= amt {
+			delete(m.bridgeReserves, assetID)
+		} else {
+			m.bridgeReserves[assetID] -= amt
+		}
+	}
+}
+
+// botTransfer is called from the exchange adaptor when attempting to swap
+// funds with another running bot that uses the same CEX. If toDEX is true,
+// the requesting bot receives DEX funds and gives up CEX funds. The market of
+// the bot that accepted the transfer is returned, or nil if no bot accepted.
+// The bots are resolved with the startUpdateMtx locked, but it is released
+// before the peers are asked, since each peer checks and applies the transfer
+// atomically.
+func (m *MarketMaker) botTransfer(mkt *MarketWithHost, dexAssetID, cexAssetID uint32, amt uint64, toDEX bool) (*MarketWithHost, error) {
+	m.startUpdateMtx.Lock()
+	runningBots := m.runningBotsLookup()
+	m.startUpdateMtx.Unlock()
+	rb, found := runningBots[*mkt]
+	if !found {
+		return nil, fmt.Errorf("botTransfer called for non-running bot %s", mkt)
+	}
+
+	cexName := rb.cexName()
+	for peerMkt, peer := range runningBots {
+		if peerMkt == *mkt || peer.cexName() != cexName {
+			continue
+		}
+		if peer.acceptBotTransfer(mkt, dexAssetID, cexAssetID, amt, toDEX) {
+			return &peerMkt, nil
+		}
+	}
+
+	return nil, nil
+}
+
 // UpdateRunningBotInventory updates the inventory of a running bot.
 func (m *MarketMaker) UpdateRunningBotInventory(mkt *MarketWithHost, balanceDiffs *BotInventoryDiffs) error {
 	m.startUpdateMtx.Lock()
@@ -1334,6 +1658,37 @@ func (m *MarketMaker) RunOverview(startTime int64, mkt *MarketWithHost) (*Market
 	return m.eventLogDB.runOverview(startTime, mkt)
 }
 
+// RunAnalytics computes fee, fill, spread capture and PnL analytics for a
+// market making run, valued at the current fiat rates. If window is non-zero,
+// the analytics are also broken down into consecutive windows of that
+// duration.
+func (m *MarketMaker) RunAnalytics(startTime int64, mkt *MarketWithHost, window time.Duration) (*RunAnalytics, error) {
+	overview, err := m.eventLogDB.runOverview(startTime, mkt)
+	if err != nil {
+		return nil, err
+	}
+	events, err := m.eventLogDB.runEvents(startTime, mkt, 0, nil, false, noFilters)
+	if err != nil {
+		return nil, err
+	}
+
+	// Prefer the rates from the fiat rate feed, falling back to the rates
+	// stored with the run.
+	fiatRates := make(map[uint32]float64)
+	if overview.FinalState != nil {
+		for assetID, rate := range overview.FinalState.FiatRates {
+			fiatRates[assetID] = rate
+		}
+	}
+	for assetID, rate := range m.core.FiatConversionRates() {
+		if rate > 0 {
+			fiatRates[assetID] = rate
+		}
+	}
+
+	return newRunAnalytics(startTime, mkt, overview, events, window, fiatRates, time.Now())
+}
+
 func (m *MarketMaker) updateDEXOrderEvent(mkt *MarketWithHost, event *MarketMakingEvent, cexBaseID, cexQuoteID uint32) (*MarketMakingEvent, error) {
 	orderEvent := event.DEXOrderEvent
 
@@ -1503,6 +1858,35 @@ func (m *MarketMaker) updateDepositEvent(event *MarketMakingEvent, cexName strin
 	}, nil
 }
 
+func (m *MarketMaker) updateBridgeEvent(event *MarketMakingEvent) (*MarketMakingEvent, error) {
+	bridgeEvent := event.BridgeEvent
+	bridgeTx := bridgeEvent.BridgeTx
+	if bridgeTx != nil && !bridgeComplete(bridgeTx) {
+		tx, err := m.core.WalletTransaction(bridgeEvent.FromAssetID, bridgeTx.ID)
+		if err != nil {
+			return nil, fmt.Errorf("error fetching transaction: %v", err)
+		}
+		bridgeTx = tx
+	}
+
+	dexEffects, cexEffects := bridgeRebalanceBalanceEffects(bridgeTx, bridgeEvent.ToAssetID, bridgeEvent.CEXAssetID, bridgeEvent.CEXDebit)
+
+	return &MarketMakingEvent{
+		ID:             event.ID,
+		TimeStamp:      event.TimeStamp,
+		Pending:        !bridgeComplete(bridgeTx),
+		BalanceEffects: combineBalanceEffects(dexEffects, cexEffects),
+		BridgeEvent: &BridgeEvent{
+			FromAssetID: bridgeEvent.FromAssetID,
+			ToAssetID:   bridgeEvent.ToAssetID,
+			CEXAssetID:  bridgeEvent.CEXAssetID,
+			BridgeName:  bridgeEvent.BridgeName,
+			BridgeTx:    bridgeTx,
+			CEXDebit:    bridgeEvent.CEXDebit,
+		},
+	}, nil
+}
+
 func (m *MarketMaker) updateWithdrawalEvent(event *MarketMakingEvent, cexName string) (*MarketMakingEvent, error) {
 	withdrawalTx := event.WithdrawalEvent.WithdrawalTx
 	bridgeTx := event.WithdrawalEvent.BridgeTx
@@ -1616,6 +2000,8 @@ func (m *MarketMaker) updatePendingEvent(mkt *MarketWithHost, event *MarketMakin
 		return m.updateDepositEvent(event, cexName)
 	case event.WithdrawalEvent != nil:
 		return m.updateWithdrawalEvent(event, cexName)
+	case event.BridgeEvent != nil:
+		return m.updateBridgeEvent(event)
 	default:
 		return event, nil
 	}
@@ -1646,6 +2032,13 @@ func (f *RunLogFilters) filter(event *MarketMakingEvent) bool {
 		return f.Deposits
 	case event.WithdrawalEvent != nil:
 		return f.Withdrawals
+	case event.BridgeEvent != nil:
+		return f.Withdrawals
+	case event.BotTransferEvent != nil:
+		if event.BotTransferEvent.ToDEX {
+			return f.Withdrawals
+		}
+		return f.Deposits
 	default:
 		return false
 	}
@@ -1776,7 +2169,7 @@ func marketFees(c clientCore, host string, baseID, quoteID uint32, useMaxFeeRate
 		}, nil
 }
 
-func (m *MarketMaker) availableBalances(mkt *MarketWithHost, cexBaseID, cexQuoteID uint32, cexCfg *CEXConfig) (dexBalances, cexBalances map[uint32]uint64, _ error) {
+func (m *MarketMaker) availableBalances(mkt *MarketWithHost, cexBaseID, cexQuoteID uint32, extraDEXAssets []uint32, cexCfg *CEXConfig) (dexBalances, cexBalances map[uint32]uint64, _ error) {
 	dexAssets := make(map[uint32]interface{})
 	cexAssets := make(map[uint32]interface{})
 
@@ -1784,6 +2177,9 @@ func (m *MarketMaker) availableBalances(mkt *MarketWithHost, cexBaseID, cexQuote
 	dexAssets[mkt.QuoteID] = struct{}{}
 	dexAssets[feeAssetID(mkt.BaseID)] = struct{}{}
 	dexAssets[feeAssetID(mkt.QuoteID)] = struct{}{}
+	for _, assetID := range extraDEXAssets {
+		dexAssets[assetID] = struct{}{}
+	}
 
 	if cexCfg != nil {
 		cexAssets[cexBaseID] = struct{}{}
@@ -1909,6 +2305,18 @@ func (m *MarketMaker) availableBalances(mkt *MarketWithHost, cexBaseID, cexQuote
 					totalCEXBalances[assetID] -= bal
 				}
 			}
+			m.bridgeReservesMtx.Lock()
+			for assetID, bal := range m.bridgeReserves {
+				if _, found := totalDEXBalances[assetID]; !found {
+					continue
+				}
+				if bal > totalDEXBalances[assetID] {
+					totalDEXBalances[assetID] = 0
+				} else {
+					totalDEXBalances[assetID] -= bal
+				}
+			}
+			m.bridgeReservesMtx.Unlock()
 			return totalDEXBalances, totalCEXBalances, nil
 		}
 
@@ -1932,7 +2340,13 @@ func (m *MarketMaker) AvailableBalances(mkt *MarketWithHost, cexBaseID, cexQuote
 		cexCfg = cex.CEXConfig
 	}
 
-	return m.availableBalances(mkt, cexBaseID, cexQuoteID, cexCfg)
+	// Bots that trade on other markets need balances of additional assets.
+	var extraDEXAssets []uint32
+	if botCfg, _, err := m.configsForMarket(mkt, nil); err == nil {
+		extraDEXAssets = botCfg.extraDEXAssets()
+	}
+
+	return m.availableBalances(mkt, cexBaseID, cexQuoteID, extraDEXAssets, cexCfg)
 }
 
 func sellStr(sell bool) string {
@@ -535,12 +535,12 @@ func rateAdjustment(feesInQuoteUnits, lotSize uint64) uint64 {
 // the DEX order book based on the rate of the counter trade on the CEX. The
 // logic is in the dexPlacementRate function, so that it can be separately
 // tested.
-func (a *arbMarketMaker) dexPlacementRate(cexRate uint64, sell bool) (uint64, error) {
+func (a *arbMarketMaker) dexPlacementRate(cexRate uint64, sell bool, profit float64) (uint64, error) {
 	feesInQuoteUnits, err := a.OrderFeesInUnits(sell, false, cexRate)
 	if err != nil {
 		return 0, fmt.Errorf("error getting fees in quote units: %w", err)
 	}
-	return dexPlacementRate(cexRate, sell, a.cfg().Profit, a.market, feesInQuoteUnits, a.log)
+	return dexPlacementRate(cexRate, sell, profit, a.market, feesInQuoteUnits, a.log)
 }
 
 func msgRate(rate float64, baseID, quoteID uint32) uint64 {
@@ -801,25 +801,81 @@ func (a *arbMarketMaker) validateArbTrades(arbTrades []*arbTradeArgs) error {
 	return nil
 }
 
-func (a *arbMarketMaker) ordersToPlace() (buys, sells []*TradePlacement, err error) {
-	lotSize := a.lotSize.Load()
-	botCfg := a.botCfg()
+func (a *arbMarketMaker) ordersToPlace() (buys, sells []*TradePlacement, skew *InventorySkew, err error) {
+	skewer := newInventorySkewer(a.botCfg().InventorySkew, a.inventoryTotals)
+	return arbMMOrdersToPlace(a.cfg(), a.botCfg(), a.market, a.CEX.VWAP, a.CEX.InvVWAP, a.validateArbTrades, a.dexPlacementRate, skewer, a.log)
+}
+
+// arbMMPlacementProfit returns the profit to target for placements on one
+// side of the DEX book. The arb market maker's rates are anchored to the CEX
+// counter trades, so the inventory skew is expressed through the profit. The
+// gap multiplier scales the configured profit, and the basis shift moves both
+// sides in the same direction. The profit is never skewed below zero, so the
+// placements still cover fees.
+func arbMMPlacementProfit(profit float64, skew *InventorySkew, sell bool) float64 {
+	if skew == nil {
+		return profit
+	}
+	p := profit * skew.gapMultiplier(sell)
+	if sell {
+		p += skew.BasisShift
+	} else {
+		p -= skew.BasisShift
+	}
+	return max(p, 0)
+}
+
+// arbMMOrdersToPlace calculates the placements of an arb market maker from the
+// CEX order book. validateArbTrades checks that the potential counter trades
+// are valid on the CEX, and placementRate converts a CEX counter trade rate to
+// a DEX placement rate for a profit. If skewer is non-nil, the inventory is
+// valued at the CEX mid-gap and the profit on each side is skewed
+// accordingly.
+func arbMMOrdersToPlace(cfg *ArbMarketMakerConfig, botCfg *BotConfig, mkt *market, vwap, invVwap vwapFunc,
+	validateArbTrades func([]*arbTradeArgs) error, placementRate func(cexRate uint64, sell bool, profit float64) (uint64, error),
+	skewer *inventorySkewer, log dex.Logger) (buys, sells []*TradePlacement, skew *InventorySkew, err error) {
+
+	lotSize := mkt.lotSize.Load()
+	if skewer != nil {
+		midGap := func() (uint64, error) {
+			var rates [2]uint64
+			for i, sell := range []bool{false, true} {
+				filled, cexRate, _, _, err := arbMMExtremaAndTrades(sell, lotSize, 1, cfg.MultiHop,
+					botCfg.CEXBaseID, botCfg.CEXQuoteID, lotSize, vwap, invVwap)
+				if err != nil {
+					return 0, err
+				}
+				if !filled {
+					return 0, nil
+				}
+				rates[i] = cexRate
+			}
+			return (rates[0] + rates[1]) / 2, nil
+		}
+		var rate uint64
+		if rate, err = midGap(); err != nil {
+			return nil, nil, nil, fmt.Errorf("error getting CEX mid-gap: %w", err)
+		}
+		skew = skewer.skew(rate)
+	}
+
 	orders := func(cfgPlacements []*ArbMarketMakingPlacement, sellOnDEX bool) ([]*TradePlacement, error) {
 		newPlacements := make([]*TradePlacement, 0, len(cfgPlacements))
+		profit := arbMMPlacementProfit(cfg.Profit, skew, sellOnDEX)
 		var cumulativeCEXDepth uint64
 		for i, cfgPlacement := range cfgPlacements {
 			cumulativeCEXDepth += uint64(float64(cfgPlacement.Lots*lotSize) * cfgPlacement.Multiplier)
 
 			filled, cexRate, multiHopRates, arbTrades, err := arbMMExtremaAndTrades(sellOnDEX,
-				cumulativeCEXDepth, cfgPlacement.Lots, a.cfg().MultiHop,
-				botCfg.CEXBaseID, botCfg.CEXQuoteID, lotSize, a.CEX.VWAP, a.CEX.InvVWAP)
+				cumulativeCEXDepth, cfgPlacement.Lots, cfg.MultiHop,
+				botCfg.CEXBaseID, botCfg.CEXQuoteID, lotSize, vwap, invVwap)
 			if err != nil {
 				return nil, fmt.Errorf("error getting VWAP: %w", err)
 			}
 
-			if a.log.Level() == dex.LevelTrace {
-				a.log.Tracef("%s placement orders: %s placement # %d, lots = %d, cex rate = %s, filled = %t",
-					a.name, sellStr(sellOnDEX), i, cfgPlacement.Lots, a.fmtRate(cexRate), filled,
+			if log.Level() == dex.LevelTrace {
+				log.Tracef("%s placement orders: %s placement # %d, lots = %d, cex rate = %s, filled = %t",
+					mkt.name, sellStr(sellOnDEX), i, cfgPlacement.Lots, mkt.fmtRate(cexRate), filled,
 				)
 			}
 
@@ -832,7 +888,7 @@ func (a *arbMarketMaker) ordersToPlace() (buys, sells []*TradePlacement, err err
 				continue
 			}
 
-			if err := a.validateArbTrades(arbTrades); err != nil {
+			if err := validateArbTrades(arbTrades); err != nil {
 				newPlacements = append(newPlacements, &TradePlacement{
 					Error: &BotProblems{
 						UnknownError: fmt.Sprintf("error validating arb trades: %v", err),
@@ -841,7 +897,7 @@ func (a *arbMarketMaker) ordersToPlace() (buys, sells []*TradePlacement, err err
 				continue
 			}
 
-			placementRate, err := a.dexPlacementRate(cexRate, sellOnDEX)
+			placementRate, err := placementRate(cexRate, sellOnDEX, profit)
 			if err != nil {
 				return nil, fmt.Errorf("error calculating DEX placement rate: %w", err)
 			}
@@ -857,13 +913,16 @@ func (a *arbMarketMaker) ordersToPlace() (buys, sells []*TradePlacement, err err
 		return newPlacements, nil
 	}
 
-	buys, err = orders(a.cfg().BuyPlacements, false)
+	buys, err = orders(cfg.BuyPlacements, false)
 	if err != nil {
-		return
+		return nil, nil, nil, err
 	}
 
-	sells, err = orders(a.cfg().SellPlacements, true)
-	return
+	sells, err = orders(cfg.SellPlacements, true)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return buys, sells, skew, nil
 }
 
 // distribution parses the current inventory distribution and checks if better
@@ -878,11 +937,11 @@ func (a *arbMarketMaker) distribution(additionalDEX, additionalCEX map[uint32]ui
 	if err != nil {
 		return nil, fmt.Errorf("error getting cex counter-rates: %w", err)
 	}
-	adjustedBuy, err := a.dexPlacementRate(dexBuyRate, false)
+	adjustedBuy, err := a.dexPlacementRate(dexBuyRate, false, a.cfg().Profit)
 	if err != nil {
 		return nil, fmt.Errorf("error getting adjusted buy rate: %v", err)
 	}
-	adjustedSell, err := a.dexPlacementRate(dexSellRate, true)
+	adjustedSell, err := a.dexPlacementRate(dexSellRate, true, a.cfg().Profit)
 	if err != nil {
 		return nil, fmt.Errorf("error getting adjusted sell rate: %v", err)
 	}
@@ -952,7 +1011,7 @@ func (a *arbMarketMaker) rebalance(epoch uint64, book *orderbook.OrderBook) {
 	}
 
 	var buysReport, sellsReport *OrderReport
-	buyOrders, sellOrders, determinePlacementsErr := a.ordersToPlace()
+	buyOrders, sellOrders, skew, determinePlacementsErr := a.ordersToPlace()
 	if determinePlacementsErr != nil {
 		a.tryCancelOrders(a.ctx, &epoch, false)
 	} else {
@@ -966,9 +1025,10 @@ func (a *arbMarketMaker) rebalance(epoch uint64, book *orderbook.OrderBook) {
 	}
 
 	epochReport := &EpochReport{
-		BuysReport:  buysReport,
-		SellsReport: sellsReport,
-		EpochNum:    epoch,
+		BuysReport:    buysReport,
+		SellsReport:   sellsReport,
+		InventorySkew: skew,
+		EpochNum:      epoch,
 	}
 	epochReport.setPreOrderProblems(determinePlacementsErr)
 	a.updateEpochReport(epochReport)
@@ -1788,6 +1788,7 @@ func mustParseAdaptorFromMarket(m *core.Market) *unifiedExchangeAdaptor {
 		eventLogDB:         newTEventLogDB(),
 		pendingDeposits:    make(map[string]*pendingDeposit),
 		pendingWithdrawals: make(map[string]*pendingWithdrawal),
+		pendingBridges:     make(map[string]*pendingBridge),
 		clientCore:         tCore,
 		cexProblems:        newCEXProblems(),
 		internalTransfer: func(mwh *MarketWithHost, fn doInternalTransferFunc) error {
@@ -37,6 +37,10 @@ const (
 	// GapStrategyPercentPlus sets the spread as a ratio of the mid-gap rate
 	// plus the break-even gap.
 	GapStrategyPercentPlus GapStrategy = "percent-plus"
+	// GapStrategyVolatility sets the spread as a multiple of the market's
+	// realized volatility, clamped to the floor and ceiling in the
+	// VolatilityGapConfig, plus the break-even gap.
+	GapStrategyVolatility GapStrategy = "volatility"
 )
 
 // OrderPlacement represents the distance from the mid-gap and the
@@ -72,10 +76,15 @@ type BasicMarketMakingConfig struct {
 	// before they are replaced (units: ratio of price). Default: 0.1%.
 	// 0 <= x <= 0.01.
 	DriftTolerance float64 `json:"driftTolerance"`
+
+	// Volatility configures GapStrategyVolatility. It is required for that
+	// strategy and ignored otherwise.
+	Volatility *VolatilityGapConfig `json:"volatility,omitempty"`
 }
 
 func needBreakEvenHalfSpread(strat GapStrategy) bool {
-	return strat == GapStrategyAbsolutePlus || strat == GapStrategyPercentPlus || strat == GapStrategyMultiplier
+	return strat == GapStrategyAbsolutePlus || strat == GapStrategyPercentPlus || strat == GapStrategyMultiplier ||
+		strat == GapStrategyVolatility
 }
 
 func (c *BasicMarketMakingConfig) validate() error {
@@ -90,10 +99,20 @@ func (c *BasicMarketMakingConfig) validate() error {
 		c.GapStrategy != GapStrategyPercent &&
 		c.GapStrategy != GapStrategyPercentPlus &&
 		c.GapStrategy != GapStrategyAbsolute &&
-		c.GapStrategy != GapStrategyAbsolutePlus {
+		c.GapStrategy != GapStrategyAbsolutePlus &&
+		c.GapStrategy != GapStrategyVolatility {
 		return fmt.Errorf("unknown gap strategy %q", c.GapStrategy)
 	}
 
+	if c.GapStrategy == GapStrategyVolatility {
+		if c.Volatility == nil {
+			return errors.New("no volatility config provided for volatility gap strategy")
+		}
+		if err := c.Volatility.validate(); err != nil {
+			return fmt.Errorf("invalid volatility config: %w", err)
+		}
+	}
+
 	validatePlacement := func(p *OrderPlacement) error {
 		var limits [2]float64
 		switch c.GapStrategy {
@@ -103,6 +122,8 @@ func (c *BasicMarketMakingConfig) validate() error {
 			limits = [2]float64{0, 0.1}
 		case GapStrategyAbsolute, GapStrategyAbsolutePlus:
 			limits = [2]float64{0, math.MaxFloat64} // validate at < spot price at creation time
+		case GapStrategyVolatility:
+			limits = [2]float64{0, 10}
 		default:
 			return fmt.Errorf("unknown gap strategy %q", c.GapStrategy)
 		}
@@ -151,6 +172,9 @@ func (c *BasicMarketMakingConfig) copy() *BasicMarketMakingConfig {
 
 	cfg.SellPlacements = utils.Map(c.SellPlacements, copyOrderPlacement)
 	cfg.BuyPlacements = utils.Map(c.BuyPlacements, copyOrderPlacement)
+	if c.Volatility != nil {
+		cfg.Volatility = c.Volatility.copy()
+	}
 
 	return &cfg
 }
@@ -193,13 +217,22 @@ type basicMMCalculator interface {
 	basisPrice() (bp uint64, err error)
 	halfSpread(uint64) (uint64, error)
 	feeGapStats(uint64) (*FeeGapStats, error)
+	volatilityHalfSpread(cfg *VolatilityGapConfig, basisPrice uint64) uint64
+}
+
+// basicMMCalculatorCore is the subset of botCoreAdaptor methods required by
+// basicMMCalculatorImpl.
+type basicMMCalculatorCore interface {
+	ExchangeRateFromFiatSources() uint64
+	OrderFeesInUnits(sell, base bool, rate uint64) (uint64, error)
 }
 
 type basicMMCalculatorImpl struct {
 	*market
 	oracle oracle
-	core   botCoreAdaptor
+	core   basicMMCalculatorCore
 	cfg    *BasicMarketMakingConfig
+	vol    *volatilityTracker
 	log    dex.Logger
 }
 
@@ -326,12 +359,32 @@ func (b *basicMMCalculatorImpl) feeGapStats(basisPrice uint64) (*FeeGapStats, er
 	}, nil
 }
 
+// volatilityHalfSpread calculates the half-spread for GapStrategyVolatility.
+func (b *basicMMCalculatorImpl) volatilityHalfSpread(cfg *VolatilityGapConfig, basisPrice uint64) uint64 {
+	vol, ok := b.vol.volatility(cfg.Candles)
+	if !ok {
+		b.log.Meter("volatility_nodata_"+b.market.name, time.Hour).Infof(
+			"Not enough data to measure volatility for %s. Using ceiling %.4f", b.market.name, cfg.Ceiling,
+		)
+	}
+	halfSpread := cfg.halfSpread(basisPrice, vol, ok)
+	if b.log.Level() == dex.LevelTrace {
+		b.log.Tracef("volatilityHalfSpread: volatility = %.5f, measured = %t, half-spread = %s",
+			vol, ok, b.fmtRate(halfSpread))
+	}
+	return halfSpread
+}
+
 type basicMarketMaker struct {
 	*unifiedExchangeAdaptor
 	core             botCoreAdaptor
 	oracle           oracle
 	rebalanceRunning atomic.Bool
 	calculator       basicMMCalculator
+	volatility       *volatilityTracker
+	// sampleCEXMidGap is set if the CEX market is subscribed so that the CEX
+	// mid-gap can be sampled for volatility measurements.
+	sampleCEXMidGap bool
 }
 
 var _ bot = (*basicMarketMaker)(nil)
@@ -340,26 +393,35 @@ func (m *basicMarketMaker) cfg() *BasicMarketMakingConfig {
 	return m.botCfg().BasicMMConfig
 }
 
-func (m *basicMarketMaker) orderPrice(basisPrice, feeAdj uint64, sell bool, gapFactor float64) uint64 {
+func (m *basicMarketMaker) orderPrice(basisPrice, feeAdj, volAdj uint64, sell bool, gapFactor float64) uint64 {
+	return basicMMOrderPrice(m.cfg(), m.market, basisPrice, feeAdj, volAdj, sell, gapFactor)
+}
+
+// basicMMOrderPrice calculates the rate of a placement using the configured
+// gap strategy. volAdj is the volatility half-spread, and is only used by
+// GapStrategyVolatility.
+func basicMMOrderPrice(cfg *BasicMarketMakingConfig, mkt *market, basisPrice, feeAdj, volAdj uint64, sell bool, gapFactor float64) uint64 {
 	var adj uint64
 
 	// Apply the base strategy.
-	switch m.cfg().GapStrategy {
+	switch cfg.GapStrategy {
 	case GapStrategyMultiplier:
 		adj = uint64(math.Round(float64(feeAdj) * gapFactor))
 	case GapStrategyPercent, GapStrategyPercentPlus:
 		adj = uint64(math.Round(gapFactor * float64(basisPrice)))
 	case GapStrategyAbsolute, GapStrategyAbsolutePlus:
-		adj = m.msgRate(gapFactor)
+		adj = mkt.msgRate(gapFactor)
+	case GapStrategyVolatility:
+		adj = uint64(math.Round(float64(volAdj) * gapFactor))
 	}
 
-	// Add the break-even to the "-plus" strategies
-	switch m.cfg().GapStrategy {
-	case GapStrategyAbsolutePlus, GapStrategyPercentPlus:
+	// Add the break-even to the "-plus" and volatility strategies
+	switch cfg.GapStrategy {
+	case GapStrategyAbsolutePlus, GapStrategyPercentPlus, GapStrategyVolatility:
 		adj += feeAdj
 	}
 
-	adj = steppedRate(adj, m.rateStep.Load())
+	adj = steppedRate(adj, mkt.rateStep.Load())
 
 	if sell {
 		return basisPrice + adj
@@ -372,36 +434,59 @@ func (m *basicMarketMaker) orderPrice(basisPrice, feeAdj uint64, sell bool, gapF
 	return basisPrice - adj
 }
 
-func (m *basicMarketMaker) ordersToPlace() (buyOrders, sellOrders []*TradePlacement, err error) {
-	basisPrice, err := m.calculator.basisPrice()
+func (m *basicMarketMaker) ordersToPlace() (buyOrders, sellOrders []*TradePlacement, skew *InventorySkew, err error) {
+	skewer := newInventorySkewer(m.botCfg().InventorySkew, m.inventoryTotals)
+	buyOrders, sellOrders, feeGap, skew, err := basicMMOrdersToPlace(m.cfg(), m.market, m.calculator, skewer, m.log)
+	if feeGap != nil {
+		m.registerFeeGap(feeGap)
+	}
+	return buyOrders, sellOrders, skew, err
+}
+
+// basicMMOrdersToPlace calculates the placements of a basic market maker. The
+// fee gap stats are returned if they were calculated, even if there is an
+// error. If skewer is non-nil, the basis price and gap factors are adjusted
+// for the bot's inventory and the applied skew is returned.
+func basicMMOrdersToPlace(cfg *BasicMarketMakingConfig, mkt *market, calculator basicMMCalculator, skewer *inventorySkewer, log dex.Logger) (buyOrders, sellOrders []*TradePlacement, feeGap *FeeGapStats, skew *InventorySkew, err error) {
+	basisPrice, err := calculator.basisPrice()
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, nil, err
 	}
 
-	feeGap, err := m.calculator.feeGapStats(basisPrice)
+	feeGap, err = calculator.feeGapStats(basisPrice)
 	if err != nil {
-		return nil, nil, fmt.Errorf("error calculating fee gap stats: %w", err)
+		return nil, nil, nil, nil, fmt.Errorf("error calculating fee gap stats: %w", err)
 	}
 
-	m.registerFeeGap(feeGap)
 	var feeAdj uint64
-	if needBreakEvenHalfSpread(m.cfg().GapStrategy) {
+	if needBreakEvenHalfSpread(cfg.GapStrategy) {
 		feeAdj = feeGap.FeeGap / 2
 	}
 
-	if m.log.Level() == dex.LevelTrace {
-		m.log.Tracef("ordersToPlace %s, basis price = %s, break-even fee adjustment = %s",
-			m.name, m.fmtRate(basisPrice), m.fmtRate(feeAdj))
+	var volAdj uint64
+	if cfg.GapStrategy == GapStrategyVolatility {
+		volAdj = calculator.volatilityHalfSpread(cfg.Volatility, basisPrice)
+	}
+
+	// The fee gap is calculated from the unskewed basis price, since the
+	// break-even spread does not depend on the inventory.
+	skew = skewer.skew(basisPrice)
+	skewedBasisPrice := skew.shiftRate(basisPrice, mkt.rateStep.Load())
+
+	if log.Level() == dex.LevelTrace {
+		log.Tracef("ordersToPlace %s, basis price = %s, skewed basis price = %s, break-even fee adjustment = %s, volatility adjustment = %s",
+			mkt.name, mkt.fmtRate(basisPrice), mkt.fmtRate(skewedBasisPrice), mkt.fmtRate(feeAdj), mkt.fmtRate(volAdj))
 	}
 
 	orders := func(orderPlacements []*OrderPlacement, sell bool) []*TradePlacement {
 		placements := make([]*TradePlacement, 0, len(orderPlacements))
+		gapMultiplier := skew.gapMultiplier(sell)
 		for i, p := range orderPlacements {
-			rate := m.orderPrice(basisPrice, feeAdj, sell, p.GapFactor)
+			rate := basicMMOrderPrice(cfg, mkt, skewedBasisPrice, feeAdj, volAdj, sell, p.GapFactor*gapMultiplier)
 
-			if m.log.Level() == dex.LevelTrace {
-				m.log.Tracef("ordersToPlace.orders: %s placement # %d, gap factor = %f, rate = %s, %+v",
-					sellStr(sell), i, p.GapFactor, m.fmtRate(rate), rate)
+			if log.Level() == dex.LevelTrace {
+				log.Tracef("ordersToPlace.orders: %s placement # %d, gap factor = %f, gap multiplier = %f, rate = %s, %+v",
+					sellStr(sell), i, p.GapFactor, gapMultiplier, mkt.fmtRate(rate), rate)
 			}
 
 			lots := p.Lots
@@ -416,9 +501,9 @@ func (m *basicMarketMaker) ordersToPlace() (buyOrders, sellOrders []*TradePlacem
 		return placements
 	}
 
-	buyOrders = orders(m.cfg().BuyPlacements, false)
-	sellOrders = orders(m.cfg().SellPlacements, true)
-	return buyOrders, sellOrders, nil
+	buyOrders = orders(cfg.BuyPlacements, false)
+	sellOrders = orders(cfg.SellPlacements, true)
+	return buyOrders, sellOrders, feeGap, skew, nil
 }
 
 func (m *basicMarketMaker) rebalance(newEpoch uint64) {
@@ -429,13 +514,18 @@ func (m *basicMarketMaker) rebalance(newEpoch uint64) {
 
 	m.log.Tracef("rebalance: epoch %d", newEpoch)
 
+	if m.sampleCEXMidGap {
+		botCfg := m.botCfg()
+		m.volatility.addMidGap(time.Now(), m.CEX.MidGap(botCfg.CEXBaseID, botCfg.CEXQuoteID))
+	}
+
 	if !m.checkBotHealth(newEpoch) {
 		m.tryCancelOrders(m.ctx, &newEpoch, false)
 		return
 	}
 
 	var buysReport, sellsReport *OrderReport
-	buyOrders, sellOrders, determinePlacementsErr := m.ordersToPlace()
+	buyOrders, sellOrders, skew, determinePlacementsErr := m.ordersToPlace()
 	if determinePlacementsErr != nil {
 		m.tryCancelOrders(m.ctx, &newEpoch, false)
 	} else {
@@ -444,9 +534,10 @@ func (m *basicMarketMaker) rebalance(newEpoch uint64) {
 	}
 
 	epochReport := &EpochReport{
-		BuysReport:  buysReport,
-		SellsReport: sellsReport,
-		EpochNum:    newEpoch,
+		BuysReport:    buysReport,
+		SellsReport:   sellsReport,
+		InventorySkew: skew,
+		EpochNum:      newEpoch,
 	}
 	epochReport.setPreOrderProblems(determinePlacementsErr)
 	m.updateEpochReport(epochReport)
@@ -458,16 +549,41 @@ func (m *basicMarketMaker) botLoop(ctx context.Context) (*sync.WaitGroup, error)
 		return nil, fmt.Errorf("failed to sync book: %v", err)
 	}
 
+	m.volatility = newVolatilityTracker()
 	m.calculator = &basicMMCalculatorImpl{
 		market: m.market,
 		oracle: m.oracle,
 		core:   m.core,
 		cfg:    m.cfg(),
+		vol:    m.volatility,
 		log:    m.log,
 	}
 
-	// Process book updates
 	var wg sync.WaitGroup
+
+	// The volatility gap strategy measures volatility from the DEX candles
+	// and, if the bot has a CEX, the CEX mid-gap.
+	if m.cfg().GapStrategy == GapStrategyVolatility {
+		if err := bookFeed.Candles(volatilityCandleDur); err != nil {
+			m.log.Errorf("Error subscribing to %s candles: %v", volatilityCandleDur, err)
+		}
+		if m.CEX != nil {
+			botCfg := m.botCfg()
+			if err := m.SubscribeMarket(ctx, botCfg.CEXBaseID, botCfg.CEXQuoteID); err != nil {
+				m.log.Errorf("Error subscribing to CEX market for volatility measurements: %v", err)
+			} else {
+				m.sampleCEXMidGap = true
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					<-ctx.Done()
+					m.UnsubscribeMarket(botCfg.CEXBaseID, botCfg.CEXQuoteID)
+				}()
+			}
+		}
+	}
+
+	// Process book updates
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
@@ -480,9 +596,17 @@ func (m *basicMarketMaker) botLoop(ctx context.Context) (*sync.WaitGroup, error)
 					m.kill()
 					return
 				}
-				switch epoch := ni.Payload.(type) {
+				switch payload := ni.Payload.(type) {
 				case *core.ResolvedEpoch:
-					m.rebalance(epoch.Current)
+					m.rebalance(payload.Current)
+				case *core.CandlesPayload:
+					if payload.Dur == volatilityCandleDur {
+						m.volatility.setCandles(payload.Candles)
+					}
+				case core.CandleUpdate:
+					if payload.Dur == volatilityCandleDur {
+						m.volatility.addCandle(payload.Candle)
+					}
 				}
 			case <-ctx.Done():
 				return
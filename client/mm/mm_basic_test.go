@@ -14,7 +14,8 @@ type tBasicMMCalculator struct {
 	bp    uint64
 	bpErr error
 
-	hs uint64
+	hs  uint64
+	vhs uint64
 }
 
 var _ basicMMCalculator = (*tBasicMMCalculator)(nil)
@@ -29,6 +30,10 @@ func (r *tBasicMMCalculator) halfSpread(basisPrice uint64) (uint64, error) {
 func (r *tBasicMMCalculator) feeGapStats(basisPrice uint64) (*FeeGapStats, error) {
 	return &FeeGapStats{FeeGap: r.hs * 2}, nil
 }
+
+func (r *tBasicMMCalculator) volatilityHalfSpread(cfg *VolatilityGapConfig, basisPrice uint64) uint64 {
+	return r.vhs
+}
 func TestBasisPrice(t *testing.T) {
 	mkt := &core.Market{
 		RateStep:   1,
@@ -245,12 +250,14 @@ func TestUpdateLotSize(t *testing.T) {
 func TestBasicMMRebalance(t *testing.T) {
 	const basisPrice uint64 = 5e6
 	const halfSpread uint64 = 2e5
+	const volHalfSpread uint64 = 1e5
 	const rateStep uint64 = 1e3
 	const atomToConv float64 = 1
 
 	calculator := &tBasicMMCalculator{
-		bp: basisPrice,
-		hs: halfSpread,
+		bp:  basisPrice,
+		hs:  halfSpread,
+		vhs: volHalfSpread,
 	}
 
 	type test struct {
@@ -381,6 +388,30 @@ func TestBasicMMRebalance(t *testing.T) {
 				{Lots: 1, Rate: steppedRate(basisPrice+halfSpread+1e6, rateStep)},
 			},
 		},
+		{
+			name:     "volatility",
+			strategy: GapStrategyVolatility,
+			cfgBuyPlacements: []*OrderPlacement{
+				{Lots: 1, GapFactor: 1},
+				{Lots: 2, GapFactor: 2},
+				{Lots: 3, GapFactor: 4},
+			},
+			cfgSellPlacements: []*OrderPlacement{
+				{Lots: 3, GapFactor: 4},
+				{Lots: 2, GapFactor: 2},
+				{Lots: 1, GapFactor: 1},
+			},
+			expBuyPlacements: []*TradePlacement{
+				{Lots: 1, Rate: steppedRate(basisPrice-halfSpread-volHalfSpread, rateStep)},
+				{Lots: 2, Rate: steppedRate(basisPrice-halfSpread-2*volHalfSpread, rateStep)},
+				{Lots: 3, Rate: steppedRate(basisPrice-halfSpread-4*volHalfSpread, rateStep)},
+			},
+			expSellPlacements: []*TradePlacement{
+				{Lots: 3, Rate: steppedRate(basisPrice+halfSpread+4*volHalfSpread, rateStep)},
+				{Lots: 2, Rate: steppedRate(basisPrice+halfSpread+2*volHalfSpread, rateStep)},
+				{Lots: 1, Rate: steppedRate(basisPrice+halfSpread+volHalfSpread, rateStep)},
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -436,6 +467,7 @@ func TestBasicMMRebalance(t *testing.T) {
 					GapStrategy:    tt.strategy,
 					BuyPlacements:  tt.cfgBuyPlacements,
 					SellPlacements: tt.cfgSellPlacements,
+					Volatility:     &VolatilityGapConfig{Candles: 24, Ceiling: 0.05},
 				}})
 
 			mm.rebalance(100)
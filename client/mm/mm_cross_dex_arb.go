@@ -0,0 +1,625 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package mm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"decred.org/dcrdex/client/core"
+	"decred.org/dcrdex/dex"
+	"decred.org/dcrdex/dex/calc"
+	"decred.org/dcrdex/dex/order"
+)
+
+// CrossDEXArbConfig is the configuration for a bot that arbitrages the bot's
+// market against the same market on a second DEX host. Orders on the counter
+// host are the counter-trades of the bot's orders, like the CEX trades of
+// the SimpleArbConfig bot. Both hosts' orders are funded by the same wallets.
+type CrossDEXArbConfig struct {
+	// CounterHost is the second DEX host. It must list the bot's market.
+	CounterHost string `json:"counterHost"`
+	// ProfitTrigger is the minimum profit, after fees, as a ratio of the
+	// value of the base asset traded, before an arbitrage is initiated.
+	// Range: 0 < ProfitTrigger << 1.
+	ProfitTrigger float64 `json:"profitTrigger"`
+	// MaxActiveArbs sets a limit on the number of active arbitrage sequences
+	// that can be open simultaneously. A sequence is active until the swaps
+	// of both of its orders settle.
+	MaxActiveArbs uint32 `json:"maxActiveArbs"`
+	// NumEpochsLeaveOpen is the number of epochs an arbitrage sequence will
+	// stay open if one or both of the orders were not filled.
+	NumEpochsLeaveOpen uint32 `json:"numEpochsLeaveOpen"`
+	// SettlementTime is the expected time, in seconds, for the swaps of both
+	// orders to settle. Unlike a CEX trade, the bot's position on either
+	// host is not final until its swaps settle, and the price may move in
+	// the meantime.
+	SettlementTime uint64 `json:"settlementTime"`
+	// SettlementRiskFactor is the number of standard deviations of the
+	// price movement expected over the SettlementTime that is added to the
+	// ProfitTrigger. The expected price movement is measured from the
+	// realized volatility of the bot's market. If zero, the settlement time
+	// risk is not considered.
+	SettlementRiskFactor float64 `json:"settlementRiskFactor"`
+}
+
+func (c *CrossDEXArbConfig) copy() *CrossDEXArbConfig {
+	cfg := *c
+	return &cfg
+}
+
+func (c *CrossDEXArbConfig) validate(host string) error {
+	if c.CounterHost == "" || c.CounterHost == host {
+		return fmt.Errorf("counter host must be a different DEX host")
+	}
+
+	if c.ProfitTrigger <= 0 || c.ProfitTrigger > 1 {
+		return fmt.Errorf("profit trigger must be 0 < t <= 1, but got %v", c.ProfitTrigger)
+	}
+
+	if c.MaxActiveArbs == 0 {
+		return fmt.Errorf("must allow at least 1 active arb")
+	}
+
+	if c.NumEpochsLeaveOpen < 2 {
+		return fmt.Errorf("arbs must be left open for at least 2 epochs")
+	}
+
+	if c.SettlementRiskFactor < 0 {
+		return fmt.Errorf("settlement risk factor must be non-negative, but got %v", c.SettlementRiskFactor)
+	}
+
+	if c.SettlementRiskFactor > 0 && c.SettlementTime == 0 {
+		return fmt.Errorf("settlement time must be set to use a settlement risk factor")
+	}
+
+	return nil
+}
+
+// maxCrossDEXArbUnits is the maximum number of units, multiples of the lot
+// sizes of both hosts, that are checked when looking for the most profitable
+// quantity to arbitrage.
+const maxCrossDEXArbUnits = 100
+
+// crossDEXArb represents an attempted arbitrage sequence.
+type crossDEXArb struct {
+	dexOrder     *core.Order
+	counterOrder *core.Order
+	sellOnDEX    bool
+	startEpoch   uint64
+	// canceled is set once the booked orders of the arb have been canceled.
+	// A canceled arb is kept until the swaps of any matches settle.
+	canceled bool
+}
+
+// filled is true if neither of the arb's orders is booked.
+func (a *crossDEXArb) filled() bool {
+	return a.dexOrder.Status > order.OrderStatusBooked && a.counterOrder.Status > order.OrderStatusBooked
+}
+
+// unmatched is true if neither of the arb's orders has been matched.
+func (a *crossDEXArb) unmatched() bool {
+	return len(a.dexOrder.Matches) == 0 && len(a.counterOrder.Matches) == 0
+}
+
+// complete is true if neither of the arb's orders is booked and the swaps of
+// all of their matches have settled.
+func (a *crossDEXArb) complete() bool {
+	return dexOrderComplete(a.dexOrder) && dexOrderComplete(a.counterOrder)
+}
+
+// crossDEXArbOpportunity is a profitable pair of trades.
+type crossDEXArbOpportunity struct {
+	sellOnDEX   bool
+	qty         uint64
+	dexRate     uint64
+	counterRate uint64
+	profitUSD   float64
+}
+
+// crossDEXHost is the lot size, book, and fee estimates of the market on one
+// of the hosts.
+type crossDEXHost struct {
+	lotSize   uint64
+	book      dexOrderBook
+	baseFees  *LotFees
+	quoteFees *LotFees
+}
+
+// orderFees returns the swap and redeem fees of an order, in the fee assets
+// of the base and quote assets.
+func (h *crossDEXHost) orderFees(sell bool, qty uint64) (baseFees, quoteFees uint64) {
+	lots := qty / h.lotSize
+	if sell {
+		return lots * h.baseFees.Swap, lots * h.quoteFees.Redeem
+	}
+	return lots * h.baseFees.Redeem, lots * h.quoteFees.Swap
+}
+
+type crossDEXArbBot struct {
+	*unifiedExchangeAdaptor
+	rebalanceRunning atomic.Bool
+	volatility       *volatilityTracker
+
+	hostsMtx sync.RWMutex
+	dexHost  *crossDEXHost
+	counter  *crossDEXHost
+
+	activeArbsMtx sync.RWMutex
+	// activeArbs are keyed by sellOnDEX.
+	activeArbs map[bool][]*crossDEXArb
+}
+
+var _ bot = (*crossDEXArbBot)(nil)
+
+func (a *crossDEXArbBot) cfg() *CrossDEXArbConfig {
+	return a.botCfg().CrossDEXArbConfig
+}
+
+// requiredProfit is the ProfitTrigger plus the settlement time risk
+// premium. ok is false if the volatility of the market cannot be measured
+// yet.
+func (a *crossDEXArbBot) requiredProfit() (profit float64, ok bool) {
+	cfg := a.cfg()
+	if cfg.SettlementRiskFactor == 0 {
+		return cfg.ProfitTrigger, true
+	}
+	vol, ok := a.volatility.volatility(defaultVolatilityCandles)
+	if !ok {
+		return 0, false
+	}
+	periods := float64(cfg.SettlementTime*1000) / float64(volatilityCandleMs)
+	return cfg.ProfitTrigger + cfg.SettlementRiskFactor*vol*math.Sqrt(periods), true
+}
+
+// sufficientBalance checks whether the bot has the balance to place both
+// orders of an arb simultaneously.
+func (a *crossDEXArbBot) sufficientBalance(sellOnDEX bool, qty, dexRate, counterRate uint64, dexHost, counter *crossDEXHost) bool {
+	baseFeeID, quoteFeeID := feeAssetID(a.dexBaseID), feeAssetID(a.dexQuoteID)
+	reqs := make(map[uint32]uint64)
+	addReqs := func(h *crossDEXHost, sell bool, rate uint64) {
+		if sell {
+			reqs[a.dexBaseID] += qty
+			reqs[baseFeeID] += qty / h.lotSize * h.baseFees.Swap
+		} else {
+			reqs[a.dexQuoteID] += calc.BaseToQuote(rate, qty)
+			reqs[quoteFeeID] += qty / h.lotSize * h.quoteFees.Swap
+		}
+	}
+	addReqs(dexHost, sellOnDEX, dexRate)
+	addReqs(counter, !sellOnDEX, counterRate)
+	for assetID, req := range reqs {
+		if a.DEXBalance(assetID).Available < req {
+			return false
+		}
+	}
+	return true
+}
+
+// arbExistsOnSide checks if an arbitrage opportunity exists either when
+// buying or selling on the bot's host.
+func (a *crossDEXArbBot) arbExistsOnSide(sellOnDEX bool, minProfit float64) (*crossDEXArbOpportunity, error) {
+	a.hostsMtx.RLock()
+	dexHost, counter := a.dexHost, a.counter
+	a.hostsMtx.RUnlock()
+
+	fiatRates := a.fiatRates.Load().(map[uint32]float64)
+
+	// The quantity must be a multiple of the lot sizes of both hosts.
+	unit := dexHost.lotSize / gcd(dexHost.lotSize, counter.lotSize) * counter.lotSize
+
+	var best *crossDEXArbOpportunity
+	for units := uint64(1); units <= maxCrossDEXArbUnits; units++ {
+		qty := units * unit
+		dexAvg, dexExtrema, dexFilled, err := dexHost.book.VWAP(qty/dexHost.lotSize, dexHost.lotSize, !sellOnDEX)
+		if err != nil {
+			return nil, fmt.Errorf("error calculating dex VWAP: %w", err)
+		}
+		counterAvg, counterExtrema, counterFilled, err := counter.book.VWAP(qty/counter.lotSize, counter.lotSize, sellOnDEX)
+		if err != nil {
+			return nil, fmt.Errorf("error calculating counter host VWAP: %w", err)
+		}
+		if !dexFilled || !counterFilled {
+			break
+		}
+
+		buyAvg, sellAvg := dexAvg, counterAvg
+		if sellOnDEX {
+			buyAvg, sellAvg = counterAvg, dexAvg
+		}
+		quoteForBuy, quoteFromSell := calc.BaseToQuote(buyAvg, qty), calc.BaseToQuote(sellAvg, qty)
+		if quoteFromSell <= quoteForBuy {
+			break
+		}
+
+		if !a.sufficientBalance(sellOnDEX, qty, dexExtrema, counterExtrema, dexHost, counter) {
+			break
+		}
+
+		dexBaseFees, dexQuoteFees := dexHost.orderFees(sellOnDEX, qty)
+		counterBaseFees, counterQuoteFees := counter.orderFees(!sellOnDEX, qty)
+		grossUSD, err := usdValue(fiatRates, a.dexQuoteID, quoteFromSell-quoteForBuy)
+		if err != nil {
+			return nil, err
+		}
+		baseFeesUSD, err := usdValue(fiatRates, feeAssetID(a.dexBaseID), dexBaseFees+counterBaseFees)
+		if err != nil {
+			return nil, err
+		}
+		quoteFeesUSD, err := usdValue(fiatRates, feeAssetID(a.dexQuoteID), dexQuoteFees+counterQuoteFees)
+		if err != nil {
+			return nil, err
+		}
+		qtyUSD, err := usdValue(fiatRates, a.dexBaseID, qty)
+		if err != nil {
+			return nil, err
+		}
+		profitUSD := grossUSD - baseFeesUSD - quoteFeesUSD
+		if profitUSD/qtyUSD < minProfit || (best != nil && profitUSD < best.profitUSD) {
+			break
+		}
+
+		best = &crossDEXArbOpportunity{
+			sellOnDEX:   sellOnDEX,
+			qty:         qty,
+			dexRate:     dexExtrema,
+			counterRate: counterExtrema,
+			profitUSD:   profitUSD,
+		}
+	}
+
+	if best != nil {
+		a.log.Infof("cross-DEX arb opportunity - sellOnDex: %t, qty: %s, dexRate: %s, counterRate: %s, profit: %.2f USD",
+			sellOnDEX, a.fmtBase(best.qty), a.fmtRate(best.dexRate), a.fmtRate(best.counterRate), best.profitUSD)
+	}
+
+	return best, nil
+}
+
+// arbExists checks if an arbitrage opportunity exists.
+func (a *crossDEXArbBot) arbExists() (*crossDEXArbOpportunity, error) {
+	minProfit, ok := a.requiredProfit()
+	if !ok {
+		a.log.Debugf("not enough data to measure settlement risk")
+		return nil, nil
+	}
+	for _, sellOnDEX := range []bool{false, true} {
+		opp, err := a.arbExistsOnSide(sellOnDEX, minProfit)
+		if err != nil || opp != nil {
+			return opp, err
+		}
+	}
+	return nil, nil
+}
+
+// numActiveArbs returns the number of active arbs. The activeArbsMtx must be
+// held.
+func (a *crossDEXArbBot) numActiveArbs() int {
+	return len(a.activeArbs[false]) + len(a.activeArbs[true])
+}
+
+// executeArb places the orders on the counter host and the bot's host. An
+// entry will be added to a.activeArbs if both orders are successfully placed.
+// executeArb returns false if the orders were not placed.
+func (a *crossDEXArbBot) executeArb(opp *crossDEXArbOpportunity, epoch uint64) bool {
+	a.activeArbsMtx.Lock()
+	defer a.activeArbsMtx.Unlock()
+
+	if a.numActiveArbs() >= int(a.cfg().MaxActiveArbs) {
+		a.log.Info("cannot execute arb because already at max arbs")
+		return false
+	}
+
+	// Orders of arbs in the opposite direction could match the new orders.
+	// They are canceled, and the arb can be executed once the cancels are
+	// processed.
+	for _, arb := range a.activeArbs[!opp.sellOnDEX] {
+		if !arb.canceled && !arb.filled() {
+			a.log.Info("cannot execute arb opportunity due to self-match")
+			a.cancelArbs(!opp.sellOnDEX, func(*crossDEXArb) bool { return true })
+			return false
+		}
+	}
+
+	// Place the counter host order first. If placing the order on the bot's
+	// host fails, the counter host order can be canceled.
+	counterHost := a.cfg().CounterHost
+	counterOrder, err := a.dexTradeOnMarket(counterHost, a.dexBaseID, a.dexQuoteID, opp.counterRate, opp.qty, !opp.sellOnDEX)
+	if err != nil {
+		a.log.Errorf("error placing order on %s: %v", counterHost, err)
+		return false
+	}
+
+	dexOrder, err := a.dexTradeOnMarket(a.host, a.dexBaseID, a.dexQuoteID, opp.dexRate, opp.qty, opp.sellOnDEX)
+	if err != nil {
+		a.log.Errorf("error placing dex order: %v", err)
+		if err := a.Cancel(counterOrder.ID); err != nil {
+			a.log.Errorf("error canceling order %s on %s: %v", counterOrder.ID, counterHost, err)
+		}
+		return false
+	}
+
+	if a.activeArbs == nil {
+		a.activeArbs = make(map[bool][]*crossDEXArb, 2)
+	}
+	a.activeArbs[opp.sellOnDEX] = append(a.activeArbs[opp.sellOnDEX], &crossDEXArb{
+		dexOrder:     dexOrder,
+		counterOrder: counterOrder,
+		sellOnDEX:    opp.sellOnDEX,
+		startEpoch:   epoch,
+	})
+	return true
+}
+
+// cancelArb cancels the orders of an arb that are still booked. cancelArb
+// returns true if the arb can be dropped because neither order was matched.
+// Otherwise, the arb is marked canceled and kept until its swaps settle.
+func (a *crossDEXArbBot) cancelArb(arb *crossDEXArb) bool {
+	for _, o := range []*core.Order{arb.dexOrder, arb.counterOrder} {
+		if o.Status > order.OrderStatusBooked {
+			continue
+		}
+		if err := a.Cancel(o.ID); err != nil {
+			a.log.Errorf("failed to cancel order %s on %s: %v", o.ID, o.Host, err)
+		}
+	}
+	arb.canceled = true
+	return arb.unmatched()
+}
+
+// cancelArbs cancels the booked orders of the arbs on a side that match the
+// filter. Arbs that are filled, or that were already canceled, are skipped.
+// The activeArbsMtx must be held.
+func (a *crossDEXArbBot) cancelArbs(sellOnDEX bool, filter func(*crossDEXArb) bool) {
+	arbs := a.activeArbs[sellOnDEX]
+	if len(arbs) == 0 {
+		return
+	}
+	remaining := make([]*crossDEXArb, 0, len(arbs))
+	for _, arb := range arbs {
+		if arb.canceled || arb.filled() || !filter(arb) || !a.cancelArb(arb) {
+			remaining = append(remaining, arb)
+		}
+	}
+	a.activeArbs[sellOnDEX] = remaining
+}
+
+// handleDEXOrderUpdate is called when either host sends a notification that
+// the status of an order has changed.
+func (a *crossDEXArbBot) handleDEXOrderUpdate(o *core.Order) {
+	a.activeArbsMtx.Lock()
+	defer a.activeArbsMtx.Unlock()
+
+	for sellOnDEX, arbs := range a.activeArbs {
+		for i, arb := range arbs {
+			switch {
+			case bytes.Equal(arb.dexOrder.ID, o.ID):
+				arb.dexOrder = o
+			case bytes.Equal(arb.counterOrder.ID, o.ID):
+				arb.counterOrder = o
+			default:
+				continue
+			}
+			// The arb is kept until the swaps of both orders settle, so
+			// that it counts towards the MaxActiveArbs while the bot's
+			// position is still at risk.
+			if arb.complete() {
+				a.activeArbs[sellOnDEX] = append(arbs[:i], arbs[i+1:]...)
+			}
+			return
+		}
+	}
+}
+
+// updateHosts updates the lot sizes and fee estimates of the market on both
+// hosts.
+func (a *crossDEXArbBot) updateHosts() error {
+	counterHost := a.cfg().CounterHost
+	mkt, err := a.ExchangeMarket(counterHost, a.dexBaseID, a.dexQuoteID)
+	if err != nil {
+		return err
+	}
+	baseFees, quoteFees, err := marketFees(a.clientCore, a.host, a.dexBaseID, a.dexQuoteID, false)
+	if err != nil {
+		return err
+	}
+	counterBaseFees, counterQuoteFees, err := marketFees(a.clientCore, counterHost, a.dexBaseID, a.dexQuoteID, false)
+	if err != nil {
+		return err
+	}
+
+	a.hostsMtx.Lock()
+	defer a.hostsMtx.Unlock()
+	a.dexHost = &crossDEXHost{
+		lotSize:   a.lotSize.Load(),
+		book:      a.dexHost.book,
+		baseFees:  baseFees,
+		quoteFees: quoteFees,
+	}
+	a.counter = &crossDEXHost{
+		lotSize:   mkt.LotSize,
+		book:      a.counter.book,
+		baseFees:  counterBaseFees,
+		quoteFees: counterQuoteFees,
+	}
+	return nil
+}
+
+// counterHostHealthy checks that the bot can trade on the counter host.
+func (a *crossDEXArbBot) counterHostHealthy() error {
+	counterHost := a.cfg().CounterHost
+	exchange, err := a.Exchange(counterHost)
+	if err != nil {
+		return fmt.Errorf("error getting exchange %s: %w", counterHost, err)
+	}
+	if exchange.Auth.EffectiveTier <= 0 {
+		return fmt.Errorf("account suspended on %s", counterHost)
+	}
+	userParcels, parcelLimit, err := a.TradingLimits(counterHost)
+	if err != nil {
+		return fmt.Errorf("error getting trading limits on %s: %w", counterHost, err)
+	}
+	if userParcels >= parcelLimit {
+		return fmt.Errorf("trading limit reached on %s", counterHost)
+	}
+	return nil
+}
+
+func (a *crossDEXArbBot) tryArb(newEpoch uint64) (*crossDEXArbOpportunity, error) {
+	if !(a.checkBotHealth(newEpoch) && a.tradingLimitNotReached(newEpoch)) {
+		return nil, nil
+	}
+
+	if err := a.counterHostHealthy(); err != nil {
+		return nil, err
+	}
+
+	if err := a.updateHosts(); err != nil {
+		return nil, fmt.Errorf("error updating markets: %w", err)
+	}
+
+	opp, err := a.arbExists()
+	if err != nil || opp == nil {
+		return nil, err
+	}
+
+	if !a.executeArb(opp, newEpoch) {
+		return nil, nil
+	}
+	return opp, nil
+}
+
+// rebalance checks if there is an arbitrage opportunity between the hosts,
+// and if so, executes trades to capitalize on it.
+func (a *crossDEXArbBot) rebalance(newEpoch uint64) {
+	if !a.rebalanceRunning.CompareAndSwap(false, true) {
+		return
+	}
+	defer a.rebalanceRunning.Store(false)
+	a.log.Tracef("rebalance: epoch %d", newEpoch)
+
+	a.hostsMtx.RLock()
+	book := a.dexHost.book
+	a.hostsMtx.RUnlock()
+	if midGap, err := book.MidGap(); err == nil {
+		a.volatility.addMidGap(time.Now(), midGap)
+	}
+
+	// Cancel the orders of expired arbs before looking for a new arb, so
+	// that a stale order does not block an arb in the opposite direction.
+	expired := func(arb *crossDEXArb) bool {
+		return newEpoch-arb.startEpoch > uint64(a.cfg().NumEpochsLeaveOpen)
+	}
+	a.activeArbsMtx.Lock()
+	a.cancelArbs(false, expired)
+	a.cancelArbs(true, expired)
+	a.activeArbsMtx.Unlock()
+
+	epochReport := &EpochReport{EpochNum: newEpoch}
+	if _, err := a.tryArb(newEpoch); err != nil {
+		epochReport.setPreOrderProblems(err)
+	}
+	a.unifiedExchangeAdaptor.updateEpochReport(epochReport)
+}
+
+func (a *crossDEXArbBot) botLoop(ctx context.Context) (*sync.WaitGroup, error) {
+	counterHost := a.cfg().CounterHost
+	book, bookFeed, err := a.SyncBook(a.host, a.dexBaseID, a.dexQuoteID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sync book: %v", err)
+	}
+	counterBook, counterFeed, err := a.SyncBook(counterHost, a.dexBaseID, a.dexQuoteID)
+	if err != nil {
+		bookFeed.Close()
+		return nil, fmt.Errorf("failed to sync %s book: %v", counterHost, err)
+	}
+
+	a.hostsMtx.Lock()
+	a.dexHost = &crossDEXHost{book: book}
+	a.counter = &crossDEXHost{book: counterBook}
+	a.hostsMtx.Unlock()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer bookFeed.Close()
+		for {
+			select {
+			case ni, ok := <-bookFeed.Next():
+				if !ok {
+					a.log.Error("Stopping bot due to nil book feed.")
+					a.kill()
+					return
+				}
+				switch epoch := ni.Payload.(type) {
+				case *core.ResolvedEpoch:
+					a.rebalance(epoch.Current)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// The counter host's book is kept up to date by core, but the feed
+	// must be drained.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer counterFeed.Close()
+		for {
+			select {
+			case _, ok := <-counterFeed.Next():
+				if !ok {
+					a.log.Errorf("Stopping bot due to nil %s book feed.", counterHost)
+					a.kill()
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		orderUpdates := a.SubscribeOrderUpdates()
+		for {
+			select {
+			case n := <-orderUpdates:
+				a.handleDEXOrderUpdate(n)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return &wg, nil
+}
+
+func newCrossDEXArbBot(cfg *BotConfig, adaptorCfg *exchangeAdaptorCfg, log dex.Logger) (*crossDEXArbBot, error) {
+	if cfg.CrossDEXArbConfig == nil {
+		// implies bug in caller
+		return nil, fmt.Errorf("no cross-DEX arb config provided")
+	}
+
+	adaptor, err := newUnifiedExchangeAdaptor(adaptorCfg)
+	if err != nil {
+		return nil, fmt.Errorf("error constructing exchange adaptor: %w", err)
+	}
+
+	a := &crossDEXArbBot{
+		unifiedExchangeAdaptor: adaptor,
+		volatility:             newVolatilityTracker(),
+	}
+	adaptor.setBotLoop(a.botLoop)
+	return a, nil
+}
@@ -0,0 +1,222 @@
+//go:build !harness && !botlive
+
+package mm
+
+import (
+	"testing"
+	"time"
+
+	"decred.org/dcrdex/client/core"
+	"decred.org/dcrdex/dex/order"
+)
+
+func TestCrossDEXArb(t *testing.T) {
+	const dcrID, btcID = 42, 0
+	const counterHost = "counter.dex"
+	const epochsOpen = 2
+
+	cfg := &CrossDEXArbConfig{
+		CounterHost:        counterHost,
+		ProfitTrigger:      0.05,
+		MaxActiveArbs:      1,
+		NumEpochsLeaveOpen: epochsOpen,
+	}
+	if err := cfg.validate("dex.com"); err != nil {
+		t.Fatalf("unexpected error validating config: %v", err)
+	}
+	for _, c := range []*CrossDEXArbConfig{
+		{CounterHost: "dex.com", ProfitTrigger: 0.05, MaxActiveArbs: 1, NumEpochsLeaveOpen: 2},
+		{CounterHost: counterHost, ProfitTrigger: 0, MaxActiveArbs: 1, NumEpochsLeaveOpen: 2},
+		{CounterHost: counterHost, ProfitTrigger: 0.05, MaxActiveArbs: 0, NumEpochsLeaveOpen: 2},
+		{CounterHost: counterHost, ProfitTrigger: 0.05, MaxActiveArbs: 1, NumEpochsLeaveOpen: 1},
+		{CounterHost: counterHost, ProfitTrigger: 0.05, MaxActiveArbs: 1, NumEpochsLeaveOpen: 2, SettlementRiskFactor: 1},
+	} {
+		if err := c.validate("dex.com"); err == nil {
+			t.Fatalf("no error for invalid config %+v", c)
+		}
+	}
+
+	// DCR = $20, BTC = $50,000. The bids on the bot's host are 10% above the
+	// asks on the counter host. The counter host's lot size is half of the
+	// bot's host's.
+	fiatRates := map[uint32]float64{dcrID: 20, btcID: 50_000}
+	mkt := &core.Market{BaseID: dcrID, QuoteID: btcID, LotSize: 1e8, RateStep: 1e2, AtomToConv: 1}
+	counterMkt := &core.Market{BaseID: dcrID, QuoteID: btcID, LotSize: 5e7, RateStep: 1e2, AtomToConv: 1}
+	const fee = 100
+	lotFees := &LotFees{Swap: fee, Redeem: fee, Refund: fee}
+	dexBook := &tOrderBook{
+		bidsVWAP: map[uint64]vwapResult{1: {4.4e4, 4.4e4}, 2: {4.1e4, 4e4}},
+		asksVWAP: map[uint64]vwapResult{1: {4.5e4, 4.5e4}},
+	}
+	counterBook := &tOrderBook{
+		bidsVWAP: map[uint64]vwapResult{2: {4e4, 4e4}},
+		asksVWAP: map[uint64]vwapResult{2: {4e4, 4e4}, 4: {4e4, 4e4}},
+	}
+
+	newBot := func(failOn int) (*crossDEXArbBot, *tTriangleCore) {
+		u := mustParseAdaptorFromMarket(mkt)
+		tc := &tTriangleCore{
+			tCore:   u.clientCore.(*tCore),
+			markets: map[[2]uint32]*core.Market{{dcrID, btcID}: counterMkt},
+			failOn:  failOn,
+		}
+		tc.parcelLimit = 1
+		tc.singleLotSellFees = &OrderFees{LotFeeRange: &LotFeeRange{Max: lotFees}}
+		tc.singleLotBuyFees = &OrderFees{LotFeeRange: &LotFeeRange{Max: lotFees}}
+		u.clientCore = tc
+		u.fiatRates.Store(fiatRates)
+		u.baseDexBalances = map[uint32]int64{dcrID: 10e8, btcID: 1e6}
+		u.botCfgV.Store(&BotConfig{
+			Host:              u.host,
+			BaseID:            dcrID,
+			QuoteID:           btcID,
+			CrossDEXArbConfig: cfg,
+		})
+		bot := &crossDEXArbBot{
+			unifiedExchangeAdaptor: u,
+			volatility:             newVolatilityTracker(),
+			dexHost:                &crossDEXHost{book: dexBook},
+			counter:                &crossDEXHost{book: counterBook},
+		}
+		if err := bot.updateHosts(); err != nil {
+			t.Fatalf("error updating hosts: %v", err)
+		}
+		return bot, tc
+	}
+
+	bot, tc := newBot(0)
+
+	// Selling 1 DCR on the bot's host and buying it on the counter host
+	// profits $2, less fees. Selling 2 DCR is not profitable enough.
+	const expProfit = 2 - (fee*20+fee*50_000)/1e8 - 2*(fee*20+fee*50_000)/1e8
+	opp, err := bot.arbExists()
+	if err != nil || opp == nil || !opp.sellOnDEX || opp.qty != 1e8 || opp.dexRate != 4.4e4 || opp.counterRate != 4e4 {
+		t.Fatalf("wrong opportunity %+v, err = %v", opp, err)
+	}
+	if opp.profitUSD < expProfit-1e-9 || opp.profitUSD > expProfit+1e-9 {
+		t.Fatalf("wrong profit. wanted %f, got %f", expProfit, opp.profitUSD)
+	}
+	cfg.ProfitTrigger = 0.1
+	if opp, _ := bot.arbExists(); opp != nil {
+		t.Fatalf("opportunity found below profit trigger")
+	}
+
+	// The settlement risk premium requires a measurable volatility. 5%
+	// moves every 5 minutes with a 5 minute settlement time raise the
+	// required profit by ~4.9%.
+	cfg.ProfitTrigger = 0.04
+	cfg.SettlementTime = 300
+	cfg.SettlementRiskFactor = 1
+	if opp, _ := bot.arbExists(); opp != nil {
+		t.Fatalf("opportunity found without volatility")
+	}
+	stamp := time.Now()
+	for i, rate := range []uint64{1e6, 1.05e6, 1e6, 1.05e6} {
+		bot.volatility.addMidGap(stamp.Add(time.Duration(i)*5*time.Minute), rate)
+	}
+	if opp, _ := bot.arbExists(); opp == nil {
+		t.Fatalf("no opportunity with settlement risk premium")
+	}
+	cfg.ProfitTrigger = 0.05
+	if opp, _ := bot.arbExists(); opp != nil {
+		t.Fatalf("opportunity found below settlement risk premium")
+	}
+	cfg.SettlementRiskFactor = 0
+
+	bot.rebalance(1)
+	if len(tc.multiTradesPlaced) != 2 {
+		t.Fatalf("expected 2 trades, got %d", len(tc.multiTradesPlaced))
+	}
+	counterForm, dexForm := tc.multiTradesPlaced[0], tc.multiTradesPlaced[1]
+	if counterForm.Host != counterHost || counterForm.Sell || counterForm.Placements[0].Rate != 4e4 {
+		t.Fatalf("wrong counter host trade %+v", counterForm)
+	}
+	if dexForm.Host != bot.host || !dexForm.Sell || dexForm.Placements[0].Rate != 4.4e4 {
+		t.Fatalf("wrong dex trade %+v", dexForm)
+	}
+
+	// At max active arbs.
+	bot.rebalance(2)
+	if len(tc.multiTradesPlaced) != 2 {
+		t.Fatalf("trade placed with max active arbs")
+	}
+
+	// A filled arb is kept until the swaps of both orders settle.
+	updateOrders := func(orders []*core.Order, update func(o *core.Order)) {
+		t.Helper()
+		for _, o := range orders {
+			u := *o
+			update(&u)
+			bot.handleDEXOrderUpdate(&u)
+		}
+	}
+	updateOrders(tc.orders, func(o *core.Order) {
+		o.Status = order.OrderStatusExecuted
+		o.Matches = []*core.Match{{Active: true}}
+	})
+	if bot.numActiveArbs() != 1 {
+		t.Fatalf("filled arb removed before settlement")
+	}
+	bot.rebalance(10)
+	if len(tc.multiTradesPlaced) != 2 || len(tc.cancelsPlaced) != 0 || bot.numActiveArbs() != 1 {
+		t.Fatalf("settling arb expired")
+	}
+	updateOrders(tc.orders, func(o *core.Order) {
+		o.Status = order.OrderStatusExecuted
+		o.Matches = []*core.Match{{}}
+		o.AllFeesConfirmed = true
+	})
+	if bot.numActiveArbs() != 0 {
+		t.Fatalf("settled arb not removed")
+	}
+
+	// Unfilled orders are canceled after NumEpochsLeaveOpen.
+	bot.rebalance(10)
+	if len(tc.multiTradesPlaced) != 4 {
+		t.Fatalf("expected 4 trades, got %d", len(tc.multiTradesPlaced))
+	}
+	bot.rebalance(10 + epochsOpen)
+	if len(tc.cancelsPlaced) != 0 {
+		t.Fatalf("orders canceled early")
+	}
+	// The expired arb is canceled before looking for a new arb, so it does
+	// not block the next one.
+	bot.rebalance(11 + epochsOpen)
+	if len(tc.cancelsPlaced) != 2 || len(tc.multiTradesPlaced) != 6 || bot.numActiveArbs() != 1 {
+		t.Fatalf("expired arb not canceled before the next arb. cancels = %d, trades = %d, arbs = %d",
+			len(tc.cancelsPlaced), len(tc.multiTradesPlaced), bot.numActiveArbs())
+	}
+
+	// An unfilled arb in the opposite direction is canceled, and the new arb
+	// is executed once the cancels are processed. A partially matched arb is
+	// kept until its swaps settle, but does not block the new arb.
+	bot, tc = newBot(0)
+	oppositeDEX := &core.Order{ID: order.OrderID{0x01}.Bytes(), Status: order.OrderStatusBooked}
+	oppositeCounter := &core.Order{ID: order.OrderID{0x02}.Bytes(), Status: order.OrderStatusBooked,
+		Matches: []*core.Match{{Active: true}}}
+	bot.activeArbs = map[bool][]*crossDEXArb{false: {{
+		dexOrder:     oppositeDEX,
+		counterOrder: oppositeCounter,
+		startEpoch:   1,
+	}}}
+	cfg.MaxActiveArbs = 2
+	bot.rebalance(2)
+	if len(tc.multiTradesPlaced) != 0 || len(tc.cancelsPlaced) != 2 {
+		t.Fatalf("opposite arb not canceled. trades = %d, cancels = %d", len(tc.multiTradesPlaced), len(tc.cancelsPlaced))
+	}
+	if bot.numActiveArbs() != 1 || !bot.activeArbs[false][0].canceled {
+		t.Fatalf("partially matched arb not kept as canceled")
+	}
+	bot.rebalance(3)
+	if len(tc.multiTradesPlaced) != 2 || bot.numActiveArbs() != 2 {
+		t.Fatalf("arb not executed after canceling opposite arb")
+	}
+
+	// If the order on the bot's host fails, the counter host order is
+	// canceled.
+	bot, tc = newBot(2)
+	bot.rebalance(1)
+	if len(tc.multiTradesPlaced) != 2 || len(tc.cancelsPlaced) != 1 || bot.numActiveArbs() != 0 {
+		t.Fatalf("counter host order not canceled after failed trade")
+	}
+}
@@ -0,0 +1,523 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package mm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"decred.org/dcrdex/client/core"
+	"decred.org/dcrdex/client/mm/libxc"
+	"decred.org/dcrdex/dex"
+)
+
+// Strategy is a custom bot strategy. Strategies are registered with
+// RegisterStrategy and selected with the StrategyConfig field of a BotConfig.
+// The bot running a Strategy handles order placement, balance accounting and
+// the event log in the same way as the built-in bots, so a Strategy only has
+// to decide where orders should be.
+//
+// The methods of a Strategy are never called concurrently.
+type Strategy interface {
+	// CEXMarkets returns the CEX markets, as [base, quote] asset IDs, that
+	// must be subscribed before the strategy is started. The strategy can
+	// query the books of these markets using the StrategyEnv.
+	CEXMarkets() [][2]uint32
+	// Epoch is called at the start of each DEX epoch. The returned actions
+	// are executed by the bot. If an error is returned, all of the bot's
+	// DEX orders are cancelled.
+	Epoch(env StrategyEnv, epoch *StrategyEpoch) (*StrategyActions, error)
+	// DEXOrderUpdate is called when the status of one of the bot's DEX
+	// orders changes.
+	DEXOrderUpdate(env StrategyEnv, o *core.Order)
+	// CEXTradeUpdate is called when one of the bot's CEX trades is updated.
+	CEXTradeUpdate(env StrategyEnv, trade *libxc.Trade)
+}
+
+// StrategyConstructor constructs a Strategy from the Config field of the
+// StrategyConfig.
+type StrategyConstructor func(cfg json.RawMessage, log dex.Logger) (Strategy, error)
+
+var (
+	strategiesMtx sync.RWMutex
+	strategies    = make(map[string]StrategyConstructor)
+)
+
+// RegisterStrategy registers a custom strategy under a name. It should be
+// called from an init function. RegisterStrategy panics if a strategy is
+// already registered under the name.
+func RegisterStrategy(name string, constructor StrategyConstructor) {
+	strategiesMtx.Lock()
+	defer strategiesMtx.Unlock()
+
+	if constructor == nil {
+		panic("mm: RegisterStrategy constructor is nil")
+	}
+	if _, dup := strategies[name]; dup {
+		panic(fmt.Sprintf("mm: RegisterStrategy called twice for strategy %q", name))
+	}
+	strategies[name] = constructor
+}
+
+func strategyConstructor(name string) (StrategyConstructor, bool) {
+	strategiesMtx.RLock()
+	defer strategiesMtx.RUnlock()
+	c, found := strategies[name]
+	return c, found
+}
+
+// newStrategy constructs the strategy registered under the config's name.
+func newStrategy(cfg *StrategyConfig, log dex.Logger) (Strategy, error) {
+	constructor, found := strategyConstructor(cfg.Name)
+	if !found {
+		return nil, fmt.Errorf("unknown strategy %q", cfg.Name)
+	}
+	strategy, err := constructor(cfg.Config, log.SubLogger(cfg.Name))
+	if err != nil {
+		return nil, fmt.Errorf("error constructing strategy %q: %w", cfg.Name, err)
+	}
+	return strategy, nil
+}
+
+// RegisteredStrategies returns the names of the registered strategies.
+func RegisteredStrategies() []string {
+	strategiesMtx.RLock()
+	defer strategiesMtx.RUnlock()
+	names := make([]string, 0, len(strategies))
+	for name := range strategies {
+		names = append(names, name)
+	}
+	return names
+}
+
+// StrategyConfig is the configuration for a bot running a custom Strategy.
+type StrategyConfig struct {
+	// Name is the name the strategy was registered under.
+	Name string `json:"name"`
+	// Config is passed to the strategy's constructor.
+	Config json.RawMessage `json:"config,omitempty"`
+	// DriftTolerance is how far away from an ideal price orders can drift
+	// before they are replaced (units: ratio of price). Default: 0.1%.
+	// 0 <= x <= 0.01.
+	DriftTolerance float64 `json:"driftTolerance"`
+	// MaxBuyPlacements and MaxSellPlacements are the maximum number of
+	// placements the strategy will return for each side. They are used to
+	// check the user's trading limits.
+	MaxBuyPlacements  uint32 `json:"maxBuyPlacements"`
+	MaxSellPlacements uint32 `json:"maxSellPlacements"`
+}
+
+func (c *StrategyConfig) validate() error {
+	if _, found := strategyConstructor(c.Name); !found {
+		return fmt.Errorf("unknown strategy %q", c.Name)
+	}
+	if c.DriftTolerance == 0 {
+		c.DriftTolerance = 0.001
+	}
+	if c.DriftTolerance < 0 || c.DriftTolerance > 0.01 {
+		return fmt.Errorf("drift tolerance %f out of bounds", c.DriftTolerance)
+	}
+	return nil
+}
+
+func (c *StrategyConfig) copy() *StrategyConfig {
+	cfg := *c
+	if c.Config != nil {
+		cfg.Config = make(json.RawMessage, len(c.Config))
+		copy(cfg.Config, c.Config)
+	}
+	return &cfg
+}
+
+// StrategyEpoch is the state of the bot at the start of a DEX epoch.
+type StrategyEpoch struct {
+	// Epoch is the epoch number.
+	Epoch uint64
+	// Balances are the bot's current balances.
+	Balances *BalanceState
+}
+
+// StrategyCEXTrade is a trade that a Strategy wants to make on the CEX.
+type StrategyCEXTrade struct {
+	BaseID  uint32
+	QuoteID uint32
+	Sell    bool
+	// Rate is ignored for market orders.
+	Rate uint64
+	// Qty is in the base asset.
+	Qty uint64
+	// QuoteQty is only used for market buys.
+	QuoteQty  uint64
+	OrderType libxc.OrderType
+}
+
+// StrategyActions are the actions a Strategy wants the bot to take in an
+// epoch.
+type StrategyActions struct {
+	// Buys and Sells are the desired DEX placements, in decreasing priority
+	// order. The bot's orders are updated in the same way as the basic
+	// market maker's: orders that have drifted too far from their placement
+	// rate are cancelled, and new orders are placed until the placement's
+	// lots are on the book. A placement with a zero rate cancels the orders
+	// previously placed for that index.
+	Buys  []*TradePlacement
+	Sells []*TradePlacement
+	// CEXTrades are trades to be made on the CEX.
+	CEXTrades []*StrategyCEXTrade
+}
+
+// StrategyEnv provides a Strategy with market data and the bot's balances.
+type StrategyEnv interface {
+	// Market returns the DEX market's host and asset IDs.
+	Market() *MarketWithHost
+	// CEXAssets returns the asset IDs of the base and quote assets on the
+	// CEX.
+	CEXAssets() (baseID, quoteID uint32)
+	LotSize() uint64
+	RateStep() uint64
+	DEXBalance(assetID uint32) *BotBalance
+	CEXBalance(assetID uint32) *BotBalance
+	// DEXMidGap returns the mid-gap rate of the DEX book.
+	DEXMidGap() (uint64, error)
+	// DEXVWAP returns the volume weighted average and extrema rates of
+	// matching a number of lots on the DEX book.
+	DEXVWAP(lots uint64, sell bool) (avg, extrema uint64, filled bool, err error)
+	// CEXMidGap returns the mid-gap rate of a subscribed CEX market. It
+	// returns 0 if there is no CEX or the book is not available.
+	CEXMidGap(baseID, quoteID uint32) uint64
+	// CEXVWAP returns the volume weighted average and extrema rates of a
+	// trade of qty base asset on a subscribed CEX market. sell refers to the
+	// side of a DEX order, so a sell is priced using the CEX asks.
+	CEXVWAP(baseID, quoteID uint32, sell bool, qty uint64) (vwap, extrema uint64, filled bool, err error)
+	// CancelCEXTrade cancels a trade on the CEX.
+	CancelCEXTrade(baseID, quoteID uint32, tradeID string) error
+	// OrderFeesInUnits returns the estimated fees for a lot of a DEX order
+	// in units of the base or quote asset.
+	OrderFeesInUnits(sell, base bool, rate uint64) (uint64, error)
+	// ExchangeRateFromFiatSources returns the market rate derived from fiat
+	// exchange rates.
+	ExchangeRateFromFiatSources() uint64
+}
+
+// strategyBot is a bot that runs a custom Strategy.
+type strategyBot struct {
+	*unifiedExchangeAdaptor
+	book             dexOrderBook
+	rebalanceRunning atomic.Bool
+	// tradeUpdates is only subscribed once, since the adaptor's subscription
+	// outlives restarts of the bot loop.
+	tradeUpdates <-chan *libxc.Trade
+
+	// strategyMtx serializes calls to the strategy. The strategy is
+	// constructed with the bot, and is replaced when the strategy's
+	// configuration is updated. Otherwise, it is kept across restarts of the
+	// bot loop.
+	strategyMtx sync.Mutex
+	strategy    Strategy
+}
+
+var _ bot = (*strategyBot)(nil)
+var _ StrategyEnv = (*strategyBot)(nil)
+
+func (s *strategyBot) cfg() *StrategyConfig {
+	return s.botCfg().StrategyConfig
+}
+
+// updateConfig updates the bot's configuration. If the name or Config of the
+// StrategyConfig changed, a new strategy is constructed from it, and the
+// update is rejected if the strategy cannot be constructed. The bot loop is
+// paused during the update, and runs the new strategy when it restarts.
+func (s *strategyBot) updateConfig(cfg *BotConfig, autoRebalanceCfg *AutoRebalanceConfig) error {
+	if cfg.StrategyConfig == nil {
+		return errors.New("cannot change bot type")
+	}
+	var strategy Strategy
+	if oldCfg := s.cfg(); oldCfg.Name != cfg.StrategyConfig.Name || !bytes.Equal(oldCfg.Config, cfg.StrategyConfig.Config) {
+		var err error
+		if strategy, err = newStrategy(cfg.StrategyConfig, s.log); err != nil {
+			return err
+		}
+	}
+	if err := s.unifiedExchangeAdaptor.updateConfig(cfg, autoRebalanceCfg); err != nil {
+		return err
+	}
+	if strategy != nil {
+		s.strategyMtx.Lock()
+		s.strategy = strategy
+		s.strategyMtx.Unlock()
+	}
+	return nil
+}
+
+// Market returns the DEX market. Part of the StrategyEnv interface.
+func (s *strategyBot) Market() *MarketWithHost {
+	return &MarketWithHost{
+		Host:    s.host,
+		BaseID:  s.dexBaseID,
+		QuoteID: s.dexQuoteID,
+	}
+}
+
+// CEXAssets returns the CEX asset IDs. Part of the StrategyEnv interface.
+func (s *strategyBot) CEXAssets() (baseID, quoteID uint32) {
+	botCfg := s.botCfg()
+	return botCfg.CEXBaseID, botCfg.CEXQuoteID
+}
+
+// LotSize returns the market's lot size. Part of the StrategyEnv interface.
+func (s *strategyBot) LotSize() uint64 {
+	return s.lotSize.Load()
+}
+
+// RateStep returns the market's rate step. Part of the StrategyEnv interface.
+func (s *strategyBot) RateStep() uint64 {
+	return s.rateStep.Load()
+}
+
+// DEXMidGap returns the mid-gap of the DEX book. Part of the StrategyEnv
+// interface.
+func (s *strategyBot) DEXMidGap() (uint64, error) {
+	return s.book.MidGap()
+}
+
+// DEXVWAP returns the VWAP of the DEX book. Part of the StrategyEnv
+// interface.
+func (s *strategyBot) DEXVWAP(lots uint64, sell bool) (avg, extrema uint64, filled bool, err error) {
+	return s.book.VWAP(lots, s.lotSize.Load(), sell)
+}
+
+// CEXMidGap returns the mid-gap of a CEX market. Part of the StrategyEnv
+// interface.
+func (s *strategyBot) CEXMidGap(baseID, quoteID uint32) uint64 {
+	if s.CEX == nil {
+		return 0
+	}
+	return s.CEX.MidGap(baseID, quoteID)
+}
+
+// CEXVWAP returns the VWAP of a CEX market. Part of the StrategyEnv
+// interface.
+func (s *strategyBot) CEXVWAP(baseID, quoteID uint32, sell bool, qty uint64) (vwap, extrema uint64, filled bool, err error) {
+	if s.CEX == nil {
+		return 0, 0, false, errors.New("no CEX configured")
+	}
+	return s.CEX.VWAP(baseID, quoteID, sell, qty)
+}
+
+// CancelCEXTrade cancels a CEX trade. Part of the StrategyEnv interface.
+func (s *strategyBot) CancelCEXTrade(baseID, quoteID uint32, tradeID string) error {
+	if s.CEX == nil {
+		return errors.New("no CEX configured")
+	}
+	return s.CancelTrade(s.ctx, baseID, quoteID, tradeID)
+}
+
+// executeCEXTrades sends the strategy's CEX trades to the CEX. The trades
+// are recorded in the event log by the exchange adaptor. A failed trade does
+// not prevent the remaining trades from being sent, and the errors of all
+// failed trades are returned.
+func (s *strategyBot) executeCEXTrades(trades []*StrategyCEXTrade) error {
+	if len(trades) == 0 {
+		return nil
+	}
+	if s.CEX == nil {
+		return errors.New("strategy requested CEX trades but no CEX is configured")
+	}
+	var errs []error
+	for _, t := range trades {
+		trade, err := s.CEXTrade(s.ctx, t.BaseID, t.QuoteID, t.Sell, t.Rate, t.Qty, t.QuoteQty, t.OrderType)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("error sending %s trade on %s-%s to CEX: %w", sellStr(t.Sell),
+				dex.BipIDSymbol(t.BaseID), dex.BipIDSymbol(t.QuoteID), err))
+			continue
+		}
+		s.strategyMtx.Lock()
+		s.strategy.CEXTradeUpdate(s, trade)
+		s.strategyMtx.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+func (s *strategyBot) rebalance(epoch uint64) {
+	if !s.rebalanceRunning.CompareAndSwap(false, true) {
+		return
+	}
+	defer s.rebalanceRunning.Store(false)
+
+	s.log.Tracef("rebalance: epoch %d", epoch)
+
+	if !s.checkBotHealth(epoch) {
+		s.tryCancelOrders(s.ctx, &epoch, false)
+		return
+	}
+
+	s.strategyMtx.Lock()
+	actions, err := s.strategy.Epoch(s, &StrategyEpoch{
+		Epoch:    epoch,
+		Balances: s.balanceState(),
+	})
+	s.strategyMtx.Unlock()
+	if err == nil && actions == nil {
+		actions = &StrategyActions{}
+	}
+
+	var buysReport, sellsReport *OrderReport
+	if err != nil {
+		err = fmt.Errorf("strategy error: %w", err)
+		s.tryCancelOrders(s.ctx, &epoch, false)
+	} else {
+		driftTolerance := s.cfg().DriftTolerance
+		_, buysReport = s.multiTrade(actions.Buys, false, driftTolerance, epoch)
+		_, sellsReport = s.multiTrade(actions.Sells, true, driftTolerance, epoch)
+		if len(actions.CEXTrades) > 0 {
+			cexErr := s.executeCEXTrades(actions.CEXTrades)
+			if cexErr != nil {
+				s.log.Errorf("Error executing CEX trades: %v", cexErr)
+			}
+			s.updateCEXProblems(cexTradeProblem, 0, cexErr)
+		}
+	}
+
+	epochReport := &EpochReport{
+		BuysReport:  buysReport,
+		SellsReport: sellsReport,
+		EpochNum:    epoch,
+	}
+	epochReport.setPreOrderProblems(err)
+	s.updateEpochReport(epochReport)
+}
+
+func (s *strategyBot) botLoop(ctx context.Context) (*sync.WaitGroup, error) {
+	// The strategy is only replaced by updateConfig, which is called while
+	// the loop is stopped.
+	s.strategyMtx.Lock()
+	strategy := s.strategy
+	s.strategyMtx.Unlock()
+	cfg := s.cfg()
+
+	book, bookFeed, err := s.SyncBook(s.host, s.dexBaseID, s.dexQuoteID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sync book: %v", err)
+	}
+	s.book = book
+
+	cexMkts := strategy.CEXMarkets()
+	if len(cexMkts) > 0 && s.CEX == nil {
+		bookFeed.Close()
+		return nil, fmt.Errorf("strategy %q requires a CEX", cfg.Name)
+	}
+	for i, mkt := range cexMkts {
+		if err := s.SubscribeMarket(ctx, mkt[0], mkt[1]); err != nil {
+			for _, subscribed := range cexMkts[:i] {
+				s.UnsubscribeMarket(subscribed[0], subscribed[1])
+			}
+			bookFeed.Close()
+			return nil, fmt.Errorf("failed to subscribe to cex market: %v", err)
+		}
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer bookFeed.Close()
+		for {
+			select {
+			case ni, ok := <-bookFeed.Next():
+				if !ok {
+					s.log.Error("Stopping bot due to nil book feed.")
+					s.kill()
+					return
+				}
+				switch epoch := ni.Payload.(type) {
+				case *core.ResolvedEpoch:
+					s.rebalance(epoch.Current)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		orderUpdates := s.SubscribeOrderUpdates()
+		for {
+			select {
+			case o := <-orderUpdates:
+				s.strategyMtx.Lock()
+				strategy.DEXOrderUpdate(s, o)
+				s.strategyMtx.Unlock()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	if s.CEX != nil {
+		if s.tradeUpdates == nil {
+			s.tradeUpdates = s.SubscribeTradeUpdates()
+		}
+		tradeUpdates := s.tradeUpdates
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case trade := <-tradeUpdates:
+					s.strategyMtx.Lock()
+					strategy.CEXTradeUpdate(s, trade)
+					s.strategyMtx.Unlock()
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		<-ctx.Done()
+		for _, mkt := range cexMkts {
+			s.UnsubscribeMarket(mkt[0], mkt[1])
+		}
+	}()
+
+	return &wg, nil
+}
+
+func newStrategyBot(cfg *BotConfig, adaptorCfg *exchangeAdaptorCfg, log dex.Logger) (*strategyBot, error) {
+	if cfg.StrategyConfig == nil {
+		// implies bug in caller
+		return nil, errors.New("no strategy config provided")
+	}
+
+	adaptor, err := newUnifiedExchangeAdaptor(adaptorCfg)
+	if err != nil {
+		return nil, fmt.Errorf("error constructing exchange adaptor: %w", err)
+	}
+
+	if err := cfg.StrategyConfig.validate(); err != nil {
+		return nil, fmt.Errorf("invalid strategy config: %w", err)
+	}
+
+	strategy, err := newStrategy(cfg.StrategyConfig, adaptor.log)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &strategyBot{
+		unifiedExchangeAdaptor: adaptor,
+		strategy:               strategy,
+	}
+	adaptor.setBotLoop(s.botLoop)
+	return s, nil
+}
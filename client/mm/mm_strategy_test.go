@@ -0,0 +1,244 @@
+//go:build !harness && !botlive
+
+package mm
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"decred.org/dcrdex/client/core"
+	"decred.org/dcrdex/client/mm/libxc"
+	"decred.org/dcrdex/dex"
+	"decred.org/dcrdex/dex/calc"
+)
+
+type tStrategy struct {
+	actions  *StrategyActions
+	err      error
+	epochs   []*StrategyEpoch
+	orderUps []*core.Order
+}
+
+func (s *tStrategy) CEXMarkets() [][2]uint32 { return nil }
+func (s *tStrategy) Epoch(env StrategyEnv, epoch *StrategyEpoch) (*StrategyActions, error) {
+	s.epochs = append(s.epochs, epoch)
+	return s.actions, s.err
+}
+func (s *tStrategy) DEXOrderUpdate(env StrategyEnv, o *core.Order) {
+	s.orderUps = append(s.orderUps, o)
+}
+func (s *tStrategy) CEXTradeUpdate(env StrategyEnv, trade *libxc.Trade) {}
+
+func TestStrategyConfig(t *testing.T) {
+	const name = "test-strategy-config"
+	RegisterStrategy(name, func(cfg json.RawMessage, log dex.Logger) (Strategy, error) {
+		return &tStrategy{}, nil
+	})
+
+	cfg := &StrategyConfig{Name: name}
+	if err := cfg.validate(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.DriftTolerance != 0.001 {
+		t.Fatalf("default drift tolerance not set")
+	}
+
+	if err := (&StrategyConfig{Name: "unknown"}).validate(); err == nil {
+		t.Fatalf("no error for unknown strategy")
+	}
+	if err := (&StrategyConfig{Name: name, DriftTolerance: 0.1}).validate(); err == nil {
+		t.Fatalf("no error for out of bounds drift tolerance")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("no panic for duplicate registration")
+		}
+	}()
+	RegisterStrategy(name, func(cfg json.RawMessage, log dex.Logger) (Strategy, error) {
+		return nil, nil
+	})
+}
+
+func TestStrategyBotRebalance(t *testing.T) {
+	const lotSize = 5e9
+	const rateStep = 1e3
+	const baseID, quoteID = 42, 0
+	const buyRate, sellRate = 4e6, 6e6
+
+	newBot := func(strategy *tStrategy) (*strategyBot, *tCore) {
+		s := &strategyBot{
+			unifiedExchangeAdaptor: mustParseAdaptorFromMarket(&core.Market{
+				RateStep:   rateStep,
+				AtomToConv: 1,
+				LotSize:    lotSize,
+				BaseID:     baseID,
+				QuoteID:    quoteID,
+			}),
+			strategy: strategy,
+		}
+		tcore := newTCore()
+		tcore.setWalletsAndExchange(&core.Market{
+			BaseID:  baseID,
+			QuoteID: quoteID,
+		})
+		s.clientCore = tcore
+		s.fiatRates.Store(map[uint32]float64{baseID: 1, quoteID: 1})
+		s.buyFees = &OrderFees{
+			LotFeeRange: &LotFeeRange{
+				Max:       &LotFees{Swap: 2e5, Redeem: 1e5},
+				Estimated: &LotFees{},
+			},
+			BookingFeesPerLot: 2e5,
+		}
+		s.sellFees = &OrderFees{
+			LotFeeRange: &LotFeeRange{
+				Max:       &LotFees{Swap: 3e6, Redeem: 1e6},
+				Estimated: &LotFees{},
+			},
+			BookingFeesPerLot: 3e6,
+		}
+		s.baseDexBalances[baseID] = lotSize * 50
+		s.baseDexBalances[quoteID] = int64(calc.BaseToQuote(buyRate, lotSize*50))
+		s.botCfgV.Store(&BotConfig{
+			Host:    s.host,
+			BaseID:  baseID,
+			QuoteID: quoteID,
+			StrategyConfig: &StrategyConfig{
+				Name:           "test",
+				DriftTolerance: 0.001,
+			},
+		})
+		return s, tcore
+	}
+
+	strategy := &tStrategy{
+		actions: &StrategyActions{
+			Buys:  []*TradePlacement{{Rate: buyRate, Lots: 2}},
+			Sells: []*TradePlacement{{Rate: sellRate, Lots: 1}},
+		},
+	}
+	s, tcore := newBot(strategy)
+	s.rebalance(100)
+
+	if len(strategy.epochs) != 1 || strategy.epochs[0].Epoch != 100 {
+		t.Fatalf("strategy not called for epoch")
+	}
+	if strategy.epochs[0].Balances.Balances[baseID].Available != lotSize*50 {
+		t.Fatalf("wrong base balance passed to strategy")
+	}
+	if len(tcore.multiTradesPlaced) != 2 {
+		t.Fatalf("expected buy and sell multi-trades, got %d", len(tcore.multiTradesPlaced))
+	}
+	buys, sells := tcore.multiTradesPlaced[0], tcore.multiTradesPlaced[1]
+	if len(buys.Placements) != 1 || buys.Placements[0].Rate != buyRate || buys.Placements[0].Qty != 2*lotSize {
+		t.Fatalf("wrong buy placements %+v", buys.Placements)
+	}
+	if len(sells.Placements) != 1 || sells.Placements[0].Rate != sellRate || sells.Placements[0].Qty != lotSize {
+		t.Fatalf("wrong sell placements %+v", sells.Placements)
+	}
+
+	// A strategy error is reported in the epoch report and no orders are
+	// placed.
+	strategy = &tStrategy{err: errors.New("test error")}
+	s, tcore = newBot(strategy)
+	s.rebalance(101)
+	if len(tcore.multiTradesPlaced) != 0 {
+		t.Fatalf("orders placed after strategy error")
+	}
+	report := s.latestEpoch()
+	if report == nil || report.PreOrderProblems == nil || report.PreOrderProblems.UnknownError == "" {
+		t.Fatalf("strategy error not reported")
+	}
+
+	// CEX trades without a CEX are an error, but DEX placements are still
+	// made.
+	strategy = &tStrategy{
+		actions: &StrategyActions{
+			Buys:      []*TradePlacement{{Rate: buyRate, Lots: 1}},
+			CEXTrades: []*StrategyCEXTrade{{BaseID: baseID, QuoteID: quoteID, Rate: buyRate, Qty: lotSize}},
+		},
+	}
+	s, tcore = newBot(strategy)
+	s.rebalance(102)
+	if len(tcore.multiTradesPlaced) != 1 {
+		t.Fatalf("expected buy multi-trade, got %d", len(tcore.multiTradesPlaced))
+	}
+	if problems := s.latestCEXProblems(); problems == nil || problems.TradeErr == nil {
+		t.Fatalf("CEX trade error not reported")
+	}
+
+	// All CEX trades are attempted, and their errors are returned.
+	cex := newTCEX()
+	cex.tradeErr = errors.New("test error")
+	s.CEX = cex
+	s.baseCexBalances[baseID] = lotSize * 10
+	s.baseCexBalances[quoteID] = int64(calc.BaseToQuote(buyRate, lotSize*10))
+	err := s.executeCEXTrades([]*StrategyCEXTrade{
+		{BaseID: baseID, QuoteID: quoteID, Rate: buyRate, Qty: lotSize},
+		{BaseID: baseID, QuoteID: quoteID, Sell: true, Rate: sellRate, Qty: lotSize},
+	})
+	if err == nil || len(err.(interface{ Unwrap() []error }).Unwrap()) != 2 {
+		t.Fatalf("expected errors for both CEX trades, got %v", err)
+	}
+}
+
+func TestStrategyBotUpdateConfig(t *testing.T) {
+	const name = "test-strategy-update"
+	var constructed []string
+	RegisterStrategy(name, func(cfg json.RawMessage, log dex.Logger) (Strategy, error) {
+		if string(cfg) == `"bad"` {
+			return nil, errors.New("bad config")
+		}
+		constructed = append(constructed, string(cfg))
+		return &tStrategy{}, nil
+	})
+
+	newCfg := func(strategyCfg string, driftTolerance float64) *BotConfig {
+		return &BotConfig{
+			Host:       "host",
+			BaseID:     42,
+			QuoteID:    0,
+			CEXBaseID:  42,
+			CEXQuoteID: 0,
+			StrategyConfig: &StrategyConfig{
+				Name:           name,
+				Config:         json.RawMessage(strategyCfg),
+				DriftTolerance: driftTolerance,
+			},
+		}
+	}
+
+	u := mustParseAdaptorFromMarket(&core.Market{BaseID: 42, QuoteID: 0, LotSize: 1e8, RateStep: 1e3, AtomToConv: 1})
+	u.bridgesSupported = func([]*configuredBridge) error { return nil }
+	u.fiatRates.Store(map[uint32]float64{42: 1, 0: 1})
+	u.botCfgV.Store(newCfg(`"a"`, 0.001))
+	strategy := &tStrategy{}
+	s := &strategyBot{unifiedExchangeAdaptor: u, strategy: strategy}
+
+	// Changes to the bot's settings keep the strategy.
+	if err := s.updateConfig(newCfg(`"a"`, 0.002), nil); err != nil {
+		t.Fatalf("error updating drift tolerance: %v", err)
+	}
+	if s.strategy != strategy || len(constructed) != 0 || s.cfg().DriftTolerance != 0.002 {
+		t.Fatalf("strategy replaced after drift tolerance update")
+	}
+
+	// A change to the strategy's config constructs a new strategy.
+	if err := s.updateConfig(newCfg(`"b"`, 0.002), nil); err != nil {
+		t.Fatalf("error updating strategy config: %v", err)
+	}
+	if s.strategy == strategy || len(constructed) != 1 || constructed[0] != `"b"` {
+		t.Fatalf("strategy not replaced after strategy config update")
+	}
+
+	// A config that the strategy rejects is not applied.
+	strategy = s.strategy.(*tStrategy)
+	if err := s.updateConfig(newCfg(`"bad"`, 0.002), nil); err == nil {
+		t.Fatalf("no error for rejected strategy config")
+	}
+	if s.strategy != strategy || string(s.cfg().Config) != `"b"` {
+		t.Fatalf("rejected strategy config applied")
+	}
+}
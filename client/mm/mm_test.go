@@ -89,6 +89,17 @@ type tCore struct {
 	parcelLimit       uint32
 	exchange          *core.Exchange
 	walletStates      map[uint32]*core.WalletState
+
+	bridgePaths         map[uint32]map[uint32][]string
+	bridgeFeesAndLimits map[string]*core.BridgeFeesAndLimits
+	bridgesInitiated    []*bridgeArgs
+}
+
+type bridgeArgs struct {
+	fromAssetID uint32
+	toAssetID   uint32
+	amt         uint64
+	bridgeName  string
 }
 
 func newTCore() *tCore {
@@ -227,6 +238,7 @@ func (c *tCore) WalletState(assetID uint32) *core.WalletState {
 }
 
 func (c *tCore) Bridge(fromAssetID, toAssetID uint32, amt uint64, bridgeName string) (txID string, err error) {
+	c.bridgesInitiated = append(c.bridgesInitiated, &bridgeArgs{fromAssetID, toAssetID, amt, bridgeName})
 	return "bridge_tx_id", nil
 }
 
@@ -267,6 +279,21 @@ func (c *tCore) BridgeContractApprovalStatus(assetID uint32, bridgeName string)
 	return asset.Approved, nil
 }
 
+func (c *tCore) BridgeFeesAndLimits(fromAssetID, toAssetID uint32, bridgeName string) (*core.BridgeFeesAndLimits, error) {
+	if c.bridgeFeesAndLimits == nil {
+		return nil, fmt.Errorf("no bridge fees")
+	}
+	fl, found := c.bridgeFeesAndLimits[bridgeName]
+	if !found {
+		return nil, fmt.Errorf("unknown bridge %s", bridgeName)
+	}
+	return fl, nil
+}
+
+func (c *tCore) AllBridgePaths() (map[uint32]map[uint32][]string, error) {
+	return c.bridgePaths, nil
+}
+
 type dexOrder struct {
 	rate uint64
 	qty  uint64
@@ -709,9 +736,10 @@ func (c *tBotCexAdaptor) SufficientBalanceForCEXTrade(baseID, quoteID uint32, se
 func (c *tBotCexAdaptor) Book() (_, _ []*core.MiniOrder, _ error) { return nil, nil, nil }
 
 type tExchangeAdaptor struct {
-	dexBalances map[uint32]*BotBalance
-	cexBalances map[uint32]*BotBalance
-	cfg         *BotConfig
+	dexBalances    map[uint32]*BotBalance
+	cexBalances    map[uint32]*BotBalance
+	cfg            *BotConfig
+	acceptTransfer func() bool
 }
 
 var _ bot = (*tExchangeAdaptor)(nil)
@@ -751,6 +779,9 @@ func (t *tExchangeAdaptor) withPause(func() error) error    { return nil }
 func (t *tExchangeAdaptor) botCfg() *BotConfig              { return t.cfg }
 func (t *tExchangeAdaptor) latestEpoch() *EpochReport       { return &EpochReport{} }
 func (t *tExchangeAdaptor) latestCEXProblems() *CEXProblems { return nil }
+func (t *tExchangeAdaptor) acceptBotTransfer(*MarketWithHost, uint32, uint32, uint64, bool) bool {
+	return t.acceptTransfer != nil && t.acceptTransfer()
+}
 
 func TestAvailableBalances(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
@@ -895,3 +926,87 @@ func TestAvailableBalances(t *testing.T) {
 	checkAvailableBalances(btcUsdc, &binanceName, map[uint32]uint64{0: 3e5, 60: 7e5, 60001: 4e5}, map[uint32]uint64{0: 5e5, 60001: 4e5})
 	checkAvailableBalances(dcrUsdc, &binanceUSName, map[uint32]uint64{42: 9e5, 60: 7e5, 60001: 4e5}, map[uint32]uint64{42: 7e5, 60001: 6e5})
 }
+
+func TestBridgeAndBotTransfer(t *testing.T) {
+	const usdcEthID, usdcPolygonID = 60001, 966001
+
+	tCore := newTCore()
+	tCore.setAssetBalances(map[uint32]uint64{
+		42:            1e8,
+		0:             1e8,
+		usdcEthID:     5e6,
+		usdcPolygonID: 1e6,
+	})
+
+	mkt := &MarketWithHost{Host: "dex.com", BaseID: 42, QuoteID: 0}
+	peerMkt := &MarketWithHost{Host: "dex2.com", BaseID: 42, QuoteID: 0}
+	newBot := func(mkt *MarketWithHost) *tExchangeAdaptor {
+		return &tExchangeAdaptor{
+			cfg: &BotConfig{Host: mkt.Host, BaseID: mkt.BaseID, QuoteID: mkt.QuoteID, CEXName: libxc.Binance},
+		}
+	}
+	bot, peer := newBot(mkt), newBot(peerMkt)
+
+	m := &MarketMaker{
+		ctx:  context.Background(),
+		log:  tLogger,
+		core: tCore,
+		runningBots: map[MarketWithHost]*runningBot{
+			*mkt:     {bot: bot},
+			*peerMkt: {bot: peer},
+		},
+	}
+
+	availableUSDC := func() (eth, polygon uint64) {
+		t.Helper()
+		dex, _, err := m.availableBalances(peerMkt, 42, 0, []uint32{usdcEthID, usdcPolygonID}, nil)
+		if err != nil {
+			t.Fatalf("error getting available balances: %v", err)
+		}
+		return dex[usdcEthID], dex[usdcPolygonID]
+	}
+
+	// The sources with sufficient funds are reserved while the transfer is
+	// made, without blocking bots from being started or updated.
+	err := m.bridgeTransfer(mkt, []uint32{usdcEthID, usdcPolygonID}, 2e6, func(dexAvailable map[uint32]uint64) error {
+		if dexAvailable[usdcEthID] != 5e6 || dexAvailable[usdcPolygonID] != 1e6 {
+			t.Fatalf("wrong available balances passed to doTransfer: %v", dexAvailable)
+		}
+		if !m.startUpdateMtx.TryLock() {
+			t.Fatalf("startUpdateMtx locked during bridge transfer")
+		}
+		m.startUpdateMtx.Unlock()
+		if eth, polygon := availableUSDC(); eth != 3e6 || polygon != 1e6 {
+			t.Fatalf("wrong available balances during bridge transfer: eth = %d, polygon = %d", eth, polygon)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("bridgeTransfer error: %v", err)
+	}
+	if eth, polygon := availableUSDC(); eth != 5e6 || polygon != 1e6 {
+		t.Fatalf("reserves not released: eth = %d, polygon = %d", eth, polygon)
+	}
+	if err := m.bridgeTransfer(&MarketWithHost{Host: "dex3.com"}, nil, 1, func(map[uint32]uint64) error { return nil }); err == nil {
+		t.Fatalf("no error for bridge transfer for a bot that is not running")
+	}
+
+	// Peers are asked without the startUpdateMtx locked.
+	peer.acceptTransfer = func() bool {
+		if !m.startUpdateMtx.TryLock() {
+			t.Fatalf("startUpdateMtx locked during bot transfer")
+		}
+		m.startUpdateMtx.Unlock()
+		return true
+	}
+	accepted, err := m.botTransfer(mkt, 42, 42, 1e7, true)
+	if err != nil || accepted == nil || *accepted != *peerMkt {
+		t.Fatalf("bot transfer not accepted by peer: %v, %v", accepted, err)
+	}
+
+	// Bots on other CEXes are not asked.
+	peer.cfg.CEXName = libxc.BinanceUS
+	if accepted, err := m.botTransfer(mkt, 42, 42, 1e7, true); err != nil || accepted != nil {
+		t.Fatalf("bot transfer accepted by a bot on another CEX")
+	}
+}
@@ -0,0 +1,703 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package mm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"decred.org/dcrdex/client/core"
+	"decred.org/dcrdex/dex"
+	"decred.org/dcrdex/dex/calc"
+	"decred.org/dcrdex/dex/order"
+)
+
+// TriangleArbConfig is the configuration for a bot that arbitrages a cycle
+// of three markets on the same DEX host. The cycle is formed by the bot's
+// market and the markets pairing each of its assets with an intermediate
+// asset, e.g. DCR/BTC, BTC/USDT and DCR/USDT.
+type TriangleArbConfig struct {
+	// IntermediateAssetID is the third asset of the cycle. The DEX host
+	// must have markets pairing it with both the base and quote assets of
+	// the bot's market.
+	IntermediateAssetID uint32 `json:"intermediateAssetID"`
+	// MinProfit is the minimum profit of a cycle, after swap and redeem
+	// fees, as a ratio of the value of the base asset traded. Range:
+	// 0 < MinProfit < 1.
+	MinProfit float64 `json:"minProfit"`
+	// MaxInFlightLots is the maximum number of lots, in lots of the bot's
+	// market, that can be in cycles that have not yet settled. Because all
+	// three legs of a cycle are traded simultaneously from the bot's
+	// inventory, this bounds the inventory exposed while swaps settle.
+	MaxInFlightLots uint64 `json:"maxInFlightLots"`
+	// NumEpochsLeaveOpen is the number of epochs the orders of a cycle will
+	// stay booked if they are not filled.
+	NumEpochsLeaveOpen uint32 `json:"numEpochsLeaveOpen"`
+}
+
+func (c *TriangleArbConfig) copy() *TriangleArbConfig {
+	cfg := *c
+	return &cfg
+}
+
+func (c *TriangleArbConfig) validate(baseID, quoteID uint32) error {
+	if c.IntermediateAssetID == baseID || c.IntermediateAssetID == quoteID {
+		return fmt.Errorf("intermediate asset must differ from the market's assets")
+	}
+
+	if c.MinProfit <= 0 || c.MinProfit >= 1 {
+		return fmt.Errorf("min profit must be 0 < p < 1, but got %v", c.MinProfit)
+	}
+
+	if c.MaxInFlightLots == 0 {
+		return fmt.Errorf("must allow at least 1 in-flight lot")
+	}
+
+	if c.NumEpochsLeaveOpen < 2 {
+		return fmt.Errorf("orders must be left open for at least 2 epochs")
+	}
+
+	return nil
+}
+
+// triangleLeg is one of the three markets of the cycle.
+type triangleLeg struct {
+	baseID  uint32
+	quoteID uint32
+	lotSize uint64
+	book    dexOrderBook
+	// baseFees and quoteFees are the estimated per-lot fees in the base and
+	// quote fee assets. See marketFees.
+	baseFees  *LotFees
+	quoteFees *LotFees
+}
+
+// triangleTrade is an order on one leg of a cycle.
+type triangleTrade struct {
+	leg  *triangleLeg
+	sell bool
+	rate uint64
+	qty  uint64
+	// spent and received are the estimated amounts of the from and to assets
+	// of the trade, based on the VWAP of the book.
+	spent    uint64
+	received uint64
+}
+
+func (t *triangleTrade) lots() uint64 {
+	return t.qty / t.leg.lotSize
+}
+
+func (t *triangleTrade) fromAsset() uint32 {
+	if t.sell {
+		return t.leg.baseID
+	}
+	return t.leg.quoteID
+}
+
+func (t *triangleTrade) toAsset() uint32 {
+	if t.sell {
+		return t.leg.quoteID
+	}
+	return t.leg.baseID
+}
+
+// lockAmounts returns the amounts of each asset that will be locked by the
+// order.
+func (t *triangleTrade) lockAmounts(amts map[uint32]uint64) {
+	lots := t.lots()
+	if t.sell {
+		amts[t.leg.baseID] += t.qty
+		amts[feeAssetID(t.leg.baseID)] += lots * t.leg.baseFees.Swap
+	} else {
+		amts[t.leg.quoteID] += calc.BaseToQuote(t.rate, t.qty)
+		amts[feeAssetID(t.leg.quoteID)] += lots * t.leg.quoteFees.Swap
+	}
+}
+
+// triangleOpportunity is a profitable trade sequence around the cycle.
+type triangleOpportunity struct {
+	// forward cycles sell the base asset on the bot's market, then trade
+	// the quote asset for the intermediate asset, and the intermediate
+	// asset back to the base asset. Reverse cycles go the other way.
+	forward bool
+	// lots is the number of lots of the bot's market.
+	lots        uint64
+	trades      [3]*triangleTrade
+	profitUSD   float64
+	profitRatio float64
+}
+
+// triangleCycle is an executed cycle that has not yet settled.
+type triangleCycle struct {
+	forward    bool
+	lots       uint64
+	startEpoch uint64
+	orders     []*core.Order
+	canceled   bool
+}
+
+// settled is true when all of the cycle's orders are complete, including
+// the settlement of all of their matches.
+func (c *triangleCycle) settled() bool {
+	for _, o := range c.orders {
+		if !dexOrderComplete(o) {
+			return false
+		}
+	}
+	return true
+}
+
+// booked is true if any of the cycle's orders are still on the book.
+func (c *triangleCycle) booked() bool {
+	for _, o := range c.orders {
+		if o.Status <= order.OrderStatusBooked {
+			return true
+		}
+	}
+	return false
+}
+
+type triangleArbBot struct {
+	*unifiedExchangeAdaptor
+	rebalanceRunning atomic.Bool
+
+	// legs are the bot's market, the market pairing the quote asset with
+	// the intermediate asset, and the market pairing the intermediate
+	// asset with the base asset.
+	legsMtx sync.RWMutex
+	legs    [3]*triangleLeg
+
+	cyclesMtx sync.RWMutex
+	cycles    []*triangleCycle
+}
+
+var _ bot = (*triangleArbBot)(nil)
+
+func (t *triangleArbBot) cfg() *TriangleArbConfig {
+	return t.botCfg().TriangleArbConfig
+}
+
+// legTrade calculates the trade that converts amt of the from asset on a
+// leg, matching the book up to the VWAP extrema. ok is false if there is not
+// enough liquidity or amt is less than a lot.
+func legTrade(leg *triangleLeg, sell bool, amt uint64) (trade *triangleTrade, ok bool, err error) {
+	if sell {
+		lots := amt / leg.lotSize
+		if lots == 0 {
+			return nil, false, nil
+		}
+		// Selling matches the buy side of the book.
+		avg, extrema, filled, err := leg.book.VWAP(lots, leg.lotSize, false)
+		if err != nil || !filled {
+			return nil, false, err
+		}
+		qty := lots * leg.lotSize
+		return &triangleTrade{
+			leg:      leg,
+			sell:     true,
+			rate:     extrema,
+			qty:      qty,
+			spent:    qty,
+			received: calc.BaseToQuote(avg, qty),
+		}, true, nil
+	}
+
+	// For a buy, find the most lots that can be bought with amt at the
+	// extrema rate, which is the rate that the order will lock funds at.
+	_, extrema, filled, err := leg.book.VWAP(1, leg.lotSize, true)
+	if err != nil || !filled {
+		return nil, false, err
+	}
+	maxLots := amt / calc.BaseToQuote(extrema, leg.lotSize)
+	if maxLots == 0 {
+		return nil, false, nil
+	}
+	var searchErr error
+	lots := uint64(sort.Search(int(maxLots), func(i int) bool {
+		lots := uint64(i + 1)
+		_, extrema, filled, err := leg.book.VWAP(lots, leg.lotSize, true)
+		if err != nil {
+			searchErr = err
+			return true
+		}
+		return !filled || calc.BaseToQuote(extrema, lots*leg.lotSize) > amt
+	}))
+	if searchErr != nil {
+		return nil, false, searchErr
+	}
+	if lots == 0 {
+		return nil, false, nil
+	}
+	avg, extrema, _, err := leg.book.VWAP(lots, leg.lotSize, true)
+	if err != nil {
+		return nil, false, err
+	}
+	qty := lots * leg.lotSize
+	return &triangleTrade{
+		leg:      leg,
+		rate:     extrema,
+		qty:      qty,
+		spent:    calc.BaseToQuote(avg, qty),
+		received: qty,
+	}, true, nil
+}
+
+// simulateCycle calculates the trades and profit of a cycle starting with
+// lots of the base asset of the bot's market. ok is false if the cycle
+// cannot be completed with the liquidity on the books.
+func simulateCycle(legs [3]*triangleLeg, forward bool, lots uint64, fiatRates map[uint32]float64) (opp *triangleOpportunity, ok bool, err error) {
+	mkt, quoteLeg, baseLeg := legs[0], legs[1], legs[2]
+	var intermediateID uint32
+	if quoteLeg.baseID == mkt.quoteID {
+		intermediateID = quoteLeg.quoteID
+	} else {
+		intermediateID = quoteLeg.baseID
+	}
+	// path is the asset held before each step.
+	path := []uint32{mkt.baseID, mkt.quoteID, intermediateID, mkt.baseID}
+	stepLegs := []*triangleLeg{mkt, quoteLeg, baseLeg}
+	if !forward {
+		path = []uint32{mkt.baseID, intermediateID, mkt.quoteID, mkt.baseID}
+		stepLegs = []*triangleLeg{baseLeg, quoteLeg, mkt}
+	}
+
+	opp = &triangleOpportunity{forward: forward, lots: lots}
+	amt := lots * mkt.lotSize
+	for i, leg := range stepLegs {
+		sell := leg.baseID == path[i]
+		trade, ok, err := legTrade(leg, sell, amt)
+		if err != nil || !ok {
+			return nil, false, err
+		}
+		opp.trades[i] = trade
+		amt = trade.received
+	}
+
+	for _, trade := range opp.trades {
+		received, err := usdValue(fiatRates, trade.toAsset(), trade.received)
+		if err != nil {
+			return nil, false, err
+		}
+		spent, err := usdValue(fiatRates, trade.fromAsset(), trade.spent)
+		if err != nil {
+			return nil, false, err
+		}
+		// Selling pays the swap fees of the base asset and the redeem fees
+		// of the quote asset. Buying is the opposite.
+		lots := trade.lots()
+		swapFeeAsset, redeemFeeAsset := feeAssetID(trade.leg.quoteID), feeAssetID(trade.leg.baseID)
+		swapFees, redeemFees := lots*trade.leg.quoteFees.Swap, lots*trade.leg.baseFees.Redeem
+		if trade.sell {
+			swapFeeAsset, redeemFeeAsset = feeAssetID(trade.leg.baseID), feeAssetID(trade.leg.quoteID)
+			swapFees, redeemFees = lots*trade.leg.baseFees.Swap, lots*trade.leg.quoteFees.Redeem
+		}
+		swapFeesUSD, err := usdValue(fiatRates, swapFeeAsset, swapFees)
+		if err != nil {
+			return nil, false, err
+		}
+		redeemFeesUSD, err := usdValue(fiatRates, redeemFeeAsset, redeemFees)
+		if err != nil {
+			return nil, false, err
+		}
+		opp.profitUSD += received - spent - swapFeesUSD - redeemFeesUSD
+	}
+
+	startUSD, err := usdValue(fiatRates, mkt.baseID, lots*mkt.lotSize)
+	if err != nil {
+		return nil, false, err
+	}
+	opp.profitRatio = opp.profitUSD / startUSD
+
+	return opp, true, nil
+}
+
+// sufficientBalance checks whether the bot has the balance to place all of
+// the trades of an opportunity simultaneously.
+func (t *triangleArbBot) sufficientBalance(opp *triangleOpportunity) bool {
+	reqs := make(map[uint32]uint64)
+	for _, trade := range opp.trades {
+		trade.lockAmounts(reqs)
+	}
+	for assetID, req := range reqs {
+		if t.DEXBalance(assetID).Available < req {
+			return false
+		}
+	}
+	return true
+}
+
+// inFlightLots is the number of lots in cycles that have not yet settled.
+func (t *triangleArbBot) inFlightLots() (lots uint64) {
+	t.cyclesMtx.RLock()
+	defer t.cyclesMtx.RUnlock()
+	for _, c := range t.cycles {
+		lots += c.lots
+	}
+	return lots
+}
+
+// bestOpportunity finds the most profitable cycle in either direction that
+// meets the minimum profit and that the bot can afford.
+func (t *triangleArbBot) bestOpportunity() (*triangleOpportunity, error) {
+	cfg := t.cfg()
+	inFlight := t.inFlightLots()
+	if inFlight >= cfg.MaxInFlightLots {
+		return nil, nil
+	}
+	maxLots := cfg.MaxInFlightLots - inFlight
+
+	t.legsMtx.RLock()
+	legs := t.legs
+	t.legsMtx.RUnlock()
+
+	fiatRates := t.fiatRates.Load().(map[uint32]float64)
+
+	// Matching deeper into the books gets worse rates, so the profit
+	// increases with the lots up to a peak, and then decreases. Binary search
+	// for the largest number of lots that meets the requirements and is more
+	// profitable than one less lot, rather than walking the books for every
+	// number of lots.
+	var best *triangleOpportunity
+	for _, forward := range []bool{true, false} {
+		sims := make(map[uint64]*triangleOpportunity)
+		simulate := func(lots uint64) (*triangleOpportunity, error) {
+			if opp, found := sims[lots]; found {
+				return opp, nil
+			}
+			opp, ok, err := simulateCycle(legs, forward, lots, fiatRates)
+			if err != nil || !ok {
+				return nil, err
+			}
+			sims[lots] = opp
+			return opp, nil
+		}
+		var searchErr error
+		n := sort.Search(int(maxLots), func(i int) bool {
+			lots := uint64(i + 1)
+			opp, err := simulate(lots)
+			if err != nil {
+				searchErr = err
+				return true
+			}
+			if opp == nil || opp.profitRatio < cfg.MinProfit || !t.sufficientBalance(opp) {
+				return true
+			}
+			if lots == 1 {
+				return false
+			}
+			prev, err := simulate(lots - 1)
+			if err != nil {
+				searchErr = err
+				return true
+			}
+			return prev == nil || opp.profitUSD < prev.profitUSD
+		})
+		if searchErr != nil {
+			return nil, searchErr
+		}
+		if n == 0 {
+			continue
+		}
+		opp, err := simulate(uint64(n))
+		if err != nil {
+			return nil, err
+		}
+		if best == nil || opp.profitUSD > best.profitUSD {
+			best = opp
+		}
+	}
+
+	if best != nil {
+		t.log.Infof("triangle arb opportunity - forward: %t, lots: %d, profit: %.2f USD (%.2f%%)",
+			best.forward, best.lots, best.profitUSD, best.profitRatio*100)
+	}
+
+	return best, nil
+}
+
+// executeCycle places the orders for all three legs of a cycle. If any of
+// the orders fail, the orders already placed are canceled.
+func (t *triangleArbBot) executeCycle(opp *triangleOpportunity, epoch uint64) {
+	// Hold the lock for the entire process so that order updates that
+	// arrive before the orders are recorded are not missed.
+	t.cyclesMtx.Lock()
+	defer t.cyclesMtx.Unlock()
+
+	orders := make([]*core.Order, 0, len(opp.trades))
+	for _, trade := range opp.trades {
+		o, err := t.dexTradeOnMarket(t.host, trade.leg.baseID, trade.leg.quoteID, trade.rate, trade.qty, trade.sell)
+		if err != nil {
+			t.log.Errorf("error placing %s order on %s-%s: %v", sellStr(trade.sell),
+				dex.BipIDSymbol(trade.leg.baseID), dex.BipIDSymbol(trade.leg.quoteID), err)
+			for _, o := range orders {
+				if err := t.Cancel(o.ID); err != nil {
+					t.log.Errorf("error canceling order %s: %v", o.ID, err)
+				}
+			}
+			return
+		}
+		orders = append(orders, o)
+	}
+
+	t.cycles = append(t.cycles, &triangleCycle{
+		forward:    opp.forward,
+		lots:       opp.lots,
+		startEpoch: epoch,
+		orders:     orders,
+	})
+}
+
+// cancelCycle cancels the orders of a cycle that are still booked.
+//
+// cyclesMtx MUST be held when calling this function.
+func (t *triangleArbBot) cancelCycle(c *triangleCycle) {
+	for _, o := range c.orders {
+		if o.Status > order.OrderStatusBooked {
+			continue
+		}
+		if err := t.Cancel(o.ID); err != nil {
+			t.log.Errorf("error canceling order %s: %v", o.ID, err)
+		}
+	}
+	c.canceled = true
+}
+
+// handleDEXOrderUpdate is called when the DEX sends a notification that the
+// status of an order has changed. Cycles are removed once all of their
+// orders have settled.
+func (t *triangleArbBot) handleDEXOrderUpdate(o *core.Order) {
+	t.cyclesMtx.Lock()
+	defer t.cyclesMtx.Unlock()
+
+	for i, c := range t.cycles {
+		for j, co := range c.orders {
+			if !bytes.Equal(co.ID, o.ID) {
+				continue
+			}
+			c.orders[j] = o
+			if c.settled() {
+				t.cycles = append(t.cycles[:i], t.cycles[i+1:]...)
+			}
+			return
+		}
+	}
+}
+
+// updateLegs updates the lot sizes and fee estimates of the legs.
+func (t *triangleArbBot) updateLegs() error {
+	t.legsMtx.Lock()
+	defer t.legsMtx.Unlock()
+	for i, leg := range t.legs {
+		lotSize := t.lotSize.Load()
+		if i > 0 {
+			mkt, err := t.ExchangeMarket(t.host, leg.baseID, leg.quoteID)
+			if err != nil {
+				return err
+			}
+			lotSize = mkt.LotSize
+		}
+		baseFees, quoteFees, err := marketFees(t.clientCore, t.host, leg.baseID, leg.quoteID, false)
+		if err != nil {
+			return err
+		}
+		t.legs[i] = &triangleLeg{
+			baseID:    leg.baseID,
+			quoteID:   leg.quoteID,
+			lotSize:   lotSize,
+			book:      leg.book,
+			baseFees:  baseFees,
+			quoteFees: quoteFees,
+		}
+	}
+	return nil
+}
+
+// tryArb executes the best opportunity, if there is one and it would not
+// self-match. The executed opportunity is returned.
+func (t *triangleArbBot) tryArb(newEpoch uint64) (*triangleOpportunity, error) {
+	if !(t.checkBotHealth(newEpoch) && t.tradingLimitNotReached(newEpoch)) {
+		return nil, nil
+	}
+
+	if err := t.updateLegs(); err != nil {
+		return nil, fmt.Errorf("error updating markets: %w", err)
+	}
+
+	opp, err := t.bestOpportunity()
+	if err != nil || opp == nil {
+		return nil, err
+	}
+
+	// Cancel the booked orders of cycles in the opposite direction, which
+	// could match the new orders. The new cycle is executed once they are
+	// off the books.
+	t.cyclesMtx.Lock()
+	var selfMatch bool
+	for _, c := range t.cycles {
+		if c.forward != opp.forward && c.booked() {
+			t.cancelCycle(c)
+			selfMatch = true
+		}
+	}
+	t.cyclesMtx.Unlock()
+	if selfMatch {
+		t.log.Info("cannot execute triangle arb opportunity due to self-match")
+		return nil, nil
+	}
+
+	t.executeCycle(opp, newEpoch)
+	return opp, nil
+}
+
+// rebalance checks if there is a profitable cycle and if so, executes it.
+// Unfilled orders of cycles that have been open too long are canceled.
+func (t *triangleArbBot) rebalance(newEpoch uint64) {
+	if !t.rebalanceRunning.CompareAndSwap(false, true) {
+		return
+	}
+	defer t.rebalanceRunning.Store(false)
+	t.log.Tracef("rebalance: epoch %d", newEpoch)
+
+	epochReport := &EpochReport{EpochNum: newEpoch}
+	if _, err := t.tryArb(newEpoch); err != nil {
+		epochReport.setPreOrderProblems(err)
+	}
+	t.unifiedExchangeAdaptor.updateEpochReport(epochReport)
+
+	t.cyclesMtx.Lock()
+	for _, c := range t.cycles {
+		if !c.canceled && newEpoch-c.startEpoch > uint64(t.cfg().NumEpochsLeaveOpen) {
+			t.cancelCycle(c)
+		}
+	}
+	t.cyclesMtx.Unlock()
+}
+
+// findLeg finds the market pairing two assets on the bot's DEX host.
+func (t *triangleArbBot) findLeg(assetA, assetB uint32) (*triangleLeg, error) {
+	if _, err := t.ExchangeMarket(t.host, assetA, assetB); err == nil {
+		return &triangleLeg{baseID: assetA, quoteID: assetB}, nil
+	}
+	if _, err := t.ExchangeMarket(t.host, assetB, assetA); err == nil {
+		return &triangleLeg{baseID: assetB, quoteID: assetA}, nil
+	}
+	return nil, fmt.Errorf("no %s-%s market on %s", dex.BipIDSymbol(assetA), dex.BipIDSymbol(assetB), t.host)
+}
+
+func (t *triangleArbBot) botLoop(ctx context.Context) (*sync.WaitGroup, error) {
+	intermediateID := t.cfg().IntermediateAssetID
+	quoteLeg, err := t.findLeg(t.dexQuoteID, intermediateID)
+	if err != nil {
+		return nil, err
+	}
+	baseLeg, err := t.findLeg(intermediateID, t.dexBaseID)
+	if err != nil {
+		return nil, err
+	}
+	legs := [3]*triangleLeg{{baseID: t.dexBaseID, quoteID: t.dexQuoteID}, quoteLeg, baseLeg}
+
+	feeds := make([]core.BookFeed, 0, len(legs))
+	closeFeeds := func() {
+		for _, feed := range feeds {
+			feed.Close()
+		}
+	}
+	for _, leg := range legs {
+		book, feed, err := t.SyncBook(t.host, leg.baseID, leg.quoteID)
+		if err != nil {
+			closeFeeds()
+			return nil, fmt.Errorf("failed to sync %s-%s book: %v", dex.BipIDSymbol(leg.baseID), dex.BipIDSymbol(leg.quoteID), err)
+		}
+		leg.book = book
+		feeds = append(feeds, feed)
+	}
+
+	t.legsMtx.Lock()
+	t.legs = legs
+	t.legsMtx.Unlock()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer feeds[0].Close()
+		for {
+			select {
+			case ni, ok := <-feeds[0].Next():
+				if !ok {
+					t.log.Error("Stopping bot due to nil book feed.")
+					t.kill()
+					return
+				}
+				switch epoch := ni.Payload.(type) {
+				case *core.ResolvedEpoch:
+					t.rebalance(epoch.Current)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// The books of the other legs are kept up to date by core, but the
+	// feeds must be drained.
+	for _, feed := range feeds[1:] {
+		wg.Add(1)
+		go func(feed core.BookFeed) {
+			defer wg.Done()
+			defer feed.Close()
+			for {
+				select {
+				case _, ok := <-feed.Next():
+					if !ok {
+						t.log.Error("Stopping bot due to nil book feed.")
+						t.kill()
+						return
+					}
+				case <-ctx.Done():
+					return
+				}
+			}
+		}(feed)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		orderUpdates := t.SubscribeOrderUpdates()
+		for {
+			select {
+			case n := <-orderUpdates:
+				t.handleDEXOrderUpdate(n)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return &wg, nil
+}
+
+func newTriangleArbBot(cfg *BotConfig, adaptorCfg *exchangeAdaptorCfg, log dex.Logger) (*triangleArbBot, error) {
+	if cfg.TriangleArbConfig == nil {
+		// implies bug in caller
+		return nil, fmt.Errorf("no triangle arb config provided")
+	}
+
+	adaptor, err := newUnifiedExchangeAdaptor(adaptorCfg)
+	if err != nil {
+		return nil, fmt.Errorf("error constructing exchange adaptor: %w", err)
+	}
+
+	t := &triangleArbBot{
+		unifiedExchangeAdaptor: adaptor,
+	}
+	adaptor.setBotLoop(t.botLoop)
+	return t, nil
+}
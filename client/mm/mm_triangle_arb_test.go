@@ -0,0 +1,253 @@
+//go:build !harness && !botlive
+
+package mm
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+
+	"decred.org/dcrdex/client/core"
+	"decred.org/dcrdex/dex/encode"
+	"decred.org/dcrdex/dex/order"
+)
+
+// tTriangleCore serves multiple markets and returns a new order for every
+// trade placed.
+type tTriangleCore struct {
+	*tCore
+	markets map[[2]uint32]*core.Market
+	orders  []*core.Order
+	failOn  int
+}
+
+func (c *tTriangleCore) ExchangeMarket(host string, baseID, quoteID uint32) (*core.Market, error) {
+	if mkt, found := c.markets[[2]uint32{baseID, quoteID}]; found {
+		return mkt, nil
+	}
+	return nil, fmt.Errorf("no market")
+}
+
+func (c *tTriangleCore) MultiTrade(pw []byte, form *core.MultiTradeForm) []*core.MultiTradeResult {
+	c.multiTradesPlaced = append(c.multiTradesPlaced, form)
+	if len(c.multiTradesPlaced) == c.failOn {
+		return []*core.MultiTradeResult{{Error: errors.New("test error")}}
+	}
+	var oid order.OrderID
+	copy(oid[:], encode.RandomBytes(32))
+	o := &core.Order{
+		ID:      oid[:],
+		Host:    form.Host,
+		BaseID:  form.Base,
+		QuoteID: form.Quote,
+		Sell:    form.Sell,
+		Qty:     form.Placements[0].Qty,
+		Rate:    form.Placements[0].Rate,
+		Status:  order.OrderStatusBooked,
+	}
+	c.orders = append(c.orders, o)
+	return []*core.MultiTradeResult{{Order: o}}
+}
+
+func TestTriangleArb(t *testing.T) {
+	const dcrID, btcID, ltcID = 42, 0, 2
+	const epochsOpen = 2
+
+	cfg := &TriangleArbConfig{
+		IntermediateAssetID: ltcID,
+		MinProfit:           0.05,
+		MaxInFlightLots:     1,
+		NumEpochsLeaveOpen:  epochsOpen,
+	}
+	if err := cfg.validate(dcrID, btcID); err != nil {
+		t.Fatalf("unexpected error validating config: %v", err)
+	}
+	for _, c := range []*TriangleArbConfig{
+		{IntermediateAssetID: btcID, MinProfit: 0.05, MaxInFlightLots: 1, NumEpochsLeaveOpen: 2},
+		{IntermediateAssetID: ltcID, MinProfit: 0, MaxInFlightLots: 1, NumEpochsLeaveOpen: 2},
+		{IntermediateAssetID: ltcID, MinProfit: 0.05, MaxInFlightLots: 0, NumEpochsLeaveOpen: 2},
+		{IntermediateAssetID: ltcID, MinProfit: 0.05, MaxInFlightLots: 1, NumEpochsLeaveOpen: 1},
+	} {
+		if err := c.validate(dcrID, btcID); err == nil {
+			t.Fatalf("no error for invalid config %+v", c)
+		}
+	}
+
+	// DCR = $20, BTC = $50,000, LTC = $100. The DCR-BTC bids are at a 10%
+	// premium, so selling DCR for BTC, BTC for LTC, and LTC for DCR is
+	// profitable.
+	fiatRates := map[uint32]float64{dcrID: 20, btcID: 50_000, ltcID: 100}
+	dcrBTC := &core.Market{BaseID: dcrID, QuoteID: btcID, LotSize: 1e8, RateStep: 1e2, AtomToConv: 1}
+	ltcBTC := &core.Market{BaseID: ltcID, QuoteID: btcID, LotSize: 1e7, RateStep: 1e2, AtomToConv: 1}
+	dcrLTC := &core.Market{BaseID: dcrID, QuoteID: ltcID, LotSize: 1e8, RateStep: 1e2, AtomToConv: 1}
+	books := [3]*tOrderBook{
+		{
+			bidsVWAP: map[uint64]vwapResult{1: {4.4e4, 4.4e4}, 2: {4.3e4, 4.2e4}},
+			asksVWAP: map[uint64]vwapResult{1: {4.1e4, 4.1e4}},
+		},
+		{
+			bidsVWAP: map[uint64]vwapResult{1: {1.9e5, 1.9e5}},
+			asksVWAP: map[uint64]vwapResult{1: {2e5, 2e5}, 2: {2e5, 2e5}, 3: {2e5, 2e5}, 4: {2.05e5, 2.1e5}},
+		},
+		{
+			bidsVWAP: map[uint64]vwapResult{1: {1.9e7, 1.9e7}},
+			asksVWAP: map[uint64]vwapResult{1: {2e7, 2e7}, 2: {2e7, 2e7}},
+		},
+	}
+	const fee = 100
+	lotFees := &LotFees{Swap: fee, Redeem: fee, Refund: fee}
+	legs := [3]*triangleLeg{
+		{baseID: dcrID, quoteID: btcID, lotSize: 1e8, book: books[0], baseFees: lotFees, quoteFees: lotFees},
+		{baseID: ltcID, quoteID: btcID, lotSize: 1e7, book: books[1], baseFees: lotFees, quoteFees: lotFees},
+		{baseID: dcrID, quoteID: ltcID, lotSize: 1e8, book: books[2], baseFees: lotFees, quoteFees: lotFees},
+	}
+
+	// Forward, 1 lot: sell 1 DCR for 44,000 sats, buy 0.2 LTC for 40,000
+	// sats, and buy 1 DCR for 0.2 LTC. The profit is the $2 premium, less
+	// the fees.
+	opp, ok, err := simulateCycle(legs, true, 1, fiatRates)
+	if err != nil || !ok {
+		t.Fatalf("error simulating cycle: ok = %t, err = %v", ok, err)
+	}
+	const expFeesUSD = fee*(20+50_000)/1e8 + 2*fee*(50_000+100)/1e8 + fee*(100+20)/1e8
+	if expProfit := 2 - expFeesUSD; math.Abs(opp.profitUSD-expProfit) > 1e-9 {
+		t.Fatalf("wrong profit. wanted %f, got %f", expProfit, opp.profitUSD)
+	}
+	if tr := opp.trades[1]; tr.sell || tr.qty != 2e7 || tr.spent != 4e4 {
+		t.Fatalf("wrong second leg %+v", tr)
+	}
+	if opp, ok, _ := simulateCycle(legs, false, 1, fiatRates); ok && opp.profitUSD >= 0 {
+		t.Fatalf("reverse cycle should not be profitable")
+	}
+
+	newBot := func(failOn int) (*triangleArbBot, *tTriangleCore) {
+		u := mustParseAdaptorFromMarket(dcrBTC)
+		tc := &tTriangleCore{
+			tCore:   u.clientCore.(*tCore),
+			markets: map[[2]uint32]*core.Market{{dcrID, btcID}: dcrBTC, {ltcID, btcID}: ltcBTC, {dcrID, ltcID}: dcrLTC},
+			failOn:  failOn,
+		}
+		tc.parcelLimit = 1
+		tc.singleLotSellFees = &OrderFees{LotFeeRange: &LotFeeRange{Max: lotFees}}
+		tc.singleLotBuyFees = &OrderFees{LotFeeRange: &LotFeeRange{Max: lotFees}}
+		u.clientCore = tc
+		u.fiatRates.Store(fiatRates)
+		u.baseDexBalances = map[uint32]int64{dcrID: 10e8, btcID: 1e6, ltcID: 1e8}
+		u.botCfgV.Store(&BotConfig{
+			Host:              u.host,
+			BaseID:            dcrID,
+			QuoteID:           btcID,
+			TriangleArbConfig: cfg,
+		})
+		bot := &triangleArbBot{unifiedExchangeAdaptor: u}
+		for i, leg := range legs {
+			l := *leg
+			bot.legs[i] = &l
+		}
+		return bot, tc
+	}
+
+	bot, tc := newBot(0)
+
+	// The 2 lot cycle is less profitable than the 1 lot cycle.
+	cfg.MaxInFlightLots = 2
+	opp, err = bot.bestOpportunity()
+	if err != nil || opp == nil || !opp.forward || opp.lots != 1 {
+		t.Fatalf("wrong best opportunity %+v, err = %v", opp, err)
+	}
+	// Larger cycles are not filled by the books.
+	cfg.MaxInFlightLots = 10
+	opp, err = bot.bestOpportunity()
+	if err != nil || opp == nil || !opp.forward || opp.lots != 1 {
+		t.Fatalf("wrong best opportunity with excess lots %+v, err = %v", opp, err)
+	}
+	cfg.MinProfit = 0.1
+	if opp, _ := bot.bestOpportunity(); opp != nil {
+		t.Fatalf("opportunity found below min profit")
+	}
+	cfg.MinProfit = 0.05
+	cfg.MaxInFlightLots = 1
+
+	// Insufficient LTC balance for the last leg.
+	bot.baseDexBalances[ltcID] = 1e7
+	if opp, _ := bot.bestOpportunity(); opp != nil {
+		t.Fatalf("opportunity found with insufficient balance")
+	}
+	bot.baseDexBalances[ltcID] = 1e8
+
+	bot.rebalance(1)
+	if len(tc.multiTradesPlaced) != 3 {
+		t.Fatalf("expected 3 trades, got %d", len(tc.multiTradesPlaced))
+	}
+	for i, exp := range []struct {
+		base, quote uint32
+		sell        bool
+		qty, rate   uint64
+	}{
+		{dcrID, btcID, true, 1e8, 4.4e4},
+		{ltcID, btcID, false, 2e7, 2e5},
+		{dcrID, ltcID, false, 1e8, 2e7},
+	} {
+		form := tc.multiTradesPlaced[i]
+		if form.Base != exp.base || form.Quote != exp.quote || form.Sell != exp.sell ||
+			form.Placements[0].Qty != exp.qty || form.Placements[0].Rate != exp.rate {
+			t.Fatalf("wrong trade %d: %+v, %+v", i, form, form.Placements[0])
+		}
+	}
+	if bot.inFlightLots() != 1 {
+		t.Fatalf("wrong in-flight lots %d", bot.inFlightLots())
+	}
+
+	// At max in-flight lots.
+	bot.rebalance(2)
+	if len(tc.multiTradesPlaced) != 3 {
+		t.Fatalf("trade placed with max in-flight lots")
+	}
+
+	// The cycle is in-flight until all orders have settled.
+	for i, o := range tc.orders {
+		settled := *o
+		settled.Status = order.OrderStatusExecuted
+		settled.AllFeesConfirmed = true
+		bot.handleDEXOrderUpdate(&settled)
+		if inFlight := bot.inFlightLots(); (i < 2 && inFlight != 1) || (i == 2 && inFlight != 0) {
+			t.Fatalf("wrong in-flight lots %d after %d orders settled", inFlight, i+1)
+		}
+	}
+
+	// Unfilled orders are canceled after NumEpochsLeaveOpen.
+	bot.rebalance(10)
+	if len(tc.multiTradesPlaced) != 6 {
+		t.Fatalf("expected 6 trades, got %d", len(tc.multiTradesPlaced))
+	}
+	bot.rebalance(10 + epochsOpen)
+	if len(tc.cancelsPlaced) != 0 {
+		t.Fatalf("orders canceled early")
+	}
+	bot.rebalance(11 + epochsOpen)
+	if len(tc.cancelsPlaced) != 3 {
+		t.Fatalf("expected 3 cancels, got %d", len(tc.cancelsPlaced))
+	}
+
+	// If a leg fails, the other legs are canceled.
+	bot, tc = newBot(3)
+	bot.rebalance(1)
+	if len(tc.multiTradesPlaced) != 3 || len(tc.cancelsPlaced) != 2 || bot.inFlightLots() != 0 {
+		t.Fatalf("legs not canceled after failed trade. %d trades, %d cancels", len(tc.multiTradesPlaced), len(tc.cancelsPlaced))
+	}
+
+	// Booked orders of a reverse cycle are canceled rather than executing a
+	// cycle that could match them.
+	cfg.MaxInFlightLots = 2
+	bot, tc = newBot(0)
+	reverse := &core.Order{ID: encode.RandomBytes(32), Status: order.OrderStatusBooked}
+	bot.cycles = []*triangleCycle{{lots: 1, orders: []*core.Order{reverse}}}
+	opp, err = bot.tryArb(1)
+	if err != nil || opp != nil {
+		t.Fatalf("opportunity returned after self-match: %+v, err = %v", opp, err)
+	}
+	if len(tc.multiTradesPlaced) != 0 || len(tc.cancelsPlaced) != 1 || !bot.cycles[0].canceled {
+		t.Fatalf("reverse cycle not canceled. %d trades, %d cancels", len(tc.multiTradesPlaced), len(tc.cancelsPlaced))
+	}
+}
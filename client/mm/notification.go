@@ -4,6 +4,8 @@
 package mm
 
 import (
+	"fmt"
+
 	"decred.org/dcrdex/client/db"
 )
 
@@ -13,6 +15,7 @@ const (
 	NoteTypeCEXNotification = "cexnote"
 	NoteTypeEpochReport     = "epochreport"
 	NoteTypeCEXProblems     = "cexproblems"
+	NoteTypeRiskLimit       = "risklimit"
 )
 
 type runStatsNote struct {
@@ -109,3 +112,35 @@ func newCexProblemsNote(host string, baseID, quoteID uint32, problems *CEXProble
 		Problems:     problems,
 	}
 }
+
+const (
+	TopicKillSwitch   = "KillSwitch"
+	TopicBotRiskLimit = "BotRiskLimit"
+)
+
+type riskLimitNote struct {
+	db.Notification
+	Host    string `json:"host,omitempty"`
+	BaseID  uint32 `json:"baseID,omitempty"`
+	QuoteID uint32 `json:"quoteID,omitempty"`
+	Reason  string `json:"reason"`
+}
+
+func newKillSwitchNote(reason error) *riskLimitNote {
+	return &riskLimitNote{
+		Notification: db.NewNotification(NoteTypeRiskLimit, TopicKillSwitch, "Market making kill switch",
+			fmt.Sprintf("All bots were stopped and their orders canceled: %v", reason), db.ErrorLevel),
+		Reason: reason.Error(),
+	}
+}
+
+func newBotRiskLimitNote(mkt *MarketWithHost, reason error) *riskLimitNote {
+	return &riskLimitNote{
+		Notification: db.NewNotification(NoteTypeRiskLimit, TopicBotRiskLimit, "Market making bot stopped",
+			fmt.Sprintf("The bot on %s was stopped: %v", mkt, reason), db.WarningLevel),
+		Host:    mkt.Host,
+		BaseID:  mkt.BaseID,
+		QuoteID: mkt.QuoteID,
+		Reason:  reason.Error(),
+	}
+}
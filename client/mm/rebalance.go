@@ -0,0 +1,555 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package mm
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"decred.org/dcrdex/client/asset"
+)
+
+// This file contains the alternatives to CEX deposits and withdrawals that
+// the auto-rebalancer can use if they are enabled in the AutoRebalanceConfig.
+//
+// Bot transfers swap DEX funds for CEX funds with another running bot that
+// uses the same wallet and CEX. They are free, so they are always tried
+// first.
+//
+// Bridge rebalances are used in place of CEX withdrawals. Unallocated funds
+// of the same token on another chain are bridged into the bot's DEX wallet,
+// and the bot's CEX balance is released in exchange. If there are multiple
+// paths, the one with the lowest fees, as reported by the source wallet's
+// BridgeInitiationFeesAndLimits and the destination wallet's
+// BridgeCompletionFees, is used.
+
+type doBridgeTransferFunc func(dexAvailable map[uint32]uint64) error
+
+// pendingBridge represents a bridge of unallocated funds into one of the
+// bot's DEX wallets that has not yet been completed.
+type pendingBridge struct {
+	eventLogID  uint64
+	timestamp   int64
+	fromAssetID uint32
+	toAssetID   uint32
+	cexAssetID  uint32
+	bridgeName  string
+	// cexDebit is the amount of the bot's CEX balance that was released in
+	// exchange for the bridged funds.
+	cexDebit   uint64
+	confirming atomic.Bool
+
+	txMtx    sync.RWMutex
+	bridgeTx *asset.WalletTransaction
+}
+
+func bridgeComplete(bridgeTx *asset.WalletTransaction) bool {
+	return bridgeTx != nil && bridgeTx.BridgeCounterpartTx != nil && bridgeTx.BridgeCounterpartTx.Complete
+}
+
+func bridgeRebalanceBalanceEffects(bridgeTx *asset.WalletTransaction, toAssetID, cexAssetID uint32, cexDebit uint64) (dex, cex *BalanceEffects) {
+	dex, cex = newBalanceEffects(), newBalanceEffects()
+
+	cex.Settled[cexAssetID] = -int64(cexDebit)
+
+	// The funds and initiation fees on the source chain are taken from
+	// unallocated funds, but the bot pays the fees on the destination chain.
+	if bridgeComplete(bridgeTx) {
+		dex.Settled[toAssetID] += int64(bridgeTx.BridgeCounterpartTx.AmountReceived)
+		dex.Settled[feeAssetID(toAssetID)] -= int64(bridgeTx.BridgeCounterpartTx.Fees)
+	} else {
+		dex.Pending[toAssetID] += bridgeTx.Amount
+	}
+
+	return
+}
+
+func (b *pendingBridge) balanceEffects() (dex, cex *BalanceEffects) {
+	b.txMtx.RLock()
+	defer b.txMtx.RUnlock()
+
+	return bridgeRebalanceBalanceEffects(b.bridgeTx, b.toAssetID, b.cexAssetID, b.cexDebit)
+}
+
+// updateBridgeEvent updates the event log with the current state of a
+// pending bridge and sends an event notification.
+func (u *unifiedExchangeAdaptor) updateBridgeEvent(b *pendingBridge) {
+	b.txMtx.RLock()
+	bridgeTx := b.bridgeTx
+	b.txMtx.RUnlock()
+
+	e := &MarketMakingEvent{
+		ID:             b.eventLogID,
+		TimeStamp:      b.timestamp,
+		BalanceEffects: combineBalanceEffects(b.balanceEffects()),
+		Pending:        !bridgeComplete(bridgeTx),
+		BridgeEvent: &BridgeEvent{
+			FromAssetID: b.fromAssetID,
+			ToAssetID:   b.toAssetID,
+			CEXAssetID:  b.cexAssetID,
+			BridgeName:  b.bridgeName,
+			BridgeTx:    bridgeTx,
+			CEXDebit:    b.cexDebit,
+		},
+	}
+
+	u.eventLogDB.storeEvent(u.startTime.Load(), u.mwh, e, u.balanceState())
+	u.notifyEvent(e)
+}
+
+// bridgePath is a possible source of funds for a bridge rebalance.
+type bridgePath struct {
+	fromAssetID uint32
+	bridgeName  string
+	feesUSD     float64
+}
+
+// bridgeSources returns the assets that can be bridged to toAssetID, and the
+// names of the bridges that can be used for each.
+func bridgeSources(paths map[uint32]map[uint32][]string, toAssetID uint32) map[uint32][]string {
+	sources := make(map[uint32][]string)
+	for fromAssetID, dests := range paths {
+		if fromAssetID == toAssetID {
+			continue
+		}
+		if bridgeNames := dests[toAssetID]; len(bridgeNames) > 0 {
+			sources[fromAssetID] = bridgeNames
+		}
+	}
+	return sources
+}
+
+// feesInUSD converts the fees returned by BridgeFeesAndLimits to USD. An
+// error is returned if the fiat rate of any of the fee assets is unknown.
+func feesInUSD(fees map[uint32]uint64, fiatRates map[uint32]float64) (float64, error) {
+	var usd float64
+	for assetID, atoms := range fees {
+		if atoms == 0 {
+			continue
+		}
+		rate := fiatRates[assetID]
+		if rate == 0 {
+			return 0, fmt.Errorf("no fiat rate for %d", assetID)
+		}
+		ui, err := asset.UnitInfo(assetID)
+		if err != nil {
+			return 0, err
+		}
+		usd += float64(atoms) / float64(ui.Conventional.ConversionFactor) * rate
+	}
+	return usd, nil
+}
+
+// cheapestBridgePath finds the bridge with the lowest fees that can be used
+// to move amt of unallocated funds to toAssetID. nil is returned if there is
+// no bridge that can be used.
+func (u *unifiedExchangeAdaptor) cheapestBridgePath(sources map[uint32][]string, toAssetID uint32, amt uint64, dexAvailable map[uint32]uint64) *bridgePath {
+	fiatRates := u.clientCore.FiatConversionRates()
+
+	var best *bridgePath
+	for fromAssetID, bridgeNames := range sources {
+		if dexAvailable[fromAssetID] < amt {
+			continue
+		}
+		for _, bridgeName := range bridgeNames {
+			feesAndLimits, err := u.clientCore.BridgeFeesAndLimits(fromAssetID, toAssetID, bridgeName)
+			if err != nil {
+				u.log.Debugf("Error getting %s bridge fees from %d to %d: %v", bridgeName, fromAssetID, toAssetID, err)
+				continue
+			}
+			if feesAndLimits.HasLimits && (amt < feesAndLimits.MinLimit || amt > feesAndLimits.MaxLimit) {
+				continue
+			}
+			feesUSD, err := feesInUSD(feesAndLimits.Fees, fiatRates)
+			if err != nil {
+				u.log.Debugf("Unable to value %s bridge fees from %d to %d: %v", bridgeName, fromAssetID, toAssetID, err)
+				continue
+			}
+			if best == nil || feesUSD < best.feesUSD {
+				best = &bridgePath{
+					fromAssetID: fromAssetID,
+					bridgeName:  bridgeName,
+					feesUSD:     feesUSD,
+				}
+			}
+		}
+	}
+
+	return best
+}
+
+// bridgeRebalance attempts to bridge amt of unallocated funds of the same
+// token on another chain to dexAssetID in place of withdrawing amt of
+// cexAssetID from the CEX. The bot's CEX balance is debited by amt. false is
+// returned if there was no path with sufficient unallocated funds.
+func (u *unifiedExchangeAdaptor) bridgeRebalance(dexAssetID, cexAssetID uint32, amt uint64) (bool, error) {
+	paths, err := u.clientCore.AllBridgePaths()
+	if err != nil {
+		return false, fmt.Errorf("error getting bridge paths: %w", err)
+	}
+	sources := bridgeSources(paths, dexAssetID)
+	if len(sources) == 0 {
+		return false, nil
+	}
+	fromAssetIDs := make([]uint32, 0, len(sources))
+	for assetID := range sources {
+		fromAssetIDs = append(fromAssetIDs, assetID)
+	}
+
+	var bridgeTx *asset.WalletTransaction
+	var path *bridgePath
+	err = u.bridgeTransfer(u.mwh, fromAssetIDs, amt, func(dexAvailable map[uint32]uint64) error {
+		path = u.cheapestBridgePath(sources, dexAssetID, amt, dexAvailable)
+		if path == nil {
+			return nil
+		}
+
+		txID, err := u.Bridge(path.fromAssetID, dexAssetID, amt, path.bridgeName)
+		if err != nil {
+			return fmt.Errorf("failed to initiate bridge from asset %d to asset %d using bridge %s: %w",
+				path.fromAssetID, dexAssetID, path.bridgeName, err)
+		}
+
+		bridgeTx, err = u.clientCore.WalletTransaction(path.fromAssetID, txID)
+		if err != nil {
+			return fmt.Errorf("failed to retrieve bridge transaction %s for asset %d: %w", txID, path.fromAssetID, err)
+		}
+
+		return nil
+	})
+	if err != nil || path == nil {
+		return false, err
+	}
+
+	u.log.Infof("Bridged %s from asset %d using %s in place of a withdrawal. TxID = %s",
+		u.fmtQty(dexAssetID, amt), path.fromAssetID, path.bridgeName, bridgeTx.ID)
+
+	if dexAssetID == u.dexBaseID {
+		u.pendingBaseRebalance.Store(true)
+	} else {
+		u.pendingQuoteRebalance.Store(true)
+	}
+
+	b := &pendingBridge{
+		eventLogID:  u.eventLogID.Add(1),
+		timestamp:   time.Now().Unix(),
+		fromAssetID: path.fromAssetID,
+		toAssetID:   dexAssetID,
+		cexAssetID:  cexAssetID,
+		bridgeName:  path.bridgeName,
+		cexDebit:    amt,
+		bridgeTx:    bridgeTx,
+	}
+
+	u.balancesMtx.Lock()
+	u.pendingBridges[bridgeTx.ID] = b
+	u.balancesMtx.Unlock()
+
+	u.updateBridgeEvent(b)
+	u.sendStatsUpdate()
+
+	txID := bridgeTx.ID
+	u.wg.Add(1)
+	go func() {
+		defer u.wg.Done()
+		timer := time.NewTimer(time.Minute)
+		defer timer.Stop()
+		for {
+			select {
+			case <-timer.C:
+				if u.confirmBridge(u.ctx, txID) {
+					return
+				}
+				timer.Reset(time.Minute)
+			case <-u.ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return true, nil
+}
+
+// pendingBridgeComplete is called after a bridge has been completed. The
+// balance effects are applied to the base balances, and the bridge is removed
+// from the pending map.
+func (u *unifiedExchangeAdaptor) pendingBridgeComplete(txID string) {
+	u.balancesMtx.Lock()
+	b, found := u.pendingBridges[txID]
+	if !found {
+		u.balancesMtx.Unlock()
+		return
+	}
+
+	delete(u.pendingBridges, txID)
+
+	if b.toAssetID == u.dexBaseID {
+		u.pendingBaseRebalance.Store(false)
+	} else {
+		u.pendingQuoteRebalance.Store(false)
+	}
+
+	dexEffects, cexEffects := b.balanceEffects()
+	for assetID, v := range dexEffects.Settled {
+		u.baseDexBalances[assetID] += v
+	}
+	for assetID, v := range cexEffects.Settled {
+		u.baseCexBalances[assetID] += v
+	}
+	u.balancesMtx.Unlock()
+
+	u.updateBridgeEvent(b)
+	u.sendStatsUpdate()
+
+	u.balancesMtx.RLock()
+	u.logBalanceAdjustments(dexEffects.Settled, cexEffects.Settled, fmt.Sprintf("Bridge %s complete.", txID))
+	u.balancesMtx.RUnlock()
+}
+
+// confirmBridge checks whether a pending bridge has been completed. true is
+// returned if the bridge is no longer pending.
+func (u *unifiedExchangeAdaptor) confirmBridge(ctx context.Context, txID string) bool {
+	u.balancesMtx.RLock()
+	b, found := u.pendingBridges[txID]
+	u.balancesMtx.RUnlock()
+	if !found {
+		return true
+	}
+
+	if !b.confirming.CompareAndSwap(false, true) {
+		return false
+	}
+	defer b.confirming.Store(false)
+
+	if ctx.Err() != nil {
+		return false
+	}
+
+	tx, err := u.clientCore.WalletTransaction(b.fromAssetID, txID)
+	if err != nil {
+		u.log.Errorf("Error getting bridge transaction %s: %v", txID, err)
+		return false
+	}
+
+	b.txMtx.Lock()
+	b.bridgeTx = tx
+	b.txMtx.Unlock()
+
+	if !bridgeComplete(tx) {
+		u.updateBridgeEvent(b)
+		return false
+	}
+
+	u.pendingBridgeComplete(txID)
+	return true
+}
+
+// botTransferBalanceEffects returns the effects of a bot transfer on the bot
+// that requested it. If toDEX is true, the bot receives DEX funds and gives
+// up CEX funds.
+func botTransferBalanceEffects(dexAssetID, cexAssetID uint32, amt uint64, toDEX bool) (dexDiffs, cexDiffs map[uint32]int64) {
+	dexDiffs, cexDiffs = make(map[uint32]int64), make(map[uint32]int64)
+	if toDEX {
+		dexDiffs[dexAssetID] = int64(amt)
+		cexDiffs[cexAssetID] = -int64(amt)
+	} else {
+		dexDiffs[dexAssetID] = -int64(amt)
+		cexDiffs[cexAssetID] = int64(amt)
+	}
+	return
+}
+
+// applyBotTransfer applies the balance effects of a bot transfer with peer
+// and records it in the event log. If toDEX is true, this bot receives DEX
+// funds and gives up CEX funds.
+func (u *unifiedExchangeAdaptor) applyBotTransfer(peer *MarketWithHost, dexAssetID, cexAssetID uint32, amt uint64, toDEX bool) {
+	u.balancesMtx.Lock()
+	dexDiffs, cexDiffs := u.adjustBotTransferBalances(dexAssetID, cexAssetID, amt, toDEX)
+	u.balancesMtx.Unlock()
+
+	u.recordBotTransfer(peer, dexAssetID, cexAssetID, amt, toDEX, dexDiffs, cexDiffs)
+}
+
+// adjustBotTransferBalances applies the balance effects of a bot transfer to
+// the base balances. The balancesMtx MUST be locked.
+func (u *unifiedExchangeAdaptor) adjustBotTransferBalances(dexAssetID, cexAssetID uint32, amt uint64, toDEX bool) (dexDiffs, cexDiffs map[uint32]int64) {
+	dexDiffs, cexDiffs = botTransferBalanceEffects(dexAssetID, cexAssetID, amt, toDEX)
+	for assetID, diff := range dexDiffs {
+		u.baseDexBalances[assetID] += diff
+	}
+	for assetID, diff := range cexDiffs {
+		u.baseCexBalances[assetID] += diff
+	}
+	return dexDiffs, cexDiffs
+}
+
+// recordBotTransfer records a bot transfer that has been applied to the
+// balances in the event log.
+func (u *unifiedExchangeAdaptor) recordBotTransfer(peer *MarketWithHost, dexAssetID, cexAssetID uint32, amt uint64, toDEX bool, dexDiffs, cexDiffs map[uint32]int64) {
+	effects := newBalanceEffects()
+	for assetID, diff := range dexDiffs {
+		effects.Settled[assetID] += diff
+	}
+	for assetID, diff := range cexDiffs {
+		effects.Settled[assetID] += diff
+	}
+
+	e := &MarketMakingEvent{
+		ID:             u.eventLogID.Add(1),
+		TimeStamp:      time.Now().Unix(),
+		BalanceEffects: effects,
+		BotTransferEvent: &BotTransferEvent{
+			Peer:       peer,
+			DEXAssetID: dexAssetID,
+			CEXAssetID: cexAssetID,
+			Amount:     amt,
+			ToDEX:      toDEX,
+		},
+	}
+	u.eventLogDB.storeEvent(u.startTime.Load(), u.mwh, e, u.balanceState())
+	u.notifyEvent(e)
+	u.sendStatsUpdate()
+
+	u.balancesMtx.RLock()
+	u.logBalanceAdjustments(dexDiffs, cexDiffs, fmt.Sprintf("bot transfer with %s", peer))
+	u.balancesMtx.RUnlock()
+}
+
+// acceptBotTransfer is called on a bot when another bot requests a bot
+// transfer. toDEX is from the perspective of the requesting bot, so if it is
+// true, this bot gives up DEX funds and receives CEX funds. The transfer is
+// only accepted if this bot has enabled bot transfers, trades the same
+// assets, and would not be left with less of the asset on the giving side
+// than on the receiving side. If the transfer is accepted, it is applied to
+// this bot's balances before returning true. The balances are checked and
+// updated with the balancesMtx locked, so concurrent transfers cannot spend
+// the same funds.
+func (u *unifiedExchangeAdaptor) acceptBotTransfer(peer *MarketWithHost, dexAssetID, cexAssetID uint32, amt uint64, toDEX bool) bool {
+	autoRebalanceCfg := u.autoRebalanceCfg()
+	if autoRebalanceCfg == nil || !autoRebalanceCfg.BotTransfers || u.CEX == nil {
+		return false
+	}
+
+	botCfg := u.botCfg()
+	switch {
+	case dexAssetID == u.dexBaseID && cexAssetID == botCfg.CEXBaseID:
+		if u.pendingBaseRebalance.Load() {
+			return false
+		}
+	case dexAssetID == u.dexQuoteID && cexAssetID == botCfg.CEXQuoteID:
+		if u.pendingQuoteRebalance.Load() {
+			return false
+		}
+	default:
+		return false
+	}
+
+	u.balancesMtx.Lock()
+	// A bot that is stopping has already released its balances.
+	if u.ctx.Err() != nil {
+		u.balancesMtx.Unlock()
+		return false
+	}
+	dexBal, cexBal := u.dexBalance(dexAssetID), u.cexBalance(cexAssetID)
+	dexTotal := dexBal.Available + dexBal.Locked + dexBal.Pending
+	cexTotal := cexBal.Available + cexBal.Reserved + cexBal.Pending
+	giveAvail, giveTotal, receiveTotal := cexBal.Available, cexTotal, dexTotal
+	if toDEX {
+		giveAvail, giveTotal, receiveTotal = dexBal.Available, dexTotal, cexTotal
+	}
+	if giveAvail < amt || giveTotal-amt < receiveTotal+amt {
+		u.balancesMtx.Unlock()
+		return false
+	}
+	dexDiffs, cexDiffs := u.adjustBotTransferBalances(dexAssetID, cexAssetID, amt, !toDEX)
+	u.balancesMtx.Unlock()
+
+	u.recordBotTransfer(peer, dexAssetID, cexAssetID, amt, !toDEX, dexDiffs, cexDiffs)
+	return true
+}
+
+// tryBotTransfer requests a bot transfer from another bot that uses the same
+// wallet and CEX. true is returned if a bot accepted the transfer.
+func (u *unifiedExchangeAdaptor) tryBotTransfer(dexAssetID, cexAssetID uint32, amt uint64, toDEX bool) (bool, error) {
+	u.balancesMtx.RLock()
+	var avail uint64
+	if toDEX {
+		avail = u.cexBalance(cexAssetID).Available
+	} else {
+		avail = u.dexBalance(dexAssetID).Available
+	}
+	u.balancesMtx.RUnlock()
+	if avail < amt {
+		return false, nil
+	}
+
+	peer, err := u.botTransfer(u.mwh, dexAssetID, cexAssetID, amt, toDEX)
+	if err != nil || peer == nil {
+		return false, err
+	}
+
+	u.applyBotTransfer(peer, dexAssetID, cexAssetID, amt, toDEX)
+	return true, nil
+}
+
+// tryAlternativeTransfers attempts to fulfill the deposits and withdrawals in
+// the distribution using bot transfers and bridges, if they are enabled.
+// Any transfers that are fulfilled are zeroed in the distribution so that
+// they are not also done through the CEX.
+func (u *unifiedExchangeAdaptor) tryAlternativeTransfers(dist *distribution, autoRebalanceCfg *AutoRebalanceConfig) error {
+	if !autoRebalanceCfg.BotTransfers && !autoRebalanceCfg.BridgeRebalance {
+		return nil
+	}
+
+	botCfg := u.botCfg()
+	try := func(inv *assetInventory, dexAssetID, cexAssetID uint32) error {
+		if autoRebalanceCfg.BotTransfers {
+			if inv.toWithdraw > 0 {
+				ok, err := u.tryBotTransfer(dexAssetID, cexAssetID, inv.toWithdraw, true)
+				if err != nil {
+					return err
+				}
+				if ok {
+					inv.toWithdraw = 0
+				}
+			} else if inv.toDeposit > 0 {
+				ok, err := u.tryBotTransfer(dexAssetID, cexAssetID, inv.toDeposit, false)
+				if err != nil {
+					return err
+				}
+				if ok {
+					inv.toDeposit = 0
+				}
+			}
+		}
+
+		if autoRebalanceCfg.BridgeRebalance && inv.toWithdraw > 0 && inv.cexAvail >= inv.toWithdraw {
+			ok, err := u.bridgeRebalance(dexAssetID, cexAssetID, inv.toWithdraw)
+			if err != nil {
+				return err
+			}
+			if ok {
+				inv.toWithdraw = 0
+			}
+		}
+
+		return nil
+	}
+
+	if !u.pendingBaseRebalance.Load() {
+		if err := try(dist.baseInv, u.dexBaseID, botCfg.CEXBaseID); err != nil {
+			return fmt.Errorf("error rebalancing base: %w", err)
+		}
+	}
+	if !u.pendingQuoteRebalance.Load() {
+		if err := try(dist.quoteInv, u.dexQuoteID, botCfg.CEXQuoteID); err != nil {
+			return fmt.Errorf("error rebalancing quote: %w", err)
+		}
+	}
+
+	return nil
+}
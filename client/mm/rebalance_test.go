@@ -0,0 +1,294 @@
+//go:build !harness && !botlive
+
+package mm
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"decred.org/dcrdex/client/asset"
+	"decred.org/dcrdex/client/core"
+)
+
+func TestBridgeRebalance(t *testing.T) {
+	const (
+		ethID         = 60
+		polygonID     = 966
+		usdcEthID     = 60001
+		usdcPolygonID = 966001
+		dcrID         = 42
+		bridgeTxID    = "bridge_tx_id"
+	)
+
+	newAdaptor := func(unallocated map[uint32]uint64) (*unifiedExchangeAdaptor, *tCore) {
+		tCore := newTCore()
+		tCore.fiatRates = map[uint32]float64{ethID: 2000, polygonID: 0.5, usdcEthID: 1, usdcPolygonID: 1}
+		tCore.bridgePaths = map[uint32]map[uint32][]string{
+			usdcEthID:     {usdcPolygonID: {"across", "cctp", "limited"}},
+			usdcPolygonID: {usdcEthID: {"across", "cctp"}},
+		}
+		tCore.bridgeFeesAndLimits = map[string]*core.BridgeFeesAndLimits{
+			// 0.002 ETH = $4
+			"across": {Fees: map[uint32]uint64{ethID: 2e6}},
+			// 0.0005 ETH + 1 POL = $1.50
+			"cctp": {Fees: map[uint32]uint64{ethID: 5e5, polygonID: 1e9}},
+			// Free, but the amount is below the minimum.
+			"limited": {Fees: map[uint32]uint64{}, HasLimits: true, MinLimit: 1e12, MaxLimit: 1e13},
+		}
+		tCore.walletTxs[bridgeTxID] = &asset.WalletTransaction{
+			ID:                  bridgeTxID,
+			Amount:              2e6,
+			BridgeCounterpartTx: &asset.BridgeCounterpartTx{AssetID: usdcPolygonID},
+		}
+
+		u := mustParseAdaptor(&exchangeAdaptorCfg{
+			mwh: &MarketWithHost{
+				Host:    "dex.com",
+				BaseID:  dcrID,
+				QuoteID: usdcPolygonID,
+			},
+			core:                tCore,
+			cex:                 newTCEX(),
+			eventLogDB:          newTEventLogDB(),
+			autoRebalanceConfig: &AutoRebalanceConfig{BridgeRebalance: true},
+			baseDexBalances:     map[uint32]uint64{dcrID: 1e9, usdcPolygonID: 1e6, polygonID: 1e10},
+			baseCexBalances:     map[uint32]uint64{dcrID: 1e9, usdcPolygonID: 10e6},
+			botCfg: &BotConfig{
+				Host:       "dex.com",
+				BaseID:     dcrID,
+				QuoteID:    usdcPolygonID,
+				CEXBaseID:  dcrID,
+				CEXQuoteID: usdcPolygonID,
+			},
+		})
+		u.bridgeTransfer = func(mwh *MarketWithHost, fromAssetIDs []uint32, amt uint64, f doBridgeTransferFunc) error {
+			if len(fromAssetIDs) != 1 || fromAssetIDs[0] != usdcEthID {
+				t.Fatalf("unexpected bridge sources %v", fromAssetIDs)
+			}
+			return f(unallocated)
+		}
+		return u, tCore
+	}
+
+	// Insufficient unallocated funds.
+	u, tCore := newAdaptor(map[uint32]uint64{usdcEthID: 1e6})
+	ok, err := u.bridgeRebalance(usdcPolygonID, usdcPolygonID, 2e6)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ok || len(tCore.bridgesInitiated) != 0 {
+		t.Fatalf("bridge initiated without sufficient unallocated funds")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	u, tCore = newAdaptor(map[uint32]uint64{usdcEthID: 5e6})
+	u.ctx = ctx
+	ok, err = u.bridgeRebalance(usdcPolygonID, usdcPolygonID, 2e6)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !ok {
+		t.Fatalf("bridge not initiated")
+	}
+	if len(tCore.bridgesInitiated) != 1 {
+		t.Fatalf("expected 1 bridge, got %d", len(tCore.bridgesInitiated))
+	}
+	if b := tCore.bridgesInitiated[0]; b.fromAssetID != usdcEthID || b.toAssetID != usdcPolygonID || b.amt != 2e6 || b.bridgeName != "cctp" {
+		t.Fatalf("wrong bridge initiated: %+v", b)
+	}
+	if !u.pendingQuoteRebalance.Load() {
+		t.Fatalf("pending quote rebalance not set")
+	}
+
+	checkBalances := func(dexAvail, dexPending, cexAvail, polygonAvail uint64) {
+		t.Helper()
+		dexBal := u.DEXBalance(usdcPolygonID)
+		if dexBal.Available != dexAvail || dexBal.Pending != dexPending {
+			t.Fatalf("wrong dex balance. wanted available %d, pending %d, got %+v", dexAvail, dexPending, dexBal)
+		}
+		if bal := u.CEXBalance(usdcPolygonID).Available; bal != cexAvail {
+			t.Fatalf("wrong cex balance. wanted %d, got %d", cexAvail, bal)
+		}
+		if bal := u.DEXBalance(polygonID).Available; bal != polygonAvail {
+			t.Fatalf("wrong polygon balance. wanted %d, got %d", polygonAvail, bal)
+		}
+	}
+	checkLastEvent := func(pending bool) {
+		t.Helper()
+		events := u.eventLogDB.(*tEventLogDB).storedEvents
+		e := events[len(events)-1]
+		if e.BridgeEvent == nil {
+			t.Fatalf("last event is not a bridge event")
+		}
+		if e.Pending != pending {
+			t.Fatalf("wrong pending status. wanted %v, got %v", pending, e.Pending)
+		}
+		if e.BridgeEvent.FromAssetID != usdcEthID || e.BridgeEvent.CEXDebit != 2e6 || e.BridgeEvent.BridgeName != "cctp" {
+			t.Fatalf("wrong bridge event: %+v", e.BridgeEvent)
+		}
+	}
+
+	checkBalances(1e6, 2e6, 8e6, 1e10)
+	checkLastEvent(true)
+
+	// Not yet complete.
+	if u.confirmBridge(ctx, bridgeTxID) {
+		t.Fatalf("bridge confirmed before completion")
+	}
+	checkBalances(1e6, 2e6, 8e6, 1e10)
+
+	tCore.walletTxsMtx.Lock()
+	tCore.walletTxs[bridgeTxID] = &asset.WalletTransaction{
+		ID:     bridgeTxID,
+		Amount: 2e6,
+		BridgeCounterpartTx: &asset.BridgeCounterpartTx{
+			AssetID:        usdcPolygonID,
+			Complete:       true,
+			AmountReceived: 1.99e6,
+			Fees:           1e6,
+		},
+	}
+	tCore.walletTxsMtx.Unlock()
+
+	if !u.confirmBridge(ctx, bridgeTxID) {
+		t.Fatalf("bridge not confirmed")
+	}
+	checkBalances(2.99e6, 0, 8e6, 1e10-1e6)
+	checkLastEvent(false)
+	if u.pendingQuoteRebalance.Load() {
+		t.Fatalf("pending quote rebalance not cleared")
+	}
+	if len(u.pendingBridges) != 0 {
+		t.Fatalf("bridge still pending")
+	}
+}
+
+func TestBotTransfers(t *testing.T) {
+	const (
+		dcrID = 42
+		btcID = 0
+	)
+
+	newAdaptor := func(mwh *MarketWithHost, dexBase, cexBase uint64) *unifiedExchangeAdaptor {
+		tCore := newTCore()
+		return mustParseAdaptor(&exchangeAdaptorCfg{
+			mwh:                 mwh,
+			core:                tCore,
+			cex:                 newTCEX(),
+			eventLogDB:          newTEventLogDB(),
+			autoRebalanceConfig: &AutoRebalanceConfig{BotTransfers: true},
+			baseDexBalances:     map[uint32]uint64{dcrID: dexBase, btcID: 1e8},
+			baseCexBalances:     map[uint32]uint64{dcrID: cexBase, btcID: 1e8},
+			botCfg: &BotConfig{
+				Host:       mwh.Host,
+				BaseID:     dcrID,
+				QuoteID:    btcID,
+				CEXName:    "Binance",
+				CEXBaseID:  dcrID,
+				CEXQuoteID: btcID,
+			},
+		})
+	}
+
+	mkt := &MarketWithHost{Host: "dex.com", BaseID: dcrID, QuoteID: btcID}
+	peerMkt := &MarketWithHost{Host: "dex2.com", BaseID: dcrID, QuoteID: btcID}
+	u := newAdaptor(mkt, 1e8, 10e8)
+	peer := newAdaptor(peerMkt, 10e8, 2e8)
+	u.botTransfer = func(mwh *MarketWithHost, dexAssetID, cexAssetID uint32, amt uint64, toDEX bool) (*MarketWithHost, error) {
+		if peer.acceptBotTransfer(mwh, dexAssetID, cexAssetID, amt, toDEX) {
+			return peerMkt, nil
+		}
+		return nil, nil
+	}
+
+	checkBalances := func(a *unifiedExchangeAdaptor, dexBase, cexBase uint64) {
+		t.Helper()
+		if bal := a.DEXBalance(dcrID).Available; bal != dexBase {
+			t.Fatalf("wrong dex balance. wanted %d, got %d", dexBase, bal)
+		}
+		if bal := a.CEXBalance(dcrID).Available; bal != cexBase {
+			t.Fatalf("wrong cex balance. wanted %d, got %d", cexBase, bal)
+		}
+	}
+
+	// The peer has DEX funds to spare.
+	dist := &distribution{
+		baseInv:  &assetInventory{toWithdraw: 3e8},
+		quoteInv: &assetInventory{},
+	}
+	if err := u.tryAlternativeTransfers(dist, u.autoRebalanceCfg()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dist.baseInv.toWithdraw != 0 {
+		t.Fatalf("withdrawal not replaced by bot transfer")
+	}
+	checkBalances(u, 4e8, 7e8)
+	checkBalances(peer, 7e8, 5e8)
+
+	events := u.eventLogDB.(*tEventLogDB).storedEvents
+	if len(events) != 1 || events[0].BotTransferEvent == nil || !events[0].BotTransferEvent.ToDEX ||
+		*events[0].BotTransferEvent.Peer != *peerMkt || events[0].BotTransferEvent.Amount != 3e8 {
+		t.Fatalf("wrong bot transfer events: %+v", events)
+	}
+	peerEvents := peer.eventLogDB.(*tEventLogDB).storedEvents
+	if len(peerEvents) != 1 || peerEvents[0].BotTransferEvent == nil || peerEvents[0].BotTransferEvent.ToDEX ||
+		*peerEvents[0].BotTransferEvent.Peer != *mkt {
+		t.Fatalf("wrong peer bot transfer events: %+v", peerEvents)
+	}
+
+	// Another transfer would leave the peer with less on the DEX than on
+	// the CEX.
+	dist.baseInv.toWithdraw = 2e8
+	if err := u.tryAlternativeTransfers(dist, u.autoRebalanceCfg()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dist.baseInv.toWithdraw != 2e8 {
+		t.Fatalf("bot transfer should have been rejected")
+	}
+	checkBalances(u, 4e8, 7e8)
+	checkBalances(peer, 7e8, 5e8)
+
+	// The peer has not enabled bot transfers.
+	peer.autoRebalanceCfgV.Store(&AutoRebalanceConfig{})
+	dist.baseInv.toWithdraw = 1e8
+	if err := u.tryAlternativeTransfers(dist, u.autoRebalanceCfg()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if dist.baseInv.toWithdraw != 1e8 {
+		t.Fatalf("bot transfer should have been rejected")
+	}
+	checkBalances(peer, 7e8, 5e8)
+
+	// Concurrent requests cannot spend the same funds. The peer accepts
+	// transfers until it would be left with less on the DEX than on the CEX.
+	peer = newAdaptor(peerMkt, 10e8, 2e8)
+	var wg sync.WaitGroup
+	var accepted atomic.Uint32
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if peer.acceptBotTransfer(mkt, dcrID, dcrID, 1e8, true) {
+				accepted.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if accepted.Load() != 4 {
+		t.Fatalf("expected 4 accepted transfers, got %d", accepted.Load())
+	}
+	checkBalances(peer, 6e8, 6e8)
+
+	// A stopped bot does not accept transfers.
+	peer = newAdaptor(peerMkt, 10e8, 2e8)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	peer.ctx = ctx
+	if peer.acceptBotTransfer(mkt, dcrID, dcrID, 1e8, true) {
+		t.Fatalf("stopped bot accepted a transfer")
+	}
+	checkBalances(peer, 10e8, 2e8)
+}
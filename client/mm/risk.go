@@ -0,0 +1,385 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package mm
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"decred.org/dcrdex/client/asset"
+	"decred.org/dcrdex/dex"
+)
+
+// riskCheckInterval is how often the risk limits are checked against the
+// running bots.
+var riskCheckInterval = time.Second * 10
+
+// RiskConfig is the portfolio-wide risk limits that apply across all running
+// bots.
+type RiskConfig struct {
+	// ExposureCaps are the maximum total holdings of an asset, in atoms,
+	// across all running bots, including DEX and CEX balances. A bot cannot
+	// be started with an allocation that would exceed a cap, and if a cap is
+	// exceeded while bots are running, the kill switch is triggered. CEX
+	// holdings of an asset that a bot bridges to are counted as the DEX
+	// asset, and the same token on different chains is counted as one asset,
+	// so a cap on any of them applies to the total.
+	ExposureCaps map[uint32]uint64 `json:"exposureCaps,omitempty"`
+	// MaxBotDrawdown is the maximum drawdown of a single bot, as a ratio of
+	// the USD value of its allocation. Drawdown is measured from the bot's
+	// peak profit. A bot that exceeds its max drawdown is stopped. Zero
+	// disables the limit. 0 <= x < 1.
+	MaxBotDrawdown float64 `json:"maxBotDrawdown"`
+	// MaxDrawdown is the maximum aggregate drawdown of all bots run since
+	// the market maker was started or the kill switch was last reset, as a
+	// ratio of the total USD value of their allocations. Exceeding the
+	// aggregate drawdown triggers the kill switch. Zero disables the limit.
+	// 0 <= x < 1.
+	MaxDrawdown float64 `json:"maxDrawdown"`
+}
+
+func (c *RiskConfig) validate() error {
+	if c.MaxBotDrawdown < 0 || c.MaxBotDrawdown >= 1 {
+		return fmt.Errorf("max bot drawdown %f out of bounds", c.MaxBotDrawdown)
+	}
+	if c.MaxDrawdown < 0 || c.MaxDrawdown >= 1 {
+		return fmt.Errorf("max drawdown %f out of bounds", c.MaxDrawdown)
+	}
+	return nil
+}
+
+func (c *RiskConfig) copy() *RiskConfig {
+	cfg := *c
+	if c.ExposureCaps != nil {
+		cfg.ExposureCaps = make(map[uint32]uint64, len(c.ExposureCaps))
+		for assetID, v := range c.ExposureCaps {
+			cfg.ExposureCaps[assetID] = v
+		}
+	}
+	return &cfg
+}
+
+// RiskStatus is the state of the risk manager.
+type RiskStatus struct {
+	Config *RiskConfig `json:"config"`
+	// Exposure is the total holdings of each asset across all running bots.
+	// Tokens are reported under the asset ID returned by exposureAssetID.
+	Exposure map[uint32]uint64 `json:"exposure"`
+	// Drawdown is the current aggregate drawdown.
+	Drawdown float64 `json:"drawdown"`
+	// Killed is set if the kill switch has been triggered. No bots can be
+	// started until the kill switch is reset.
+	Killed *StampedError `json:"killed,omitempty"`
+}
+
+// botRiskState is the state of a running bot that is relevant to the risk
+// limits.
+type botRiskState struct {
+	mkt   MarketWithHost
+	stats *RunStats
+	// alloc is the bot's starting allocation. It is counted towards the
+	// exposure if the bot's stats are not available.
+	alloc *BotBalanceAllocation
+	// cexAssets maps the CEX assets that the bot bridges to to the DEX assets
+	// they are bridged from.
+	cexAssets map[uint32]uint32
+}
+
+// runDrawdown tracks the peak profit of a run.
+type runDrawdown struct {
+	basis      float64
+	profit     float64
+	peakProfit float64
+}
+
+func (d *runDrawdown) update(pl *ProfitLoss) {
+	d.basis = pl.InitialUSD + pl.ModsUSD
+	d.profit = pl.Profit
+	d.peakProfit = max(d.peakProfit, pl.Profit)
+}
+
+func (d *runDrawdown) drawdown() float64 {
+	if d.basis <= 0 {
+		return 0
+	}
+	return (d.peakProfit - d.profit) / d.basis
+}
+
+// riskCheck is the result of checking the running bots against the risk
+// limits.
+type riskCheck struct {
+	// stopBots are bots that exceeded their max drawdown.
+	stopBots map[MarketWithHost]error
+	// kill is set if the kill switch should be triggered.
+	kill error
+}
+
+// riskManager enforces the RiskConfig across all running bots.
+type riskManager struct {
+	log dex.Logger
+
+	mtx      sync.Mutex
+	cfg      *RiskConfig
+	exposure map[uint32]uint64
+	// runs tracks the drawdown of every run since the risk manager was
+	// created or the kill switch was last reset. Runs that have ended keep
+	// their final profit so that they count towards the aggregate drawdown.
+	runs       map[string]*runDrawdown
+	aggregate  runDrawdown
+	killed     *StampedError
+	killReason error
+}
+
+// newRiskManager is the constructor for a riskManager. If killed is non-nil,
+// the kill switch is in the triggered state.
+func newRiskManager(cfg *RiskConfig, killed *StampedError, log dex.Logger) *riskManager {
+	r := &riskManager{
+		log:      log,
+		exposure: make(map[uint32]uint64),
+		runs:     make(map[string]*runDrawdown),
+	}
+	if killed != nil {
+		k := *killed
+		r.killed = &k
+		r.killReason = errors.New(k.Error)
+	}
+	r.setConfig(cfg)
+	return r
+}
+
+func (r *riskManager) setConfig(cfg *RiskConfig) {
+	if cfg == nil {
+		cfg = &RiskConfig{}
+	}
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.cfg = cfg.copy()
+}
+
+// killErr returns a non-nil error if the kill switch has been triggered.
+func (r *riskManager) killErr() error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.killed == nil {
+		return nil
+	}
+	return fmt.Errorf("kill switch triggered: %v", r.killReason)
+}
+
+// kill triggers the kill switch. kill returns nil if the kill switch was
+// already triggered.
+func (r *riskManager) kill(reason error) *StampedError {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if r.killed != nil {
+		return nil
+	}
+	r.killed = newStampedError(reason)
+	r.killReason = reason
+	k := *r.killed
+	return &k
+}
+
+// reset resets the kill switch and the drawdown tracking.
+func (r *riskManager) reset() {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.killed = nil
+	r.killReason = nil
+	r.runs = make(map[string]*runDrawdown)
+	r.aggregate = runDrawdown{}
+}
+
+// checkAllocation checks that starting the bot with its allocation would not
+// exceed the exposure caps, given the current exposure of the running bots.
+func (r *riskManager) checkAllocation(bot *botRiskState, running []*botRiskState) error {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	if r.killed != nil {
+		return fmt.Errorf("kill switch triggered: %v", r.killReason)
+	}
+
+	return r.checkExposure(totalExposure(append(running, bot)))
+}
+
+// checkExposure must be called with the mtx locked.
+func (r *riskManager) checkExposure(exposure map[uint32]uint64) error {
+	for assetID, limit := range r.cfg.ExposureCaps {
+		id, limit := exposureAssetID(assetID), convertAtoms(assetID, exposureAssetID(assetID), limit)
+		if v := exposure[id]; v > limit {
+			return fmt.Errorf("%s exposure %d exceeds cap %d", dex.BipIDSymbol(id), v, limit)
+		}
+	}
+	return nil
+}
+
+// cexAssetMap maps the CEX assets that a bot bridges to to the DEX assets
+// they are bridged from.
+func cexAssetMap(cfg *BotConfig) map[uint32]uint32 {
+	if cfg == nil || cfg.CEXName == "" {
+		return nil
+	}
+	m := make(map[uint32]uint32, 2)
+	if cfg.CEXBaseID != cfg.BaseID {
+		m[cfg.CEXBaseID] = cfg.BaseID
+	}
+	if cfg.CEXQuoteID != cfg.QuoteID {
+		m[cfg.CEXQuoteID] = cfg.QuoteID
+	}
+	return m
+}
+
+// exposureAssetID returns the ID of the asset that holdings of the asset are
+// counted as. The same token on different chains, e.g. usdc.eth and
+// usdc.polygon, is counted as the token with the lowest asset ID.
+func exposureAssetID(assetID uint32) uint32 {
+	if asset.TokenInfo(assetID) == nil {
+		return assetID
+	}
+	symbol := dex.TokenSymbol(dex.BipIDSymbol(assetID))
+	id := assetID
+	for _, ra := range asset.Assets() {
+		for tokenID := range ra.Tokens {
+			if tokenID < id && dex.TokenSymbol(dex.BipIDSymbol(tokenID)) == symbol {
+				id = tokenID
+			}
+		}
+	}
+	return id
+}
+
+// convertAtoms converts an amount in atoms of one asset to atoms of another
+// asset with the same conventional unit.
+func convertAtoms(fromID, toID uint32, v uint64) uint64 {
+	if fromID == toID {
+		return v
+	}
+	fromUI, err := asset.UnitInfo(fromID)
+	if err != nil {
+		return v
+	}
+	toUI, err := asset.UnitInfo(toID)
+	if err != nil {
+		return v
+	}
+	fromFactor, toFactor := fromUI.Conventional.ConversionFactor, toUI.Conventional.ConversionFactor
+	if fromFactor == 0 || toFactor == 0 || fromFactor == toFactor {
+		return v
+	}
+	if toFactor > fromFactor {
+		return v * (toFactor / fromFactor)
+	}
+	return v / (fromFactor / toFactor)
+}
+
+// totalExposure sums the DEX and CEX holdings of the bots. CEX holdings of
+// bridged assets are counted as the DEX asset they are bridged from, and all
+// holdings are counted under the asset returned by exposureAssetID.
+func totalExposure(bots []*botRiskState) map[uint32]uint64 {
+	exposure := make(map[uint32]uint64)
+	add := func(assetID uint32, v uint64) {
+		id := exposureAssetID(assetID)
+		exposure[id] += convertAtoms(assetID, id, v)
+	}
+	for _, b := range bots {
+		addCEX := func(assetID uint32, v uint64) {
+			if dexAssetID, found := b.cexAssets[assetID]; found {
+				v = convertAtoms(assetID, dexAssetID, v)
+				assetID = dexAssetID
+			}
+			add(assetID, v)
+		}
+		if b.stats == nil {
+			if b.alloc != nil {
+				for assetID, v := range b.alloc.DEX {
+					add(assetID, v)
+				}
+				for assetID, v := range b.alloc.CEX {
+					addCEX(assetID, v)
+				}
+			}
+			continue
+		}
+		for assetID, bal := range b.stats.DEXBalances {
+			add(assetID, bal.Available+bal.Locked+bal.Pending+bal.Reserved)
+		}
+		for assetID, bal := range b.stats.CEXBalances {
+			addCEX(assetID, bal.Available+bal.Locked+bal.Pending+bal.Reserved)
+		}
+	}
+	return exposure
+}
+
+// check updates the exposure and drawdowns of the running bots, and checks
+// them against the risk limits.
+func (r *riskManager) check(running []*botRiskState) *riskCheck {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+
+	res := &riskCheck{stopBots: make(map[MarketWithHost]error)}
+
+	r.exposure = totalExposure(running)
+	if r.killed != nil {
+		return res
+	}
+
+	if err := r.checkExposure(r.exposure); err != nil {
+		res.kill = err
+	}
+
+	for _, b := range running {
+		if b.stats == nil || b.stats.ProfitLoss == nil {
+			continue
+		}
+		k := string(runKey(b.stats.StartTime, &b.mkt))
+		d, found := r.runs[k]
+		if !found {
+			d = &runDrawdown{}
+			r.runs[k] = d
+		}
+		d.update(b.stats.ProfitLoss)
+		if r.cfg.MaxBotDrawdown > 0 && d.drawdown() > r.cfg.MaxBotDrawdown {
+			res.stopBots[b.mkt] = fmt.Errorf("drawdown %.2f%% exceeds max bot drawdown %.2f%%",
+				d.drawdown()*100, r.cfg.MaxBotDrawdown*100)
+		}
+	}
+
+	var basis, profit float64
+	for _, d := range r.runs {
+		basis += d.basis
+		profit += d.profit
+	}
+	r.aggregate.basis = basis
+	r.aggregate.profit = profit
+	r.aggregate.peakProfit = max(r.aggregate.peakProfit, profit)
+	if res.kill == nil && r.cfg.MaxDrawdown > 0 && r.aggregate.drawdown() > r.cfg.MaxDrawdown {
+		res.kill = fmt.Errorf("aggregate drawdown %.2f%% exceeds max drawdown %.2f%%",
+			r.aggregate.drawdown()*100, r.cfg.MaxDrawdown*100)
+	}
+
+	return res
+}
+
+func (r *riskManager) status() *RiskStatus {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	exposure := make(map[uint32]uint64, len(r.exposure))
+	for assetID, v := range r.exposure {
+		exposure[assetID] = v
+	}
+	var killed *StampedError
+	if r.killed != nil {
+		k := *r.killed
+		killed = &k
+	}
+	return &RiskStatus{
+		Config:   r.cfg.copy(),
+		Exposure: exposure,
+		Drawdown: r.aggregate.drawdown(),
+		Killed:   killed,
+	}
+}
@@ -0,0 +1,268 @@
+//go:build !harness && !botlive
+
+package mm
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"decred.org/dcrdex/client/core"
+	"decred.org/dcrdex/dex"
+	"decred.org/dcrdex/dex/order"
+)
+
+func TestRiskManager(t *testing.T) {
+	const baseID, quoteID = 42, 0
+	mkt1 := MarketWithHost{Host: "dex.com", BaseID: baseID, QuoteID: quoteID}
+	mkt2 := MarketWithHost{Host: "dex.com", BaseID: 60, QuoteID: quoteID}
+
+	botState := func(mkt MarketWithHost, startTime int64, dexBase, cexBase uint64, basis, profit float64) *botRiskState {
+		return &botRiskState{
+			mkt: mkt,
+			stats: &RunStats{
+				StartTime:   startTime,
+				DEXBalances: map[uint32]*BotBalance{mkt.BaseID: {Available: dexBase / 2, Locked: dexBase / 2}},
+				CEXBalances: map[uint32]*BotBalance{mkt.BaseID: {Available: cexBase}},
+				ProfitLoss: &ProfitLoss{
+					InitialUSD: basis,
+					Profit:     profit,
+				},
+			},
+		}
+	}
+
+	r := newRiskManager(&RiskConfig{
+		ExposureCaps:   map[uint32]uint64{baseID: 10e8},
+		MaxBotDrawdown: 0.1,
+		MaxDrawdown:    0.05,
+	}, nil, tLogger)
+
+	// Within limits.
+	res := r.check([]*botRiskState{
+		botState(mkt1, 1, 4e8, 4e8, 1000, 50),
+		botState(mkt2, 1, 1e18, 0, 1000, 0),
+	})
+	if res.kill != nil || len(res.stopBots) != 0 {
+		t.Fatalf("unexpected risk check result: kill = %v, stop = %v", res.kill, res.stopBots)
+	}
+	if exp := r.status().Exposure[baseID]; exp != 8e8 {
+		t.Fatalf("wrong exposure %d", exp)
+	}
+
+	// Starting a bot that would exceed the exposure cap is an error.
+	running := []*botRiskState{botState(mkt1, 1, 4e8, 4e8, 1000, 50)}
+	if err := r.checkAllocation(&botRiskState{alloc: &BotBalanceAllocation{DEX: map[uint32]uint64{baseID: 1e8}, CEX: map[uint32]uint64{baseID: 2e8}}}, running); err == nil {
+		t.Fatalf("no error for allocation exceeding exposure cap")
+	}
+	if err := r.checkAllocation(&botRiskState{alloc: &BotBalanceAllocation{DEX: map[uint32]uint64{baseID: 1e8}}}, running); err != nil {
+		t.Fatalf("unexpected error for allocation within cap: %v", err)
+	}
+
+	// A bot drawdown of 12% from the peak stops the bot. The aggregate
+	// drawdown is 6% of the total basis, which triggers the kill switch.
+	res = r.check([]*botRiskState{
+		botState(mkt1, 1, 4e8, 4e8, 1000, -70),
+		botState(mkt2, 1, 1e18, 0, 1000, 0),
+	})
+	if res.stopBots[mkt1] == nil || res.stopBots[mkt2] != nil {
+		t.Fatalf("wrong bots stopped: %v", res.stopBots)
+	}
+	if res.kill == nil {
+		t.Fatalf("kill switch not triggered by aggregate drawdown")
+	}
+
+	// Once killed, no bots can be started until reset.
+	killed := r.kill(res.kill)
+	if killed == nil {
+		t.Fatalf("kill switch already triggered")
+	}
+	if r.kill(res.kill) != nil {
+		t.Fatalf("kill switch triggered twice")
+	}
+	if r.killErr() == nil || r.status().Killed == nil {
+		t.Fatalf("kill switch not reported")
+	}
+	if err := r.checkAllocation(&botRiskState{alloc: &BotBalanceAllocation{}}, nil); err == nil {
+		t.Fatalf("no error starting bot after kill switch")
+	}
+	// A saved kill switch is restored.
+	if r := newRiskManager(nil, killed, tLogger); r.killErr() == nil || r.status().Killed.Error != killed.Error {
+		t.Fatalf("kill switch not restored")
+	}
+	r.reset()
+	if err := r.checkAllocation(&botRiskState{alloc: &BotBalanceAllocation{}}, nil); err != nil {
+		t.Fatalf("unexpected error after reset: %v", err)
+	}
+
+	// A bot without stats counts its allocation towards the exposure.
+	running = []*botRiskState{{mkt: mkt1, alloc: &BotBalanceAllocation{DEX: map[uint32]uint64{baseID: 8e8}}}}
+	if err := r.checkAllocation(&botRiskState{alloc: &BotBalanceAllocation{DEX: map[uint32]uint64{baseID: 3e8}}}, running); err == nil {
+		t.Fatalf("no error for allocation exceeding exposure cap with a new bot")
+	}
+
+	// Ended runs count towards the aggregate drawdown.
+	r.check([]*botRiskState{botState(mkt1, 2, 0, 0, 1000, 100)})
+	res = r.check([]*botRiskState{botState(mkt2, 3, 0, 0, 1000, -150)})
+	if res.kill == nil {
+		t.Fatalf("kill switch not triggered after ended run")
+	}
+
+	// Exceeding an exposure cap triggers the kill switch.
+	r.reset()
+	res = r.check([]*botRiskState{botState(mkt1, 4, 6e8, 6e8, 1000, 0)})
+	if res.kill == nil {
+		t.Fatalf("kill switch not triggered by exposure")
+	}
+
+	for _, cfg := range []*RiskConfig{{MaxBotDrawdown: -0.1}, {MaxDrawdown: 1}} {
+		if err := cfg.validate(); err == nil {
+			t.Fatalf("no error for invalid config %+v", cfg)
+		}
+	}
+}
+
+func TestExposureAssets(t *testing.T) {
+	usdcEth, _ := dex.BipSymbolID("usdc.eth")
+	usdcPolygon, _ := dex.BipSymbolID("usdc.polygon")
+	wethPolygon, _ := dex.BipSymbolID("weth.polygon")
+	const ethID = 60
+
+	mkt := MarketWithHost{Host: "dex.com", BaseID: ethID, QuoteID: usdcEth}
+	bot := &botRiskState{
+		mkt: mkt,
+		stats: &RunStats{
+			DEXBalances: map[uint32]*BotBalance{
+				ethID:   {Available: 1e9},
+				usdcEth: {Available: 100e6},
+			},
+			CEXBalances: map[uint32]*BotBalance{
+				wethPolygon: {Available: 1e9},
+				usdcPolygon: {Available: 50e6},
+			},
+		},
+		cexAssets: map[uint32]uint32{wethPolygon: ethID},
+	}
+
+	exposure := totalExposure([]*botRiskState{bot})
+	if exposure[ethID] != 2e9 {
+		t.Fatalf("wrong bridged asset exposure %d", exposure[ethID])
+	}
+	if exposure[usdcEth] != 150e6 {
+		t.Fatalf("wrong token exposure %d", exposure[usdcEth])
+	}
+	if len(exposure) != 2 {
+		t.Fatalf("assets not combined: %v", exposure)
+	}
+
+	// A cap on the token on any chain applies to the total.
+	r := newRiskManager(&RiskConfig{ExposureCaps: map[uint32]uint64{usdcPolygon: 120e6}}, nil, tLogger)
+	if res := r.check([]*botRiskState{bot}); res.kill == nil {
+		t.Fatalf("kill switch not triggered by exposure of token on another chain")
+	}
+}
+
+func TestKillSwitch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tCore := newTCore()
+	tCore.market = &core.Market{EpochLen: 10}
+	tCore.fiatRates = map[uint32]float64{42: 20, 0: 60_000}
+	tCore.singleLotBuyFees = tFees(1e5, 1e5, 1e5, 0)
+	tCore.singleLotSellFees = tFees(1e5, 1e5, 1e5, 0)
+	tCore.orders = make(map[order.OrderID]*core.Order)
+
+	cfgPath := filepath.Join(t.TempDir(), "mm.json")
+	m, err := NewMarketMaker(tCore, "", cfgPath, tLogger)
+	if err != nil {
+		t.Fatalf("NewMarketMaker error: %v", err)
+	}
+
+	// runBot adds a connected bot with a booked DEX order.
+	runBot := func(oid order.OrderID) *runningBot {
+		t.Helper()
+		mkt := MarketWithHost{Host: "dex.com", BaseID: 42, QuoteID: 0}
+		alloc := &BotBalanceAllocation{DEX: map[uint32]uint64{42: 1e9, 0: 1e9}}
+		adaptor := mustParseAdaptor(&exchangeAdaptorCfg{
+			core:            tCore,
+			mwh:             &mkt,
+			baseDexBalances: alloc.DEX,
+			eventLogDB:      &tEventLogDB{},
+		})
+		po := &pendingDEXOrder{}
+		po.state.Store(&dexOrderState{
+			dexBalanceEffects: &BalanceEffects{Settled: map[uint32]int64{42: -1e8}, Locked: map[uint32]uint64{42: 1e8}, Pending: map[uint32]uint64{}},
+			cexBalanceEffects: &BalanceEffects{},
+			order:             &core.Order{ID: oid[:], Sell: true},
+		})
+		adaptor.pendingDEXOrders[oid] = po
+		tCore.orders[oid] = &core.Order{ID: oid[:], Status: order.OrderStatusBooked}
+		cm := dex.NewConnectionMaster(adaptor)
+		if err := cm.ConnectOnce(ctx); err != nil {
+			t.Fatalf("error connecting bot: %v", err)
+		}
+		rb := &runningBot{bot: adaptor, cm: cm, alloc: alloc}
+		m.runningBotsMtx.Lock()
+		m.runningBots[mkt] = rb
+		m.runningBotsMtx.Unlock()
+		return rb
+	}
+
+	checkKilled := func(rb *runningBot, oid order.OrderID) {
+		t.Helper()
+		if rb.cm.On() {
+			t.Fatalf("bot not stopped")
+		}
+		if len(tCore.cancelsPlaced) != 1 || tCore.cancelsPlaced[0] != oid {
+			t.Fatalf("order not canceled. cancels: %v", tCore.cancelsPlaced)
+		}
+		tCore.cancelsPlaced = nil
+		if m.RiskStatus().Killed == nil {
+			t.Fatalf("kill switch not reported")
+		}
+		if err := m.risk.checkAllocation(&botRiskState{alloc: &BotBalanceAllocation{}}, nil); err == nil {
+			t.Fatalf("no error starting a bot after the kill switch")
+		}
+		// The kill switch is restored after a restart.
+		m2, err := NewMarketMaker(tCore, "", cfgPath, tLogger)
+		if err != nil {
+			t.Fatalf("NewMarketMaker error: %v", err)
+		}
+		if m2.RiskStatus().Killed == nil {
+			t.Fatalf("kill switch not restored after restart")
+		}
+	}
+
+	// The user triggers the kill switch.
+	oid := order.OrderID{0x01}
+	rb := runBot(oid)
+	if err := m.KillSwitch(); err != nil {
+		t.Fatalf("KillSwitch error: %v", err)
+	}
+	checkKilled(rb, oid)
+
+	if err := m.ResetKillSwitch(); err != nil {
+		t.Fatalf("ResetKillSwitch error: %v", err)
+	}
+	if m.RiskStatus().Killed != nil {
+		t.Fatalf("kill switch not reset")
+	}
+	b, err := os.ReadFile(cfgPath)
+	if err != nil {
+		t.Fatalf("error reading config file: %v", err)
+	}
+	if m2, _ := NewMarketMaker(tCore, "", cfgPath, tLogger); m2.RiskStatus().Killed != nil {
+		t.Fatalf("kill switch reset not saved: %s", string(b))
+	}
+
+	// Exceeding an exposure cap triggers the kill switch automatically.
+	if err := m.UpdateRiskConfig(&RiskConfig{ExposureCaps: map[uint32]uint64{42: 1e8}}); err != nil {
+		t.Fatalf("UpdateRiskConfig error: %v", err)
+	}
+	oid = order.OrderID{0x02}
+	rb = runBot(oid)
+	m.checkRiskLimits()
+	checkKilled(rb, oid)
+}
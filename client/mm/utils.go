@@ -2,11 +2,13 @@ package mm
 
 import (
 	"errors"
+	"fmt"
 	"math"
 
 	"decred.org/dcrdex/client/asset"
 	"decred.org/dcrdex/client/core"
 	"decred.org/dcrdex/client/mm/libxc"
+	"decred.org/dcrdex/dex"
 	"decred.org/dcrdex/dex/msgjson"
 )
 
@@ -20,6 +22,14 @@ func steppedRate(r, step uint64) uint64 {
 	return uint64(math.Round(steps * float64(step)))
 }
 
+// gcd is the greatest common divisor of a and b.
+func gcd(a, b uint64) uint64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
+
 // updateBotProblemsBasedOnError updates BotProblems based on an error
 // encountered during market making.
 func updateBotProblemsBasedOnError(problems *BotProblems, err error) {
@@ -79,3 +89,16 @@ func feeAssetID(assetID uint32) uint32 {
 	}
 	return assetID
 }
+
+// usdValue converts an amount of an asset to USD.
+func usdValue(fiatRates map[uint32]float64, assetID uint32, atoms uint64) (float64, error) {
+	fiatRate := fiatRates[assetID]
+	if fiatRate == 0 {
+		return 0, fmt.Errorf("no fiat rate for %s", dex.BipIDSymbol(assetID))
+	}
+	ui, err := asset.UnitInfo(assetID)
+	if err != nil {
+		return 0, err
+	}
+	return float64(atoms) / float64(ui.Conventional.ConversionFactor) * fiatRate, nil
+}
@@ -0,0 +1,194 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package mm
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"decred.org/dcrdex/dex/candles"
+	"decred.org/dcrdex/dex/msgjson"
+)
+
+const (
+	// volatilityCandleDur is the DEX candle duration used to measure realized
+	// volatility. Volatility is expressed per candle of this duration.
+	volatilityCandleDur = "5m"
+	volatilityCandleMs  = uint64(5 * time.Minute / time.Millisecond)
+	// defaultVolatilityCandles is the default number of candles in the
+	// volatility window.
+	defaultVolatilityCandles = 24
+	// maxMidGapSamples is the maximum number of mid-gap samples retained.
+	maxMidGapSamples = 1000
+)
+
+// VolatilityGapConfig is the configuration for GapStrategyVolatility. The
+// half-spread is the realized volatility of the market as a ratio of the
+// basis price, clamped to [Floor, Ceiling], and multiplied by the placement's
+// GapFactor.
+type VolatilityGapConfig struct {
+	// Candles is the number of 5 minute DEX candles in the window used to
+	// calculate the realized volatility. Mid-gap samples from the CEX book
+	// are drawn from the same period. Default: 24 (2 hours).
+	// 2 <= x <= 1000.
+	Candles int `json:"candles"`
+	// Floor is the minimum half-spread, as a ratio of the basis price.
+	// 0 <= x < Ceiling.
+	Floor float64 `json:"floor"`
+	// Ceiling is the maximum half-spread, as a ratio of the basis price. The
+	// ceiling is also used if there is not enough data to measure the
+	// volatility. Floor < x <= 0.1.
+	Ceiling float64 `json:"ceiling"`
+}
+
+func (c *VolatilityGapConfig) validate() error {
+	if c.Candles == 0 {
+		c.Candles = defaultVolatilityCandles
+	}
+	if c.Candles < 2 || c.Candles > candles.CacheSize {
+		return fmt.Errorf("volatility candles %d out of bounds", c.Candles)
+	}
+	if c.Floor < 0 || c.Ceiling > 0.1 || c.Floor >= c.Ceiling {
+		return fmt.Errorf("invalid volatility floor %f and ceiling %f", c.Floor, c.Ceiling)
+	}
+	return nil
+}
+
+func (c *VolatilityGapConfig) copy() *VolatilityGapConfig {
+	cfg := *c
+	return &cfg
+}
+
+// halfSpread is the volatility-based half-spread for the basis price.
+func (c *VolatilityGapConfig) halfSpread(basisPrice uint64, volatility float64, ok bool) uint64 {
+	if !ok {
+		volatility = c.Ceiling
+	}
+	volatility = math.Min(math.Max(volatility, c.Floor), c.Ceiling)
+	return uint64(math.Round(volatility * float64(basisPrice)))
+}
+
+type stampedRate struct {
+	stamp uint64 // ms
+	rate  uint64
+}
+
+// volatilityTracker measures the realized volatility of a market from DEX
+// candles and periodically sampled mid-gap rates, e.g. from a CEX book.
+type volatilityTracker struct {
+	mtx     sync.RWMutex
+	candles *candles.Cache
+	mids    []*stampedRate
+}
+
+func newVolatilityTracker() *volatilityTracker {
+	return &volatilityTracker{
+		candles: candles.NewCache(candles.CacheSize, volatilityCandleMs),
+	}
+}
+
+// setCandles replaces the DEX candles.
+func (v *volatilityTracker) setCandles(cs []msgjson.Candle) {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+	v.candles.Reset()
+	for i := range cs {
+		v.candles.Add(&cs[i])
+	}
+}
+
+// addCandle adds or updates the most recent DEX candle.
+func (v *volatilityTracker) addCandle(c *msgjson.Candle) {
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+	v.candles.Add(c)
+}
+
+// addMidGap records a mid-gap sample.
+func (v *volatilityTracker) addMidGap(stamp time.Time, rate uint64) {
+	if rate == 0 {
+		return
+	}
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+	if len(v.mids) == maxMidGapSamples {
+		copy(v.mids, v.mids[1:])
+		v.mids = v.mids[:len(v.mids)-1]
+	}
+	v.mids = append(v.mids, &stampedRate{
+		stamp: uint64(stamp.UnixMilli()),
+		rate:  rate,
+	})
+}
+
+// volatility returns the realized volatility per 5 minute candle over the
+// most recent n candles. If both DEX candles and mid-gap samples are
+// available, the greater of the two is used. ok is false if there is not
+// enough data to measure the volatility. A nil *volatilityTracker has no
+// data.
+func (v *volatilityTracker) volatility(n int) (vol float64, ok bool) {
+	if v == nil {
+		return 0, false
+	}
+	v.mtx.RLock()
+	defer v.mtx.RUnlock()
+
+	cs := v.candles.CandlesCopy()
+	if len(cs) > n+1 {
+		cs = cs[len(cs)-n-1:]
+	}
+	rates := make([]uint64, 0, len(cs))
+	for _, c := range cs {
+		if c.EndRate > 0 {
+			rates = append(rates, c.EndRate)
+		}
+	}
+	if candleVol, enough := realizedVolatility(rates); enough {
+		vol, ok = candleVol, true
+	}
+
+	if len(v.mids) < 3 {
+		return vol, ok
+	}
+	latest := v.mids[len(v.mids)-1].stamp
+	cutoff := latest - min(latest, uint64(n)*volatilityCandleMs)
+	rates = rates[:0]
+	var first uint64
+	for _, s := range v.mids {
+		if s.stamp < cutoff {
+			continue
+		}
+		if len(rates) == 0 {
+			first = s.stamp
+		}
+		rates = append(rates, s.rate)
+	}
+	if len(rates) < 3 || latest <= first {
+		return vol, ok
+	}
+	midVol, enough := realizedVolatility(rates)
+	if !enough {
+		return vol, ok
+	}
+	// Scale the per-sample volatility to the candle duration.
+	sampleInterval := float64(latest-first) / float64(len(rates)-1)
+	midVol *= math.Sqrt(float64(volatilityCandleMs) / sampleInterval)
+	return math.Max(vol, midVol), true
+}
+
+// realizedVolatility is the root mean square of the log returns of a series
+// of rates. At least two returns are required.
+func realizedVolatility(rates []uint64) (float64, bool) {
+	if len(rates) < 3 {
+		return 0, false
+	}
+	var sumSq float64
+	for i := 1; i < len(rates); i++ {
+		r := math.Log(float64(rates[i]) / float64(rates[i-1]))
+		sumSq += r * r
+	}
+	return math.Sqrt(sumSq / float64(len(rates)-1)), true
+}
@@ -0,0 +1,138 @@
+//go:build !harness && !botlive
+
+package mm
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"decred.org/dcrdex/dex/msgjson"
+)
+
+func TestVolatilityTracker(t *testing.T) {
+	var v *volatilityTracker
+	if _, ok := v.volatility(10); ok {
+		t.Fatalf("nil tracker has volatility")
+	}
+
+	v = newVolatilityTracker()
+	if _, ok := v.volatility(10); ok {
+		t.Fatalf("empty tracker has volatility")
+	}
+
+	// Alternate between two rates so that every log return has the same
+	// magnitude.
+	const r0, r1 = 1e8, 1.01e8
+	expVol := math.Log(float64(r1) / float64(r0))
+	rate := func(i int) uint64 {
+		if i%2 == 0 {
+			return r0
+		}
+		return r1
+	}
+
+	cs := make([]msgjson.Candle, 0, 20)
+	for i := 0; i < 20; i++ {
+		start := uint64(i) * volatilityCandleMs
+		cs = append(cs, msgjson.Candle{
+			StartStamp: start,
+			EndStamp:   start + volatilityCandleMs - 1,
+			EndRate:    rate(i),
+		})
+	}
+	v.setCandles(cs)
+	vol, ok := v.volatility(10)
+	if !ok {
+		t.Fatalf("no volatility from candles")
+	}
+	if math.Abs(vol-expVol) > 1e-9 {
+		t.Fatalf("wrong candle volatility. wanted %f, got %f", expVol, vol)
+	}
+
+	// A new flat candle lowers the volatility.
+	start := uint64(20) * volatilityCandleMs
+	v.addCandle(&msgjson.Candle{
+		StartStamp: start,
+		EndStamp:   start + volatilityCandleMs - 1,
+		EndRate:    rate(19),
+	})
+	vol, _ = v.volatility(10)
+	if vol >= expVol {
+		t.Fatalf("expected volatility to drop, got %f", vol)
+	}
+
+	// Mid-gap samples taken every minute with the same returns are scaled
+	// to the candle duration and are more volatile than the candles.
+	stamp := time.Unix(1e9, 0)
+	for i := 0; i < 30; i++ {
+		v.addMidGap(stamp.Add(time.Duration(i)*time.Minute), rate(i))
+	}
+	vol, ok = v.volatility(10)
+	if !ok {
+		t.Fatalf("no volatility with mid-gaps")
+	}
+	expMidVol := expVol * math.Sqrt(5)
+	if math.Abs(vol-expMidVol) > 1e-9 {
+		t.Fatalf("wrong mid-gap volatility. wanted %f, got %f", expMidVol, vol)
+	}
+}
+
+func TestVolatilityHalfSpread(t *testing.T) {
+	cfg := &VolatilityGapConfig{
+		Candles: 10,
+		Floor:   0.001,
+		Ceiling: 0.02,
+	}
+	const basisPrice = 1e8
+
+	tests := []struct {
+		name string
+		vol  float64
+		ok   bool
+		exp  uint64
+	}{
+		{
+			name: "in range",
+			vol:  0.005,
+			ok:   true,
+			exp:  5e5,
+		},
+		{
+			name: "floor",
+			vol:  0.0001,
+			ok:   true,
+			exp:  1e5,
+		},
+		{
+			name: "ceiling",
+			vol:  0.5,
+			ok:   true,
+			exp:  2e6,
+		},
+		{
+			name: "no data",
+			exp:  2e6,
+		},
+	}
+
+	for _, tt := range tests {
+		if hs := cfg.halfSpread(basisPrice, tt.vol, tt.ok); hs != tt.exp {
+			t.Fatalf("%s: wanted half-spread %d, got %d", tt.name, tt.exp, hs)
+		}
+	}
+
+	for _, badCfg := range []*VolatilityGapConfig{
+		{Candles: 1, Ceiling: 0.01},
+		{Candles: 10, Floor: 0.01, Ceiling: 0.01},
+		{Candles: 10, Ceiling: 0.2},
+	} {
+		if err := badCfg.validate(); err == nil {
+			t.Fatalf("no error for bad config %+v", badCfg)
+		}
+	}
+	defaultCfg := &VolatilityGapConfig{Ceiling: 0.01}
+	if err := defaultCfg.validate(); err != nil || defaultCfg.Candles != defaultVolatilityCandles {
+		t.Fatalf("default config not set. err = %v, candles = %d", err, defaultCfg.Candles)
+	}
+}
@@ -0,0 +1,202 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package rpcserver
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"decred.org/dcrdex/client/core"
+	"decred.org/dcrdex/dex/msgjson"
+	"golang.org/x/time/rate"
+)
+
+// API key permission scopes. Each route that an API key may use requires
+// exactly one scope. Routes that are not assigned a scope, such as appseed,
+// init, and API key management, are only available with the RPC user and
+// password.
+const (
+	scopeRead     = "read"
+	scopeTrade    = "trade"
+	scopeMM       = "mm"
+	scopeWithdraw = "withdraw"
+)
+
+var apiKeyScopes = []string{scopeRead, scopeTrade, scopeMM, scopeWithdraw}
+
+// routeScopes maps routes to the scope required to use them with an API key.
+var routeScopes = map[string]string{
+	helpRoute:                scopeRead,
+	versionRoute:             scopeRead,
+	exchangesRoute:           scopeRead,
+	getDEXConfRoute:          scopeRead,
+	bondAssetsRoute:          scopeRead,
+	walletsRoute:             scopeRead,
+	walletPeersRoute:         scopeRead,
+	orderBookRoute:           scopeRead,
+	myOrdersRoute:            scopeRead,
+	notificationsRoute:       scopeRead,
+	mmAvailableBalancesRoute: scopeRead,
+	mmStatusRoute:            scopeRead,
+	mmAnalyticsRoute:         scopeRead,
+	mmRiskStatusRoute:        scopeRead,
+	stakeStatusRoute:         scopeRead,
+	txHistoryRoute:           scopeRead,
+	walletTxRoute:            scopeRead,
+	checkBridgeApprovalRoute: scopeRead,
+	pendingBridgesRoute:      scopeRead,
+	bridgeHistoryRoute:       scopeRead,
+	supportedBridgesRoute:    scopeRead,
+	bridgeFeesAndLimitsRoute: scopeRead,
+	stopOrdersRoute:          scopeRead,
+	scheduledOrderRoute:      scopeRead,
+	scheduledOrdersRoute:     scopeRead,
+	taxExportRoute:           scopeRead,
+	allowlistRoute:           scopeRead,
+
+	tradeRoute:                scopeTrade,
+	multiTradeRoute:           scopeTrade,
+	cancelRoute:               scopeTrade,
+	amendRoute:                scopeTrade,
+	stopOrderRoute:            scopeTrade,
+	cancelStopOrderRoute:      scopeTrade,
+	scheduleOrderRoute:        scopeTrade,
+	cancelScheduledOrderRoute: scopeTrade,
+
+	startBotRoute:            scopeMM,
+	stopBotRoute:             scopeMM,
+	updateRunningBotCfgRoute: scopeMM,
+	updateRunningBotInvRoute: scopeMM,
+	mmKillSwitchRoute:        scopeMM,
+	mmResetKillSwitchRoute:   scopeMM,
+	setMMRiskConfigRoute:     scopeMM,
+
+	sendRoute:           scopeWithdraw,
+	withdrawRoute:       scopeWithdraw,
+	withdrawBchSpvRoute: scopeWithdraw,
+	bridgeRoute:         scopeWithdraw,
+}
+
+// ctxAPIKey is the request context key for the authenticated *apiKey. Requests
+// authenticated with the RPC user and password have no API key.
+type ctxKey int
+
+const ctxAPIKey ctxKey = iota
+
+// apiKey is an API key loaded from the client DB, with its request rate
+// limiter.
+type apiKey struct {
+	*core.APIKey
+	scopes map[string]bool
+	// limiter is nil if the key has no rate limit.
+	limiter *rate.Limiter
+}
+
+// allows checks whether the API key may use the route.
+func (k *apiKey) allows(route string) bool {
+	scope, found := routeScopes[route]
+	return found && k.scopes[scope]
+}
+
+// newAPIKey creates an *apiKey with a rate limiter allowing the key's
+// per-minute rate limit, and bursts of up to the same number of requests.
+func newAPIKey(k *core.APIKey) *apiKey {
+	key := &apiKey{
+		APIKey: k,
+		scopes: make(map[string]bool, len(k.Scopes)),
+	}
+	for _, scope := range k.Scopes {
+		key.scopes[scope] = true
+	}
+	if k.RateLimit > 0 {
+		key.limiter = rate.NewLimiter(rate.Every(time.Minute/time.Duration(k.RateLimit)), int(k.RateLimit))
+	}
+	return key
+}
+
+// loadAPIKeys loads the API keys from core.
+func (s *RPCServer) loadAPIKeys() error {
+	keys, err := s.core.APIKeys()
+	if err != nil {
+		return err
+	}
+	s.apiKeyMtx.Lock()
+	s.apiKeys = make(map[[32]byte]*apiKey, len(keys))
+	s.apiKeyMtx.Unlock()
+	for _, k := range keys {
+		s.addAPIKey(k)
+	}
+	return nil
+}
+
+// addAPIKey adds the API key to the keys that can authenticate.
+func (s *RPCServer) addAPIKey(k *core.APIKey) {
+	if len(k.Hash) != sha256.Size {
+		log.Errorf("Ignoring API key %q with invalid hash length %d", k.Name, len(k.Hash))
+		return
+	}
+	var h [32]byte
+	copy(h[:], k.Hash)
+	s.apiKeyMtx.Lock()
+	s.apiKeys[h] = newAPIKey(k)
+	s.apiKeyMtx.Unlock()
+}
+
+// removeAPIKey removes the named API key from the keys that can authenticate.
+func (s *RPCServer) removeAPIKey(name string) {
+	s.apiKeyMtx.Lock()
+	defer s.apiKeyMtx.Unlock()
+	for h, k := range s.apiKeys {
+		if k.Name == name {
+			delete(s.apiKeys, h)
+		}
+	}
+}
+
+// apiKey finds the API key with the name and secret.
+func (s *RPCServer) apiKey(name, secret string) *apiKey {
+	h := sha256.Sum256([]byte(secret))
+	s.apiKeyMtx.RLock()
+	key, found := s.apiKeys[h]
+	s.apiKeyMtx.RUnlock()
+	if !found || subtle.ConstantTimeCompare([]byte(key.Name), []byte(name)) != 1 {
+		return nil
+	}
+	return key
+}
+
+// requestAPIKey is the API key that authenticated the request, or nil if the
+// request was authenticated with the RPC user and password.
+func requestAPIKey(r *http.Request) *apiKey {
+	key, _ := r.Context().Value(ctxAPIKey).(*apiKey)
+	return key
+}
+
+// permissionError is the error returned when an API key lacks the scope
+// needed for a route.
+func permissionError(key *apiKey, route string) *msgjson.Error {
+	if scope, found := routeScopes[route]; found {
+		return msgjson.NewError(msgjson.RPCPermissionError, "API key %q does not have the %q scope required for %s", key.Name, scope, route)
+	}
+	return msgjson.NewError(msgjson.RPCPermissionError, "%s requires the RPC user and password", route)
+}
+
+// validateScopes checks that the scopes are known and not repeated.
+func validateScopes(scopes []string) error {
+	seen := make(map[string]bool, len(scopes))
+	for _, scope := range scopes {
+		if seen[scope] {
+			return fmt.Errorf("%w: duplicate scope %q", errArgs, scope)
+		}
+		seen[scope] = true
+		if !slices.Contains(apiKeyScopes, scope) {
+			return fmt.Errorf("%w: unknown scope %q. valid scopes are %v", errArgs, scope, apiKeyScopes)
+		}
+	}
+	return nil
+}
@@ -57,6 +57,11 @@ const (
 	updateRunningBotInvRoute   = "updaterunningbotinv"
 	mmAvailableBalancesRoute   = "mmavailablebalances"
 	mmStatusRoute              = "mmstatus"
+	mmAnalyticsRoute           = "mmanalytics"
+	mmKillSwitchRoute          = "mmkillswitch"
+	mmResetKillSwitchRoute     = "mmresetkillswitch"
+	mmRiskStatusRoute          = "mmriskstatus"
+	setMMRiskConfigRoute       = "setmmriskconfig"
 	multiTradeRoute            = "multitrade"
 	stakeStatusRoute           = "stakestatus"
 	setVSPRoute                = "setvsp"
@@ -72,6 +77,23 @@ const (
 	bridgeHistoryRoute         = "bridgehistory"
 	supportedBridgesRoute      = "supportedbridges"
 	bridgeFeesAndLimitsRoute   = "bridgefeesandlimits"
+	stopOrderRoute             = "stoporder"
+	cancelStopOrderRoute       = "cancelstoporder"
+	stopOrdersRoute            = "stoporders"
+	scheduleOrderRoute         = "scheduleorder"
+	cancelScheduledOrderRoute  = "cancelscheduledorder"
+	scheduledOrderRoute        = "scheduledorder"
+	scheduledOrdersRoute       = "scheduledorders"
+	amendRoute                 = "amend"
+	taxExportRoute             = "taxexport"
+	addressBookRoute           = "addressbook"
+	addAddressRoute            = "addaddress"
+	removeAddressRoute         = "removeaddress"
+	allowlistRoute             = "allowlist"
+	setAllowlistRoute          = "setallowlist"
+	apiKeysRoute               = "apikeys"
+	createAPIKeyRoute          = "createapikey"
+	revokeAPIKeyRoute          = "revokeapikey"
 )
 
 const (
@@ -80,10 +102,13 @@ const (
 	walletLockedStr   = "%s wallet locked"
 	walletUnlockedStr = "%s wallet unlocked"
 	canceledOrderStr  = "canceled order %s"
+	canceledStopStr   = "canceled stop order %s"
+	canceledSchedStr  = "canceled scheduled order %s"
 	logoutStr         = "goodbye"
 	walletStatusStr   = "%s wallet has been %s"
 	setVotePrefsStr   = "vote preferences set"
 	setVSPStr         = "vsp set to %s"
+	revokedAPIKeyStr  = "revoked API key %s"
 )
 
 // createResponse creates a msgjson response payload.
@@ -139,6 +164,11 @@ var routes = map[string]func(s *RPCServer, params *RawParams) *msgjson.ResponseP
 	stopBotRoute:               handleStopBot,
 	mmAvailableBalancesRoute:   handleMMAvailableBalances,
 	mmStatusRoute:              handleMMStatus,
+	mmAnalyticsRoute:           handleMMAnalytics,
+	mmKillSwitchRoute:          handleMMKillSwitch,
+	mmResetKillSwitchRoute:     handleMMResetKillSwitch,
+	mmRiskStatusRoute:          handleMMRiskStatus,
+	setMMRiskConfigRoute:       handleSetMMRiskConfig,
 	updateRunningBotCfgRoute:   handleUpdateRunningBotCfg,
 	updateRunningBotInvRoute:   handleUpdateRunningBotInventory,
 	multiTradeRoute:            handleMultiTrade,
@@ -156,6 +186,23 @@ var routes = map[string]func(s *RPCServer, params *RawParams) *msgjson.ResponseP
 	bridgeHistoryRoute:         handleBridgeHistory,
 	supportedBridgesRoute:      handleSupportedBridges,
 	bridgeFeesAndLimitsRoute:   handleBridgeFeesAndLimits,
+	stopOrderRoute:             handleStopOrder,
+	cancelStopOrderRoute:       handleCancelStopOrder,
+	stopOrdersRoute:            handleStopOrders,
+	scheduleOrderRoute:         handleScheduleOrder,
+	cancelScheduledOrderRoute:  handleCancelScheduledOrder,
+	scheduledOrderRoute:        handleScheduledOrder,
+	scheduledOrdersRoute:       handleScheduledOrders,
+	amendRoute:                 handleAmend,
+	taxExportRoute:             handleTaxExport,
+	addressBookRoute:           handleAddressBook,
+	addAddressRoute:            handleAddAddress,
+	removeAddressRoute:         handleRemoveAddress,
+	allowlistRoute:             handleAllowlist,
+	setAllowlistRoute:          handleSetAllowlist,
+	apiKeysRoute:               handleAPIKeys,
+	createAPIKeyRoute:          handleCreateAPIKey,
+	revokeAPIKeyRoute:          handleRevokeAPIKey,
 }
 
 // handleHelp handles requests for help. Returns general help for all commands
@@ -573,6 +620,132 @@ func handleCancel(s *RPCServer, params *RawParams) *msgjson.ResponsePayload {
 	return createResponse(cancelRoute, &res, nil)
 }
 
+// handleAmend handles requests for amend. *msgjson.ResponsePayload.Error is
+// empty if successful.
+func handleAmend(s *RPCServer, params *RawParams) *msgjson.ResponsePayload {
+	form, err := parseAmendArgs(params)
+	if err != nil {
+		return usage(amendRoute, err)
+	}
+	defer form.appPass.Clear()
+	res, err := s.core.AmendOrder(form.appPass, form.srvForm)
+	if err != nil {
+		resErr := msgjson.NewError(msgjson.RPCAmendOrderError, "unable to amend order %s: %v", form.srvForm.OrderID, err)
+		return createResponse(amendRoute, nil, resErr)
+	}
+	tradeRes := &tradeResponse{
+		OrderID: res.ID.String(),
+		Sig:     res.Sig.String(),
+		Stamp:   res.Stamp,
+	}
+	return createResponse(amendRoute, &tradeRes, nil)
+}
+
+// handleStopOrder handles requests for stoporder. *msgjson.ResponsePayload.Error
+// is empty if successful.
+func handleStopOrder(s *RPCServer, params *RawParams) *msgjson.ResponsePayload {
+	form, err := parseStopOrderArgs(params)
+	if err != nil {
+		return usage(stopOrderRoute, err)
+	}
+	so, err := s.core.PlaceStopOrder(form.srvForm)
+	if err != nil {
+		resErr := msgjson.NewError(msgjson.RPCStopOrderError, "unable to place stop order: %v", err)
+		return createResponse(stopOrderRoute, nil, resErr)
+	}
+	return createResponse(stopOrderRoute, so, nil)
+}
+
+// handleCancelStopOrder handles requests for cancelstoporder.
+// *msgjson.ResponsePayload.Error is empty if successful.
+func handleCancelStopOrder(s *RPCServer, params *RawParams) *msgjson.ResponsePayload {
+	id, err := parseClientOrderIDArgs(params)
+	if err != nil {
+		return usage(cancelStopOrderRoute, err)
+	}
+	if err := s.core.CancelStopOrder(id); err != nil {
+		resErr := msgjson.NewError(msgjson.RPCStopOrderError, "unable to cancel stop order %q: %v", id, err)
+		return createResponse(cancelStopOrderRoute, nil, resErr)
+	}
+	res := fmt.Sprintf(canceledStopStr, id)
+	return createResponse(cancelStopOrderRoute, &res, nil)
+}
+
+// handleStopOrders handles requests for stoporders.
+// *msgjson.ResponsePayload.Error is empty if successful.
+func handleStopOrders(s *RPCServer, params *RawParams) *msgjson.ResponsePayload {
+	activeOnly, err := parseActiveOnlyArgs(params)
+	if err != nil {
+		return usage(stopOrdersRoute, err)
+	}
+	sos, err := s.core.StopOrders(activeOnly)
+	if err != nil {
+		resErr := msgjson.NewError(msgjson.RPCStopOrderError, "unable to retrieve stop orders: %v", err)
+		return createResponse(stopOrdersRoute, nil, resErr)
+	}
+	return createResponse(stopOrdersRoute, sos, nil)
+}
+
+// handleScheduleOrder handles requests for scheduleorder.
+// *msgjson.ResponsePayload.Error is empty if successful.
+func handleScheduleOrder(s *RPCServer, params *RawParams) *msgjson.ResponsePayload {
+	form, err := parseScheduleOrderArgs(params)
+	if err != nil {
+		return usage(scheduleOrderRoute, err)
+	}
+	so, err := s.core.CreateScheduledOrder(form.srvForm)
+	if err != nil {
+		resErr := msgjson.NewError(msgjson.RPCScheduledOrderError, "unable to create scheduled order: %v", err)
+		return createResponse(scheduleOrderRoute, nil, resErr)
+	}
+	return createResponse(scheduleOrderRoute, so, nil)
+}
+
+// handleCancelScheduledOrder handles requests for cancelscheduledorder.
+// *msgjson.ResponsePayload.Error is empty if successful.
+func handleCancelScheduledOrder(s *RPCServer, params *RawParams) *msgjson.ResponsePayload {
+	id, err := parseClientOrderIDArgs(params)
+	if err != nil {
+		return usage(cancelScheduledOrderRoute, err)
+	}
+	if err := s.core.CancelScheduledOrder(id); err != nil {
+		resErr := msgjson.NewError(msgjson.RPCScheduledOrderError, "unable to cancel scheduled order %q: %v", id, err)
+		return createResponse(cancelScheduledOrderRoute, nil, resErr)
+	}
+	res := fmt.Sprintf(canceledSchedStr, id)
+	return createResponse(cancelScheduledOrderRoute, &res, nil)
+}
+
+// handleScheduledOrder handles requests for scheduledorder.
+// *msgjson.ResponsePayload.Error is empty if successful.
+func handleScheduledOrder(s *RPCServer, params *RawParams) *msgjson.ResponsePayload {
+	id, err := parseClientOrderIDArgs(params)
+	if err != nil {
+		return usage(scheduledOrderRoute, err)
+	}
+	so, err := s.core.ScheduledOrder(id)
+	if err != nil {
+		resErr := msgjson.NewError(msgjson.RPCScheduledOrderError, "unable to retrieve scheduled order %q: %v", id, err)
+		return createResponse(scheduledOrderRoute, nil, resErr)
+	}
+	return createResponse(scheduledOrderRoute, so, nil)
+}
+
+// handleScheduledOrders handles requests for scheduledorders.
+// *msgjson.ResponsePayload.Error is empty if successful.
+func handleScheduledOrders(s *RPCServer, params *RawParams) *msgjson.ResponsePayload {
+	activeOnly, err := parseActiveOnlyArgs(params)
+	if err != nil {
+		return usage(scheduledOrdersRoute, err)
+	}
+	sos, err := s.core.ScheduledOrders(activeOnly)
+	if err != nil {
+		resErr := msgjson.NewError(msgjson.RPCScheduledOrderError, "unable to retrieve scheduled orders: %v", err)
+		return createResponse(scheduledOrdersRoute, nil, resErr)
+	}
+	return createResponse(scheduledOrdersRoute, sos, nil)
+}
+
 // handleWithdraw handles requests for withdraw. *msgjson.ResponsePayload.Error
 // is empty if successful.
 func handleWithdraw(s *RPCServer, params *RawParams) *msgjson.ResponsePayload {
@@ -599,7 +772,7 @@ func send(s *RPCServer, params *RawParams, route string) *msgjson.ResponsePayloa
 		resErr := msgjson.NewError(msgjson.RPCFundTransferError, "empty pass")
 		return createResponse(route, nil, resErr)
 	}
-	coin, err := s.core.Send(form.appPass, form.assetID, form.value, form.address, subtract)
+	coin, err := s.core.AllowlistedSend(form.appPass, form.assetID, form.value, form.address, subtract)
 	if err != nil {
 		resErr := msgjson.NewError(msgjson.RPCFundTransferError, "unable to %s: %v", route, err)
 		return createResponse(route, nil, resErr)
@@ -608,6 +781,130 @@ func send(s *RPCServer, params *RawParams, route string) *msgjson.ResponsePayloa
 	return createResponse(route, &res, nil)
 }
 
+// handleAddressBook handles requests for addressbook.
+// *msgjson.ResponsePayload.Error is empty if successful.
+func handleAddressBook(s *RPCServer, params *RawParams) *msgjson.ResponsePayload {
+	appPass, err := parseAddressBookArgs(params)
+	if err != nil {
+		return usage(addressBookRoute, err)
+	}
+	defer appPass.Clear()
+	entries, err := s.core.AddressBook(appPass)
+	if err != nil {
+		resErr := msgjson.NewError(msgjson.RPCAddressBookError, "unable to retrieve address book: %v", err)
+		return createResponse(addressBookRoute, nil, resErr)
+	}
+	return createResponse(addressBookRoute, entries, nil)
+}
+
+// handleAddAddress handles requests for addaddress.
+// *msgjson.ResponsePayload.Error is empty if successful.
+func handleAddAddress(s *RPCServer, params *RawParams) *msgjson.ResponsePayload {
+	form, err := parseAddAddressArgs(params)
+	if err != nil {
+		return usage(addAddressRoute, err)
+	}
+	defer form.appPass.Clear()
+	entry, err := s.core.AddAddress(form.appPass, form.assetID, form.address, form.label)
+	if err != nil {
+		resErr := msgjson.NewError(msgjson.RPCAddressBookError, "unable to add address: %v", err)
+		return createResponse(addAddressRoute, nil, resErr)
+	}
+	return createResponse(addAddressRoute, entry, nil)
+}
+
+// handleRemoveAddress handles requests for removeaddress.
+// *msgjson.ResponsePayload.Error is empty if successful.
+func handleRemoveAddress(s *RPCServer, params *RawParams) *msgjson.ResponsePayload {
+	form, err := parseRemoveAddressArgs(params)
+	if err != nil {
+		return usage(removeAddressRoute, err)
+	}
+	defer form.appPass.Clear()
+	if err := s.core.RemoveAddress(form.appPass, form.id); err != nil {
+		resErr := msgjson.NewError(msgjson.RPCAddressBookError, "unable to remove address: %v", err)
+		return createResponse(removeAddressRoute, nil, resErr)
+	}
+	res := "address removed"
+	return createResponse(removeAddressRoute, &res, nil)
+}
+
+// handleAllowlist handles requests for allowlist.
+// *msgjson.ResponsePayload.Error is empty if successful.
+func handleAllowlist(s *RPCServer, _ *RawParams) *msgjson.ResponsePayload {
+	status, err := s.core.Allowlist()
+	if err != nil {
+		resErr := msgjson.NewError(msgjson.RPCAddressBookError, "unable to retrieve allowlist status: %v", err)
+		return createResponse(allowlistRoute, nil, resErr)
+	}
+	return createResponse(allowlistRoute, status, nil)
+}
+
+// handleSetAllowlist handles requests for setallowlist.
+// *msgjson.ResponsePayload.Error is empty if successful.
+func handleSetAllowlist(s *RPCServer, params *RawParams) *msgjson.ResponsePayload {
+	form, err := parseSetAllowlistArgs(params)
+	if err != nil {
+		return usage(setAllowlistRoute, err)
+	}
+	defer form.appPass.Clear()
+	status, err := s.core.SetAllowlist(form.appPass, form.enable)
+	if err != nil {
+		resErr := msgjson.NewError(msgjson.RPCAddressBookError, "unable to set allowlist: %v", err)
+		return createResponse(setAllowlistRoute, nil, resErr)
+	}
+	return createResponse(setAllowlistRoute, status, nil)
+}
+
+// handleAPIKeys handles requests for apikeys. *msgjson.ResponsePayload.Error
+// is empty if successful.
+func handleAPIKeys(s *RPCServer, _ *RawParams) *msgjson.ResponsePayload {
+	keys, err := s.core.APIKeys()
+	if err != nil {
+		resErr := msgjson.NewError(msgjson.RPCAPIKeyError, "unable to retrieve API keys: %v", err)
+		return createResponse(apiKeysRoute, nil, resErr)
+	}
+	return createResponse(apiKeysRoute, keys, nil)
+}
+
+// handleCreateAPIKey handles requests for createapikey.
+// *msgjson.ResponsePayload.Error is empty if successful.
+func handleCreateAPIKey(s *RPCServer, params *RawParams) *msgjson.ResponsePayload {
+	form, err := parseCreateAPIKeyArgs(params)
+	if err != nil {
+		return usage(createAPIKeyRoute, err)
+	}
+	defer form.appPass.Clear()
+	secret, key, err := s.core.CreateAPIKey(form.appPass, form.name, form.scopes, form.rateLimit)
+	if err != nil {
+		resErr := msgjson.NewError(msgjson.RPCAPIKeyError, "unable to create API key: %v", err)
+		return createResponse(createAPIKeyRoute, nil, resErr)
+	}
+	s.addAPIKey(key)
+	res := &CreateAPIKeyResponse{
+		APIKey: key,
+		Secret: secret,
+	}
+	return createResponse(createAPIKeyRoute, res, nil)
+}
+
+// handleRevokeAPIKey handles requests for revokeapikey.
+// *msgjson.ResponsePayload.Error is empty if successful.
+func handleRevokeAPIKey(s *RPCServer, params *RawParams) *msgjson.ResponsePayload {
+	form, err := parseRevokeAPIKeyArgs(params)
+	if err != nil {
+		return usage(revokeAPIKeyRoute, err)
+	}
+	defer form.appPass.Clear()
+	if err := s.core.RevokeAPIKey(form.appPass, form.name); err != nil {
+		resErr := msgjson.NewError(msgjson.RPCAPIKeyError, "unable to revoke API key: %v", err)
+		return createResponse(revokeAPIKeyRoute, nil, resErr)
+	}
+	s.removeAPIKey(form.name)
+	res := fmt.Sprintf(revokedAPIKeyStr, form.name)
+	return createResponse(revokeAPIKeyRoute, &res, nil)
+}
+
 // handleRescanWallet handles requests to rescan a wallet. This may trigger an
 // asynchronous resynchronization of wallet address activity, and the wallet
 // state should be consulted for status. *msgjson.ResponsePayload.Error is empty
@@ -984,6 +1281,92 @@ func handleMMStatus(s *RPCServer, params *RawParams) *msgjson.ResponsePayload {
 	return createResponse(mmStatusRoute, status, nil)
 }
 
+func handleMMAnalytics(s *RPCServer, params *RawParams) *msgjson.ResponsePayload {
+	form, err := parseMMAnalyticsArgs(params)
+	if err != nil {
+		return usage(mmAnalyticsRoute, err)
+	}
+
+	analytics, err := s.mm.RunAnalytics(form.startTime, form.mkt, form.window)
+	if err != nil {
+		resErr := msgjson.NewError(msgjson.RPCMMAnalyticsError, "unable to compute analytics: %v", err)
+		return createResponse(mmAnalyticsRoute, nil, resErr)
+	}
+
+	if !form.csv {
+		return createResponse(mmAnalyticsRoute, analytics, nil)
+	}
+
+	var b strings.Builder
+	if err := analytics.WriteCSV(&b); err != nil {
+		resErr := msgjson.NewError(msgjson.RPCMMAnalyticsError, "unable to write CSV: %v", err)
+		return createResponse(mmAnalyticsRoute, nil, resErr)
+	}
+	return createResponse(mmAnalyticsRoute, b.String(), nil)
+}
+
+func handleMMKillSwitch(s *RPCServer, _ *RawParams) *msgjson.ResponsePayload {
+	if err := s.mm.KillSwitch(); err != nil {
+		resErr := msgjson.NewError(msgjson.RPCMMRiskError, "kill switch error: %v", err)
+		return createResponse(mmKillSwitchRoute, nil, resErr)
+	}
+	return createResponse(mmKillSwitchRoute, "kill switch triggered", nil)
+}
+
+func handleMMResetKillSwitch(s *RPCServer, _ *RawParams) *msgjson.ResponsePayload {
+	if err := s.mm.ResetKillSwitch(); err != nil {
+		resErr := msgjson.NewError(msgjson.RPCMMRiskError, "unable to reset kill switch: %v", err)
+		return createResponse(mmResetKillSwitchRoute, nil, resErr)
+	}
+	return createResponse(mmResetKillSwitchRoute, "kill switch reset", nil)
+}
+
+func handleMMRiskStatus(s *RPCServer, _ *RawParams) *msgjson.ResponsePayload {
+	return createResponse(mmRiskStatusRoute, s.mm.RiskStatus(), nil)
+}
+
+func handleSetMMRiskConfig(s *RPCServer, params *RawParams) *msgjson.ResponsePayload {
+	cfg, err := parseSetMMRiskConfigArgs(params)
+	if err != nil {
+		return usage(setMMRiskConfigRoute, err)
+	}
+	if err := s.mm.UpdateRiskConfig(cfg); err != nil {
+		resErr := msgjson.NewError(msgjson.RPCMMRiskError, "unable to update risk config: %v", err)
+		return createResponse(setMMRiskConfigRoute, nil, resErr)
+	}
+	return createResponse(setMMRiskConfigRoute, "updated risk config", nil)
+}
+
+// handleTaxExport handles requests for taxexport. *msgjson.ResponsePayload.Error
+// is empty if successful.
+func handleTaxExport(s *RPCServer, params *RawParams) *msgjson.ResponsePayload {
+	form, err := parseTaxExportArgs(params)
+	if err != nil {
+		return usage(taxExportRoute, err)
+	}
+
+	report, err := s.core.TaxExport(form.srvForm)
+	if err != nil {
+		resErr := msgjson.NewError(msgjson.RPCTaxExportError, "unable to export history: %v", err)
+		return createResponse(taxExportRoute, nil, resErr)
+	}
+
+	var b strings.Builder
+	switch form.format {
+	case taxExportLedgerFormat:
+		err = report.WriteLedgerCSV(&b)
+	case taxExportCostBasisFormat:
+		err = report.WriteCostBasisCSV(&b)
+	default:
+		return createResponse(taxExportRoute, report, nil)
+	}
+	if err != nil {
+		resErr := msgjson.NewError(msgjson.RPCTaxExportError, "unable to write CSV: %v", err)
+		return createResponse(taxExportRoute, nil, resErr)
+	}
+	return createResponse(taxExportRoute, b.String(), nil)
+}
+
 func handleSetVSP(s *RPCServer, params *RawParams) *msgjson.ResponsePayload {
 	form, err := parseSetVSPArgs(params)
 	if err != nil {
@@ -1080,7 +1463,7 @@ func handleWithdrawBchSpv(s *RPCServer, params *RawParams) *msgjson.ResponsePayl
 	}
 	defer appPW.Clear()
 
-	txB, err := s.core.GenerateBCHRecoveryTransaction(appPW, recipient)
+	txB, err := s.core.AllowlistedBCHRecoveryTransaction(appPW, recipient)
 	if err != nil {
 		resErr := msgjson.NewError(msgjson.RPCCreateWalletError, "error generating tx: %v", err)
 		return createResponse(withdrawBchSpvRoute, nil, resErr)
@@ -1673,6 +2056,182 @@ var helpMsgs = map[string]helpMsg{
     orderID (string): The hex ID of the order to cancel`,
 		returns: `Returns:
     string: The message "` + fmt.Sprintf(canceledOrderStr, "[order ID]") + `"`,
+	},
+	amendRoute: {
+		pwArgsShort: `"appPass"`,
+		argsShort:   `"orderID" rate qty`,
+		cmdSummary: `Amend a standing limit order. The order is canceled and a
+    replacement with the new rate and quantity is placed in the same epoch.
+    The replacement is funded with new coins, even for account-based assets,
+    so the wallet needs the funds for both orders until the cancel executes.
+    The replacement is not placed if the cancel order is rejected.`,
+		pwArgsLong: `Password Args:
+    appPass (string): The Bison Wallet password.`,
+		argsLong: `Args:
+    orderID (string): The hex ID of the order to amend.
+    rate (int): The rate of the replacement order. 0 keeps the original rate.
+    qty (int): The quantity of the replacement order. 0 keeps the remaining
+      quantity of the original order.`,
+		returns: `Returns:
+    obj: The replacement order details.
+    {
+      "orderid" (string): The order's unique hex identifier.
+      "sig" (string): The DEX's signature of the order information.
+      "stamp" (int): The time the order was signed in milliseconds since 00:00:00
+        Jan 1 1970.
+    }`,
+	},
+	addressBookRoute: {
+		pwArgsShort: `"appPass"`,
+		cmdSummary:  `List the address book.`,
+		pwArgsLong: `Password Args:
+    appPass (string): The Bison Wallet password.`,
+		returns: `Returns:
+    array: The address book entries.
+    [
+      {
+        "id" (string): The entry's hex ID.
+        "assetID" (int): The asset's BIP-44 registered coin index.
+        "symbol" (string): The asset's symbol.
+        "address" (string): The address.
+        "label" (string): The address label.
+        "stamp" (int): The time the address was added in milliseconds since
+          00:00:00 Jan 1 1970.
+        "activeAt" (int): The time after which the address may be used with the
+          allowlist enabled in milliseconds since 00:00:00 Jan 1 1970.
+      },...
+    ]`,
+	},
+	addAddressRoute: {
+		pwArgsShort: `"appPass"`,
+		argsShort:   `assetID "address" ("label")`,
+		cmdSummary: `Add an address to the address book, or update the label of an
+    existing address. A new address can only be used with the withdrawal
+    allowlist enabled after a delay.`,
+		pwArgsLong: `Password Args:
+    appPass (string): The Bison Wallet password.`,
+		argsLong: `Args:
+    assetID (int): The asset's BIP-44 registered coin index. e.g. 42 for DCR.
+      See https://github.com/satoshilabs/slips/blob/master/slip-0044.md
+    address (string): The address.
+    label (string): Optional. A label for the address.`,
+		returns: `Returns:
+    obj: The address book entry.
+    {
+      "id" (string): The entry's hex ID.
+      "assetID" (int): The asset's BIP-44 registered coin index.
+      "symbol" (string): The asset's symbol.
+      "address" (string): The address.
+      "label" (string): The address label.
+      "stamp" (int): The time the address was added in milliseconds since
+        00:00:00 Jan 1 1970.
+      "activeAt" (int): The time after which the address may be used with the
+        allowlist enabled in milliseconds since 00:00:00 Jan 1 1970.
+    }`,
+	},
+	removeAddressRoute: {
+		pwArgsShort: `"appPass"`,
+		argsShort:   `"id"`,
+		cmdSummary:  `Remove an address from the address book.`,
+		pwArgsLong: `Password Args:
+    appPass (string): The Bison Wallet password.`,
+		argsLong: `Args:
+    id (string): The hex ID of the address book entry.`,
+		returns: `Returns:
+    string: The message "address removed"`,
+	},
+	allowlistRoute: {
+		cmdSummary: `Get the state of the withdrawal allowlist.`,
+		returns: `Returns:
+    obj: The allowlist state.
+    {
+      "enabled" (bool): Whether the allowlist is enabled.
+      "disableAt" (int): The time a pending disable takes effect in
+        milliseconds since 00:00:00 Jan 1 1970. Omitted if none is pending.
+      "delay" (int): The delay in milliseconds before new addresses may be
+        used and before a disable takes effect.
+    }`,
+	},
+	setAllowlistRoute: {
+		pwArgsShort: `"appPass"`,
+		argsShort:   `enable`,
+		cmdSummary: `Enable or disable the withdrawal allowlist. While enabled, send and
+    withdraw only succeed to addresses in the address book that were added at
+    least the allowlist delay ago. Enabling takes effect immediately. Disabling
+    takes effect after the allowlist delay.`,
+		pwArgsLong: `Password Args:
+    appPass (string): The Bison Wallet password.`,
+		argsLong: `Args:
+    enable (bool): Whether to enable the allowlist.`,
+		returns: `Returns:
+    obj: The allowlist state.
+    {
+      "enabled" (bool): Whether the allowlist is enabled.
+      "disableAt" (int): The time a pending disable takes effect in
+        milliseconds since 00:00:00 Jan 1 1970. Omitted if none is pending.
+      "delay" (int): The delay in milliseconds before new addresses may be
+        used and before a disable takes effect.
+    }`,
+	},
+	apiKeysRoute: {
+		cmdSummary: `List the API keys. API keys are only managed with the RPC user and
+    password.`,
+		returns: `Returns:
+    array: The API keys.
+    [
+      {
+        "name" (string): The key name, used as the user name for HTTP basic
+          authentication.
+        "scopes" (array): The key's permission scopes.
+        "rateLimit" (int): The number of requests allowed per minute. Zero if
+          unlimited.
+        "stamp" (int): The time the key was created in milliseconds since
+          00:00:00 Jan 1 1970.
+      },...
+    ]`,
+	},
+	createAPIKeyRoute: {
+		pwArgsShort: `"appPass"`,
+		argsShort:   `"name" "scopes" (rateLimit)`,
+		cmdSummary: `Create an API key. Requests authenticated with the key name as the
+    user and the returned secret as the password may only use the routes in
+    the key's permission scopes. Scopes are independent, so most keys should
+    include read. The scopes are:
+      read: Routes that only retrieve information.
+      trade: Placing, amending, and canceling orders.
+      mm: Starting, stopping, and updating market making bots.
+      withdraw: Sending and withdrawing funds, and bridging.
+    Other routes, such as appseed, are only available with the RPC user and
+    password. Only a hash of the secret is stored.`,
+		pwArgsLong: `Password Args:
+    appPass (string): The Bison Wallet password.`,
+		argsLong: `Args:
+    name (string): A unique name for the key. Cannot contain a colon or
+      whitespace.
+    scopes (string): Comma-separated permission scopes. e.g. "read,trade"
+    rateLimit (int): Optional. The number of requests allowed per minute.
+      Default is unlimited.`,
+		returns: `Returns:
+    obj: The API key.
+    {
+      "name" (string): The key name.
+      "scopes" (array): The key's permission scopes.
+      "rateLimit" (int): The number of requests allowed per minute.
+      "stamp" (int): The time the key was created in milliseconds since
+        00:00:00 Jan 1 1970.
+      "secret" (string): The key secret. It cannot be retrieved again.
+    }`,
+	},
+	revokeAPIKeyRoute: {
+		pwArgsShort: `"appPass"`,
+		argsShort:   `"name"`,
+		cmdSummary:  `Revoke an API key.`,
+		pwArgsLong: `Password Args:
+    appPass (string): The Bison Wallet password.`,
+		argsLong: `Args:
+    name (string): The key name.`,
+		returns: `Returns:
+    string: The message "` + fmt.Sprintf(revokedAPIKeyStr, "[name]") + `"`,
 	},
 	rescanWalletRoute: {
 		argsShort: `assetID (force)`,
@@ -1695,7 +2254,8 @@ needed to complete a swap.`,
 	withdrawRoute: {
 		pwArgsShort: `"appPass"`,
 		argsShort:   `assetID value "address"`,
-		cmdSummary:  `Withdraw value from an exchange wallet to address. Fees are subtracted from the value.`,
+		cmdSummary: `Withdraw value from an exchange wallet to address. Fees are subtracted from the value.
+    If the withdrawal allowlist is enabled, the address must be allowlisted.`,
 		pwArgsLong: `Password Args:
     appPass (string): The Bison Wallet password.`,
 		argsLong: `Args:
@@ -1711,7 +2271,8 @@ needed to complete a swap.`,
 	sendRoute: {
 		pwArgsShort: `"appPass"`,
 		argsShort:   `assetID value "address"`,
-		cmdSummary:  `Sends exact value from an exchange wallet to address.`,
+		cmdSummary: `Sends exact value from an exchange wallet to address. If the
+    withdrawal allowlist is enabled, the address must be allowlisted.`,
 		pwArgsLong: `Password Args:
     appPass (string): The Bison Wallet password.`,
 		argsLong: `Args:
@@ -1864,8 +2425,12 @@ needed to complete a swap.`,
 		addr (string): The peer's address (host:port).`,
 	},
 	notificationsRoute: {
-		cmdSummary: `See recent notifications.`,
-		argsShort:  `(num)`,
+		cmdSummary: `See recent notifications. For a live feed, connect a websocket to
+    /ws to receive "notify" notifications. Send a "subscribe" request with
+    {"types": [...], "topics": [...]} to receive only the matching
+    notification types (e.g. order, match, balance, bondpost, runstats) and
+    topics. An empty subscription receives everything.`,
+		argsShort: `(num)`,
 		argsLong: `Args:
 		num (int): The number of notifications to load.`,
 	},
@@ -1903,6 +2468,87 @@ needed to complete a swap.`,
 	mmStatusRoute: {
 		cmdSummary: `Get market making status.`,
 	},
+	mmAnalyticsRoute: {
+		cmdSummary: `Get PnL, fee, fill ratio and spread capture analytics for a market making run.`,
+		argsShort:  `(host) (baseID) (quoteID) (startTime) [window] [format]`,
+		argsLong: `Args:
+		host (string): The DEX address.
+		baseID (int): The base asset's BIP-44 registered coin index.
+		quoteID (int): The quote asset's BIP-44 registered coin index.
+		startTime (int): The start time of the run, in unix seconds.
+		window (int): Optional. Break the analytics down into windows of this
+		  many seconds. Default is 0, no windows.
+		format (string): Optional. "json" or "csv". Default is "json".`,
+		returns: `Returns:
+    obj or string: The run analytics as JSON, or a CSV string if the format is csv.`,
+	},
+	mmKillSwitchRoute: {
+		cmdSummary: `Stop all running market making bots, canceling their orders.
+    No bots can be started until the kill switch is reset, even after a
+    restart.`,
+		returns: `Returns:
+    string: The message "kill switch triggered"`,
+	},
+	mmResetKillSwitchRoute: {
+		cmdSummary: `Reset the market making kill switch so that bots can be started
+    again. The aggregate drawdown tracking is also reset.`,
+		returns: `Returns:
+    string: The message "kill switch reset"`,
+	},
+	mmRiskStatusRoute: {
+		cmdSummary: `Get the market making risk limits, the current exposure and
+    aggregate drawdown, and the state of the kill switch.`,
+		returns: `Returns:
+    obj: The risk status.
+    {
+      "config" (obj): The risk limits.
+      "exposure" (obj): The total holdings of each asset across all running
+        bots, keyed by asset ID.
+      "drawdown" (float): The current aggregate drawdown.
+      "killed" (obj): Set if the kill switch has been triggered.
+    }`,
+	},
+	setMMRiskConfigRoute: {
+		argsShort:  `maxBotDrawdown maxDrawdown (exposureCaps)`,
+		cmdSummary: `Set the portfolio-wide market making risk limits.`,
+		argsLong: `Args:
+    maxBotDrawdown (float): The maximum drawdown of a single bot, as a ratio
+      of the USD value of its allocation. 0 disables the limit.
+    maxDrawdown (float): The maximum aggregate drawdown of all bots, as a
+      ratio of the USD value of their allocations. Exceeding it triggers the
+      kill switch. 0 disables the limit.
+    exposureCaps (string): Optional. A JSON-encoded list of
+      [assetID, cap] pairs. A cap is the maximum total holdings of the asset,
+      in atoms, across all running bots.`,
+		returns: `Returns:
+    string: The message "updated risk config"`,
+	},
+	taxExportRoute: {
+		argsShort: `start end (method) (format)`,
+		cmdSummary: `Export the trade history, swap, redeem, refund and bond fees, and
+    wallet sends and receives for tax reporting and accounting. Entries are
+    valued in USD at the time of the entry. The cost basis of disposals is
+    determined from the entire history.`,
+		argsLong: `Args:
+    start (int): The start of the period in milliseconds since 00:00:00 Jan 1 1970.
+    end (int): The end of the period in milliseconds since 00:00:00 Jan 1 1970.
+      0 is now.
+    method (string): Optional. The cost basis method, "fifo" or "lifo".
+      Default is "fifo".
+    format (string): Optional. "json", "ledger" for a CSV of all balance
+      changes, or "costbasis" for a CSV of disposals with their cost basis
+      and gain. Default is "json".`,
+		returns: `Returns:
+    obj or string: The report as JSON, or a CSV string if the format is
+      ledger or costbasis.
+    {
+      "start" (int): The start of the period.
+      "end" (int): The end of the period.
+      "method" (string): The cost basis method.
+      "ledger" (array): The balance changes in the period.
+      "disposals" (array): The disposals of acquired lots in the period.
+    }`,
+	},
 	updateRunningBotCfgRoute: {
 		cmdSummary: `Update the config and optionally the inventory of a running bot`,
 		argsShort:  `(cfgPath) (host) (baseID) (quoteID) (dexInventory) (cexInventory)`,
@@ -2034,7 +2680,9 @@ an spv wallet and enables options to view and set the vsp.
 	withdrawBchSpvRoute: {
 		pwArgsShort: `"appPass"`,
 		argsShort:   `recipient`,
-		cmdSummary:  `Get a transaction that will withdraw all funds from the deprecated Bitcoin Cash SPV wallet`,
+		cmdSummary: `Get a transaction that will withdraw all funds from the deprecated Bitcoin Cash SPV wallet.
+    While the withdrawal allowlist is enabled, the recipient must be an
+    allowlisted bch address in the address book.`,
 		argsLong: `Args:
 		  recipient (string): The Bitcoin Cash address to withdraw the funds to`,
 	},
@@ -2093,4 +2741,103 @@ an spv wallet and enables options to view and set the vsp.
 		toAssetID (int): The asset's BIP-44 registered coin index on the "to" chain.
 		bridgeName (string): The name of the bridge to query.`,
 	},
+	stopOrderRoute: {
+		argsShort: `"host" "type" sell base quote qty "priceSource" triggerRate rate trailDelta limitOffset immediate (options)`,
+		cmdSummary: `Place a stop-limit or trailing-stop order. The order is held by the
+    client and submitted as a limit order when triggered. Wallets must be
+    unlocked for the limit order to be placed.`,
+		argsLong: `Args:
+    host (string): The DEX to trade on.
+    type (string): The stop order type, "stoplimit" or "trailingstop".
+    sell (bool): Whether the order is selling.
+    base (int): The BIP-44 coin index for the market's base asset.
+    quote (int): The BIP-44 coin index for the market's quote asset.
+    qty (int): The number of units to buy/sell. Must be a multiple of the lot size.
+    priceSource (string): The market rate checked against the trigger,
+      "midgap" or "lasttrade". Empty defaults to "midgap".
+    triggerRate (int): For a stoplimit order, the rate at which the order is
+      triggered. A sell is triggered when the market rate falls to triggerRate,
+      and a buy when it rises to triggerRate.
+    rate (int): For a stoplimit order, the limit order rate. Must be a
+      multiple of the market's rate step.
+    trailDelta (int): For a trailingstop order, the distance the market rate
+      must move against the best rate seen since placement to trigger the order.
+    limitOffset (int): For a trailingstop order, the distance between the
+      trigger rate and the limit order rate.
+    immediate (bool): Require immediate match. Do not book the order.
+    options (string): Optional. A JSON-encoded string->string mapping of
+      additional trade options.`,
+		returns: `Returns:
+    obj: The stop order.`,
+	},
+	cancelStopOrderRoute: {
+		argsShort:  `"stopOrderID"`,
+		cmdSummary: `Cancel a waiting stop order.`,
+		argsLong: `Args:
+    stopOrderID (string): The hex ID of the stop order to cancel.`,
+		returns: `Returns:
+    string: The message "` + fmt.Sprintf(canceledStopStr, "[stop order ID]") + `"`,
+	},
+	stopOrdersRoute: {
+		argsShort:  `(activeOnly)`,
+		cmdSummary: `List stop orders.`,
+		argsLong: `Args:
+    activeOnly (bool): Optional. Only list stop orders that have not been
+      triggered or canceled. Default is false.`,
+		returns: `Returns:
+    array: An array of stop orders, newest first.`,
+	},
+	scheduleOrderRoute: {
+		argsShort: `"host" "type" sell base quote qty rate duration slices visibleQty immediate (options)`,
+		cmdSummary: `Create a TWAP or iceberg order. The order is held by the client and
+    executed as a series of child limit orders. Wallets must be unlocked for
+    the child orders to be placed.`,
+		argsLong: `Args:
+    host (string): The DEX to trade on.
+    type (string): The scheduled order type, "twap" or "iceberg".
+    sell (bool): Whether the order is selling.
+    base (int): The BIP-44 coin index for the market's base asset.
+    quote (int): The BIP-44 coin index for the market's quote asset.
+    qty (int): The total number of units to buy/sell. Must be a multiple of
+      the lot size.
+    rate (int): The rate of every child limit order. Must be a multiple of the
+      market's rate step.
+    duration (int): For a twap order, the time over which the child orders are
+      placed, in milliseconds.
+    slices (int): For a twap order, the number of child orders. Every child
+      order must be in a different epoch.
+    visibleQty (int): For an iceberg order, the maximum quantity of the single
+      booked child order. Must be a multiple of the lot size.
+    immediate (bool): Require immediate match for twap child orders. Do not
+      book the orders. Must be false for iceberg orders.
+    options (string): Optional. A JSON-encoded string->string mapping of
+      additional trade options.`,
+		returns: `Returns:
+    obj: The scheduled order.`,
+	},
+	cancelScheduledOrderRoute: {
+		argsShort:  `"scheduledOrderID"`,
+		cmdSummary: `Cancel an active scheduled order and any of its booked child orders.`,
+		argsLong: `Args:
+    scheduledOrderID (string): The hex ID of the scheduled order to cancel.`,
+		returns: `Returns:
+    string: The message "` + fmt.Sprintf(canceledSchedStr, "[scheduled order ID]") + `"`,
+	},
+	scheduledOrderRoute: {
+		argsShort:  `"scheduledOrderID"`,
+		cmdSummary: `Get the status, aggregate fill and fees of a scheduled order.`,
+		argsLong: `Args:
+    scheduledOrderID (string): The hex ID of the scheduled order.`,
+		returns: `Returns:
+    obj: The scheduled order.`,
+	},
+	scheduledOrdersRoute: {
+		argsShort:  `(activeOnly)`,
+		cmdSummary: `List scheduled orders.`,
+		argsLong: `Args:
+    activeOnly (bool): Optional. Only list scheduled orders that are still
+      active. Default is false.`,
+		returns: `Returns:
+    array: An array of scheduled orders, newest first.`,
+	},
 }
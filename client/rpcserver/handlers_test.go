@@ -762,6 +762,381 @@ func TestHandleCancel(t *testing.T) {
 	}
 }
 
+func TestHandleAmend(t *testing.T) {
+	params := &RawParams{
+		PWArgs: []encode.PassBytes{encode.PassBytes("abc")},
+		Args:   []string{"fb94fe99e4e32200a341f0f1cb33f34a08ac23eedab636e8adb991fa76343e1e", "5", "0"},
+	}
+	tests := []struct {
+		name        string
+		params      *RawParams
+		amendErr    error
+		wantErrCode int
+	}{{
+		name:        "ok",
+		params:      params,
+		wantErrCode: -1,
+	}, {
+		name:        "core.AmendOrder error",
+		params:      params,
+		amendErr:    errors.New("error"),
+		wantErrCode: msgjson.RPCAmendOrderError,
+	}, {
+		name:        "bad params",
+		params:      &RawParams{},
+		wantErrCode: msgjson.RPCArgumentsError,
+	}}
+	for _, test := range tests {
+		tc := &TCore{order: new(core.Order), amendErr: test.amendErr}
+		r := &RPCServer{core: tc}
+		payload := handleAmend(r, test.params)
+		res := new(tradeResponse)
+		if err := verifyResponse(payload, res, test.wantErrCode); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+	}
+}
+
+func TestHandleTaxExport(t *testing.T) {
+	report := &core.TaxReport{
+		Method: core.CostBasisFIFO,
+		Ledger: []*core.TaxLedgerEntry{{Type: core.TaxEntryTrade, AssetID: 42, Symbol: "dcr", Amount: -1e8}},
+	}
+	tests := []struct {
+		name         string
+		params       *RawParams
+		taxExportErr error
+		wantErrCode  int
+		wantCSV      bool
+	}{{
+		name:        "ok json",
+		params:      &RawParams{Args: []string{"0", "0"}},
+		wantErrCode: -1,
+	}, {
+		name:        "ok ledger",
+		params:      &RawParams{Args: []string{"0", "0", "lifo", "ledger"}},
+		wantErrCode: -1,
+		wantCSV:     true,
+	}, {
+		name:        "ok cost basis",
+		params:      &RawParams{Args: []string{"0", "0", "fifo", "costbasis"}},
+		wantErrCode: -1,
+		wantCSV:     true,
+	}, {
+		name:         "core.TaxExport error",
+		params:       &RawParams{Args: []string{"0", "0"}},
+		taxExportErr: errors.New("error"),
+		wantErrCode:  msgjson.RPCTaxExportError,
+	}, {
+		name:        "bad params",
+		params:      &RawParams{},
+		wantErrCode: msgjson.RPCArgumentsError,
+	}}
+	for _, test := range tests {
+		tc := &TCore{taxReport: report, taxExportErr: test.taxExportErr}
+		r := &RPCServer{core: tc}
+		payload := handleTaxExport(r, test.params)
+		var res any = new(core.TaxReport)
+		if test.wantCSV {
+			res = new(string)
+		}
+		if err := verifyResponse(payload, res, test.wantErrCode); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+		if test.wantCSV && !strings.Contains(*res.(*string), "\n") {
+			t.Fatalf("%s: expected CSV rows, got %q", test.name, *res.(*string))
+		}
+	}
+}
+
+func TestHandleAddressBook(t *testing.T) {
+	pwParams := func(args ...string) *RawParams {
+		return &RawParams{PWArgs: []encode.PassBytes{encode.PassBytes("abc")}, Args: args}
+	}
+	tests := []struct {
+		name           string
+		handler        func(*RPCServer, *RawParams) *msgjson.ResponsePayload
+		params         *RawParams
+		res            any
+		addressBookErr error
+		wantErrCode    int
+	}{{
+		name:        "addressbook ok",
+		handler:     handleAddressBook,
+		params:      pwParams(),
+		res:         new([]*core.AddressBookEntry),
+		wantErrCode: -1,
+	}, {
+		name:           "addressbook core error",
+		handler:        handleAddressBook,
+		params:         pwParams(),
+		addressBookErr: errors.New("error"),
+		wantErrCode:    msgjson.RPCAddressBookError,
+	}, {
+		name:        "addressbook bad params",
+		handler:     handleAddressBook,
+		params:      &RawParams{},
+		wantErrCode: msgjson.RPCArgumentsError,
+	}, {
+		name:        "addaddress ok",
+		handler:     handleAddAddress,
+		params:      pwParams("42", "addr", "label"),
+		res:         new(core.AddressBookEntry),
+		wantErrCode: -1,
+	}, {
+		name:           "addaddress core error",
+		handler:        handleAddAddress,
+		params:         pwParams("42", "addr"),
+		addressBookErr: errors.New("error"),
+		wantErrCode:    msgjson.RPCAddressBookError,
+	}, {
+		name:        "addaddress bad params",
+		handler:     handleAddAddress,
+		params:      pwParams("42"),
+		wantErrCode: msgjson.RPCArgumentsError,
+	}, {
+		name:        "removeaddress ok",
+		handler:     handleRemoveAddress,
+		params:      pwParams("0102030405060708"),
+		res:         new(string),
+		wantErrCode: -1,
+	}, {
+		name:           "removeaddress core error",
+		handler:        handleRemoveAddress,
+		params:         pwParams("0102030405060708"),
+		addressBookErr: errors.New("error"),
+		wantErrCode:    msgjson.RPCAddressBookError,
+	}, {
+		name:        "allowlist ok",
+		handler:     handleAllowlist,
+		params:      &RawParams{},
+		res:         new(core.AllowlistStatus),
+		wantErrCode: -1,
+	}, {
+		name:           "allowlist core error",
+		handler:        handleAllowlist,
+		params:         &RawParams{},
+		addressBookErr: errors.New("error"),
+		wantErrCode:    msgjson.RPCAddressBookError,
+	}, {
+		name:        "setallowlist ok",
+		handler:     handleSetAllowlist,
+		params:      pwParams("true"),
+		res:         new(core.AllowlistStatus),
+		wantErrCode: -1,
+	}, {
+		name:        "setallowlist bad params",
+		handler:     handleSetAllowlist,
+		params:      pwParams("maybe"),
+		wantErrCode: msgjson.RPCArgumentsError,
+	}}
+	for _, test := range tests {
+		tc := &TCore{
+			addressBook:    []*core.AddressBookEntry{{AssetID: 42, Address: "addr"}},
+			allowlist:      &core.AllowlistStatus{Enabled: true},
+			addressBookErr: test.addressBookErr,
+		}
+		r := &RPCServer{core: tc}
+		payload := test.handler(r, test.params)
+		res := test.res
+		if res == nil {
+			res = new(any)
+		}
+		if err := verifyResponse(payload, res, test.wantErrCode); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+	}
+}
+
+func TestHandleAPIKeys(t *testing.T) {
+	tc := &TCore{}
+	s := &RPCServer{core: tc, apiKeys: make(map[[32]byte]*apiKey)}
+
+	payload := handleCreateAPIKey(s, &RawParams{
+		PWArgs: []encode.PassBytes{encode.PassBytes("abc")},
+		Args:   []string{"monitor", "read,mm", "60"},
+	})
+	res := new(CreateAPIKeyResponse)
+	if err := verifyResponse(payload, res, -1); err != nil {
+		t.Fatal(err)
+	}
+	if res.Name != "monitor" || res.Secret == "" || res.RateLimit != 60 {
+		t.Fatalf("wrong createapikey result: %+v", res)
+	}
+	key := s.apiKey("monitor", res.Secret)
+	if key == nil {
+		t.Fatalf("created API key not loaded")
+	}
+	if !key.allows(mmStatusRoute) || !key.allows(startBotRoute) || key.allows(tradeRoute) || key.allows(appSeedRoute) {
+		t.Fatalf("wrong API key permissions")
+	}
+	if s.apiKey("trader", res.Secret) != nil {
+		t.Fatalf("API key found with the wrong name")
+	}
+
+	if err := verifyResponse(handleAPIKeys(s, &RawParams{}), new([]*core.APIKey), -1); err != nil {
+		t.Fatal(err)
+	}
+
+	// The app password is required.
+	payload = handleRevokeAPIKey(s, &RawParams{Args: []string{"monitor"}})
+	if err := verifyResponse(payload, new(any), msgjson.RPCArgumentsError); err != nil {
+		t.Fatal(err)
+	}
+	payload = handleRevokeAPIKey(s, &RawParams{
+		PWArgs: []encode.PassBytes{encode.PassBytes("abc")},
+		Args:   []string{"monitor"},
+	})
+	if err := verifyResponse(payload, new(string), -1); err != nil {
+		t.Fatal(err)
+	}
+	if s.apiKey("monitor", res.Secret) != nil {
+		t.Fatalf("revoked API key still loaded")
+	}
+
+	tc.apiKeyErr = errors.New("error")
+	payload = handleCreateAPIKey(s, &RawParams{
+		PWArgs: []encode.PassBytes{encode.PassBytes("abc")},
+		Args:   []string{"monitor", "read"},
+	})
+	if err := verifyResponse(payload, new(any), msgjson.RPCAPIKeyError); err != nil {
+		t.Fatal(err)
+	}
+	payload = handleRevokeAPIKey(s, &RawParams{
+		PWArgs: []encode.PassBytes{encode.PassBytes("abc")},
+		Args:   []string{"monitor"},
+	})
+	if err := verifyResponse(payload, new(any), msgjson.RPCAPIKeyError); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyResponse(handleAPIKeys(s, &RawParams{}), new(any), msgjson.RPCAPIKeyError); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestHandleStopOrder(t *testing.T) {
+	params := &RawParams{
+		Args: []string{
+			"1.2.3.4:3000", // 0. DEX
+			"stoplimit",    // 1. Type
+			"true",         // 2. Sell
+			"42",           // 3. Base
+			"0",            // 4. Quote
+			"1",            // 5. Qty
+			"midgap",       // 6. PriceSource
+			"2",            // 7. TriggerRate
+			"1",            // 8. Rate
+			"0",            // 9. TrailDelta
+			"0",            // 10. LimitOffset
+			"false",        // 11. TifNow
+		}}
+	tests := []struct {
+		name         string
+		params       *RawParams
+		stopOrderErr error
+		wantErrCode  int
+	}{{
+		name:        "ok",
+		params:      params,
+		wantErrCode: -1,
+	}, {
+		name:         "core.PlaceStopOrder error",
+		params:       params,
+		stopOrderErr: errors.New("error"),
+		wantErrCode:  msgjson.RPCStopOrderError,
+	}, {
+		name:        "bad params",
+		params:      &RawParams{},
+		wantErrCode: msgjson.RPCArgumentsError,
+	}}
+	for _, test := range tests {
+		tc := &TCore{stopOrder: new(core.StopOrder), stopOrderErr: test.stopOrderErr}
+		r := &RPCServer{core: tc}
+		payload := handleStopOrder(r, test.params)
+		res := new(core.StopOrder)
+		if err := verifyResponse(payload, res, test.wantErrCode); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+	}
+}
+
+func TestHandleCancelStopOrder(t *testing.T) {
+	params := &RawParams{Args: []string{"0102030405060708"}}
+	tests := []struct {
+		name         string
+		params       *RawParams
+		stopOrderErr error
+		wantErrCode  int
+	}{{
+		name:        "ok",
+		params:      params,
+		wantErrCode: -1,
+	}, {
+		name:         "core.CancelStopOrder error",
+		params:       params,
+		stopOrderErr: errors.New("error"),
+		wantErrCode:  msgjson.RPCStopOrderError,
+	}, {
+		name:        "bad id",
+		params:      &RawParams{Args: []string{"zz"}},
+		wantErrCode: msgjson.RPCArgumentsError,
+	}}
+	for _, test := range tests {
+		tc := &TCore{stopOrderErr: test.stopOrderErr}
+		r := &RPCServer{core: tc}
+		payload := handleCancelStopOrder(r, test.params)
+		res := ""
+		if err := verifyResponse(payload, &res, test.wantErrCode); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+	}
+}
+
+func TestHandleScheduleOrder(t *testing.T) {
+	params := &RawParams{
+		Args: []string{
+			"1.2.3.4:3000", // 0. DEX
+			"twap",         // 1. Type
+			"true",         // 2. Sell
+			"42",           // 3. Base
+			"0",            // 4. Quote
+			"10",           // 5. Qty
+			"1",            // 6. Rate
+			"60000",        // 7. Duration
+			"5",            // 8. Slices
+			"0",            // 9. VisibleQty
+			"false",        // 10. TifNow
+		}}
+	tests := []struct {
+		name              string
+		params            *RawParams
+		scheduledOrderErr error
+		wantErrCode       int
+	}{{
+		name:        "ok",
+		params:      params,
+		wantErrCode: -1,
+	}, {
+		name:              "core.CreateScheduledOrder error",
+		params:            params,
+		scheduledOrderErr: errors.New("error"),
+		wantErrCode:       msgjson.RPCScheduledOrderError,
+	}, {
+		name:        "bad params",
+		params:      &RawParams{},
+		wantErrCode: msgjson.RPCArgumentsError,
+	}}
+	for _, test := range tests {
+		tc := &TCore{scheduledOrder: new(core.ScheduledOrder), scheduledOrderErr: test.scheduledOrderErr}
+		r := &RPCServer{core: tc}
+		payload := handleScheduleOrder(r, test.params)
+		res := new(core.ScheduledOrder)
+		if err := verifyResponse(payload, res, test.wantErrCode); err != nil {
+			t.Fatalf("%s: %v", test.name, err)
+		}
+	}
+}
+
 // tCoin satisfies the asset.Coin interface.
 type tCoin struct{}
 
@@ -41,6 +41,10 @@ const (
 	rpcSemverMinor uint32 = 4
 	rpcSemverPatch uint32 = 0
 
+	// notifyRoute is the route of the core notifications sent to websocket
+	// clients.
+	notifyRoute = "notify"
+
 	// rpcTimeoutSeconds is the number of seconds a connection to the RPC server
 	// is allowed to stay open without authenticating before it is closed.
 	rpcTimeoutSeconds = 10
@@ -60,6 +64,8 @@ type clientCore interface {
 	AssetBalance(assetID uint32) (*core.WalletBalance, error)
 	Book(host string, base, quote uint32) (orderBook *core.OrderBook, err error)
 	Cancel(orderID dex.Bytes) error
+	AmendOrder(appPass []byte, form *core.AmendForm) (*core.Order, error)
+	TaxExport(form *core.TaxExportForm) (*core.TaxReport, error)
 	CloseWallet(assetID uint32) error
 	CreateWallet(appPass, walletPass []byte, form *core.WalletForm) error
 	DiscoverAccount(dexAddr string, pass []byte, certI any) (*core.Exchange, bool, error)
@@ -73,10 +79,26 @@ type clientCore interface {
 	PostBond(form *core.PostBondForm) (*core.PostBondResult, error)
 	UpdateBondOptions(form *core.BondOptionsForm) error
 	Trade(appPass []byte, form *core.TradeForm) (order *core.Order, err error)
+	PlaceStopOrder(form *core.StopOrderForm) (*core.StopOrder, error)
+	CancelStopOrder(id dex.Bytes) error
+	StopOrders(activeOnly bool) ([]*core.StopOrder, error)
+	CreateScheduledOrder(form *core.ScheduledOrderForm) (*core.ScheduledOrder, error)
+	CancelScheduledOrder(id dex.Bytes) error
+	ScheduledOrder(id dex.Bytes) (*core.ScheduledOrder, error)
+	ScheduledOrders(activeOnly bool) ([]*core.ScheduledOrder, error)
 	Wallets() (walletsStates []*core.WalletState)
 	WalletState(assetID uint32) *core.WalletState
 	RescanWallet(assetID uint32, force bool) error
-	Send(appPass []byte, assetID uint32, value uint64, addr string, subtract bool) (asset.Coin, error)
+	NotificationFeed() *core.NoteFeed
+	AllowlistedSend(appPass []byte, assetID uint32, value uint64, addr string, subtract bool) (asset.Coin, error)
+	AddressBook(appPass []byte) ([]*core.AddressBookEntry, error)
+	AddAddress(appPass []byte, assetID uint32, address, label string) (*core.AddressBookEntry, error)
+	RemoveAddress(appPass []byte, id dex.Bytes) error
+	Allowlist() (*core.AllowlistStatus, error)
+	SetAllowlist(appPass []byte, enable bool) (*core.AllowlistStatus, error)
+	APIKeys() ([]*core.APIKey, error)
+	CreateAPIKey(appPass []byte, name string, scopes []string, rateLimit uint32) (string, *core.APIKey, error)
+	RevokeAPIKey(appPass []byte, name string) error
 	ExportSeed(pw []byte) (string, error)
 	DeleteArchivedRecords(olderThan *time.Time, matchesFileStr, ordersFileStr string) (int, error)
 	WalletPeers(assetID uint32) ([]*asset.WalletPeer, error)
@@ -100,11 +122,13 @@ type clientCore interface {
 	SetVSP(assetID uint32, addr string) error
 	PurchaseTickets(assetID uint32, pw []byte, n int) error
 	SetVotingPreferences(assetID uint32, choices, tSpendPolicy, treasuryPolicy map[string]string) error
-	GenerateBCHRecoveryTransaction(appPW []byte, recipient string) ([]byte, error)
+	AllowlistedBCHRecoveryTransaction(appPW []byte, recipient string) ([]byte, error)
 }
 
 // RPCServer is a single-client http and websocket server enabling a JSON
-// interface to Bison Wallet.
+// interface to Bison Wallet. Requests are authenticated with either the RPC
+// user and password, which grant every route, or an API key, which grants
+// only the routes in its permission scopes.
 type RPCServer struct {
 	core      clientCore
 	mm        *mm.MarketMaker
@@ -114,6 +138,8 @@ type RPCServer struct {
 	tlsConfig *tls.Config
 	srv       *http.Server
 	authSHA   [32]byte
+	apiKeyMtx sync.RWMutex
+	apiKeys   map[[32]byte]*apiKey // keyed by secret hash
 	wg        sync.WaitGroup
 	bwVersion *SemVersion
 	ctx       context.Context
@@ -192,6 +218,11 @@ func (s *RPCServer) handleJSON(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Responses not accepted", http.StatusMethodNotAllowed)
 		return
 	}
+	if key := requestAPIKey(r); key != nil && !key.allows(req.Route) {
+		log.Warnf("API key %q denied access to route %q", key.Name, req.Route)
+		s.writeResponse(w, req, &msgjson.ResponsePayload{Error: permissionError(key, req.Route)})
+		return
+	}
 	s.parseHTTPRequest(w, req)
 }
 
@@ -265,6 +296,10 @@ func New(cfg *Config) (*RPCServer, error) {
 		base64.StdEncoding.EncodeToString([]byte(login))
 	s.authSHA = sha256.Sum256([]byte(auth))
 
+	if err := s.loadAPIKeys(); err != nil {
+		return nil, fmt.Errorf("error loading API keys: %w", err)
+	}
+
 	// Middleware
 	mux.Use(middleware.Recoverer)
 	mux.Use(middleware.RealIP)
@@ -304,9 +339,19 @@ func (s *RPCServer) Connect(ctx context.Context) (*sync.WaitGroup, error) {
 
 	// Configure the websocket handler before starting the server.
 	s.mux.Get("/ws", func(w http.ResponseWriter, r *http.Request) {
+		if key := requestAPIKey(r); key != nil && !key.scopes[scopeRead] {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
 		s.wsServer.HandleConnect(ctx, w, r)
 	})
 
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.readNotifications(ctx)
+	}()
+
 	s.wg.Add(1)
 	go func() {
 		defer s.wg.Done()
@@ -322,6 +367,22 @@ func (s *RPCServer) Connect(ctx context.Context) (*sync.WaitGroup, error) {
 	return &s.wg, nil
 }
 
+// readNotifications reads from the Core notification channel and relays to
+// websocket clients.
+func (s *RPCServer) readNotifications(ctx context.Context) {
+	ch := s.core.NotificationFeed()
+	defer ch.ReturnFeed()
+
+	for {
+		select {
+		case n := <-ch.C:
+			s.wsServer.Notify(notifyRoute, n)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
 // handleRequest sends the request to the correct handler function if able.
 func (s *RPCServer) handleRequest(req *msgjson.Message) *msgjson.ResponsePayload {
 	payload := new(msgjson.ResponsePayload)
@@ -353,12 +414,17 @@ func (s *RPCServer) handleRequest(req *msgjson.Message) *msgjson.ResponsePayload
 // parseHTTPRequest parses the msgjson message in the request body, creates a
 // response message, and writes it to the http.ResponseWriter.
 func (s *RPCServer) parseHTTPRequest(w http.ResponseWriter, req *msgjson.Message) {
-	payload := s.handleRequest(req)
+	s.writeResponse(w, req, s.handleRequest(req))
+}
+
+// writeResponse creates a response message for the request with the payload
+// and writes it to the http.ResponseWriter.
+func (s *RPCServer) writeResponse(w http.ResponseWriter, req *msgjson.Message, payload *msgjson.ResponsePayload) {
 	resp, err := msgjson.NewResponse(req.ID, payload.Result, payload.Error)
 	if err != nil {
 		msg := fmt.Sprintf("error encoding response: %v", err)
 		http.Error(w, msg, http.StatusInternalServerError)
-		log.Errorf("parseHTTPRequest: NewResponse failed: %s", msg)
+		log.Errorf("writeResponse: NewResponse failed: %s", msg)
 		return
 	}
 	writeJSON(w, resp)
@@ -378,11 +444,28 @@ func (s *RPCServer) authMiddleware(next http.Handler) http.Handler {
 			return
 		}
 		authSHA := sha256.Sum256([]byte(auth[0]))
-		if subtle.ConstantTimeCompare(s.authSHA[:], authSHA[:]) != 1 {
+		if subtle.ConstantTimeCompare(s.authSHA[:], authSHA[:]) == 1 {
+			log.Debugf("authenticated user with ip: %s", r.RemoteAddr)
+			next.ServeHTTP(w, r)
+			return
+		}
+		// Not the RPC user. Try the API keys.
+		name, secret, ok := r.BasicAuth()
+		if !ok {
+			fail()
+			return
+		}
+		key := s.apiKey(name, secret)
+		if key == nil {
 			fail()
 			return
 		}
-		log.Debugf("authenticated user with ip: %s", r.RemoteAddr)
-		next.ServeHTTP(w, r)
+		if key.limiter != nil && !key.limiter.Allow() {
+			log.Warnf("API key %q rate limited, ip: %s", key.Name, r.RemoteAddr)
+			http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+			return
+		}
+		log.Debugf("authenticated API key %q with ip: %s", key.Name, r.RemoteAddr)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), ctxAPIKey, key)))
 	})
 }
@@ -56,6 +56,14 @@ type TCore struct {
 	order                    *core.Order
 	tradeErr                 error
 	cancelErr                error
+	amendErr                 error
+	taxReport                *core.TaxReport
+	taxExportErr             error
+	addressBook              []*core.AddressBookEntry
+	allowlist                *core.AllowlistStatus
+	addressBookErr           error
+	apiKeys                  []*core.APIKey
+	apiKeyErr                error
 	coin                     asset.Coin
 	sendErr                  error
 	logoutErr                error
@@ -71,6 +79,10 @@ type TCore struct {
 	stakeStatus              *asset.TicketStakingStatus
 	stakeStatusErr           error
 	setVotingPrefErr         error
+	stopOrder                *core.StopOrder
+	stopOrderErr             error
+	scheduledOrder           *core.ScheduledOrder
+	scheduledOrderErr        error
 }
 
 func (c *TCore) Balance(uint32) (uint64, error) {
@@ -80,12 +92,23 @@ func (c *TCore) Book(dex string, base, quote uint32) (*core.OrderBook, error) {
 	return c.book, c.bookErr
 }
 func (c *TCore) AckNotes(ids []dex.Bytes) {}
+func (c *TCore) NotificationFeed() *core.NoteFeed {
+	return &core.NoteFeed{
+		C: make(chan core.Notification, 1),
+	}
+}
 func (c *TCore) AssetBalance(uint32) (*core.WalletBalance, error) {
 	return nil, c.balanceErr
 }
 func (c *TCore) Cancel(oid dex.Bytes) error {
 	return c.cancelErr
 }
+func (c *TCore) AmendOrder(appPass []byte, form *core.AmendForm) (*core.Order, error) {
+	return c.order, c.amendErr
+}
+func (c *TCore) TaxExport(form *core.TaxExportForm) (*core.TaxReport, error) {
+	return c.taxReport, c.taxExportErr
+}
 func (c *TCore) CreateWallet(appPW, walletPW []byte, form *core.WalletForm) error {
 	c.newWalletForm = form
 	return c.createWalletErr
@@ -144,15 +167,79 @@ func (c *TCore) SyncBook(dex string, base, quote uint32) (*orderbook.OrderBook,
 func (c *TCore) Trade(appPass []byte, form *core.TradeForm) (order *core.Order, err error) {
 	return c.order, c.tradeErr
 }
+func (c *TCore) PlaceStopOrder(form *core.StopOrderForm) (*core.StopOrder, error) {
+	return c.stopOrder, c.stopOrderErr
+}
+func (c *TCore) CancelStopOrder(id dex.Bytes) error {
+	return c.stopOrderErr
+}
+func (c *TCore) StopOrders(activeOnly bool) ([]*core.StopOrder, error) {
+	if c.stopOrder == nil {
+		return nil, c.stopOrderErr
+	}
+	return []*core.StopOrder{c.stopOrder}, c.stopOrderErr
+}
+func (c *TCore) CreateScheduledOrder(form *core.ScheduledOrderForm) (*core.ScheduledOrder, error) {
+	return c.scheduledOrder, c.scheduledOrderErr
+}
+func (c *TCore) CancelScheduledOrder(id dex.Bytes) error {
+	return c.scheduledOrderErr
+}
+func (c *TCore) ScheduledOrder(id dex.Bytes) (*core.ScheduledOrder, error) {
+	return c.scheduledOrder, c.scheduledOrderErr
+}
+func (c *TCore) ScheduledOrders(activeOnly bool) ([]*core.ScheduledOrder, error) {
+	if c.scheduledOrder == nil {
+		return nil, c.scheduledOrderErr
+	}
+	return []*core.ScheduledOrder{c.scheduledOrder}, c.scheduledOrderErr
+}
 func (c *TCore) Wallets() []*core.WalletState {
 	return c.wallets
 }
 func (c *TCore) WalletState(assetID uint32) *core.WalletState {
 	return c.walletState
 }
-func (c *TCore) Send(pw []byte, assetID uint32, value uint64, addr string, subtract bool) (asset.Coin, error) {
+func (c *TCore) AllowlistedSend(pw []byte, assetID uint32, value uint64, addr string, subtract bool) (asset.Coin, error) {
 	return c.coin, c.sendErr
 }
+func (c *TCore) AddressBook(appPass []byte) ([]*core.AddressBookEntry, error) {
+	return c.addressBook, c.addressBookErr
+}
+func (c *TCore) AddAddress(appPass []byte, assetID uint32, address, label string) (*core.AddressBookEntry, error) {
+	if c.addressBookErr != nil {
+		return nil, c.addressBookErr
+	}
+	return &core.AddressBookEntry{AssetID: assetID, Address: address, Label: label}, nil
+}
+func (c *TCore) RemoveAddress(appPass []byte, id dex.Bytes) error {
+	return c.addressBookErr
+}
+func (c *TCore) Allowlist() (*core.AllowlistStatus, error) {
+	return c.allowlist, c.addressBookErr
+}
+func (c *TCore) SetAllowlist(appPass []byte, enable bool) (*core.AllowlistStatus, error) {
+	if c.addressBookErr != nil {
+		return nil, c.addressBookErr
+	}
+	return &core.AllowlistStatus{Enabled: enable}, nil
+}
+func (c *TCore) APIKeys() ([]*core.APIKey, error) {
+	return c.apiKeys, c.apiKeyErr
+}
+func (c *TCore) CreateAPIKey(appPass []byte, name string, scopes []string, rateLimit uint32) (string, *core.APIKey, error) {
+	if c.apiKeyErr != nil {
+		return "", nil, c.apiKeyErr
+	}
+	secret := name + "secret"
+	hash := sha256.Sum256([]byte(secret))
+	key := &core.APIKey{Name: name, Hash: hash[:], Scopes: scopes, RateLimit: rateLimit}
+	c.apiKeys = append(c.apiKeys, key)
+	return secret, key, nil
+}
+func (c *TCore) RevokeAPIKey(appPass []byte, name string) error {
+	return c.apiKeyErr
+}
 func (c *TCore) ExportSeed(pw []byte) (string, error) {
 	return c.exportSeed, c.exportSeedErr
 }
@@ -198,7 +285,7 @@ func (c *TCore) TxHistory(assetID uint32, n int, refID *string, past bool) ([]*a
 func (c *TCore) WalletTransaction(assetID uint32, txID string) (*asset.WalletTransaction, error) {
 	return nil, nil
 }
-func (c *TCore) GenerateBCHRecoveryTransaction(appPW []byte, recipient string) ([]byte, error) {
+func (c *TCore) AllowlistedBCHRecoveryTransaction(appPW []byte, recipient string) ([]byte, error) {
 	return nil, nil
 }
 func (c *TCore) BridgeContractApprovalStatus(assetID uint32, bridgeName string) (asset.ApprovalStatus, error) {
@@ -547,3 +634,62 @@ func TestAuthMiddleware(t *testing.T) {
 		wantAuthError(test.name, test.wantErr)
 	}
 }
+
+func TestAPIKeyAuth(t *testing.T) {
+	s, shutdown := newTServer(t, false, "user", "pass")
+	defer shutdown()
+	tc := s.core.(*TCore)
+	secret, key, _ := tc.CreateAPIKey(nil, "monitor", []string{scopeRead}, 2)
+	s.addAPIKey(key)
+
+	h := s.authMiddleware(http.HandlerFunc(s.handleJSON))
+	request := func(user, pass, route string) (*tResponseWriter, *msgjson.ResponsePayload) {
+		t.Helper()
+		msg, _ := msgjson.NewRequest(1, route, nil)
+		b, _ := json.Marshal(msg)
+		r, _ := http.NewRequest("POST", "", bytes.NewBuffer(b))
+		r.SetBasicAuth(user, pass)
+		w := &tResponseWriter{}
+		h.ServeHTTP(w, r)
+		if w.code != http.StatusOK {
+			return w, nil
+		}
+		resp := new(msgjson.Message)
+		if err := json.Unmarshal(w.b, resp); err != nil {
+			t.Fatalf("unable to unmarshal response: %v", err)
+		}
+		payload := new(msgjson.ResponsePayload)
+		if err := json.Unmarshal(resp.Payload, payload); err != nil {
+			t.Fatalf("unable to unmarshal payload: %v", err)
+		}
+		return w, payload
+	}
+
+	// The RPC user can use any route.
+	if _, payload := request("user", "pass", appSeedRoute); payload == nil || (payload.Error != nil && payload.Error.Code == msgjson.RPCPermissionError) {
+		t.Fatalf("RPC user denied")
+	}
+
+	// Wrong secret.
+	if w, _ := request("monitor", "pass", versionRoute); w.code != http.StatusUnauthorized {
+		t.Fatalf("expected unauthorized for wrong secret, got %d", w.code)
+	}
+
+	_, payload := request("monitor", secret, versionRoute)
+	if payload == nil || payload.Error != nil {
+		t.Fatalf("API key denied a read route")
+	}
+	_, payload = request("monitor", secret, sendRoute)
+	if payload == nil || payload.Error == nil || payload.Error.Code != msgjson.RPCPermissionError {
+		t.Fatalf("API key not denied a withdraw route")
+	}
+
+	// The rate limit allows a burst of 2.
+	if w, _ := request("monitor", secret, versionRoute); w.code != http.StatusTooManyRequests {
+		t.Fatalf("expected rate limit, got %d", w.code)
+	}
+	// The RPC user is not rate limited.
+	if w, _ := request("user", "pass", versionRoute); w.code != http.StatusOK {
+		t.Fatalf("RPC user rate limited")
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"math"
 	"strconv"
+	"strings"
 	"time"
 
 	"decred.org/dcrdex/client/core"
@@ -40,6 +41,13 @@ type VersionResponse struct {
 	BWVersion    *SemVersion `json:"dexcVersion"`
 }
 
+// CreateAPIKeyResponse is the result of creating an API key. The secret is
+// not stored and cannot be retrieved again.
+type CreateAPIKeyResponse struct {
+	*core.APIKey
+	Secret string `json:"secret"`
+}
+
 // SemVersion holds a semver version JSON object.
 type SemVersion struct {
 	VersionString string `json:"versionString"`
@@ -161,6 +169,63 @@ type cancelForm struct {
 	orderID dex.Bytes
 }
 
+// amendForm combines the application password and the user's amend details.
+type amendForm struct {
+	appPass encode.PassBytes
+	srvForm *core.AmendForm
+}
+
+// Formats of the taxexport route.
+const (
+	taxExportJSONFormat      = "json"
+	taxExportLedgerFormat    = "ledger"
+	taxExportCostBasisFormat = "costbasis"
+)
+
+// taxExportForm is information necessary to export the history for tax
+// reporting.
+type taxExportForm struct {
+	srvForm *core.TaxExportForm
+	format  string
+}
+
+// addAddressForm is information necessary to add an address to the address
+// book.
+type addAddressForm struct {
+	appPass encode.PassBytes
+	assetID uint32
+	address string
+	label   string
+}
+
+// removeAddressForm is information necessary to remove an address from the
+// address book.
+type removeAddressForm struct {
+	appPass encode.PassBytes
+	id      dex.Bytes
+}
+
+// setAllowlistForm is information necessary to enable or disable the
+// withdrawal allowlist.
+type setAllowlistForm struct {
+	appPass encode.PassBytes
+	enable  bool
+}
+
+// createAPIKeyForm is information necessary to create an API key.
+type createAPIKeyForm struct {
+	appPass   encode.PassBytes
+	name      string
+	scopes    []string
+	rateLimit uint32
+}
+
+// revokeAPIKeyForm is information necessary to revoke an API key.
+type revokeAPIKeyForm struct {
+	appPass encode.PassBytes
+	name    string
+}
+
 // sendOrWithdrawForm is information necessary to send or withdraw funds.
 type sendOrWithdrawForm struct {
 	appPass encode.PassBytes
@@ -202,6 +267,13 @@ type mmAvailableBalancesForm struct {
 	cexName    *string
 }
 
+type mmAnalyticsForm struct {
+	mkt       *mm.MarketWithHost
+	startTime int64
+	window    time.Duration
+	csv       bool
+}
+
 type startBotForm struct {
 	appPass     encode.PassBytes
 	cfgFilePath string
@@ -686,6 +758,152 @@ func parseCancelArgs(params *RawParams) (*cancelForm, error) {
 	return &cancelForm{orderID: oidB}, nil
 }
 
+func parseAmendArgs(params *RawParams) (*amendForm, error) {
+	if err := checkNArgs(params, []int{1}, []int{3}); err != nil {
+		return nil, err
+	}
+	id := params.Args[0]
+	if len(id) != orderIdLen {
+		return nil, fmt.Errorf("%w: orderID has incorrect length", errArgs)
+	}
+	oidB, err := hex.DecodeString(id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid order id hex", errArgs)
+	}
+	rate, err := checkUIntArg(params.Args[1], "rate", 64)
+	if err != nil {
+		return nil, err
+	}
+	qty, err := checkUIntArg(params.Args[2], "qty", 64)
+	if err != nil {
+		return nil, err
+	}
+	return &amendForm{
+		appPass: params.PWArgs[0],
+		srvForm: &core.AmendForm{
+			OrderID: oidB,
+			Rate:    rate,
+			Qty:     qty,
+		},
+	}, nil
+}
+
+func parseTaxExportArgs(params *RawParams) (*taxExportForm, error) {
+	if err := checkNArgs(params, []int{0}, []int{2, 4}); err != nil {
+		return nil, err
+	}
+	start, err := checkUIntArg(params.Args[0], "start", 64)
+	if err != nil {
+		return nil, err
+	}
+	end, err := checkUIntArg(params.Args[1], "end", 64)
+	if err != nil {
+		return nil, err
+	}
+	form := &taxExportForm{
+		srvForm: &core.TaxExportForm{
+			Start:  start,
+			End:    end,
+			Method: core.CostBasisFIFO,
+		},
+		format: taxExportJSONFormat,
+	}
+	if len(params.Args) > 2 {
+		switch params.Args[2] {
+		case core.CostBasisFIFO, core.CostBasisLIFO:
+			form.srvForm.Method = params.Args[2]
+		default:
+			return nil, fmt.Errorf("%w: unknown cost basis method %q", errArgs, params.Args[2])
+		}
+	}
+	if len(params.Args) > 3 {
+		switch params.Args[3] {
+		case taxExportJSONFormat, taxExportLedgerFormat, taxExportCostBasisFormat:
+			form.format = params.Args[3]
+		default:
+			return nil, fmt.Errorf("%w: unknown format %q", errArgs, params.Args[3])
+		}
+	}
+	return form, nil
+}
+
+func parseAddressBookArgs(params *RawParams) (encode.PassBytes, error) {
+	if err := checkNArgs(params, []int{1}, []int{0}); err != nil {
+		return nil, err
+	}
+	return params.PWArgs[0], nil
+}
+
+func parseAddAddressArgs(params *RawParams) (*addAddressForm, error) {
+	if err := checkNArgs(params, []int{1}, []int{2, 3}); err != nil {
+		return nil, err
+	}
+	assetID, err := checkUIntArg(params.Args[0], "assetID", 32)
+	if err != nil {
+		return nil, err
+	}
+	form := &addAddressForm{
+		appPass: params.PWArgs[0],
+		assetID: uint32(assetID),
+		address: params.Args[1],
+	}
+	if len(params.Args) > 2 {
+		form.label = params.Args[2]
+	}
+	return form, nil
+}
+
+func parseRemoveAddressArgs(params *RawParams) (*removeAddressForm, error) {
+	if err := checkNArgs(params, []int{1}, []int{1}); err != nil {
+		return nil, err
+	}
+	id, err := hex.DecodeString(params.Args[0])
+	if err != nil || len(id) == 0 {
+		return nil, fmt.Errorf("%w: invalid address book entry id", errArgs)
+	}
+	return &removeAddressForm{appPass: params.PWArgs[0], id: id}, nil
+}
+
+func parseSetAllowlistArgs(params *RawParams) (*setAllowlistForm, error) {
+	if err := checkNArgs(params, []int{1}, []int{1}); err != nil {
+		return nil, err
+	}
+	enable, err := checkBoolArg(params.Args[0], "enable")
+	if err != nil {
+		return nil, err
+	}
+	return &setAllowlistForm{appPass: params.PWArgs[0], enable: enable}, nil
+}
+
+func parseCreateAPIKeyArgs(params *RawParams) (*createAPIKeyForm, error) {
+	if err := checkNArgs(params, []int{1}, []int{2, 3}); err != nil {
+		return nil, err
+	}
+	form := &createAPIKeyForm{
+		appPass: params.PWArgs[0],
+		name:    params.Args[0],
+		scopes:  strings.Split(params.Args[1], ","),
+	}
+	if err := validateScopes(form.scopes); err != nil {
+		return nil, err
+	}
+	if len(params.Args) > 2 {
+		rateLimit, err := checkUIntArg(params.Args[2], "rateLimit", 32)
+		if err != nil {
+			return nil, err
+		}
+		form.rateLimit = uint32(rateLimit)
+	}
+	return form, nil
+}
+
+func parseRevokeAPIKeyArgs(params *RawParams) (*revokeAPIKeyForm, error) {
+	if err := checkNArgs(params, []int{1}, []int{1}); err != nil {
+		return nil, err
+	}
+	return &revokeAPIKeyForm{appPass: params.PWArgs[0], name: params.Args[0]}, nil
+}
+
 func parseSendOrWithdrawArgs(params *RawParams) (*sendOrWithdrawForm, error) {
 	if err := checkNArgs(params, []int{1}, []int{3}); err != nil {
 		return nil, err
@@ -967,6 +1185,66 @@ func parseStopBotArgs(params *RawParams) (*mm.MarketWithHost, error) {
 	return parseMktWithHost(params.Args[0], params.Args[1], params.Args[2])
 }
 
+func parseSetMMRiskConfigArgs(params *RawParams) (*mm.RiskConfig, error) {
+	if err := checkNArgs(params, []int{0}, []int{2, 3}); err != nil {
+		return nil, err
+	}
+	maxBotDrawdown, err := strconv.ParseFloat(params.Args[0], 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid maxBotDrawdown: %v", errArgs, err)
+	}
+	maxDrawdown, err := strconv.ParseFloat(params.Args[1], 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid maxDrawdown: %v", errArgs, err)
+	}
+	cfg := &mm.RiskConfig{
+		MaxBotDrawdown: maxBotDrawdown,
+		MaxDrawdown:    maxDrawdown,
+	}
+	if len(params.Args) > 2 {
+		cfg.ExposureCaps, err = parseBotBalances(params.Args[2])
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid exposureCaps: %v", errArgs, err)
+		}
+	}
+	return cfg, nil
+}
+
+func parseMMAnalyticsArgs(params *RawParams) (*mmAnalyticsForm, error) {
+	if err := checkNArgs(params, []int{0}, []int{4, 6}); err != nil {
+		return nil, err
+	}
+	mkt, err := parseMktWithHost(params.Args[0], params.Args[1], params.Args[2])
+	if err != nil {
+		return nil, err
+	}
+	startTime, err := checkIntArg(params.Args[3], "startTime", 64)
+	if err != nil {
+		return nil, err
+	}
+	form := &mmAnalyticsForm{
+		mkt:       mkt,
+		startTime: startTime,
+	}
+	if len(params.Args) > 4 {
+		window, err := checkUIntArg(params.Args[4], "window", 32)
+		if err != nil {
+			return nil, err
+		}
+		form.window = time.Duration(window) * time.Second
+	}
+	if len(params.Args) > 5 {
+		switch params.Args[5] {
+		case "json":
+		case "csv":
+			form.csv = true
+		default:
+			return nil, fmt.Errorf("%w: unknown format %q", errArgs, params.Args[5])
+		}
+	}
+	return form, nil
+}
+
 func parseUpdateRunningBotArgs(params *RawParams) (*updateRunningBotForm, error) {
 	if err := checkNArgs(params, []int{0}, []int{4, 6}); err != nil {
 		return nil, err
@@ -1189,3 +1467,171 @@ func parseWalletTxArgs(params *RawParams) (*walletTxForm, error) {
 		txID:    params.Args[1],
 	}, nil
 }
+
+// stopOrderForm is the user's stop order details.
+type stopOrderForm struct {
+	srvForm *core.StopOrderForm
+}
+
+// parseStopOrderArgs parses the arguments for the stoporder route.
+func parseStopOrderArgs(params *RawParams) (*stopOrderForm, error) {
+	if err := checkNArgs(params, []int{0}, []int{12, 13}); err != nil {
+		return nil, err
+	}
+	sell, err := checkBoolArg(params.Args[2], "sell")
+	if err != nil {
+		return nil, err
+	}
+	base, err := checkUIntArg(params.Args[3], "base", 32)
+	if err != nil {
+		return nil, err
+	}
+	quote, err := checkUIntArg(params.Args[4], "quote", 32)
+	if err != nil {
+		return nil, err
+	}
+	qty, err := checkUIntArg(params.Args[5], "qty", 64)
+	if err != nil {
+		return nil, err
+	}
+	triggerRate, err := checkUIntArg(params.Args[7], "triggerRate", 64)
+	if err != nil {
+		return nil, err
+	}
+	rate, err := checkUIntArg(params.Args[8], "rate", 64)
+	if err != nil {
+		return nil, err
+	}
+	trailDelta, err := checkUIntArg(params.Args[9], "trailDelta", 64)
+	if err != nil {
+		return nil, err
+	}
+	limitOffset, err := checkUIntArg(params.Args[10], "limitOffset", 64)
+	if err != nil {
+		return nil, err
+	}
+	tifnow, err := checkBoolArg(params.Args[11], "immediate")
+	if err != nil {
+		return nil, err
+	}
+	var options map[string]string
+	if len(params.Args) == 13 {
+		options, err = checkMapArg(params.Args[12], "options")
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &stopOrderForm{
+		srvForm: &core.StopOrderForm{
+			Host:        params.Args[0],
+			Type:        params.Args[1],
+			Sell:        sell,
+			Base:        uint32(base),
+			Quote:       uint32(quote),
+			Qty:         qty,
+			PriceSource: params.Args[6],
+			TriggerRate: triggerRate,
+			Rate:        rate,
+			TrailDelta:  trailDelta,
+			LimitOffset: limitOffset,
+			TifNow:      tifnow,
+			Options:     options,
+		},
+	}, nil
+}
+
+// parseClientOrderIDArgs parses the arguments for routes that take the ID of
+// a client-side stop or scheduled order as their only argument.
+func parseClientOrderIDArgs(params *RawParams) (dex.Bytes, error) {
+	if err := checkNArgs(params, []int{0}, []int{1}); err != nil {
+		return nil, err
+	}
+	id, err := hex.DecodeString(params.Args[0])
+	if err != nil || len(id) == 0 {
+		return nil, fmt.Errorf("%w: invalid id hex", errArgs)
+	}
+	return id, nil
+}
+
+// parseActiveOnlyArgs parses the optional activeOnly argument of the
+// stoporders and scheduledorders routes.
+func parseActiveOnlyArgs(params *RawParams) (bool, error) {
+	if err := checkNArgs(params, []int{0}, []int{0, 1}); err != nil {
+		return false, err
+	}
+	if len(params.Args) == 0 {
+		return false, nil
+	}
+	return checkBoolArg(params.Args[0], "activeOnly")
+}
+
+// scheduledOrderForm is the user's TWAP or iceberg order details.
+type scheduledOrderForm struct {
+	srvForm *core.ScheduledOrderForm
+}
+
+// parseScheduleOrderArgs parses the arguments for the scheduleorder route.
+func parseScheduleOrderArgs(params *RawParams) (*scheduledOrderForm, error) {
+	if err := checkNArgs(params, []int{0}, []int{11, 12}); err != nil {
+		return nil, err
+	}
+	sell, err := checkBoolArg(params.Args[2], "sell")
+	if err != nil {
+		return nil, err
+	}
+	base, err := checkUIntArg(params.Args[3], "base", 32)
+	if err != nil {
+		return nil, err
+	}
+	quote, err := checkUIntArg(params.Args[4], "quote", 32)
+	if err != nil {
+		return nil, err
+	}
+	qty, err := checkUIntArg(params.Args[5], "qty", 64)
+	if err != nil {
+		return nil, err
+	}
+	rate, err := checkUIntArg(params.Args[6], "rate", 64)
+	if err != nil {
+		return nil, err
+	}
+	duration, err := checkUIntArg(params.Args[7], "duration", 64)
+	if err != nil {
+		return nil, err
+	}
+	slices, err := checkUIntArg(params.Args[8], "slices", 32)
+	if err != nil {
+		return nil, err
+	}
+	visibleQty, err := checkUIntArg(params.Args[9], "visibleQty", 64)
+	if err != nil {
+		return nil, err
+	}
+	tifnow, err := checkBoolArg(params.Args[10], "immediate")
+	if err != nil {
+		return nil, err
+	}
+	var options map[string]string
+	if len(params.Args) == 12 {
+		options, err = checkMapArg(params.Args[11], "options")
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &scheduledOrderForm{
+		srvForm: &core.ScheduledOrderForm{
+			Host:       params.Args[0],
+			Type:       params.Args[1],
+			Sell:       sell,
+			Base:       uint32(base),
+			Quote:      uint32(quote),
+			Qty:        qty,
+			Rate:       rate,
+			Duration:   duration,
+			Slices:     uint32(slices),
+			VisibleQty: visibleQty,
+			TifNow:     tifnow,
+			Options:    options,
+		},
+	}, nil
+}
@@ -7,8 +7,11 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"reflect"
 	"testing"
+	"time"
 
+	"decred.org/dcrdex/client/core"
 	"decred.org/dcrdex/dex/encode"
 )
 
@@ -482,6 +485,279 @@ func TestParseCancelArgs(t *testing.T) {
 	}
 }
 
+func TestParseAmendArgs(t *testing.T) {
+	const oid = "fb94fe99e4e32200a341f0f1cb33f34a08ac23eedab636e8adb991fa76343e1e"
+	paramsWithArgs := func(args ...string) *RawParams {
+		return &RawParams{
+			PWArgs: []encode.PassBytes{encode.PassBytes("abc")},
+			Args:   args,
+		}
+	}
+	tests := []struct {
+		name    string
+		params  *RawParams
+		wantErr error
+	}{{
+		name:   "ok",
+		params: paramsWithArgs(oid, "5", "10"),
+	}, {
+		name:    "order ID incorrect length",
+		params:  paramsWithArgs(oid[2:], "5", "10"),
+		wantErr: errArgs,
+	}, {
+		name:    "order ID not hex",
+		params:  paramsWithArgs("z"+oid[1:], "5", "10"),
+		wantErr: errArgs,
+	}, {
+		name:    "bad rate",
+		params:  paramsWithArgs(oid, "-5", "10"),
+		wantErr: errArgs,
+	}, {
+		name:    "not enough args",
+		params:  paramsWithArgs(oid, "5"),
+		wantErr: errArgs,
+	}}
+	for _, test := range tests {
+		form, err := parseAmendArgs(test.params)
+		if test.wantErr != nil {
+			if errors.Is(err, test.wantErr) {
+				continue
+			}
+			t.Fatalf("expected error for test %v", test.name)
+		}
+		if err != nil {
+			t.Fatalf("unexpected error %v for test %s", err, test.name)
+		}
+		if form.srvForm.OrderID.String() != oid || form.srvForm.Rate != 5 || form.srvForm.Qty != 10 {
+			t.Fatalf("wrong form for test %s: %+v", test.name, form.srvForm)
+		}
+	}
+}
+
+func TestParseTaxExportArgs(t *testing.T) {
+	tests := []struct {
+		name       string
+		args       []string
+		wantMethod string
+		wantFormat string
+		wantErr    error
+	}{{
+		name:       "ok",
+		args:       []string{"1000", "2000"},
+		wantMethod: core.CostBasisFIFO,
+		wantFormat: taxExportJSONFormat,
+	}, {
+		name:       "ok method and format",
+		args:       []string{"1000", "2000", "lifo", "costbasis"},
+		wantMethod: core.CostBasisLIFO,
+		wantFormat: taxExportCostBasisFormat,
+	}, {
+		name:    "bad start",
+		args:    []string{"-1", "2000"},
+		wantErr: errArgs,
+	}, {
+		name:    "unknown method",
+		args:    []string{"1000", "2000", "hifo"},
+		wantErr: errArgs,
+	}, {
+		name:    "unknown format",
+		args:    []string{"1000", "2000", "fifo", "xml"},
+		wantErr: errArgs,
+	}, {
+		name:    "not enough args",
+		args:    []string{"1000"},
+		wantErr: errArgs,
+	}}
+	for _, test := range tests {
+		form, err := parseTaxExportArgs(&RawParams{Args: test.args})
+		if test.wantErr != nil {
+			if errors.Is(err, test.wantErr) {
+				continue
+			}
+			t.Fatalf("expected error for test %v", test.name)
+		}
+		if err != nil {
+			t.Fatalf("unexpected error %v for test %s", err, test.name)
+		}
+		if form.srvForm.Start != 1000 || form.srvForm.End != 2000 || form.srvForm.Method != test.wantMethod || form.format != test.wantFormat {
+			t.Fatalf("wrong form for test %s: %+v, format %s", test.name, form.srvForm, form.format)
+		}
+	}
+}
+
+func TestParseCreateAPIKeyArgs(t *testing.T) {
+	pwParams := func(args ...string) *RawParams {
+		return &RawParams{PWArgs: []encode.PassBytes{encode.PassBytes("abc")}, Args: args}
+	}
+
+	form, err := parseCreateAPIKeyArgs(pwParams("monitor", "read,mm", "30"))
+	if err != nil {
+		t.Fatalf("parseCreateAPIKeyArgs error: %v", err)
+	}
+	if form.name != "monitor" || len(form.scopes) != 2 || form.scopes[1] != scopeMM || form.rateLimit != 30 {
+		t.Fatalf("wrong create API key form: %+v", form)
+	}
+	if form, err = parseCreateAPIKeyArgs(pwParams("monitor", "read")); err != nil || form.rateLimit != 0 {
+		t.Fatalf("parseCreateAPIKeyArgs error without rate limit: %v", err)
+	}
+	for _, params := range []*RawParams{
+		pwParams("monitor"),
+		pwParams("monitor", ""),
+		pwParams("monitor", "read,admin"),
+		pwParams("monitor", "read,read"),
+		pwParams("monitor", "read", "-1"),
+		{Args: []string{"monitor", "read"}},
+	} {
+		if _, err := parseCreateAPIKeyArgs(params); !errors.Is(err, errArgs) {
+			t.Fatalf("expected errArgs for create API key args %v, got %v", params.Args, err)
+		}
+	}
+}
+
+func TestParseAddressBookArgs(t *testing.T) {
+	pwParams := func(args ...string) *RawParams {
+		return &RawParams{PWArgs: []encode.PassBytes{encode.PassBytes("abc")}, Args: args}
+	}
+
+	form, err := parseAddAddressArgs(pwParams("42", "addr", "label"))
+	if err != nil {
+		t.Fatalf("parseAddAddressArgs error: %v", err)
+	}
+	if form.assetID != 42 || form.address != "addr" || form.label != "label" {
+		t.Fatalf("wrong add address form: %+v", form)
+	}
+	if form, err = parseAddAddressArgs(pwParams("42", "addr")); err != nil || form.label != "" {
+		t.Fatalf("parseAddAddressArgs error without label: %v", err)
+	}
+	for _, params := range []*RawParams{
+		pwParams("-1", "addr"),
+		pwParams("42"),
+		{Args: []string{"42", "addr"}},
+	} {
+		if _, err := parseAddAddressArgs(params); !errors.Is(err, errArgs) {
+			t.Fatalf("expected errArgs for add address args %v, got %v", params.Args, err)
+		}
+	}
+
+	rmForm, err := parseRemoveAddressArgs(pwParams("0a0b"))
+	if err != nil {
+		t.Fatalf("parseRemoveAddressArgs error: %v", err)
+	}
+	if rmForm.id.String() != "0a0b" {
+		t.Fatalf("wrong remove address id %s", rmForm.id)
+	}
+	for _, id := range []string{"", "zz"} {
+		if _, err := parseRemoveAddressArgs(pwParams(id)); !errors.Is(err, errArgs) {
+			t.Fatalf("expected errArgs for id %q, got %v", id, err)
+		}
+	}
+
+	setForm, err := parseSetAllowlistArgs(pwParams("true"))
+	if err != nil {
+		t.Fatalf("parseSetAllowlistArgs error: %v", err)
+	}
+	if !setForm.enable {
+		t.Fatalf("allowlist not enabled")
+	}
+	if _, err := parseSetAllowlistArgs(pwParams("yes please")); !errors.Is(err, errArgs) {
+		t.Fatalf("expected errArgs for bad bool, got %v", err)
+	}
+
+	if _, err := parseAddressBookArgs(&RawParams{}); !errors.Is(err, errArgs) {
+		t.Fatalf("expected errArgs for missing password, got %v", err)
+	}
+}
+
+func TestParseStopOrderArgs(t *testing.T) {
+	paramsWithArgs := func(args ...string) *RawParams {
+		return &RawParams{Args: append([]string{"host", "trailingstop", "false", "42", "0", "10",
+			"lasttrade", "0", "0", "5", "1"}, args...)}
+	}
+	tests := []struct {
+		name    string
+		params  *RawParams
+		wantErr error
+	}{{
+		name:   "ok",
+		params: paramsWithArgs("true"),
+	}, {
+		name:   "ok with options",
+		params: paramsWithArgs("true", `{"a":"b"}`),
+	}, {
+		name:    "immediate not a bool",
+		params:  paramsWithArgs("yes"),
+		wantErr: errArgs,
+	}, {
+		name:    "bad options",
+		params:  paramsWithArgs("true", "a"),
+		wantErr: errArgs,
+	}, {
+		name:    "not enough args",
+		params:  paramsWithArgs(),
+		wantErr: errArgs,
+	}}
+	for _, test := range tests {
+		form, err := parseStopOrderArgs(test.params)
+		if test.wantErr != nil {
+			if errors.Is(err, test.wantErr) {
+				continue
+			}
+			t.Fatalf("expected error for test %v", test.name)
+		}
+		if err != nil {
+			t.Fatalf("unexpected error %v for test %s", err, test.name)
+		}
+		f := form.srvForm
+		if f.Host != "host" || f.Type != "trailingstop" || f.Sell || f.Base != 42 || f.Quote != 0 ||
+			f.Qty != 10 || f.PriceSource != "lasttrade" || f.TrailDelta != 5 || f.LimitOffset != 1 || !f.TifNow {
+			t.Fatalf("%s: wrong form %+v", test.name, f)
+		}
+	}
+}
+
+func TestParseScheduleOrderArgs(t *testing.T) {
+	paramsWithArgs := func(args ...string) *RawParams {
+		return &RawParams{Args: append([]string{"host", "iceberg", "true", "42", "0", "100",
+			"5", "0", "0", "10"}, args...)}
+	}
+	tests := []struct {
+		name    string
+		params  *RawParams
+		wantErr error
+	}{{
+		name:   "ok",
+		params: paramsWithArgs("false"),
+	}, {
+		name:   "ok with options",
+		params: paramsWithArgs("false", `{"a":"b"}`),
+	}, {
+		name:    "immediate not a bool",
+		params:  paramsWithArgs("no"),
+		wantErr: errArgs,
+	}, {
+		name:    "not enough args",
+		params:  paramsWithArgs(),
+		wantErr: errArgs,
+	}}
+	for _, test := range tests {
+		form, err := parseScheduleOrderArgs(test.params)
+		if test.wantErr != nil {
+			if errors.Is(err, test.wantErr) {
+				continue
+			}
+			t.Fatalf("expected error for test %v", test.name)
+		}
+		if err != nil {
+			t.Fatalf("unexpected error %v for test %s", err, test.name)
+		}
+		f := form.srvForm
+		if f.Host != "host" || f.Type != "iceberg" || !f.Sell || f.Base != 42 || f.Qty != 100 ||
+			f.Rate != 5 || f.VisibleQty != 10 || f.TifNow {
+			t.Fatalf("%s: wrong form %+v", test.name, f)
+		}
+	}
+}
+
 func TestParseSendOrWithdrawArgs(t *testing.T) {
 	paramsWithArgs := func(id, value string) *RawParams {
 		pw := encode.PassBytes("password123")
@@ -902,3 +1178,97 @@ func TestParseSetVotingPreferencesArgs(t *testing.T) {
 		}
 	}
 }
+
+func TestParseMMAnalyticsArgs(t *testing.T) {
+	paramsWithArgs := func(args ...string) *RawParams {
+		return &RawParams{Args: args}
+	}
+	tests := []struct {
+		name       string
+		params     *RawParams
+		wantWindow time.Duration
+		wantCSV    bool
+		wantErr    error
+	}{{
+		name:   "ok",
+		params: paramsWithArgs("dex.com", "42", "0", "1700000000"),
+	}, {
+		name:       "ok with window and format",
+		params:     paramsWithArgs("dex.com", "42", "0", "1700000000", "3600", "csv"),
+		wantWindow: time.Hour,
+		wantCSV:    true,
+	}, {
+		name:    "start time not a number",
+		params:  paramsWithArgs("dex.com", "42", "0", "abc"),
+		wantErr: errArgs,
+	}, {
+		name:    "bad format",
+		params:  paramsWithArgs("dex.com", "42", "0", "1700000000", "60", "xml"),
+		wantErr: errArgs,
+	}, {
+		name:    "not enough args",
+		params:  paramsWithArgs("dex.com", "42", "0"),
+		wantErr: errArgs,
+	}}
+	for _, test := range tests {
+		form, err := parseMMAnalyticsArgs(test.params)
+		if test.wantErr != nil {
+			if errors.Is(err, test.wantErr) {
+				continue
+			}
+			t.Fatalf("%q: expected error %v, got %v", test.name, test.wantErr, err)
+		}
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", test.name, err)
+		}
+		if form.startTime != 1700000000 || form.window != test.wantWindow || form.csv != test.wantCSV {
+			t.Fatalf("%q: wrong form %+v", test.name, form)
+		}
+	}
+}
+
+func TestParseSetMMRiskConfigArgs(t *testing.T) {
+	paramsWithArgs := func(args ...string) *RawParams {
+		return &RawParams{Args: args}
+	}
+	tests := []struct {
+		name     string
+		params   *RawParams
+		wantCaps map[uint32]uint64
+		wantErr  error
+	}{{
+		name:   "ok",
+		params: paramsWithArgs("0.1", "0.05"),
+	}, {
+		name:     "ok with exposure caps",
+		params:   paramsWithArgs("0.1", "0.05", "[[42,1000000000],[0,5000000]]"),
+		wantCaps: map[uint32]uint64{42: 1e9, 0: 5e6},
+	}, {
+		name:    "drawdown not a number",
+		params:  paramsWithArgs("abc", "0.05"),
+		wantErr: errArgs,
+	}, {
+		name:    "negative exposure cap",
+		params:  paramsWithArgs("0.1", "0.05", "[[42,-1]]"),
+		wantErr: errArgs,
+	}, {
+		name:    "not enough args",
+		params:  paramsWithArgs("0.1"),
+		wantErr: errArgs,
+	}}
+	for _, test := range tests {
+		cfg, err := parseSetMMRiskConfigArgs(test.params)
+		if test.wantErr != nil {
+			if errors.Is(err, test.wantErr) {
+				continue
+			}
+			t.Fatalf("%q: expected error %v, got %v", test.name, test.wantErr, err)
+		}
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", test.name, err)
+		}
+		if cfg.MaxBotDrawdown != 0.1 || cfg.MaxDrawdown != 0.05 || !reflect.DeepEqual(cfg.ExposureCaps, test.wantCaps) {
+			t.Fatalf("%q: wrong config %+v", test.name, cfg)
+		}
+	}
+}
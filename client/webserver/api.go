@@ -5,6 +5,7 @@ package webserver
 
 import (
 	"archive/zip"
+	"bytes"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
@@ -918,6 +919,212 @@ func (s *WebServer) apiCancel(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, simpleAck())
 }
 
+// apiAmend is the handler for the '/amend' API request.
+func (s *WebServer) apiAmend(w http.ResponseWriter, r *http.Request) {
+	form := new(amendForm)
+	defer form.Pass.Clear()
+	if !readPost(w, r, form) {
+		return
+	}
+	pass, err := s.resolvePass(form.Pass, r)
+	if err != nil {
+		s.writeAPIError(w, fmt.Errorf("password error: %w", err))
+		return
+	}
+	defer zero(pass)
+	if form.Amend == nil {
+		s.writeAPIError(w, errors.New("amendment missing"))
+		return
+	}
+	ord, err := s.core.AmendOrder(pass, form.Amend)
+	if err != nil {
+		s.writeAPIError(w, fmt.Errorf("error amending order %s: %w", form.Amend.OrderID, err))
+		return
+	}
+	writeJSON(w, &struct {
+		OK    bool        `json:"ok"`
+		Order *core.Order `json:"order"`
+	}{
+		OK:    true,
+		Order: ord,
+	})
+}
+
+// apiTaxExport is the handler for the '/taxexport' API request. The report is
+// returned as JSON, or as a CSV attachment if the requested format is "ledger"
+// or "costbasis".
+func (s *WebServer) apiTaxExport(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		*core.TaxExportForm
+		Format string `json:"format"`
+	}
+	if !readPost(w, r, &req) {
+		return
+	}
+	if req.TaxExportForm == nil {
+		req.TaxExportForm = new(core.TaxExportForm)
+	}
+
+	var writeCSV func(*core.TaxReport, io.Writer) error
+	switch req.Format {
+	case "", "json":
+	case "ledger":
+		writeCSV = (*core.TaxReport).WriteLedgerCSV
+	case "costbasis":
+		writeCSV = (*core.TaxReport).WriteCostBasisCSV
+	default:
+		s.writeAPIError(w, fmt.Errorf("unknown format %q", req.Format))
+		return
+	}
+
+	report, err := s.core.TaxExport(req.TaxExportForm)
+	if err != nil {
+		s.writeAPIError(w, fmt.Errorf("error exporting history: %w", err))
+		return
+	}
+
+	if writeCSV == nil {
+		writeJSON(w, &struct {
+			OK     bool            `json:"ok"`
+			Report *core.TaxReport `json:"report"`
+		}{
+			OK:     true,
+			Report: report,
+		})
+		return
+	}
+
+	var b bytes.Buffer
+	if err := writeCSV(report, &b); err != nil {
+		s.writeAPIError(w, fmt.Errorf("error writing CSV: %w", err))
+		return
+	}
+	fileName := fmt.Sprintf("tax_%s_%s_%d_%d.csv", req.Format, report.Method, report.Start, report.End)
+	w.Header().Set("Content-Disposition", "attachment; filename="+fileName)
+	w.Header().Set("Content-Type", "text/csv")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(b.Bytes()); err != nil {
+		log.Errorf("error writing tax export CSV: %v", err)
+	}
+}
+
+// apiAddressBook is the handler for the '/addressbook' API request.
+func (s *WebServer) apiAddressBook(w http.ResponseWriter, r *http.Request) {
+	form := &struct {
+		Pass encode.PassBytes `json:"pass"`
+	}{}
+	defer form.Pass.Clear()
+	if !readPost(w, r, form) {
+		return
+	}
+	appPW, err := s.resolvePass(form.Pass, r)
+	if err != nil {
+		s.writeAPIError(w, fmt.Errorf("password error: %w", err))
+		return
+	}
+	book, err := s.core.AddressBook(appPW)
+	if err != nil {
+		s.writeAPIError(w, fmt.Errorf("error retrieving address book: %w", err))
+		return
+	}
+	status, err := s.core.Allowlist()
+	if err != nil {
+		s.writeAPIError(w, fmt.Errorf("error retrieving allowlist status: %w", err))
+		return
+	}
+	writeJSON(w, &struct {
+		OK          bool                     `json:"ok"`
+		AddressBook []*core.AddressBookEntry `json:"addressBook"`
+		Allowlist   *core.AllowlistStatus    `json:"allowlist"`
+	}{
+		OK:          true,
+		AddressBook: book,
+		Allowlist:   status,
+	})
+}
+
+// apiAddAddress is the handler for the '/addaddress' API request. The app
+// password is always required and is never taken from the cache.
+func (s *WebServer) apiAddAddress(w http.ResponseWriter, r *http.Request) {
+	form := &struct {
+		Pass    encode.PassBytes `json:"pass"`
+		AssetID uint32           `json:"assetID"`
+		Address string           `json:"address"`
+		Label   string           `json:"label"`
+	}{}
+	defer form.Pass.Clear()
+	if !readPost(w, r, form) {
+		return
+	}
+	if len(form.Pass) == 0 {
+		s.writeAPIError(w, fmt.Errorf("empty password"))
+		return
+	}
+	entry, err := s.core.AddAddress(form.Pass, form.AssetID, form.Address, form.Label)
+	if err != nil {
+		s.writeAPIError(w, fmt.Errorf("error adding address: %w", err))
+		return
+	}
+	writeJSON(w, &struct {
+		OK    bool                   `json:"ok"`
+		Entry *core.AddressBookEntry `json:"entry"`
+	}{
+		OK:    true,
+		Entry: entry,
+	})
+}
+
+// apiRemoveAddress is the handler for the '/removeaddress' API request.
+func (s *WebServer) apiRemoveAddress(w http.ResponseWriter, r *http.Request) {
+	form := &struct {
+		Pass encode.PassBytes `json:"pass"`
+		ID   dex.Bytes        `json:"id"`
+	}{}
+	defer form.Pass.Clear()
+	if !readPost(w, r, form) {
+		return
+	}
+	appPW, err := s.resolvePass(form.Pass, r)
+	if err != nil {
+		s.writeAPIError(w, fmt.Errorf("password error: %w", err))
+		return
+	}
+	if err := s.core.RemoveAddress(appPW, form.ID); err != nil {
+		s.writeAPIError(w, fmt.Errorf("error removing address: %w", err))
+		return
+	}
+	writeJSON(w, simpleAck())
+}
+
+// apiSetAllowlist is the handler for the '/setallowlist' API request. The app
+// password is always required and is never taken from the cache.
+func (s *WebServer) apiSetAllowlist(w http.ResponseWriter, r *http.Request) {
+	form := &struct {
+		Pass   encode.PassBytes `json:"pass"`
+		Enable bool             `json:"enable"`
+	}{}
+	defer form.Pass.Clear()
+	if !readPost(w, r, form) {
+		return
+	}
+	if len(form.Pass) == 0 {
+		s.writeAPIError(w, fmt.Errorf("empty password"))
+		return
+	}
+	status, err := s.core.SetAllowlist(form.Pass, form.Enable)
+	if err != nil {
+		s.writeAPIError(w, fmt.Errorf("error setting allowlist: %w", err))
+		return
+	}
+	writeJSON(w, &struct {
+		OK        bool                  `json:"ok"`
+		Allowlist *core.AllowlistStatus `json:"allowlist"`
+	}{
+		OK:        true,
+		Allowlist: status,
+	})
+}
+
 // apiCloseWallet is the handler for the '/closewallet' API request.
 func (s *WebServer) apiCloseWallet(w http.ResponseWriter, r *http.Request) {
 	form := &struct {
@@ -1216,6 +1423,144 @@ func (s *WebServer) apiDefaultWalletCfg(w http.ResponseWriter, r *http.Request)
 	})
 }
 
+// apiStopOrder is the handler for the '/stoporder' API request.
+func (s *WebServer) apiStopOrder(w http.ResponseWriter, r *http.Request) {
+	form := new(core.StopOrderForm)
+	if !readPost(w, r, form) {
+		return
+	}
+	so, err := s.core.PlaceStopOrder(form)
+	if err != nil {
+		s.writeAPIError(w, fmt.Errorf("error placing stop order: %w", err))
+		return
+	}
+	writeJSON(w, &struct {
+		OK        bool            `json:"ok"`
+		StopOrder *core.StopOrder `json:"stopOrder"`
+	}{
+		OK:        true,
+		StopOrder: so,
+	})
+}
+
+// apiCancelStopOrder is the handler for the '/cancelstoporder' API request.
+func (s *WebServer) apiCancelStopOrder(w http.ResponseWriter, r *http.Request) {
+	form := &struct {
+		ID dex.Bytes `json:"id"`
+	}{}
+	if !readPost(w, r, form) {
+		return
+	}
+	if err := s.core.CancelStopOrder(form.ID); err != nil {
+		s.writeAPIError(w, fmt.Errorf("error canceling stop order %s: %w", form.ID, err))
+		return
+	}
+	writeJSON(w, simpleAck())
+}
+
+// apiStopOrders responds with the user's stop orders.
+func (s *WebServer) apiStopOrders(w http.ResponseWriter, r *http.Request) {
+	form := &struct {
+		ActiveOnly bool `json:"activeOnly"`
+	}{}
+	if !readPost(w, r, form) {
+		return
+	}
+	sos, err := s.core.StopOrders(form.ActiveOnly)
+	if err != nil {
+		s.writeAPIError(w, fmt.Errorf("StopOrders error: %w", err))
+		return
+	}
+	writeJSON(w, &struct {
+		OK         bool              `json:"ok"`
+		StopOrders []*core.StopOrder `json:"stopOrders"`
+	}{
+		OK:         true,
+		StopOrders: sos,
+	})
+}
+
+// apiScheduleOrder is the handler for the '/scheduleorder' API request.
+func (s *WebServer) apiScheduleOrder(w http.ResponseWriter, r *http.Request) {
+	form := new(core.ScheduledOrderForm)
+	if !readPost(w, r, form) {
+		return
+	}
+	so, err := s.core.CreateScheduledOrder(form)
+	if err != nil {
+		s.writeAPIError(w, fmt.Errorf("error creating scheduled order: %w", err))
+		return
+	}
+	writeJSON(w, &struct {
+		OK             bool                 `json:"ok"`
+		ScheduledOrder *core.ScheduledOrder `json:"scheduledOrder"`
+	}{
+		OK:             true,
+		ScheduledOrder: so,
+	})
+}
+
+// apiCancelScheduledOrder is the handler for the '/cancelscheduledorder' API
+// request.
+func (s *WebServer) apiCancelScheduledOrder(w http.ResponseWriter, r *http.Request) {
+	form := &struct {
+		ID dex.Bytes `json:"id"`
+	}{}
+	if !readPost(w, r, form) {
+		return
+	}
+	if err := s.core.CancelScheduledOrder(form.ID); err != nil {
+		s.writeAPIError(w, fmt.Errorf("error canceling scheduled order %s: %w", form.ID, err))
+		return
+	}
+	writeJSON(w, simpleAck())
+}
+
+// apiScheduledOrder responds with the status, aggregate fill and fees of a
+// scheduled order.
+func (s *WebServer) apiScheduledOrder(w http.ResponseWriter, r *http.Request) {
+	form := &struct {
+		ID dex.Bytes `json:"id"`
+	}{}
+	if !readPost(w, r, form) {
+		return
+	}
+	so, err := s.core.ScheduledOrder(form.ID)
+	if err != nil {
+		s.writeAPIError(w, fmt.Errorf("ScheduledOrder error: %w", err))
+		return
+	}
+	writeJSON(w, &struct {
+		OK             bool                 `json:"ok"`
+		ScheduledOrder *core.ScheduledOrder `json:"scheduledOrder"`
+	}{
+		OK:             true,
+		ScheduledOrder: so,
+	})
+}
+
+// apiScheduledOrders responds with the user's scheduled orders.
+func (s *WebServer) apiScheduledOrders(w http.ResponseWriter, r *http.Request) {
+	form := &struct {
+		ActiveOnly bool `json:"activeOnly"`
+	}{}
+	if !readPost(w, r, form) {
+		return
+	}
+	sos, err := s.core.ScheduledOrders(form.ActiveOnly)
+	if err != nil {
+		s.writeAPIError(w, fmt.Errorf("ScheduledOrders error: %w", err))
+		return
+	}
+	writeJSON(w, &struct {
+		OK              bool                   `json:"ok"`
+		ScheduledOrders []*core.ScheduledOrder `json:"scheduledOrders"`
+	}{
+		OK:              true,
+		ScheduledOrders: sos,
+	})
+}
+
 // apiOrders responds with a filtered list of user orders.
 func (s *WebServer) apiOrders(w http.ResponseWriter, r *http.Request) {
 	filter := new(core.OrderFilter)
@@ -1747,6 +2092,60 @@ func (s *WebServer) apiRunLogs(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// apiRunAnalytics is the handler for the '/mmanalytics' API request. The
+// analytics are returned as JSON, or as a CSV attachment if the requested
+// format is "csv".
+func (s *WebServer) apiRunAnalytics(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		StartTime int64              `json:"startTime"`
+		Market    *mm.MarketWithHost `json:"market"`
+		Window    uint32             `json:"window"` // seconds
+		Format    string             `json:"format"`
+	}
+	if !readPost(w, r, &req) {
+		return
+	}
+
+	if req.Market == nil {
+		s.writeAPIError(w, errors.New("market missing"))
+		return
+	}
+	if req.Format != "" && req.Format != "json" && req.Format != "csv" {
+		s.writeAPIError(w, fmt.Errorf("unknown format %q", req.Format))
+		return
+	}
+
+	analytics, err := s.mm.RunAnalytics(req.StartTime, req.Market, time.Duration(req.Window)*time.Second)
+	if err != nil {
+		s.writeAPIError(w, fmt.Errorf("error computing run analytics: %w", err))
+		return
+	}
+
+	if req.Format != "csv" {
+		writeJSON(w, &struct {
+			OK        bool             `json:"ok"`
+			Analytics *mm.RunAnalytics `json:"analytics"`
+		}{
+			OK:        true,
+			Analytics: analytics,
+		})
+		return
+	}
+
+	var b bytes.Buffer
+	if err := analytics.WriteCSV(&b); err != nil {
+		s.writeAPIError(w, fmt.Errorf("error writing CSV: %w", err))
+		return
+	}
+	fileName := fmt.Sprintf("mm_analytics_%s_%s_%d.csv", dex.BipIDSymbol(req.Market.BaseID), dex.BipIDSymbol(req.Market.QuoteID), req.StartTime)
+	w.Header().Set("Content-Disposition", "attachment; filename="+fileName)
+	w.Header().Set("Content-Type", "text/csv")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(b.Bytes()); err != nil {
+		log.Errorf("error writing analytics CSV: %v", err)
+	}
+}
+
 func (s *WebServer) apiCEXBook(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		Host    string `json:"host"`
@@ -2112,6 +2511,53 @@ func (s *WebServer) apiMarketMakingStatus(w http.ResponseWriter, r *http.Request
 	})
 }
 
+// apiKillSwitch stops all running market making bots and prevents any bots
+// from being started until the kill switch is reset.
+func (s *WebServer) apiKillSwitch(w http.ResponseWriter, r *http.Request) {
+	if err := s.mm.KillSwitch(); err != nil {
+		s.writeAPIError(w, fmt.Errorf("kill switch error: %w", err))
+		return
+	}
+	writeJSON(w, simpleAck())
+}
+
+// apiResetKillSwitch allows market making bots to be started again after the
+// kill switch was triggered.
+func (s *WebServer) apiResetKillSwitch(w http.ResponseWriter, r *http.Request) {
+	if err := s.mm.ResetKillSwitch(); err != nil {
+		s.writeAPIError(w, fmt.Errorf("error resetting kill switch: %w", err))
+		return
+	}
+	writeJSON(w, simpleAck())
+}
+
+func (s *WebServer) apiRiskStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, &struct {
+		OK     bool           `json:"ok"`
+		Status *mm.RiskStatus `json:"status"`
+	}{
+		OK:     true,
+		Status: s.mm.RiskStatus(),
+	})
+}
+
+func (s *WebServer) apiUpdateRiskConfig(w http.ResponseWriter, r *http.Request) {
+	var cfg *mm.RiskConfig
+	if !readPost(w, r, &cfg) {
+		s.writeAPIError(w, fmt.Errorf("failed to read config"))
+		return
+	}
+	if cfg == nil {
+		s.writeAPIError(w, errors.New("risk config missing"))
+		return
+	}
+	if err := s.mm.UpdateRiskConfig(cfg); err != nil {
+		s.writeAPIError(w, err)
+		return
+	}
+	writeJSON(w, simpleAck())
+}
+
 func (s *WebServer) apiTxHistory(w http.ResponseWriter, r *http.Request) {
 	var form struct {
 		AssetID uint32 `json:"assetID"`
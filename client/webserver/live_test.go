@@ -1756,6 +1756,74 @@ func (c *TCore) Cancel(oid dex.Bytes) error {
 	return nil
 }
 
+func (c *TCore) AmendOrder(pw []byte, form *core.AmendForm) (*core.Order, error) {
+	c.Cancel(form.OrderID)
+	return &core.Order{
+		ID:    ordertest.RandomOrderID().Bytes(),
+		Type:  order.LimitOrderType,
+		Stamp: uint64(time.Now().UnixMilli()),
+		Rate:  form.Rate,
+		Qty:   form.Qty,
+	}, nil
+}
+
+func (c *TCore) TaxExport(form *core.TaxExportForm) (*core.TaxReport, error) {
+	return &core.TaxReport{
+		Start:     form.Start,
+		End:       uint64(time.Now().UnixMilli()),
+		Method:    core.CostBasisFIFO,
+		Ledger:    []*core.TaxLedgerEntry{},
+		Disposals: []*core.TaxDisposal{},
+	}, nil
+}
+
+func (c *TCore) AddressBook(pw []byte) ([]*core.AddressBookEntry, error) {
+	return []*core.AddressBookEntry{}, nil
+}
+func (c *TCore) AddAddress(pw []byte, assetID uint32, address, label string) (*core.AddressBookEntry, error) {
+	now := uint64(time.Now().UnixMilli())
+	return &core.AddressBookEntry{
+		ID:       encode.RandomBytes(8),
+		AssetID:  assetID,
+		Symbol:   unbip(assetID),
+		Address:  address,
+		Label:    label,
+		Stamp:    now,
+		ActiveAt: now + uint64(24*time.Hour/time.Millisecond),
+	}, nil
+}
+func (c *TCore) RemoveAddress(pw []byte, id dex.Bytes) error {
+	return nil
+}
+func (c *TCore) Allowlist() (*core.AllowlistStatus, error) {
+	return &core.AllowlistStatus{Delay: uint64(24 * time.Hour / time.Millisecond)}, nil
+}
+func (c *TCore) SetAllowlist(pw []byte, enable bool) (*core.AllowlistStatus, error) {
+	return &core.AllowlistStatus{Enabled: enable, Delay: uint64(24 * time.Hour / time.Millisecond)}, nil
+}
+
+func (c *TCore) PlaceStopOrder(form *core.StopOrderForm) (*core.StopOrder, error) {
+	return &core.StopOrder{}, nil
+}
+func (c *TCore) CancelStopOrder(id dex.Bytes) error {
+	return nil
+}
+func (c *TCore) StopOrders(activeOnly bool) ([]*core.StopOrder, error) {
+	return nil, nil
+}
+func (c *TCore) CreateScheduledOrder(form *core.ScheduledOrderForm) (*core.ScheduledOrder, error) {
+	return &core.ScheduledOrder{}, nil
+}
+func (c *TCore) CancelScheduledOrder(id dex.Bytes) error {
+	return nil
+}
+func (c *TCore) ScheduledOrder(id dex.Bytes) (*core.ScheduledOrder, error) {
+	return &core.ScheduledOrder{}, nil
+}
+func (c *TCore) ScheduledOrders(activeOnly bool) ([]*core.ScheduledOrder, error) {
+	return nil, nil
+}
+
 func (c *TCore) NotificationFeed() *core.NoteFeed {
 	return &core.NoteFeed{
 		C: c.noteFeed,
@@ -2255,6 +2323,26 @@ func (m *TMarketMaker) StopBot(mkt *mm.MarketWithHost) error {
 	return nil
 }
 
+func (m *TMarketMaker) KillSwitch() error {
+	m.runningBotsMtx.Lock()
+	m.runningBots = make(map[mm.MarketWithHost]int64)
+	m.runningBotsMtx.Unlock()
+	return nil
+}
+
+func (m *TMarketMaker) ResetKillSwitch() error {
+	return nil
+}
+
+func (m *TMarketMaker) RiskStatus() *mm.RiskStatus {
+	return &mm.RiskStatus{Config: &mm.RiskConfig{}}
+}
+
+func (m *TMarketMaker) UpdateRiskConfig(cfg *mm.RiskConfig) error {
+	m.cfg.RiskConfig = cfg
+	return nil
+}
+
 func (m *TMarketMaker) UpdateCEXConfig(updatedCfg *mm.CEXConfig) error {
 	for i := 0; i < len(m.cfg.CexConfigs); i++ {
 		cfg := m.cfg.CexConfigs[i]
@@ -2509,6 +2597,10 @@ func randomWalletTransaction(txType asset.TransactionType, qty uint64) *asset.Wa
 	return tx
 }
 
+func (m *TMarketMaker) RunAnalytics(startTime int64, mkt *mm.MarketWithHost, window time.Duration) (*mm.RunAnalytics, error) {
+	return &mm.RunAnalytics{StartTime: startTime, Market: mkt}, nil
+}
+
 func (m *TMarketMaker) RunLogs(startTime int64, mkt *mm.MarketWithHost, n uint64, refID *uint64, filters *mm.RunLogFilters) ([]*mm.MarketMakingEvent, []*mm.MarketMakingEvent, *mm.MarketMakingRunOverview, error) {
 	if n == 0 {
 		n = uint64(rand.Intn(100))
@@ -41,6 +41,7 @@ export const GapStrategyAbsolute = 'absolute'
 export const GapStrategyAbsolutePlus = 'absolute-plus'
 export const GapStrategyPercent = 'percent'
 export const GapStrategyPercentPlus = 'percent-plus'
+export const GapStrategyVolatility = 'volatility'
 
 export const botTypeBasicMM = 'basicMM'
 export const botTypeArbMM = 'arbMM'
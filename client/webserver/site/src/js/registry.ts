@@ -774,6 +774,8 @@ export interface AutoRebalanceConfig {
   minBaseTransfer: number
   minQuoteTransfer: number
   internalOnly: boolean
+  botTransfers?: boolean
+  bridgeRebalance?: boolean
 }
 
 export interface BasicMarketMakingConfig {
@@ -781,6 +783,13 @@ export interface BasicMarketMakingConfig {
   sellPlacements: OrderPlacement[]
   buyPlacements: OrderPlacement[]
   driftTolerance: number
+  volatility?: VolatilityGapConfig
+}
+
+export interface VolatilityGapConfig {
+  candles: number
+  floor: number
+  ceiling: number
 }
 
 export interface ArbMarketMakingPlacement {
@@ -856,15 +865,50 @@ export interface BotConfig {
   quoteWalletOptions?: Record<string, string>
   cexName: string
   uiConfig: UIConfig
+  inventorySkew?: InventorySkewConfig
   basicMarketMakingConfig?: BasicMarketMakingConfig
   arbMarketMakingConfig?: ArbMarketMakingConfig
   simpleArbConfig?: SimpleArbConfig
+  strategyConfig?: StrategyConfig
+  triangleArbConfig?: TriangleArbConfig
+  crossDEXArbConfig?: CrossDEXArbConfig
+}
+
+export interface StrategyConfig {
+  name: string
+  config?: any
+  driftTolerance: number
+  maxBuyPlacements: number
+  maxSellPlacements: number
+}
+
+export interface TriangleArbConfig {
+  intermediateAssetID: number
+  minProfit: number
+  maxInFlightLots: number
+  numEpochsLeaveOpen: number
+}
+
+export interface CrossDEXArbConfig {
+  counterHost: string
+  profitTrigger: number
+  maxActiveArbs: number
+  numEpochsLeaveOpen: number
+  settlementTime: number
+  settlementRiskFactor: number
+}
+
+export interface InventorySkewConfig {
+  targetBaseRatio: number
+  riskAversion: number
+  spreadSkew: number
 }
 
 export interface CEXConfig {
   name: string
   apiKey: string
   apiSecret: string
+  apiPassphrase?: string
 }
 
 export interface MarketWithHost {
@@ -1006,6 +1050,16 @@ export interface EpochReport {
   preOrderProblems?: BotProblems
   buysReport?: OrderReport
   sellsReport?: OrderReport
+  inventorySkew?: InventorySkew
+}
+
+export interface InventorySkew {
+  baseRatio: number
+  targetBaseRatio: number
+  deviation: number
+  basisShift: number
+  buyGapMultiplier: number
+  sellGapMultiplier: number
 }
 
 export interface CEXProblems {
@@ -1022,9 +1076,26 @@ export interface MMBotStatus {
   cexProblems?: CEXProblems
 }
 
+export interface RiskConfig {
+  exposureCaps?: Record<number, number>
+  maxBotDrawdown: number
+  maxDrawdown: number
+}
+
+export interface RiskStatus {
+  config: RiskConfig
+  exposure: Record<number, number>
+  drawdown: number
+  killed?: {
+    stamp: number
+    error: string
+  }
+}
+
 export interface MarketMakingStatus {
   cexes: Record<string, MMCEXStatus>
   bots: MMBotStatus[]
+  risk: RiskStatus
 }
 
 export interface DEXOrderEvent {
@@ -1064,6 +1135,23 @@ export interface WithdrawalEvent {
   cexDebit: number
 }
 
+export interface BridgeEvent {
+  fromAssetID: number
+  toAssetID: number
+  cexAssetID: number
+  bridgeName: string
+  bridgeTx: WalletTransaction
+  cexDebit: number
+}
+
+export interface BotTransferEvent {
+  peer: MarketWithHost
+  assetID: number
+  cexAssetID: number
+  amount: number
+  toDEX: boolean
+}
+
 export interface BalanceEffects {
   settled: Record<number, number>
   pending: Record<number, number>
@@ -1080,6 +1168,8 @@ export interface MarketMakingEvent {
   cexOrderEvent?: CEXOrderEvent
   depositEvent?: DepositEvent
   withdrawalEvent?: WithdrawalEvent
+  bridgeEvent?: BridgeEvent
+  botTransferEvent?: BotTransferEvent
 }
 
 interface MarketDay {
@@ -113,6 +113,11 @@ type cancelForm struct {
 	OrderID dex.Bytes `json:"orderID"`
 }
 
+type amendForm struct {
+	Pass  encode.PassBytes `json:"pw"`
+	Amend *core.AmendForm  `json:"amend"`
+}
+
 // sendForm is sent to initiate either send tx.
 type sendForm struct {
 	AssetID  uint32           `json:"assetID"`
@@ -132,6 +132,20 @@ type clientCore interface {
 	Trade(pw []byte, form *core.TradeForm) (*core.Order, error)
 	TradeAsync(pw []byte, form *core.TradeForm) (*core.InFlightOrder, error)
 	Cancel(oid dex.Bytes) error
+	AmendOrder(pw []byte, form *core.AmendForm) (*core.Order, error)
+	TaxExport(form *core.TaxExportForm) (*core.TaxReport, error)
+	AddressBook(pw []byte) ([]*core.AddressBookEntry, error)
+	AddAddress(pw []byte, assetID uint32, address, label string) (*core.AddressBookEntry, error)
+	RemoveAddress(pw []byte, id dex.Bytes) error
+	Allowlist() (*core.AllowlistStatus, error)
+	SetAllowlist(pw []byte, enable bool) (*core.AllowlistStatus, error)
+	PlaceStopOrder(form *core.StopOrderForm) (*core.StopOrder, error)
+	CancelStopOrder(id dex.Bytes) error
+	StopOrders(activeOnly bool) ([]*core.StopOrder, error)
+	CreateScheduledOrder(form *core.ScheduledOrderForm) (*core.ScheduledOrder, error)
+	CancelScheduledOrder(id dex.Bytes) error
+	ScheduledOrder(id dex.Bytes) (*core.ScheduledOrder, error)
+	ScheduledOrders(activeOnly bool) ([]*core.ScheduledOrder, error)
 	NotificationFeed() *core.NoteFeed
 	Logout() error
 	Orders(*core.OrderFilter) ([]*core.Order, error)
@@ -194,10 +208,15 @@ type MMCore interface {
 	ArchivedRuns() ([]*mm.MarketMakingRun, error)
 	RunOverview(startTime int64, mkt *mm.MarketWithHost) (*mm.MarketMakingRunOverview, error)
 	RunLogs(startTime int64, mkt *mm.MarketWithHost, n uint64, refID *uint64, filter *mm.RunLogFilters) (events, updatedEvents []*mm.MarketMakingEvent, overview *mm.MarketMakingRunOverview, err error)
+	RunAnalytics(startTime int64, mkt *mm.MarketWithHost, window time.Duration) (*mm.RunAnalytics, error)
 	CEXBook(host string, baseID, quoteID uint32) (buys, sells []*core.MiniOrder, _ error)
 	UpdateRunningBotCfg(cfg *mm.BotConfig, balanceDiffs *mm.BotInventoryDiffs, autoRebalanceCfg *mm.AutoRebalanceConfig, saveUpdate bool) error
 	AvailableBalances(mkt *mm.MarketWithHost, cexBaseID, cexQuoteID uint32, cexName *string) (dexBalances, cexBalances map[uint32]uint64, _ error)
 	MaxFundingFees(mkt *mm.MarketWithHost, maxBuyPlacements, maxSellPlacements uint32, baseOptions, quoteOptions map[string]string) (buyFees, sellFees uint64, err error)
+	KillSwitch() error
+	ResetKillSwitch() error
+	RiskStatus() *mm.RiskStatus
+	UpdateRiskConfig(cfg *mm.RiskConfig) error
 }
 
 // genCertPair generates a key/cert pair to the paths provided.
@@ -559,6 +578,19 @@ func New(cfg *Config) (*WebServer, error) {
 			apiAuth.Post("/trade", s.apiTrade)
 			apiAuth.Post("/tradeasync", s.apiTradeAsync)
 			apiAuth.Post("/cancel", s.apiCancel)
+			apiAuth.Post("/amend", s.apiAmend)
+			apiAuth.Post("/taxexport", s.apiTaxExport)
+			apiAuth.Post("/addressbook", s.apiAddressBook)
+			apiAuth.Post("/addaddress", s.apiAddAddress)
+			apiAuth.Post("/removeaddress", s.apiRemoveAddress)
+			apiAuth.Post("/setallowlist", s.apiSetAllowlist)
+			apiAuth.Post("/stoporder", s.apiStopOrder)
+			apiAuth.Post("/cancelstoporder", s.apiCancelStopOrder)
+			apiAuth.Post("/stoporders", s.apiStopOrders)
+			apiAuth.Post("/scheduleorder", s.apiScheduleOrder)
+			apiAuth.Post("/cancelscheduledorder", s.apiCancelScheduledOrder)
+			apiAuth.Post("/scheduledorder", s.apiScheduledOrder)
+			apiAuth.Post("/scheduledorders", s.apiScheduledOrders)
 			apiAuth.Post("/logout", s.apiLogout)
 			apiAuth.Post("/balance", s.apiGetBalance)
 			apiAuth.Post("/parseconfig", s.apiParseConfig)
@@ -615,10 +647,15 @@ func New(cfg *Config) (*WebServer, error) {
 			apiAuth.Post("/updatecexconfig", s.apiUpdateCEXConfig)
 			apiAuth.Post("/removebotconfig", s.apiRemoveBotConfig)
 			apiAuth.Get("/marketmakingstatus", s.apiMarketMakingStatus)
+			apiAuth.Post("/mmkillswitch", s.apiKillSwitch)
+			apiAuth.Post("/mmresetkillswitch", s.apiResetKillSwitch)
+			apiAuth.Get("/mmriskstatus", s.apiRiskStatus)
+			apiAuth.Post("/updateriskconfig", s.apiUpdateRiskConfig)
 			apiAuth.Post("/marketreport", s.apiMarketReport)
 			apiAuth.Post("/cexbalance", s.apiCEXBalance)
 			apiAuth.Get("/archivedmmruns", s.apiArchivedRuns)
 			apiAuth.Post("/mmrunlogs", s.apiRunLogs)
+			apiAuth.Post("/mmanalytics", s.apiRunAnalytics)
 			apiAuth.Post("/cexbook", s.apiCEXBook)
 			apiAuth.Post("/availablebalances", s.apiAvailableBalances)
 			apiAuth.Post("/maxfundingfees", s.apiMaxFundingFees)
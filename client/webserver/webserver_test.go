@@ -220,6 +220,53 @@ func trade(form *core.TradeForm) *core.Order {
 	}
 }
 func (c *TCore) Cancel(oid dex.Bytes) error { return nil }
+func (c *TCore) AmendOrder(pw []byte, form *core.AmendForm) (*core.Order, error) {
+	if c.tradeErr != nil {
+		return nil, c.tradeErr
+	}
+	return &core.Order{Rate: form.Rate, Qty: form.Qty}, nil
+}
+func (c *TCore) TaxExport(form *core.TaxExportForm) (*core.TaxReport, error) {
+	if c.tradeErr != nil {
+		return nil, c.tradeErr
+	}
+	return &core.TaxReport{Start: form.Start, End: form.End, Method: form.Method}, nil
+}
+func (c *TCore) AddressBook(pw []byte) ([]*core.AddressBookEntry, error) {
+	return nil, nil
+}
+func (c *TCore) AddAddress(pw []byte, assetID uint32, address, label string) (*core.AddressBookEntry, error) {
+	if c.tradeErr != nil {
+		return nil, c.tradeErr
+	}
+	return &core.AddressBookEntry{AssetID: assetID, Address: address, Label: label}, nil
+}
+func (c *TCore) RemoveAddress(pw []byte, id dex.Bytes) error { return nil }
+func (c *TCore) Allowlist() (*core.AllowlistStatus, error) {
+	return &core.AllowlistStatus{}, nil
+}
+func (c *TCore) SetAllowlist(pw []byte, enable bool) (*core.AllowlistStatus, error) {
+	return &core.AllowlistStatus{Enabled: enable}, nil
+}
+func (c *TCore) PlaceStopOrder(form *core.StopOrderForm) (*core.StopOrder, error) {
+	return &core.StopOrder{}, nil
+}
+func (c *TCore) CancelStopOrder(id dex.Bytes) error { return nil }
+func (c *TCore) StopOrders(activeOnly bool) ([]*core.StopOrder, error) {
+	return nil, nil
+}
+func (c *TCore) CreateScheduledOrder(form *core.ScheduledOrderForm) (*core.ScheduledOrder, error) {
+	return &core.ScheduledOrder{}, nil
+}
+func (c *TCore) CancelScheduledOrder(id dex.Bytes) error {
+	return nil
+}
+func (c *TCore) ScheduledOrder(id dex.Bytes) (*core.ScheduledOrder, error) {
+	return &core.ScheduledOrder{}, nil
+}
+func (c *TCore) ScheduledOrders(activeOnly bool) ([]*core.ScheduledOrder, error) {
+	return nil, nil
+}
 
 func (c *TCore) NotificationFeed() *core.NoteFeed {
 	return &core.NoteFeed{
@@ -1023,6 +1070,82 @@ func testTrade(t *testing.T, async bool) {
 	ensure(`{"ok":false,"msg":"expected dummy error"}`)
 }
 
+func TestAPIAmend(t *testing.T) {
+	s, tCore, shutdown := newTServer(t, false)
+	defer shutdown()
+	writer := new(TWriter)
+	reader := new(TReader)
+
+	body := &amendForm{Pass: []byte("random")}
+	ensure := func(want string) {
+		t.Helper()
+		ensureResponse(t, s.apiAmend, want, reader, writer, body, nil)
+	}
+
+	ensure(`{"ok":false,"msg":"amendment missing"}`)
+
+	body.Amend = &core.AmendForm{OrderID: encode.RandomBytes(32), Rate: 5}
+	tCore.tradeErr = tErr
+	ensure(`{"ok":false,"msg":"expected dummy error"}`)
+}
+
+func TestAPITaxExport(t *testing.T) {
+	s, tCore, shutdown := newTServer(t, false)
+	defer shutdown()
+	writer := new(TWriter)
+	reader := new(TReader)
+
+	body := &struct {
+		*core.TaxExportForm
+		Format string `json:"format"`
+	}{
+		TaxExportForm: &core.TaxExportForm{Start: 1, End: 2, Method: core.CostBasisFIFO},
+		Format:        "xml",
+	}
+	ensure := func(want string) {
+		t.Helper()
+		ensureResponse(t, s.apiTaxExport, want, reader, writer, body, nil)
+	}
+
+	ensure(`{"ok":false,"msg":"unknown format \"xml\""}`)
+
+	body.Format = "json"
+	ensure(`{"ok":true,"report":{"start":1,"end":2,"method":"fifo","ledger":null,"disposals":null}}`)
+
+	tCore.tradeErr = tErr
+	ensure(`{"ok":false,"msg":"expected dummy error"}`)
+}
+
+func TestAPIAddAddress(t *testing.T) {
+	s, tCore, shutdown := newTServer(t, false)
+	defer shutdown()
+	writer := new(TWriter)
+	reader := new(TReader)
+
+	body := &struct {
+		Pass    string `json:"pass"`
+		AssetID uint32 `json:"assetID"`
+		Address string `json:"address"`
+		Label   string `json:"label"`
+	}{
+		AssetID: 42,
+		Address: "addr",
+		Label:   "cold",
+	}
+	ensure := func(want string) {
+		t.Helper()
+		ensureResponse(t, s.apiAddAddress, want, reader, writer, body, nil)
+	}
+
+	ensure(`{"ok":false,"msg":"empty password"}`)
+
+	body.Pass = "abc"
+	ensure(`{"ok":true,"entry":{"id":"","assetID":42,"symbol":"","address":"addr","label":"cold","stamp":0,"activeAt":0}}`)
+
+	tCore.tradeErr = tErr
+	ensure(`{"ok":false,"msg":"expected dummy error"}`)
+}
+
 func Test_prepareAddr(t *testing.T) {
 	tests := []struct {
 		name       string
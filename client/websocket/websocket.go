@@ -45,6 +45,10 @@ type wsClient struct {
 
 	feedMtx sync.RWMutex
 	feed    *bookFeed
+
+	// noteFilter limits the notifications sent to the client. nil if the
+	// client has not subscribed, in which case all notifications are sent.
+	noteFilter atomic.Pointer[noteFilter]
 }
 
 func newWSClient(addr string, conn ws.Connection, hndlr func(msg *msgjson.Message) *msgjson.Error, logger dex.Logger) *wsClient {
@@ -172,16 +176,24 @@ func (s *Server) connect(ctx context.Context, conn ws.Connection, addr string) {
 	s.log.Tracef("Disconnected websocket client %s", addr)
 }
 
-// Notify sends a notification to the websocket client.
+// Notify sends a notification to the websocket clients. If the payload is a
+// core.Notification, clients that have subscribed to a subset of notifications
+// only receive it if it matches their subscription.
 func (s *Server) Notify(route string, payload any) {
 	msg, err := msgjson.NewNotification(route, payload)
 	if err != nil {
 		s.log.Errorf("%q notification encoding error: %v", route, err)
 		return
 	}
+	note, _ := payload.(core.Notification)
 	s.clientsMtx.RLock()
 	defer s.clientsMtx.RUnlock()
 	for _, cl := range s.clients {
+		if note != nil {
+			if f := cl.noteFilter.Load(); f != nil && !f.matches(note) {
+				continue
+			}
+		}
 		if err = cl.Send(msg); err != nil {
 			s.log.Warnf("Failed to send %v notification to client %v at %v: %v",
 				msg.Route, cl.cid, cl.Addr(), err)
@@ -215,6 +227,7 @@ var wsHandlers = map[string]wsHandler{
 	"loadcandles": wsLoadCandles,
 	"unmarket":    wsUnmarket,
 	"acknotes":    wsAckNotes,
+	"subscribe":   wsSubscribe,
 }
 
 // marketLoad is sent by websocket clients to subscribe to a market and request
@@ -369,3 +382,60 @@ func wsAckNotes(s *Server, _ *wsClient, msg *msgjson.Message) *msgjson.Error {
 	s.core.AckNotes(ids)
 	return nil
 }
+
+// noteSubscription is sent by websocket clients to limit the notifications
+// they receive to the specified notification types and topics. An empty list
+// matches everything, so an empty subscription restores the full feed.
+type noteSubscription struct {
+	Types  []string `json:"types"`
+	Topics []string `json:"topics"`
+}
+
+// noteFilter is a client's notification subscription.
+type noteFilter struct {
+	types  map[string]bool
+	topics map[core.Topic]bool
+}
+
+// matches checks whether the notification matches the subscribed types and
+// topics.
+func (f *noteFilter) matches(n core.Notification) bool {
+	if len(f.types) > 0 && !f.types[n.Type()] {
+		return false
+	}
+	return len(f.topics) == 0 || f.topics[n.Topic()]
+}
+
+// wsSubscribe is the handler for the 'subscribe' websocket route. It replaces
+// the client's notification subscription, and responds with the subscription.
+func wsSubscribe(s *Server, cl *wsClient, msg *msgjson.Message) *msgjson.Error {
+	req := new(noteSubscription)
+	if len(msg.Payload) > 0 {
+		if err := json.Unmarshal(msg.Payload, req); err != nil {
+			return msgjson.NewError(msgjson.RPCParseError, "error unmarshalling subscribe payload: %v", err)
+		}
+	}
+	if len(req.Types) == 0 && len(req.Topics) == 0 {
+		cl.noteFilter.Store(nil)
+	} else {
+		f := &noteFilter{
+			types:  make(map[string]bool, len(req.Types)),
+			topics: make(map[core.Topic]bool, len(req.Topics)),
+		}
+		for _, t := range req.Types {
+			f.types[t] = true
+		}
+		for _, t := range req.Topics {
+			f.topics[core.Topic(t)] = true
+		}
+		cl.noteFilter.Store(f)
+	}
+	resp, err := msgjson.NewResponse(msg.ID, req, nil)
+	if err != nil {
+		return msgjson.NewError(msgjson.RPCInternal, "error encoding subscribe response: %v", err)
+	}
+	if err := cl.Send(resp); err != nil {
+		s.log.Warnf("Failed to send subscribe response to client %v at %v: %v", cl.cid, cl.Addr(), err)
+	}
+	return nil
+}
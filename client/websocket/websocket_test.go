@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"decred.org/dcrdex/client/core"
+	"decred.org/dcrdex/client/db"
 	"decred.org/dcrdex/client/orderbook"
 	"decred.org/dcrdex/dex"
 	"decred.org/dcrdex/dex/msgjson"
@@ -302,3 +303,102 @@ func TestClientMap(t *testing.T) {
 		t.Fatal("connection not closed on server shutdown")
 	}
 }
+
+func TestSubscribe(t *testing.T) {
+	srv, _ := newTServer()
+	link := newLink()
+	// Buffer the writes so that a notification and the sentinel that follows
+	// it are both captured.
+	link.conn.respReady = make(chan []byte, 8)
+	linkWg, err := link.cl.Connect(tCtx)
+	if err != nil {
+		t.Fatalf("WSLink Start: %v", err)
+	}
+	defer func() {
+		link.cl.Disconnect()
+		linkWg.Wait()
+	}()
+	srv.clients[link.cl.cid] = link.cl
+
+	newNote := func(noteType string, topic core.Topic) core.Notification {
+		n := db.NewNotification(noteType, topic, "", "", db.Data)
+		return &n
+	}
+	orderNote := newNote(core.NoteTypeOrder, core.TopicOrderBooked)
+	balanceNote := newNote(core.NoteTypeBalance, core.TopicBalanceUpdated)
+
+	nextMsg := func() *msgjson.Message {
+		t.Helper()
+		select {
+		case b := <-link.conn.respReady:
+			msg := new(msgjson.Message)
+			if err := json.Unmarshal(b, msg); err != nil {
+				t.Fatalf("bad message: %s", b)
+			}
+			return msg
+		case <-time.After(time.Second):
+			t.Fatalf("no message received")
+		}
+		return nil
+	}
+	// checkNotified follows the notification with a sentinel that is never
+	// filtered. Messages are written in order, so a filtered notification
+	// is detected by the sentinel arriving first.
+	checkNotified := func(n core.Notification, want bool) {
+		t.Helper()
+		srv.Notify("notify", n)
+		srv.Notify("sentinel", "payload")
+		msg := nextMsg()
+		if msg.Route == "notify" {
+			if !want {
+				t.Fatalf("unexpected %s notification", n.Type())
+			}
+			if msg.Type != msgjson.Notification {
+				t.Fatalf("bad notification message type %d", msg.Type)
+			}
+			msg = nextMsg()
+		} else if want {
+			t.Fatalf("no %s notification", n.Type())
+		}
+		if msg.Route != "sentinel" {
+			t.Fatalf("expected sentinel, got %q", msg.Route)
+		}
+	}
+	subscribe := func(sub *noteSubscription) {
+		t.Helper()
+		req, _ := msgjson.NewRequest(1, "subscribe", sub)
+		if msgErr := srv.handleMessage(link.cl, req); msgErr != nil {
+			t.Fatalf("'subscribe' error: %d: %s", msgErr.Code, msgErr.Message)
+		}
+		if msg := nextMsg(); msg.Type != msgjson.Response {
+			t.Fatalf("bad subscribe response type %d", msg.Type)
+		}
+	}
+
+	// Everything is sent before subscribing.
+	checkNotified(orderNote, true)
+	checkNotified(balanceNote, true)
+
+	subscribe(&noteSubscription{Types: []string{core.NoteTypeOrder}})
+	checkNotified(orderNote, true)
+	checkNotified(balanceNote, false)
+
+	subscribe(&noteSubscription{Topics: []string{string(core.TopicBalanceUpdated)}})
+	checkNotified(orderNote, false)
+	checkNotified(balanceNote, true)
+
+	subscribe(&noteSubscription{Types: []string{core.NoteTypeOrder}, Topics: []string{string(core.TopicBalanceUpdated)}})
+	checkNotified(orderNote, false)
+	checkNotified(balanceNote, false)
+
+	// Payloads that are not core notifications are not filtered.
+	srv.Notify("other", "payload")
+	if msg := nextMsg(); msg.Route != "other" {
+		t.Fatalf("expected unfiltered notification, got %q", msg.Route)
+	}
+
+	// An empty subscription restores the full feed.
+	subscribe(&noteSubscription{})
+	checkNotified(orderNote, true)
+	checkNotified(balanceNote, true)
+}
@@ -99,6 +99,15 @@ const (
 	RPCUpdateRunningBotInvError          // 81
 	RPCMMStatusError                     // 82
 	RPCBridgeError                       // 83
+	RPCStopOrderError                    // 84
+	RPCScheduledOrderError               // 85
+	RPCMMAnalyticsError                  // 86
+	RPCAmendOrderError                   // 87
+	RPCTaxExportError                    // 88
+	RPCAddressBookError                  // 89
+	RPCAPIKeyError                       // 90
+	RPCPermissionError                   // 91
+	RPCMMRiskError                       // 92
 )
 
 // Routes are destinations for a "payload" of data. The type of data being
@@ -210,6 +219,10 @@ const (
 	// client of an upcoming trade resumption. This is part of the
 	// subscription-based orderbook notification feed.
 	ResumptionRoute = "resumption"
+	// MarketParamsRoute is the DEX-originating notification-type message
+	// informing the client of an upcoming change to a market's lot size and
+	// rate step.
+	MarketParamsRoute = "market_params"
 	// NotifyRoute is the DEX-originating notification-type message
 	// delivering text messages from the operator.
 	NotifyRoute = "notify"
@@ -917,6 +930,17 @@ type TradeResumption struct {
 	// TODO: ConfigChange bool or entire Config Market here.
 }
 
+// MarketParams is the MarketParamsRoute notification payload. The new lot size
+// and rate step apply to orders in StartEpoch and later. Booked orders that
+// are incompatible with them are revoked before StartEpoch is matched.
+type MarketParams struct {
+	MarketID   string `json:"marketid"`
+	StartEpoch uint64 `json:"startepoch"`
+	StartTime  uint64 `json:"starttime"`
+	LotSize    uint64 `json:"lotsize"`
+	RateStep   uint64 `json:"ratestep"`
+}
+
 // PreimageRequest is the server-originating preimage request payload.
 type PreimageRequest struct {
 	OrderID        Bytes `json:"orderid"`
@@ -432,6 +432,165 @@ func (s *Server) apiSuspend(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// handler for route '/addmarket?base=SYMBOL&quote=SYMBOL&lotsize=N&ratestep=N'
+// with the optional epochlen (ms), mbbuffer, and parcelsize queries.
+func (s *Server) apiAddMarket(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	base, quote := q.Get("base"), q.Get("quote")
+	if base == "" || quote == "" {
+		http.Error(w, "base and quote asset symbols are required", http.StatusBadRequest)
+		return
+	}
+
+	parseUint := func(key string, required bool) (uint64, bool) {
+		str := q.Get(key)
+		if str == "" {
+			if required {
+				http.Error(w, fmt.Sprintf("%s is required", key), http.StatusBadRequest)
+				return 0, false
+			}
+			return 0, true
+		}
+		v, err := strconv.ParseUint(str, 10, 64)
+		if err != nil || (required && v == 0) {
+			http.Error(w, fmt.Sprintf("invalid %s %q", key, str), http.StatusBadRequest)
+			return 0, false
+		}
+		return v, true
+	}
+	lotSize, ok := parseUint("lotsize", true)
+	if !ok {
+		return
+	}
+	rateStep, ok := parseUint("ratestep", true)
+	if !ok {
+		return
+	}
+	epochLen, ok := parseUint("epochlen", false)
+	if !ok {
+		return
+	}
+	parcelSize, ok := parseUint("parcelsize", false)
+	if !ok {
+		return
+	}
+	if parcelSize == 0 {
+		parcelSize = 1
+	}
+	if parcelSize > math.MaxUint32 {
+		http.Error(w, fmt.Sprintf("parcel size %d too large", parcelSize), http.StatusBadRequest)
+		return
+	}
+	var mbBuffer float64
+	if mbBufferStr := q.Get("mbbuffer"); mbBufferStr != "" {
+		var err error
+		mbBuffer, err = strconv.ParseFloat(mbBufferStr, 64)
+		if err != nil || mbBuffer < 0 {
+			http.Error(w, fmt.Sprintf("invalid market buy buffer %q", mbBufferStr), http.StatusBadRequest)
+			return
+		}
+	}
+
+	mktInf, err := dex.NewMarketInfoFromSymbols(base, quote, lotSize, rateStep, epochLen, uint32(parcelSize), mbBuffer)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	startEpoch, startTime, err := s.core.AddMarket(mktInf)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to add market: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, &AddMarketResult{
+		Market:     mktInf.Name,
+		StartEpoch: startEpoch,
+		StartTime:  APITime{startTime},
+	})
+}
+
+// handler for route '/market/{marketName}/retire'
+func (s *Server) apiRetire(w http.ResponseWriter, r *http.Request) {
+	mkt := strings.ToLower(chi.URLParam(r, marketNameKey))
+	if found, _ := s.core.MarketRunning(mkt); !found {
+		http.Error(w, fmt.Sprintf("unknown market %q", mkt), http.StatusBadRequest)
+		return
+	}
+
+	suspEpoch, err := s.core.RetireMarket(mkt)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to retire market: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, &SuspendResult{
+		Market:      mkt,
+		FinalEpoch:  suspEpoch.Idx,
+		SuspendTime: APITime{suspEpoch.End},
+	})
+}
+
+// handler for route '/market/{marketName}/params?lotsize=N&ratestep=N' with
+// the optional t query for the earliest time (ms) of the change.
+func (s *Server) apiMarketParams(w http.ResponseWriter, r *http.Request) {
+	mkt := strings.ToLower(chi.URLParam(r, marketNameKey))
+	if found, _ := s.core.MarketRunning(mkt); !found {
+		http.Error(w, fmt.Sprintf("unknown market %q", mkt), http.StatusBadRequest)
+		return
+	}
+
+	q := r.URL.Query()
+	parseUint := func(key string) (uint64, bool) {
+		str := q.Get(key)
+		v, err := strconv.ParseUint(str, 10, 64)
+		if err != nil || v == 0 {
+			http.Error(w, fmt.Sprintf("invalid %s %q", key, str), http.StatusBadRequest)
+			return 0, false
+		}
+		return v, true
+	}
+	lotSize, ok := parseUint("lotsize")
+	if !ok {
+		return
+	}
+	rateStep, ok := parseUint("ratestep")
+	if !ok {
+		return
+	}
+
+	// If the time is not specified, the zero time.Time is used to indicate
+	// ASAP.
+	var asSoonAs time.Time
+	if tStr := q.Get("t"); tStr != "" {
+		tMs, err := strconv.ParseInt(tStr, 10, 64)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid change time %q: %v", tStr, err), http.StatusBadRequest)
+			return
+		}
+		asSoonAs = time.UnixMilli(tMs)
+		if time.Until(asSoonAs) < 0 {
+			http.Error(w, fmt.Sprintf("specified change time is in the past: %v", asSoonAs),
+				http.StatusBadRequest)
+			return
+		}
+	}
+
+	change, err := s.core.ScheduleMarketParams(mkt, asSoonAs, lotSize, rateStep)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("failed to schedule market parameter change: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	writeJSON(w, &MarketParamsResult{
+		Market:     mkt,
+		StartEpoch: change.Epoch,
+		StartTime:  APITime{change.Start},
+		LotSize:    change.LotSize,
+		RateStep:   change.RateStep,
+	})
+}
+
 // apiEnableDataAPI is the handler for the `/enabledataapi/{yes}` API request,
 // used to enable or disable the HTTP data API.
 func (s *Server) apiEnableDataAPI(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,117 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package admin
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"decred.org/dcrdex/dex/msgjson"
+	"decred.org/dcrdex/dex/ws"
+	"decred.org/dcrdex/server/account"
+	dexsrv "decred.org/dcrdex/server/dex"
+)
+
+const (
+	// eventRoute is the route of the notifications sent on the event feed.
+	eventRoute = "event"
+	// eventsPingPeriod is how often the event feed pings the client, and
+	// eventsPongWait is how long to wait for a pong before disconnecting.
+	eventsPingPeriod = 20 * time.Second
+	eventsPongWait   = 2 * eventsPingPeriod
+)
+
+// eventFilter selects the events sent on an event feed. Empty fields match
+// all events.
+type eventFilter struct {
+	market string
+	acct   *account.AccountID
+}
+
+// match checks whether the event passes the filter. Events that are not
+// specific to a market, such as penalties, pass a market filter, and events
+// that are not specific to an account, such as suspensions, pass an account
+// filter.
+func (f *eventFilter) match(ev *dexsrv.Event) bool {
+	if f.market != "" && ev.Market != "" && ev.Market != f.market {
+		return false
+	}
+	if f.acct != nil && len(ev.Accounts) > 0 && !ev.Involves(*f.acct) {
+		return false
+	}
+	return true
+}
+
+// apiEvents is the handler for the '/events' API request. The connection is
+// upgraded to a websocket connection on which each event is sent as an
+// 'event' notification. The events may be filtered with the market and
+// account URL query parameters.
+func (s *Server) apiEvents(w http.ResponseWriter, r *http.Request) {
+	var filter eventFilter
+	if mkt := strings.ToLower(r.URL.Query().Get(marketNameKey)); mkt != "" {
+		if s.core.MarketStatus(mkt) == nil {
+			http.Error(w, fmt.Sprintf("unknown market %q", mkt), http.StatusBadRequest)
+			return
+		}
+		filter.market = mkt
+	}
+	if acctIDStr := r.URL.Query().Get(accountIDKey); acctIDStr != "" {
+		acctID, err := decodeAcctID(acctIDStr)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		filter.acct = &acctID
+	}
+
+	conn, err := ws.NewConnection(w, r, eventsPongWait)
+	if err != nil {
+		log.Errorf("Event feed websocket connection error: %v", err)
+		return
+	}
+
+	s.wg.Add(1)
+	defer s.wg.Done()
+
+	link := ws.NewWSLink(r.RemoteAddr, conn, eventsPingPeriod, nil, log)
+	link.RawHandler = func([]byte) {} // the feed is one-way
+	linkWG, err := link.Connect(s.ctx)
+	if err != nil {
+		log.Errorf("Event feed connection error: %v", err)
+		conn.Close()
+		return
+	}
+	defer linkWG.Wait()
+	defer link.Disconnect()
+
+	events, unsubscribe := s.core.SubscribeEvents()
+	defer unsubscribe()
+
+	log.Infof("Event feed connected for %s", r.RemoteAddr)
+	for {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				return
+			}
+			if !filter.match(ev) {
+				continue
+			}
+			note, err := msgjson.NewNotification(eventRoute, ev)
+			if err != nil {
+				log.Errorf("Error encoding %s event: %v", ev.Type, err)
+				continue
+			}
+			if err := link.Send(note); err != nil {
+				log.Debugf("Event feed for %s disconnected: %v", r.RemoteAddr, err)
+				return
+			}
+		case <-link.Done():
+			log.Infof("Event feed disconnected for %s", r.RemoteAddr)
+			return
+		}
+	}
+}
@@ -0,0 +1,155 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package admin
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"decred.org/dcrdex/dex/order"
+	"decred.org/dcrdex/server/account"
+	dexsrv "decred.org/dcrdex/server/dex"
+)
+
+// metricsContentType is the content type of the Prometheus text exposition
+// format.
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// metricsWriter writes metrics in the Prometheus text exposition format.
+type metricsWriter struct {
+	bytes.Buffer
+}
+
+// header writes the HELP and TYPE lines for a metric.
+func (mw *metricsWriter) header(name, typ, help string) {
+	fmt.Fprintf(mw, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+// sample writes a single sample. labels are alternating label names and
+// values.
+func (mw *metricsWriter) sample(name string, v any, labels ...string) {
+	mw.WriteString(name)
+	if len(labels) > 0 {
+		mw.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				mw.WriteByte(',')
+			}
+			fmt.Fprintf(mw, "%s=%q", labels[i], labels[i+1])
+		}
+		mw.WriteByte('}')
+	}
+	fmt.Fprintf(mw, " %v\n", v)
+}
+
+func boolMetric(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// writeMetrics writes the DEX metrics in the Prometheus text exposition
+// format.
+func writeMetrics(mw *metricsWriter, metrics *dexsrv.Metrics) {
+	mkts := metrics.Markets
+	sort.Slice(mkts, func(i, j int) bool { return mkts[i].Name < mkts[j].Name })
+	assets := metrics.Assets
+	sort.Slice(assets, func(i, j int) bool { return assets[i].ID < assets[j].ID })
+
+	const (
+		mktLabel   = "market"
+		sideLabel  = "side"
+		rangeLabel = "range"
+		assetLabel = "asset"
+	)
+
+	mw.header("dcrdex_market_running", "gauge", "Whether the market is accepting orders.")
+	for _, mkt := range mkts {
+		mw.sample("dcrdex_market_running", boolMetric(mkt.Running), mktLabel, mkt.Name)
+	}
+	mw.header("dcrdex_market_epochs_total", "counter", "Number of epochs processed since startup.")
+	for _, mkt := range mkts {
+		mw.sample("dcrdex_market_epochs_total", mkt.Epochs, mktLabel, mkt.Name)
+	}
+	mw.header("dcrdex_market_last_epoch", "gauge", "Index of the last processed epoch.")
+	for _, mkt := range mkts {
+		mw.sample("dcrdex_market_last_epoch", mkt.LastEpoch, mktLabel, mkt.Name)
+	}
+	mw.header("dcrdex_market_last_epoch_timestamp_seconds", "gauge", "Time that the last epoch was matched.")
+	for _, mkt := range mkts {
+		var stamp int64
+		if !mkt.LastEpochTime.IsZero() {
+			stamp = mkt.LastEpochTime.Unix()
+		}
+		mw.sample("dcrdex_market_last_epoch_timestamp_seconds", stamp, mktLabel, mkt.Name)
+	}
+	mw.header("dcrdex_market_matches_total", "counter", "Number of trade matches since startup.")
+	for _, mkt := range mkts {
+		mw.sample("dcrdex_market_matches_total", mkt.Matches, mktLabel, mkt.Name)
+	}
+	mw.header("dcrdex_market_epoch_matches", "gauge", "Number of trade matches in the last processed epoch.")
+	for _, mkt := range mkts {
+		mw.sample("dcrdex_market_epoch_matches", mkt.LastEpochMatches, mktLabel, mkt.Name)
+	}
+	mw.header("dcrdex_market_book_orders", "gauge", "Number of standing orders on the book.")
+	for _, mkt := range mkts {
+		mw.sample("dcrdex_market_book_orders", mkt.BookBuyOrders, mktLabel, mkt.Name, sideLabel, "buy")
+		mw.sample("dcrdex_market_book_orders", mkt.BookSellOrders, mktLabel, mkt.Name, sideLabel, "sell")
+	}
+	mw.header("dcrdex_market_book_depth", "gauge", "Remaining quantity of booked orders in atoms of the base asset, "+
+		"in total and within 5% and 25% of the mid-gap rate.")
+	for _, mkt := range mkts {
+		mw.sample("dcrdex_market_book_depth", mkt.BookBuys, mktLabel, mkt.Name, sideLabel, "buy", rangeLabel, "all")
+		mw.sample("dcrdex_market_book_depth", mkt.BookBuys25, mktLabel, mkt.Name, sideLabel, "buy", rangeLabel, "25pct")
+		mw.sample("dcrdex_market_book_depth", mkt.BookBuys5, mktLabel, mkt.Name, sideLabel, "buy", rangeLabel, "5pct")
+		mw.sample("dcrdex_market_book_depth", mkt.BookSells, mktLabel, mkt.Name, sideLabel, "sell", rangeLabel, "all")
+		mw.sample("dcrdex_market_book_depth", mkt.BookSells25, mktLabel, mkt.Name, sideLabel, "sell", rangeLabel, "25pct")
+		mw.sample("dcrdex_market_book_depth", mkt.BookSells5, mktLabel, mkt.Name, sideLabel, "sell", rangeLabel, "5pct")
+	}
+
+	mw.header("dcrdex_swaps_active", "gauge", "Number of active swaps by match status.")
+	for status := order.NewlyMatched; status <= order.MatchComplete; status++ {
+		mw.sample("dcrdex_swaps_active", metrics.Swaps[status], "status", status.String())
+	}
+
+	mw.header("dcrdex_clients_connected", "gauge", "Number of connected websocket clients.")
+	mw.sample("dcrdex_clients_connected", metrics.Clients)
+
+	mw.header("dcrdex_penalties_total", "counter", "Number of account penalties since startup by rule.")
+	for rule := account.NoRule + 1; rule < account.MaxRule; rule++ {
+		mw.sample("dcrdex_penalties_total", metrics.Penalties[rule], "rule", rule.String())
+	}
+
+	mw.header("dcrdex_asset_synced", "gauge", "Whether the asset backend reports that it is synced.")
+	for _, a := range assets {
+		mw.sample("dcrdex_asset_synced", boolMetric(a.Synced), assetLabel, a.Symbol)
+	}
+	mw.header("dcrdex_asset_rpc_up", "gauge", "Whether the last asset backend RPC request succeeded.")
+	for _, a := range assets {
+		mw.sample("dcrdex_asset_rpc_up", boolMetric(!a.SyncErr), assetLabel, a.Symbol)
+	}
+	mw.header("dcrdex_asset_rpc_latency_seconds", "gauge", "Duration of the asset backend sync status RPC request.")
+	for _, a := range assets {
+		mw.sample("dcrdex_asset_rpc_latency_seconds", a.RPCLatency.Seconds(), assetLabel, a.Symbol)
+	}
+	mw.header("dcrdex_asset_height", "gauge", "Best block height known to the asset backend.")
+	for _, a := range assets {
+		mw.sample("dcrdex_asset_height", a.Height, assetLabel, a.Symbol)
+	}
+}
+
+// apiMetrics is the handler for the '/metrics' request. The response is in the
+// Prometheus text exposition format.
+func (s *Server) apiMetrics(w http.ResponseWriter, _ *http.Request) {
+	var mw metricsWriter
+	writeMetrics(&mw, s.core.Metrics())
+	w.Header().Set("Content-Type", metricsContentType)
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(mw.Bytes()); err != nil {
+		log.Errorf("Write error: %v", err)
+	}
+}
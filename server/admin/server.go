@@ -27,7 +27,6 @@ import (
 	"decred.org/dcrdex/server/db"
 	dexsrv "decred.org/dcrdex/server/dex"
 	"decred.org/dcrdex/server/market"
-	"github.com/decred/slog"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 )
@@ -52,7 +51,7 @@ const (
 )
 
 var (
-	log slog.Logger
+	log dex.Logger
 )
 
 // SvrCore is satisfied by server/dex.DEX.
@@ -70,6 +69,9 @@ type SvrCore interface {
 	MarketStatuses() map[string]*market.Status
 	SuspendMarket(name string, tSusp time.Time, persistBooks bool) (*market.SuspendEpoch, error)
 	ResumeMarket(name string, asSoonAs time.Time) (startEpoch int64, startTime time.Time, err error)
+	AddMarket(mktInf *dex.MarketInfo) (startEpoch int64, startTime time.Time, err error)
+	RetireMarket(name string) (*market.SuspendEpoch, error)
+	ScheduleMarketParams(name string, asSoonAs time.Time, lotSize, rateStep uint64) (*market.ParamChange, error)
 	ForgiveMatchFail(aid account.AccountID, mid order.MatchID) (forgiven, unbanned bool, err error)
 	AccountMatchOutcomesN(user account.AccountID, n int) ([]*auth.MatchOutcome, error)
 	BookOrders(base, quote uint32) (orders []*order.LimitOrder, err error)
@@ -78,6 +80,8 @@ type SvrCore interface {
 	EnableDataAPI(yes bool)
 	CreatePrepaidBonds(n int, strength uint32, durSecs int64) ([][]byte, error)
 	ForgiveUser(user account.AccountID) error
+	Metrics() *dexsrv.Metrics
+	SubscribeEvents() (<-chan *dexsrv.Event, func())
 }
 
 // Server is a multi-client https server.
@@ -87,6 +91,11 @@ type Server struct {
 	tlsConfig *tls.Config
 	srv       *http.Server
 	authSHA   [32]byte
+
+	// ctx is the Run context, which ends the event feed connections that
+	// are not tracked by the http.Server. wg waits for them.
+	ctx context.Context
+	wg  sync.WaitGroup
 }
 
 // SrvConfig holds variables needed to create a new Server.
@@ -95,10 +104,13 @@ type SrvConfig struct {
 	Addr, Cert, Key string
 	AuthSHA         [32]byte
 	NoTLS           bool
+	// Metrics enables the /metrics endpoint, which serves metrics in the
+	// Prometheus text exposition format.
+	Metrics bool
 }
 
 // UseLogger sets the logger for the admin package.
-func UseLogger(logger slog.Logger) {
+func UseLogger(logger dex.Logger) {
 	log = logger
 }
 
@@ -166,6 +178,7 @@ func NewServer(cfg *SrvConfig) (*Server, error) {
 		})
 		r.Post("/notifyall", s.apiNotifyAll)
 		r.Get("/markets", s.apiMarkets)
+		r.Get("/addmarket", s.apiAddMarket)
 		r.Route("/market/{"+marketNameKey+"}", func(rm chi.Router) {
 			rm.Get("/", s.apiMarketInfo)
 			rm.Get("/orderbook", s.apiMarketOrderBook)
@@ -173,15 +186,24 @@ func NewServer(cfg *SrvConfig) (*Server, error) {
 			rm.Get("/matches", s.apiMarketMatches)
 			rm.Get("/suspend", s.apiSuspend)
 			rm.Get("/resume", s.apiResume)
+			rm.Get("/retire", s.apiRetire)
+			rm.Get("/params", s.apiMarketParams)
 		})
 		r.Get("/prepaybonds", s.prepayBonds)
+		r.Get("/events", s.apiEvents)
 	})
 
+	if cfg.Metrics {
+		mux.Get("/metrics", s.apiMetrics)
+	}
+
 	return s, nil
 }
 
 // Run starts the server.
 func (s *Server) Run(ctx context.Context) {
+	s.ctx = ctx
+
 	// Create listener.
 	var listener net.Listener
 	var err error
@@ -212,8 +234,9 @@ func (s *Server) Run(ctx context.Context) {
 		log.Warnf("unexpected (http.Server).Serve error: %v", err)
 	}
 
-	// Wait for Shutdown.
+	// Wait for Shutdown and the event feeds.
 	wg.Wait()
+	s.wg.Wait()
 	log.Infof("admin server off")
 }
 
@@ -17,6 +17,7 @@ import (
 	"os"
 	"path/filepath"
 	"reflect"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -35,11 +36,11 @@ import (
 	"github.com/decred/dcrd/certgen"
 	"github.com/decred/slog"
 	"github.com/go-chi/chi/v5"
+	"github.com/gorilla/websocket"
 )
 
 func init() {
-	log = slog.NewBackend(os.Stdout).Logger("TEST")
-	log.SetLevel(slog.LevelTrace)
+	log = dex.StdOutLogger("TEST", slog.LevelTrace)
 }
 
 type TMarket struct {
@@ -51,6 +52,7 @@ type TMarket struct {
 	resumeEpoch int64
 	resumeTime  time.Time
 	persist     bool
+	paramChange *market.ParamChange
 }
 
 type TCore struct {
@@ -68,6 +70,8 @@ type TCore struct {
 	marketMatches    []*dexsrv.MatchData
 	marketMatchesErr error
 	dataEnabled      uint32
+	metrics          *dexsrv.Metrics
+	events           chan *dexsrv.Event
 }
 
 func (c *TCore) ConfigMsg() json.RawMessage { return nil }
@@ -85,6 +89,44 @@ func (c *TCore) ResumeMarket(name string, tRes time.Time) (startEpoch int64, sta
 	tMkt.resumeTime = time.UnixMilli(tMkt.resumeEpoch * int64(tMkt.dur))
 	return tMkt.resumeEpoch, tMkt.resumeTime, nil
 }
+func (c *TCore) AddMarket(mktInf *dex.MarketInfo) (startEpoch int64, startTime time.Time, err error) {
+	if c.markets[mktInf.Name] != nil {
+		err = fmt.Errorf("market %s already exists", mktInf.Name)
+		return
+	}
+	tMkt := &TMarket{
+		dur:        mktInf.EpochDuration,
+		startEpoch: 1 + time.Now().UnixMilli()/int64(mktInf.EpochDuration),
+		suspend:    &market.SuspendEpoch{},
+	}
+	c.markets[mktInf.Name] = tMkt
+	return tMkt.startEpoch, time.UnixMilli(tMkt.startEpoch * int64(tMkt.dur)), nil
+}
+func (c *TCore) RetireMarket(name string) (*market.SuspendEpoch, error) {
+	tMkt := c.markets[name]
+	if tMkt == nil {
+		return nil, fmt.Errorf("unknown market %s", name)
+	}
+	tMkt.running = false
+	return tMkt.suspend, nil
+}
+func (c *TCore) ScheduleMarketParams(name string, asSoonAs time.Time, lotSize, rateStep uint64) (*market.ParamChange, error) {
+	tMkt := c.markets[name]
+	if tMkt == nil {
+		return nil, fmt.Errorf("unknown market %s", name)
+	}
+	epoch := 2 + time.Now().UnixMilli()/int64(tMkt.dur)
+	if !asSoonAs.IsZero() {
+		epoch = max(epoch, 1+asSoonAs.UnixMilli()/int64(tMkt.dur))
+	}
+	tMkt.paramChange = &market.ParamChange{
+		Epoch:    epoch,
+		Start:    time.UnixMilli(epoch * int64(tMkt.dur)),
+		LotSize:  lotSize,
+		RateStep: rateStep,
+	}
+	return tMkt.paramChange, nil
+}
 func (c *TCore) SuspendMarket(name string, tSusp time.Time, persistBooks bool) (suspEpoch *market.SuspendEpoch, err error) {
 	tMkt := c.markets[name]
 	if tMkt == nil {
@@ -223,6 +265,10 @@ func (c *TCore) AccountMatchOutcomesN(user account.AccountID, n int) ([]*auth.Ma
 func (c *TCore) Notify(_ account.AccountID, _ *msgjson.Message) {}
 func (c *TCore) NotifyAll(_ *msgjson.Message)                   {}
 func (c *TCore) ForgiveUser(account.AccountID) error            { return nil }
+func (c *TCore) Metrics() *dexsrv.Metrics                       { return c.metrics }
+func (c *TCore) SubscribeEvents() (<-chan *dexsrv.Event, func()) {
+	return c.events, func() {}
+}
 
 // genCertPair generates a key/cert pair to the paths provided.
 func genCertPair(certFile, keyFile string) error {
@@ -851,6 +897,215 @@ func TestResume(t *testing.T) {
 	}
 }
 
+func TestAddMarket(t *testing.T) {
+	core := &TCore{
+		markets: make(map[string]*TMarket),
+	}
+	srv := &Server{
+		core: core,
+	}
+
+	mux := chi.NewRouter()
+	mux.Get("/addmarket", srv.apiAddMarket)
+
+	tests := []struct {
+		name, query string
+		wantCode    int
+	}{{
+		name:     "no quote",
+		query:    "base=dcr&lotsize=100000000&ratestep=1000",
+		wantCode: http.StatusBadRequest,
+	}, {
+		name:     "no lot size",
+		query:    "base=dcr&quote=btc&ratestep=1000",
+		wantCode: http.StatusBadRequest,
+	}, {
+		name:     "zero rate step",
+		query:    "base=dcr&quote=btc&lotsize=100000000&ratestep=0",
+		wantCode: http.StatusBadRequest,
+	}, {
+		name:     "bad market buy buffer",
+		query:    "base=dcr&quote=btc&lotsize=100000000&ratestep=1000&mbbuffer=x",
+		wantCode: http.StatusBadRequest,
+	}, {
+		name:     "unknown asset",
+		query:    "base=dcr&quote=xyz&lotsize=100000000&ratestep=1000",
+		wantCode: http.StatusBadRequest,
+	}, {
+		name:     "ok",
+		query:    "base=DCR&quote=btc&lotsize=100000000&ratestep=1000&epochlen=6000&mbbuffer=1.5&parcelsize=2",
+		wantCode: http.StatusOK,
+	}, {
+		name:     "already exists",
+		query:    "base=dcr&quote=btc&lotsize=100000000&ratestep=1000",
+		wantCode: http.StatusBadRequest,
+	}}
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest(http.MethodGet, "https://localhost/addmarket?"+test.query, nil)
+		r.RemoteAddr = "localhost"
+
+		mux.ServeHTTP(w, r)
+
+		if w.Code != test.wantCode {
+			t.Fatalf("%s: apiAddMarket returned code %d, expected %d", test.name, w.Code, test.wantCode)
+		}
+		if w.Code != http.StatusOK {
+			continue
+		}
+		res := new(AddMarketResult)
+		if err := json.Unmarshal(w.Body.Bytes(), res); err != nil {
+			t.Fatalf("%s: failed to unmarshal result: %v", test.name, err)
+		}
+		if res.Market != "dcr_btc" {
+			t.Errorf("%s: incorrect market name %q", test.name, res.Market)
+		}
+		if res.StartEpoch == 0 || res.StartTime.IsZero() {
+			t.Errorf("%s: start epoch or time not set", test.name)
+		}
+	}
+}
+
+func TestRetire(t *testing.T) {
+	core := &TCore{
+		markets: make(map[string]*TMarket),
+	}
+	srv := &Server{
+		core: core,
+	}
+
+	mux := chi.NewRouter()
+	mux.Get("/market/{"+marketNameKey+"}/retire", srv.apiRetire)
+
+	// Non-existent market
+	name := "dcr_btc"
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest(http.MethodGet, "https://localhost/market/"+name+"/retire", nil)
+	r.RemoteAddr = "localhost"
+
+	mux.ServeHTTP(w, r)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("apiRetire returned code %d, expected %d", w.Code, http.StatusBadRequest)
+	}
+
+	tMkt := &TMarket{
+		running: true,
+		dur:     6000,
+		suspend: &market.SuspendEpoch{Idx: 1234, End: time.UnixMilli(1235 * 6000)},
+	}
+	core.markets[name] = tMkt
+
+	w = httptest.NewRecorder()
+	r, _ = http.NewRequest(http.MethodGet, "https://localhost/market/"+name+"/retire", nil)
+	r.RemoteAddr = "localhost"
+
+	mux.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("apiRetire returned code %d, expected %d", w.Code, http.StatusOK)
+	}
+	res := new(SuspendResult)
+	if err := json.Unmarshal(w.Body.Bytes(), res); err != nil {
+		t.Fatalf("Failed to unmarshal result: %v", err)
+	}
+	if res.Market != name || res.FinalEpoch != 1234 {
+		t.Errorf("incorrect result %+v", res)
+	}
+	if tMkt.running {
+		t.Errorf("market still running")
+	}
+}
+
+func TestMarketParams(t *testing.T) {
+	core := &TCore{
+		markets: make(map[string]*TMarket),
+	}
+	srv := &Server{
+		core: core,
+	}
+
+	mux := chi.NewRouter()
+	mux.Get("/market/{"+marketNameKey+"}/params", srv.apiMarketParams)
+
+	name := "dcr_btc"
+	tMkt := &TMarket{
+		running: true,
+		dur:     6000,
+		suspend: &market.SuspendEpoch{},
+	}
+	core.markets[name] = tMkt
+
+	tFuture := time.Now().Add(time.Hour).UnixMilli()
+	tFutureEpoch := 1 + tFuture/6000
+	tests := []struct {
+		name      string
+		mkt       string
+		query     string
+		wantCode  int
+		wantEpoch int64
+	}{{
+		name:     "unknown market",
+		mkt:      "btc_ltc",
+		query:    "?lotsize=100&ratestep=10",
+		wantCode: http.StatusBadRequest,
+	}, {
+		name:     "missing lot size",
+		mkt:      name,
+		query:    "?ratestep=10",
+		wantCode: http.StatusBadRequest,
+	}, {
+		name:     "zero rate step",
+		mkt:      name,
+		query:    "?lotsize=100&ratestep=0",
+		wantCode: http.StatusBadRequest,
+	}, {
+		name:     "time in the past",
+		mkt:      name,
+		query:    "?lotsize=100&ratestep=10&t=1",
+		wantCode: http.StatusBadRequest,
+	}, {
+		name:     "ok",
+		mkt:      name,
+		query:    "?lotsize=100&ratestep=10",
+		wantCode: http.StatusOK,
+	}, {
+		name:      "ok with time",
+		mkt:       name,
+		query:     "?lotsize=100&ratestep=10&t=" + strconv.FormatInt(tFuture, 10),
+		wantCode:  http.StatusOK,
+		wantEpoch: tFutureEpoch,
+	}}
+	for _, test := range tests {
+		tMkt.paramChange = nil
+		w := httptest.NewRecorder()
+		r, _ := http.NewRequest(http.MethodGet, "https://localhost/market/"+test.mkt+"/params"+test.query, nil)
+		r.RemoteAddr = "localhost"
+
+		mux.ServeHTTP(w, r)
+
+		if w.Code != test.wantCode {
+			t.Fatalf("%s: apiMarketParams returned code %d, expected %d", test.name, w.Code, test.wantCode)
+		}
+		if test.wantCode != http.StatusOK {
+			continue
+		}
+		res := new(MarketParamsResult)
+		if err := json.Unmarshal(w.Body.Bytes(), res); err != nil {
+			t.Fatalf("%s: Failed to unmarshal result: %v", test.name, err)
+		}
+		if tMkt.paramChange == nil {
+			t.Fatalf("%s: change not scheduled", test.name)
+		}
+		if res.Market != name || res.LotSize != 100 || res.RateStep != 10 || res.StartEpoch != tMkt.paramChange.Epoch {
+			t.Errorf("%s: incorrect result %+v", test.name, res)
+		}
+		if test.wantEpoch != 0 && res.StartEpoch != test.wantEpoch {
+			t.Errorf("%s: wrong start epoch %d, expected %d", test.name, res.StartEpoch, test.wantEpoch)
+		}
+	}
+}
+
 func TestSuspend(t *testing.T) {
 	core := &TCore{
 		markets: make(map[string]*TMarket),
@@ -1375,3 +1630,138 @@ func TestEnableDataAPI(t *testing.T) {
 	}
 
 }
+
+func TestMetrics(t *testing.T) {
+	core := &TCore{
+		metrics: &dexsrv.Metrics{
+			Markets: []*dexsrv.MarketMetrics{{
+				Metrics: &market.Metrics{
+					Epochs:           10,
+					Matches:          7,
+					LastEpoch:        12345,
+					LastEpochTime:    time.Unix(1700000000, 0),
+					LastEpochMatches: 2,
+					BookBuyOrders:    3,
+					BookSells5:       4e8,
+				},
+				Name:    "dcr_btc",
+				Running: true,
+			}},
+			Assets: []*dexsrv.AssetMetrics{{
+				ID:         42,
+				Symbol:     "dcr",
+				Synced:     true,
+				RPCLatency: time.Millisecond * 250,
+				Height:     800000,
+			}},
+			Swaps:     map[order.MatchStatus]int{order.MakerSwapCast: 5},
+			Clients:   9,
+			Penalties: map[account.Rule]uint64{account.FailureToAct: 1},
+		},
+	}
+	srv := &Server{
+		core: core,
+	}
+
+	w := httptest.NewRecorder()
+	r, _ := http.NewRequest(http.MethodGet, "https://localhost/metrics", nil)
+	srv.apiMetrics(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("apiMetrics returned code %d, expected %d", w.Code, http.StatusOK)
+	}
+	if ct := w.Result().Header.Get("Content-Type"); ct != metricsContentType {
+		t.Fatalf("wrong content type %q", ct)
+	}
+	body := w.Body.String()
+	for _, line := range []string{
+		"# TYPE dcrdex_market_epochs_total counter",
+		`dcrdex_market_running{market="dcr_btc"} 1`,
+		`dcrdex_market_epochs_total{market="dcr_btc"} 10`,
+		`dcrdex_market_matches_total{market="dcr_btc"} 7`,
+		`dcrdex_market_last_epoch{market="dcr_btc"} 12345`,
+		`dcrdex_market_last_epoch_timestamp_seconds{market="dcr_btc"} 1700000000`,
+		`dcrdex_market_epoch_matches{market="dcr_btc"} 2`,
+		`dcrdex_market_book_orders{market="dcr_btc",side="buy"} 3`,
+		`dcrdex_market_book_depth{market="dcr_btc",side="sell",range="5pct"} 400000000`,
+		`dcrdex_swaps_active{status="NewlyMatched"} 0`,
+		`dcrdex_swaps_active{status="MakerSwapCast"} 5`,
+		"dcrdex_clients_connected 9",
+		`dcrdex_penalties_total{rule="FailureToAct"} 1`,
+		`dcrdex_penalties_total{rule="PreimageReveal"} 0`,
+		`dcrdex_asset_synced{asset="dcr"} 1`,
+		`dcrdex_asset_rpc_up{asset="dcr"} 1`,
+		`dcrdex_asset_rpc_latency_seconds{asset="dcr"} 0.25`,
+		`dcrdex_asset_height{asset="dcr"} 800000`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("metrics missing line %q", line)
+		}
+	}
+}
+
+func TestEvents(t *testing.T) {
+	core := &TCore{
+		markets: map[string]*TMarket{"dcr_btc": {}},
+		events:  make(chan *dexsrv.Event, 4),
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	srv := &Server{
+		core: core,
+		ctx:  ctx,
+	}
+	mux := chi.NewRouter()
+	mux.Get("/events", srv.apiEvents)
+	httpSrv := httptest.NewServer(mux)
+	defer httpSrv.Close()
+	wsURL := "ws" + strings.TrimPrefix(httpSrv.URL, "http") + "/events"
+
+	// Bad filters are rejected before upgrading.
+	for _, query := range []string{"?market=dcr_eth", "?account=abcd"} {
+		_, resp, err := websocket.DefaultDialer.Dial(wsURL+query, nil)
+		if err == nil {
+			t.Fatalf("no error for bad filter %q", query)
+		}
+		if resp.StatusCode != http.StatusBadRequest {
+			t.Fatalf("wrong status code %d for bad filter %q", resp.StatusCode, query)
+		}
+	}
+
+	var user, otherUser account.AccountID
+	user[0], otherUser[0] = 1, 2
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL+"?market=dcr_btc&account="+user.String(), nil)
+	if err != nil {
+		t.Fatalf("Dial error: %v", err)
+	}
+	defer conn.Close()
+
+	core.events <- &dexsrv.Event{Type: dexsrv.EventOrderBooked, Market: "dcr_eth", Accounts: []account.AccountID{user}}
+	core.events <- &dexsrv.Event{Type: dexsrv.EventPenalty, Accounts: []account.AccountID{otherUser}}
+	core.events <- &dexsrv.Event{Type: dexsrv.EventSuspend, Market: "dcr_btc"}
+	core.events <- &dexsrv.Event{Type: dexsrv.EventPenalty, Accounts: []account.AccountID{user}}
+
+	for _, expType := range []dexsrv.EventType{dexsrv.EventSuspend, dexsrv.EventPenalty} {
+		conn.SetReadDeadline(time.Now().Add(time.Second * 5))
+		_, b, err := conn.ReadMessage()
+		if err != nil {
+			t.Fatalf("ReadMessage error: %v", err)
+		}
+		msg, err := msgjson.DecodeMessage(b)
+		if err != nil {
+			t.Fatalf("DecodeMessage error: %v", err)
+		}
+		if msg.Route != eventRoute {
+			t.Fatalf("wrong route %q", msg.Route)
+		}
+		var ev struct {
+			Type dexsrv.EventType `json:"type"`
+		}
+		if err := msg.Unmarshal(&ev); err != nil {
+			t.Fatalf("Unmarshal error: %v", err)
+		}
+		if ev.Type != expType {
+			t.Fatalf("expected %s event, got %s", expType, ev.Type)
+		}
+	}
+}
@@ -82,6 +82,23 @@ type ResumeResult struct {
 	StartTime  APITime `json:"starttime"`
 }
 
+// AddMarketResult is the result of a request to add a market.
+type AddMarketResult struct {
+	Market     string  `json:"market"`
+	StartEpoch int64   `json:"startepoch"`
+	StartTime  APITime `json:"starttime"`
+}
+
+// MarketParamsResult is the result of a request to change a market's lot size
+// and rate step, which take effect at StartEpoch.
+type MarketParamsResult struct {
+	Market     string  `json:"market"`
+	StartEpoch int64   `json:"startepoch"`
+	StartTime  APITime `json:"starttime"`
+	LotSize    uint64  `json:"lotsize"`
+	RateStep   uint64  `json:"ratestep"`
+}
+
 // RFC3339Milli is the RFC3339 time formatting with millisecond precision.
 const RFC3339Milli = "2006-01-02T15:04:05.999Z07:00"
 
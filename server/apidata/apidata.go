@@ -53,7 +53,7 @@ type cacheWithStoredTime struct {
 // DataAPI is a data API backend.
 type DataAPI struct {
 	db             DBSource
-	epochDurations map[string]uint64
+	epochDurations map[string]uint64 // protected by cacheMtx
 	bookSource     BookSource
 
 	spotsMtx sync.RWMutex
@@ -87,7 +87,6 @@ func (s *DataAPI) AddMarketSource(mkt MarketSource) error {
 		return err
 	}
 	epochDur := mkt.EpochDuration()
-	s.epochDurations[mktName] = epochDur
 	binCaches := make(map[uint64]*cacheWithStoredTime, len(binSizes)+1)
 	cacheList := make([]*candles.Cache, 0, len(binSizes)+1)
 	for _, binSize := range append([]uint64{epochDur}, binSizes...) {
@@ -105,11 +104,24 @@ func (s *DataAPI) AddMarketSource(mkt MarketSource) error {
 		return err
 	}
 	s.cacheMtx.Lock()
+	s.epochDurations[mktName] = epochDur
 	s.marketCaches[mktName] = binCaches
 	s.cacheMtx.Unlock()
 	return nil
 }
 
+// RemoveMarketSource removes the candle caches and spot price for a retired
+// market. The stored candles are not deleted.
+func (s *DataAPI) RemoveMarketSource(mktName string) {
+	s.cacheMtx.Lock()
+	delete(s.epochDurations, mktName)
+	delete(s.marketCaches, mktName)
+	s.cacheMtx.Unlock()
+	s.spotsMtx.Lock()
+	delete(s.spots, mktName)
+	s.spotsMtx.Unlock()
+}
+
 // SetBookSource should be called before the first call to handleBook.
 func (s *DataAPI) SetBookSource(bs BookSource) {
 	s.bookSource = bs
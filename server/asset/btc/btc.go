@@ -196,6 +196,7 @@ type Backend struct {
 // Check that Backend satisfies the Backend interface.
 var _ asset.Backend = (*Backend)(nil)
 var _ srvdex.Bonder = (*Backend)(nil)
+var _ asset.TipHeighter = (*Backend)(nil)
 
 // NewBackend is the exported constructor by which the DEX will import the
 // backend. The configPath can be an empty string, in which case the standard
@@ -540,6 +541,12 @@ func (btc *Backend) Synced() (bool, error) {
 	return !chainInfo.InitialBlockDownload && chainInfo.Headers-chainInfo.Blocks <= 1, nil
 }
 
+// TipHeight is the height of the best block in the block cache. Part of the
+// asset.TipHeighter interface.
+func (btc *Backend) TipHeight() (uint64, error) {
+	return uint64(btc.blockCache.tipHeight()), nil
+}
+
 // Redemption is an input that redeems a swap contract.
 func (btc *Backend) Redemption(redemptionID, contractID, _ []byte) (asset.Coin, error) {
 	txHash, vin, err := decodeCoinID(redemptionID)
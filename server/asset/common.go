@@ -109,6 +109,12 @@ type TokenBacker interface {
 	TokenBackend(assetID uint32, configPath string) (Backend, error)
 }
 
+// TipHeighter is implemented by backends that can report the height of the
+// best known block.
+type TipHeighter interface {
+	TipHeight() (uint64, error)
+}
+
 // Coin represents a transaction input or output.
 type Coin interface {
 	// Confirmations returns the number of confirmations for a Coin's
@@ -277,6 +277,7 @@ type Backend struct {
 
 // Check that Backend satisfies the Backend interface.
 var _ asset.Backend = (*Backend)(nil)
+var _ asset.TipHeighter = (*Backend)(nil)
 
 // unconnectedDCR returns a Backend without a node. The node should be set
 // before use.
@@ -509,6 +510,12 @@ func (dcr *Backend) Synced() (bool, error) {
 	return !chainInfo.InitialBlockDownload && chainInfo.Headers-chainInfo.Blocks <= 1, nil
 }
 
+// TipHeight is the height of the best block in the block cache. Part of the
+// asset.TipHeighter interface.
+func (dcr *Backend) TipHeight() (uint64, error) {
+	return uint64(dcr.blockCache.tipHeight()), nil
+}
+
 // Redemption is an input that redeems a swap contract.
 func (dcr *Backend) Redemption(redemptionID, contractID, _ []byte) (asset.Coin, error) {
 	txHash, vin, err := decodeCoinID(redemptionID)
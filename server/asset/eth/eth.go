@@ -304,6 +304,7 @@ var _ asset.Backend = (*ETHBackend)(nil)
 // Check that Backend satisfies the AccountBalancer interface.
 var _ asset.AccountBalancer = (*TokenBackend)(nil)
 var _ asset.AccountBalancer = (*ETHBackend)(nil)
+var _ asset.TipHeighter = (*ETHBackend)(nil)
 
 // unconnectedETH returns a Backend without a node. The node should be set
 // before use.
@@ -736,6 +737,12 @@ func (eth *baseBackend) Synced() (bool, error) {
 	return timeDiff < dexeth.MaxBlockInterval, nil
 }
 
+// TipHeight is the current block number reported by the node. Part of the
+// asset.TipHeighter interface.
+func (eth *baseBackend) TipHeight() (uint64, error) {
+	return eth.node.blockNumber(eth.ctx)
+}
+
 // Redemption returns a coin that represents a contract redemption. redeemCoinID
 // should be the transaction that sent a redemption, while contractCoinID is the
 // swap contract this redemption redeems.
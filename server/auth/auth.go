@@ -273,6 +273,11 @@ type AuthManager struct {
 	txDataSources map[uint32]TxDataSource
 
 	prepaidBondMtx sync.Mutex
+
+	penaltiesMtx sync.Mutex
+	penalties    map[account.Rule]uint64 // count since startup, for monitoring
+	penalized    func(user account.AccountID, rule account.Rule)
+	tierChanged  func(user account.AccountID, tier int64, reason string)
 }
 
 // violation badness
@@ -356,6 +361,11 @@ type Config struct {
 	// PenaltyThreshold defines the score deficit at which a user's bond is
 	// revoked.
 	PenaltyThreshold uint32
+
+	// Penalized and TierChanged are optional callbacks for monitoring user
+	// penalties and tier changes. They must not block.
+	Penalized   func(user account.AccountID, rule account.Rule)
+	TierChanged func(user account.AccountID, tier int64, reason string)
 }
 
 // NewAuthManager is the constructor for an AuthManager.
@@ -397,6 +407,9 @@ func NewAuthManager(cfg *Config) *AuthManager {
 		preimgOutcomes:   make(map[account.AccountID]*latestOutcomes[*db.PreimageOutcome]),
 		orderOutcomes:    make(map[account.AccountID]*latestOutcomes[*db.OrderOutcome]),
 		txDataSources:    cfg.TxDataSources,
+		penalties:        make(map[account.Rule]uint64),
+		penalized:        cfg.Penalized,
+		tierChanged:      cfg.TierChanged,
 	}
 
 	// Unauthenticated
@@ -993,6 +1006,13 @@ func (auth *AuthManager) Penalize(user account.AccountID, lastRule account.Rule,
 
 	log.Debugf("User %v account penalized. Last rule broken = %v. Detail: %s", user, lastRule, extraDetails)
 
+	auth.penaltiesMtx.Lock()
+	auth.penalties[lastRule]++
+	auth.penaltiesMtx.Unlock()
+	if auth.penalized != nil {
+		auth.penalized(user, lastRule)
+	}
+
 	// Notify user of penalty.
 	details := "Ordering has been suspended for this account. Post additional bond to offset violations."
 	details = fmt.Sprintf("%s\nLast Broken Rule Details: %s\n%s", details, lastRule.Description(), extraDetails)
@@ -1013,6 +1033,18 @@ func (auth *AuthManager) Penalize(user account.AccountID, lastRule account.Rule,
 	auth.Notify(user, note)
 }
 
+// PenaltyCounts returns the number of penalties issued since startup for each
+// rule.
+func (auth *AuthManager) PenaltyCounts() map[account.Rule]uint64 {
+	auth.penaltiesMtx.Lock()
+	defer auth.penaltiesMtx.Unlock()
+	counts := make(map[account.Rule]uint64, len(auth.penalties))
+	for rule, n := range auth.penalties {
+		counts[rule] = n
+	}
+	return counts
+}
+
 // AcctStatus indicates if the user is presently connected and their tier.
 func (auth *AuthManager) AcctStatus(user account.AccountID) (connected bool, tier int64) {
 	client := auth.user(user)
@@ -1117,6 +1149,9 @@ func (auth *AuthManager) sendTierChanged(acctID account.AccountID, rep *account.
 	effectiveTier := rep.EffectiveTier()
 	log.Debugf("Sending tierchanged notification to %v, new tier = %d, reason = %v",
 		acctID, effectiveTier, reason)
+	if auth.tierChanged != nil {
+		auth.tierChanged(acctID, effectiveTier, reason)
+	}
 	tierChangedNtfn := &msgjson.TierChangedNotification{
 		Tier:       effectiveTier,
 		Reputation: rep,
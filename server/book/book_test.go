@@ -268,6 +268,23 @@ func TestBook(t *testing.T) {
 	}
 }
 
+func TestSetLotSize(t *testing.T) {
+	b := New(LotSize, AccountTrackingBase)
+
+	b.SetLotSize(2 * LotSize)
+	if b.LotSize() != 2*LotSize {
+		t.Fatalf("wrong lot size. expected %d, got %d", 2*LotSize, b.LotSize())
+	}
+
+	// One lot of the old lot size is not a multiple of the new lot size.
+	if b.Insert(newLimitOrder(false, 2500000, 1, order.StandingTiF, 0)) {
+		t.Fatalf("inserted an order with an incompatible quantity")
+	}
+	if !b.Insert(newLimitOrder(false, 2500000, 2, order.StandingTiF, 0)) {
+		t.Fatalf("failed to insert an order with a compatible quantity")
+	}
+}
+
 func TestAccountTracking(t *testing.T) {
 	firstSell := bookSellOrders[len(bookSellOrders)-1]
 	allOrders := append(bookBuyOrders, bookSellOrders...)
@@ -40,6 +40,7 @@ const (
 	defaultLogDirname          = "logs"
 	defaultMarketsConfFilename = "markets.json"
 	defaultMaxLogZips          = 128
+	defaultDBDriver            = "pg"
 	defaultPGHost              = "127.0.0.1:5432"
 	defaultPGUser              = "dcrdex"
 	defaultPGDBName            = "dcrdex_{netname}"
@@ -70,6 +71,7 @@ type procOpts struct {
 type dexConf struct {
 	DataDir          string
 	Network          dex.Network
+	DBDriver         string
 	DBName           string
 	DBUser           string
 	DBPass           string
@@ -96,6 +98,7 @@ type dexConf struct {
 	AdminSrvAddr     string
 	AdminSrvPW       []byte
 	AdminSrvNoTLS    bool
+	AdminSrvMetrics  bool
 	NoResumeSwaps    bool
 	DisableDataAPI   bool
 	NodeRelayAddr    string
@@ -136,6 +139,7 @@ type flagsData struct {
 	HTTPProfile bool   `long:"httpprof" short:"p" description:"Start HTTP profiler."`
 	CPUProfile  string `long:"cpuprofile" description:"File for CPU profiling."`
 
+	DBDriver           string `long:"dbdriver" description:"Database driver, either pg for PostgreSQL or lexi for an embedded DB in the data directory. The pg* options are ignored with lexi."`
 	PGDBName           string `long:"pgdbname" description:"PostgreSQL DB name."`
 	PGUser             string `long:"pguser" description:"PostgreSQL DB user."`
 	PGPass             string `long:"pgpass" description:"PostgreSQL DB password."`
@@ -146,6 +150,7 @@ type flagsData struct {
 	AdminSrvAddr       string `long:"adminsrvaddr" description:"Administration HTTPS server address (default: 127.0.0.1:6542)."`
 	AdminSrvPassword   string `long:"adminsrvpass" description:"Admin server password. INSECURE. Do not set unless absolutely necessary."`
 	AdminSrvNoTLS      bool   `long:"adminsrvnotls" description:"Run admin server without TLS. Only use this option if you are using a securely configured reverse proxy."`
+	AdminSrvMetrics    bool   `long:"adminsrvmetrics" description:"Serve Prometheus metrics at the admin server's /metrics endpoint."`
 
 	NoResumeSwaps bool `long:"noresumeswaps" description:"Do not attempt to resume swaps that are active in the DB."`
 
@@ -255,6 +260,7 @@ func loadConfig() (*dexConf, *procOpts, error) {
 		RPCCert:          defaultRPCCertFilename,
 		RPCKey:           defaultRPCKeyFilename,
 		DebugLevel:       defaultLogLevel,
+		DBDriver:         defaultDBDriver,
 		PGDBName:         defaultPGDBName,
 		PGUser:           defaultPGUser,
 		PGHost:           defaultPGHost,
@@ -526,12 +532,19 @@ func loadConfig() (*dexConf, *procOpts, error) {
 		adminSrvAddr = cfg.AdminSrvAddr
 	}
 
+	switch cfg.DBDriver {
+	case "pg", "lexi":
+	default:
+		return loadConfigError(fmt.Errorf("unknown DB driver %q", cfg.DBDriver))
+	}
+
 	// If using {netname} then replace it with the network name.
 	cfg.PGDBName = strings.ReplaceAll(cfg.PGDBName, "{netname}", network.String())
 
 	dexCfg := &dexConf{
 		DataDir:          cfg.DataDir,
 		Network:          network,
+		DBDriver:         cfg.DBDriver,
 		DBName:           cfg.PGDBName,
 		DBHost:           dbHost,
 		DBPort:           dbPort,
@@ -558,6 +571,7 @@ func loadConfig() (*dexConf, *procOpts, error) {
 		AdminSrvOn:       cfg.AdminSrvOn,
 		AdminSrvPW:       []byte(cfg.AdminSrvPassword),
 		AdminSrvNoTLS:    cfg.AdminSrvNoTLS,
+		AdminSrvMetrics:  cfg.AdminSrvMetrics,
 		NoResumeSwaps:    cfg.NoResumeSwaps,
 		DisableDataAPI:   cfg.DisableDataAPI,
 		NodeRelayAddr:    cfg.NodeRelayAddr,
@@ -123,6 +123,7 @@ func mainCore(ctx context.Context) error {
 		Assets:     assets,
 		Network:    cfg.Network,
 		DBConf: &dexsrv.DBConf{
+			Driver:       cfg.DBDriver,
 			DBName:       cfg.DBName,
 			Host:         cfg.DBHost,
 			User:         cfg.DBUser,
@@ -162,6 +163,7 @@ func mainCore(ctx context.Context) error {
 			Cert:    cfg.RPCCert,
 			Key:     cfg.RPCKey,
 			NoTLS:   cfg.AdminSrvNoTLS,
+			Metrics: cfg.AdminSrvMetrics,
 		}
 		adminServer, err := admin.NewServer(srvCFG)
 		if err != nil {
@@ -741,6 +741,11 @@ func (s *Server) clientCount() uint64 {
 	return uint64(len(s.clients))
 }
 
+// ClientCount is the number of connected websocket clients.
+func (s *Server) ClientCount() uint64 {
+	return s.clientCount()
+}
+
 // Get the number of websocket connections for a given IP, excluding loopback.
 func (s *Server) ipConnCount(ip dex.IPKey) int64 {
 	s.wsLimiterMtx.Lock()
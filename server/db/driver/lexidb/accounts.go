@@ -0,0 +1,296 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package lexidb
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"decred.org/dcrdex/dex/encode"
+	"decred.org/dcrdex/dex/lexi"
+	"decred.org/dcrdex/server/account"
+	"decred.org/dcrdex/server/db"
+	"github.com/decred/dcrd/dcrutil/v4"
+	"github.com/dgraph-io/badger"
+)
+
+var (
+	_ db.AccountArchiver = (*Archiver)(nil)
+	_ db.KeyIndexer      = (*Archiver)(nil)
+)
+
+// dbAccount is a stored account.
+type dbAccount struct {
+	pubKey []byte
+	repVer int16
+}
+
+// MarshalBinary encodes the dbAccount as a versioned blob.
+func (acct *dbAccount) MarshalBinary() ([]byte, error) {
+	const acctVer = 0
+	return encode.BuildyBytes{acctVer}.
+		AddData(acct.pubKey).
+		AddData(encode.Uint16Bytes(uint16(acct.repVer))), nil
+}
+
+// UnmarshalBinary decodes the versioned blob into the dbAccount.
+func (acct *dbAccount) UnmarshalBinary(b []byte) error {
+	const acctVer = 0
+	ver, pushes, err := encode.DecodeBlob(encode.CopySlice(b), 2)
+	if err != nil {
+		return fmt.Errorf("error decoding account blob: %w", err)
+	}
+	if ver != acctVer {
+		return fmt.Errorf("unknown account version %d", ver)
+	}
+	if len(pushes) != 2 || len(pushes[1]) != 2 {
+		return errors.New("invalid account blob")
+	}
+	acct.pubKey = pushes[0]
+	acct.repVer = int16(encode.IntCoder.Uint16(pushes[1]))
+	return nil
+}
+
+// dbBond is a fidelity bond for an account.
+type dbBond struct {
+	acct account.AccountID
+	*db.Bond
+}
+
+// MarshalBinary encodes the dbBond as a versioned blob.
+func (b *dbBond) MarshalBinary() ([]byte, error) {
+	const bondVer = 0
+	return encode.BuildyBytes{bondVer}.
+		AddData(b.acct[:]).
+		AddData(encode.Uint16Bytes(b.Version)).
+		AddData(encode.Uint32Bytes(b.AssetID)).
+		AddData(b.CoinID).
+		AddData(encode.Uint64Bytes(uint64(b.Amount))).
+		AddData(encode.Uint32Bytes(b.Strength)).
+		AddData(encode.Uint64Bytes(uint64(b.LockTime))), nil
+}
+
+// UnmarshalBinary decodes the versioned blob into the dbBond.
+func (b *dbBond) UnmarshalBinary(bB []byte) error {
+	const bondVer = 0
+	ver, pushes, err := encode.DecodeBlob(encode.CopySlice(bB), 7)
+	if err != nil {
+		return fmt.Errorf("error decoding bond blob: %w", err)
+	}
+	if ver != bondVer {
+		return fmt.Errorf("unknown bond version %d", ver)
+	}
+	if len(pushes) != 7 || len(pushes[0]) != account.HashSize {
+		return errors.New("invalid bond blob")
+	}
+	copy(b.acct[:], pushes[0])
+	b.Bond = &db.Bond{
+		Version:  encode.IntCoder.Uint16(pushes[1]),
+		AssetID:  encode.BytesToUint32(pushes[2]),
+		CoinID:   pushes[3],
+		Amount:   int64(encode.BytesToUint64(pushes[4])),
+		Strength: encode.BytesToUint32(pushes[5]),
+		LockTime: int64(encode.BytesToUint64(pushes[6])),
+	}
+	return nil
+}
+
+func accountKey(aid account.AccountID) []byte {
+	return prefixedKey(accountKeyPrefix, aid[:])
+}
+
+func bondKey(assetID uint32, coinID []byte) []byte {
+	return prefixedKey(bondKeyPrefix, encode.Uint32Bytes(assetID), coinID)
+}
+
+func (a *Archiver) prepareAccountTables() (err error) {
+	if a.accounts, err = a.db.Table("accounts"); err != nil {
+		return fmt.Errorf("error constructing accounts table: %w", err)
+	}
+	if a.bonds, err = a.db.Table("bonds"); err != nil {
+		return fmt.Errorf("error constructing bonds table: %w", err)
+	}
+	a.bondAcctIdx, err = a.bonds.AddIndex("bond-account", func(_, v lexi.KV) ([]byte, error) {
+		b, is := v.(*dbBond)
+		if !is {
+			return nil, fmt.Errorf("wrong type %T", v)
+		}
+		return append(b.acct[:], encode.Uint64Bytes(uint64(b.LockTime))...), nil
+	})
+	if err != nil {
+		return fmt.Errorf("error constructing bond account index: %w", err)
+	}
+	if a.prepaidBonds, err = a.db.Table("prepaid-bonds"); err != nil {
+		return fmt.Errorf("error constructing prepaid bonds table: %w", err)
+	}
+	if a.feeKeys, err = a.db.Table("fee-keys"); err != nil {
+		return fmt.Errorf("error constructing fee keys table: %w", err)
+	}
+	return nil
+}
+
+func (a *Archiver) loadAccount(aid account.AccountID, opts ...lexi.GetOption) (*dbAccount, error) {
+	acct := new(dbAccount)
+	if err := a.accounts.Get(accountKey(aid), acct, opts...); err != nil {
+		return nil, err
+	}
+	return acct, nil
+}
+
+// Account retrieves the account pubkey and active bonds. If the account does
+// not exist or there is in an error retrieving any data, a nil
+// *account.Account is returned.
+func (a *Archiver) Account(aid account.AccountID, bondExpiry time.Time) (acct *account.Account, bonds []*db.Bond) {
+	dbAcct, err := a.loadAccount(aid)
+	switch {
+	case errors.Is(err, lexi.ErrKeyNotFound):
+		return nil, nil
+	case err == nil:
+	default:
+		log.Errorf("loadAccount error: %v", err)
+		return nil, nil
+	}
+	if acct, err = account.NewAccountFromPubKey(dbAcct.pubKey); err != nil {
+		log.Errorf("NewAccountFromPubKey error: %v", err)
+		return nil, nil
+	}
+
+	// Bonds are indexed by lock time, so they come out sorted.
+	seek := append(aid[:], encode.Uint64Bytes(uint64(max(bondExpiry.Unix(), 0)))...)
+	err = a.bondAcctIdx.Iterate(aid[:], func(it *lexi.Iter) error {
+		return it.V(func(vB []byte) error {
+			var b dbBond
+			if err := b.UnmarshalBinary(vB); err != nil {
+				return err
+			}
+			bonds = append(bonds, b.Bond)
+			return nil
+		})
+	}, lexi.WithSeek(seek))
+	if err != nil {
+		log.Errorf("error loading bonds for account: %v", err)
+		return nil, nil
+	}
+	return acct, bonds
+}
+
+// AccountInfo returns data for an account.
+func (a *Archiver) AccountInfo(aid account.AccountID) (*db.Account, error) {
+	dbAcct, err := a.loadAccount(aid)
+	if err != nil {
+		if errors.Is(err, lexi.ErrKeyNotFound) {
+			err = db.ArchiveError{Code: db.ErrAccountUnknown}
+		}
+		return nil, err
+	}
+	return &db.Account{
+		AccountID: aid,
+		Pubkey:    dbAcct.pubKey,
+	}, nil
+}
+
+// CreateAccountWithBond creates a new account with a fidelity bond.
+func (a *Archiver) CreateAccountWithBond(acct *account.Account, bond *db.Bond) error {
+	return a.db.Update(func(txn *badger.Txn) error {
+		err := a.accounts.Set(accountKey(acct.ID), &dbAccount{
+			pubKey: acct.PubKey.SerializeCompressed(),
+			repVer: newReputationVersion,
+		}, lexi.WithTxn(txn))
+		if err != nil {
+			return fmt.Errorf("error storing account: %w", err)
+		}
+		return a.addBond(txn, acct.ID, bond)
+	})
+}
+
+// AddBond stores a new Bond for an existing account.
+func (a *Archiver) AddBond(aid account.AccountID, bond *db.Bond) error {
+	return a.db.Update(func(txn *badger.Txn) error {
+		return a.addBond(txn, aid, bond)
+	})
+}
+
+func (a *Archiver) addBond(txn *badger.Txn, aid account.AccountID, bond *db.Bond) error {
+	err := a.bonds.Set(bondKey(bond.AssetID, bond.CoinID), &dbBond{acct: aid, Bond: bond}, lexi.WithTxn(txn))
+	if err != nil {
+		return fmt.Errorf("error storing bond: %w", err)
+	}
+	return nil
+}
+
+// DeleteBond deletes a bond. It is not an error if the bond does not exist.
+func (a *Archiver) DeleteBond(assetID uint32, coinID []byte) error {
+	if err := a.bonds.Delete(bondKey(assetID, coinID)); err != nil && !errors.Is(err, lexi.ErrKeyNotFound) {
+		return err
+	}
+	return nil
+}
+
+// FetchPrepaidBond retrieves the strength and lock time of a prepaid bond.
+func (a *Archiver) FetchPrepaidBond(coinID []byte) (strength uint32, lockTime int64, err error) {
+	b, err := a.prepaidBonds.GetRaw(prefixedKey(prepaidBondKeyPrefix, coinID))
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(b) != 12 {
+		return 0, 0, fmt.Errorf("invalid prepaid bond encoding length %d", len(b))
+	}
+	return encode.BytesToUint32(b[:4]), int64(encode.BytesToUint64(b[4:])), nil
+}
+
+// DeletePrepaidBond deletes a prepaid bond. It is not an error if the bond
+// does not exist.
+func (a *Archiver) DeletePrepaidBond(coinID []byte) error {
+	if err := a.prepaidBonds.Delete(prefixedKey(prepaidBondKeyPrefix, coinID)); err != nil && !errors.Is(err, lexi.ErrKeyNotFound) {
+		return err
+	}
+	return nil
+}
+
+// StorePrepaidBonds stores prepaid bonds with the given strength and lock time.
+func (a *Archiver) StorePrepaidBonds(coinIDs [][]byte, strength uint32, lockTime int64) error {
+	v := append(encode.Uint32Bytes(strength), encode.Uint64Bytes(uint64(lockTime))...)
+	return a.db.Update(func(txn *badger.Txn) error {
+		for _, coinID := range coinIDs {
+			if err := a.prepaidBonds.Set(prefixedKey(prepaidBondKeyPrefix, coinID), v, lexi.WithTxn(txn)); err != nil {
+				return fmt.Errorf("error storing prepaid bond: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+func feeKeyKey(xpub string) []byte {
+	return prefixedKey(feeKeyKeyPrefix, dcrutil.Hash160([]byte(xpub)))
+}
+
+// KeyIndex returns the current child index for the an xpub. If it is not
+// known, this creates a new entry with index zero.
+func (a *Archiver) KeyIndex(xpub string) (uint32, error) {
+	k := feeKeyKey(xpub)
+	b, err := a.feeKeys.GetRaw(k)
+	switch {
+	case errors.Is(err, lexi.ErrKeyNotFound): // continue to create new entry
+	case err != nil:
+		return 0, err
+	case len(b) != 4:
+		return 0, fmt.Errorf("invalid key index encoding length %d", len(b))
+	default:
+		return encode.BytesToUint32(b), nil
+	}
+
+	log.Debugf("Inserting key entry for xpub %.40s..., hash160 = %x", xpub, k[1:])
+	if err = a.feeKeys.Set(k, encode.Uint32Bytes(0)); err != nil {
+		return 0, err
+	}
+	return 0, nil
+}
+
+// SetKeyIndex records the child index for an xpub.
+func (a *Archiver) SetKeyIndex(idx uint32, xpub string) error {
+	k := feeKeyKey(xpub)
+	log.Debugf("Recording new index %d for xpub %.40s... (%x)", idx, xpub, k[1:])
+	return a.feeKeys.Set(k, encode.Uint32Bytes(idx), lexi.WithReplace())
+}
@@ -0,0 +1,395 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package lexidb
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"decred.org/dcrdex/dex/candles"
+	"decred.org/dcrdex/dex/encode"
+	"decred.org/dcrdex/dex/lexi"
+	"decred.org/dcrdex/dex/order"
+	"decred.org/dcrdex/server/db"
+)
+
+// encodeUint64s encodes the values as a versioned blob with a single push.
+func encodeUint64s(vs ...uint64) []byte {
+	const ver = 0
+	b := make([]byte, 0, len(vs)*8)
+	for _, v := range vs {
+		b = append(b, encode.Uint64Bytes(v)...)
+	}
+	return encode.BuildyBytes{ver}.AddData(b)
+}
+
+// decodeUint64s decodes a blob created with encodeUint64s into the pointers.
+func decodeUint64s(b []byte, vs ...*uint64) error {
+	ver, pushes, err := encode.DecodeBlob(b, 1)
+	if err != nil {
+		return err
+	}
+	if ver != 0 {
+		return fmt.Errorf("unknown version %d", ver)
+	}
+	if len(pushes) != 1 || len(pushes[0]) != len(vs)*8 {
+		return fmt.Errorf("expected %d encoded values", len(vs))
+	}
+	for i, v := range vs {
+		*v = encode.BytesToUint64(pushes[0][i*8 : (i+1)*8])
+	}
+	return nil
+}
+
+// dbEpoch is the match proof data for an epoch.
+type dbEpoch struct {
+	matchTime int64
+	csum      []byte
+	seed      []byte
+	revealed  []order.OrderID
+	missed    []order.OrderID
+}
+
+func orderIDsBytes(oids []order.OrderID) []byte {
+	b := make([]byte, 0, len(oids)*order.OrderIDSize)
+	for i := range oids {
+		b = append(b, oids[i][:]...)
+	}
+	return b
+}
+
+func bytesOrderIDs(b []byte) ([]order.OrderID, error) {
+	if len(b)%order.OrderIDSize != 0 {
+		return nil, fmt.Errorf("invalid order IDs length %d", len(b))
+	}
+	oids := make([]order.OrderID, len(b)/order.OrderIDSize)
+	for i := range oids {
+		copy(oids[i][:], b[i*order.OrderIDSize:])
+	}
+	return oids, nil
+}
+
+// MarshalBinary encodes the dbEpoch as a versioned blob.
+func (e *dbEpoch) MarshalBinary() ([]byte, error) {
+	const epochVer = 0
+	return encode.BuildyBytes{epochVer}.
+		AddData(encode.Uint64Bytes(uint64(e.matchTime))).
+		AddData(e.csum).
+		AddData(e.seed).
+		AddData(orderIDsBytes(e.revealed)).
+		AddData(orderIDsBytes(e.missed)), nil
+}
+
+// UnmarshalBinary decodes the versioned blob into the dbEpoch.
+func (e *dbEpoch) UnmarshalBinary(b []byte) error {
+	const epochVer = 0
+	ver, pushes, err := encode.DecodeBlob(encode.CopySlice(b), 5)
+	if err != nil {
+		return fmt.Errorf("error decoding epoch blob: %w", err)
+	}
+	if ver != epochVer {
+		return fmt.Errorf("unknown epoch version %d", ver)
+	}
+	if len(pushes) != 5 {
+		return fmt.Errorf("unknown number of epoch blob pushes %d", len(pushes))
+	}
+	e.matchTime = int64(encode.BytesToUint64(pushes[0]))
+	e.csum, e.seed = pushes[1], pushes[2]
+	if e.revealed, err = bytesOrderIDs(pushes[3]); err != nil {
+		return err
+	}
+	e.missed, err = bytesOrderIDs(pushes[4])
+	return err
+}
+
+// epochReport is the epoch-end report used to construct market history.
+type epochReport struct {
+	base, quote uint32
+	end, dur    uint64
+	db.EpochResults
+}
+
+// MarshalBinary encodes the epochReport as a versioned blob.
+func (r *epochReport) MarshalBinary() ([]byte, error) {
+	return encodeUint64s(r.end, r.dur, r.MatchVolume, r.QuoteVolume,
+		r.BookBuys, r.BookBuys5, r.BookBuys25, r.BookSells, r.BookSells5, r.BookSells25,
+		r.HighRate, r.LowRate, r.StartRate, r.EndRate), nil
+}
+
+// UnmarshalBinary decodes the versioned blob into the epochReport. The market
+// is not encoded.
+func (r *epochReport) UnmarshalBinary(b []byte) error {
+	return decodeUint64s(b, &r.end, &r.dur, &r.MatchVolume, &r.QuoteVolume,
+		&r.BookBuys, &r.BookBuys5, &r.BookBuys25, &r.BookSells, &r.BookSells5, &r.BookSells25,
+		&r.HighRate, &r.LowRate, &r.StartRate, &r.EndRate)
+}
+
+func (r *epochReport) candle() *candles.Candle {
+	return &candles.Candle{
+		StartStamp:  r.end - r.dur,
+		EndStamp:    r.end,
+		MatchVolume: r.MatchVolume,
+		QuoteVolume: r.QuoteVolume,
+		HighRate:    r.HighRate,
+		LowRate:     r.LowRate,
+		StartRate:   r.StartRate,
+		EndRate:     r.EndRate,
+	}
+}
+
+// dbCandle is a stored candle for a market and bin size.
+type dbCandle struct {
+	base, quote uint32
+	dur         uint64
+	candles.Candle
+}
+
+// MarshalBinary encodes the dbCandle as a versioned blob.
+func (c *dbCandle) MarshalBinary() ([]byte, error) {
+	return encodeUint64s(c.EndStamp, c.MatchVolume, c.QuoteVolume,
+		c.HighRate, c.LowRate, c.StartRate, c.EndRate), nil
+}
+
+// UnmarshalBinary decodes the versioned blob into the dbCandle. The market and
+// bin size are not encoded, so StartStamp must be set by the caller.
+func (c *dbCandle) UnmarshalBinary(b []byte) error {
+	return decodeUint64s(b, &c.EndStamp, &c.MatchVolume, &c.QuoteVolume,
+		&c.HighRate, &c.LowRate, &c.StartRate, &c.EndRate)
+}
+
+func epochKey(base, quote uint32, idx, dur int64) []byte {
+	return prefixedKey(epochKeyPrefix, marketBytes(base, quote),
+		encode.Uint64Bytes(uint64(idx)), encode.Uint64Bytes(uint64(dur)))
+}
+
+func epochReportEntry(base, quote uint32, end uint64) []byte {
+	return append(marketBytes(base, quote), encode.Uint64Bytes(end)...)
+}
+
+func candleEntry(base, quote uint32, dur, end uint64) []byte {
+	return append(append(marketBytes(base, quote), encode.Uint64Bytes(dur)...), encode.Uint64Bytes(end)...)
+}
+
+func (a *Archiver) prepareEpochTables() (err error) {
+	if a.epochs, err = a.db.Table("epochs"); err != nil {
+		return fmt.Errorf("error constructing epochs table: %w", err)
+	}
+	if a.epochReports, err = a.db.Table("epoch-reports"); err != nil {
+		return fmt.Errorf("error constructing epoch reports table: %w", err)
+	}
+	a.epochReportIdx, err = a.epochReports.AddIndex("epoch-report-end", func(_, v lexi.KV) ([]byte, error) {
+		r, is := v.(*epochReport)
+		if !is {
+			return nil, fmt.Errorf("wrong type %T", v)
+		}
+		return epochReportEntry(r.base, r.quote, r.end), nil
+	})
+	if err != nil {
+		return fmt.Errorf("error constructing epoch report index: %w", err)
+	}
+	if a.candles, err = a.db.Table("candles"); err != nil {
+		return fmt.Errorf("error constructing candles table: %w", err)
+	}
+	a.candleIdx, err = a.candles.AddIndex("candle-end", func(_, v lexi.KV) ([]byte, error) {
+		c, is := v.(*dbCandle)
+		if !is {
+			return nil, fmt.Errorf("wrong type %T", v)
+		}
+		return candleEntry(c.base, c.quote, c.dur, c.EndStamp), nil
+	})
+	if err != nil {
+		return fmt.Errorf("error constructing candles index: %w", err)
+	}
+	return nil
+}
+
+// InsertEpoch stores the results of a newly-processed epoch.
+func (a *Archiver) InsertEpoch(ed *db.EpochResults) error {
+	if _, err := a.market(ed.MktBase, ed.MktQuote); err != nil {
+		return err
+	}
+
+	err := a.epochs.Set(epochKey(ed.MktBase, ed.MktQuote, ed.Idx, ed.Dur), &dbEpoch{
+		matchTime: ed.MatchTime,
+		csum:      ed.CSum,
+		seed:      ed.Seed,
+		revealed:  ed.OrdersRevealed,
+		missed:    ed.OrdersMissed,
+	})
+	if err != nil {
+		a.fatalBackendErr(err)
+		return err
+	}
+
+	epochEnd := uint64((ed.Idx + 1) * ed.Dur)
+	err = a.epochReports.Set(prefixedKey(epochReportKeyPrefix, epochReportEntry(ed.MktBase, ed.MktQuote, epochEnd)), &epochReport{
+		base:         ed.MktBase,
+		quote:        ed.MktQuote,
+		end:          epochEnd,
+		dur:          uint64(ed.Dur),
+		EpochResults: *ed,
+	})
+	if err != nil {
+		a.fatalBackendErr(err)
+	}
+	return err
+}
+
+// epochMatchTime retrieves the match time of the stored epoch. If the epoch is
+// not found, found is false and the error is nil.
+func (a *Archiver) epochMatchTime(base, quote uint32, idx, dur int64) (matchTime int64, found bool, err error) {
+	var e dbEpoch
+	if err = a.epochs.Get(epochKey(base, quote, idx, dur), &e); err != nil {
+		if errors.Is(err, lexi.ErrKeyNotFound) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+	return e.matchTime, true, nil
+}
+
+// LastEpochRate gets the EndRate of the last EpochResults inserted for the
+// market. If the database is empty, no error and a rate of zero are returned.
+func (a *Archiver) LastEpochRate(base, quote uint32) (rate uint64, err error) {
+	if _, err = a.market(base, quote); err != nil {
+		return 0, err
+	}
+	err = a.epochReportIdx.Iterate(marketBytes(base, quote), func(it *lexi.Iter) error {
+		return it.V(func(vB []byte) error {
+			var r epochReport
+			if err := r.UnmarshalBinary(vB); err != nil {
+				return err
+			}
+			rate = r.EndRate
+			return lexi.ErrEndIteration
+		})
+	}, lexi.WithReverse())
+	return rate, err
+}
+
+// LoadEpochStats reads all market epoch history from the database, updating the
+// provided caches along the way.
+func (a *Archiver) LoadEpochStats(base, quote uint32, caches []*candles.Cache) error {
+	if _, err := a.market(base, quote); err != nil {
+		return err
+	}
+
+	// First. load stored candles from the candles table. Establish a start
+	// stamp for scanning epoch reports for partial candles.
+	var oldestNeeded uint64 = math.MaxUint64
+	sinceCaches := make(map[uint64]*candles.Cache, 0) // maps oldest end stamp
+	now := uint64(time.Now().UnixMilli())
+	for _, cache := range caches {
+		if err := a.loadCandles(base, quote, cache, candles.CacheSize); err != nil {
+			return fmt.Errorf("loadCandles: %w", err)
+		}
+
+		var since uint64
+		if len(cache.Candles) > 0 {
+			// If we have candles, set our since value to the next expected
+			// epoch stamp.
+			idx := cache.Last().EndStamp / cache.BinSize
+			since = (idx + 1) * cache.BinSize
+		} else {
+			since = now - (cache.BinSize * candles.CacheSize)
+			since = since - since%cache.BinSize // truncate to first end stamp of the epoch
+		}
+		if since < oldestNeeded {
+			oldestNeeded = since
+		}
+		sinceCaches[since] = cache
+	}
+
+	tstart := time.Now()
+	defer func() { log.Debugf("select epoch candles in: %v", time.Since(tstart)) }()
+
+	return a.epochReportIdx.Iterate(marketBytes(base, quote), func(it *lexi.Iter) error {
+		return it.V(func(vB []byte) error {
+			var r epochReport
+			if err := r.UnmarshalBinary(vB); err != nil {
+				return err
+			}
+			candle := r.candle()
+			for since, cache := range sinceCaches {
+				if candle.EndStamp > since {
+					cache.Add(candle)
+				}
+			}
+			return nil
+		})
+	}, lexi.WithSeek(epochReportEntry(base, quote, oldestNeeded)))
+}
+
+// LastCandleEndStamp pulls the last stored candles end stamp for a market and
+// candle duration.
+func (a *Archiver) LastCandleEndStamp(base, quote uint32, candleDur uint64) (endStamp uint64, err error) {
+	if _, err = a.market(base, quote); err != nil {
+		return 0, err
+	}
+	prefix := append(marketBytes(base, quote), encode.Uint64Bytes(candleDur)...)
+	err = a.candleIdx.Iterate(prefix, func(it *lexi.Iter) error {
+		return it.V(func(vB []byte) error {
+			var c dbCandle
+			if err := c.UnmarshalBinary(vB); err != nil {
+				return err
+			}
+			endStamp = c.EndStamp
+			return lexi.ErrEndIteration
+		})
+	}, lexi.WithReverse())
+	return endStamp, err
+}
+
+// InsertCandles inserts new candles for a market and candle duration. Existing
+// candles with the same end stamp are replaced.
+func (a *Archiver) InsertCandles(base, quote uint32, candleDur uint64, cs []*candles.Candle) error {
+	if _, err := a.market(base, quote); err != nil {
+		return err
+	}
+	for _, c := range cs {
+		k := prefixedKey(candleKeyPrefix, candleEntry(base, quote, candleDur, c.EndStamp))
+		err := a.candles.Set(k, &dbCandle{
+			base:   base,
+			quote:  quote,
+			dur:    candleDur,
+			Candle: *c,
+		}, lexi.WithReplace())
+		if err != nil {
+			a.fatalBackendErr(err)
+			return err
+		}
+	}
+	return nil
+}
+
+// loadCandles loads the last n candles of a specified duration and market into
+// the provided cache.
+func (a *Archiver) loadCandles(base, quote uint32, cache *candles.Cache, n uint64) error {
+	candleDur := cache.BinSize
+	prefix := append(marketBytes(base, quote), encode.Uint64Bytes(candleDur)...)
+	var cs []*candles.Candle
+	err := a.candleIdx.Iterate(prefix, func(it *lexi.Iter) error {
+		return it.V(func(vB []byte) error {
+			var c dbCandle
+			if err := c.UnmarshalBinary(vB); err != nil {
+				return err
+			}
+			c.StartStamp = c.EndStamp - candleDur
+			cs = append(cs, &c.Candle)
+			if uint64(len(cs)) >= n {
+				return lexi.ErrEndIteration
+			}
+			return nil
+		})
+	}, lexi.WithReverse())
+	if err != nil {
+		return err
+	}
+	for i := len(cs) - 1; i >= 0; i-- { // oldest first
+		cache.Add(cs[i])
+	}
+	return nil
+}
@@ -0,0 +1,386 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+// Package lexidb implements the server/db.DEXArchivist on the embedded lexi
+// key-value store. It is a drop-in alternative to the pg driver for small
+// or test deployments, where all data lives in a single data directory.
+package lexidb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"decred.org/dcrdex/dex"
+	"decred.org/dcrdex/dex/encode"
+	"decred.org/dcrdex/dex/lexi"
+	"decred.org/dcrdex/server/db"
+)
+
+// Driver implements db.Driver.
+type Driver struct{}
+
+// Open creates the DB backend, returning a DEXArchivist.
+func (d *Driver) Open(ctx context.Context, cfg any) (db.DEXArchivist, error) {
+	switch c := cfg.(type) {
+	case *Config:
+		return NewArchiver(ctx, c)
+	case Config:
+		return NewArchiver(ctx, &c)
+	default:
+		return nil, fmt.Errorf("invalid config type %T", cfg)
+	}
+}
+
+// UseLogger sets the package-wide logger for the registered DB Driver.
+func (*Driver) UseLogger(logger dex.Logger) {
+	UseLogger(logger)
+}
+
+func init() {
+	db.Register("lexi", &Driver{})
+}
+
+const dbVersion = 1
+
+// Config holds the Archiver's configuration.
+type Config struct {
+	// Path is the directory that holds the database files. It is created if
+	// it does not exist.
+	Path string
+
+	// MarketCfg specifies all of the markets that the Archiver should prepare.
+	MarketCfg []*dex.MarketInfo
+}
+
+// The lexi key-to-DBID mapping is shared by all tables, so every key is
+// prefixed with a byte that is unique to its table.
+const (
+	marketKeyPrefix byte = iota + 1
+	orderKeyPrefix
+	matchKeyPrefix
+	epochKeyPrefix
+	epochReportKeyPrefix
+	candleKeyPrefix
+	accountKeyPrefix
+	bondKeyPrefix
+	prepaidBondKeyPrefix
+	feeKeyKeyPrefix
+	pointsKeyPrefix
+)
+
+func prefixedKey(prefix byte, parts ...[]byte) []byte {
+	n := 1
+	for _, p := range parts {
+		n += len(p)
+	}
+	k := make([]byte, 1, n)
+	k[0] = prefix
+	for _, p := range parts {
+		k = append(k, p...)
+	}
+	return k
+}
+
+// marketBytes is the 8-byte base|quote prefix used in market-scoped index
+// entries.
+func marketBytes(base, quote uint32) []byte {
+	return append(encode.Uint32Bytes(base), encode.Uint32Bytes(quote)...)
+}
+
+func boolBytes(b bool) []byte {
+	if b {
+		return encode.ByteTrue
+	}
+	return encode.ByteFalse
+}
+
+func bytesBool(b []byte) bool {
+	return len(b) == 1 && b[0] == 1
+}
+
+// Archiver must implement server/db.DEXArchivist.
+type Archiver struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     *sync.WaitGroup
+	db     *lexi.DB
+
+	marketsMtx   sync.RWMutex
+	markets      map[string]*dex.MarketInfo
+	marketsTable *lexi.Table
+
+	orders         *lexi.Table
+	orderStatusIdx *lexi.Index // base|quote|status
+	orderAcctIdx   *lexi.Index // account|base|quote
+	orderCommitIdx *lexi.Index // commit
+
+	matches        *lexi.Table
+	matchMarketIdx *lexi.Index // base|quote|epoch time
+	matchActiveIdx *lexi.Index // base|quote, only active matches
+	matchTakerIdx  *lexi.Index // taker account
+	matchMakerIdx  *lexi.Index // maker account
+
+	epochs         *lexi.Table
+	epochReports   *lexi.Table
+	epochReportIdx *lexi.Index // base|quote|epoch end
+	candles        *lexi.Table
+	candleIdx      *lexi.Index // base|quote|duration|end stamp
+
+	accounts     *lexi.Table
+	bonds        *lexi.Table
+	bondAcctIdx  *lexi.Index // account|lock time
+	prepaidBonds *lexi.Table
+	feeKeys      *lexi.Table
+
+	points        *lexi.Table
+	pointsAcctIdx *lexi.Index // account|point ID
+	pointsMtx     sync.Mutex
+	lastPointID   int64
+
+	fatalMtx sync.RWMutex
+	fatal    chan struct{}
+	fatalErr error
+}
+
+// LastErr returns any fatal or unexpected error encountered in a recent query.
+// This may be used to check if the database had an unrecoverable error.
+func (a *Archiver) LastErr() error {
+	a.fatalMtx.RLock()
+	defer a.fatalMtx.RUnlock()
+	return a.fatalErr
+}
+
+// Fatal returns a nil or closed channel for select use. Use LastErr to get the
+// latest fatal error.
+func (a *Archiver) Fatal() <-chan struct{} {
+	a.fatalMtx.RLock()
+	defer a.fatalMtx.RUnlock()
+	return a.fatal
+}
+
+func (a *Archiver) fatalBackendErr(err error) {
+	if err == nil {
+		return
+	}
+	a.fatalMtx.Lock()
+	if a.fatalErr == nil {
+		close(a.fatal)
+	}
+	a.fatalErr = err
+	a.fatalMtx.Unlock()
+}
+
+// NewArchiver constructs a new Archiver. All tables and indexes are prepared,
+// and markets with a changed lot size have their books flushed. Use Close
+// when done with the Archiver.
+func NewArchiver(ctx context.Context, cfg *Config) (*Archiver, error) {
+	if cfg.Path == "" {
+		return nil, errors.New("no database path specified")
+	}
+	if err := os.MkdirAll(cfg.Path, 0700); err != nil {
+		return nil, fmt.Errorf("error creating db dir: %w", err)
+	}
+	ldb, err := lexi.New(&lexi.Config{
+		Path: cfg.Path,
+		Log:  log,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error initializing db: %w", err)
+	}
+
+	mktMap := make(map[string]*dex.MarketInfo, len(cfg.MarketCfg))
+	for _, mkt := range cfg.MarketCfg {
+		mktMap[mkt.Name] = mkt
+	}
+
+	a := &Archiver{
+		db:      ldb,
+		markets: mktMap,
+		fatal:   make(chan struct{}),
+	}
+	purgeMarkets, err := a.prepare(cfg.MarketCfg)
+	if err != nil {
+		ldb.Close()
+		return nil, err
+	}
+
+	a.ctx, a.cancel = context.WithCancel(ctx)
+	if a.wg, err = ldb.Connect(a.ctx); err != nil {
+		a.cancel()
+		ldb.Close()
+		return nil, err
+	}
+
+	for _, mkt := range purgeMarkets {
+		unbookedSells, unbookedBuys, err := a.FlushBook(mkt.Base, mkt.Quote)
+		if err != nil {
+			a.Close()
+			return nil, fmt.Errorf("failed to flush book for market %v: %w", mkt.Name, err)
+		}
+		log.Infof("Flushed %d sell orders and %d buy orders from market %v with a changed lot size.",
+			len(unbookedSells), len(unbookedBuys), mkt.Name)
+	}
+
+	return a, nil
+}
+
+// Close shuts down the database, returning when complete.
+func (a *Archiver) Close() error {
+	a.cancel()
+	a.wg.Wait()
+	return nil
+}
+
+// prepare checks the database version, constructs the tables and indexes, and
+// registers the configured markets. The markets with a changed lot size are
+// returned.
+func (a *Archiver) prepare(mktConfig []*dex.MarketInfo) ([]*dex.MarketInfo, error) {
+	ver, err := a.db.GetDBVersion()
+	if err != nil {
+		return nil, fmt.Errorf("error reading db version: %w", err)
+	}
+	switch {
+	case ver == 0: // fresh install
+		if err = a.db.SetDBVersion(dbVersion); err != nil {
+			return nil, fmt.Errorf("error setting db version: %w", err)
+		}
+		log.Infof("Created new database at version %d", dbVersion)
+	case ver > dbVersion:
+		return nil, fmt.Errorf("database version %d is from the future (current version %d)", ver, dbVersion)
+	}
+
+	if err = a.prepareOrderTables(); err != nil {
+		return nil, err
+	}
+	if err = a.prepareMatchTables(); err != nil {
+		return nil, err
+	}
+	if err = a.prepareEpochTables(); err != nil {
+		return nil, err
+	}
+	if err = a.prepareAccountTables(); err != nil {
+		return nil, err
+	}
+	if err = a.prepareReputationTables(); err != nil {
+		return nil, err
+	}
+
+	if a.marketsTable, err = a.db.Table("markets"); err != nil {
+		return nil, fmt.Errorf("error constructing markets table: %w", err)
+	}
+	log.Infof("Configuring %d markets: %v", len(mktConfig), mktConfig)
+	var purgeMarkets []*dex.MarketInfo
+	for _, mkt := range mktConfig {
+		purge, err := a.prepareMarket(mkt)
+		if err != nil {
+			return nil, err
+		}
+		if purge {
+			purgeMarkets = append(purgeMarkets, mkt)
+		}
+	}
+	return purgeMarkets, nil
+}
+
+// prepareMarket stores the market's lot size, returning true if the market
+// was previously stored with a different lot size.
+func (a *Archiver) prepareMarket(mkt *dex.MarketInfo) (purge bool, err error) {
+	k := prefixedKey(marketKeyPrefix, []byte(mkt.Name))
+	lotSizeB, err := a.marketsTable.GetRaw(k)
+	switch {
+	case errors.Is(err, lexi.ErrKeyNotFound):
+		log.Infof("New market specified in config: %s", mkt.Name)
+	case err != nil:
+		return false, fmt.Errorf("error reading market %s: %w", mkt.Name, err)
+	case len(lotSizeB) != 8:
+		return false, fmt.Errorf("invalid stored lot size for market %s", mkt.Name)
+	case encode.BytesToUint64(lotSizeB) == mkt.LotSize:
+		return false, nil
+	default:
+		purge = true
+	}
+	if err = a.marketsTable.Set(k, encode.Uint64Bytes(mkt.LotSize), lexi.WithReplace()); err != nil {
+		return false, fmt.Errorf("error storing market %s: %w", mkt.Name, err)
+	}
+	return purge, nil
+}
+
+// AddMarket adds a market that was not in the market config to the supported
+// markets. If the market was previously configured with a different lot size,
+// its book is flushed.
+func (a *Archiver) AddMarket(mkt *dex.MarketInfo) error {
+	purge, err := a.prepareMarket(mkt)
+	if err != nil {
+		return err
+	}
+	a.marketsMtx.Lock()
+	a.markets[mkt.Name] = mkt
+	a.marketsMtx.Unlock()
+	if purge {
+		unbookedSells, unbookedBuys, err := a.FlushBook(mkt.Base, mkt.Quote)
+		if err != nil {
+			return fmt.Errorf("failed to flush book for market %v: %w", mkt.Name, err)
+		}
+		log.Infof("Flushed %d sell orders and %d buy orders from market %v with a changed lot size.",
+			len(unbookedSells), len(unbookedBuys), mkt.Name)
+	}
+	return nil
+}
+
+// UpdateMarket changes the lot size and rate step of a supported market.
+// Unlike AddMarket, the book is not flushed, so the caller is responsible for
+// revoking booked orders that are incompatible with the new lot size.
+func (a *Archiver) UpdateMarket(mkt *dex.MarketInfo) error {
+	if _, err := a.market(mkt.Base, mkt.Quote); err != nil {
+		return err
+	}
+	k := prefixedKey(marketKeyPrefix, []byte(mkt.Name))
+	if err := a.marketsTable.Set(k, encode.Uint64Bytes(mkt.LotSize), lexi.WithReplace()); err != nil {
+		return fmt.Errorf("error storing market %s: %w", mkt.Name, err)
+	}
+	a.marketsMtx.Lock()
+	a.markets[mkt.Name] = mkt
+	a.marketsMtx.Unlock()
+	return nil
+}
+
+// RemoveMarket removes a market from the supported markets. The market's stored
+// orders and matches are kept.
+func (a *Archiver) RemoveMarket(mkt *dex.MarketInfo) {
+	a.marketsMtx.Lock()
+	delete(a.markets, mkt.Name)
+	a.marketsMtx.Unlock()
+}
+
+// marketsList returns the supported markets.
+func (a *Archiver) marketsList() []*dex.MarketInfo {
+	a.marketsMtx.RLock()
+	defer a.marketsMtx.RUnlock()
+	mkts := make([]*dex.MarketInfo, 0, len(a.markets))
+	for _, mkt := range a.markets {
+		mkts = append(mkts, mkt)
+	}
+	return mkts
+}
+
+// market returns the configured MarketInfo for the market, or an
+// ErrUnsupportedMarket ArchiveError.
+func (a *Archiver) market(base, quote uint32) (*dex.MarketInfo, error) {
+	marketName, err := dex.MarketName(base, quote)
+	if err != nil {
+		return nil, err
+	}
+	a.marketsMtx.RLock()
+	mkt, found := a.markets[marketName]
+	a.marketsMtx.RUnlock()
+	if !found {
+		return nil, db.ArchiveError{
+			Code:   db.ErrUnsupportedMarket,
+			Detail: fmt.Sprintf(`archiver does not support the market "%s"`, marketName),
+		}
+	}
+	return mkt, nil
+}
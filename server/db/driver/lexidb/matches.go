@@ -0,0 +1,758 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package lexidb
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"decred.org/dcrdex/dex/encode"
+	"decred.org/dcrdex/dex/lexi"
+	"decred.org/dcrdex/dex/order"
+	"decred.org/dcrdex/server/account"
+	"decred.org/dcrdex/server/db"
+	"github.com/dgraph-io/badger"
+)
+
+var (
+	_ db.MatchArchiver = (*Archiver)(nil)
+	_ db.SwapArchiver  = (*Archiver)(nil)
+)
+
+// dbMatch is a match with its swap data. Cancel order matches have no swap
+// and are stored inactive in MatchComplete status.
+type dbMatch struct {
+	db.MatchData
+	db.SwapData
+	base, quote uint32
+	isCancel    bool
+	forgiven    bool
+}
+
+const dbMatchPushes = 37
+
+// MarshalBinary encodes the dbMatch as a versioned blob.
+func (m *dbMatch) MarshalBinary() ([]byte, error) {
+	const matchVer = 0
+	return encode.BuildyBytes{matchVer}.
+		AddData(m.ID[:]).
+		AddData(encode.Uint32Bytes(m.base)).
+		AddData(encode.Uint32Bytes(m.quote)).
+		AddData(boolBytes(m.isCancel)).
+		AddData(boolBytes(m.forgiven)).
+		AddData(m.Taker[:]).
+		AddData(m.TakerAcct[:]).
+		AddData([]byte(m.TakerAddr)).
+		AddData(boolBytes(m.TakerSell)).
+		AddData(m.Maker[:]).
+		AddData(m.MakerAcct[:]).
+		AddData([]byte(m.MakerAddr)).
+		AddData(encode.Uint64Bytes(m.Epoch.Idx)).
+		AddData(encode.Uint64Bytes(m.Epoch.Dur)).
+		AddData(encode.Uint64Bytes(m.Quantity)).
+		AddData(encode.Uint64Bytes(m.Rate)).
+		AddData(encode.Uint64Bytes(m.BaseRate)).
+		AddData(encode.Uint64Bytes(m.QuoteRate)).
+		AddData(boolBytes(m.Active)).
+		AddData([]byte{byte(m.Status)}).
+		AddData(m.SigMatchAckMaker).
+		AddData(m.SigMatchAckTaker).
+		AddData(m.ContractA).
+		AddData(m.ContractACoinID).
+		AddData(encode.Uint64Bytes(uint64(m.ContractATime))).
+		AddData(m.ContractAAckSig).
+		AddData(m.ContractB).
+		AddData(m.ContractBCoinID).
+		AddData(encode.Uint64Bytes(uint64(m.ContractBTime))).
+		AddData(m.ContractBAckSig).
+		AddData(m.RedeemACoinID).
+		AddData(m.RedeemASecret).
+		AddData(encode.Uint64Bytes(uint64(m.RedeemATime))).
+		AddData(m.RedeemAAckSig).
+		AddData(m.RedeemBCoinID).
+		AddData(encode.Uint64Bytes(uint64(m.RedeemBTime))).
+		AddData(nil), nil // reserved
+}
+
+// UnmarshalBinary decodes the versioned blob into the dbMatch.
+func (m *dbMatch) UnmarshalBinary(b []byte) error {
+	const matchVer = 0
+	ver, pushes, err := encode.DecodeBlob(encode.CopySlice(b), dbMatchPushes)
+	if err != nil {
+		return fmt.Errorf("error decoding match blob: %w", err)
+	}
+	if ver != matchVer {
+		return fmt.Errorf("unknown match version %d", ver)
+	}
+	if len(pushes) != dbMatchPushes {
+		return fmt.Errorf("unknown number of match blob pushes %d", len(pushes))
+	}
+	for _, i := range []int{0, 5, 6, 9, 10} {
+		if len(pushes[i]) != 32 {
+			return fmt.Errorf("invalid match ID length %d in push %d", len(pushes[i]), i)
+		}
+	}
+	copy(m.ID[:], pushes[0])
+	m.base = encode.BytesToUint32(pushes[1])
+	m.quote = encode.BytesToUint32(pushes[2])
+	m.isCancel = bytesBool(pushes[3])
+	m.forgiven = bytesBool(pushes[4])
+	copy(m.Taker[:], pushes[5])
+	copy(m.TakerAcct[:], pushes[6])
+	m.TakerAddr = string(pushes[7])
+	m.TakerSell = bytesBool(pushes[8])
+	copy(m.Maker[:], pushes[9])
+	copy(m.MakerAcct[:], pushes[10])
+	m.MakerAddr = string(pushes[11])
+	m.Epoch.Idx = encode.BytesToUint64(pushes[12])
+	m.Epoch.Dur = encode.BytesToUint64(pushes[13])
+	m.Quantity = encode.BytesToUint64(pushes[14])
+	m.Rate = encode.BytesToUint64(pushes[15])
+	m.BaseRate = encode.BytesToUint64(pushes[16])
+	m.QuoteRate = encode.BytesToUint64(pushes[17])
+	m.Active = bytesBool(pushes[18])
+	if len(pushes[19]) != 1 {
+		return errors.New("invalid match status encoding")
+	}
+	m.Status = order.MatchStatus(pushes[19][0])
+	m.SigMatchAckMaker = pushes[20]
+	m.SigMatchAckTaker = pushes[21]
+	m.ContractA = pushes[22]
+	m.ContractACoinID = pushes[23]
+	m.ContractATime = int64(encode.BytesToUint64(pushes[24]))
+	m.ContractAAckSig = pushes[25]
+	m.ContractB = pushes[26]
+	m.ContractBCoinID = pushes[27]
+	m.ContractBTime = int64(encode.BytesToUint64(pushes[28]))
+	m.ContractBAckSig = pushes[29]
+	m.RedeemACoinID = pushes[30]
+	m.RedeemASecret = pushes[31]
+	m.RedeemATime = int64(encode.BytesToUint64(pushes[32]))
+	m.RedeemAAckSig = pushes[33]
+	m.RedeemBCoinID = pushes[34]
+	m.RedeemBTime = int64(encode.BytesToUint64(pushes[35]))
+	return nil
+}
+
+// epochTime is the start time of the match's epoch, for ordering.
+func (m *dbMatch) epochTime() uint64 {
+	return m.Epoch.Idx * m.Epoch.Dur
+}
+
+// lastTime is the time of the last swap action, or the end of the match's
+// epoch if there were none.
+func (m *dbMatch) lastTime() int64 {
+	return max(int64(m.Epoch.Idx+1)*int64(m.Epoch.Dur),
+		m.ContractATime, m.ContractBTime, m.RedeemATime, m.RedeemBTime)
+}
+
+// atFault indicates if the user is responsible for the failure of the match.
+func (m *dbMatch) atFault(aid account.AccountID) bool {
+	if m.isCancel || m.Active || m.forgiven {
+		return false
+	}
+	switch m.Status {
+	case order.NewlyMatched, order.TakerSwapCast:
+		return m.MakerAcct == aid
+	case order.MakerSwapCast, order.MakerRedeemed:
+		return m.TakerAcct == aid
+	}
+	return false
+}
+
+// success indicates if the user has successfully completed their part of the
+// swap. A maker is done at MakerRedeemed, unless also the taker.
+func (m *dbMatch) success(aid account.AccountID) bool {
+	if m.isCancel {
+		return false
+	}
+	switch m.Status {
+	case order.MatchComplete:
+		return true
+	case order.MakerRedeemed:
+		return m.MakerAcct == aid && m.TakerAcct != aid
+	}
+	return false
+}
+
+func (m *dbMatch) matchDataWithCoins() *db.MatchDataWithCoins {
+	return &db.MatchDataWithCoins{
+		MatchData:       m.MatchData,
+		MakerSwapCoin:   m.ContractACoinID,
+		MakerRedeemCoin: m.RedeemACoinID,
+		TakerSwapCoin:   m.ContractBCoinID,
+		TakerRedeemCoin: m.RedeemBCoinID,
+	}
+}
+
+// matchKey is the key for a match in a market. As with the pg driver's
+// per-market tables, match IDs are only unique within a market.
+func matchKey(base, quote uint32, mid order.MatchID) []byte {
+	return prefixedKey(matchKeyPrefix, marketBytes(base, quote), mid[:])
+}
+
+func matchMarketEntry(m *dbMatch) []byte {
+	return append(marketBytes(m.base, m.quote), encode.Uint64Bytes(m.epochTime())...)
+}
+
+func (a *Archiver) prepareMatchTables() (err error) {
+	if a.matches, err = a.db.Table("matches"); err != nil {
+		return fmt.Errorf("error constructing matches table: %w", err)
+	}
+	matchIndex := func(name string, f func(m *dbMatch) ([]byte, error)) (*lexi.Index, error) {
+		idx, err := a.matches.AddIndex(name, func(_, v lexi.KV) ([]byte, error) {
+			m, is := v.(*dbMatch)
+			if !is {
+				return nil, fmt.Errorf("wrong type %T", v)
+			}
+			return f(m)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error constructing %s index: %w", name, err)
+		}
+		return idx, nil
+	}
+	// All matches by market and epoch.
+	a.matchMarketIdx, err = matchIndex("match-market", func(m *dbMatch) ([]byte, error) {
+		return matchMarketEntry(m), nil
+	})
+	if err != nil {
+		return err
+	}
+	// Active trade matches by market and epoch.
+	a.matchActiveIdx, err = matchIndex("match-active", func(m *dbMatch) ([]byte, error) {
+		if !m.Active || m.isCancel {
+			return nil, lexi.ErrNotIndexed
+		}
+		return matchMarketEntry(m), nil
+	})
+	if err != nil {
+		return err
+	}
+	// Matches by the taker's and maker's accounts.
+	a.matchTakerIdx, err = matchIndex("match-taker", func(m *dbMatch) ([]byte, error) {
+		return append(m.TakerAcct[:], matchMarketEntry(m)...), nil
+	})
+	if err != nil {
+		return err
+	}
+	a.matchMakerIdx, err = matchIndex("match-maker", func(m *dbMatch) ([]byte, error) {
+		return append(m.MakerAcct[:], matchMarketEntry(m)...), nil
+	})
+	return err
+}
+
+// iterateMatches decodes the matches in the index with the given prefix.
+func iterateMatches(idx *lexi.Index, prefix []byte, f func(m *dbMatch) error, opts ...lexi.IterationOption) error {
+	return idx.Iterate(prefix, func(it *lexi.Iter) error {
+		return it.V(func(vB []byte) error {
+			m := new(dbMatch)
+			if err := m.UnmarshalBinary(vB); err != nil {
+				return fmt.Errorf("error decoding match: %w", err)
+			}
+			return f(m)
+		})
+	}, opts...)
+}
+
+// userMatches collects the matches in which the user is either the taker or
+// maker. The prefix following the account ID can narrow the search, e.g. to a
+// market. Self-matches are only returned once.
+func (a *Archiver) userMatches(aid account.AccountID, marketPrefix []byte, f func(m *dbMatch) bool) ([]*dbMatch, error) {
+	var ms []*dbMatch
+	seen := make(map[db.MarketMatchID]bool)
+	prefix := append(aid[:], marketPrefix...)
+	for _, idx := range []*lexi.Index{a.matchTakerIdx, a.matchMakerIdx} {
+		err := iterateMatches(idx, prefix, func(m *dbMatch) error {
+			k := db.MarketMatchID{MatchID: m.ID, Base: m.base, Quote: m.quote}
+			if seen[k] || !f(m) {
+				return nil
+			}
+			seen[k] = true
+			ms = append(ms, m)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ms, nil
+}
+
+// loadMatch retrieves the stored match for the market. If the match is not
+// found, the error is an ErrUnknownMatch ArchiveError.
+func (a *Archiver) loadMatch(mid order.MatchID, base, quote uint32, opts ...lexi.GetOption) (*dbMatch, error) {
+	m := new(dbMatch)
+	if err := a.matches.Get(matchKey(base, quote, mid), m, opts...); err != nil {
+		if errors.Is(err, lexi.ErrKeyNotFound) {
+			return nil, db.ArchiveError{Code: db.ErrUnknownMatch}
+		}
+		return nil, err
+	}
+	if m.base != base || m.quote != quote {
+		return nil, db.ArchiveError{Code: db.ErrUnknownMatch}
+	}
+	return m, nil
+}
+
+// InsertMatch stores a new match, or updates the quantity and status of an
+// existing one. Cancel order matches are stored with MatchComplete status and
+// are never updated.
+func (a *Archiver) InsertMatch(match *order.Match) error {
+	base, quote := match.Maker.Base(), match.Maker.Quote()
+	if _, err := a.market(base, quote); err != nil {
+		return err
+	}
+
+	var takerAddr string
+	tt := match.Taker.Trade()
+	if tt != nil {
+		takerAddr = tt.SwapAddress()
+	}
+
+	mid := match.ID()
+	err := a.db.Update(func(txn *badger.Txn) error {
+		m, err := a.loadMatch(mid, base, quote, lexi.WithGetTxn(txn))
+		switch {
+		case err == nil:
+			if m.isCancel {
+				return nil // do nothing
+			}
+			m.Quantity = match.Quantity
+			m.Status = match.Status
+			return a.matches.Set(matchKey(base, quote, mid), m, lexi.WithReplace(), lexi.WithTxn(txn))
+		case !db.IsErrMatchUnknown(err):
+			return err
+		}
+
+		m = &dbMatch{
+			MatchData: db.MatchData{
+				ID:        mid,
+				Taker:     match.Taker.ID(),
+				TakerAcct: match.Taker.User(),
+				Maker:     match.Maker.ID(),
+				MakerAcct: match.Maker.User(),
+				Epoch: order.EpochID{
+					Idx: match.Epoch.Idx,
+					Dur: match.Epoch.Dur,
+				},
+				Quantity: match.Quantity,
+				Rate:     match.Rate,
+			},
+			base:  base,
+			quote: quote,
+		}
+		// Cancel orders do not store taker or maker addresses, and are stored
+		// with complete status with no active swap negotiation.
+		if takerAddr == "" {
+			m.isCancel = true
+			m.Status = order.MatchComplete
+		} else {
+			m.TakerAddr = takerAddr
+			m.TakerSell = tt.Sell
+			m.MakerAddr = match.Maker.Trade().SwapAddress()
+			m.BaseRate = match.FeeRateBase
+			m.QuoteRate = match.FeeRateQuote
+			m.Status = match.Status
+			m.Active = true
+		}
+		return a.matches.Set(matchKey(base, quote, mid), m, lexi.WithTxn(txn))
+	})
+	if err != nil {
+		a.fatalBackendErr(err)
+		return err
+	}
+	return nil
+}
+
+// MatchByID retrieves the match for the given MatchID.
+func (a *Archiver) MatchByID(mid order.MatchID, base, quote uint32) (*db.MatchData, error) {
+	if _, err := a.market(base, quote); err != nil {
+		return nil, err
+	}
+	m, err := a.loadMatch(mid, base, quote)
+	if err != nil {
+		return nil, err
+	}
+	return &m.MatchData, nil
+}
+
+// UserMatches retrieves all matches involving a user on the given market.
+func (a *Archiver) UserMatches(aid account.AccountID, base, quote uint32) ([]*db.MatchData, error) {
+	if _, err := a.market(base, quote); err != nil {
+		return nil, err
+	}
+	ms, err := a.userMatches(aid, marketBytes(base, quote), func(*dbMatch) bool { return true })
+	if err != nil {
+		return nil, err
+	}
+	mds := make([]*db.MatchData, 0, len(ms))
+	for _, m := range ms {
+		mds = append(mds, &m.MatchData)
+	}
+	return mds, nil
+}
+
+// AllActiveUserMatches retrieves a MatchData slice for active matches in all
+// markets involving the given user. Swaps that have successfully completed or
+// failed are not included.
+func (a *Archiver) AllActiveUserMatches(aid account.AccountID) ([]*db.MatchData, error) {
+	ms, err := a.userMatches(aid, nil, func(m *dbMatch) bool {
+		if !m.Active {
+			return false
+		}
+		_, err := a.market(m.base, m.quote)
+		return err == nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	mds := make([]*db.MatchData, 0, len(ms))
+	for _, m := range ms {
+		mds = append(mds, &m.MatchData)
+	}
+	return mds, nil
+}
+
+// CompletedAndAtFaultMatchStats retrieves the outcomes of matches that were (1)
+// successfully completed by the specified user, or (2) failed with the user
+// being the at-fault party. Note that the MakerRedeemed match status may be
+// either a success or failure depending on if the user was the maker or taker
+// in the swap, respectively, and the MatchOutcome.Fail flag disambiguates this.
+func (a *Archiver) CompletedAndAtFaultMatchStats(aid account.AccountID, lastN int) ([]*db.MatchOutcome, error) {
+	var outcomes []*db.MatchOutcome
+	_, err := a.userMatches(aid, nil, func(m *dbMatch) bool {
+		success := m.success(aid)
+		if !success && !m.atFault(aid) {
+			return false
+		}
+		outcomes = append(outcomes, &db.MatchOutcome{
+			Status: m.Status,
+			ID:     m.ID,
+			Fail:   !success,
+			Time:   m.lastTime(),
+			Value:  m.Quantity,
+			Base:   m.base,
+			Quote:  m.quote,
+		})
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(outcomes, func(i, j int) bool {
+		return outcomes[i].Time < outcomes[j].Time // ascending
+	})
+	if len(outcomes) > lastN {
+		outcomes = outcomes[len(outcomes)-lastN:]
+	}
+	return outcomes, nil
+}
+
+// UserMatchFails retrieves up to the last n most recent failed and unforgiven
+// match outcomes for the user.
+func (a *Archiver) UserMatchFails(aid account.AccountID, lastN int) ([]*db.MatchFail, error) {
+	ms, err := a.userMatches(aid, nil, func(m *dbMatch) bool {
+		return m.atFault(aid)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(ms, func(i, j int) bool {
+		return ms[i].lastTime() > ms[j].lastTime() // descending
+	})
+	if len(ms) > lastN {
+		ms = ms[:lastN]
+	}
+	fails := make([]*db.MatchFail, 0, len(ms))
+	for _, m := range ms {
+		fails = append(fails, &db.MatchFail{
+			ID:     m.ID,
+			Status: m.Status,
+		})
+	}
+	return fails, nil
+}
+
+// ForgiveMatchFail marks the specified match as forgiven. Since this is an
+// administrative function, the burden is on the operator to ensure the match
+// can actually be forgiven (inactive, not already forgiven, and not in
+// MatchComplete status).
+func (a *Archiver) ForgiveMatchFail(mid order.MatchID) (bool, error) {
+	for _, mkt := range a.marketsList() {
+		var forgiven bool
+		err := a.db.Update(func(txn *badger.Txn) error {
+			forgiven = false
+			m, err := a.loadMatch(mid, mkt.Base, mkt.Quote, lexi.WithGetTxn(txn))
+			if err != nil {
+				return err
+			}
+			if m.Active {
+				return nil // not eligible to forgive
+			}
+			m.forgiven, forgiven = true, true
+			return a.matches.Set(matchKey(mkt.Base, mkt.Quote, mid), m, lexi.WithReplace(), lexi.WithTxn(txn))
+		})
+		if err != nil && !db.IsErrMatchUnknown(err) {
+			return false, err
+		}
+		if forgiven {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// MarketMatches retrieves all active matches for a market.
+func (a *Archiver) MarketMatches(base, quote uint32) ([]*db.MatchDataWithCoins, error) {
+	var ms []*db.MatchDataWithCoins
+	f := func(m *db.MatchDataWithCoins) error {
+		ms = append(ms, m)
+		return nil
+	}
+	_, err := a.MarketMatchesStreaming(base, quote, false, -1, f) // N ignored with only active
+	if err != nil {
+		return nil, err
+	}
+	return ms, nil
+}
+
+// MarketMatchesStreaming streams all active matches for a market into the
+// provided function. If includeInactive, all matches are streamed. A limit may
+// be specified, where <=0 means unlimited.
+func (a *Archiver) MarketMatchesStreaming(base, quote uint32, includeInactive bool, N int64, f func(*db.MatchDataWithCoins) error) (int, error) {
+	if _, err := a.market(base, quote); err != nil {
+		return 0, err
+	}
+	idx := a.matchActiveIdx
+	if includeInactive {
+		idx = a.matchMarketIdx
+	} else {
+		N = 0 // no limit for active matches
+	}
+
+	// Collect the matches before calling f so that f may use the Archiver.
+	var ms []*dbMatch
+	err := iterateMatches(idx, marketBytes(base, quote), func(m *dbMatch) error {
+		if m.isCancel {
+			return nil
+		}
+		ms = append(ms, m)
+		if N > 0 && int64(len(ms)) >= N {
+			return lexi.ErrEndIteration
+		}
+		return nil
+	}, lexi.WithReverse())
+	if err != nil {
+		return 0, err
+	}
+
+	var n int
+	for _, m := range ms {
+		if err := f(m.matchDataWithCoins()); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// MatchStatuses retrieves a *db.MatchStatus for every match in matchIDs for
+// which there is data, and for which the user is at least one of the parties.
+// It is not an error if a match ID in matchIDs does not match, i.e. the
+// returned slice need not be the same length as matchIDs.
+func (a *Archiver) MatchStatuses(aid account.AccountID, base, quote uint32, matchIDs []order.MatchID) ([]*db.MatchStatus, error) {
+	if _, err := a.market(base, quote); err != nil {
+		return nil, err
+	}
+	statuses := make([]*db.MatchStatus, 0, len(matchIDs))
+	for _, mid := range matchIDs {
+		m, err := a.loadMatch(mid, base, quote)
+		if err != nil {
+			if db.IsErrMatchUnknown(err) {
+				continue
+			}
+			return nil, err
+		}
+		isTaker, isMaker := m.TakerAcct == aid, m.MakerAcct == aid
+		if !isTaker && !isMaker {
+			continue
+		}
+		statuses = append(statuses, &db.MatchStatus{
+			ID:            m.ID,
+			Status:        m.Status,
+			MakerContract: m.ContractA,
+			TakerContract: m.ContractB,
+			MakerSwap:     m.ContractACoinID,
+			TakerSwap:     m.ContractBCoinID,
+			MakerRedeem:   m.RedeemACoinID,
+			TakerRedeem:   m.RedeemBCoinID,
+			Secret:        m.RedeemASecret,
+			Active:        m.Active,
+			TakerSell:     m.TakerSell,
+			IsTaker:       isTaker,
+			IsMaker:       isMaker,
+		})
+	}
+	return statuses, nil
+}
+
+// ActiveSwaps loads the full details for all active swaps across all markets.
+func (a *Archiver) ActiveSwaps() ([]*db.SwapDataFull, error) {
+	var sd []*db.SwapDataFull
+	for _, mkt := range a.marketsList() {
+		err := iterateMatches(a.matchActiveIdx, marketBytes(mkt.Base, mkt.Quote), func(m *dbMatch) error {
+			sd = append(sd, &db.SwapDataFull{
+				Base:      mkt.Base,
+				Quote:     mkt.Quote,
+				MatchData: &m.MatchData,
+				SwapData:  &m.SwapData,
+			})
+			return nil
+		}, lexi.WithReverse())
+		if err != nil {
+			return nil, err
+		}
+	}
+	return sd, nil
+}
+
+// SwapData retrieves the match status and all the SwapData for a match.
+func (a *Archiver) SwapData(mid db.MarketMatchID) (order.MatchStatus, *db.SwapData, error) {
+	if _, err := a.market(mid.Base, mid.Quote); err != nil {
+		return 0, nil, err
+	}
+	m, err := a.loadMatch(mid.MatchID, mid.Base, mid.Quote)
+	if err != nil {
+		return 0, nil, err
+	}
+	return m.Status, &m.SwapData, nil
+}
+
+// updateMatch applies the update to the stored match. The match must exist
+// for the market, otherwise an error is returned.
+func (a *Archiver) updateMatch(mid db.MarketMatchID, update func(m *dbMatch)) error {
+	if _, err := a.market(mid.Base, mid.Quote); err != nil {
+		return err
+	}
+	err := a.db.Update(func(txn *badger.Txn) error {
+		m, err := a.loadMatch(mid.MatchID, mid.Base, mid.Quote, lexi.WithGetTxn(txn))
+		if err != nil {
+			return err
+		}
+		update(m)
+		return a.matches.Set(matchKey(mid.Base, mid.Quote, mid.MatchID), m, lexi.WithReplace(), lexi.WithTxn(txn))
+	})
+	if err != nil {
+		if db.IsErrMatchUnknown(err) {
+			return fmt.Errorf("updateMatch: no match %v to update", mid)
+		}
+		a.fatalBackendErr(err)
+		return err
+	}
+	return nil
+}
+
+// Match acknowledgement message signatures.
+
+// SaveMatchAckSigA records the match data acknowledgement signature from swap
+// party A (the initiator), which is the maker in the DEX.
+func (a *Archiver) SaveMatchAckSigA(mid db.MarketMatchID, sig []byte) error {
+	return a.updateMatch(mid, func(m *dbMatch) {
+		m.SigMatchAckMaker = sig
+	})
+}
+
+// SaveMatchAckSigB records the match data acknowledgement signature from swap
+// party B (the participant), which is the taker in the DEX.
+func (a *Archiver) SaveMatchAckSigB(mid db.MarketMatchID, sig []byte) error {
+	return a.updateMatch(mid, func(m *dbMatch) {
+		m.SigMatchAckTaker = sig
+	})
+}
+
+// Swap contracts, and counterparty audit acknowledgement signatures.
+
+// SaveContractA records party A's swap contract script and the coinID (e.g.
+// transaction output) containing the contract on chain X. Note that this
+// contract contains the secret hash.
+func (a *Archiver) SaveContractA(mid db.MarketMatchID, contract []byte, coinID []byte, timestamp int64) error {
+	return a.updateMatch(mid, func(m *dbMatch) {
+		m.Status = order.MakerSwapCast
+		m.ContractACoinID, m.ContractA, m.ContractATime = coinID, contract, timestamp
+	})
+}
+
+// SaveAuditAckSigB records party B's signature acknowledging their audit of A's
+// swap contract.
+func (a *Archiver) SaveAuditAckSigB(mid db.MarketMatchID, sig []byte) error {
+	return a.updateMatch(mid, func(m *dbMatch) {
+		m.ContractAAckSig = sig
+	})
+}
+
+// SaveContractB records party B's swap contract script and the coinID (e.g.
+// transaction output) containing the contract on chain Y.
+func (a *Archiver) SaveContractB(mid db.MarketMatchID, contract []byte, coinID []byte, timestamp int64) error {
+	return a.updateMatch(mid, func(m *dbMatch) {
+		m.Status = order.TakerSwapCast
+		m.ContractBCoinID, m.ContractB, m.ContractBTime = coinID, contract, timestamp
+	})
+}
+
+// SaveAuditAckSigA records party A's signature acknowledging their audit of B's
+// swap contract.
+func (a *Archiver) SaveAuditAckSigA(mid db.MarketMatchID, sig []byte) error {
+	return a.updateMatch(mid, func(m *dbMatch) {
+		m.ContractBAckSig = sig
+	})
+}
+
+// Redemption transactions, and counterparty acknowledgement signatures.
+
+// SaveRedeemA records party A's redemption coinID (e.g. transaction output),
+// which spends party B's swap contract on chain Y, and the secret revealed by
+// the signature script of the input spending the contract. Note that this
+// transaction will contain the secret, which party B extracts.
+func (a *Archiver) SaveRedeemA(mid db.MarketMatchID, coinID, secret []byte, timestamp int64) error {
+	return a.updateMatch(mid, func(m *dbMatch) {
+		m.Status = order.MakerRedeemed
+		m.RedeemACoinID, m.RedeemASecret, m.RedeemATime = coinID, secret, timestamp
+	})
+}
+
+// SaveRedeemAckSigB records party B's signature acknowledging party A's
+// redemption, which spent their swap contract on chain Y and revealed the
+// secret.
+func (a *Archiver) SaveRedeemAckSigB(mid db.MarketMatchID, sig []byte) error {
+	return a.updateMatch(mid, func(m *dbMatch) {
+		m.RedeemAAckSig = sig
+	})
+}
+
+// SaveRedeemB records party B's redemption coinID (e.g. transaction output),
+// which spends party A's swap contract on chain X. The match is complete, and
+// is flagged as inactive.
+func (a *Archiver) SaveRedeemB(mid db.MarketMatchID, coinID []byte, timestamp int64) error {
+	return a.updateMatch(mid, func(m *dbMatch) {
+		m.Status = order.MatchComplete
+		m.RedeemBCoinID, m.RedeemBTime = coinID, timestamp
+		m.Active = false
+	})
+}
+
+// SetMatchInactive flags the match as done/inactive. This is not necessary if
+// SaveRedeemB is run for the match since it will flag the match as done. If
+// forgive is true, the failure is not counted against the at-fault user.
+func (a *Archiver) SetMatchInactive(mid db.MarketMatchID, forgive bool) error {
+	return a.updateMatch(mid, func(m *dbMatch) {
+		m.Active = false
+		if forgive {
+			m.forgiven = true
+		}
+	})
+}
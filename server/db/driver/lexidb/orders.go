@@ -0,0 +1,918 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package lexidb
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"decred.org/dcrdex/dex"
+	"decred.org/dcrdex/dex/encode"
+	"decred.org/dcrdex/dex/lexi"
+	"decred.org/dcrdex/dex/order"
+	"decred.org/dcrdex/server/account"
+	"decred.org/dcrdex/server/db"
+	"github.com/dgraph-io/badger"
+)
+
+var _ db.OrderArchiver = (*Archiver)(nil)
+
+// orderStatus is the archived status of an order. The values match those of
+// the pg driver so that the two backends have identical semantics.
+type orderStatus int16
+
+const (
+	orderStatusUnknown orderStatus = iota
+	orderStatusEpoch
+	orderStatusBooked
+	orderStatusExecuted
+	orderStatusFailed // failed helps distinguish matched from unmatched executed cancel orders
+	orderStatusCanceled
+	orderStatusRevoked // indicates a trade order was revoked, or for a cancel order that the cancel is server-generated
+)
+
+func marketToDBStatus(status order.OrderStatus) orderStatus {
+	switch status {
+	case order.OrderStatusEpoch:
+		return orderStatusEpoch
+	case order.OrderStatusBooked:
+		return orderStatusBooked
+	case order.OrderStatusExecuted:
+		return orderStatusExecuted
+	case order.OrderStatusCanceled:
+		return orderStatusCanceled
+	case order.OrderStatusRevoked:
+		return orderStatusRevoked
+	}
+	return orderStatusUnknown
+}
+
+func dbToMarketStatus(status orderStatus) order.OrderStatus {
+	switch status {
+	case orderStatusEpoch:
+		return order.OrderStatusEpoch
+	case orderStatusBooked:
+		return order.OrderStatusBooked
+	case orderStatusExecuted, orderStatusFailed: // failed is executed as far as the market is concerned
+		return order.OrderStatusExecuted
+	case orderStatusCanceled:
+		return order.OrderStatusCanceled
+	case orderStatusRevoked, -orderStatusRevoked: // negative revoke status means forgiven preimage miss
+		return order.OrderStatusRevoked
+	}
+	return order.OrderStatusUnknown
+}
+
+func (status orderStatus) String() string {
+	switch status {
+	case orderStatusFailed:
+		return "failed"
+	default:
+		return dbToMarketStatus(status).String()
+	}
+}
+
+func (status orderStatus) active() bool {
+	switch status {
+	case orderStatusEpoch, orderStatusBooked:
+		return true
+	case orderStatusCanceled, orderStatusRevoked, -orderStatusRevoked,
+		orderStatusExecuted, orderStatusFailed, orderStatusUnknown:
+		return false
+	default:
+		panic("unknown order status!") // programmer error
+	}
+}
+
+const (
+	exemptEpochIdx  int64 = -1
+	countedEpochIdx int64 = 0
+	dummyEpochDur   int64 = 1 // for idx*duration math
+)
+
+// dbOrder is an order with the data that the archiver tracks for it. The
+// filled amount of a trade order is stored with the encoded order.
+type dbOrder struct {
+	ord          order.Order
+	status       orderStatus
+	epochIdx     int64
+	epochDur     int64
+	epochGap     int32
+	preimage     []byte // nil until revealed
+	completeTime int64  // zero until the swaps complete
+}
+
+// MarshalBinary encodes the dbOrder as a versioned blob.
+func (o *dbOrder) MarshalBinary() ([]byte, error) {
+	const orderVer = 0
+	return encode.BuildyBytes{orderVer}.
+		AddData(order.EncodeOrder(o.ord)).
+		AddData(encode.Uint16Bytes(uint16(o.status))).
+		AddData(encode.Uint64Bytes(uint64(o.epochIdx))).
+		AddData(encode.Uint64Bytes(uint64(o.epochDur))).
+		AddData(encode.Uint32Bytes(uint32(o.epochGap))).
+		AddData(o.preimage).
+		AddData(encode.Uint64Bytes(uint64(o.completeTime))), nil
+}
+
+// UnmarshalBinary decodes the versioned blob into the dbOrder.
+func (o *dbOrder) UnmarshalBinary(b []byte) error {
+	const orderVer = 0
+	ver, pushes, err := encode.DecodeBlob(encode.CopySlice(b), 7)
+	if err != nil {
+		return fmt.Errorf("error decoding order blob: %w", err)
+	}
+	if ver != orderVer {
+		return fmt.Errorf("unknown order version %d", ver)
+	}
+	if len(pushes) != 7 {
+		return fmt.Errorf("unknown number of order blob pushes %d", len(pushes))
+	}
+	if o.ord, err = order.DecodeOrder(pushes[0]); err != nil {
+		return fmt.Errorf("error decoding order: %w", err)
+	}
+	prefix := o.ord.Prefix()
+	prefix.ClientTime, prefix.ServerTime = prefix.ClientTime.UTC(), prefix.ServerTime.UTC()
+	o.status = orderStatus(int16(encode.IntCoder.Uint16(pushes[1])))
+	o.epochIdx = int64(encode.BytesToUint64(pushes[2]))
+	o.epochDur = int64(encode.BytesToUint64(pushes[3]))
+	o.epochGap = int32(encode.BytesToUint32(pushes[4]))
+	o.preimage = pushes[5]
+	o.completeTime = int64(encode.BytesToUint64(pushes[6]))
+	return nil
+}
+
+// filled is the filled amount of a trade order, or -1 for a cancel order.
+func (o *dbOrder) filled() int64 {
+	if t := o.ord.Trade(); t != nil {
+		return int64(t.Filled())
+	}
+	return -1
+}
+
+func orderKey(oid order.OrderID) []byte {
+	return prefixedKey(orderKeyPrefix, oid[:])
+}
+
+func orderStatusIndexEntry(base, quote uint32, status orderStatus) []byte {
+	return append(marketBytes(base, quote), byte(int8(status)))
+}
+
+func (a *Archiver) prepareOrderTables() (err error) {
+	if a.orders, err = a.db.Table("orders"); err != nil {
+		return fmt.Errorf("error constructing orders table: %w", err)
+	}
+	// Orders by market and status, e.g. to load the book.
+	a.orderStatusIdx, err = a.orders.AddIndex("order-status", func(_, v lexi.KV) ([]byte, error) {
+		o, is := v.(*dbOrder)
+		if !is {
+			return nil, fmt.Errorf("wrong type %T", v)
+		}
+		return orderStatusIndexEntry(o.ord.Base(), o.ord.Quote(), o.status), nil
+	})
+	if err != nil {
+		return fmt.Errorf("error constructing order status index: %w", err)
+	}
+	// Orders by account and market.
+	a.orderAcctIdx, err = a.orders.AddIndex("order-account", func(_, v lexi.KV) ([]byte, error) {
+		o, is := v.(*dbOrder)
+		if !is {
+			return nil, fmt.Errorf("wrong type %T", v)
+		}
+		user := o.ord.User()
+		return append(user[:], marketBytes(o.ord.Base(), o.ord.Quote())...), nil
+	})
+	if err != nil {
+		return fmt.Errorf("error constructing order account index: %w", err)
+	}
+	// Orders by commitment. Server-generated cancel orders have no commitment
+	// and are not indexed.
+	a.orderCommitIdx, err = a.orders.AddIndex("order-commit", func(_, v lexi.KV) ([]byte, error) {
+		o, is := v.(*dbOrder)
+		if !is {
+			return nil, fmt.Errorf("wrong type %T", v)
+		}
+		commit := o.ord.Commitment()
+		if commit.IsZero() {
+			return nil, lexi.ErrNotIndexed
+		}
+		return commit[:], nil
+	})
+	if err != nil {
+		return fmt.Errorf("error constructing order commit index: %w", err)
+	}
+	return nil
+}
+
+// loadOrder retrieves the stored order for the market. If the order is not
+// found, the error is an ErrUnknownOrder ArchiveError.
+func (a *Archiver) loadOrder(oid order.OrderID, base, quote uint32, opts ...lexi.GetOption) (*dbOrder, error) {
+	o := new(dbOrder)
+	if err := a.orders.Get(orderKey(oid), o, opts...); err != nil {
+		if errors.Is(err, lexi.ErrKeyNotFound) {
+			return nil, db.ArchiveError{Code: db.ErrUnknownOrder}
+		}
+		return nil, err
+	}
+	if o.ord.Base() != base || o.ord.Quote() != quote {
+		return nil, db.ArchiveError{Code: db.ErrUnknownOrder}
+	}
+	return o, nil
+}
+
+// iterateOrders decodes the orders in the index with the given prefix.
+func iterateOrders(idx *lexi.Index, prefix []byte, f func(o *dbOrder) error) error {
+	return idx.Iterate(prefix, func(it *lexi.Iter) error {
+		return it.V(func(vB []byte) error {
+			o := new(dbOrder)
+			if err := o.UnmarshalBinary(vB); err != nil {
+				return fmt.Errorf("error decoding order: %w", err)
+			}
+			return f(o)
+		})
+	})
+}
+
+// Order retrieves an order with the given OrderID, stored for the market
+// specified by the given base and quote assets. A non-nil error will be
+// returned if the market is not recognized. If the order is not found, the
+// error value is ErrUnknownOrder, and the type is order.OrderStatusUnknown.
+func (a *Archiver) Order(oid order.OrderID, base, quote uint32) (order.Order, order.OrderStatus, error) {
+	if _, err := a.market(base, quote); err != nil {
+		return nil, order.OrderStatusUnknown, err
+	}
+	o, err := a.loadOrder(oid, base, quote)
+	if err != nil {
+		return nil, order.OrderStatusUnknown, err
+	}
+	return o.ord, dbToMarketStatus(o.status), nil
+}
+
+// NewEpochOrder stores the given order with epoch status. This is equivalent to
+// StoreOrder with OrderStatusEpoch.
+func (a *Archiver) NewEpochOrder(ord order.Order, epochIdx, epochDur int64, epochGap int32) error {
+	return a.storeOrder(ord, epochIdx, epochDur, epochGap, orderStatusEpoch)
+}
+
+// NewArchivedCancel stores a cancel order directly in the executed state. This
+// is used for orders that are canceled when the market is suspended, and
+// therefore do not need to be matched.
+func (a *Archiver) NewArchivedCancel(ord *order.CancelOrder, epochID, epochDur int64) error {
+	if _, err := a.market(ord.Base(), ord.Quote()); err != nil {
+		return err
+	}
+	return a.insertOrder(&dbOrder{
+		ord:      ord,
+		status:   orderStatusExecuted,
+		epochIdx: epochID,
+		epochDur: epochDur,
+		epochGap: db.EpochGapNA,
+	})
+}
+
+// StoreOrder stores an order for the specified epoch ID (idx:dur) with the
+// provided status. The market is determined from the Order. A non-nil error
+// will be returned if the market is not recognized. All orders are validated
+// via server/db.ValidateOrder to ensure only sensible orders reach persistent
+// storage. Updating orders should be done via one of the update functions such
+// as UpdateOrderStatus.
+func (a *Archiver) StoreOrder(ord order.Order, epochIdx, epochDur int64, status order.OrderStatus) error {
+	return a.storeOrder(ord, epochIdx, epochDur, db.EpochGapNA, marketToDBStatus(status))
+}
+
+func validateOrder(ord order.Order, status orderStatus, mkt *dex.MarketInfo) bool {
+	if status == orderStatusFailed && ord.Type() != order.CancelOrderType {
+		return false
+	}
+	return db.ValidateOrder(ord, dbToMarketStatus(status), mkt)
+}
+
+func (a *Archiver) storeOrder(ord order.Order, epochIdx, epochDur int64, epochGap int32, status orderStatus) error {
+	mkt, err := a.market(ord.Base(), ord.Quote())
+	if err != nil {
+		return err
+	}
+
+	if !validateOrder(ord, status, mkt) {
+		return db.ArchiveError{
+			Code: db.ErrInvalidOrder,
+			Detail: fmt.Sprintf("invalid order %v for status %v and market %v",
+				ord.UID(), status, mkt),
+		}
+	}
+
+	// Check for order commitment duplicates across all markets. This also
+	// covers order ID since commitment is part of order serialization.
+	commit := ord.Commitment()
+	found, prevOid, err := a.OrderWithCommit(a.ctx, commit)
+	if err != nil {
+		return err
+	}
+	if found {
+		return db.ArchiveError{
+			Code: db.ErrReusedCommit,
+			Detail: fmt.Sprintf("order %v reuses commit %v from previous order %v",
+				ord.UID(), commit, prevOid),
+		}
+	}
+
+	return a.insertOrder(&dbOrder{
+		ord:      ord,
+		status:   status,
+		epochIdx: epochIdx,
+		epochDur: epochDur,
+		epochGap: epochGap,
+	})
+}
+
+// insertOrder stores a new order. It is an error if the order already exists.
+func (a *Archiver) insertOrder(o *dbOrder) error {
+	if err := a.orders.Set(orderKey(o.ord.ID()), o); err != nil {
+		a.fatalBackendErr(err)
+		return fmt.Errorf("failed to store order %v: %w", o.ord.UID(), err)
+	}
+	return nil
+}
+
+// updateOrder loads an existing order, applies the update, and stores the
+// modified order in a single transaction. If update returns false, nothing is
+// stored.
+func (a *Archiver) updateOrder(oid order.OrderID, base, quote uint32, update func(o *dbOrder) (bool, error)) error {
+	if _, err := a.market(base, quote); err != nil {
+		return err
+	}
+	err := a.db.Update(func(txn *badger.Txn) error {
+		o, err := a.loadOrder(oid, base, quote, lexi.WithGetTxn(txn))
+		if err != nil {
+			return err
+		}
+		if changed, err := update(o); err != nil || !changed {
+			return err
+		}
+		return a.orders.Set(orderKey(oid), o, lexi.WithReplace(), lexi.WithTxn(txn))
+	})
+	var archiveErr db.ArchiveError
+	if err != nil && !errors.As(err, &archiveErr) {
+		a.fatalBackendErr(err)
+	}
+	return err
+}
+
+func (a *Archiver) updateOrderStatus(ord order.Order, status orderStatus) error {
+	var filled int64
+	if ord.Type() != order.CancelOrderType {
+		filled = int64(ord.Trade().Filled())
+	}
+	return a.updateOrderStatusByID(ord.ID(), ord.Base(), ord.Quote(), status, filled)
+}
+
+// updateOrderStatusByID updates the status and filled amount of the order. A
+// filled amount of -1 leaves the stored amount unchanged. An archived order
+// cannot be returned to an active status.
+func (a *Archiver) updateOrderStatusByID(oid order.OrderID, base, quote uint32, status orderStatus, filled int64) error {
+	return a.updateOrder(oid, base, quote, func(o *dbOrder) (bool, error) {
+		initStatus, initFilled := o.status, o.filled()
+		if initStatus == status && filled == initFilled {
+			log.Tracef("Not updating order with no status or filled amount change: %v.", oid)
+			return false, nil
+		}
+		if !initStatus.active() {
+			if status.active() {
+				return false, fmt.Errorf("Moving an order from an archived to active status: "+
+					"Order %s (%s -> %s)", oid, initStatus, status)
+			}
+			log.Infof("Archived order is changing status: Order %s (%s -> %s)",
+				oid, initStatus, status)
+		}
+		o.status = status
+		if t := o.ord.Trade(); t != nil && filled != -1 {
+			t.SetFill(uint64(filled))
+		}
+		return true, nil
+	})
+}
+
+// BookOrder updates the given LimitOrder with booked status.
+func (a *Archiver) BookOrder(lo *order.LimitOrder) error {
+	return a.updateOrderStatus(lo, orderStatusBooked)
+}
+
+// ExecuteOrder updates the given Order with executed status.
+func (a *Archiver) ExecuteOrder(ord order.Order) error {
+	return a.updateOrderStatus(ord, orderStatusExecuted)
+}
+
+// CancelOrder updates a LimitOrder with canceled status. If the order does not
+// exist in the Archiver, CancelOrder returns ErrUnknownOrder.
+func (a *Archiver) CancelOrder(lo *order.LimitOrder) error {
+	return a.updateOrderStatus(lo, orderStatusCanceled)
+}
+
+// RevokeOrder updates an Order with revoked status, which is used for
+// DEX-revoked orders rather than orders matched with a user's CancelOrder. If
+// the order does not exist in the Archiver, RevokeOrder returns
+// ErrUnknownOrder.
+func (a *Archiver) RevokeOrder(ord order.Order) (cancelID order.OrderID, timeStamp time.Time, err error) {
+	return a.revokeOrder(ord, false)
+}
+
+// RevokeOrderUncounted is like RevokeOrder except that the generated cancel
+// order will not be counted against the user. i.e. ExecutedCancelsForUser
+// should not return the cancel orders created this way.
+func (a *Archiver) RevokeOrderUncounted(ord order.Order) (cancelID order.OrderID, timeStamp time.Time, err error) {
+	return a.revokeOrder(ord, true)
+}
+
+func (a *Archiver) revokeOrder(ord order.Order, exempt bool) (cancelID order.OrderID, timeStamp time.Time, err error) {
+	// Revoke the targeted order.
+	err = a.updateOrderStatus(ord, orderStatusRevoked)
+	if err != nil {
+		return
+	}
+
+	// Store the pseudo-cancel order with status orderStatusRevoked as an
+	// indicator that this is a revocation.
+	timeStamp = time.Now().Truncate(time.Millisecond).UTC()
+	co := makePseudoCancel(ord.ID(), ord.User(), ord.Base(), ord.Quote(), timeStamp)
+	cancelID = co.ID()
+	epochIdx := countedEpochIdx
+	if exempt {
+		epochIdx = exemptEpochIdx
+	}
+	err = a.storeOrder(co, epochIdx, dummyEpochDur, db.EpochGapNA, orderStatusRevoked)
+	return
+}
+
+func makePseudoCancel(target order.OrderID, user account.AccountID, base, quote uint32, timeStamp time.Time) *order.CancelOrder {
+	// Create a server-generated cancel order to record the server's revoke
+	// order action. The zero-value Commitment is not indexed.
+	return &order.CancelOrder{
+		P: order.Prefix{
+			AccountID:  user,
+			BaseAsset:  base,
+			QuoteAsset: quote,
+			OrderType:  order.CancelOrderType,
+			ClientTime: timeStamp,
+			ServerTime: timeStamp,
+		},
+		TargetOrderID: target,
+	}
+}
+
+// FailCancelOrder updates or inserts the given CancelOrder with failed status.
+// To update a CancelOrder with executed status, use ExecuteOrder.
+func (a *Archiver) FailCancelOrder(co *order.CancelOrder) error {
+	return a.updateOrderStatus(co, orderStatusFailed)
+}
+
+// UpdateOrderStatus updates the status and filled amount of the given order.
+// Both the market and new filled amount are determined from the Order.
+func (a *Archiver) UpdateOrderStatus(ord order.Order, status order.OrderStatus) error {
+	return a.updateOrderStatus(ord, marketToDBStatus(status))
+}
+
+// UpdateOrderFilled updates the filled amount of the given order. Both the
+// market and new filled amount are determined from the Order. This function
+// applies only to market and limit orders, not cancel orders.
+func (a *Archiver) UpdateOrderFilled(ord *order.LimitOrder) error {
+	switch orderType := ord.Type(); orderType {
+	case order.MarketOrderType, order.LimitOrderType:
+	default:
+		return fmt.Errorf("cannot set filled amount for order type %v", orderType)
+	}
+	filled := ord.Trade().Filled()
+	return a.updateOrder(ord.ID(), ord.Base(), ord.Quote(), func(o *dbOrder) (bool, error) {
+		t := o.ord.Trade()
+		if t == nil {
+			return false, fmt.Errorf("cannot set filled amount for order type %v", o.ord.Type())
+		}
+		if t.Filled() == filled {
+			return false, nil // nothing to do
+		}
+		t.SetFill(filled)
+		return true, nil
+	})
+}
+
+// StorePreimage stores the preimage associated with an existing order.
+func (a *Archiver) StorePreimage(ord order.Order, pi order.Preimage) error {
+	return a.updateOrder(ord.ID(), ord.Base(), ord.Quote(), func(o *dbOrder) (bool, error) {
+		// Preimages are stored during epoch processing, specifically after
+		// users have responded with their preimages but before swap
+		// negotiation begins. Thus, this order should be active.
+		if !o.status.active() {
+			log.Warnf("Attempting to set preimage for archived order %v", ord.UID())
+		}
+		o.preimage = pi[:]
+		return true, nil
+	})
+}
+
+// OrderPreimage retrieves the preimage of an order, or the zero value if it has
+// not been revealed.
+func (a *Archiver) OrderPreimage(ord order.Order) (pi order.Preimage, err error) {
+	o, err := a.loadOrder(ord.ID(), ord.Base(), ord.Quote())
+	if err != nil {
+		return pi, err
+	}
+	copy(pi[:], o.preimage)
+	return pi, nil
+}
+
+// SetOrderCompleteTime sets the successful swap completion time for an existing
+// order. It is an error if the order is not in executed status.
+func (a *Archiver) SetOrderCompleteTime(ord order.Order, compTimeMs int64) error {
+	return a.updateOrder(ord.ID(), ord.Base(), ord.Quote(), func(o *dbOrder) (bool, error) {
+		if o.status != orderStatusExecuted { // complete_time is only set for executed orders, not canceled or revoked
+			log.Warnf("Attempting to set swap completion time for order %v in status %v, not executed",
+				ord.UID(), o.status)
+			return false, db.ArchiveError{
+				Code: db.ErrOrderNotExecuted,
+				Detail: fmt.Sprintf("unable to set completed time for order %v in status %v, not executed",
+					ord.UID(), o.status),
+			}
+		}
+		o.completeTime = compTimeMs
+		return true, nil
+	})
+}
+
+// OrderStatus gets the status, type, and filled amount of the given order. For
+// cancel orders the filled amount is -1.
+func (a *Archiver) OrderStatus(ord order.Order) (order.OrderStatus, order.OrderType, int64, error) {
+	return a.OrderStatusByID(ord.ID(), ord.Base(), ord.Quote())
+}
+
+// OrderStatusByID gets the status, type, and filled amount of the order with
+// the given OrderID in the market specified by a base and quote asset.
+func (a *Archiver) OrderStatusByID(oid order.OrderID, base, quote uint32) (order.OrderStatus, order.OrderType, int64, error) {
+	if _, err := a.market(base, quote); err != nil {
+		return order.OrderStatusUnknown, order.UnknownOrderType, -1, err
+	}
+	o, err := a.loadOrder(oid, base, quote)
+	if err != nil {
+		return order.OrderStatusUnknown, order.UnknownOrderType, -1, err
+	}
+	return dbToMarketStatus(o.status), o.ord.Type(), o.filled(), nil
+}
+
+// FlushBook revokes all booked orders for a market.
+func (a *Archiver) FlushBook(base, quote uint32) (sellsRemoved, buysRemoved []order.OrderID, err error) {
+	if _, err = a.market(base, quote); err != nil {
+		return
+	}
+
+	var booked []*order.LimitOrder
+	err = iterateOrders(a.orderStatusIdx, orderStatusIndexEntry(base, quote, orderStatusBooked), func(o *dbOrder) error {
+		if lo, ok := o.ord.(*order.LimitOrder); ok {
+			booked = append(booked, lo)
+		}
+		return nil
+	})
+	if err != nil {
+		a.fatalBackendErr(err)
+		return nil, nil, err
+	}
+
+	timeStamp := time.Now().Truncate(time.Millisecond).UTC()
+	err = a.db.Update(func(txn *badger.Txn) error {
+		sellsRemoved, buysRemoved = nil, nil
+		for _, lo := range booked {
+			oid := lo.ID()
+			o, err := a.loadOrder(oid, base, quote, lexi.WithGetTxn(txn))
+			if err != nil {
+				return err
+			}
+			o.status = orderStatusRevoked
+			if err = a.orders.Set(orderKey(oid), o, lexi.WithReplace(), lexi.WithTxn(txn)); err != nil {
+				return err
+			}
+			// Exempt pseudo-cancel, consistent with revokeOrder(..., true).
+			co := makePseudoCancel(oid, lo.User(), base, quote, timeStamp)
+			err = a.orders.Set(orderKey(co.ID()), &dbOrder{
+				ord:      co,
+				status:   orderStatusRevoked,
+				epochIdx: exemptEpochIdx,
+				epochDur: dummyEpochDur,
+				epochGap: db.EpochGapNA,
+			}, lexi.WithTxn(txn))
+			if err != nil {
+				return fmt.Errorf("failed to store pseudo-cancel order: %w", err)
+			}
+			if lo.Sell {
+				sellsRemoved = append(sellsRemoved, oid)
+			} else {
+				buysRemoved = append(buysRemoved, oid)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		a.fatalBackendErr(err)
+		return nil, nil, err
+	}
+	return
+}
+
+// BookOrders retrieves all booked orders (with order status booked) for the
+// specified market. This will be used to repopulate a market's book on
+// construction of the market.
+func (a *Archiver) BookOrders(base, quote uint32) ([]*order.LimitOrder, error) {
+	if _, err := a.market(base, quote); err != nil {
+		return nil, err
+	}
+	var los []*order.LimitOrder
+	return los, iterateOrders(a.orderStatusIdx, orderStatusIndexEntry(base, quote, orderStatusBooked), func(o *dbOrder) error {
+		lo, ok := o.ord.(*order.LimitOrder)
+		if !ok {
+			return fmt.Errorf("booked order %v is not a limit order", o.ord.ID())
+		}
+		los = append(los, lo)
+		return nil
+	})
+}
+
+// EpochOrders retrieves all epoch orders for the specified market: limit
+// orders, then market orders, then cancel orders.
+func (a *Archiver) EpochOrders(base, quote uint32) ([]order.Order, error) {
+	if _, err := a.market(base, quote); err != nil {
+		return nil, err
+	}
+	var los, mos, cos []order.Order
+	err := iterateOrders(a.orderStatusIdx, orderStatusIndexEntry(base, quote, orderStatusEpoch), func(o *dbOrder) error {
+		switch o.ord.Type() {
+		case order.LimitOrderType:
+			los = append(los, o.ord)
+		case order.MarketOrderType:
+			mos = append(mos, o.ord)
+		case order.CancelOrderType:
+			cos = append(cos, o.ord)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return append(append(los, mos...), cos...), nil
+}
+
+// ActiveOrderCoins retrieves a CoinID slice for each active order. Sell orders
+// lock base asset coins, while buy orders lock quote asset coins.
+func (a *Archiver) ActiveOrderCoins(base, quote uint32) (baseCoins, quoteCoins map[order.OrderID][]order.CoinID, err error) {
+	if _, err = a.market(base, quote); err != nil {
+		return
+	}
+	baseCoins = make(map[order.OrderID][]order.CoinID)
+	quoteCoins = make(map[order.OrderID][]order.CoinID)
+	for _, status := range []orderStatus{orderStatusEpoch, orderStatusBooked} {
+		err = iterateOrders(a.orderStatusIdx, orderStatusIndexEntry(base, quote, status), func(o *dbOrder) error {
+			t := o.ord.Trade()
+			if t == nil {
+				return nil // cancel order
+			}
+			if t.Sell {
+				baseCoins[o.ord.ID()] = t.Coins
+			} else {
+				quoteCoins[o.ord.ID()] = t.Coins
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return
+}
+
+// UserOrders retrieves all orders for the given account in the market specified
+// by a base and quote asset. Active orders are listed first.
+func (a *Archiver) UserOrders(ctx context.Context, aid account.AccountID, base, quote uint32) ([]order.Order, []order.OrderStatus, error) {
+	if _, err := a.market(base, quote); err != nil {
+		return nil, nil, err
+	}
+	var active, archived []*dbOrder
+	err := iterateOrders(a.orderAcctIdx, append(aid[:], marketBytes(base, quote)...), func(o *dbOrder) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		switch {
+		case o.ord.Type() == order.CancelOrderType:
+		case o.status.active():
+			active = append(active, o)
+		default:
+			archived = append(archived, o)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	all := append(active, archived...)
+	ords := make([]order.Order, 0, len(all))
+	statuses := make([]order.OrderStatus, 0, len(all))
+	for _, o := range all {
+		ords = append(ords, o.ord)
+		statuses = append(statuses, dbToMarketStatus(o.status))
+	}
+	return ords, statuses, nil
+}
+
+// UserOrderStatuses retrieves the statuses and filled amounts of the orders
+// with the provided order IDs for the given account in the market specified by
+// a base and quote asset. If oids is empty, the statuses of all of the user's
+// trade orders in the market are returned. Unknown order IDs are skipped.
+func (a *Archiver) UserOrderStatuses(aid account.AccountID, base, quote uint32, oids []order.OrderID) ([]*db.OrderStatus, error) {
+	if _, err := a.market(base, quote); err != nil {
+		return nil, err
+	}
+	var want map[order.OrderID]bool
+	if len(oids) > 0 {
+		want = make(map[order.OrderID]bool, len(oids))
+		for _, oid := range oids {
+			want[oid] = true
+		}
+	}
+	var statuses []*db.OrderStatus
+	return statuses, iterateOrders(a.orderAcctIdx, append(aid[:], marketBytes(base, quote)...), func(o *dbOrder) error {
+		if o.ord.Type() == order.CancelOrderType {
+			return nil
+		}
+		oid := o.ord.ID()
+		if want != nil && !want[oid] {
+			return nil
+		}
+		statuses = append(statuses, &db.OrderStatus{
+			ID:     oid,
+			Status: dbToMarketStatus(o.status),
+		})
+		return nil
+	})
+}
+
+// ActiveUserOrderStatuses retrieves the statuses of all active orders for a
+// user across all markets.
+func (a *Archiver) ActiveUserOrderStatuses(aid account.AccountID) ([]*db.OrderStatus, error) {
+	var statuses []*db.OrderStatus
+	return statuses, iterateOrders(a.orderAcctIdx, aid[:], func(o *dbOrder) error {
+		if o.ord.Type() == order.CancelOrderType || !o.status.active() {
+			return nil
+		}
+		if _, err := a.market(o.ord.Base(), o.ord.Quote()); err != nil {
+			return nil // not a configured market
+		}
+		statuses = append(statuses, &db.OrderStatus{
+			ID:     o.ord.ID(),
+			Status: dbToMarketStatus(o.status),
+		})
+		return nil
+	})
+}
+
+// CompletedUserOrders retrieves the N most recently completed orders for a user
+// across all markets.
+func (a *Archiver) CompletedUserOrders(aid account.AccountID, N int) (oids []order.OrderID, compTimes []int64, err error) {
+	type orderCompStamped struct {
+		oid order.OrderID
+		t   int64
+	}
+	var ords []orderCompStamped
+	err = iterateOrders(a.orderAcctIdx, aid[:], func(o *dbOrder) error {
+		if o.ord.Type() == order.CancelOrderType || o.status.active() || o.completeTime == 0 {
+			return nil
+		}
+		ords = append(ords, orderCompStamped{o.ord.ID(), o.completeTime})
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sort.Slice(ords, func(i, j int) bool {
+		return ords[i].t > ords[j].t // descending, latest completed order first
+	})
+
+	if N > len(ords) {
+		N = len(ords)
+	}
+
+	for i := range ords[:N] {
+		oids = append(oids, ords[i].oid)
+		compTimes = append(compTimes, ords[i].t)
+	}
+
+	return
+}
+
+// PreimageStats retrieves the last N preimage outcomes for a user's archived
+// trade orders and client-submitted cancel orders across all markets, sorted
+// by epoch close time. Forgiven misses are excluded.
+func (a *Archiver) PreimageStats(user account.AccountID, lastN int) ([]*db.PreimageResult, error) {
+	var outcomes []*db.PreimageResult
+	err := iterateOrders(a.orderAcctIdx, user[:], func(o *dbOrder) error {
+		if o.status.active() || o.status < 0 { // exclude forgiven
+			return nil
+		}
+		if commit := o.ord.Commitment(); o.ord.Type() == order.CancelOrderType && commit.IsZero() {
+			return nil // server-generated cancel
+		}
+		outcomes = append(outcomes, &db.PreimageResult{
+			Miss: o.preimage == nil && o.status == orderStatusRevoked,
+			Time: (o.epochIdx + 1) * o.epochDur,
+			ID:   o.ord.ID(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(outcomes, func(i, j int) bool {
+		return outcomes[i].Time < outcomes[j].Time // ascending
+	})
+	if len(outcomes) > lastN {
+		outcomes = outcomes[len(outcomes)-lastN:]
+	}
+	return outcomes, nil
+}
+
+// ExecutedCancelsForUser retrieves up to N executed cancel orders for a given
+// user. These may be user-initiated cancels, or cancels created by the server
+// (revokes). Executed cancel orders from all markets are returned.
+func (a *Archiver) ExecutedCancelsForUser(aid account.AccountID, N int) ([]*db.CancelRecord, error) {
+	var ords []*db.CancelRecord
+	err := iterateOrders(a.orderAcctIdx, aid[:], func(o *dbOrder) error {
+		co, ok := o.ord.(*order.CancelOrder)
+		if !ok {
+			return nil
+		}
+		switch o.status {
+		case orderStatusExecuted: // excludes orderStatusFailed
+			matchTime, found, err := a.epochMatchTime(co.Base(), co.Quote(), o.epochIdx, o.epochDur)
+			if err != nil {
+				return err
+			}
+			if !found {
+				return nil
+			}
+			ords = append(ords, &db.CancelRecord{
+				ID:        co.ID(),
+				TargetID:  co.TargetOrderID,
+				MatchTime: matchTime,
+				EpochGap:  o.epochGap,
+			})
+		case orderStatusRevoked:
+			// only include non-exempt/counted cancels
+			if o.epochIdx == exemptEpochIdx {
+				return nil
+			}
+			ords = append(ords, &db.CancelRecord{
+				ID:        co.ID(),
+				TargetID:  co.TargetOrderID,
+				MatchTime: co.ServerTime.UnixMilli(),
+				EpochGap:  db.EpochGapNA,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(ords, func(i, j int) bool {
+		return ords[i].MatchTime > ords[j].MatchTime // descending, latest completed order first
+	})
+	if len(ords) > N {
+		ords = ords[:N]
+	}
+	return ords, nil
+}
+
+// OrderWithCommit searches all markets' trade and cancel orders, both active
+// and archived, for an order with the given commitment.
+func (a *Archiver) OrderWithCommit(ctx context.Context, commit order.Commitment) (found bool, oid order.OrderID, err error) {
+	if commit.IsZero() {
+		return // server-generated cancels are not indexed
+	}
+	err = a.orderCommitIdx.Iterate(commit[:], func(it *lexi.Iter) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return it.V(func(vB []byte) error {
+			o := new(dbOrder)
+			if err := o.UnmarshalBinary(vB); err != nil {
+				return err
+			}
+			if c := o.ord.Commitment(); !bytes.Equal(c[:], commit[:]) {
+				return nil
+			}
+			found, oid = true, o.ord.ID()
+			return lexi.ErrEndIteration
+		})
+	})
+	return
+}
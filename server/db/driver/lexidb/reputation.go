@@ -0,0 +1,329 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package lexidb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"decred.org/dcrdex/dex/encode"
+	"decred.org/dcrdex/dex/lexi"
+	"decred.org/dcrdex/dex/order"
+	"decred.org/dcrdex/server/account"
+	"decred.org/dcrdex/server/db"
+	"github.com/dgraph-io/badger"
+)
+
+const newReputationVersion int16 = 1
+
+var _ db.ReputationArchiver = (*Archiver)(nil)
+
+// dbPoint is a reputation outcome for a user.
+type dbPoint struct {
+	id      int64
+	acct    account.AccountID
+	link    [32]byte
+	class   db.OutcomeClass
+	outcome db.Outcome
+}
+
+// MarshalBinary encodes the dbPoint as a versioned blob.
+func (p *dbPoint) MarshalBinary() ([]byte, error) {
+	const pointVer = 0
+	return encode.BuildyBytes{pointVer}.
+		AddData(encode.Uint64Bytes(uint64(p.id))).
+		AddData(p.acct[:]).
+		AddData(p.link[:]).
+		AddData(encode.Uint16Bytes(uint16(p.class))).
+		AddData(encode.Uint16Bytes(uint16(p.outcome))), nil
+}
+
+// UnmarshalBinary decodes the versioned blob into the dbPoint.
+func (p *dbPoint) UnmarshalBinary(b []byte) error {
+	const pointVer = 0
+	ver, pushes, err := encode.DecodeBlob(b, 5)
+	if err != nil {
+		return fmt.Errorf("error decoding point blob: %w", err)
+	}
+	if ver != pointVer {
+		return fmt.Errorf("unknown point version %d", ver)
+	}
+	if len(pushes) != 5 || len(pushes[0]) != 8 || len(pushes[1]) != account.HashSize ||
+		len(pushes[2]) != 32 || len(pushes[3]) != 2 || len(pushes[4]) != 2 {
+		return errors.New("invalid point blob")
+	}
+	p.id = int64(encode.BytesToUint64(pushes[0]))
+	copy(p.acct[:], pushes[1])
+	copy(p.link[:], pushes[2])
+	p.class = db.OutcomeClass(encode.IntCoder.Uint16(pushes[3]))
+	p.outcome = db.Outcome(encode.IntCoder.Uint16(pushes[4]))
+	return nil
+}
+
+func pointKey(id int64) []byte {
+	return prefixedKey(pointsKeyPrefix, encode.Uint64Bytes(uint64(id)))
+}
+
+func (a *Archiver) prepareReputationTables() (err error) {
+	if a.points, err = a.db.Table("points"); err != nil {
+		return fmt.Errorf("error constructing points table: %w", err)
+	}
+	a.pointsAcctIdx, err = a.points.AddIndex("points-account", func(_, v lexi.KV) ([]byte, error) {
+		p, is := v.(*dbPoint)
+		if !is {
+			return nil, fmt.Errorf("wrong type %T", v)
+		}
+		return append(p.acct[:], encode.Uint64Bytes(uint64(p.id))...), nil
+	})
+	if err != nil {
+		return fmt.Errorf("error constructing points account index: %w", err)
+	}
+	// Points IDs are assigned in insertion order, so the last entry in the
+	// table has the highest ID.
+	return a.points.Iterate(nil, func(it *lexi.Iter) error {
+		return it.V(func(vB []byte) error {
+			var p dbPoint
+			if err := p.UnmarshalBinary(vB); err != nil {
+				return err
+			}
+			a.lastPointID = p.id
+			return lexi.ErrEndIteration
+		})
+	}, lexi.WithReverse())
+}
+
+// iteratePoints decodes the user's points in ID order.
+func (a *Archiver) iteratePoints(user account.AccountID, f func(it *lexi.Iter, p *dbPoint) error, opts ...lexi.IterationOption) error {
+	return a.pointsAcctIdx.Iterate(user[:], func(it *lexi.Iter) error {
+		var p dbPoint
+		err := it.V(func(vB []byte) error {
+			return p.UnmarshalBinary(vB)
+		})
+		if err != nil {
+			return err
+		}
+		return f(it, &p)
+	}, opts...)
+}
+
+// GetUserReputationData retrieves the most recent reputation outcomes for the
+// user, up to the specified number in each outcome class.
+func (a *Archiver) GetUserReputationData(
+	ctx context.Context,
+	user account.AccountID,
+	pimgSz, matchSz, orderSz int, /* pre-allocation sizes */
+) ([]*db.PreimageOutcome, []*db.MatchResult, []*db.OrderOutcome, error) {
+	pimgs := make([]*db.PreimageOutcome, 0, pimgSz)
+	matches := make([]*db.MatchResult, 0, matchSz)
+	orders := make([]*db.OrderOutcome, 0, orderSz)
+
+	err := a.iteratePoints(user, func(_ *lexi.Iter, p *dbPoint) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		switch p.class {
+		case db.OutcomeClassPreimage:
+			pimgs = append(pimgs, &db.PreimageOutcome{
+				DBID:    p.id,
+				OrderID: p.link,
+				Miss:    p.outcome == db.OutcomePreimageMiss,
+			})
+		case db.OutcomeClassMatch:
+			matches = append(matches, &db.MatchResult{
+				DBID:         p.id,
+				MatchID:      p.link,
+				MatchOutcome: p.outcome,
+			})
+		case db.OutcomeClassOrder:
+			orders = append(orders, &db.OrderOutcome{
+				DBID:     p.id,
+				OrderID:  p.link,
+				Canceled: p.outcome == db.OutcomeOrderCanceled,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("error iterating reputation points: %w", err)
+	}
+
+	if len(pimgs) > pimgSz {
+		pimgs = pimgs[len(pimgs)-pimgSz:]
+	}
+	if len(matches) > matchSz {
+		matches = matches[len(matches)-matchSz:]
+	}
+	if len(orders) > orderSz {
+		orders = orders[len(orders)-orderSz:]
+	}
+
+	return pimgs, matches, orders, nil
+}
+
+// insertPoints stores the points in a single transaction, assigning their
+// IDs.
+func (a *Archiver) insertPoints(ctx context.Context, ps ...*dbPoint) error {
+	a.pointsMtx.Lock()
+	defer a.pointsMtx.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	var lastID int64
+	err := a.db.Update(func(txn *badger.Txn) error {
+		lastID = a.lastPointID
+		for _, p := range ps {
+			lastID++
+			p.id = lastID
+			if err := a.points.Set(pointKey(p.id), p, lexi.WithTxn(txn)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		a.fatalBackendErr(err)
+		return err
+	}
+	a.lastPointID = lastID
+	return nil
+}
+
+// AddPreimageOutcome records a preimage outcome for the user.
+func (a *Archiver) AddPreimageOutcome(ctx context.Context, user account.AccountID, oid order.OrderID, miss bool) (*db.PreimageOutcome, error) {
+	outcome := db.OutcomePreimageSuccess
+	if miss {
+		outcome = db.OutcomePreimageMiss
+	}
+	p := &dbPoint{acct: user, link: oid, class: db.OutcomeClassPreimage, outcome: outcome}
+	if err := a.insertPoints(ctx, p); err != nil {
+		return nil, err
+	}
+	return &db.PreimageOutcome{
+		DBID:    p.id,
+		OrderID: oid,
+		Miss:    miss,
+	}, nil
+}
+
+// AddMatchOutcome records a match outcome for the user.
+func (a *Archiver) AddMatchOutcome(ctx context.Context, user account.AccountID, mid order.MatchID, outcome db.Outcome) (*db.MatchResult, error) {
+	if outcome < db.OutcomeSwapSuccess || outcome > db.OutcomeNoRedeemAsTaker {
+		return nil, fmt.Errorf("invalid outcome for a match: %d", outcome)
+	}
+	p := &dbPoint{acct: user, link: mid, class: db.OutcomeClassMatch, outcome: outcome}
+	if err := a.insertPoints(ctx, p); err != nil {
+		return nil, err
+	}
+	return &db.MatchResult{
+		DBID:         p.id,
+		MatchID:      mid,
+		MatchOutcome: outcome,
+	}, nil
+}
+
+// AddOrderOutcome records an order outcome for the user.
+func (a *Archiver) AddOrderOutcome(ctx context.Context, user account.AccountID, oid order.OrderID, canceled bool) (*db.OrderOutcome, error) {
+	outcome := db.OutcomeOrderComplete
+	if canceled {
+		outcome = db.OutcomeOrderCanceled
+	}
+	p := &dbPoint{acct: user, link: oid, class: db.OutcomeClassOrder, outcome: outcome}
+	if err := a.insertPoints(ctx, p); err != nil {
+		return nil, err
+	}
+	return &db.OrderOutcome{
+		DBID:     p.id,
+		OrderID:  oid,
+		Canceled: canceled,
+	}, nil
+}
+
+// PruneOutcomes deletes the user's outcomes of the class with IDs up to and
+// including fromDBID.
+func (a *Archiver) PruneOutcomes(ctx context.Context, user account.AccountID, outcomeClass db.OutcomeClass, fromDBID int64) error {
+	return a.iteratePoints(user, func(it *lexi.Iter, p *dbPoint) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if p.id > fromDBID {
+			return lexi.ErrEndIteration
+		}
+		if p.class != outcomeClass {
+			return nil
+		}
+		return it.Delete()
+	}, lexi.WithUpdate())
+}
+
+// GetUserReputationVersion gets the reputation version of the user. Unknown
+// users are new, and have the current version.
+func (a *Archiver) GetUserReputationVersion(ctx context.Context, user account.AccountID) (int16, error) {
+	acct, err := a.loadAccount(user)
+	if err != nil {
+		if errors.Is(err, lexi.ErrKeyNotFound) {
+			// New user.
+			return newReputationVersion, nil
+		}
+		return 0, err
+	}
+	return acct.repVer, nil
+}
+
+// UpgradeUserReputationV1 stores the user's outcomes, setting their DB IDs,
+// and updates the user's reputation version.
+func (a *Archiver) UpgradeUserReputationV1(
+	ctx context.Context, user account.AccountID, pimgs []*db.PreimageOutcome, matches []*db.MatchResult, orders []*db.OrderOutcome, /* Without DB IDs */
+) ([]*db.PreimageOutcome, []*db.MatchResult, []*db.OrderOutcome, error) /* With DB IDs */ {
+	ps := make([]*dbPoint, 0, len(pimgs)+len(matches)+len(orders))
+	for _, o := range pimgs {
+		ps = append(ps, &dbPoint{acct: user, link: o.OrderID, class: db.OutcomeClassPreimage, outcome: o.Outcome()})
+	}
+	for _, o := range matches {
+		ps = append(ps, &dbPoint{acct: user, link: o.MatchID, class: db.OutcomeClassMatch, outcome: o.Outcome()})
+	}
+	for _, o := range orders {
+		ps = append(ps, &dbPoint{acct: user, link: o.OrderID, class: db.OutcomeClassOrder, outcome: o.Outcome()})
+	}
+	if err := a.insertPoints(ctx, ps...); err != nil {
+		return nil, nil, nil, fmt.Errorf("error inserting points during reputation upgrade: %w", err)
+	}
+	for i, o := range pimgs {
+		o.DBID = ps[i].id
+	}
+	for i, o := range matches {
+		o.DBID = ps[len(pimgs)+i].id
+	}
+	for i, o := range orders {
+		o.DBID = ps[len(pimgs)+len(matches)+i].id
+	}
+
+	err := a.db.Update(func(txn *badger.Txn) error {
+		acct, err := a.loadAccount(user, lexi.WithGetTxn(txn))
+		if err != nil {
+			return err
+		}
+		acct.repVer = newReputationVersion
+		return a.accounts.Set(accountKey(user), acct, lexi.WithReplace(), lexi.WithTxn(txn))
+	})
+	if err != nil && !errors.Is(err, lexi.ErrKeyNotFound) {
+		return nil, nil, nil, fmt.Errorf("error updating reputation version: %w", err)
+	}
+	return pimgs, matches, orders, nil
+}
+
+// ForgiveUser deletes all of the user's negative outcomes.
+func (a *Archiver) ForgiveUser(ctx context.Context, user account.AccountID) error {
+	err := a.iteratePoints(user, func(it *lexi.Iter, p *dbPoint) error {
+		switch p.outcome {
+		case db.OutcomeSwapSuccess, db.OutcomePreimageSuccess, db.OutcomeOrderComplete:
+			return nil
+		}
+		return it.Delete()
+	}, lexi.WithUpdate())
+	if err != nil {
+		return fmt.Errorf("error forgiving user: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+package lexidb
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+
+	"decred.org/dcrdex/dex"
+	"decred.org/dcrdex/dex/lexi"
+	"decred.org/dcrdex/dex/order"
+)
+
+var (
+	archie            *Archiver
+	archieDir         string
+	mktInfo, mktInfo2 *dex.MarketInfo
+	numMarkets        int
+)
+
+func TestMain(m *testing.M) {
+	startLogger()
+
+	// Wrap openDB so that the cleanUp function may be deferred.
+	doIt := func() int {
+		var err error
+		archieDir, err = os.MkdirTemp("", "lexidb")
+		if err != nil {
+			panic(fmt.Sprintln("no temp dir for testing:", err))
+		}
+		defer os.RemoveAll(archieDir)
+
+		// Not counted as coverage, must test Archiver constructor explicitly.
+		cleanUp, err := openDB()
+		defer cleanUp()
+		if err != nil {
+			panic(fmt.Sprintln("no db for testing:", err))
+		}
+
+		return m.Run()
+	}
+
+	os.Exit(doIt())
+}
+
+func testConfig() *Config {
+	return &Config{
+		Path:      archieDir,
+		MarketCfg: []*dex.MarketInfo{mktInfo, mktInfo2},
+	}
+}
+
+func openDB() (func() error, error) {
+	var err error
+	mktInfo, err = dex.NewMarketInfoFromSymbols("dcr", "btc", LotSize, RateStep, EpochDuration, 0, MarketBuyBuffer)
+	if err != nil {
+		return func() error { return nil }, fmt.Errorf("invalid market: %v", err)
+	}
+
+	mktInfo2, err = dex.NewMarketInfoFromSymbols("btc", "ltc", LotSize, RateStep, EpochDuration, 0, MarketBuyBuffer)
+	if err != nil {
+		return func() error { return nil }, fmt.Errorf("invalid market: %v", err)
+	}
+
+	AssetDCR = mktInfo.Base
+	AssetBTC = mktInfo.Quote
+	AssetLTC = mktInfo2.Quote
+
+	dbi := testConfig()
+	numMarkets = len(dbi.MarketCfg)
+
+	closeFn := func() error { return nil }
+
+	archie, err = NewArchiver(context.Background(), dbi)
+	if archie == nil {
+		return closeFn, err
+	}
+
+	closeFn = func() error {
+		return archie.Close()
+	}
+
+	return closeFn, err
+}
+
+// cleanTables replaces archie with an Archiver on an empty database.
+func cleanTables(*lexi.DB) error {
+	if err := archie.Close(); err != nil {
+		return err
+	}
+	if err := os.RemoveAll(archieDir); err != nil {
+		return err
+	}
+	var err error
+	archie, err = NewArchiver(context.Background(), testConfig())
+	return err
+}
+
+// loadCancelOrder loads a cancel order and its archived status.
+func loadCancelOrder(oid order.OrderID) (*order.CancelOrder, orderStatus, error) {
+	o, err := archie.loadOrder(oid, mktInfo.Base, mktInfo.Quote)
+	if err != nil {
+		return nil, orderStatusUnknown, err
+	}
+	co, ok := o.ord.(*order.CancelOrder)
+	if !ok {
+		return nil, orderStatusUnknown, fmt.Errorf("order %v is not a cancel order", oid)
+	}
+	return co, o.status, nil
+}
+
+func TestUpdateLotSize(t *testing.T) {
+	if err := cleanTables(archie.db); err != nil {
+		t.Fatalf("cleanTables: %v", err)
+	}
+
+	lo := newLimitOrder(false, 4900000, 1, order.StandingTiF, 0)
+	if err := archie.StoreOrder(lo, 1, 10, order.OrderStatusBooked); err != nil {
+		t.Fatalf("StoreOrder failed: %v", err)
+	}
+
+	// Reopen with the same lot size. The book is not flushed.
+	if err := archie.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	var err error
+	archie, err = NewArchiver(context.Background(), testConfig())
+	if err != nil {
+		t.Fatalf("NewArchiver failed: %v", err)
+	}
+	if _, status, err := archie.Order(lo.ID(), lo.Base(), lo.Quote()); err != nil {
+		t.Fatalf("Order failed: %v", err)
+	} else if status != order.OrderStatusBooked {
+		t.Fatalf("wrong status %v after reopening with the same lot size", status)
+	}
+
+	// Reopen with a changed lot size. The book is flushed.
+	if err = archie.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	mkt := *mktInfo
+	mkt.LotSize *= 10
+	archie, err = NewArchiver(context.Background(), &Config{
+		Path:      archieDir,
+		MarketCfg: []*dex.MarketInfo{&mkt, mktInfo2},
+	})
+	if err != nil {
+		t.Fatalf("NewArchiver failed: %v", err)
+	}
+	if _, status, err := archie.Order(lo.ID(), lo.Base(), lo.Quote()); err != nil {
+		t.Fatalf("Order failed: %v", err)
+	} else if status != order.OrderStatusRevoked {
+		t.Fatalf("wrong status %v after reopening with a new lot size", status)
+	}
+
+	// Restore the original market config.
+	if err = cleanTables(archie.db); err != nil {
+		t.Fatalf("cleanTables: %v", err)
+	}
+}
+
+func TestAddMarket(t *testing.T) {
+	if err := cleanTables(archie.db); err != nil {
+		t.Fatalf("cleanTables: %v", err)
+	}
+
+	mkt, err := dex.NewMarketInfoFromSymbols("dcr", "ltc", LotSize, RateStep, EpochDuration, 0, MarketBuyBuffer)
+	if err != nil {
+		t.Fatalf("invalid market: %v", err)
+	}
+	lo := newLimitOrderWithAssets(false, 4900000, 1, order.StandingTiF, 0, mkt.Base, mkt.Quote)
+	if err = archie.StoreOrder(lo, 1, 10, order.OrderStatusBooked); err == nil {
+		t.Fatalf("no error storing an order for an unknown market")
+	}
+
+	if err = archie.AddMarket(mkt); err != nil {
+		t.Fatalf("AddMarket failed: %v", err)
+	}
+	// Adding the market again with the same lot size is not an error.
+	if err = archie.AddMarket(mkt); err != nil {
+		t.Fatalf("AddMarket failed: %v", err)
+	}
+	if err = archie.StoreOrder(lo, 1, 10, order.OrderStatusBooked); err != nil {
+		t.Fatalf("StoreOrder failed: %v", err)
+	}
+
+	// Adding the market with a changed lot size flushes the book.
+	mkt2 := *mkt
+	mkt2.LotSize *= 10
+	if err = archie.AddMarket(&mkt2); err != nil {
+		t.Fatalf("AddMarket failed: %v", err)
+	}
+	if _, status, err := archie.Order(lo.ID(), lo.Base(), lo.Quote()); err != nil {
+		t.Fatalf("Order failed: %v", err)
+	} else if status != order.OrderStatusRevoked {
+		t.Fatalf("wrong status %v after adding the market with a new lot size", status)
+	}
+
+	// Updating the market's lot size does not flush the book.
+	lo2 := newLimitOrderWithAssets(false, 4900000, 10, order.StandingTiF, 0, mkt.Base, mkt.Quote)
+	if err = archie.StoreOrder(lo2, 2, 10, order.OrderStatusBooked); err != nil {
+		t.Fatalf("StoreOrder failed: %v", err)
+	}
+	mkt3 := mkt2
+	mkt3.LotSize /= 10
+	if err = archie.UpdateMarket(&mkt3); err != nil {
+		t.Fatalf("UpdateMarket failed: %v", err)
+	}
+	if _, status, err := archie.Order(lo2.ID(), lo2.Base(), lo2.Quote()); err != nil {
+		t.Fatalf("Order failed: %v", err)
+	} else if status != order.OrderStatusBooked {
+		t.Fatalf("wrong status %v after updating the market", status)
+	}
+	// The new lot size is used to validate new orders.
+	lo3 := newLimitOrderWithAssets(true, 5000000, 1, order.StandingTiF, 0, mkt.Base, mkt.Quote)
+	if err = archie.StoreOrder(lo3, 3, 10, order.OrderStatusBooked); err != nil {
+		t.Fatalf("StoreOrder failed after updating the lot size: %v", err)
+	}
+	// Unknown markets cannot be updated.
+	mkt4, _ := dex.NewMarketInfoFromSymbols("ltc", "doge", LotSize, RateStep, EpochDuration, 0, MarketBuyBuffer)
+	if err = archie.UpdateMarket(mkt4); err == nil {
+		t.Fatalf("no error updating an unknown market")
+	}
+
+	if err = cleanTables(archie.db); err != nil {
+		t.Fatalf("cleanTables: %v", err)
+	}
+}
@@ -544,11 +544,12 @@ func (a *Archiver) storeOrder(ord order.Order, epochIdx, epochDur int64, epochGa
 		return err
 	}
 
-	if !validateOrder(ord, status, a.markets[marketSchema]) {
+	mkt := a.marketInfo(marketSchema)
+	if !validateOrder(ord, status, mkt) {
 		return db.ArchiveError{
 			Code: db.ErrInvalidOrder,
 			Detail: fmt.Sprintf("invalid order %v for status %v and market %v",
-				ord.UID(), status, a.markets[marketSchema]),
+				ord.UID(), status, mkt),
 		}
 	}
 
@@ -743,7 +744,7 @@ type orderCompStamped struct {
 func (a *Archiver) CompletedUserOrders(aid account.AccountID, N int) (oids []order.OrderID, compTimes []int64, err error) {
 	var ords []orderCompStamped
 
-	for schema := range a.markets {
+	for schema := range a.marketsCopy() {
 		tableName := fullOrderTableName(a.dbName, schema, false) // NOT active table
 		ctx, cancel := context.WithTimeout(a.ctx, a.queryTimeout)
 		mktOids, err := completedUserOrders(ctx, a.db, tableName, aid, N)
@@ -831,7 +832,7 @@ func (a *Archiver) PreimageStats(user account.AccountID, lastN int) ([]*db.Preim
 		return rows.Err()
 	}
 
-	for schema := range a.markets {
+	for schema := range a.marketsCopy() {
 		// archived trade orders
 		stmt := fmt.Sprintf(internal.PreimageResultsLastN, fullOrderTableName(a.dbName, schema, false))
 		if err := queryOutcomes(stmt); err != nil {
@@ -1132,7 +1133,7 @@ func (a *Archiver) UserOrderStatuses(aid account.AccountID, base, quote uint32,
 // active orders for a user across all markets.
 func (a *Archiver) ActiveUserOrderStatuses(aid account.AccountID) ([]*db.OrderStatus, error) {
 	var orders []*db.OrderStatus
-	for schema := range a.markets {
+	for schema := range a.marketsCopy() {
 		tableName := fullOrderTableName(a.dbName, schema, true) // active table
 		mktOrders, err := a.userOrderStatusesFromTable(tableName, aid, nil)
 		if err != nil {
@@ -1192,7 +1193,7 @@ func (a *Archiver) userOrderStatusesFromTable(fullTable string, aid account.Acco
 // and archived, for an order with the given Commitment.
 func (a *Archiver) OrderWithCommit(ctx context.Context, commit order.Commitment) (found bool, oid order.OrderID, err error) {
 	// Check all markets.
-	for marketSchema := range a.markets {
+	for marketSchema := range a.marketsCopy() {
 		found, oid, err = orderForCommit(ctx, a.db, a.dbName, marketSchema, commit)
 		if err != nil {
 			a.fatalBackendErr(err)
@@ -1213,7 +1214,7 @@ func (a *Archiver) OrderWithCommit(ctx context.Context, commit order.Commitment)
 func (a *Archiver) ExecutedCancelsForUser(aid account.AccountID, N int) (ords []*db.CancelRecord, err error) {
 
 	// Check all markets.
-	for marketSchema := range a.markets {
+	for marketSchema := range a.marketsCopy() {
 		// Query for executed cancels (user-initiated).
 		cancelTableName := fullCancelOrderTableName(a.dbName, marketSchema, false) // executed cancel orders are inactive
 		epochsTableName := fullEpochsTableName(a.dbName, marketSchema)
@@ -69,9 +69,11 @@ type Archiver struct {
 	queryTimeout time.Duration
 	db           *sql.DB
 	dbName       string
-	markets      map[string]*dex.MarketInfo
 	tables       archiverTables
 
+	marketsMtx sync.RWMutex
+	markets    map[string]*dex.MarketInfo // keyed by market schema
+
 	queries struct {
 		selectPoints            *sql.Stmt // internal.SelectPoints
 		insertPoints            *sql.Stmt // internal.InsertPoints
@@ -193,7 +195,7 @@ func NewArchiver(ctx context.Context, cfg *Config) (*Archiver, error) {
 		return nil, err
 	}
 	for _, staleMarket := range purgeMarkets {
-		mkt := archiver.markets[staleMarket]
+		mkt := archiver.marketInfo(staleMarket)
 		if mkt == nil { // shouldn't happen
 			return nil, fmt.Errorf("unrecognized market %v", staleMarket)
 		}
@@ -222,8 +224,7 @@ func (a *Archiver) marketSchema(base, quote uint32) (string, error) {
 		return "", err
 	}
 	schema := marketSchema(marketName)
-	_, found := a.markets[schema]
-	if !found {
+	if a.marketInfo(schema) == nil {
 		return "", db.ArchiveError{
 			Code:   db.ErrUnsupportedMarket,
 			Detail: fmt.Sprintf(`archiver does not support the market "%s"`, schema),
@@ -232,6 +233,73 @@ func (a *Archiver) marketSchema(base, quote uint32) (string, error) {
 	return schema, nil
 }
 
+// marketInfo returns the MarketInfo for the market schema, or nil if the
+// market is not supported.
+func (a *Archiver) marketInfo(schema string) *dex.MarketInfo {
+	a.marketsMtx.RLock()
+	defer a.marketsMtx.RUnlock()
+	return a.markets[schema]
+}
+
+// marketsCopy returns a copy of the supported markets, keyed by market schema.
+func (a *Archiver) marketsCopy() map[string]*dex.MarketInfo {
+	a.marketsMtx.RLock()
+	defer a.marketsMtx.RUnlock()
+	mkts := make(map[string]*dex.MarketInfo, len(a.markets))
+	for schema, mkt := range a.markets {
+		mkts[schema] = mkt
+	}
+	return mkts
+}
+
+// AddMarket prepares the tables for a market that was not in the market
+// config, and adds it to the supported markets. If the market was previously
+// configured with a different lot size, its book is flushed.
+func (a *Archiver) AddMarket(mkt *dex.MarketInfo) error {
+	schema := marketSchema(mkt.Name)
+	purgeMarkets, err := prepareMarkets(a.db, []*dex.MarketInfo{mkt})
+	if err != nil {
+		return err
+	}
+	a.marketsMtx.Lock()
+	a.markets[schema] = mkt
+	a.marketsMtx.Unlock()
+	if len(purgeMarkets) > 0 {
+		unbookedSells, unbookedBuys, err := a.FlushBook(mkt.Base, mkt.Quote)
+		if err != nil {
+			return fmt.Errorf("failed to flush book for market %v: %w", mkt.Name, err)
+		}
+		log.Infof("Flushed %d sell orders and %d buy orders from market %v with a changed lot size.",
+			len(unbookedSells), len(unbookedBuys), mkt.Name)
+	}
+	return nil
+}
+
+// UpdateMarket changes the lot size and rate step of a supported market.
+// Unlike AddMarket, the book is not flushed, so the caller is responsible for
+// revoking booked orders that are incompatible with the new lot size.
+func (a *Archiver) UpdateMarket(mkt *dex.MarketInfo) error {
+	schema, err := a.marketSchema(mkt.Base, mkt.Quote)
+	if err != nil {
+		return err
+	}
+	if err := updateLotSize(a.db, publicSchema, mkt.Name, mkt.LotSize); err != nil {
+		return fmt.Errorf("failed to update lot size for market %v: %w", mkt.Name, err)
+	}
+	a.marketsMtx.Lock()
+	a.markets[schema] = mkt
+	a.marketsMtx.Unlock()
+	return nil
+}
+
+// RemoveMarket removes a market from the supported markets. The market's tables
+// are not dropped.
+func (a *Archiver) RemoveMarket(mkt *dex.MarketInfo) {
+	a.marketsMtx.Lock()
+	delete(a.markets, marketSchema(mkt.Name))
+	a.marketsMtx.Unlock()
+}
+
 func (a *Archiver) prepareQueries() (err error) {
 	a.queries.selectPoints, err = a.db.Prepare(fmt.Sprintf(internal.SelectPoints, a.tables.points))
 	if err != nil {
@@ -88,6 +88,16 @@ type DEXArchivist interface {
 	LastCandleEndStamp(base, quote uint32, candleDur uint64) (uint64, error)
 	InsertCandles(base, quote uint32, dur uint64, cs []*candles.Candle) error
 
+	// AddMarket prepares storage for a market that was not in the market
+	// config provided to the archivist's constructor.
+	AddMarket(mkt *dex.MarketInfo) error
+	// UpdateMarket changes the stored lot size and rate step of a supported
+	// market without flushing its book.
+	UpdateMarket(mkt *dex.MarketInfo) error
+	// RemoveMarket removes a market added with AddMarket from the supported
+	// markets. The market's stored orders and matches are kept.
+	RemoveMarket(mkt *dex.MarketInfo)
+
 	OrderArchiver
 	AccountArchiver
 	KeyIndexer
@@ -31,6 +31,7 @@ import (
 	"decred.org/dcrdex/server/coinlock"
 	"decred.org/dcrdex/server/comms"
 	"decred.org/dcrdex/server/db"
+	"decred.org/dcrdex/server/db/driver/lexidb"
 	"decred.org/dcrdex/server/db/driver/pg"
 	"decred.org/dcrdex/server/market"
 	"decred.org/dcrdex/server/noderelay"
@@ -258,8 +259,61 @@ func loadMarketConf(net dex.Network, src io.Reader) ([]*dex.MarketInfo, []*Asset
 	return markets, assets, nil
 }
 
+// validateMarket checks a market against the configuration of its assets.
+// Markets added at runtime are refused if they fail the checks, while the
+// markets created at startup only log a warning. getAsset returns the
+// configuration of an asset, or nil if the asset is not configured.
+func validateMarket(mkt *dex.MarketInfo, getAsset func(assetID uint32) *dex.Asset) error {
+	if mkt.Base == mkt.Quote {
+		return fmt.Errorf("market %s has the same base and quote asset", mkt.Name)
+	}
+	if mkt.EpochDuration == 0 {
+		return fmt.Errorf("market %s has a zero epoch duration", mkt.Name)
+	}
+	if mkt.ParcelSize == 0 {
+		return fmt.Errorf("market %s has a zero parcel size", mkt.Name)
+	}
+	for _, assetID := range []uint32{mkt.Base, mkt.Quote} {
+		if getAsset(assetID) == nil {
+			return fmt.Errorf("asset %d (%s) is not configured", assetID, dex.BipIDSymbol(assetID))
+		}
+		if is, parentID := asset.IsToken(assetID); is && getAsset(parentID) == nil {
+			return fmt.Errorf("parent asset %s not configured for token %s",
+				dex.BipIDSymbol(parentID), dex.BipIDSymbol(assetID))
+		}
+	}
+	return validateMarketParams(mkt.Name, getAsset(mkt.Base), getAsset(mkt.Quote), mkt.LotSize, mkt.RateStep)
+}
+
+// validateMarketParams checks a market's lot size and rate step. The lot size
+// may not be below the base asset's minimum lot size at its max fee rate, which
+// would create dust swaps, and one lot at one rate step must be worth at least
+// one atom of the quote asset.
+func validateMarketParams(mktName string, base, quote *dex.Asset, lotSize, rateStep uint64) error {
+	if lotSize == 0 || rateStep == 0 {
+		return fmt.Errorf("market %s lot size and rate step must be non-zero", mktName)
+	}
+	for _, a := range []*dex.Asset{base, quote} {
+		if a.MaxFeeRate == 0 {
+			return fmt.Errorf("max fee rate of 0 is invalid for asset %s", a.Symbol)
+		}
+	}
+	if minLotSize, _, found := asset.Minimums(base.ID, base.MaxFeeRate); found && lotSize < minLotSize {
+		return fmt.Errorf("market %s lot size %d is below the minimum lot size %d for %s at max fee rate %d",
+			mktName, lotSize, minLotSize, base.Symbol, base.MaxFeeRate)
+	}
+	if calc.BaseToQuote(rateStep, lotSize) == 0 {
+		return fmt.Errorf("market %s rate step %d is too small for lot size %d", mktName, rateStep, lotSize)
+	}
+	return nil
+}
+
 // DBConf groups the database configuration parameters.
 type DBConf struct {
+	// Driver is the name of the DB driver, "pg" or "lexi". The lexi driver
+	// keeps the database in the DEX data directory, and the remaining fields
+	// are ignored. An empty Driver is the same as "pg".
+	Driver       string
 	DBName       string
 	User         string
 	Pass         string
@@ -454,28 +508,65 @@ func (ss *subsystem) stop() {
 // components of the DEX.
 type DEX struct {
 	network     dex.Network
-	markets     map[string]*market.Market
 	assets      map[uint32]*swap.SwapperAsset
 	storage     db.DEXArchivist
 	authMgr     *auth.AuthManager
 	swapper     *swap.Swapper
 	orderRouter *market.OrderRouter
 	bookRouter  *market.BookRouter
-	subsystems  []subsystem
+	dexBalancer *market.DEXBalancer
+	dataAPI     *apidata.DataAPI
 	server      *comms.Server
+	events      *eventFeed
+	// newMarket creates a market with the same dependencies as the markets
+	// created at startup.
+	newMarket func(*dex.MarketInfo) (*market.Market, error)
+
+	// marketsMtx guards markets, retiring, and subsystems, which change when
+	// markets are added or retired.
+	marketsMtx sync.RWMutex
+	markets    map[string]*market.Market
+	retiring   map[string]bool
+	subsystems []subsystem
+
+	// quit is closed by Stop to end the market retirement goroutines, which
+	// are tracked by wg.
+	quit chan struct{}
+	wg   sync.WaitGroup
+	// ctx is the parent context of markets added after startup. It is
+	// canceled by Stop.
+	ctx    context.Context
+	cancel context.CancelFunc
 
 	configRespMtx sync.RWMutex
 	configResp    *configResponse
 }
 
-// configResponse is defined here to leave open the possibility for hot
-// adjustable parameters while storing a pre-encoded config response message. An
-// update method will need to be defined in the future for this purpose.
+// configResponse stores a pre-encoded config response message along with the
+// ConfigResult, which is updated and re-encoded when a market is suspended,
+// resumed, added, or retired.
 type configResponse struct {
-	configMsg *msgjson.ConfigResult // constant for now
+	configMsg *msgjson.ConfigResult
 	configEnc json.RawMessage
 }
 
+// cfgMarket creates the config response entry for a market.
+func cfgMarket(name string, mkt *market.Market, startEpochIdx int64) *msgjson.Market {
+	return &msgjson.Market{
+		Name:            name,
+		Base:            mkt.Base(),
+		Quote:           mkt.Quote(),
+		LotSize:         mkt.LotSize(),
+		RateStep:        mkt.RateStep(),
+		EpochLen:        mkt.EpochDuration(),
+		MarketBuyBuffer: mkt.MarketBuyBuffer(),
+		ParcelSize:      mkt.ParcelSize(),
+		MarketStatus: msgjson.MarketStatus{
+			StartEpoch: uint64(startEpochIdx),
+		},
+	}
+}
+
 func newConfigResponse(cfg *DexConf, bondAssets map[string]*msgjson.BondAsset,
 	cfgAssets []*msgjson.Asset, cfgMarkets []*msgjson.Market) (*configResponse, error) {
 
@@ -535,6 +626,34 @@ func (cr *configResponse) setMktResume(name string, startEpoch uint64) (epochLen
 	return 0
 }
 
+func (cr *configResponse) setMktParams(name string, lotSize, rateStep uint64) {
+	for _, mkt := range cr.configMsg.Markets {
+		if mkt.Name == name {
+			mkt.LotSize = lotSize
+			mkt.RateStep = rateStep
+			cr.remarshal()
+			return
+		}
+	}
+	log.Errorf("Failed to update lot size and rate step for market %q", name)
+}
+
+func (cr *configResponse) addMarket(mkt *msgjson.Market) {
+	cr.configMsg.Markets = append(cr.configMsg.Markets, mkt)
+	cr.remarshal()
+}
+
+func (cr *configResponse) removeMarket(name string) {
+	mkts := make([]*msgjson.Market, 0, len(cr.configMsg.Markets))
+	for _, mkt := range cr.configMsg.Markets {
+		if mkt.Name != name {
+			mkts = append(mkts, mkt)
+		}
+	}
+	cr.configMsg.Markets = mkts
+	cr.remarshal()
+}
+
 func (cr *configResponse) remarshal() {
 	encResult, err := json.Marshal(cr.configMsg)
 	if err != nil {
@@ -547,8 +666,14 @@ func (cr *configResponse) remarshal() {
 // Stop shuts down the DEX. Stop returns only after all components have
 // completed their shutdown.
 func (dm *DEX) Stop() {
+	close(dm.quit)
+	dm.cancel()
+	dm.wg.Wait()
 	log.Infof("Stopping all DEX subsystems.")
-	for _, ss := range dm.subsystems {
+	dm.marketsMtx.RLock()
+	subsystems := dm.subsystems
+	dm.marketsMtx.RUnlock()
+	for _, ss := range subsystems {
 		log.Infof("Stopping %s...", ss.name)
 		ss.stop()
 		log.Infof("%s is now shut down.", ss.name)
@@ -671,17 +796,30 @@ func NewDEX(ctx context.Context, cfg *DexConf) (*DEX, error) {
 		assetIDs[i] = assetID
 	}
 
-	// Create DEXArchivist with the pg DB driver. The fee Addressers require the
-	// archivist for key index storage and retrieval.
-	pgCfg := &pg.Config{
-		Host:         cfg.DBConf.Host,
-		Port:         strconv.Itoa(int(cfg.DBConf.Port)),
-		User:         cfg.DBConf.User,
-		Pass:         cfg.DBConf.Pass,
-		DBName:       cfg.DBConf.DBName,
-		ShowPGConfig: cfg.DBConf.ShowPGConfig,
-		QueryTimeout: 20 * time.Minute,
-		MarketCfg:    cfg.Markets,
+	// Create DEXArchivist with the configured DB driver. The fee Addressers
+	// require the archivist for key index storage and retrieval.
+	dbDriver := cfg.DBConf.Driver
+	var dbCfg any
+	switch dbDriver {
+	case "", "pg":
+		dbDriver = "pg"
+		dbCfg = &pg.Config{
+			Host:         cfg.DBConf.Host,
+			Port:         strconv.Itoa(int(cfg.DBConf.Port)),
+			User:         cfg.DBConf.User,
+			Pass:         cfg.DBConf.Pass,
+			DBName:       cfg.DBConf.DBName,
+			ShowPGConfig: cfg.DBConf.ShowPGConfig,
+			QueryTimeout: 20 * time.Minute,
+			MarketCfg:    cfg.Markets,
+		}
+	case "lexi":
+		dbCfg = &lexidb.Config{
+			Path:      filepath.Join(cfg.DataDir, "dexdb"),
+			MarketCfg: cfg.Markets,
+		}
+	default:
+		return nil, fmt.Errorf("unknown DB driver %q", dbDriver)
 	}
 	// After DEX construction, the storage subsystem should be stopped
 	// gracefully with its Close method, and in coordination with other
@@ -696,7 +834,7 @@ func NewDEX(ctx context.Context, cfg *DexConf) (*DEX, error) {
 		case <-running: // DB shutdown now only via dex.Stop=>db.Close
 		}
 	}()
-	storage, err := db.Open(ctxDB, "pg", pgCfg)
+	storage, err := db.Open(ctxDB, dbDriver, dbCfg)
 	if err != nil {
 		return nil, fmt.Errorf("db.Open: %w", err)
 	}
@@ -885,10 +1023,21 @@ func NewDEX(ctx context.Context, cfg *DexConf) (*DEX, error) {
 		return nil, err
 	}
 
-	// Create the user order unbook dispatcher for the AuthManager.
+	// The DEX manager is created now so that the dispatchers below can find
+	// markets added after startup. The remaining fields are set once the
+	// subsystems are created.
 	markets := make(map[string]*market.Market, len(cfg.Markets))
+	dexMgr := &DEX{
+		network:  cfg.Network,
+		markets:  markets,
+		retiring: make(map[string]bool),
+		quit:     make(chan struct{}),
+	}
+	dexMgr.ctx, dexMgr.cancel = context.WithCancel(context.Background())
+
+	// Create the user order unbook dispatcher for the AuthManager.
 	userUnbookFun := func(user account.AccountID) {
-		for _, mkt := range markets {
+		for _, mkt := range dexMgr.marketList() {
 			mkt.UnbookUserOrders(user)
 		}
 	}
@@ -926,6 +1075,9 @@ func NewDEX(ctx context.Context, cfg *DexConf) (*DEX, error) {
 
 	dataAPI := apidata.NewDataAPI(storage, server.RegisterHTTP)
 
+	// Real-time events for operators.
+	events := newEventFeed()
+
 	authCfg := auth.Config{
 		Storage:          storage,
 		Signer:           signer{cfg.DEXPrivKey},
@@ -940,6 +1092,8 @@ func NewDEX(ctx context.Context, cfg *DexConf) (*DEX, error) {
 		PenaltyThreshold: cfg.PenaltyThreshold,
 		TxDataSources:    txDataSources,
 		Route:            server.Route,
+		Penalized:        events.penaltyEvent,
+		TierChanged:      events.tierChangeEvent,
 	}
 
 	authMgr := auth.NewAuthManager(&authCfg)
@@ -958,7 +1112,12 @@ func NewDEX(ctx context.Context, cfg *DexConf) (*DEX, error) {
 			log.Errorf("bad market for order %v: %v", ord.ID(), err)
 			return
 		}
-		markets[name].SwapDone(ord, match, fail)
+		mkt := dexMgr.market(name)
+		if mkt == nil {
+			log.Errorf("unknown market %s for order %v", name, ord.ID())
+			return
+		}
+		mkt.SwapDone(ord, match, fail)
 	}
 
 	// Create the swapper.
@@ -971,6 +1130,7 @@ func NewDEX(ctx context.Context, cfg *DexConf) (*DEX, error) {
 		LockTimeTaker:    dex.LockTimeTaker(cfg.Network),
 		LockTimeMaker:    dex.LockTimeMaker(cfg.Network),
 		SwapDone:         swapDone,
+		MatchEvent:       events.matchEvent,
 		NoResume:         cfg.NoResumeSwaps,
 		// TODO: set the AllowPartialRestore bool to allow startup with a
 		// missing asset backend if necessary in an emergency.
@@ -985,7 +1145,7 @@ func NewDEX(ctx context.Context, cfg *DexConf) (*DEX, error) {
 		return nil, err
 	}
 
-	// Because the dexBalancer relies on the marketTunnels map, and NewMarket
+	// Because the dexBalancer relies on the markets added to it, and NewMarket
 	// checks necessary balances for account-based assets using the dexBalancer,
 	// that means that each market can only query orders for the markets that
 	// were initialized before it was, which is fine, but notable. The
@@ -1002,17 +1162,15 @@ func NewDEX(ctx context.Context, cfg *DexConf) (*DEX, error) {
 	// they are all instantiated, so we are synchronous in our use of the
 	// marketTunnels map.
 	marketTunnels := make(map[string]market.MarketTunnel, len(cfg.Markets))
-	pendingAccounters := make(map[string]market.PendingAccounter, len(cfg.Markets))
 
-	dexBalancer, err := market.NewDEXBalancer(pendingAccounters, backedAssets, swapper)
+	dexBalancer, err := market.NewDEXBalancer(nil, backedAssets, swapper)
 	if err != nil {
 		return nil, fmt.Errorf("NewDEXBalancer error: %w", err)
 	}
 
 	// Markets
 	var orderRouter *market.OrderRouter
-	usersWithOrders := make(map[account.AccountID]struct{})
-	for _, mktInf := range cfg.Markets {
+	newMarket := func(mktInf *dex.MarketInfo) (*market.Market, error) {
 		// nilness of the coin locker signals account-based asset.
 		var baseCoinLocker, quoteCoinLocker coinlock.CoinLocker
 		b, q := backedAssets[mktInf.Base], backedAssets[mktInf.Quote]
@@ -1044,18 +1202,46 @@ func NewDEX(ctx context.Context, cfg *DexConf) (*DEX, error) {
 				return orderRouter.CheckParcelLimit(user, mktInf.Name, calcParcels)
 			},
 			MinimumRate: minRate,
+			BookEvent: func(be *market.BookEvent) {
+				events.bookEvent(mktInf.Name, be)
+			},
+			ParamsChanged: func(change *market.ParamChange) {
+				dexMgr.marketParamsChanged(mktInf.Name, change)
+			},
 		})
 		if err != nil {
 			return nil, fmt.Errorf("NewMarket failed: %w", err)
 		}
-		markets[mktInf.Name] = mkt
-		marketTunnels[mktInf.Name] = mkt
-		pendingAccounters[mktInf.Name] = mkt
 		log.Infof("Preparing historical market data API for market %v...", mktInf.Name)
 		err = dataAPI.AddMarketSource(mkt)
 		if err != nil {
 			return nil, fmt.Errorf("DataSource.AddMarketSource: %w", err)
 		}
+		return mkt, nil
+	}
+
+	assetConfig := func(assetID uint32) *dex.Asset {
+		if ba := backedAssets[assetID]; ba != nil {
+			return &ba.Asset
+		}
+		return nil
+	}
+
+	usersWithOrders := make(map[account.AccountID]struct{})
+	for _, mktInf := range cfg.Markets {
+		// Configured markets are only checked by ValidateConfigFile, so an
+		// existing markets.json does not stop the server from starting.
+		// Markets added or changed at runtime must pass these checks.
+		if err := validateMarket(mktInf, assetConfig); err != nil {
+			log.Warnf("Market %s would be refused if added at runtime: %v", mktInf.Name, err)
+		}
+		mkt, err := newMarket(mktInf)
+		if err != nil {
+			return nil, err
+		}
+		markets[mktInf.Name] = mkt
+		marketTunnels[mktInf.Name] = mkt
+		dexBalancer.AddMarket(mkt)
 
 		// Having loaded the book, get the accounts owning the orders.
 		_, buys, sells := mkt.Book()
@@ -1086,19 +1272,7 @@ func NewDEX(ctx context.Context, cfg *DexConf) (*DEX, error) {
 		startEpochIdx := 1 + now/int64(mkt.EpochDuration())
 		mkt.SetStartEpochIdx(startEpochIdx)
 		bookSources[name] = mkt
-		cfgMarkets = append(cfgMarkets, &msgjson.Market{
-			Name:            name,
-			Base:            mkt.Base(),
-			Quote:           mkt.Quote(),
-			LotSize:         mkt.LotSize(),
-			RateStep:        mkt.RateStep(),
-			EpochLen:        mkt.EpochDuration(),
-			MarketBuyBuffer: mkt.MarketBuyBuffer(),
-			ParcelSize:      mkt.ParcelSize(),
-			MarketStatus: msgjson.MarketStatus{
-				StartEpoch: uint64(startEpochIdx),
-			},
-		})
+		cfgMarkets = append(cfgMarkets, cfgMarket(name, mkt, startEpochIdx))
 	}
 
 	// Book router
@@ -1133,19 +1307,19 @@ func NewDEX(ctx context.Context, cfg *DexConf) (*DEX, error) {
 		return nil, err
 	}
 
-	dexMgr := &DEX{
-		network:     cfg.Network,
-		markets:     markets,
-		assets:      lockableAssets,
-		swapper:     swapper,
-		authMgr:     authMgr,
-		storage:     storage,
-		orderRouter: orderRouter,
-		bookRouter:  bookRouter,
-		subsystems:  subsystems,
-		server:      server,
-		configResp:  cfgResp,
-	}
+	dexMgr.assets = lockableAssets
+	dexMgr.swapper = swapper
+	dexMgr.authMgr = authMgr
+	dexMgr.storage = storage
+	dexMgr.orderRouter = orderRouter
+	dexMgr.bookRouter = bookRouter
+	dexMgr.dexBalancer = dexBalancer
+	dexMgr.dataAPI = dataAPI
+	dexMgr.newMarket = newMarket
+	dexMgr.subsystems = subsystems
+	dexMgr.server = server
+	dexMgr.events = events
+	dexMgr.configResp = cfgResp
 
 	server.RegisterHTTP(msgjson.ConfigRoute, dexMgr.handleDEXConfig)
 	server.RegisterHTTP(msgjson.HealthRoute, dexMgr.handleHealthFlag)
@@ -1186,7 +1360,7 @@ func (dm *DEX) Asset(id uint32) (*asset.BackedAsset, error) {
 // the optimal fee rates for new swaps for for the specified asset. That is,
 // values above 1 increase the fee rate, while values below 1 decrease it.
 func (dm *DEX) SetFeeRateScale(assetID uint32, scale float64) {
-	for _, mkt := range dm.markets {
+	for _, mkt := range dm.marketList() {
 		if mkt.Base() == assetID || mkt.Quote() == assetID {
 			mkt.SetFeeRateScale(assetID, scale)
 		}
@@ -1197,7 +1371,7 @@ func (dm *DEX) SetFeeRateScale(assetID uint32, scale float64) {
 // rate scale factor, which is 1.0 by default.
 func (dm *DEX) ScaleFeeRate(assetID uint32, rate uint64) uint64 {
 	// Any market will have the rate. Just find the first one.
-	for _, mkt := range dm.markets {
+	for _, mkt := range dm.marketList() {
 		if mkt.Base() == assetID || mkt.Quote() == assetID {
 			return mkt.ScaleFeeRate(assetID, rate)
 		}
@@ -1215,7 +1389,7 @@ func (dm *DEX) ConfigMsg() json.RawMessage {
 // TODO: for just market running status, the DEX manager should use its
 // knowledge of Market subsystem state.
 func (dm *DEX) MarketRunning(mktName string) (found, running bool) {
-	mkt := dm.markets[mktName]
+	mkt := dm.market(mktName)
 	if mkt == nil {
 		return
 	}
@@ -1225,7 +1399,7 @@ func (dm *DEX) MarketRunning(mktName string) (found, running bool) {
 // MarketStatus returns the market.Status for the named market. If the market is
 // unknown to the DEX, nil is returned.
 func (dm *DEX) MarketStatus(mktName string) *market.Status {
-	mkt := dm.markets[mktName]
+	mkt := dm.market(mktName)
 	if mkt == nil {
 		return nil
 	}
@@ -1235,13 +1409,52 @@ func (dm *DEX) MarketStatus(mktName string) *market.Status {
 // MarketStatuses returns a map of market names to market.Status for all known
 // markets.
 func (dm *DEX) MarketStatuses() map[string]*market.Status {
-	statuses := make(map[string]*market.Status, len(dm.markets))
-	for name, mkt := range dm.markets {
+	mkts := dm.marketsCopy()
+	statuses := make(map[string]*market.Status, len(mkts))
+	for name, mkt := range mkts {
 		statuses[name] = mkt.Status()
 	}
 	return statuses
 }
 
+// market retrieves the named market, or nil if the market is unknown.
+func (dm *DEX) market(mktName string) *market.Market {
+	dm.marketsMtx.RLock()
+	defer dm.marketsMtx.RUnlock()
+	return dm.markets[mktName]
+}
+
+// assetConfig returns the configuration of an asset, or nil if the asset is
+// not configured.
+func (dm *DEX) assetConfig(assetID uint32) *dex.Asset {
+	if a := dm.assets[assetID]; a != nil {
+		return &a.Asset
+	}
+	return nil
+}
+
+// marketsCopy returns a copy of the markets map.
+func (dm *DEX) marketsCopy() map[string]*market.Market {
+	dm.marketsMtx.RLock()
+	defer dm.marketsMtx.RUnlock()
+	mkts := make(map[string]*market.Market, len(dm.markets))
+	for name, mkt := range dm.markets {
+		mkts[name] = mkt
+	}
+	return mkts
+}
+
+// marketList returns the markets.
+func (dm *DEX) marketList() []*market.Market {
+	dm.marketsMtx.RLock()
+	defer dm.marketsMtx.RUnlock()
+	mkts := make([]*market.Market, 0, len(dm.markets))
+	for _, mkt := range dm.markets {
+		mkts = append(mkts, mkt)
+	}
+	return mkts
+}
+
 // SuspendMarket schedules a suspension of a given market, with the option to
 // persist the orders on the book (or purge the book automatically on market
 // shutdown). The scheduled final epoch and suspend time are returned. This is a
@@ -1251,12 +1464,14 @@ func (dm *DEX) SuspendMarket(name string, tSusp time.Time, persistBooks bool) (s
 	name = strings.ToLower(name)
 
 	// Locate the (running) subsystem for this market.
-	i := dm.findSubsys(marketSubSysName(name))
-	if i == -1 {
+	dm.marketsMtx.RLock()
+	ssw := dm.marketSubsys(name)
+	dm.marketsMtx.RUnlock()
+	if ssw == nil {
 		err = fmt.Errorf("market subsystem %s not found", name)
 		return
 	}
-	if !dm.subsystems[i].ssw.On() {
+	if !ssw.On() {
 		err = fmt.Errorf("market subsystem %s is not running", name)
 		return
 	}
@@ -1290,6 +1505,17 @@ func (dm *DEX) SuspendMarket(name string, tSusp time.Time, persistBooks bool) (s
 	return
 }
 
+// marketSubsys returns the StartStopWaiter for the named market's subsystem, or
+// nil if there is no such subsystem. The marketsMtx MUST be locked.
+func (dm *DEX) marketSubsys(name string) *dex.StartStopWaiter {
+	if i := dm.findSubsys(marketSubSysName(name)); i != -1 {
+		return dm.subsystems[i].ssw
+	}
+	return nil
+}
+
+// findSubsys returns the index of the named subsystem, or -1 if there is no
+// such subsystem. The marketsMtx MUST be locked.
 func (dm *DEX) findSubsys(name string) int {
 	for i := range dm.subsystems {
 		if dm.subsystems[i].name == name {
@@ -1304,11 +1530,17 @@ func (dm *DEX) findSubsys(name string) int {
 // duration, as the market only starts at the beginning of an epoch.
 func (dm *DEX) ResumeMarket(name string, asSoonAs time.Time) (startEpoch int64, startTime time.Time, err error) {
 	name = strings.ToLower(name)
+	dm.marketsMtx.Lock()
+	defer dm.marketsMtx.Unlock()
 	mkt := dm.markets[name]
 	if mkt == nil {
 		err = fmt.Errorf("unknown market %s", name)
 		return
 	}
+	if dm.retiring[name] {
+		err = fmt.Errorf("market %s is being retired", name)
+		return
+	}
 
 	// Get the next available start epoch given the earliest allowed time.
 	// Requires the market to be stopped already.
@@ -1363,6 +1595,254 @@ func (dm *DEX) ResumeMarket(name string, asSoonAs time.Time) (startEpoch int64,
 	return
 }
 
+// retireCheckInterval is how often a retiring market is checked for active
+// swaps.
+var retireCheckInterval = time.Minute
+
+// AddMarket creates and starts a market that was not in the market config. The
+// market's assets must already be configured, and the market is checked as for
+// the markets in the market config. The updated config is broadcast to all
+// connected clients. The market is not written to the config file, so it must
+// also be added there to be created on the next startup.
+func (dm *DEX) AddMarket(mktInf *dex.MarketInfo) (startEpoch int64, startTime time.Time, err error) {
+	name := strings.ToLower(mktInf.Name)
+	mktInf.Name = name
+	if err = validateMarket(mktInf, dm.assetConfig); err != nil {
+		return
+	}
+	if dm.market(name) != nil {
+		err = fmt.Errorf("market %s already exists", name)
+		return
+	}
+
+	// Undo the registrations made so far if the market is not added.
+	var undo []func()
+	defer func() {
+		if err != nil {
+			for i := len(undo) - 1; i >= 0; i-- {
+				undo[i]()
+			}
+		}
+	}()
+
+	// The Market is created without holding the marketsMtx since NewMarket
+	// loads the book, checking balances with the Swapper.
+	if err = dm.storage.AddMarket(mktInf); err != nil {
+		err = fmt.Errorf("error preparing storage for market %s: %w", name, err)
+		return
+	}
+	undo = append(undo, func() { dm.storage.RemoveMarket(mktInf) })
+	mkt, err := dm.newMarket(mktInf)
+	if err != nil {
+		return
+	}
+	undo = append(undo, func() { dm.dataAPI.RemoveMarketSource(name) })
+
+	dm.marketsMtx.Lock()
+	defer dm.marketsMtx.Unlock()
+	if dm.markets[name] != nil {
+		err = fmt.Errorf("market %s already exists", name)
+		return
+	}
+
+	startEpoch = 1 + time.Now().UnixMilli()/int64(mkt.EpochDuration())
+	startTime = time.UnixMilli(startEpoch * int64(mkt.EpochDuration()))
+	mkt.SetStartEpochIdx(startEpoch)
+	dm.dexBalancer.AddMarket(mkt)
+	undo = append(undo, func() { dm.dexBalancer.RemoveMarket(mkt) })
+	if err = dm.bookRouter.AddBook(name, mkt); err != nil {
+		return
+	}
+	undo = append(undo, func() { dm.bookRouter.RemoveBook(name) })
+
+	// Start the market after the book router, and before the order router.
+	ssw := dex.NewStartStopWaiter(mkt)
+	ssw.Start(dm.ctx)
+	if err = dm.orderRouter.AddMarket(name, mkt); err != nil {
+		ssw.Stop()
+		ssw.WaitForShutdown()
+		err = fmt.Errorf("error adding market %s to the order router: %w", name, err)
+		return
+	}
+	dm.subsystems = append([]subsystem{{name: marketSubSysName(name), ssw: ssw}}, dm.subsystems...)
+	dm.markets[name] = mkt
+
+	dm.configRespMtx.Lock()
+	dm.configResp.addMarket(cfgMarket(name, mkt, startEpoch))
+	dm.configRespMtx.Unlock()
+	dm.broadcastConfig()
+
+	log.Infof("Added market %s, starting at epoch %d (%v)", name, startEpoch, startTime)
+	return
+}
+
+// RetireMarket suspends the named market as soon as possible, purging the
+// book, and removes it once all of its active swaps are complete. A market
+// that is already suspended is removed without waiting for a new suspension.
+// When the market is removed, the updated config is broadcast to all
+// connected clients. The market is not removed from the config file, so it
+// must also be removed there to not be recreated on the next startup.
+func (dm *DEX) RetireMarket(name string) (*market.SuspendEpoch, error) {
+	name = strings.ToLower(name)
+	dm.marketsMtx.Lock()
+	mkt := dm.markets[name]
+	if mkt == nil {
+		dm.marketsMtx.Unlock()
+		return nil, fmt.Errorf("unknown market %s", name)
+	}
+	if dm.retiring[name] {
+		dm.marketsMtx.Unlock()
+		return nil, fmt.Errorf("market %s is already being retired", name)
+	}
+	dm.retiring[name] = true
+	ssw := dm.marketSubsys(name)
+	dm.marketsMtx.Unlock()
+
+	var suspEpoch *market.SuspendEpoch
+	if ssw != nil && ssw.On() {
+		var err error
+		suspEpoch, err = dm.SuspendMarket(name, time.Time{}, false)
+		if err != nil {
+			dm.marketsMtx.Lock()
+			delete(dm.retiring, name)
+			dm.marketsMtx.Unlock()
+			return nil, err
+		}
+	} else {
+		status := mkt.Status()
+		suspEpoch = &market.SuspendEpoch{
+			Idx: status.SuspendEpoch,
+			End: time.UnixMilli((status.SuspendEpoch + 1) * int64(status.EpochDuration)),
+		}
+	}
+
+	dm.wg.Add(1)
+	go func() {
+		defer dm.wg.Done()
+		dm.retireMarket(name, mkt, ssw)
+	}()
+
+	return suspEpoch, nil
+}
+
+// retireMarket waits for the market to stop and for its active swaps to
+// complete, and then removes it.
+func (dm *DEX) retireMarket(name string, mkt *market.Market, ssw *dex.StartStopWaiter) {
+	ticker := time.NewTicker(retireCheckInterval)
+	defer ticker.Stop()
+	for {
+		if !mkt.Running() && dm.swapper.MarketSwapCount(mkt.Base(), mkt.Quote()) == 0 {
+			break
+		}
+		select {
+		case <-ticker.C:
+		case <-dm.quit:
+			log.Warnf("Shutting down before market %s was retired.", name)
+			return
+		}
+	}
+	if ssw != nil {
+		ssw.Stop() // a no-op if the suspension already stopped the market
+		ssw.WaitForShutdown()
+	}
+
+	// Purge any orders persisted by an earlier suspension.
+	mkt.PurgeBook()
+
+	dm.orderRouter.RemoveMarket(name)
+	dm.bookRouter.RemoveBook(name)
+	dm.dexBalancer.RemoveMarket(mkt)
+	dm.dataAPI.RemoveMarketSource(name)
+
+	dm.marketsMtx.Lock()
+	delete(dm.markets, name)
+	delete(dm.retiring, name)
+	if i := dm.findSubsys(marketSubSysName(name)); i != -1 {
+		dm.subsystems = append(dm.subsystems[:i:i], dm.subsystems[i+1:]...)
+	}
+	dm.marketsMtx.Unlock()
+
+	dm.configRespMtx.Lock()
+	dm.configResp.removeMarket(name)
+	dm.configRespMtx.Unlock()
+	dm.broadcastConfig()
+
+	log.Infof("Retired market %s", name)
+}
+
+// ScheduleMarketParams schedules a change to the lot size and rate step of a
+// market as soon as the given time, but no sooner than a couple of epochs from
+// now. A MarketParams notification is broadcast to all connected clients
+// immediately, and the updated config is broadcast when the change takes
+// effect. Booked orders that are incompatible with the new lot size or rate
+// step are revoked. The market config file is not modified, so it must also be
+// updated to avoid a book purge on the next startup.
+func (dm *DEX) ScheduleMarketParams(name string, asSoonAs time.Time, lotSize, rateStep uint64) (*market.ParamChange, error) {
+	name = strings.ToLower(name)
+	dm.marketsMtx.RLock()
+	mkt, retiring := dm.markets[name], dm.retiring[name]
+	dm.marketsMtx.RUnlock()
+	if mkt == nil {
+		return nil, fmt.Errorf("unknown market %s", name)
+	}
+	if retiring {
+		return nil, fmt.Errorf("market %s is being retired", name)
+	}
+	// Apply the same checks as for the markets created at startup.
+	base, quote := dm.assetConfig(mkt.Base()), dm.assetConfig(mkt.Quote())
+	if err := validateMarketParams(name, base, quote, lotSize, rateStep); err != nil {
+		return nil, err
+	}
+
+	// The minimum rate that avoids dust depends on the lot size.
+	quoteMinLotSize, _, _ := asset.Minimums(quote.ID, quote.MaxFeeRate)
+	minRate := calc.MinimumMarketRate(lotSize, quoteMinLotSize)
+
+	change, err := mkt.ScheduleParamChange(asSoonAs, lotSize, rateStep, minRate)
+	if err != nil {
+		return nil, err
+	}
+
+	// Broadcast a MarketParams notification to all connected clients.
+	note, err := msgjson.NewNotification(msgjson.MarketParamsRoute, msgjson.MarketParams{
+		MarketID:   name,
+		StartEpoch: uint64(change.Epoch),
+		StartTime:  uint64(change.Start.UnixMilli()),
+		LotSize:    lotSize,
+		RateStep:   rateStep,
+	})
+	if err != nil {
+		log.Errorf("Failed to create market params notification: %v", err)
+		// Notification or not, the change is scheduled, so do not return error.
+	} else {
+		dm.server.Broadcast(note)
+	}
+	return change, nil
+}
+
+// marketParamsChanged updates the config when a scheduled change to a
+// market's lot size and rate step takes effect, and broadcasts the updated
+// config to all connected clients.
+func (dm *DEX) marketParamsChanged(name string, change *market.ParamChange) {
+	dm.configRespMtx.Lock()
+	dm.configResp.setMktParams(name, change.LotSize, change.RateStep)
+	dm.configRespMtx.Unlock()
+	dm.broadcastConfig()
+}
+
+// broadcastConfig sends the current config to all connected clients in a
+// config notification.
+func (dm *DEX) broadcastConfig() {
+	dm.configRespMtx.RLock()
+	note, err := msgjson.NewNotification(msgjson.ConfigRoute, dm.configResp.configEnc)
+	dm.configRespMtx.RUnlock()
+	if err != nil {
+		log.Errorf("Failed to create config notification: %v", err)
+		return
+	}
+	dm.server.Broadcast(note)
+}
+
 // AccountInfo returns data for an account.
 func (dm *DEX) AccountInfo(aid account.AccountID) (*db.Account, error) {
 	// TODO: consider asking the auth manager for account info, including tier.
@@ -0,0 +1,525 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package dex
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"decred.org/dcrdex/dex"
+	"decred.org/dcrdex/dex/candles"
+	"decred.org/dcrdex/dex/msgjson"
+	"decred.org/dcrdex/dex/order"
+	"decred.org/dcrdex/server/account"
+	"decred.org/dcrdex/server/apidata"
+	"decred.org/dcrdex/server/asset"
+	"decred.org/dcrdex/server/coinlock"
+	"decred.org/dcrdex/server/comms"
+	"decred.org/dcrdex/server/db"
+	"decred.org/dcrdex/server/market"
+	"decred.org/dcrdex/server/matcher"
+	"decred.org/dcrdex/server/swap"
+)
+
+func TestMain(m *testing.M) {
+	comms.UseLogger(dex.Disabled)
+	os.Exit(m.Run())
+}
+
+// tStorage is a DEXArchivist that stores the book orders and markets. The
+// methods that are not used by the tests panic.
+type tStorage struct {
+	db.DEXArchivist
+
+	mtx          sync.Mutex
+	addMarketErr error
+	markets      map[string]bool
+	bookOrders   []*order.LimitOrder
+	flushed      []order.OrderID
+}
+
+func newTStorage() *tStorage {
+	return &tStorage{markets: make(map[string]bool)}
+}
+
+func (s *tStorage) AddMarket(mkt *dex.MarketInfo) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.addMarketErr != nil {
+		return s.addMarketErr
+	}
+	s.markets[mkt.Name] = true
+	return nil
+}
+
+func (s *tStorage) RemoveMarket(mkt *dex.MarketInfo) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	delete(s.markets, mkt.Name)
+}
+
+func (s *tStorage) hasMarket(name string) bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.markets[name]
+}
+
+func (s *tStorage) Close() error           { return nil }
+func (s *tStorage) LastErr() error         { return nil }
+func (s *tStorage) Fatal() <-chan struct{} { return nil }
+
+func (s *tStorage) BookOrders(base, quote uint32) ([]*order.LimitOrder, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return append([]*order.LimitOrder(nil), s.bookOrders...), nil
+}
+
+func (s *tStorage) EpochOrders(base, quote uint32) ([]order.Order, error) { return nil, nil }
+
+func (s *tStorage) FlushBook(base, quote uint32) (sells, buys []order.OrderID, err error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for _, lo := range s.bookOrders {
+		if lo.Sell {
+			sells = append(sells, lo.ID())
+		} else {
+			buys = append(buys, lo.ID())
+		}
+		s.flushed = append(s.flushed, lo.ID())
+	}
+	s.bookOrders = nil
+	return
+}
+
+func (s *tStorage) flushedOrders() []order.OrderID {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return append([]order.OrderID(nil), s.flushed...)
+}
+
+func (s *tStorage) MarketMatches(base, quote uint32) ([]*db.MatchDataWithCoins, error) {
+	return nil, nil
+}
+
+func (s *tStorage) InsertEpoch(*db.EpochResults) error { return nil }
+func (s *tStorage) LastEpochRate(base, quote uint32) (uint64, error) {
+	return 0, nil
+}
+func (s *tStorage) LoadEpochStats(uint32, uint32, []*candles.Cache) error { return nil }
+func (s *tStorage) LastCandleEndStamp(uint32, uint32, uint64) (uint64, error) {
+	return 0, nil
+}
+func (s *tStorage) InsertCandles(uint32, uint32, uint64, []*candles.Candle) error { return nil }
+
+// tAuth satisfies the AuthManager interfaces of the market and swap packages.
+type tAuth struct{}
+
+func (a *tAuth) Route(string, func(account.AccountID, *msgjson.Message) *msgjson.Error) {}
+func (a *tAuth) Auth(account.AccountID, []byte, []byte) error                           { return nil }
+func (a *tAuth) AcctStatus(account.AccountID) (bool, int64)                             { return true, 1 }
+func (a *tAuth) Sign(...msgjson.Signable)                                               {}
+func (a *tAuth) Send(account.AccountID, *msgjson.Message) error                         { return nil }
+func (a *tAuth) Request(account.AccountID, *msgjson.Message, func(comms.Link, *msgjson.Message)) error {
+	return nil
+}
+func (a *tAuth) RequestWithTimeout(account.AccountID, *msgjson.Message, func(comms.Link, *msgjson.Message), time.Duration, func()) error {
+	return nil
+}
+func (a *tAuth) PreimageSuccess(account.AccountID, time.Time, order.OrderID)                    {}
+func (a *tAuth) MissedPreimage(account.AccountID, time.Time, order.OrderID)                     {}
+func (a *tAuth) RecordCancel(account.AccountID, order.OrderID, order.OrderID, int32, time.Time) {}
+func (a *tAuth) RecordCompletedOrder(account.AccountID, order.OrderID, time.Time)               {}
+func (a *tAuth) UserReputation(account.AccountID) (int64, int32, int32, error) {
+	return 1, 0, 1, nil
+}
+func (a *tAuth) SwapSuccess(account.AccountID, db.MarketMatchID, uint64, time.Time) {}
+func (a *tAuth) Inaction(account.AccountID, db.Outcome, db.MarketMatchID, uint64, time.Time, order.OrderID) {
+}
+
+// tBackend is a UTXO-based asset backend. The methods that are not used by
+// the tests panic.
+type tBackend struct {
+	asset.Backend
+
+	mtx        sync.Mutex
+	unspentErr error
+}
+
+func (b *tBackend) VerifyUnspentCoin(context.Context, []byte) error {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.unspentErr
+}
+
+func (b *tBackend) FundingCoin(context.Context, []byte, []byte) (asset.FundingCoin, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (b *tBackend) ValidateOrderFunding(swapVal, valSum, inputCount, inputsSize, maxSwaps uint64, nfo *dex.Asset) bool {
+	return true
+}
+
+func (b *tBackend) Synced() (bool, error) { return true, nil }
+
+func (b *tBackend) setUnspentErr(err error) {
+	b.mtx.Lock()
+	b.unspentErr = err
+	b.mtx.Unlock()
+}
+
+type tFeeFetcher struct {
+	maxFeeRate uint64
+}
+
+func (f *tFeeFetcher) FeeRate(context.Context) uint64     { return 10 }
+func (f *tFeeFetcher) SwapFeeRate(context.Context) uint64 { return 10 }
+func (f *tFeeFetcher) LastRate() uint64                   { return 10 }
+func (f *tFeeFetcher) MaxFeeRate() uint64                 { return f.maxFeeRate }
+
+type tFeeSource struct{}
+
+func (tFeeSource) LastRate(uint32) uint64 { return 10 }
+
+const (
+	tBaseID, tQuoteID = 42, 0
+	tLotSize          = 1e8
+	tRateStep         = 100
+	tEpochDur         = 100 // ms
+)
+
+// tDEX is a DEX with the components that AddMarket and RetireMarket use.
+type tDEX struct {
+	*DEX
+	storage *tStorage
+	backend *tBackend
+}
+
+func newTDEX(t *testing.T, existingMarkets map[string]market.MarketTunnel) *tDEX {
+	t.Helper()
+
+	storage := newTStorage()
+	backend := &tBackend{}
+	auth := &tAuth{}
+	assets := make(map[uint32]*swap.SwapperAsset, 2)
+	backedAssets := make(map[uint32]*asset.BackedAsset, 2)
+	for _, id := range []uint32{tBaseID, tQuoteID} {
+		ba := &asset.BackedAsset{
+			Asset: dex.Asset{
+				ID:         id,
+				Symbol:     dex.BipIDSymbol(id),
+				MaxFeeRate: 10,
+				SwapConf:   1,
+			},
+			Backend: backend,
+		}
+		backedAssets[id] = ba
+		assets[id] = &swap.SwapperAsset{
+			BackedAsset: ba,
+			Locker:      coinlock.NewMasterCoinLocker().Swap(),
+		}
+	}
+
+	swapper, err := swap.NewSwapper(&swap.Config{
+		Assets:           assets,
+		Storage:          storage,
+		AuthManager:      auth,
+		BroadcastTimeout: time.Minute,
+		TxWaitExpiration: time.Minute,
+		LockTimeTaker:    dex.LockTimeTaker(dex.Simnet),
+		LockTimeMaker:    dex.LockTimeMaker(dex.Simnet),
+		NoResume:         true,
+		SwapDone:         func(order.Order, *order.Match, bool) {},
+	})
+	if err != nil {
+		t.Fatalf("NewSwapper error: %v", err)
+	}
+	dexBalancer, err := market.NewDEXBalancer(nil, backedAssets, swapper)
+	if err != nil {
+		t.Fatalf("NewDEXBalancer error: %v", err)
+	}
+	server, err := comms.NewServer(&comms.RPCConfig{
+		ListenAddrs: []string{"127.0.0.1:0"},
+		NoTLS:       true,
+	})
+	if err != nil {
+		t.Fatalf("NewServer error: %v", err)
+	}
+	dataAPI := apidata.NewDataAPI(storage, func(string, comms.HTTPHandler) {})
+
+	dm := &DEX{
+		network:     dex.Simnet,
+		assets:      assets,
+		storage:     storage,
+		swapper:     swapper,
+		dexBalancer: dexBalancer,
+		dataAPI:     dataAPI,
+		server:      server,
+		events:      newEventFeed(),
+		markets:     make(map[string]*market.Market),
+		retiring:    make(map[string]bool),
+		quit:        make(chan struct{}),
+		configResp:  &configResponse{configMsg: &msgjson.ConfigResult{}},
+	}
+	dm.ctx, dm.cancel = context.WithCancel(context.Background())
+	dm.orderRouter = market.NewOrderRouter(&market.OrderRouterConfig{
+		AuthManager:  auth,
+		Assets:       backedAssets,
+		Markets:      existingMarkets,
+		FeeSource:    tFeeSource{},
+		DEXBalancer:  dexBalancer,
+		MatchSwapper: swapper,
+	})
+	dm.bookRouter = market.NewBookRouter(nil, tFeeSource{}, func(string, comms.MsgHandler) {})
+	dm.newMarket = func(mktInf *dex.MarketInfo) (*market.Market, error) {
+		mkt, err := market.NewMarket(&market.Config{
+			MarketInfo:      mktInf,
+			Storage:         storage,
+			Swapper:         swapper,
+			AuthManager:     auth,
+			FeeFetcherBase:  &tFeeFetcher{10},
+			CoinLockerBase:  coinlock.NewMasterCoinLocker().Book(),
+			FeeFetcherQuote: &tFeeFetcher{10},
+			CoinLockerQuote: coinlock.NewMasterCoinLocker().Book(),
+			DataCollector:   dataAPI,
+			Balancer:        dexBalancer,
+			CheckParcelLimit: func(account.AccountID, market.MarketParcelCalculator) bool {
+				return true
+			},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("NewMarket failed: %w", err)
+		}
+		if err := dataAPI.AddMarketSource(mkt); err != nil {
+			return nil, fmt.Errorf("DataSource.AddMarketSource: %w", err)
+		}
+		return mkt, nil
+	}
+	t.Cleanup(dm.Stop)
+
+	return &tDEX{DEX: dm, storage: storage, backend: backend}
+}
+
+func tMarketInfo(t *testing.T) *dex.MarketInfo {
+	t.Helper()
+	mktInf, err := dex.NewMarketInfo(tBaseID, tQuoteID, tLotSize, tRateStep, tEpochDur, 1.5)
+	if err != nil {
+		t.Fatalf("NewMarketInfo error: %v", err)
+	}
+	return mktInf
+}
+
+func tBookOrder(sell bool) *order.LimitOrder {
+	return &order.LimitOrder{
+		P: order.Prefix{
+			AccountID:  account.AccountID{0x01},
+			BaseAsset:  tBaseID,
+			QuoteAsset: tQuoteID,
+			OrderType:  order.LimitOrderType,
+			ClientTime: time.Now(),
+			ServerTime: time.Now(),
+		},
+		T: order.Trade{
+			Coins:    []order.CoinID{{0x02}},
+			Sell:     sell,
+			Quantity: tLotSize,
+			Address:  "address",
+		},
+		Rate:  tRateStep * 1000,
+		Force: order.StandingTiF,
+	}
+}
+
+// waitFor waits for the condition to be met, checking every epoch.
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	timeout := time.After(10 * time.Second)
+	for !cond() {
+		select {
+		case <-timeout:
+			t.Fatalf("timed out")
+		case <-time.After(tEpochDur * time.Millisecond):
+		}
+	}
+}
+
+// checkNotRegistered checks that no part of a market is registered.
+func (dm *tDEX) checkNotRegistered(t *testing.T, name string) {
+	t.Helper()
+	if dm.market(name) != nil {
+		t.Fatalf("market %s is registered", name)
+	}
+	if dm.storage.hasMarket(name) {
+		t.Fatalf("market %s is in storage", name)
+	}
+	if _, err := dm.bookRouter.Book(name); err == nil || !strings.Contains(err.Error(), "unknown") {
+		t.Fatalf("market %s is in the book router", name)
+	}
+	if _, err := dm.dataAPI.ReportEpoch(tBaseID, tQuoteID, 1, &matcher.MatchCycleStats{}); err == nil {
+		t.Fatalf("market %s is a data API source", name)
+	}
+	dm.marketsMtx.RLock()
+	ssw := dm.marketSubsys(name)
+	dm.marketsMtx.RUnlock()
+	if ssw != nil {
+		t.Fatalf("market %s has a subsystem", name)
+	}
+	dm.configRespMtx.RLock()
+	defer dm.configRespMtx.RUnlock()
+	for _, mkt := range dm.configResp.configMsg.Markets {
+		if mkt.Name == name {
+			t.Fatalf("market %s is in the config", name)
+		}
+	}
+}
+
+func TestAddMarket(t *testing.T) {
+	name := tMarketInfo(t).Name
+
+	// Invalid markets are refused before anything is registered.
+	dm := newTDEX(t, nil)
+	mktInf := tMarketInfo(t)
+	mktInf.LotSize = 0
+	if _, _, err := dm.AddMarket(mktInf); err == nil {
+		t.Fatalf("no error for zero lot size")
+	}
+	dm.checkNotRegistered(t, name)
+
+	// Storage failure.
+	dm.storage.addMarketErr = errors.New("test error")
+	if _, _, err := dm.AddMarket(tMarketInfo(t)); err == nil {
+		t.Fatalf("no error for storage failure")
+	}
+	dm.checkNotRegistered(t, name)
+	dm.storage.addMarketErr = nil
+
+	// Asset backend failure while loading the book.
+	dm.storage.bookOrders = []*order.LimitOrder{tBookOrder(true)}
+	dm.backend.setUnspentErr(errors.New("test error"))
+	if _, _, err := dm.AddMarket(tMarketInfo(t)); err == nil {
+		t.Fatalf("no error for backend failure")
+	}
+	dm.checkNotRegistered(t, name)
+	dm.backend.setUnspentErr(nil)
+	dm.storage.bookOrders = nil
+
+	// Book router failure.
+	otherMkt, err := dm.newMarket(tMarketInfo(t))
+	if err != nil {
+		t.Fatalf("error creating market: %v", err)
+	}
+	dm.dataAPI.RemoveMarketSource(name)
+	if err := dm.bookRouter.AddBook(name, otherMkt); err != nil {
+		t.Fatalf("AddBook error: %v", err)
+	}
+	if _, _, err := dm.AddMarket(tMarketInfo(t)); err == nil || !strings.Contains(err.Error(), "book") {
+		t.Fatalf("no book router error, err = %v", err)
+	}
+	// The book that was already registered is not removed.
+	if _, err := dm.bookRouter.Book(name); err != nil && strings.Contains(err.Error(), "unknown") {
+		t.Fatalf("existing book removed")
+	}
+	dm.bookRouter.RemoveBook(name)
+	dm.checkNotRegistered(t, name)
+
+	// Order router failure. The market is stopped.
+	dm = newTDEX(t, map[string]market.MarketTunnel{name: nil})
+	if _, _, err := dm.AddMarket(tMarketInfo(t)); err == nil || !strings.Contains(err.Error(), "order router") {
+		t.Fatalf("no order router error, err = %v", err)
+	}
+	dm.checkNotRegistered(t, name)
+
+	// Success.
+	dm = newTDEX(t, nil)
+	startEpoch, _, err := dm.AddMarket(tMarketInfo(t))
+	if err != nil {
+		t.Fatalf("AddMarket error: %v", err)
+	}
+	waitFor(t, func() bool {
+		_, running := dm.MarketRunning(name)
+		return running
+	})
+	if !dm.storage.hasMarket(name) {
+		t.Fatalf("market not in storage")
+	}
+	if _, err := dm.bookRouter.Book(name); err != nil && strings.Contains(err.Error(), "unknown") {
+		t.Fatalf("market not in the book router")
+	}
+	dm.configRespMtx.RLock()
+	mkts := dm.configResp.configMsg.Markets
+	dm.configRespMtx.RUnlock()
+	if len(mkts) != 1 || mkts[0].Name != name || mkts[0].MarketStatus.StartEpoch != uint64(startEpoch) {
+		t.Fatalf("market not in the config")
+	}
+	if _, _, err := dm.AddMarket(tMarketInfo(t)); err == nil {
+		t.Fatalf("no error adding the market twice")
+	}
+}
+
+func TestRetireMarket(t *testing.T) {
+	defer func(d time.Duration) { retireCheckInterval = d }(retireCheckInterval)
+	retireCheckInterval = tEpochDur * time.Millisecond
+
+	dm := newTDEX(t, nil)
+	sell, buy := tBookOrder(true), tBookOrder(false)
+	dm.storage.bookOrders = []*order.LimitOrder{sell, buy}
+	name := tMarketInfo(t).Name
+	if _, _, err := dm.AddMarket(tMarketInfo(t)); err != nil {
+		t.Fatalf("AddMarket error: %v", err)
+	}
+	if _, buys, sells := dm.market(name).Book(); len(buys) != 1 || len(sells) != 1 {
+		t.Fatalf("orders not booked")
+	}
+
+	if _, err := dm.RetireMarket("unknown"); err == nil {
+		t.Fatalf("no error retiring an unknown market")
+	}
+	if _, err := dm.RetireMarket(name); err != nil {
+		t.Fatalf("RetireMarket error: %v", err)
+	}
+	if _, err := dm.RetireMarket(name); err == nil {
+		t.Fatalf("no error retiring a market twice")
+	}
+
+	// Wait for the market to be suspended and removed.
+	waitFor(t, func() bool { return dm.market(name) == nil })
+
+	// The booked orders are canceled.
+	flushed := dm.storage.flushedOrders()
+	if len(flushed) != 2 {
+		t.Fatalf("expected 2 orders flushed, got %d", len(flushed))
+	}
+	for _, oid := range []order.OrderID{sell.ID(), buy.ID()} {
+		if flushed[0] != oid && flushed[1] != oid {
+			t.Fatalf("order %s not flushed", oid)
+		}
+	}
+	// The market is no longer routed and has no swaps.
+	if suspEpoch := dm.orderRouter.SuspendMarket(name, time.Time{}, false); suspEpoch != nil {
+		t.Fatalf("market still in the order router")
+	}
+	if n := dm.swapper.MarketSwapCount(tBaseID, tQuoteID); n != 0 {
+		t.Fatalf("%d swaps for retired market", n)
+	}
+	// The market's storage is kept so that its history is available.
+	dm.storage.RemoveMarket(tMarketInfo(t))
+	dm.checkNotRegistered(t, name)
+	dm.marketsMtx.RLock()
+	retiring := dm.retiring[name]
+	dm.marketsMtx.RUnlock()
+	if retiring {
+		t.Fatalf("market still retiring")
+	}
+
+	// The market can be added again.
+	dm.storage.bookOrders = nil
+	if _, _, err := dm.AddMarket(tMarketInfo(t)); err != nil {
+		t.Fatalf("error adding the market again: %v", err)
+	}
+}
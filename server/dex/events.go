@@ -0,0 +1,199 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package dex
+
+import (
+	"sync"
+	"time"
+
+	"decred.org/dcrdex/dex"
+	"decred.org/dcrdex/dex/order"
+	"decred.org/dcrdex/server/account"
+	"decred.org/dcrdex/server/market"
+)
+
+// eventBufferSize is the size of each event subscriber's channel. Events are
+// dropped for a subscriber that falls this far behind.
+const eventBufferSize = 256
+
+// EventType identifies the kind of an Event.
+type EventType string
+
+const (
+	EventOrderBooked   EventType = "orderbooked"
+	EventOrderUnbooked EventType = "orderunbooked"
+	EventMatch         EventType = "match"
+	EventSwapStep      EventType = "swapstep"
+	EventMatchRevoked  EventType = "matchrevoked"
+	EventPenalty       EventType = "penalty"
+	EventTierChange    EventType = "tierchange"
+	EventSuspend       EventType = "suspend"
+	EventResume        EventType = "resume"
+)
+
+// Event is a real-time event for server operators. Only the fields relevant
+// to the event Type are set.
+type Event struct {
+	Type  EventType `json:"type"`
+	Stamp uint64    `json:"stamp"`
+	// Market is set for order, match, and market events.
+	Market string `json:"market,omitempty"`
+	// Accounts are the users involved. For match events, these are the maker
+	// and taker, in that order.
+	Accounts []account.AccountID `json:"accounts,omitempty"`
+	// OrderIDs are the order IDs involved. For match events, these are the
+	// maker's and taker's orders, in that order.
+	OrderIDs []order.OrderID `json:"orderids,omitempty"`
+	MatchID  *order.MatchID  `json:"matchid,omitempty"`
+	Side     string          `json:"side,omitempty"`
+	Quantity uint64          `json:"qty,omitempty"`
+	Rate     uint64          `json:"rate,omitempty"`
+	// Status is the match status for match, swap step, and revocation events.
+	Status string `json:"status,omitempty"`
+	// Epoch is the order's epoch for book events, the final epoch of a
+	// suspend, or the first epoch of a resume.
+	Epoch       int64  `json:"epoch,omitempty"`
+	PersistBook bool   `json:"persistbook,omitempty"`
+	Rule        string `json:"rule,omitempty"`
+	Tier        *int64 `json:"tier,omitempty"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+// Involves checks whether the user is one of the Event's Accounts.
+func (ev *Event) Involves(user account.AccountID) bool {
+	for _, acct := range ev.Accounts {
+		if acct == user {
+			return true
+		}
+	}
+	return false
+}
+
+// eventFeed distributes Events to subscribers without blocking the sender.
+type eventFeed struct {
+	mtx  sync.RWMutex
+	subs map[chan *Event]struct{}
+}
+
+func newEventFeed() *eventFeed {
+	return &eventFeed{
+		subs: make(map[chan *Event]struct{}),
+	}
+}
+
+func (f *eventFeed) send(ev *Event) {
+	ev.Stamp = uint64(time.Now().UnixMilli())
+	f.mtx.RLock()
+	defer f.mtx.RUnlock()
+	for c := range f.subs {
+		select {
+		case c <- ev:
+		default:
+			log.Warnf("Event subscriber is blocking. Dropping %s event.", ev.Type)
+		}
+	}
+}
+
+func (f *eventFeed) subscribe() (<-chan *Event, func()) {
+	c := make(chan *Event, eventBufferSize)
+	f.mtx.Lock()
+	f.subs[c] = struct{}{}
+	f.mtx.Unlock()
+	var once sync.Once
+	return c, func() {
+		once.Do(func() {
+			f.mtx.Lock()
+			delete(f.subs, c)
+			close(c)
+			f.mtx.Unlock()
+		})
+	}
+}
+
+// bookEvent sends an Event for a market.BookEvent.
+func (f *eventFeed) bookEvent(mktName string, be *market.BookEvent) {
+	ev := &Event{
+		Market:      mktName,
+		Epoch:       be.EpochIdx,
+		PersistBook: be.PersistBook,
+	}
+	switch be.Action {
+	case "book":
+		ev.Type = EventOrderBooked
+	case "unbook":
+		ev.Type = EventOrderUnbooked
+	case "suspend":
+		ev.Type = EventSuspend
+	case "resume":
+		ev.Type = EventResume
+	default:
+		return
+	}
+	if be.Order != nil {
+		ev.Accounts = []account.AccountID{be.Order.User()}
+		ev.OrderIDs = []order.OrderID{be.Order.ID()}
+		if lo, ok := be.Order.(*order.LimitOrder); ok {
+			ev.Side = "buy"
+			if lo.Sell {
+				ev.Side = "sell"
+			}
+			ev.Quantity = lo.Remaining()
+			ev.Rate = lo.Rate
+		}
+	}
+	f.send(ev)
+}
+
+// matchEvent sends an Event for a new match, a swap step, or a revocation.
+func (f *eventFeed) matchEvent(match *order.Match, status order.MatchStatus, revoked bool) {
+	mktName, err := dex.MarketName(match.Maker.Base(), match.Maker.Quote())
+	if err != nil {
+		log.Errorf("bad market for match %v: %v", match.ID(), err)
+		return
+	}
+	mid := match.ID()
+	ev := &Event{
+		Type:     EventSwapStep,
+		Market:   mktName,
+		Accounts: []account.AccountID{match.Maker.User(), match.Taker.User()},
+		OrderIDs: []order.OrderID{match.Maker.ID(), match.Taker.ID()},
+		MatchID:  &mid,
+		Quantity: match.Quantity,
+		Rate:     match.Rate,
+		Status:   status.String(),
+	}
+	switch {
+	case revoked:
+		ev.Type = EventMatchRevoked
+	case status == order.NewlyMatched:
+		ev.Type = EventMatch
+	}
+	f.send(ev)
+}
+
+// penaltyEvent sends an Event for a user penalty.
+func (f *eventFeed) penaltyEvent(user account.AccountID, rule account.Rule) {
+	f.send(&Event{
+		Type:     EventPenalty,
+		Accounts: []account.AccountID{user},
+		Rule:     rule.String(),
+	})
+}
+
+// tierChangeEvent sends an Event for a user tier change.
+func (f *eventFeed) tierChangeEvent(user account.AccountID, tier int64, reason string) {
+	f.send(&Event{
+		Type:     EventTierChange,
+		Accounts: []account.AccountID{user},
+		Tier:     &tier,
+		Reason:   reason,
+	})
+}
+
+// SubscribeEvents creates a subscription to the DEX's real-time Events. The
+// returned function ends the subscription and closes the channel. A subscriber
+// that does not keep up with the Events will miss some.
+func (dm *DEX) SubscribeEvents() (<-chan *Event, func()) {
+	return dm.events.subscribe()
+}
@@ -0,0 +1,106 @@
+// This code is available on the terms of the project LICENSE.md file,
+// also available online at https://blueoakcouncil.org/license/1.0.0.
+
+package dex
+
+import (
+	"sync"
+	"time"
+
+	"decred.org/dcrdex/dex/order"
+	"decred.org/dcrdex/server/account"
+	"decred.org/dcrdex/server/asset"
+	"decred.org/dcrdex/server/market"
+)
+
+// MarketMetrics are the monitoring metrics for a market.
+type MarketMetrics struct {
+	*market.Metrics
+	Name    string
+	Base    uint32
+	Quote   uint32
+	Running bool
+}
+
+// AssetMetrics are the monitoring metrics for an asset backend.
+type AssetMetrics struct {
+	ID     uint32
+	Symbol string
+	// Synced is the result of the backend's Synced method, and SyncErr is
+	// true if that call failed.
+	Synced  bool
+	SyncErr bool
+	// RPCLatency is the duration of the Synced call, which requests chain
+	// info from the node.
+	RPCLatency time.Duration
+	// Height is the backend's best block height. Height is zero if the
+	// backend is not an asset.TipHeighter or the height could not be
+	// retrieved.
+	Height uint64
+}
+
+// Metrics is a snapshot of the DEX's monitoring metrics.
+type Metrics struct {
+	Markets []*MarketMetrics
+	Assets  []*AssetMetrics
+	// Swaps is the number of active swaps in each status.
+	Swaps map[order.MatchStatus]int
+	// Clients is the number of connected websocket clients.
+	Clients uint64
+	// Penalties is the number of penalties issued since startup for each
+	// rule.
+	Penalties map[account.Rule]uint64
+}
+
+// Metrics collects a snapshot of the DEX's monitoring metrics. The asset
+// backends are queried concurrently, so the RPC latencies are measured
+// independently.
+func (dm *DEX) Metrics() *Metrics {
+	mkts := dm.marketsCopy()
+	metrics := &Metrics{
+		Markets:   make([]*MarketMetrics, 0, len(mkts)),
+		Assets:    make([]*AssetMetrics, 0, len(dm.assets)),
+		Swaps:     dm.swapper.ActiveSwapCounts(),
+		Clients:   dm.server.ClientCount(),
+		Penalties: dm.authMgr.PenaltyCounts(),
+	}
+
+	for name, mkt := range mkts {
+		metrics.Markets = append(metrics.Markets, &MarketMetrics{
+			Metrics: mkt.Metrics(),
+			Name:    name,
+			Base:    mkt.Base(),
+			Quote:   mkt.Quote(),
+			Running: mkt.Running(),
+		})
+	}
+
+	var wg sync.WaitGroup
+	for assetID, a := range dm.assets {
+		am := &AssetMetrics{
+			ID:     assetID,
+			Symbol: a.Symbol,
+		}
+		metrics.Assets = append(metrics.Assets, am)
+		wg.Add(1)
+		go func(be asset.Backend) {
+			defer wg.Done()
+			start := time.Now()
+			synced, err := be.Synced()
+			am.RPCLatency = time.Since(start)
+			if err != nil {
+				log.Warnf("Error checking %s backend sync status for metrics: %v", am.Symbol, err)
+				am.SyncErr = true
+			}
+			am.Synced = synced
+			if th, ok := be.(asset.TipHeighter); ok {
+				if am.Height, err = th.TipHeight(); err != nil {
+					log.Warnf("Error getting %s tip height for metrics: %v", am.Symbol, err)
+				}
+			}
+		}(a.Backend)
+	}
+	wg.Wait()
+
+	return metrics
+}
@@ -5,6 +5,7 @@ package market
 
 import (
 	"fmt"
+	"sync"
 
 	"decred.org/dcrdex/dex"
 	"decred.org/dcrdex/dex/calc"
@@ -41,6 +42,8 @@ type MatchNegotiator interface {
 type DEXBalancer struct {
 	assets          map[uint32]*backedBalancer
 	matchNegotiator MatchNegotiator
+	// marketsMtx guards the markets of the backedBalancers.
+	marketsMtx sync.RWMutex
 }
 
 // NewDEXBalancer is a constructor for a DEXBalancer. Provided assets will
@@ -211,9 +214,13 @@ func (b *DEXBalancer) CheckBalance(acctAddr string, assetID, redeemAssetID uint3
 
 		isToken := ba.feeBalancer != nil
 
+		b.marketsMtx.RLock()
+		markets := ba.markets
+		b.marketsMtx.RUnlock()
+
 		var l uint64
 		var r int
-		for _, mt := range ba.markets {
+		for _, mt := range markets {
 			newQty, newLots, newRedeems := mt.AccountPending(acctAddr, assetID)
 			l += newLots
 			q += newQty
@@ -272,6 +279,40 @@ func (b *DEXBalancer) CheckBalance(acctAddr string, assetID, redeemAssetID uint3
 	return bal >= reqFunds
 }
 
+// AddMarket adds a market whose pending orders are counted in balance checks
+// for the market's account-based assets.
+func (b *DEXBalancer) AddMarket(mkt PendingAccounter) {
+	b.marketsMtx.Lock()
+	defer b.marketsMtx.Unlock()
+	for _, assetID := range []uint32{mkt.Base(), mkt.Quote()} {
+		if ba, found := b.assets[assetID]; found {
+			// Copy so that a slice read by CheckBalance is never modified.
+			markets := make([]PendingAccounter, 0, len(ba.markets)+1)
+			ba.markets = append(append(markets, ba.markets...), mkt)
+		}
+	}
+}
+
+// RemoveMarket removes a market that was added with AddMarket or provided to
+// the constructor.
+func (b *DEXBalancer) RemoveMarket(mkt PendingAccounter) {
+	b.marketsMtx.Lock()
+	defer b.marketsMtx.Unlock()
+	for _, assetID := range []uint32{mkt.Base(), mkt.Quote()} {
+		ba, found := b.assets[assetID]
+		if !found {
+			continue
+		}
+		markets := make([]PendingAccounter, 0, len(ba.markets))
+		for _, m := range ba.markets {
+			if m != mkt {
+				markets = append(markets, m)
+			}
+		}
+		ba.markets = markets
+	}
+}
+
 // backedBalancer is similar to a BackedAsset, but with the Backends already
 // cast to AccountBalancer.
 type backedBalancer struct {
@@ -279,5 +320,5 @@ type backedBalancer struct {
 	assetInfo   *dex.Asset
 	feeBalancer *backedBalancer       // feeBalancer != nil implies that this is a token
 	feeFamily   map[uint32]*dex.Asset // Excluding self
-	markets     []PendingAccounter
+	markets     []PendingAccounter    // guarded by DEXBalancer.marketsMtx
 }
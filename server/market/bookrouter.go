@@ -77,6 +77,8 @@ func (bua updateAction) String() string {
 		return "matchProof"
 	case suspendAction:
 		return "suspend"
+	case resumeAction:
+		return "resume"
 	default:
 		return ""
 	}
@@ -203,6 +205,22 @@ type msgBook struct {
 	source        BookSource
 	baseID        uint32
 	quoteID       uint32
+	// quit stops the book's monitoring goroutine. quit is set when the
+	// goroutine is started, and is guarded by the BookRouter's booksMtx.
+	quit context.CancelFunc
+}
+
+func newMsgBook(name string, src BookSource) *msgBook {
+	return &msgBook{
+		name:   name,
+		orders: make(map[order.OrderID]*msgjson.BookOrderNote),
+		subs: &subscribers{
+			conns: make(map[uint64]comms.Link),
+		},
+		source:  src,
+		baseID:  src.Base(),
+		quoteID: src.Quote(),
+	}
 }
 
 func (book *msgBook) setEpoch(idx int64) {
@@ -283,9 +301,15 @@ func (book *msgBook) addBulkOrders(epoch int64, orderSets ...[]*order.LimitOrder
 // of subscribers, and maintaining an intermediate copy of the orderbook in
 // message payload format for quick, full-book syncing.
 type BookRouter struct {
-	books     map[string]*msgBook
 	feeSource FeeSource
 
+	// booksMtx guards books and ctx. ctx is set by Run, and is used to start
+	// the monitoring goroutines for books added while running.
+	booksMtx sync.RWMutex
+	books    map[string]*msgBook
+	ctx      context.Context
+	wg       sync.WaitGroup
+
 	priceFeeders *subscribers
 	spotsMtx     sync.RWMutex
 	spots        map[string]*msgjson.Spot
@@ -305,18 +329,7 @@ func NewBookRouter(sources map[string]BookSource, feeSource FeeSource, route fun
 		spots: make(map[string]*msgjson.Spot),
 	}
 	for mkt, src := range sources {
-		subs := &subscribers{
-			conns: make(map[uint64]comms.Link),
-		}
-		book := &msgBook{
-			name:    mkt,
-			orders:  make(map[order.OrderID]*msgjson.BookOrderNote),
-			subs:    subs,
-			source:  src,
-			baseID:  src.Base(),
-			quoteID: src.Quote(),
-		}
-		router.books[mkt] = book
+		router.books[mkt] = newMsgBook(mkt, src)
 	}
 	route(msgjson.OrderBookRoute, router.handleOrderBook)
 	route(msgjson.UnsubOrderBookRoute, router.handleUnsubOrderBook)
@@ -328,15 +341,64 @@ func NewBookRouter(sources map[string]BookSource, feeSource FeeSource, route fun
 
 // Run implements dex.Runner, and is blocking.
 func (r *BookRouter) Run(ctx context.Context) {
-	var wg sync.WaitGroup
+	r.booksMtx.Lock()
+	r.ctx = ctx
 	for _, b := range r.books {
-		wg.Add(1)
-		go func(b *msgBook) {
-			r.runBook(ctx, b)
-			wg.Done()
-		}(b)
+		r.startBook(b)
+	}
+	r.booksMtx.Unlock()
+	<-ctx.Done()
+	r.wg.Wait()
+}
+
+// startBook starts the monitoring goroutine for the book. The booksMtx MUST be
+// locked, and r.ctx MUST be set.
+func (r *BookRouter) startBook(b *msgBook) {
+	var ctx context.Context
+	ctx, b.quit = context.WithCancel(r.ctx)
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.runBook(ctx, b)
+	}()
+}
+
+// AddBook adds the order book for a new market. If the BookRouter is running,
+// the book's monitoring goroutine is started immediately.
+func (r *BookRouter) AddBook(mktName string, src BookSource) error {
+	r.booksMtx.Lock()
+	defer r.booksMtx.Unlock()
+	if _, found := r.books[mktName]; found {
+		return fmt.Errorf("book for market %s already exists", mktName)
 	}
-	wg.Wait()
+	b := newMsgBook(mktName, src)
+	r.books[mktName] = b
+	if r.ctx != nil {
+		r.startBook(b)
+	}
+	return nil
+}
+
+// RemoveBook stops monitoring the order book for a retired market and removes
+// it. The book's subscribers are dropped.
+func (r *BookRouter) RemoveBook(mktName string) {
+	r.booksMtx.Lock()
+	defer r.booksMtx.Unlock()
+	b, found := r.books[mktName]
+	if !found {
+		return
+	}
+	delete(r.books, mktName)
+	if b.quit != nil {
+		b.quit()
+	}
+}
+
+// book retrieves the book for the market, or nil if the market is unknown.
+func (r *BookRouter) book(mktName string) *msgBook {
+	r.booksMtx.RLock()
+	defer r.booksMtx.RUnlock()
+	return r.books[mktName]
 }
 
 // runBook is a monitoring loop for an order book.
@@ -549,7 +611,7 @@ out:
 
 // Book creates a copy of the book as a *msgjson.OrderBook.
 func (r *BookRouter) Book(mktName string) (*msgjson.OrderBook, error) {
-	book := r.books[mktName]
+	book := r.book(mktName)
 	if book == nil {
 		return nil, fmt.Errorf("market %s unknown", mktName)
 	}
@@ -627,8 +689,8 @@ func (r *BookRouter) handleOrderBook(conn comms.Link, msg *msgjson.Message) *msg
 			Message: "market name error: " + err.Error(),
 		}
 	}
-	book, found := r.books[mkt]
-	if !found {
+	book := r.book(mkt)
+	if book == nil {
 		return &msgjson.Error{
 			Code:    msgjson.UnknownMarket,
 			Message: "unknown market",
@@ -651,7 +713,7 @@ func (r *BookRouter) handleUnsubOrderBook(conn comms.Link, msg *msgjson.Message)
 			Message: "error parsing unsub_orderbook request",
 		}
 	}
-	book := r.books[unsub.MarketID]
+	book := r.book(unsub.MarketID)
 	if book == nil {
 		return &msgjson.Error{
 			Code:    msgjson.UnknownMarket,
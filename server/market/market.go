@@ -98,7 +98,29 @@ type Config struct {
 	Balancer         Balancer
 	CheckParcelLimit func(user account.AccountID, calcParcels MarketParcelCalculator) bool
 	MinimumRate      uint64
-}
+	// BookEvent, if set, is called for each order booked or unbooked and
+	// each suspend or resume of the market. BookEvent must not block.
+	BookEvent func(*BookEvent)
+	// ParamsChanged, if set, is called when a scheduled ParamChange takes
+	// effect for new orders.
+	ParamsChanged func(*ParamChange)
+}
+
+// ParamChange is a scheduled change to a market's lot size and rate step.
+type ParamChange struct {
+	// Epoch is the index of the first epoch with the new lot size and rate
+	// step, and Start is the time that epoch begins.
+	Epoch    int64
+	Start    time.Time
+	LotSize  uint64
+	RateStep uint64
+	// MinimumRate is the new minimum rate, which depends on the lot size.
+	MinimumRate uint64
+}
+
+// minParamChangeEpochs is how many epochs after the current epoch is the
+// soonest a ParamChange may take effect, giving clients time to learn of it.
+const minParamChangeEpochs = 2
 
 // Market is the market manager. It should not be overly involved with details
 // of accounts and authentication. Via the account package it should request
@@ -171,9 +193,25 @@ type Market struct {
 	dataCollector DataCollector
 	lastRate      uint64
 
+	// Monitoring
+	metricsMtx sync.RWMutex
+	metrics    Metrics
+	bookEvent  func(*BookEvent)
+
 	checkParcelLimit func(user account.AccountID, calcParcels MarketParcelCalculator) bool
 
-	minimumRate uint64
+	// paramsMtx guards the lot size, rate step, and minimum rate, which start
+	// with the configured values but may be changed by a scheduled
+	// ParamChange. paramChange is the scheduled change, and bookParams is a
+	// change that is in effect for new orders but is not yet applied to the
+	// book.
+	paramsMtx     sync.RWMutex
+	minimumRate   uint64
+	lotSize       uint64
+	rateStep      uint64
+	paramChange   *ParamChange
+	bookParams    *ParamChange
+	paramsChanged func(*ParamChange)
 }
 
 // Storage is the DB interface required by Market.
@@ -186,6 +224,7 @@ type Storage interface {
 	LastEpochRate(base, quote uint32) (uint64, error)
 	MarketMatches(base, quote uint32) ([]*db.MatchDataWithCoins, error)
 	InsertMatch(match *order.Match) error
+	UpdateMarket(mkt *dex.MarketInfo) error
 }
 
 // NewMarket creates a new Market for the provided base and quote assets, with
@@ -506,6 +545,10 @@ ordersLoop:
 		lastRate:         lastEpochEndRate,
 		checkParcelLimit: cfg.CheckParcelLimit,
 		minimumRate:      cfg.MinimumRate,
+		bookEvent:        cfg.BookEvent,
+		lotSize:          mktInfo.LotSize,
+		rateStep:         mktInfo.RateStep,
+		paramsChanged:    cfg.ParamsChanged,
 	}, nil
 }
 
@@ -635,6 +678,42 @@ func (m *Market) Status() *Status {
 	}
 }
 
+// Metrics are counters and book depth figures for monitoring a Market.
+type Metrics struct {
+	// Epochs is the number of epochs processed since startup.
+	Epochs uint64
+	// Matches is the number of trade matches since startup. Cancel order
+	// matches are not counted.
+	Matches uint64
+	// LastEpoch is the index of the last processed epoch, and LastEpochTime
+	// is when it was matched.
+	LastEpoch     int64
+	LastEpochTime time.Time
+	// LastEpochMatches is the number of trade matches in the last processed
+	// epoch.
+	LastEpochMatches uint64
+	// BookBuyOrders and BookSellOrders are the number of standing orders
+	// after the last match cycle.
+	BookBuyOrders  uint64
+	BookSellOrders uint64
+	// Book depth in units of the base asset after the last match cycle. See
+	// matcher.MatchCycleStats.
+	BookBuys    uint64
+	BookBuys5   uint64
+	BookBuys25  uint64
+	BookSells   uint64
+	BookSells5  uint64
+	BookSells25 uint64
+}
+
+// Metrics returns a snapshot of the Market's monitoring counters.
+func (m *Market) Metrics() *Metrics {
+	m.metricsMtx.RLock()
+	defer m.metricsMtx.RUnlock()
+	metrics := m.metrics
+	return &metrics
+}
+
 // Running indicates is the market is accepting new orders. This will return
 // false when suspended, but false does not necessarily mean Run has stopped
 // since a start epoch may be set. Note that this method is of limited use and
@@ -667,12 +746,137 @@ func (m *Market) MarketBuyBuffer() float64 {
 
 // LotSize returns the market's lot size in units of the base asset.
 func (m *Market) LotSize() uint64 {
-	return m.marketInfo.LotSize
+	m.paramsMtx.RLock()
+	defer m.paramsMtx.RUnlock()
+	return m.lotSize
 }
 
 // RateStep returns the market's rate step in units of the quote asset.
 func (m *Market) RateStep() uint64 {
-	return m.marketInfo.RateStep
+	m.paramsMtx.RLock()
+	defer m.paramsMtx.RUnlock()
+	return m.rateStep
+}
+
+// ScheduleParamChange schedules a change to the market's lot size, rate step,
+// and minimum rate. The change takes effect with the first epoch that starts at or after
+// asSoonAs, but no sooner than minParamChangeEpochs epochs from now. Orders in
+// that epoch and later are validated with the new lot size and rate step, and
+// booked orders that are incompatible with them are revoked before the epoch
+// is matched. A previously scheduled change that is not yet in effect is
+// replaced.
+func (m *Market) ScheduleParamChange(asSoonAs time.Time, lotSize, rateStep, minimumRate uint64) (*ParamChange, error) {
+	if lotSize == 0 || rateStep == 0 {
+		return nil, errors.New("lot size and rate step must be non-zero")
+	}
+
+	dur := int64(m.EpochDuration())
+	soonestIdx := time.Now().UnixMilli()/dur + minParamChangeEpochs
+	epochIdx := max((asSoonAs.UnixMilli()+dur-1)/dur, soonestIdx)
+	change := &ParamChange{
+		Epoch:       epochIdx,
+		Start:       time.UnixMilli(epochIdx * dur),
+		LotSize:     lotSize,
+		RateStep:    rateStep,
+		MinimumRate: minimumRate,
+	}
+
+	m.paramsMtx.Lock()
+	m.paramChange = change
+	m.paramsMtx.Unlock()
+
+	log.Infof("Market %s lot size and rate step changing to %d and %d at epoch %d (%v)",
+		m.marketInfo.Name, lotSize, rateStep, epochIdx, change.Start)
+	return change, nil
+}
+
+// applyParamChange puts a scheduled ParamChange into effect for new orders if
+// it starts by the epoch with index epochIdx. The change is applied to the
+// book by applyBookParams before the epoch is matched.
+func (m *Market) applyParamChange(epochIdx int64) {
+	m.paramsMtx.Lock()
+	change := m.paramChange
+	if change == nil || epochIdx < change.Epoch {
+		m.paramsMtx.Unlock()
+		return
+	}
+	m.paramChange = nil
+	m.paramsMtx.Unlock()
+
+	// Storage validates new orders with the stored lot size. The write is done
+	// without holding paramsMtx, which order validation needs. This is the only
+	// caller that applies a change, and it runs on the market's epoch loop, so
+	// the taken change cannot be applied twice. A change scheduled during the
+	// write is kept for a later epoch.
+	mktInfo := *m.marketInfo
+	mktInfo.LotSize, mktInfo.RateStep = change.LotSize, change.RateStep
+	if err := m.storage.UpdateMarket(&mktInfo); err != nil {
+		log.Errorf("Failed to store the new lot size and rate step for market %s. Not changing them: %v",
+			m.marketInfo.Name, err)
+		return
+	}
+
+	m.paramsMtx.Lock()
+	m.lotSize, m.rateStep, m.minimumRate = change.LotSize, change.RateStep, change.MinimumRate
+	m.bookParams = change
+	m.paramsMtx.Unlock()
+
+	log.Infof("Market %s now using lot size %d and rate step %d as of epoch %d",
+		m.marketInfo.Name, change.LotSize, change.RateStep, epochIdx)
+
+	if m.paramsChanged != nil {
+		m.lazy(func() { m.paramsChanged(change) })
+	}
+}
+
+// applyBookParams applies a ParamChange that is in effect for new orders to
+// the book if the epoch with index epochIdx is the first with the new lot size
+// and rate step. Booked orders that are incompatible with the new lot size or
+// rate step are unbooked and revoked. The revocations are not counted against
+// the users.
+func (m *Market) applyBookParams(epochIdx int64, notifyChan chan<- *updateSignal) {
+	m.paramsMtx.Lock()
+	change := m.bookParams
+	if change == nil || epochIdx < change.Epoch {
+		m.paramsMtx.Unlock()
+		return
+	}
+	m.bookParams = nil
+	m.paramsMtx.Unlock()
+
+	m.bookMtx.Lock()
+	m.book.SetLotSize(change.LotSize)
+	var removed []*order.LimitOrder
+	for _, lo := range append(m.book.BuyOrders(), m.book.SellOrders()...) {
+		if lo.Quantity%change.LotSize == 0 && lo.FillAmt%change.LotSize == 0 && lo.Rate%change.RateStep == 0 {
+			continue
+		}
+		if _, ok := m.book.Remove(lo.ID()); ok {
+			delete(m.settling, lo.ID())
+			removed = append(removed, lo)
+		}
+	}
+	m.bookMtx.Unlock()
+
+	if len(removed) > 0 {
+		log.Infof("Revoking %d booked orders from market %s that are incompatible with lot size %d and rate step %d.",
+			len(removed), m.marketInfo.Name, change.LotSize, change.RateStep)
+	}
+
+	for _, lo := range removed {
+		m.unlockOrderCoins(lo)
+		if _, _, err := m.storage.RevokeOrderUncounted(lo); err != nil {
+			log.Errorf("Failed to revoke order %v: %v", lo, err)
+		}
+		m.sendRevokeOrderNote(lo.ID(), lo.User())
+		notifyChan <- &updateSignal{
+			action: unbookAction,
+			data: sigDataUnbookedOrder{
+				order:    lo,
+				epochIdx: -1, // NOTE: no epoch
+			},
+		}
+	}
 }
 
 // Base is the base asset ID.
@@ -725,6 +929,43 @@ func (m *Market) sendToFeeds(sig *updateSignal) {
 		s <- sig
 	}
 	m.orderFeedMtx.RUnlock()
+	if m.bookEvent != nil {
+		if ev := newBookEvent(sig); ev != nil {
+			m.bookEvent(ev)
+		}
+	}
+}
+
+// BookEvent describes a change to a Market's book or running status.
+type BookEvent struct {
+	// Action is one of "book", "unbook", "suspend" or "resume".
+	Action string
+	// Order is the booked or unbooked order.
+	Order order.Order
+	// EpochIdx is the epoch of the booked or unbooked order, the final epoch
+	// for a suspend, or the first epoch for a resume.
+	EpochIdx int64
+	// PersistBook indicates if the book is kept through a suspend.
+	PersistBook bool
+}
+
+// newBookEvent creates a *BookEvent from an *updateSignal, or returns nil if
+// the signal is not a book change or market status change.
+func newBookEvent(sig *updateSignal) *BookEvent {
+	ev := &BookEvent{Action: sig.action.String()}
+	switch sigData := sig.data.(type) {
+	case sigDataBookedOrder:
+		ev.Order, ev.EpochIdx = sigData.order, sigData.epochIdx
+	case sigDataUnbookedOrder:
+		ev.Order, ev.EpochIdx = sigData.order, sigData.epochIdx
+	case sigDataSuspend:
+		ev.EpochIdx, ev.PersistBook = sigData.finalEpoch, sigData.persistBook
+	case sigDataResume:
+		ev.EpochIdx = sigData.epochIdx
+	default:
+		return nil
+	}
+	return ev
 }
 
 type orderUpdateSignal struct {
@@ -1185,7 +1426,7 @@ func (m *Market) AccountPending(acctAddr string, assetID uint32) (qty, lots uint
 		midGap = m.RateStep()
 	}
 
-	lotSize := m.marketInfo.LotSize
+	lotSize := m.LotSize()
 	switch assetID {
 	case base:
 		m.iterateBaseAccount(acctAddr, func(trade *order.Trade, rate uint64) {
@@ -1522,6 +1763,9 @@ func (m *Market) Run(ctx context.Context) {
 		nextEpochIdx = currentEpoch.Epoch + 1
 		m.activeEpochIdx = currentEpoch.Epoch
 
+		// Orders in this epoch use any scheduled lot size and rate step.
+		m.applyParamChange(currentEpoch.Epoch)
+
 		if !running {
 			// Check that both blockchains are synced before actually starting.
 			synced, err := m.swapper.ChainsSynced(m.marketInfo.Base, m.marketInfo.Quote)
@@ -1719,7 +1963,7 @@ func (m *Market) analysisHelpers() (
 		if ord.Type() == order.MarketOrderType && !ord.Trade().Sell {
 			// Market buy qty is in quote asset. Convert to base.
 			if midGap == 0 {
-				qty = m.marketInfo.LotSize // no orders on the book; call it 1 lot
+				qty = m.LotSize() // no orders on the book; call it 1 lot
 			} else {
 				qty = calc.QuoteToBase(midGap, qty)
 			}
@@ -1762,7 +2006,7 @@ func (m *Market) parcels(user account.AccountID, addParcelWeight uint64) float64
 
 	bookedBuyAmt, bookedSellAmt, _, _ := m.book.UserOrderTotals(user)
 	makerQty += bookedBuyAmt + bookedSellAmt
-	return calc.Parcels(makerQty+addParcelWeight, takerQty, m.marketInfo.LotSize, m.marketInfo.ParcelSize)
+	return calc.Parcels(makerQty+addParcelWeight, takerQty, m.LotSize(), m.marketInfo.ParcelSize)
 }
 
 // processOrder performs the following actions:
@@ -2368,6 +2612,10 @@ func (m *Market) processReadyEpoch(epoch *readyEpoch, notifyChan chan<- *updateS
 	}
 	cancelMatches := make([]cancelMatch, 0)
 
+	// Apply any lot size and rate step change to the book before matching the
+	// first epoch with the new values.
+	m.applyBookParams(epoch.Epoch, notifyChan)
+
 	// Perform order matching using the preimages to shuffle the queue.
 	m.bookMtx.Lock()        // allow a coherent view of book orders with (*Market).Book
 	matchTime := time.Now() // considered as the time at which matched cancel orders are executed
@@ -2375,6 +2623,7 @@ func (m *Market) processReadyEpoch(epoch *readyEpoch, notifyChan chan<- *updateS
 	m.bookEpochIdx = epoch.Epoch + 1
 	epochDur := int64(m.EpochDuration())
 	var canceled []order.OrderID
+	var tradeMatches uint64
 	for _, ms := range matches {
 		// Set the epoch ID.
 		ms.Epoch.Idx = uint64(epoch.Epoch)
@@ -2394,6 +2643,7 @@ func (m *Market) processReadyEpoch(epoch *readyEpoch, notifyChan chan<- *updateS
 			}
 			m.settling[match.Taker.ID()] += match.Quantity
 			m.settling[match.Maker.ID()] += match.Quantity
+			tradeMatches++
 		}
 	}
 	for _, oid := range canceled {
@@ -2401,8 +2651,25 @@ func (m *Market) processReadyEpoch(epoch *readyEpoch, notifyChan chan<- *updateS
 		// there is no completion credit on a canceled order.
 		delete(m.settling, oid)
 	}
+	bookBuyOrders, bookSellOrders := m.book.BuyCount(), m.book.SellCount()
 	m.bookMtx.Unlock()
 
+	m.metricsMtx.Lock()
+	m.metrics.Epochs++
+	m.metrics.Matches += tradeMatches
+	m.metrics.LastEpoch = epoch.Epoch
+	m.metrics.LastEpochTime = matchTime
+	m.metrics.LastEpochMatches = tradeMatches
+	m.metrics.BookBuyOrders = uint64(bookBuyOrders)
+	m.metrics.BookSellOrders = uint64(bookSellOrders)
+	m.metrics.BookBuys = stats.BookBuys
+	m.metrics.BookBuys5 = stats.BookBuys5
+	m.metrics.BookBuys25 = stats.BookBuys25
+	m.metrics.BookSells = stats.BookSells
+	m.metrics.BookSells5 = stats.BookSells5
+	m.metrics.BookSells25 = stats.BookSells25
+	m.metricsMtx.Unlock()
+
 	if len(ordersRevealed) > 0 {
 		log.Infof("Matching complete for market %v epoch %d:"+
 			" %d matches (%d partial fills), %d completed OK (not booked),"+
@@ -2672,7 +2939,7 @@ func (m *Market) processReadyEpoch(epoch *readyEpoch, notifyChan chan<- *updateS
 	}
 }
 
-// validateOrder uses db.ValidateOrder to ensure that the provided order is
+// validateOrder uses order.ValidateOrder to ensure that the provided order is
 // valid for the current market with epoch order status.
 func (m *Market) validateOrder(ord order.Order) error {
 	// First check the order commitment before bothering the Market's run loop.
@@ -2682,12 +2949,23 @@ func (m *Market) validateOrder(ord order.Order) error {
 		return ErrInvalidCommitment
 	}
 
-	if !db.ValidateOrder(ord, order.OrderStatusEpoch, m.marketInfo) {
+	// The lot size and rate step may have changed since the order router
+	// validated the order.
+	m.paramsMtx.RLock()
+	lotSize, rateStep, minimumRate := m.lotSize, m.rateStep, m.minimumRate
+	m.paramsMtx.RUnlock()
+
+	if order.ValidateOrder(ord, order.OrderStatusEpoch, lotSize) != nil {
 		return ErrInvalidOrder // non-specific
 	}
 
-	if lo, is := ord.(*order.LimitOrder); is && lo.Rate < m.minimumRate {
-		return ErrInvalidRate
+	if lo, is := ord.(*order.LimitOrder); is {
+		if lo.Rate < minimumRate {
+			return ErrInvalidRate
+		}
+		if lo.Rate%rateStep != 0 {
+			return ErrInvalidOrder
+		}
 	}
 
 	return nil
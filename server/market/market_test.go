@@ -42,6 +42,7 @@ type TArchivist struct {
 	archivedCancels      []*order.CancelOrder
 	epochInserted        chan struct{}
 	revoked              order.Order
+	updatedMkt           *dex.MarketInfo
 }
 
 func (ta *TArchivist) Close() error           { return nil }
@@ -129,6 +130,14 @@ func (ta *TArchivist) InsertEpoch(ed *db.EpochResults) error {
 	}
 	return nil
 }
+func (ta *TArchivist) AddMarket(mkt *dex.MarketInfo) error { return nil }
+func (ta *TArchivist) RemoveMarket(mkt *dex.MarketInfo)    {}
+func (ta *TArchivist) UpdateMarket(mkt *dex.MarketInfo) error {
+	ta.mtx.Lock()
+	defer ta.mtx.Unlock()
+	ta.updatedMkt = mkt
+	return nil
+}
 func (ta *TArchivist) LastEpochRate(base, quote uint32) (rate uint64, err error) {
 	return 1, nil
 }
@@ -805,6 +814,105 @@ func TestMarket_Suspend_Persist(t *testing.T) {
 	}
 }
 
+func TestMarket_ScheduleParamChange(t *testing.T) {
+	mkt, storage, _, cleanup, err := newTestMarket()
+	if err != nil {
+		t.Fatalf("newTestMarket failure: %v", err)
+	}
+	defer cleanup()
+
+	newLotSize, newRateStep := uint64(2*dcrLotSize), uint64(2*btcRateStep)
+
+	// Orders with an even number of lots and a rate that is a multiple of the
+	// new rate step stay on the book.
+	keepBuy := makeLO(buyer3, 2_000_000, 2, order.StandingTiF)
+	keepSell := makeLO(seller3, 4_000_000, 4, order.StandingTiF)
+	oddLots := makeLO(seller3, 4_000_000, 3, order.StandingTiF)
+	oddRate := makeLO(buyer3, 2_001_000, 2, order.StandingTiF)
+	for _, lo := range []*order.LimitOrder{keepBuy, keepSell, oddLots, oddRate} {
+		if !mkt.book.Insert(lo) {
+			t.Fatalf("Failed to Insert order into book.")
+		}
+	}
+
+	if _, err = mkt.ScheduleParamChange(time.Time{}, 0, newRateStep, 0); err == nil {
+		t.Fatalf("no error for a zero lot size")
+	}
+
+	dur := int64(mkt.EpochDuration())
+	soonest := time.Now().UnixMilli()/dur + minParamChangeEpochs
+	change, err := mkt.ScheduleParamChange(time.Time{}, newLotSize, newRateStep, 0)
+	if err != nil {
+		t.Fatalf("ScheduleParamChange error: %v", err)
+	}
+	if change.Epoch < soonest {
+		t.Fatalf("change scheduled for epoch %d, sooner than %d", change.Epoch, soonest)
+	}
+	if change.Start.UnixMilli() != change.Epoch*dur {
+		t.Fatalf("wrong start time %v for epoch %d", change.Start, change.Epoch)
+	}
+
+	var changed *ParamChange
+	mkt.paramsChanged = func(c *ParamChange) { changed = c }
+
+	// Nothing changes before the scheduled epoch.
+	mkt.applyParamChange(change.Epoch - 1)
+	if mkt.LotSize() != dcrLotSize || mkt.RateStep() != btcRateStep {
+		t.Fatalf("lot size and rate step changed early")
+	}
+
+	mkt.applyParamChange(change.Epoch)
+	mkt.tasks.Wait()
+	if mkt.LotSize() != newLotSize || mkt.RateStep() != newRateStep {
+		t.Fatalf("lot size and rate step not changed")
+	}
+	if changed != change {
+		t.Fatalf("ParamsChanged not called")
+	}
+	if storage.updatedMkt == nil || storage.updatedMkt.LotSize != newLotSize {
+		t.Fatalf("new lot size not stored")
+	}
+
+	// New orders are validated with the new lot size and rate step.
+	lo := makeLO(buyer3, 2_000_000, 3, order.StandingTiF)
+	lo.SetTime(time.Now())
+	if err = mkt.validateOrder(lo); !errors.Is(err, ErrInvalidOrder) {
+		t.Fatalf("expected ErrInvalidOrder for an incompatible lot size, got %v", err)
+	}
+	lo = makeLO(buyer3, 2_001_000, 2, order.StandingTiF)
+	lo.SetTime(time.Now())
+	if err = mkt.validateOrder(lo); !errors.Is(err, ErrInvalidOrder) {
+		t.Fatalf("expected ErrInvalidOrder for an incompatible rate, got %v", err)
+	}
+
+	// The book is not changed until the change epoch is matched.
+	notifyChan := make(chan *updateSignal, 4)
+	mkt.applyBookParams(change.Epoch-1, notifyChan)
+	if mkt.book.LotSize() != dcrLotSize {
+		t.Fatalf("book lot size changed early")
+	}
+
+	mkt.applyBookParams(change.Epoch, notifyChan)
+	if mkt.book.LotSize() != newLotSize {
+		t.Fatalf("book lot size not changed")
+	}
+	close(notifyChan)
+	unbooked := make(map[order.OrderID]bool)
+	for sig := range notifyChan {
+		if sig.action != unbookAction {
+			t.Fatalf("expected unbookAction signal, got %v", sig.action)
+		}
+		unbooked[sig.data.(sigDataUnbookedOrder).order.ID()] = true
+	}
+	if len(unbooked) != 2 || !unbooked[oddLots.ID()] || !unbooked[oddRate.ID()] {
+		t.Fatalf("wrong orders unbooked: %v", unbooked)
+	}
+	_, buys, sells := mkt.Book()
+	if len(buys) != 1 || buys[0].ID() != keepBuy.ID() || len(sells) != 1 || sells[0].ID() != keepSell.ID() {
+		t.Fatalf("compatible orders not left on the book")
+	}
+}
+
 func TestMarket_Run(t *testing.T) {
 	// This test exercises the Market's main loop, which cycles the epochs and
 	// queues (or not) incoming orders.
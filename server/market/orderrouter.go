@@ -10,6 +10,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"sync"
 	"time"
 
 	"decred.org/dcrdex/dex"
@@ -149,6 +150,7 @@ type MatchSwapper interface {
 type OrderRouter struct {
 	auth        AuthManager
 	assets      map[uint32]*asset.BackedAsset
+	tunnelsMtx  sync.RWMutex
 	tunnels     map[string]MarketTunnel
 	latencyQ    *wait.TickerQueue
 	feeSource   FeeSource
@@ -171,7 +173,7 @@ func NewOrderRouter(cfg *OrderRouterConfig) *OrderRouter {
 	router := &OrderRouter{
 		auth:        cfg.AuthManager,
 		assets:      cfg.Assets,
-		tunnels:     cfg.Markets,
+		tunnels:     make(map[string]MarketTunnel, len(cfg.Markets)),
 		latencyQ:    wait.NewTickerQueue(2 * time.Second),
 		feeSource:   cfg.FeeSource,
 		dexBalancer: cfg.DEXBalancer,
@@ -180,6 +182,9 @@ func NewOrderRouter(cfg *OrderRouterConfig) *OrderRouter {
 	cfg.AuthManager.Route(msgjson.LimitRoute, router.handleLimit)
 	cfg.AuthManager.Route(msgjson.MarketRoute, router.handleMarket)
 	cfg.AuthManager.Route(msgjson.CancelRoute, router.handleCancel)
+	for mktName, tunnel := range cfg.Markets {
+		router.tunnels[mktName] = tunnel
+	}
 	return router
 }
 
@@ -494,7 +499,7 @@ func (r *OrderRouter) processTrade(oRecord *orderRecord, tunnel MarketTunnel, as
 
 	// Use this as a chance to check user's existing market orders.
 	// TODO: check all markets?
-	for mktName, tunnel := range r.tunnels {
+	for mktName, tunnel := range r.tunnelsCopy() {
 		unbookedUnfunded := tunnel.CheckUnfilled(assets.funding.ID, oRecord.order.User())
 		for _, badLo := range unbookedUnfunded {
 			log.Infof("Unbooked unfunded order %v from market %s for user %v", badLo, mktName, oRecord.order.User())
@@ -725,7 +730,7 @@ func (r *OrderRouter) CheckParcelLimit(user account.AccountID, targetMarketName
 
 	var otherMarketParcels float64
 	var settlingQty uint64
-	for mktName, mkt := range r.tunnels {
+	for mktName, mkt := range r.tunnelsCopy() {
 		if mktName == targetMarketName {
 			settlingQty = settlingQuantities[mktName]
 			continue
@@ -881,13 +886,51 @@ func (r *OrderRouter) extractMarket(prefix *msgjson.Prefix) (MarketTunnel, *msgj
 	if err != nil {
 		return nil, msgjson.NewError(msgjson.UnknownMarketError, "asset lookup error: %v", err.Error())
 	}
-	tunnel, found := r.tunnels[mktName]
-	if !found {
+	tunnel := r.tunnel(mktName)
+	if tunnel == nil {
 		return nil, msgjson.NewError(msgjson.UnknownMarketError, "unknown market %s", mktName)
 	}
 	return tunnel, nil
 }
 
+// tunnel retrieves the MarketTunnel for the market, or nil if the market is
+// unknown.
+func (r *OrderRouter) tunnel(mktName string) MarketTunnel {
+	r.tunnelsMtx.RLock()
+	defer r.tunnelsMtx.RUnlock()
+	return r.tunnels[mktName]
+}
+
+// tunnelsCopy returns a copy of the market tunnels map.
+func (r *OrderRouter) tunnelsCopy() map[string]MarketTunnel {
+	r.tunnelsMtx.RLock()
+	defer r.tunnelsMtx.RUnlock()
+	tunnels := make(map[string]MarketTunnel, len(r.tunnels))
+	for mktName, tunnel := range r.tunnels {
+		tunnels[mktName] = tunnel
+	}
+	return tunnels
+}
+
+// AddMarket adds a market that will accept orders routed by the OrderRouter.
+func (r *OrderRouter) AddMarket(mktName string, tunnel MarketTunnel) error {
+	r.tunnelsMtx.Lock()
+	defer r.tunnelsMtx.Unlock()
+	if _, found := r.tunnels[mktName]; found {
+		return fmt.Errorf("market %s already exists", mktName)
+	}
+	r.tunnels[mktName] = tunnel
+	return nil
+}
+
+// RemoveMarket removes a retired market. Orders for the market will be
+// rejected as for an unknown market.
+func (r *OrderRouter) RemoveMarket(mktName string) {
+	r.tunnelsMtx.Lock()
+	delete(r.tunnels, mktName)
+	r.tunnelsMtx.Unlock()
+}
+
 // SuspendEpoch holds the index and end time of final epoch marking the
 // suspension of a market.
 type SuspendEpoch struct {
@@ -904,8 +947,8 @@ type SuspendEpoch struct {
 // blocking order submission according to the schedule rather than just checking
 // Market.Running prior to submitting incoming orders to the Market.
 func (r *OrderRouter) SuspendMarket(mktName string, asSoonAs time.Time, persistBooks bool) *SuspendEpoch {
-	mkt, found := r.tunnels[mktName]
-	if !found {
+	mkt := r.tunnel(mktName)
+	if mkt == nil {
 		return nil
 	}
 
@@ -919,8 +962,9 @@ func (r *OrderRouter) SuspendMarket(mktName string, asSoonAs time.Time, persistB
 // Suspend is like SuspendMarket, but for all known markets.
 func (r *OrderRouter) Suspend(asSoonAs time.Time, persistBooks bool) map[string]*SuspendEpoch {
 
-	suspendTimes := make(map[string]*SuspendEpoch, len(r.tunnels))
-	for name, mkt := range r.tunnels {
+	tunnels := r.tunnelsCopy()
+	suspendTimes := make(map[string]*SuspendEpoch, len(tunnels))
+	for name, mkt := range tunnels {
 		idx, ts := mkt.Suspend(asSoonAs, persistBooks)
 		suspendTimes[name] = &SuspendEpoch{Idx: idx, End: ts}
 	}
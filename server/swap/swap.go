@@ -228,6 +228,9 @@ type Swapper struct {
 	authMgr AuthManager
 	// swapDone is callback for reporting a swap outcome.
 	swapDone func(oid order.Order, match *order.Match, fail bool)
+	// matchEvent is an optional callback for reporting new matches, swap
+	// step transitions, and revocations.
+	matchEvent func(match *order.Match, status order.MatchStatus, revoked bool)
 
 	// The matches maps and the contained matches are protected by the matchMtx.
 	matchMtx    sync.RWMutex
@@ -284,6 +287,10 @@ type Config struct {
 	// SwapDone registers a match with the DEX manager (or other consumer) for a
 	// given order as being finished.
 	SwapDone func(oid order.Order, match *order.Match, fail bool)
+	// MatchEvent, if set, is called when a new trade match is negotiated,
+	// when a match's status advances, and when a match is revoked. The
+	// Match's Status field should not be accessed. MatchEvent must not block.
+	MatchEvent func(match *order.Match, status order.MatchStatus, revoked bool)
 }
 
 // NewSwapper is a constructor for a Swapper.
@@ -307,6 +314,7 @@ func NewSwapper(cfg *Config) (*Swapper, error) {
 		storage:          cfg.Storage,
 		authMgr:          authMgr,
 		swapDone:         cfg.SwapDone,
+		matchEvent:       cfg.MatchEvent,
 		latencyQ:         wait.NewTaperingTickerQueue(fastRecheckInterval, taperedRecheckInterval),
 		matches:          make(map[order.MatchID]*matchTracker),
 		userMatches:      make(map[account.AccountID]map[order.MatchID]*matchTracker),
@@ -520,6 +528,32 @@ func (s *Swapper) AccountStats(acctAddr string, assetID uint32) (qty, swaps uint
 	return stats.qty, stats.swaps, stats.redeems
 }
 
+// ActiveSwapCounts returns the number of active matches in each status.
+func (s *Swapper) ActiveSwapCounts() map[order.MatchStatus]int {
+	counts := make(map[order.MatchStatus]int)
+	s.matchMtx.RLock()
+	defer s.matchMtx.RUnlock()
+	for _, mt := range s.matches {
+		mt.mtx.RLock()
+		counts[mt.Status]++
+		mt.mtx.RUnlock()
+	}
+	return counts
+}
+
+// MarketSwapCount is the number of active swaps for the market.
+func (s *Swapper) MarketSwapCount(base, quote uint32) int {
+	s.matchMtx.RLock()
+	defer s.matchMtx.RUnlock()
+	var n int
+	for _, mt := range s.matches {
+		if mt.Maker.Base() == base && mt.Maker.Quote() == quote {
+			n++
+		}
+	}
+	return n
+}
+
 // ChainsSynced will return true if both specified asset's backends are synced.
 func (s *Swapper) ChainsSynced(base, quote uint32) (bool, error) {
 	b, found := s.coins[base]
@@ -1078,10 +1112,19 @@ func (s *Swapper) failMatch(match *matchTracker, userFault bool) {
 			match.Quantity, refTime, orderAtFault.ID())
 	}
 
+	s.reportMatchEvent(match.Match, match.Status, true)
+
 	// Send the revoke_match messages, and solicit acks.
 	s.revoke(match)
 }
 
+// reportMatchEvent calls the MatchEvent callback, if one was configured.
+func (s *Swapper) reportMatchEvent(match *order.Match, status order.MatchStatus, revoked bool) {
+	if s.matchEvent != nil {
+		s.matchEvent(match, status, revoked)
+	}
+}
+
 type fail struct {
 	match *matchTracker
 	fault bool
@@ -1657,6 +1700,7 @@ func (s *Swapper) processInit(msg *msgjson.Message, params *msgjson.Init, stepIn
 	stepInfo.match.mtx.Lock()
 	stepInfo.match.Status = stepInfo.nextStep // handleInit (gate mechanism) won't allow backward progress
 	stepInfo.match.mtx.Unlock()
+	s.reportMatchEvent(stepInfo.match.Match, stepInfo.nextStep, false)
 
 	// Only unlock match map after the statuses and txn times are stored,
 	// ensuring that checkInaction will not revoke the match as we respond and
@@ -1796,6 +1840,7 @@ func (s *Swapper) processRedeem(msg *msgjson.Message, params *msgjson.Redeem, st
 	match.mtx.Lock()
 	match.Status = newStatus // handleRedeem (gate mechanism) won't allow backward progress
 	match.mtx.Unlock()
+	s.reportMatchEvent(match.Match, newStatus, false)
 
 	// Only unlock match map after the statuses and txn times are stored,
 	// ensuring that checkInaction will not revoke the match as we respond.
@@ -2521,6 +2566,10 @@ func (s *Swapper) Negotiate(matchSets []*order.MatchSet) {
 	}
 	s.matchMtx.Unlock()
 
+	for _, match := range toMonitor {
+		s.reportMatchEvent(match.Match, order.NewlyMatched, false)
+	}
+
 	// Send the user match notifications.
 	for user, matches := range userMatches {
 		// msgs is a slice of msgjson.Match created by newMatchAckers